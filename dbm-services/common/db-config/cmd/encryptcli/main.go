@@ -1,2 +1,189 @@
-// Package encryptcli tool
-package encryptcli
+// Package main encryptcli 使用内置 aes-256-gcm 分块格式加解密文件，不依赖 openssl/xbcrypt
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"dbm-services/common/go-pubpkg/iocrypt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	Execute()
+}
+
+var rootCmd = &cobra.Command{
+	Use:   "encryptcli",
+	Short: "encryptcli encrypt or decrypt file with native aes-256-gcm chunked format",
+	Long:  "encryptcli encrypt or decrypt file with native aes-256-gcm chunked format",
+}
+
+// Execute run root command
+func Execute() {
+	err := rootCmd.Execute()
+	if err != nil {
+		_, _ = os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+
+var genKeyCmd = &cobra.Command{
+	Use:   "genkey",
+	Short: "generate rsa key pair used to wrap data key",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		bits, _ := cmd.Flags().GetInt("bits")
+		outDir, _ := cmd.Flags().GetString("out-dir")
+		priv, pub, err := iocrypt.GenerateKeyPair(bits)
+		if err != nil {
+			return err
+		}
+		pubBytes, err := iocrypt.PublicKeyToBytes(pub)
+		if err != nil {
+			return err
+		}
+		if err = os.WriteFile(filepath.Join(outDir, "private.pem"), iocrypt.PrivateKeyToBytes(priv), 0600); err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(outDir, "public.pem"), pubBytes, 0644)
+	},
+}
+
+var encryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "encrypt file, use --passphrase or --public-key",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		passphrase, _ := cmd.Flags().GetString("passphrase")
+		publicKeyFile, _ := cmd.Flags().GetString("public-key")
+		chunkSize, _ := cmd.Flags().GetInt("chunk-size")
+		tool := iocrypt.GcmCrypt{Passphrase: passphrase, ChunkSize: chunkSize}
+		if publicKeyFile != "" {
+			bs, err := os.ReadFile(publicKeyFile)
+			if err != nil {
+				return errors.Wrap(err, "read public key")
+			}
+			if tool.PublicKey, err = iocrypt.BytesToPublicKey(bs); err != nil {
+				return errors.Wrapf(err, "parse public key %s", publicKeyFile)
+			}
+		} else if passphrase == "" {
+			return errors.New("need --passphrase or --public-key")
+		}
+
+		in, out, err := openInOut(cmd)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		defer out.Close()
+
+		w, err := iocrypt.FileEncryptWriter(tool, out)
+		if err != nil {
+			return err
+		}
+		if _, err = io.Copy(w, in); err != nil {
+			return err
+		}
+		return w.Close()
+	},
+}
+
+var decryptCmd = &cobra.Command{
+	Use:   "decrypt",
+	Short: "decrypt file, use --passphrase or --private-key",
+	Long: "decrypt file, use --passphrase or --private-key\n" +
+		"--offset/--length decrypt only part of the file, need a regular input file",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key := iocrypt.GcmKey{}
+		key.Passphrase, _ = cmd.Flags().GetString("passphrase")
+		privateKeyFile, _ := cmd.Flags().GetString("private-key")
+		offset, _ := cmd.Flags().GetInt64("offset")
+		length, _ := cmd.Flags().GetInt64("length")
+		if privateKeyFile != "" {
+			bs, err := os.ReadFile(privateKeyFile)
+			if err != nil {
+				return errors.Wrap(err, "read private key")
+			}
+			if key.PrivateKey, err = iocrypt.BytesToPrivateKey(bs); err != nil {
+				return errors.Wrapf(err, "parse private key %s", privateKeyFile)
+			}
+		}
+
+		in, out, err := openInOut(cmd)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		defer out.Close()
+
+		if offset == 0 && length < 0 {
+			r, err := iocrypt.NewGcmReader(in, key)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(out, r)
+			return err
+		}
+
+		// 只解密需要的块
+		st, err := in.Stat()
+		if err != nil {
+			return err
+		}
+		if !st.Mode().IsRegular() {
+			return errors.New("--offset/--length need a regular input file")
+		}
+		ra, err := iocrypt.NewGcmReaderAt(in, st.Size(), key)
+		if err != nil {
+			return err
+		}
+		if length < 0 {
+			length = ra.Size() - offset
+		}
+		_, err = io.Copy(out, io.NewSectionReader(ra, offset, length))
+		return err
+	},
+}
+
+// openInOut - 表示标准输入输出
+func openInOut(cmd *cobra.Command) (*os.File, *os.File, error) {
+	inFile, _ := cmd.Flags().GetString("in")
+	outFile, _ := cmd.Flags().GetString("out")
+	in, out := os.Stdin, os.Stdout
+	var err error
+	if inFile != "-" {
+		if in, err = os.Open(inFile); err != nil {
+			return nil, nil, err
+		}
+	}
+	if outFile != "-" {
+		if out, err = os.OpenFile(outFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644); err != nil {
+			_ = in.Close()
+			return nil, nil, err
+		}
+	}
+	return in, out, nil
+}
+
+func init() {
+	genKeyCmd.Flags().Int("bits", 2048, "rsa key bits")
+	genKeyCmd.Flags().String("out-dir", ".", "dir to write private.pem and public.pem")
+
+	for _, c := range []*cobra.Command{encryptCmd, decryptCmd} {
+		c.Flags().StringP("in", "i", "-", "input file, - means stdin")
+		c.Flags().StringP("out", "o", "-", "output file, - means stdout")
+		c.Flags().StringP("passphrase", "k", "", "passphrase")
+	}
+	encryptCmd.Flags().String("public-key", "", "rsa public key file, wrap a random data key with it")
+	encryptCmd.Flags().Int("chunk-size", iocrypt.DefaultChunkSize, "plain text bytes per chunk")
+	encryptCmd.MarkFlagsMutuallyExclusive("passphrase", "public-key")
+
+	decryptCmd.Flags().String("private-key", "", "rsa private key file")
+	decryptCmd.Flags().Int64("offset", 0, "plain text offset to start decrypt")
+	decryptCmd.Flags().Int64("length", -1, "plain text bytes to decrypt, -1 means to the end")
+	decryptCmd.MarkFlagsMutuallyExclusive("passphrase", "private-key")
+
+	rootCmd.AddCommand(genKeyCmd, encryptCmd, decryptCmd)
+}
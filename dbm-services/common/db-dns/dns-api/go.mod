@@ -8,6 +8,7 @@ require (
 	bk-dnsapi/pkg v0.0.0-20200327131337-b2b67ca8129b
 	github.com/gin-gonic/gin v1.10.0
 	github.com/jinzhu/gorm v1.9.10
+	github.com/mattn/go-sqlite3 v1.14.17
 	github.com/pkg/errors v0.9.1
 	github.com/spf13/viper v1.19.0
 	go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin v0.52.0
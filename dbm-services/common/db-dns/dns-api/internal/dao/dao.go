@@ -1,6 +1,8 @@
 package dao
 
 import (
+	"fmt"
+
 	"bk-dnsapi/internal/domain/entity"
 
 	"github.com/jinzhu/gorm"
@@ -13,6 +15,9 @@ var (
 	DnsDB *gorm.DB
 )
 
+// ipColumnLength ip字段长度，需要能存下ipv6
+const ipColumnLength = 64
+
 // Init 初始化
 func Init() error {
 	if err := InitDnsDB(); err != nil {
@@ -37,12 +42,21 @@ func InitDnsDB() error {
 			&entity.TbDnsBase{},
 			&entity.TbDnsIdcMap{},
 			&entity.TbDnsServer{},
-			&entity.TbDnsConfig{})
+			&entity.TbDnsConfig{},
+			&entity.TbDnsChangeLog{})
+		if err = migrateIpColumn(db); err != nil {
+			return err
+		}
 		// 创建索引
 		db.Table("tb_dns_base").AddIndex("idx_ip_port", "ip", "port")
 		db.Table("tb_dns_base").AddIndex("idx_domain_name_app", "domain_name", "app")
 		db.Table("tb_dns_base").AddIndex("idx_app_manager", "app", "manager")
 		db.Table("tb_dns_base").AddUniqueIndex("uidx_domain_name_ip_port", "domain_name", "ip", "port")
+		// 回滚和查询都按app+云区域+域名过滤再按时间排序
+		db.Table("tb_dns_change_log").AddIndex("idx_app_cloud_domain_change_time",
+			"app", "bk_cloud_id", "domain_name", "change_time")
+		db.Table("tb_dns_change_log").AddIndex("idx_cloud_op_type_change_time", "bk_cloud_id", "op_type", "change_time")
+		db.Table("tb_dns_change_log").AddIndex("idx_batch_id", "batch_id")
 
 		db.Table("tb_dns_server").AddUniqueIndex("uidx_ip", "ip")
 		db.Table("tb_dns_config").AddUniqueIndex("inx_p", "paraname")
@@ -51,6 +65,27 @@ func InitDnsDB() error {
 	return nil
 }
 
+// migrateIpColumn AutoMigrate不会修改已有字段，ip需要放宽到能存下ipv6
+// 只有字段长度不够时才执行，避免每次启动都改表
+func migrateIpColumn(db *gorm.DB) error {
+	var col struct {
+		Length int64 `gorm:"column:length"`
+	}
+	if err := db.Raw("select character_maximum_length as length from information_schema.columns "+
+		"where table_schema = database() and table_name = ? and column_name = ?",
+		(&entity.TbDnsBase{}).TableName(), "ip").Scan(&col).Error; err != nil {
+		return errors.Wrap(err, "query ip column of tb_dns_base failed")
+	}
+	if col.Length >= ipColumnLength {
+		return nil
+	}
+	if err := db.Model(&entity.TbDnsBase{}).
+		ModifyColumn("ip", fmt.Sprintf("varchar(%d)", ipColumnLength)).Error; err != nil {
+		return errors.Wrap(err, "modify ip column of tb_dns_base failed")
+	}
+	return nil
+}
+
 // Close 关闭连接
 func Close() error {
 	if err := DnsDB.Close(); err != nil {
@@ -25,7 +25,7 @@ type TbDnsBase struct {
 	Uid            int64     `gorm:"column:uid;size:11;primary_key;AUTO_INCREMENT"  json:"uid"`
 	App            string    `gorm:"size:32;column:app" json:"app"`
 	DomainName     string    `gorm:"size:255;column:domain_name" json:"domain_name"`
-	Ip             string    `gorm:"size:20;column:ip" json:"ip"`
+	Ip             string    `gorm:"size:64;column:ip" json:"ip"`
 	Port           int       `gorm:"size:11;column:port" json:"port"`
 	StartTime      time.Time `gorm:"column:start_time" json:"start_time"`
 	LastChangeTime time.Time `gorm:"column:last_change_time" json:"last_change_time"`
@@ -64,6 +64,37 @@ func (t *TbDnsBase) TableUnique() [][]string {
 	}
 }
 
+// 变更类型
+const (
+	ChangeOpAdd    = "add"
+	ChangeOpDelete = "delete"
+	ChangeOpUpdate = "update"
+)
+
+// TbDnsChangeLog 域名变更记录表，只追加不修改
+type TbDnsChangeLog struct {
+	Uid        int64     `gorm:"column:uid;size:11;primary_key;AUTO_INCREMENT"  json:"uid"`
+	BatchId    string    `gorm:"size:64;column:batch_id" json:"batch_id"`
+	App        string    `gorm:"size:32;column:app" json:"app"`
+	DomainName string    `gorm:"size:255;column:domain_name" json:"domain_name"`
+	BkCloudId  int64     `gorm:"size:32;column:bk_cloud_id" json:"bk_cloud_id"`
+	OpType     string    `gorm:"size:16;column:op_type" json:"op_type"`
+	BeforeIp   string    `gorm:"size:64;column:before_ip" json:"before_ip"`
+	BeforePort int       `gorm:"size:11;column:before_port" json:"before_port"`
+	AfterIp    string    `gorm:"size:64;column:after_ip" json:"after_ip"`
+	AfterPort  int       `gorm:"size:11;column:after_port" json:"after_port"`
+	Manager    string    `gorm:"size:32;column:manager" json:"manager"`
+	Remark     string    `gorm:"size:128;column:remark" json:"remark"`
+	Operator   string    `gorm:"size:64;column:operator" json:"operator"`
+	Source     string    `gorm:"size:128;column:source" json:"source"`
+	ChangeTime time.Time `gorm:"column:change_time" json:"change_time"`
+}
+
+// TableName tb_dns_change_log
+func (t *TbDnsChangeLog) TableName() string {
+	return "tb_dns_change_log"
+}
+
 // TbDnsServer 服务器表
 type TbDnsServer struct {
 	Uid            int64     `gorm:"column:uid;size:11;primary_key;AUTO_INCREMENT"  json:"uid"`
@@ -6,17 +6,20 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 
 	"bk-dnsapi/pkg/logger"
+
+	"github.com/jinzhu/gorm"
 )
 
 // DnsDomainBaseRepo dns_base方法接口
 type DnsDomainBaseRepo interface {
 	Get(map[string]interface{}, []string) ([]interface{}, error)
-	Insert(d []*entity.TbDnsBase) (num int64, err error)
-	Delete(tableName, app, domainName string, bkCloudId int64, ins []string) (rowsAffected int64, err error)
-	Update(d *entity.TbDnsBase, newIP string, newPort int) (rowsAffected int64, err error)
-	UpdateDomainBatch(bs []UpdateBatchDnsBase) (rowsAffected int64, err error)
+	Insert(d []*entity.TbDnsBase, op OpInfo) (num int64, err error)
+	Delete(tableName, app, domainName string, bkCloudId int64, ins []string, op OpInfo) (rowsAffected int64, err error)
+	Update(d *entity.TbDnsBase, newIP string, newPort int, op OpInfo) (rowsAffected int64, err error)
+	UpdateDomainBatch(bs []UpdateBatchDnsBase, op OpInfo) (rowsAffected int64, err error)
 }
 
 // DnsDomainBaseImpl dns_base方法实现
@@ -49,6 +52,13 @@ func (base *DnsDomainBaseImpl) Get(query map[string]interface{}, fields []string
 		if k == "ins" || k == "ip" {
 			continue
 		}
+		// 增量查询，只返回该时间之后变更过的记录
+		if k == "last_change_time" {
+			if t, ok := v.(string); ok && t != "" {
+				where = fmt.Sprintf("%s and last_change_time >= '%s'", where, t)
+			}
+			continue
+		}
 		switch v.(type) {
 		case []string:
 			if len(v.([]string)) != 0 {
@@ -109,8 +119,9 @@ func (base *DnsDomainBaseImpl) Get(query map[string]interface{}, fields []string
 }
 
 // Insert 插入域名
-func (base *DnsDomainBaseImpl) Insert(dnsList []*entity.TbDnsBase) (num int64, err error) {
+func (base *DnsDomainBaseImpl) Insert(dnsList []*entity.TbDnsBase, op OpInfo) (num int64, err error) {
 	tx := dao.DnsDB.Begin()
+	op = op.withBatchId()
 	for _, l := range dnsList {
 		logger.Info(fmt.Sprintf("insert op:[%+v]", l))
 		r := tx.Create(&l)
@@ -119,6 +130,10 @@ func (base *DnsDomainBaseImpl) Insert(dnsList []*entity.TbDnsBase) (num int64, e
 			return 0, r.Error
 		}
 		num += r.RowsAffected
+		if err = writeChangeLog(tx, op.newChangeLog(entity.ChangeOpAdd, l, "", 0, l.Ip, l.Port)); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
 	}
 	if err = tx.Commit().Error; err != nil {
 		return 0, err
@@ -128,63 +143,114 @@ func (base *DnsDomainBaseImpl) Insert(dnsList []*entity.TbDnsBase) (num int64, e
 
 // Delete 删除域名
 func (base *DnsDomainBaseImpl) Delete(tableName, app, domainName string, bkCloudId int64,
-	ins []string) (rowsAffected int64, err error) {
-	execSql := fmt.Sprintf("delete from %s where  app = '%s' and bk_cloud_id = '%d'",
-		tableName, app, bkCloudId)
-	if domainName != "" {
-		execSql = fmt.Sprintf("%s and domain_name = '%s'", execSql, domainName)
-	}
-	if len(ins) != 0 {
-		insStr := "''"
-		ipStr := "''"
-		for _, i := range ins {
-			if strings.HasSuffix(i, "#0") {
-				ip := strings.Split(i, "#")[0]
-				ipStr = fmt.Sprintf("%s,'%s'", ipStr, ip)
-			} else {
-				insStr = fmt.Sprintf("%s,'%s'", insStr, i)
-			}
+	ins []string, op OpInfo) (rowsAffected int64, err error) {
+	logger.Info(fmt.Sprintf("delete op:{table:%s, app:%s, domain:%s, bk_cloud_id:%d, ins:%+v}",
+		tableName, app, domainName, bkCloudId, ins))
 
-		}
-		insStr = strings.Trim(insStr, ",")
-		ipStr = strings.Trim(ipStr, ",")
-		execSql = fmt.Sprintf("%s and  (concat(ip,'#',port) in (%s) or ip in (%s))",
-			execSql, insStr, ipStr)
-	} else {
-		execSql = fmt.Sprintf("delete from %s where  domain_name = '%s' and app = '%s' and bk_cloud_id ='%d'",
-			tableName, domainName, app, bkCloudId)
+	// 先查出要删除的记录用于写变更记录，和删除放在同一个事务里，两者使用同一份条件
+	tx := dao.DnsDB.Begin()
+	var rows []entity.TbDnsBase
+	if err = deleteScope(tx, tableName, app, domainName, bkCloudId, ins).
+		Set("gorm:query_option", "FOR UPDATE").Find(&rows).Error; err != nil && !entity.IsNoRowFoundError(err) {
+		tx.Rollback()
+		return 0, err
 	}
-	logger.Info(fmt.Sprintf("delete sql:[%+v]", execSql))
-	r := dao.DnsDB.Exec(execSql)
-
+	r := deleteScope(tx, tableName, app, domainName, bkCloudId, ins).Delete(&entity.TbDnsBase{})
 	if r.Error != nil {
+		tx.Rollback()
 		return 0, r.Error
 	}
+	op = op.withBatchId()
+	for i := range rows {
+		d := &rows[i]
+		if err = writeChangeLog(tx, op.newChangeLog(entity.ChangeOpDelete, d, d.Ip, d.Port, "", 0)); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+	if err = tx.Commit().Error; err != nil {
+		return 0, err
+	}
 	return r.RowsAffected, nil
 }
 
+// deleteScope 删除条件。ins为空时删除整个域名，否则只删除匹配的实例，"ip#0"表示该ip的所有端口
+func deleteScope(tx *gorm.DB, tableName, app, domainName string, bkCloudId int64, ins []string) *gorm.DB {
+	db := tx.Table(tableName).Where("app = ? and bk_cloud_id = ?", app, bkCloudId)
+	if len(ins) == 0 {
+		return db.Where("domain_name = ?", domainName)
+	}
+	if domainName != "" {
+		db = db.Where("domain_name = ?", domainName)
+	}
+	insList := []string{""}
+	ipList := []string{""}
+	for _, i := range ins {
+		if strings.HasSuffix(i, "#0") {
+			ipList = append(ipList, strings.Split(i, "#")[0])
+		} else {
+			insList = append(insList, i)
+		}
+	}
+	return db.Where("(concat(ip,'#',port) in (?) or ip in (?))", insList, ipList)
+}
+
 // Update 更新单个域名
-func (base *DnsDomainBaseImpl) Update(d *entity.TbDnsBase, newIP string, newPort int) (rowsAffected int64, err error) {
+func (base *DnsDomainBaseImpl) Update(d *entity.TbDnsBase, newIP string, newPort int, op OpInfo) (
+	rowsAffected int64, err error) {
 	logger.Info(fmt.Sprintf("update op:{[%+v], newIp:%+v, nowPort:%+v}", d, newIP, newPort))
-	r := dao.DnsDB.Model(d).Update(map[string]interface{}{"ip": newIP, "port": newPort})
-	return r.RowsAffected, r.Error
+	oldIp, oldPort := d.Ip, d.Port
+	tx := dao.DnsDB.Begin()
+	r := tx.Model(d).Update(map[string]interface{}{"ip": newIP, "port": newPort,
+		"last_change_time": time.Now()})
+	if r.Error != nil {
+		tx.Rollback()
+		return 0, r.Error
+	}
+	if r.RowsAffected > 0 {
+		l := op.withBatchId().newChangeLog(entity.ChangeOpUpdate, d, oldIp, oldPort, newIP, newPort)
+		if err = writeChangeLog(tx, l); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+	if err = tx.Commit().Error; err != nil {
+		return 0, err
+	}
+	return r.RowsAffected, nil
 }
 
 // UpdateDomainBatch 批量更新域名
-func (base *DnsDomainBaseImpl) UpdateDomainBatch(bs []UpdateBatchDnsBase) (rowsAffected int64, err error) {
+func (base *DnsDomainBaseImpl) UpdateDomainBatch(bs []UpdateBatchDnsBase, op OpInfo) (rowsAffected int64, err error) {
 	rowsAffected = 0
 	tx := dao.DnsDB.Begin()
+	op = op.withBatchId()
 
 	for _, b := range bs {
 		logger.Info(fmt.Sprintf("update op:{[%+v]}", b))
+		var rows []entity.TbDnsBase
+		if err = tx.Set("gorm:query_option", "FOR UPDATE").
+			Where("app = ? and bk_cloud_id = ?", b.App, b.BkCloudId).
+			Where("domain_name = ? and ip = ? and port = ?", b.DomainName, b.OIp, b.OPort).
+			Find(&rows).Error; err != nil && !entity.IsNoRowFoundError(err) {
+			tx.Rollback()
+			return 0, err
+		}
 		r := tx.Model(&entity.TbDnsBase{}).Where("app = ? and bk_cloud_id = ?", b.App, b.BkCloudId).
 			Where("domain_name = ? and ip = ? and port = ?", b.DomainName, b.OIp, b.OPort).
-			Update(map[string]interface{}{"ip": b.NIp, "port": b.NPort})
+			Update(map[string]interface{}{"ip": b.NIp, "port": b.NPort, "last_change_time": time.Now()})
 		if r.Error != nil {
 			tx.Rollback()
 			return 0, r.Error
 		}
 		rowsAffected += r.RowsAffected
+		for i := range rows {
+			d := &rows[i]
+			if err = writeChangeLog(tx, op.newChangeLog(entity.ChangeOpUpdate, d, b.OIp, b.OPort, b.NIp, b.NPort)); err != nil {
+				tx.Rollback()
+				return 0, err
+			}
+		}
 	}
 	if err = tx.Commit().Error; err != nil {
 		return 0, err
@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"strconv"
+	"testing"
+
+	"bk-dnsapi/internal/domain/entity"
+)
+
+func TestDeleteScope(t *testing.T) {
+	cases := []struct {
+		name   string
+		domain string
+		ins    []string
+		want   []string
+	}{
+		{"whole domain", "a.db.", nil, []string{"1.1.1.1#3306", "1.1.1.1#3307", "2.2.2.2#3306"}},
+		{"instance", "a.db.", []string{"1.1.1.1#3306"}, []string{"1.1.1.1#3306"}},
+		{"all ports of ip", "a.db.", []string{"1.1.1.1#0"}, []string{"1.1.1.1#3306", "1.1.1.1#3307"}},
+		{"instance of any domain", "", []string{"1.1.1.1#3306", "2.2.2.2#0"},
+			[]string{"1.1.1.1#3306", "1.1.1.1#3306", "2.2.2.2#3306"}},
+		{"no match", "a.db.", []string{"3.3.3.3#3306"}, nil},
+		// 单引号作为参数传递，不会拼进sql
+		{"quote in domain", "a.db.' or '1'='1", nil, nil},
+	}
+	for _, c := range cases {
+		db := newTestDB(t)
+		addRecord(t, db, "1.1.1.1", 3306)
+		addRecord(t, db, "1.1.1.1", 3307)
+		addRecord(t, db, "2.2.2.2", 3306)
+		other := &entity.TbDnsBase{App: "app", DomainName: "b.db.", Ip: "1.1.1.1", Port: 3306, Status: "1"}
+		otherApp := &entity.TbDnsBase{App: "app2", DomainName: "a.db.", Ip: "1.1.1.1", Port: 3306, Status: "1"}
+		if err := db.Create(other).Create(otherApp).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		var rows []entity.TbDnsBase
+		if err := deleteScope(db, "tb_dns_base", "app", c.domain, 0, c.ins).
+			Order("ip, port").Find(&rows).Error; err != nil {
+			t.Fatalf("%s: select failed: %v", c.name, err)
+		}
+		r := deleteScope(db, "tb_dns_base", "app", c.domain, 0, c.ins).Delete(&entity.TbDnsBase{})
+		if r.Error != nil {
+			t.Fatalf("%s: delete failed: %v", c.name, r.Error)
+		}
+		var got []string
+		for _, row := range rows {
+			got = append(got, row.Ip+"#"+strconv.Itoa(row.Port))
+		}
+		if len(got) != len(c.want) || int(r.RowsAffected) != len(c.want) {
+			t.Errorf("%s: selected %v, deleted %d, want %v", c.name, got, r.RowsAffected, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("%s: selected %v, want %v", c.name, got, c.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package domain
+
+import (
+	"bk-dnsapi/internal/dao"
+	"bk-dnsapi/internal/domain/entity"
+	"fmt"
+	"strconv"
+	"time"
+
+	"bk-dnsapi/pkg/logger"
+
+	"github.com/jinzhu/gorm"
+)
+
+// SourceRevert 回滚产生的变更记录来源
+const SourceRevert = "revert"
+
+// OpInfo 变更操作信息，写入变更记录
+type OpInfo struct {
+	Operator string
+	Source   string
+	BatchId  string
+}
+
+// NewOpInfo 同一次请求的变更使用同一个batch_id，便于整体查看
+func NewOpInfo(operator, source string) OpInfo {
+	return OpInfo{Operator: operator, Source: source}.withBatchId()
+}
+
+func (op OpInfo) withBatchId() OpInfo {
+	if op.BatchId == "" {
+		op.BatchId = strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return op
+}
+
+func (op OpInfo) newChangeLog(opType string, d *entity.TbDnsBase, beforeIp string, beforePort int,
+	afterIp string, afterPort int) *entity.TbDnsChangeLog {
+	return &entity.TbDnsChangeLog{
+		BatchId:    op.BatchId,
+		App:        d.App,
+		DomainName: d.DomainName,
+		BkCloudId:  d.BkCloudId,
+		OpType:     opType,
+		BeforeIp:   beforeIp,
+		BeforePort: beforePort,
+		AfterIp:    afterIp,
+		AfterPort:  afterPort,
+		Manager:    d.Manager,
+		Remark:     d.Remark,
+		Operator:   op.Operator,
+		Source:     op.Source,
+		ChangeTime: time.Now(),
+	}
+}
+
+// writeChangeLog 写变更记录，需要和变更本身在同一个事务里
+func writeChangeLog(tx *gorm.DB, l *entity.TbDnsChangeLog) error {
+	logger.Info(fmt.Sprintf("change log:[%+v]", l))
+	return tx.Create(l).Error
+}
+
+// ChangeLogQuery 变更记录查询条件
+type ChangeLogQuery struct {
+	App         string
+	BkCloudId   int64
+	DomainNames []string
+	OpType      string
+	BeginTime   time.Time
+	EndTime     time.Time
+	Limit       int
+}
+
+// DnsChangeLogRepo 变更记录接口方法
+type DnsChangeLogRepo interface {
+	Get(q ChangeLogQuery) ([]entity.TbDnsChangeLog, error)
+	Revert(app string, bkCloudId int64, domainNames []string, t time.Time, op OpInfo) (int64, []int64, error)
+}
+
+// DnsChangeLogImpl 变更记录实现类
+type DnsChangeLogImpl struct {
+}
+
+// DnsChangeLogResource 构造类
+func DnsChangeLogResource() DnsChangeLogRepo {
+	return &DnsChangeLogImpl{}
+}
+
+// Get 按域名和时间范围查询变更记录，按时间倒序
+func (dcl *DnsChangeLogImpl) Get(q ChangeLogQuery) ([]entity.TbDnsChangeLog, error) {
+	db := dao.DnsDB.Model(&entity.TbDnsChangeLog{})
+	if q.App != "" {
+		db = db.Where("app = ?", q.App)
+	}
+	db = db.Where("bk_cloud_id = ?", q.BkCloudId)
+	if len(q.DomainNames) != 0 {
+		db = db.Where("domain_name in (?)", q.DomainNames)
+	}
+	if q.OpType != "" {
+		db = db.Where("op_type = ?", q.OpType)
+	}
+	if !q.BeginTime.IsZero() {
+		db = db.Where("change_time >= ?", q.BeginTime)
+	}
+	if !q.EndTime.IsZero() {
+		db = db.Where("change_time <= ?", q.EndTime)
+	}
+	if q.Limit > 0 {
+		db = db.Limit(q.Limit)
+	}
+
+	var l []entity.TbDnsChangeLog
+	if err := db.Order("uid desc").Find(&l).Error; err != nil && !entity.IsNoRowFoundError(err) {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Revert 把域名回滚到t时刻的状态
+// 按变更倒序依次执行逆操作，整体在一个事务里，回滚本身也会写变更记录
+// 目标状态已存在而未执行的变更记录id通过skipped返回，调用方据此判断是否完整回滚
+func (dcl *DnsChangeLogImpl) Revert(app string, bkCloudId int64, domainNames []string, t time.Time,
+	op OpInfo) (rowsAffected int64, skipped []int64, err error) {
+	tx := dao.DnsDB.Begin()
+	var logs []entity.TbDnsChangeLog
+	if err = tx.Set("gorm:query_option", "FOR UPDATE").
+		Where("app = ? and bk_cloud_id = ? and domain_name in (?) and change_time > ?",
+			app, bkCloudId, domainNames, t).
+		Order("uid desc").Find(&logs).Error; err != nil && !entity.IsNoRowFoundError(err) {
+		tx.Rollback()
+		return 0, nil, err
+	}
+
+	if rowsAffected, skipped, err = revertLogs(tx, logs, op.withBatchId()); err != nil {
+		tx.Rollback()
+		return 0, nil, err
+	}
+	if err = tx.Commit().Error; err != nil {
+		return 0, nil, err
+	}
+	return rowsAffected, skipped, nil
+}
+
+// revertLogs 依次回滚变更记录，返回影响行数和被跳过的变更记录id
+func revertLogs(tx *gorm.DB, logs []entity.TbDnsChangeLog, op OpInfo) (rowsAffected int64, skipped []int64,
+	err error) {
+	for _, l := range logs {
+		logger.Info(fmt.Sprintf("revert change log:[%+v]", l))
+		n, err := revertOne(tx, l, op)
+		if err != nil {
+			return 0, nil, err
+		}
+		if n == 0 {
+			logger.Warn(fmt.Sprintf("change log[%d] skipped, target state already exists", l.Uid))
+			skipped = append(skipped, l.Uid)
+		}
+		rowsAffected += n
+	}
+	return rowsAffected, skipped, nil
+}
+
+// revertOne 执行单条变更的逆操作。目标状态已经存在时跳过并返回0，保证重复回滚不报错
+func revertOne(tx *gorm.DB, l entity.TbDnsChangeLog, op OpInfo) (int64, error) {
+	d := &entity.TbDnsBase{
+		App:        l.App,
+		DomainName: l.DomainName,
+		BkCloudId:  l.BkCloudId,
+		Manager:    l.Manager,
+		Remark:     l.Remark,
+	}
+	match := func(ip string, port int) *gorm.DB {
+		return tx.Model(&entity.TbDnsBase{}).Where("app = ? and bk_cloud_id = ?", l.App, l.BkCloudId).
+			Where("domain_name = ? and ip = ? and port = ?", l.DomainName, ip, port)
+	}
+
+	switch l.OpType {
+	case entity.ChangeOpAdd:
+		r := match(l.AfterIp, l.AfterPort).Delete(&entity.TbDnsBase{})
+		if r.Error != nil || r.RowsAffected == 0 {
+			return 0, r.Error
+		}
+		return r.RowsAffected, writeChangeLog(tx,
+			op.newChangeLog(entity.ChangeOpDelete, d, l.AfterIp, l.AfterPort, "", 0))
+	case entity.ChangeOpDelete:
+		var cnt int
+		if err := match(l.BeforeIp, l.BeforePort).Count(&cnt).Error; err != nil || cnt > 0 {
+			return 0, err
+		}
+		d.Ip = l.BeforeIp
+		d.Port = l.BeforePort
+		d.StartTime = time.Now()
+		d.LastChangeTime = time.Now()
+		d.Status = "1"
+		r := tx.Create(d)
+		if r.Error != nil {
+			return 0, r.Error
+		}
+		return r.RowsAffected, writeChangeLog(tx,
+			op.newChangeLog(entity.ChangeOpAdd, d, "", 0, l.BeforeIp, l.BeforePort))
+	case entity.ChangeOpUpdate:
+		r := match(l.AfterIp, l.AfterPort).
+			Update(map[string]interface{}{"ip": l.BeforeIp, "port": l.BeforePort, "last_change_time": time.Now()})
+		if r.Error != nil || r.RowsAffected == 0 {
+			return 0, r.Error
+		}
+		return r.RowsAffected, writeChangeLog(tx,
+			op.newChangeLog(entity.ChangeOpUpdate, d, l.AfterIp, l.AfterPort, l.BeforeIp, l.BeforePort))
+	default:
+		return 0, fmt.Errorf("unknown op_type[%s] of change log[%d]", l.OpType, l.Uid)
+	}
+}
@@ -0,0 +1,211 @@
+package domain
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"bk-dnsapi/internal/domain/entity"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/mattn/go-sqlite3"
+)
+
+func init() {
+	// sqlite没有mysql的concat，注册一个同名函数供删除条件使用
+	sql.Register("sqlite3_concat", &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("concat", func(args ...interface{}) string {
+				var s string
+				for _, a := range args {
+					s += fmt.Sprint(a)
+				}
+				return s
+			}, true)
+		},
+	})
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	sqlDB, err := sql.Open("sqlite3_concat", ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite failed: %v", err)
+	}
+	// 内存库每个连接独立，只保留一个连接
+	sqlDB.SetMaxOpenConns(1)
+	db, err := gorm.Open("sqlite3", sqlDB)
+	if err != nil {
+		t.Fatalf("open sqlite failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if err = db.AutoMigrate(&entity.TbDnsBase{}, &entity.TbDnsChangeLog{}).Error; err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	return db
+}
+
+func addRecord(t *testing.T, db *gorm.DB, ip string, port int) {
+	d := &entity.TbDnsBase{App: "app", DomainName: "a.db.", BkCloudId: 0, Ip: ip, Port: port, Status: "1"}
+	if err := db.Create(d).Error; err != nil {
+		t.Fatalf("create record failed: %v", err)
+	}
+}
+
+func records(t *testing.T, db *gorm.DB) []entity.TbDnsBase {
+	var l []entity.TbDnsBase
+	if err := db.Where("domain_name = ?", "a.db.").Order("ip, port").Find(&l).Error; err != nil {
+		t.Fatalf("query records failed: %v", err)
+	}
+	return l
+}
+
+func changeLog(opType, beforeIp string, beforePort int, afterIp string, afterPort int) entity.TbDnsChangeLog {
+	return entity.TbDnsChangeLog{
+		Uid: 1, App: "app", DomainName: "a.db.", BkCloudId: 0, OpType: opType,
+		BeforeIp: beforeIp, BeforePort: beforePort, AfterIp: afterIp, AfterPort: afterPort,
+		ChangeTime: time.Now(),
+	}
+}
+
+func TestRevertOne(t *testing.T) {
+	op := NewOpInfo("tester", SourceRevert)
+
+	t.Run("add", func(t *testing.T) {
+		db := newTestDB(t)
+		addRecord(t, db, "1.1.1.1", 3306)
+		n, err := revertOne(db, changeLog(entity.ChangeOpAdd, "", 0, "1.1.1.1", 3306), op)
+		if err != nil || n != 1 {
+			t.Fatalf("revert add: n=%d err=%v", n, err)
+		}
+		if l := records(t, db); len(l) != 0 {
+			t.Fatalf("record should be deleted, got %+v", l)
+		}
+		// 目标状态已存在，重复回滚跳过
+		n, err = revertOne(db, changeLog(entity.ChangeOpAdd, "", 0, "1.1.1.1", 3306), op)
+		if err != nil || n != 0 {
+			t.Fatalf("revert add again: n=%d err=%v", n, err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		db := newTestDB(t)
+		n, err := revertOne(db, changeLog(entity.ChangeOpDelete, "1.1.1.1", 3306, "", 0), op)
+		if err != nil || n != 1 {
+			t.Fatalf("revert delete: n=%d err=%v", n, err)
+		}
+		l := records(t, db)
+		if len(l) != 1 || l[0].Ip != "1.1.1.1" || l[0].Port != 3306 || l[0].App != "app" {
+			t.Fatalf("record should be restored, got %+v", l)
+		}
+		n, err = revertOne(db, changeLog(entity.ChangeOpDelete, "1.1.1.1", 3306, "", 0), op)
+		if err != nil || n != 0 {
+			t.Fatalf("revert delete again: n=%d err=%v", n, err)
+		}
+		if l = records(t, db); len(l) != 1 {
+			t.Fatalf("record should not be duplicated, got %+v", l)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		db := newTestDB(t)
+		addRecord(t, db, "2.2.2.2", 3307)
+		n, err := revertOne(db, changeLog(entity.ChangeOpUpdate, "1.1.1.1", 3306, "2.2.2.2", 3307), op)
+		if err != nil || n != 1 {
+			t.Fatalf("revert update: n=%d err=%v", n, err)
+		}
+		l := records(t, db)
+		if len(l) != 1 || l[0].Ip != "1.1.1.1" || l[0].Port != 3306 {
+			t.Fatalf("record should be updated back, got %+v", l)
+		}
+		n, err = revertOne(db, changeLog(entity.ChangeOpUpdate, "1.1.1.1", 3306, "2.2.2.2", 3307), op)
+		if err != nil || n != 0 {
+			t.Fatalf("revert update again: n=%d err=%v", n, err)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		db := newTestDB(t)
+		if _, err := revertOne(db, changeLog("rename", "", 0, "", 0), op); err == nil {
+			t.Fatal("unknown op_type should fail")
+		}
+	})
+}
+
+// TestRevertOneChangeLog 回滚本身要写反向的变更记录，且同一批次共用batch_id
+func TestRevertOneChangeLog(t *testing.T) {
+	db := newTestDB(t)
+	op := NewOpInfo("tester", SourceRevert)
+	addRecord(t, db, "2.2.2.2", 3307)
+	// 按变更倒序回滚: add 1.1.1.1 -> update 1.1.1.1=>2.2.2.2
+	logs := []entity.TbDnsChangeLog{
+		changeLog(entity.ChangeOpUpdate, "1.1.1.1", 3306, "2.2.2.2", 3307),
+		changeLog(entity.ChangeOpAdd, "", 0, "1.1.1.1", 3306),
+	}
+	for _, l := range logs {
+		if _, err := revertOne(db, l, op); err != nil {
+			t.Fatalf("revert %s failed: %v", l.OpType, err)
+		}
+	}
+	if l := records(t, db); len(l) != 0 {
+		t.Fatalf("domain should be empty after revert, got %+v", l)
+	}
+
+	var written []entity.TbDnsChangeLog
+	if err := db.Order("uid").Find(&written).Error; err != nil {
+		t.Fatalf("query change log failed: %v", err)
+	}
+	if len(written) != 2 {
+		t.Fatalf("expect 2 change logs, got %d", len(written))
+	}
+	want := []struct {
+		opType            string
+		beforeIp, afterIp string
+	}{
+		{entity.ChangeOpUpdate, "2.2.2.2", "1.1.1.1"},
+		{entity.ChangeOpDelete, "1.1.1.1", ""},
+	}
+	for i, w := range want {
+		l := written[i]
+		if l.OpType != w.opType || l.BeforeIp != w.beforeIp || l.AfterIp != w.afterIp {
+			t.Errorf("change log %d: got %s %s->%s, want %s %s->%s",
+				i, l.OpType, l.BeforeIp, l.AfterIp, w.opType, w.beforeIp, w.afterIp)
+		}
+		if l.BatchId != op.BatchId || l.Source != SourceRevert || l.Operator != "tester" {
+			t.Errorf("change log %d: unexpected op info %+v", i, l)
+		}
+	}
+}
+
+// TestRevertLogsSkipped 目标状态已存在的变更记录要作为跳过返回，不能当作回滚成功
+func TestRevertLogsSkipped(t *testing.T) {
+	db := newTestDB(t)
+	op := NewOpInfo("tester", SourceRevert)
+	addRecord(t, db, "1.1.1.1", 3306)
+	logs := []entity.TbDnsChangeLog{
+		changeLog(entity.ChangeOpAdd, "", 0, "1.1.1.1", 3306),
+		// 记录已被手工删除
+		changeLog(entity.ChangeOpAdd, "", 0, "2.2.2.2", 3306),
+		changeLog(entity.ChangeOpUpdate, "3.3.3.3", 3306, "4.4.4.4", 3306),
+	}
+	for i := range logs {
+		logs[i].Uid = int64(i + 1)
+	}
+	n, skipped, err := revertLogs(db, logs, op)
+	if err != nil {
+		t.Fatalf("revert failed: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("rows affected %d, want 1", n)
+	}
+	if want := []int64{2, 3}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("skipped %v, want %v", skipped, want)
+	}
+
+	n, skipped, err = revertLogs(db, logs[:1], op)
+	if err != nil || n != 0 || !reflect.DeepEqual(skipped, []int64{1}) {
+		t.Errorf("revert again: n=%d skipped=%v err=%v", n, skipped, err)
+	}
+}
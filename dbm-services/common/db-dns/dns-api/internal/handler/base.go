@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"bk-dnsapi/internal/domain/repo/domain"
 	"bk-dnsapi/pkg/errno"
 	"bk-dnsapi/pkg/logger"
 	"fmt"
@@ -22,10 +23,13 @@ func (h *Handler) Routes() []*gin.RouteInfo {
 		{Method: http.MethodPost, Path: "/domain", HandlerFunc: h.UpdateDns},
 		{Method: http.MethodPost, Path: "/domain/batch", HandlerFunc: h.UpdateBatchDns},
 		{Method: http.MethodPost, Path: "/config", HandlerFunc: h.UpdateConfig},
+		{Method: http.MethodPost, Path: "/domain/revert", HandlerFunc: h.RevertDns},
 
 		{Method: http.MethodGet, Path: "/domain", HandlerFunc: h.GetDns},
 		{Method: http.MethodGet, Path: "/domain/all", HandlerFunc: h.GetAllDns},
+		{Method: http.MethodGet, Path: "/domain/deleted", HandlerFunc: h.GetDeletedDns},
 		{Method: http.MethodGet, Path: "/config/all", HandlerFunc: h.GetAllConfig},
+		{Method: http.MethodGet, Path: "/domain/changelog", HandlerFunc: h.GetChangeLog},
 	}
 }
 
@@ -52,3 +56,11 @@ func SendResponse(c *gin.Context, err error, data Data) {
 		Data:    data,
 	})
 }
+
+// getOpInfo 变更操作信息，没有传source时记录请求来源ip
+func getOpInfo(c *gin.Context, operator, source string) domain.OpInfo {
+	if source == "" {
+		source = c.ClientIP()
+	}
+	return domain.NewOpInfo(operator, source)
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"bk-dnsapi/internal/domain/repo/domain"
+	"bk-dnsapi/pkg/tools"
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"bk-dnsapi/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	timeLayout          = "2006-01-02 15:04:05"
+	defaultChangeLogNum = 1000
+)
+
+// GetChangeLog 按域名和时间范围查询变更记录
+func (h *Handler) GetChangeLog(c *gin.Context) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error(fmt.Sprintf("panic error:%v,stack:%s", r, string(debug.Stack())))
+			SendResponse(c,
+				fmt.Errorf("panic error:%v", r),
+				Data{})
+		}
+	}()
+
+	var err error
+	var errMsg string
+	q := domain.ChangeLogQuery{
+		App:         strings.TrimSpace(c.Query("app")),
+		DomainNames: tools.TransZeroStrings(c.QueryArray("domain_name")),
+		Limit:       defaultChangeLogNum,
+	}
+	if len(q.DomainNames) == 0 {
+		SendResponse(c, fmt.Errorf("param must have [domain_name]"), Data{})
+		return
+	}
+	for i, d := range q.DomainNames {
+		if q.DomainNames[i], err = tools.CheckDomain(d); err != nil {
+			errMsg += err.Error() + "\r\n"
+		}
+	}
+	if q.BkCloudId, err = strconv.ParseInt(tools.TransZeroString(c.Query("bk_cloud_id")), 10, 64); err != nil {
+		errMsg += fmt.Sprintf("bk_cloud_id format error:%s", err.Error()) + "\r\n"
+	}
+	if q.BeginTime, err = parseTimeParam(c.Query("begin_time")); err != nil {
+		errMsg += fmt.Sprintf("begin_time format error:%s", err.Error()) + "\r\n"
+	}
+	if q.EndTime, err = parseTimeParam(c.Query("end_time")); err != nil {
+		errMsg += fmt.Sprintf("end_time format error:%s", err.Error()) + "\r\n"
+	}
+	if limit := strings.TrimSpace(c.Query("limit")); limit != "" {
+		if q.Limit, err = strconv.Atoi(limit); err != nil || q.Limit <= 0 {
+			errMsg += fmt.Sprintf("limit[%s] format error", limit) + "\r\n"
+		}
+	}
+	if errMsg != "" {
+		SendResponse(c, fmt.Errorf(errMsg), Data{})
+		return
+	}
+
+	logger.Info(fmt.Sprintf("query change log. params[%+v]", q))
+	rs, err := domain.DnsChangeLogResource().Get(q)
+	if err != nil {
+		SendResponse(c, err, Data{})
+		return
+	}
+	SendResponse(c, nil, Data{rs, int64(len(rs))})
+}
+
+// DnsRevertReqParam 回滚参数
+type DnsRevertReqParam struct {
+	App         string   `json:"app,required"`
+	BkCloudId   int64    `json:"bk_cloud_id"`
+	DomainNames []string `json:"domain_names,required"`
+	RevertTime  string   `json:"revert_time,required"`
+	Operator    string   `json:"operator"`
+	Source      string   `json:"source"`
+}
+
+// RevertResult 回滚结果，SkippedUids为目标状态已存在而跳过的变更记录id
+type RevertResult struct {
+	SkippedUids []int64 `json:"skipped_uids"`
+}
+
+// RevertDns 把域名回滚到revert_time时刻的状态
+func (h *Handler) RevertDns(c *gin.Context) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error(fmt.Sprintf("panic error:%v,stack:%s", r, string(debug.Stack())))
+			SendResponse(c,
+				fmt.Errorf("panic error:%v", r),
+				Data{})
+		}
+	}()
+
+	var revertParam DnsRevertReqParam
+	err := c.BindJSON(&revertParam)
+	if err != nil {
+		SendResponse(c, err, Data{})
+		return
+	}
+	logger.Info(fmt.Sprintf("revert dns begin, param [%+v]", revertParam))
+
+	var errMsg string
+	if revertParam.App == "" || len(revertParam.DomainNames) == 0 {
+		errMsg += "param must have [app and domain_names]" + "\r\n"
+	}
+	for i, d := range revertParam.DomainNames {
+		if revertParam.DomainNames[i], err = tools.CheckDomain(d); err != nil {
+			errMsg += err.Error() + "\r\n"
+		}
+	}
+	revertTime, err := parseTimeParam(revertParam.RevertTime)
+	if err != nil || revertTime.IsZero() {
+		errMsg += fmt.Sprintf("revert_time[%s] format error", revertParam.RevertTime) + "\r\n"
+	} else if revertTime.After(time.Now()) {
+		errMsg += fmt.Sprintf("revert_time[%s] is in the future", revertParam.RevertTime) + "\r\n"
+	}
+	if errMsg != "" {
+		SendResponse(c, fmt.Errorf(errMsg), Data{})
+		return
+	}
+
+	if revertParam.Source == "" {
+		revertParam.Source = domain.SourceRevert
+	}
+	rowsAffected, skipped, err := domain.DnsChangeLogResource().Revert(revertParam.App, revertParam.BkCloudId,
+		revertParam.DomainNames, revertTime, getOpInfo(c, revertParam.Operator, revertParam.Source))
+	_, _ = domain.DnsConfigResource().UpdateLaseUpdateTime()
+	SendResponse(c, err, Data{RowsNum: rowsAffected, Detail: RevertResult{SkippedUids: skipped}})
+}
+
+// parseTimeParam 解析时间参数，为空返回零值
+func parseTimeParam(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation(timeLayout, s, time.Local)
+}
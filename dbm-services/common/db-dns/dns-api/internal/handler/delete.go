@@ -17,6 +17,8 @@ type DnsBaseDelReqParam struct {
 	// Appid 	int64		`json:"appid"`
 	App       string `json:"app,required"`
 	BkCloudId int64  `json:"bk_cloud_id"`
+	Operator  string `json:"operator"`
+	Source    string `json:"source"`
 	Domains   []struct {
 		DomainName string   `json:"domain_name"`
 		Instances  []string `json:"instances,required"`
@@ -74,25 +76,27 @@ func (h *Handler) DelDns(c *gin.Context) {
 			if !strings.Contains(ins, "#") {
 				ins += "#0"
 			}
-			_, _, err := tools.GetIpPortByIns(ins)
+			ip, port, err := tools.GetIpPortByIns(ins)
 			if err != nil {
 				errMsg += err.Error() + "\r\n"
 				continue
 			}
-			ips = append(ips, ins)
+			// 使用规范化后的ip，保证ipv6不同写法都能匹配
+			ips = append(ips, fmt.Sprintf("%s#%d", ip, port))
 		}
 
 		domainList = append(domainList, domains.DomainName)
 		ipsList = append(ipsList, ips)
 	}
 	if errMsg != "" {
-		SendResponse(c, err, Data{})
+		SendResponse(c, fmt.Errorf(errMsg), Data{})
 		return
 	}
 
+	op := getOpInfo(c, delParam.Operator, delParam.Source)
 	for i := 0; i < len(domainList); i++ {
 		rowsNum, _ := domain.DnsDomainResource().Delete(dnsBase.TableName(), delParam.App,
-			domainList[i], delParam.BkCloudId, ipsList[i])
+			domainList[i], delParam.BkCloudId, ipsList[i], op)
 		rowsAffected += rowsNum
 	}
 	_, _ = domain.DnsConfigResource().UpdateLaseUpdateTime()
@@ -19,6 +19,8 @@ type DnsBasePutReqParam struct {
 	// Appid 	int64		`json:"appid"`
 	App       string `json:"app,required"`
 	BkCloudId int64  `json:"bk_cloud_id"`
+	Operator  string `json:"operator"`
+	Source    string `json:"source"`
 	Domains   []struct {
 		DomainName string   `json:"domain_name"`
 		Instances  []string `json:"instances,required"`
@@ -99,7 +101,8 @@ func (h *Handler) AddDns(c *gin.Context) {
 	info, _ := json.Marshal(dnsBaseList)
 	logger.Info(fmt.Sprintf("add insert begin exec, param [%+v]", string(info)))
 
-	rowsAffected, err := domain.DnsDomainResource().Insert(dnsBaseList)
+	rowsAffected, err := domain.DnsDomainResource().Insert(dnsBaseList,
+		getOpInfo(c, addParam.Operator, addParam.Source))
 	_, _ = domain.DnsConfigResource().UpdateLaseUpdateTime()
 	SendResponse(c, err, Data{RowsNum: rowsAffected})
 }
@@ -6,7 +6,9 @@ import (
 	"bk-dnsapi/pkg/tools"
 	"fmt"
 	"runtime/debug"
+	"strconv"
 	"strings"
+	"time"
 
 	"bk-dnsapi/pkg/logger"
 
@@ -100,6 +102,7 @@ func (h *Handler) GetDns(c *gin.Context) {
 }
 
 // GetAllDns 查询所有域名。共reload程序用
+// 传入last_change_time(格式2006-01-02 15:04:05)时只返回该时间之后变更的记录，用于增量刷新
 func (h *Handler) GetAllDns(c *gin.Context) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -111,11 +114,20 @@ func (h *Handler) GetAllDns(c *gin.Context) {
 	}()
 
 	bkCloudId := tools.TransZeroString(c.Query("bk_cloud_id"))
-	columns := []string{"ip", "domain_name"}
-	logger.Info(fmt.Sprintf("get all dns  query begin. bk_cloud_id is %v", bkCloudId))
+	lastChangeTime := strings.TrimSpace(c.Query("last_change_time"))
+	columns := []string{"uid", "ip", "port", "domain_name", "last_change_time"}
+	logger.Info(fmt.Sprintf("get all dns  query begin. bk_cloud_id is %v, last_change_time is %v",
+		bkCloudId, lastChangeTime))
 
 	params := make(map[string]interface{})
 	params["bk_cloud_id"] = bkCloudId
+	if lastChangeTime != "" {
+		if _, err := time.ParseInLocation("2006-01-02 15:04:05", lastChangeTime, time.Local); err != nil {
+			SendResponse(c, fmt.Errorf("last_change_time format error:%s", err.Error()), Data{})
+			return
+		}
+		params["last_change_time"] = lastChangeTime
+	}
 	rs, err := domain.DnsDomainResource().Get(params, columns)
 	if err != nil {
 		SendResponse(c, err, Data{})
@@ -127,6 +139,43 @@ func (h *Handler) GetAllDns(c *gin.Context) {
 
 }
 
+// GetDeletedDns 查询last_change_time之后删除的域名记录。共reload程序增量刷新用
+// 增量查询tb_dns_base无法感知删除，删除记录从变更记录表中获取
+func (h *Handler) GetDeletedDns(c *gin.Context) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error(fmt.Sprintf("panic error:%v,stack:%s", r, string(debug.Stack())))
+			SendResponse(c,
+				fmt.Errorf("panic error:%v", r),
+				Data{})
+		}
+	}()
+
+	bkCloudId, err := strconv.ParseInt(tools.TransZeroString(c.Query("bk_cloud_id")), 10, 64)
+	if err != nil {
+		SendResponse(c, fmt.Errorf("bk_cloud_id format error:%s", err.Error()), Data{})
+		return
+	}
+	lastChangeTime, err := parseTimeParam(c.Query("last_change_time"))
+	if err != nil || lastChangeTime.IsZero() {
+		SendResponse(c, fmt.Errorf("param must have [last_change_time] with format %s", timeLayout), Data{})
+		return
+	}
+	logger.Info(fmt.Sprintf("get deleted dns query begin. bk_cloud_id is %v, last_change_time is %v",
+		bkCloudId, lastChangeTime))
+
+	rs, err := domain.DnsChangeLogResource().Get(domain.ChangeLogQuery{
+		BkCloudId: bkCloudId,
+		OpType:    entity.ChangeOpDelete,
+		BeginTime: lastChangeTime,
+	})
+	if err != nil {
+		SendResponse(c, err, Data{})
+		return
+	}
+	SendResponse(c, nil, Data{rs, int64(len(rs))})
+}
+
 // GetAllConfig 查询所有config表配置
 func (h *Handler) GetAllConfig(c *gin.Context) {
 	defer func() {
@@ -17,6 +17,8 @@ type DnsBasePostReqParam struct {
 	BkCloudId  int64  `json:"bk_cloud_id"`
 	Instance   string `json:"instance,required"`
 	DomainName string `json:"domain_name,required"`
+	Operator   string `json:"operator"`
+	Source     string `json:"source"`
 	Set        struct {
 		Instance string `json:"instance,required"`
 	} `json:"set,required"`
@@ -27,6 +29,8 @@ type DnsBaseBatchPostReqParam struct {
 	App        string `json:"app,required"`
 	DomainName string `json:"domain_name,required"`
 	BkCloudId  int64  `json:"bk_cloud_id"`
+	Operator   string `json:"operator"`
+	Source     string `json:"source"`
 	Sets       []struct {
 		OldInstance string `json:"old_instance,required"`
 		NewInstance string `json:"new_instance,required"`
@@ -90,7 +94,8 @@ func (h *Handler) UpdateDns(c *gin.Context) {
 	}{App: updateParam.App, DomainName: updateParam.DomainName, OIp: ip,
 		OPort: port, NIp: newIp, NPort: newPort, BkCloudId: updateParam.BkCloudId})
 
-	rowsAffected, err := domain.DnsDomainResource().UpdateDomainBatch(batchDnsBases)
+	rowsAffected, err := domain.DnsDomainResource().UpdateDomainBatch(batchDnsBases,
+		getOpInfo(c, updateParam.Operator, updateParam.Source))
 	_, _ = domain.DnsConfigResource().UpdateLaseUpdateTime()
 	SendResponse(c, err, Data{
 		Detail:  nil,
@@ -149,7 +154,8 @@ func (h *Handler) UpdateBatchDns(c *gin.Context) {
 		return
 	}
 
-	rowsAffected, err := domain.DnsDomainResource().UpdateDomainBatch(batchDnsBases)
+	rowsAffected, err := domain.DnsDomainResource().UpdateDomainBatch(batchDnsBases,
+		getOpInfo(c, updateParam.Operator, updateParam.Source))
 	_, _ = domain.DnsConfigResource().UpdateLaseUpdateTime()
 
 	SendResponse(c, err, Data{
@@ -2,6 +2,7 @@ package tools
 
 import (
 	"fmt"
+	"net"
 	"regexp"
 	"strconv"
 	"strings"
@@ -26,58 +27,69 @@ func CheckDomain(domain string) (string, error) {
 	}
 }
 
-// CheckIp 检查ip正则
+// CheckIp 检查ip格式，支持ipv4和ipv6，返回规范化后的ip
+// ipv4映射的ipv6写法(::ffff:1.1.1.1)同时落在A和AAAA记录上，不允许使用
 func CheckIp(ip string) (string, error) {
 	ip = strings.TrimSpace(ip)
-	var pattern string = `^(\d+)\.(\d+)\.(\d+)\.(\d+)$`
-	if isIp, err := regexp.MatchString(pattern, ip); err != nil {
-		return ip, err
-	} else {
-		if isIp {
-			return ip, nil
-		} else {
-			return "", fmt.Errorf("ip[%s] format error", ip)
-		}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return "", fmt.Errorf("ip[%s] format error", ip)
 	}
+	if parsed.To4() != nil && strings.Contains(ip, ":") {
+		return "", fmt.Errorf("ip[%s] is ipv4-mapped ipv6 address, use ipv4 format instead", ip)
+	}
+	return parsed.String(), nil
+}
+
+// IsIpv6 是否为ipv6地址
+func IsIpv6(ip string) bool {
+	parsed := net.ParseIP(strings.TrimSpace(ip))
+	return parsed != nil && parsed.To4() == nil
 }
 
-// CheckInstance TODO
+// CheckInstance 检查ip#port格式，返回规范化后的实例
 func CheckInstance(instance string) (string, error) {
 	instance = strings.TrimSpace(instance)
-	var pattern string = `^(\d+)\.(\d+)\.(\d+)\.(\d+)#(\d+)$`
-	if isInstance, err := regexp.MatchString(pattern, instance); err != nil {
+	ip, port, err := splitInstance(instance)
+	if err != nil {
 		return "", err
-	} else {
-		if isInstance {
-			return instance, nil
-		} else {
-			return "", fmt.Errorf("instance[%s] format error", instance)
-		}
 	}
+	return fmt.Sprintf("%s#%d", ip, port), nil
 }
 
 // GetIpPortByIns 拆分ip,port
 func GetIpPortByIns(ins string) (ip string, port int, err error) {
-	if strings.Contains(ins, "#") {
-		ins, err = CheckInstance(ins)
-		if err != nil {
-			return "", 0, err
-		}
-		// ip格式错误
-		ip, err = CheckIp(strings.Split(ins, "#")[0])
-		if err != nil {
-			return "", 0, err
-		}
-		// 端口格式错误
-		port, err = strconv.Atoi(strings.Split(ins, "#")[1])
-		if err != nil {
-			return "", 0, err
-		}
-	} else {
-		// 必须带端口
+	// 必须带端口
+	if !strings.Contains(ins, "#") {
 		return "", 0, fmt.Errorf("ins[%s] format not like ip#port", ins)
 	}
-	return
+	return splitInstance(strings.TrimSpace(ins))
+}
+
+func splitInstance(instance string) (ip string, port int, err error) {
+	idx := strings.LastIndex(instance, "#")
+	if idx < 0 {
+		return "", 0, fmt.Errorf("instance[%s] format error", instance)
+	}
+	// ipv6允许写成[v6]#port
+	host := instance[:idx]
+	bracketed := strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]")
+	if bracketed {
+		host = host[1 : len(host)-1]
+	}
+	// ip格式错误
+	if ip, err = CheckIp(host); err != nil {
+		return "", 0, err
+	}
+	if bracketed && !IsIpv6(ip) {
+		return "", 0, fmt.Errorf("instance[%s] only ipv6 can be bracketed", instance)
+	}
+	// 端口格式错误
+	port, err = strconv.Atoi(instance[idx+1:])
+	if err != nil || port < 0 || port > 65535 {
+		return "", 0, fmt.Errorf("instance[%s] port format error", instance)
+	}
+	return ip, port, nil
 }
 
 // TransZeroStrings 转换空值
@@ -0,0 +1,89 @@
+package tools
+
+import "testing"
+
+func TestCheckIp(t *testing.T) {
+	cases := []struct {
+		ip   string
+		want string
+		fail bool
+	}{
+		{"1.1.1.1", "1.1.1.1", false},
+		{" 1.1.1.1 ", "1.1.1.1", false},
+		{"2001:DB8::1", "2001:db8::1", false},
+		{"2001:0db8:0000:0000:0000:0000:0000:0001", "2001:db8::1", false},
+		{"::1", "::1", false},
+		// ipv4映射的ipv6
+		{"::ffff:1.1.1.1", "", true},
+		{"::ffff:101:101", "", true},
+		{"[2001:db8::1]", "", true},
+		{"1.1.1", "", true},
+		{"1.1.1.256", "", true},
+		{"", "", true},
+	}
+	for _, c := range cases {
+		got, err := CheckIp(c.ip)
+		if (err != nil) != c.fail {
+			t.Errorf("CheckIp(%q) err %v, want fail %v", c.ip, err, c.fail)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("CheckIp(%q) = %q, want %q", c.ip, got, c.want)
+		}
+	}
+}
+
+func TestIsIpv6(t *testing.T) {
+	cases := []struct {
+		ip   string
+		want bool
+	}{
+		{"1.1.1.1", false},
+		{"2001:db8::1", true},
+		{" 2001:db8::1 ", true},
+		{"::1", true},
+		{"::ffff:1.1.1.1", false},
+		{"[2001:db8::1]", false},
+		{"abc", false},
+	}
+	for _, c := range cases {
+		if got := IsIpv6(c.ip); got != c.want {
+			t.Errorf("IsIpv6(%q) = %v, want %v", c.ip, got, c.want)
+		}
+	}
+}
+
+func TestSplitInstance(t *testing.T) {
+	cases := []struct {
+		ins  string
+		ip   string
+		port int
+		fail bool
+	}{
+		{"1.1.1.1#3306", "1.1.1.1", 3306, false},
+		{"1.1.1.1#0", "1.1.1.1", 0, false},
+		{"2001:db8::1#3306", "2001:db8::1", 3306, false},
+		{"2001:DB8:0::1#3306", "2001:db8::1", 3306, false},
+		{"[2001:db8::1]#3306", "2001:db8::1", 3306, false},
+		{"[2001:db8::1]:3306", "", 0, true},
+		{"2001:db8::1:3306", "", 0, true},
+		{"[1.1.1.1]#3306", "", 0, true},
+		{"::ffff:1.1.1.1#3306", "", 0, true},
+		{"[::ffff:1.1.1.1]#3306", "", 0, true},
+		{"1.1.1.1:3306", "", 0, true},
+		{"1.1.1.1#", "", 0, true},
+		{"1.1.1.1#65536", "", 0, true},
+		{"1.1.1.1#-1", "", 0, true},
+		{"1.1.1.1#33#06", "", 0, true},
+	}
+	for _, c := range cases {
+		ip, port, err := splitInstance(c.ins)
+		if (err != nil) != c.fail {
+			t.Errorf("splitInstance(%q) err %v, want fail %v", c.ins, err, c.fail)
+			continue
+		}
+		if ip != c.ip || port != c.port {
+			t.Errorf("splitInstance(%q) = %s,%d, want %s,%d", c.ins, ip, port, c.ip, c.port)
+		}
+	}
+}
@@ -37,32 +37,74 @@ type ApiResp struct {
 // QueryAllDomainPost TODO
 // POST方法 查询所有域名记录
 func QueryAllDomainPost() ([]dao.TbDnsBase, error) {
+	return QueryDomainPost("")
+}
+
+// QueryDomainPost POST方法 查询域名记录
+// lastChangeTime不为空时只查询该时间(格式2006-01-02 15:04:05)之后变更的记录
+func QueryDomainPost(lastChangeTime string) ([]dao.TbDnsBase, error) {
+	var data ApiResp
+	if err := proxyPassPost("/apis/proxypass/dns/domain/all/", lastChangeTime, &data); err != nil {
+		return nil, err
+	}
+	if data.Code != 0 {
+		return nil, fmt.Errorf("query domain failed, code:%d, message:%s", data.Code, data.Message)
+	}
+	return data.Data.Detail, nil
+}
+
+// DeletedResp 查询已删除域名记录的响应
+type DeletedResp struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    struct {
+		Detail  []dao.TbDnsChangeLog
+		RowsNum int `json:"rowsNum"`
+	}
+}
+
+// QueryDeletedDomainPost POST方法 查询lastChangeTime之后删除的域名记录
+func QueryDeletedDomainPost(lastChangeTime string) ([]dao.TbDnsChangeLog, error) {
+	var data DeletedResp
+	if err := proxyPassPost("/apis/proxypass/dns/domain/deleted/", lastChangeTime, &data); err != nil {
+		return nil, err
+	}
+	if data.Code != 0 {
+		return nil, fmt.Errorf("query deleted domain failed, code:%d, message:%s", data.Code, data.Message)
+	}
+	return data.Data.Detail, nil
+}
+
+// proxyPassPost 通过透传接口查询，响应解析到result
+func proxyPassPost(path string, lastChangeTime string, result interface{}) error {
 	queryBody := make(map[string]string)
 	queryBody["db_cloud_token"] = config.GetConfig("db_cloud_token")
 	queryBody["bk_cloud_id"] = config.GetConfig("bk_cloud_id")
+	if lastChangeTime != "" {
+		queryBody["last_change_time"] = lastChangeTime
+	}
 	logger.Info.Printf(fmt.Sprintf("body query params is ['%+v']", queryBody))
 
 	bodyData, err := json.Marshal(queryBody)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	bk_url := config.GetConfig("bk_dns_api_url")
-	req, err := http.NewRequest("POST", bk_url+"/apis/proxypass/dns/domain/all/", bytes.NewBuffer(bodyData))
+	req, err := http.NewRequest("POST", bk_url+path, bytes.NewBuffer(bodyData))
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	req.Header.Add("Content-Type", "application/json")
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer resp.Body.Close()
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	var data ApiResp
-	json.Unmarshal(body, &data)
-	return data.Data.Detail, nil
+	return json.Unmarshal(body, result)
 }
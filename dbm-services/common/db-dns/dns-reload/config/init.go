@@ -57,3 +57,12 @@ func GetConfig(k string) string {
 
 	return v
 }
+
+// GetConfigDefault 获取配置，不存在时返回默认值
+func GetConfigDefault(k string, def string) string {
+	v, _ok := ConfigMap[k]
+	if !_ok {
+		return def
+	}
+	return v
+}
@@ -22,6 +22,16 @@ func (t *TbDnsBase) TableName() string {
 	return "tb_dns_base"
 }
 
+// TbDnsChangeLog 域名变更记录，增量刷新时用于感知删除
+type TbDnsChangeLog struct {
+	Uid        int64  `json:"uid"`
+	DomainName string `json:"domain_name"`
+	OpType     string `json:"op_type"`
+	BeforeIp   string `json:"before_ip"`
+	BeforePort int64  `json:"before_port"`
+	ChangeTime string `json:"change_time"`
+}
+
 // TbDnsServer TODO
 type TbDnsServer struct {
 	Uid            int64  `gorm:"column:uid" db:"column:uid" json:"uid" form:"uid"`
@@ -1,3 +1,12 @@
 module dnsReload
 
 go 1.19
+
+require github.com/miekg/dns v1.1.56
+
+require (
+	golang.org/x/mod v0.12.0 // indirect
+	golang.org/x/net v0.15.0 // indirect
+	golang.org/x/sys v0.12.0 // indirect
+	golang.org/x/tools v0.13.0 // indirect
+)
@@ -25,9 +25,17 @@ func main() {
 	if err != nil {
 		intervalTime = 3
 	}
+	// native模式下dns记录在内存中应答，不再生成zone文件和reload bind
+	nativeMode := service.IsNativeMode()
+	if nativeMode {
+		service.StartNativeServer(time.Duration(intervalTime) * time.Second)
+	}
 	for {
-		err := service.Reload(localIp)
-		if err != nil {
+		if nativeMode {
+			if err := service.SyncNative(); err != nil {
+				logger.Error.Printf("native dns sync error [%+v]", err)
+			}
+		} else if err := service.Reload(localIp); err != nil {
 			logger.Error.Printf("dns reload error [%+v]", err)
 		}
 		time.Sleep(time.Duration(intervalTime) * time.Second)
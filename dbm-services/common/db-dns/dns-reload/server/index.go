@@ -0,0 +1,168 @@
+// Package server 内置dns服务，直接从内存索引应答A/AAAA/SRV查询
+package server
+
+import (
+	"dnsReload/dao"
+	"dnsReload/util"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DomainIndex tb_dns_base的内存索引
+type DomainIndex struct {
+	mu     sync.RWMutex
+	byUid  map[int64]dao.TbDnsBase
+	byName map[string][]dao.TbDnsBase
+	// 本服务负责解析的zone，不在这些zone内的查询转发给forward ip
+	zones          map[string]struct{}
+	lastChangeTime time.Time
+}
+
+// NewDomainIndex 初始化索引
+func NewDomainIndex() *DomainIndex {
+	return &DomainIndex{
+		byUid:  make(map[int64]dao.TbDnsBase),
+		byName: make(map[string][]dao.TbDnsBase),
+		zones:  make(map[string]struct{}),
+	}
+}
+
+// Replace 全量替换，用于定期全量同步(增量拉取无法感知删除)
+func (idx *DomainIndex) Replace(list []dao.TbDnsBase) {
+	byUid := make(map[int64]dao.TbDnsBase, len(list))
+	for _, d := range list {
+		byUid[d.Uid] = d
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.byUid = byUid
+	idx.lastChangeTime = time.Time{}
+	idx.rebuild()
+}
+
+// Upsert 增量更新，按uid覆盖
+func (idx *DomainIndex) Upsert(list []dao.TbDnsBase) {
+	if len(list) == 0 {
+		return
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for _, d := range list {
+		idx.byUid[d.Uid] = d
+	}
+	idx.rebuild()
+}
+
+// Delete 按变更记录删除域名记录
+// 只删除变更时间不晚于删除时间的记录，删除后又新增的同名记录不受影响
+func (idx *DomainIndex) Delete(logs []dao.TbDnsChangeLog) int {
+	if len(logs) == 0 {
+		return 0
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	deleted := 0
+	var lastDeleteTime time.Time
+	for _, l := range logs {
+		deleteTime := parseChangeTime(l.ChangeTime)
+		if deleteTime.After(lastDeleteTime) {
+			lastDeleteTime = deleteTime
+		}
+		name := Fqdn(l.DomainName)
+		for uid, d := range idx.byUid {
+			if Fqdn(d.DomainName) != name || d.Ip != l.BeforeIp || d.Port != l.BeforePort {
+				continue
+			}
+			if parseChangeTime(d.LastChangeTime).After(deleteTime) {
+				continue
+			}
+			delete(idx.byUid, uid)
+			deleted++
+		}
+	}
+	idx.rebuild()
+	// 只有删除时lastChangeTime也要前进，避免重复拉取同一批删除记录
+	if lastDeleteTime.After(idx.lastChangeTime) {
+		idx.lastChangeTime = lastDeleteTime
+	}
+	return deleted
+}
+
+// rebuild 重建域名及zone索引，调用方需持有写锁
+func (idx *DomainIndex) rebuild() {
+	byName := make(map[string][]dao.TbDnsBase)
+	zones := make(map[string]struct{})
+	for _, d := range idx.byUid {
+		name := Fqdn(d.DomainName)
+		if name == "." {
+			continue
+		}
+		byName[name] = append(byName[name], d)
+		zones[Fqdn(util.GetZoneName(d.DomainName))] = struct{}{}
+		if t := parseChangeTime(d.LastChangeTime); t.After(idx.lastChangeTime) {
+			idx.lastChangeTime = t
+		}
+	}
+	idx.byName = byName
+	idx.zones = zones
+}
+
+// Lookup 查询域名记录，zone为域名所属的zone，为空表示不在本服务负责的zone内
+func (idx *DomainIndex) Lookup(name string) (records []dao.TbDnsBase, zone string) {
+	name = Fqdn(name)
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	labels := strings.Split(strings.TrimSuffix(name, "."), ".")
+	for i := range labels {
+		z := strings.Join(labels[i:], ".") + "."
+		if _, ok := idx.zones[z]; ok {
+			zone = z
+			break
+		}
+	}
+	if zone == "" {
+		return nil, ""
+	}
+	return idx.byName[name], zone
+}
+
+// LastChangeTime 已加载记录的最大变更时间，用于增量拉取
+func (idx *DomainIndex) LastChangeTime() time.Time {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.lastChangeTime
+}
+
+// Len 记录数
+func (idx *DomainIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.byUid)
+}
+
+// Fqdn 转换为小写且以.结尾的域名
+func Fqdn(name string) string {
+	return strings.Trim(strings.TrimSpace(strings.ToLower(name)), ".") + "."
+}
+
+// ParseIp 解析记录中的ip，非法ip返回nil
+func ParseIp(ip string) net.IP {
+	return net.ParseIP(strings.TrimSpace(ip))
+}
+
+// parseChangeTime dns-api返回RFC3339格式，兼容2006-01-02 15:04:05
+func parseChangeTime(s string) time.Time {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.Local); err == nil {
+		return t
+	}
+	return time.Time{}
+}
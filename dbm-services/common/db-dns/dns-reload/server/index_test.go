@@ -0,0 +1,134 @@
+package server
+
+import (
+	"dnsReload/dao"
+	"sort"
+	"testing"
+	"time"
+)
+
+const changeTimeLayout = "2006-01-02 15:04:05"
+
+func record(uid int64, name, ip string, port int64, changeTime string) dao.TbDnsBase {
+	return dao.TbDnsBase{Uid: uid, DomainName: name, Ip: ip, Port: port, LastChangeTime: changeTime}
+}
+
+func deleteLog(name, ip string, port int64, changeTime string) dao.TbDnsChangeLog {
+	return dao.TbDnsChangeLog{DomainName: name, OpType: "delete", BeforeIp: ip, BeforePort: port,
+		ChangeTime: changeTime}
+}
+
+func lookupIps(t *testing.T, idx *DomainIndex, name string) []string {
+	t.Helper()
+	records, _ := idx.Lookup(name)
+	var ips []string
+	for _, d := range records {
+		ips = append(ips, d.Ip)
+	}
+	sort.Strings(ips)
+	return ips
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDomainIndexLookup(t *testing.T) {
+	idx := NewDomainIndex()
+	idx.Replace([]dao.TbDnsBase{
+		record(1, "master.app.mysql.db.", "1.1.1.1", 3306, "2024-01-01 00:00:00"),
+		record(2, "Master.App.Mysql.DB", "2.2.2.2", 3306, "2024-01-01 00:00:01"),
+		record(3, "slave.app.mysql.db", "2001:db8::1", 3306, "2024-01-01 00:00:02"),
+		record(4, ".", "3.3.3.3", 3306, "2024-01-01 00:00:03"),
+	})
+
+	cases := []struct {
+		name string
+		zone string
+		ips  []string
+	}{
+		{"master.app.mysql.db.", "app.mysql.db.", []string{"1.1.1.1", "2.2.2.2"}},
+		{"MASTER.app.mysql.db", "app.mysql.db.", []string{"1.1.1.1", "2.2.2.2"}},
+		{"slave.app.mysql.db.", "app.mysql.db.", []string{"2001:db8::1"}},
+		// zone内不存在的域名
+		{"proxy.app.mysql.db.", "app.mysql.db.", nil},
+		{"www.example.com.", "", nil},
+		{"mysql.db.", "", nil},
+	}
+	for _, c := range cases {
+		records, zone := idx.Lookup(c.name)
+		if zone != c.zone {
+			t.Errorf("lookup %s zone %q, want %q", c.name, zone, c.zone)
+		}
+		if ips := lookupIps(t, idx, c.name); !equalStrings(ips, c.ips) {
+			t.Errorf("lookup %s got %v(%d records), want %v", c.name, ips, len(records), c.ips)
+		}
+	}
+	if idx.Len() != 4 {
+		t.Errorf("len %d, want 4", idx.Len())
+	}
+	// 全量替换后lastChangeTime取最大变更时间
+	want, _ := time.ParseInLocation(changeTimeLayout, "2024-01-01 00:00:02", time.Local)
+	if !idx.LastChangeTime().Equal(want) {
+		t.Errorf("last change time %s, want %s", idx.LastChangeTime(), want)
+	}
+}
+
+func TestDomainIndexIncremental(t *testing.T) {
+	idx := NewDomainIndex()
+	idx.Replace([]dao.TbDnsBase{
+		record(1, "master.app.mysql.db", "1.1.1.1", 3306, "2024-01-01 00:00:00"),
+		record(2, "slave.app.mysql.db", "2.2.2.2", 3306, "2024-01-01 00:00:00"),
+	})
+
+	// 修改uid 1并新增uid 3
+	idx.Upsert([]dao.TbDnsBase{
+		record(1, "master.app.mysql.db", "4.4.4.4", 3306, "2024-01-01 00:01:00"),
+		record(3, "master.app.mysql.db", "5.5.5.5", 3306, "2024-01-01 00:01:00"),
+	})
+	if ips := lookupIps(t, idx, "master.app.mysql.db"); !equalStrings(ips, []string{"4.4.4.4", "5.5.5.5"}) {
+		t.Fatalf("after upsert got %v", ips)
+	}
+
+	// 删除晚于记录的变更时间才生效，删除后又新增的同名记录不受影响
+	idx.Upsert([]dao.TbDnsBase{record(4, "slave.app.mysql.db", "2.2.2.2", 3306, "2024-01-01 00:03:00")})
+	deleted := idx.Delete([]dao.TbDnsChangeLog{
+		deleteLog("slave.app.mysql.db.", "2.2.2.2", 3306, "2024-01-01 00:02:00"),
+		deleteLog("master.app.mysql.db", "5.5.5.5", 3306, "2024-01-01T00:02:00+08:00"),
+		deleteLog("master.app.mysql.db", "9.9.9.9", 3306, "2024-01-01 00:02:00"),
+	})
+	if deleted != 1 {
+		t.Errorf("deleted %d, want 1", deleted)
+	}
+	if ips := lookupIps(t, idx, "slave.app.mysql.db"); !equalStrings(ips, []string{"2.2.2.2"}) {
+		t.Errorf("record added after delete should be kept, got %v", ips)
+	}
+	if idx.Len() != 3 {
+		t.Errorf("len %d, want 3", idx.Len())
+	}
+
+	// 只有删除时lastChangeTime也要前进
+	idx = NewDomainIndex()
+	idx.Replace([]dao.TbDnsBase{record(1, "master.app.mysql.db", "1.1.1.1", 3306, "2024-01-01 00:00:00")})
+	if n := idx.Delete([]dao.TbDnsChangeLog{
+		deleteLog("master.app.mysql.db", "1.1.1.1", 3306, "2024-01-01 00:05:00"),
+	}); n != 1 {
+		t.Fatalf("deleted %d, want 1", n)
+	}
+	want, _ := time.ParseInLocation(changeTimeLayout, "2024-01-01 00:05:00", time.Local)
+	if !idx.LastChangeTime().Equal(want) {
+		t.Errorf("last change time %s, want %s", idx.LastChangeTime(), want)
+	}
+	// 域名全部删除后zone也不再由本服务应答
+	if _, zone := idx.Lookup("master.app.mysql.db"); zone != "" {
+		t.Errorf("zone %q should be removed", zone)
+	}
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"dnsReload/dao"
+	"dnsReload/logger"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// DnsServer 内置权威dns服务
+type DnsServer struct {
+	Index *DomainIndex
+	Addr  string
+	Ttl   uint32
+	// 不在本服务zone内的查询转发到这些地址
+	Forwarders []string
+	client     *dns.Client
+}
+
+// NewDnsServer 初始化dns服务
+func NewDnsServer(index *DomainIndex, addr string, ttl uint32, forwarders []string) *DnsServer {
+	var fs []string
+	for _, f := range forwarders {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(f); err != nil {
+			f = net.JoinHostPort(f, "53")
+		}
+		fs = append(fs, f)
+	}
+	return &DnsServer{
+		Index:      index,
+		Addr:       addr,
+		Ttl:        ttl,
+		Forwarders: fs,
+		client:     &dns.Client{Timeout: 2 * time.Second},
+	}
+}
+
+// Run 同时监听udp、tcp，任一监听失败即返回
+func (s *DnsServer) Run() error {
+	errChan := make(chan error, 2)
+	for _, network := range []string{"udp", "tcp"} {
+		srv := &dns.Server{Addr: s.Addr, Net: network, Handler: s}
+		go func(srv *dns.Server) {
+			logger.Info.Printf("native dns server listen on %s/%s", srv.Addr, srv.Net)
+			errChan <- srv.ListenAndServe()
+		}(srv)
+	}
+	return <-errChan
+}
+
+// ServeDNS 应答查询，本服务zone内的直接从索引应答，其他转发
+func (s *DnsServer) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	m := s.Resolve(r)
+	if err := w.WriteMsg(m); err != nil {
+		logger.Warning.Printf("write dns response error [%+v]", err)
+	}
+}
+
+// Resolve 生成查询的应答
+func (s *DnsServer) Resolve(r *dns.Msg) *dns.Msg {
+	m := new(dns.Msg)
+	if len(r.Question) != 1 {
+		m.SetRcode(r, dns.RcodeFormatError)
+		return m
+	}
+	q := r.Question[0]
+
+	name := q.Name
+	if q.Qtype == dns.TypeSRV {
+		name = trimSrvLabels(name)
+	}
+	records, zone := s.Index.Lookup(name)
+	if zone == "" {
+		return s.forward(r)
+	}
+
+	m.SetReply(r)
+	m.Authoritative = true
+	if len(records) == 0 {
+		m.Rcode = dns.RcodeNameError
+		m.Ns = append(m.Ns, s.soa(zone))
+		return m
+	}
+
+	switch q.Qtype {
+	case dns.TypeA, dns.TypeAAAA, dns.TypeANY:
+		m.Answer = append(m.Answer, s.addressRRs(q.Name, records, q.Qtype)...)
+	case dns.TypeSRV:
+		m.Answer = append(m.Answer, s.srvRRs(q.Name, records)...)
+		m.Extra = append(m.Extra, s.addressRRs(Fqdn(name), records, dns.TypeANY)...)
+	}
+	if len(m.Answer) == 0 {
+		// 域名存在但没有该类型的记录
+		m.Ns = append(m.Ns, s.soa(zone))
+	}
+	return m
+}
+
+// addressRRs 生成A/AAAA记录，同一ip只返回一条
+func (s *DnsServer) addressRRs(name string, records []dao.TbDnsBase, qtype uint16) []dns.RR {
+	var rrs []dns.RR
+	seen := make(map[string]struct{})
+	for _, d := range records {
+		ip := ParseIp(d.Ip)
+		if ip == nil {
+			continue
+		}
+		if _, ok := seen[ip.String()]; ok {
+			continue
+		}
+		seen[ip.String()] = struct{}{}
+
+		if ip4 := ip.To4(); ip4 != nil {
+			if qtype == dns.TypeA || qtype == dns.TypeANY {
+				rrs = append(rrs, &dns.A{Hdr: s.header(name, dns.TypeA), A: ip4})
+			}
+		} else if qtype == dns.TypeAAAA || qtype == dns.TypeANY {
+			rrs = append(rrs, &dns.AAAA{Hdr: s.header(name, dns.TypeAAAA), AAAA: ip})
+		}
+	}
+	return rrs
+}
+
+// srvRRs 生成SRV记录，target为域名本身，端口为实例端口
+func (s *DnsServer) srvRRs(name string, records []dao.TbDnsBase) []dns.RR {
+	var rrs []dns.RR
+	seen := make(map[int64]struct{})
+	for _, d := range records {
+		if d.Port <= 0 || d.Port > 65535 {
+			continue
+		}
+		if _, ok := seen[d.Port]; ok {
+			continue
+		}
+		seen[d.Port] = struct{}{}
+		rrs = append(rrs, &dns.SRV{
+			Hdr:      s.header(name, dns.TypeSRV),
+			Priority: 0,
+			Weight:   10,
+			Port:     uint16(d.Port),
+			Target:   Fqdn(d.DomainName),
+		})
+	}
+	return rrs
+}
+
+func (s *DnsServer) header(name string, rrtype uint16) dns.RR_Header {
+	return dns.RR_Header{Name: name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: s.Ttl}
+}
+
+// soa 与bind zone文件模板保持一致
+func (s *DnsServer) soa(zone string) dns.RR {
+	return &dns.SOA{
+		Hdr:     s.header(zone, dns.TypeSOA),
+		Ns:      "db.",
+		Mbox:    "root.db.",
+		Serial:  2012011601,
+		Refresh: 600,
+		Retry:   14400,
+		Expire:  7200,
+		Minttl:  900,
+	}
+}
+
+// forward 依次尝试转发，全部失败返回SERVFAIL
+func (s *DnsServer) forward(r *dns.Msg) *dns.Msg {
+	for _, addr := range s.Forwarders {
+		resp, _, err := s.client.Exchange(r, addr)
+		if err == nil && resp != nil {
+			return resp
+		}
+		logger.Warning.Printf("forward %s to %s error [%+v]", r.Question[0].Name, addr, err)
+	}
+	m := new(dns.Msg)
+	m.SetRcode(r, dns.RcodeServerFailure)
+	return m
+}
+
+// trimSrvLabels 去掉SRV查询名前缀的_service._proto
+func trimSrvLabels(name string) string {
+	labels := dns.SplitDomainName(name)
+	i := 0
+	for i < len(labels) && strings.HasPrefix(labels[i], "_") {
+		i++
+	}
+	if i == len(labels) {
+		return name
+	}
+	return fmt.Sprintf("%s.", strings.Join(labels[i:], "."))
+}
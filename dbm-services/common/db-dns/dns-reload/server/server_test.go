@@ -0,0 +1,221 @@
+package server
+
+import (
+	"dnsReload/dao"
+	"dnsReload/logger"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestMain(m *testing.M) {
+	logger.Info = log.New(io.Discard, "", 0)
+	logger.Warning = log.New(io.Discard, "", 0)
+	logger.Error = log.New(io.Discard, "", 0)
+	os.Exit(m.Run())
+}
+
+func newTestServer(forwarders ...string) *DnsServer {
+	idx := NewDomainIndex()
+	idx.Replace([]dao.TbDnsBase{
+		record(1, "master.app.mysql.db", "1.1.1.1", 3306, "2024-01-01 00:00:00"),
+		record(2, "master.app.mysql.db", "2001:db8::1", 3306, "2024-01-01 00:00:00"),
+		record(3, "master.app.mysql.db", "1.1.1.1", 3307, "2024-01-01 00:00:00"),
+		record(4, "slave.app.mysql.db", "2.2.2.2", 3306, "2024-01-01 00:00:00"),
+	})
+	return NewDnsServer(idx, "127.0.0.1:0", 6, forwarders)
+}
+
+func query(name string, qtype uint16) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	return m
+}
+
+// rrValues 记录的值，A/AAAA为ip，SRV为target:port
+func rrValues(rrs []dns.RR) []string {
+	var l []string
+	for _, rr := range rrs {
+		switch v := rr.(type) {
+		case *dns.A:
+			l = append(l, v.A.String())
+		case *dns.AAAA:
+			l = append(l, v.AAAA.String())
+		case *dns.SRV:
+			l = append(l, fmt.Sprintf("%s:%d", v.Target, v.Port))
+		case *dns.SOA:
+			l = append(l, "soa:"+v.Hdr.Name)
+		}
+	}
+	sort.Strings(l)
+	return l
+}
+
+// startUdpServer 在随机端口启动udp dns服务，返回监听地址
+func startUdpServer(t *testing.T, handler dns.Handler) string {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	srv := &dns.Server{PacketConn: pc, Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go func() { _ = srv.ActivateAndServe() }()
+	<-started
+	t.Cleanup(func() { _ = srv.Shutdown() })
+	return pc.LocalAddr().String()
+}
+
+func TestResolve(t *testing.T) {
+	s := newTestServer()
+	cases := []struct {
+		name   string
+		qtype  uint16
+		rcode  int
+		answer []string
+		ns     []string
+		extra  []string
+	}{
+		{"master.app.mysql.db.", dns.TypeA, dns.RcodeSuccess, []string{"1.1.1.1"}, nil, nil},
+		{"MASTER.APP.MYSQL.DB.", dns.TypeA, dns.RcodeSuccess, []string{"1.1.1.1"}, nil, nil},
+		{"master.app.mysql.db.", dns.TypeAAAA, dns.RcodeSuccess, []string{"2001:db8::1"}, nil, nil},
+		{"master.app.mysql.db.", dns.TypeANY, dns.RcodeSuccess, []string{"1.1.1.1", "2001:db8::1"}, nil, nil},
+		// 域名存在但没有该类型记录
+		{"slave.app.mysql.db.", dns.TypeAAAA, dns.RcodeSuccess, nil, []string{"soa:app.mysql.db."}, nil},
+		{"slave.app.mysql.db.", dns.TypeMX, dns.RcodeSuccess, nil, []string{"soa:app.mysql.db."}, nil},
+		{"proxy.app.mysql.db.", dns.TypeA, dns.RcodeNameError, nil, []string{"soa:app.mysql.db."}, nil},
+		// SRV任意_service._proto前缀都应答，target为域名本身
+		{"_dbm._tcp.master.app.mysql.db.", dns.TypeSRV, dns.RcodeSuccess,
+			[]string{"master.app.mysql.db.:3306", "master.app.mysql.db.:3307"}, nil,
+			[]string{"1.1.1.1", "2001:db8::1"}},
+		{"_mysql._udp.slave.app.mysql.db.", dns.TypeSRV, dns.RcodeSuccess,
+			[]string{"slave.app.mysql.db.:3306"}, nil, []string{"2.2.2.2"}},
+		{"slave.app.mysql.db.", dns.TypeSRV, dns.RcodeSuccess,
+			[]string{"slave.app.mysql.db.:3306"}, nil, []string{"2.2.2.2"}},
+		{"_dbm._tcp.proxy.app.mysql.db.", dns.TypeSRV, dns.RcodeNameError, nil, []string{"soa:app.mysql.db."}, nil},
+	}
+	for _, c := range cases {
+		r := query(c.name, c.qtype)
+		m := s.Resolve(r)
+		if m.Rcode != c.rcode || !m.Authoritative || m.Id != r.Id {
+			t.Errorf("%s %s: rcode %d aa %v id %d, want rcode %d aa true id %d", c.name,
+				dns.TypeToString[c.qtype], m.Rcode, m.Authoritative, m.Id, c.rcode, r.Id)
+		}
+		for _, part := range []struct {
+			what      string
+			got, want []string
+		}{
+			{"answer", rrValues(m.Answer), c.answer},
+			{"ns", rrValues(m.Ns), c.ns},
+			{"extra", rrValues(m.Extra), c.extra},
+		} {
+			if !equalStrings(part.got, part.want) {
+				t.Errorf("%s %s: %s %v, want %v", c.name, dns.TypeToString[c.qtype], part.what, part.got, part.want)
+			}
+		}
+		for _, rr := range m.Answer {
+			if rr.Header().Name != c.name || rr.Header().Ttl != 6 {
+				t.Errorf("%s: unexpected answer header %s", c.name, rr.Header().String())
+			}
+		}
+	}
+
+	// 多个问题的查询不支持
+	r := query("master.app.mysql.db.", dns.TypeA)
+	r.Question = append(r.Question, r.Question[0])
+	if m := s.Resolve(r); m.Rcode != dns.RcodeFormatError {
+		t.Errorf("multi question rcode %d, want FORMERR", m.Rcode)
+	}
+}
+
+func TestTrimSrvLabels(t *testing.T) {
+	cases := map[string]string{
+		"_dbm._tcp.master.app.mysql.db.": "master.app.mysql.db.",
+		"_a._b._c.master.app.mysql.db.":  "master.app.mysql.db.",
+		"master.app.mysql.db.":           "master.app.mysql.db.",
+		"master._tcp.app.mysql.db.":      "master._tcp.app.mysql.db.",
+		"_dbm._tcp.":                     "_dbm._tcp.",
+	}
+	for name, want := range cases {
+		if got := trimSrvLabels(name); got != want {
+			t.Errorf("trimSrvLabels(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestForward(t *testing.T) {
+	upstream := startUdpServer(t, dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = append(m.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   net.ParseIP("9.9.9.9"),
+		})
+		_ = w.WriteMsg(m)
+	}))
+	// 转发地址不带端口时默认53
+	if s := NewDnsServer(NewDomainIndex(), "", 6, []string{" 1.1.1.1 ", "", "2.2.2.2:5353"}); !equalStrings(
+		s.Forwarders, []string{"1.1.1.1:53", "2.2.2.2:5353"}) {
+		t.Errorf("forwarders %v", s.Forwarders)
+	}
+
+	// 第一个转发地址不可用时尝试下一个
+	dead, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadAddr := dead.LocalAddr().String()
+	_ = dead.Close()
+	s := newTestServer(deadAddr, upstream)
+	s.client.Timeout = 200 * time.Millisecond
+
+	m := s.Resolve(query("www.example.com.", dns.TypeA))
+	if m.Rcode != dns.RcodeSuccess || m.Authoritative {
+		t.Errorf("forwarded rcode %d aa %v", m.Rcode, m.Authoritative)
+	}
+	if got := rrValues(m.Answer); !equalStrings(got, []string{"9.9.9.9"}) {
+		t.Errorf("forwarded answer %v", got)
+	}
+	// 本服务zone内的查询不转发
+	if got := rrValues(s.Resolve(query("slave.app.mysql.db.", dns.TypeA)).Answer); !equalStrings(got,
+		[]string{"2.2.2.2"}) {
+		t.Errorf("local answer %v", got)
+	}
+
+	// 全部转发失败返回SERVFAIL
+	s = newTestServer(deadAddr)
+	s.client.Timeout = 200 * time.Millisecond
+	if m := s.Resolve(query("www.example.com.", dns.TypeA)); m.Rcode != dns.RcodeServerFailure {
+		t.Errorf("rcode %d, want SERVFAIL", m.Rcode)
+	}
+}
+
+// TestServeDNS 通过真实udp请求验证应答可以被正常编码和解析
+func TestServeDNS(t *testing.T) {
+	addr := startUdpServer(t, newTestServer())
+	c := &dns.Client{}
+	for _, q := range []struct {
+		name  string
+		qtype uint16
+		want  []string
+	}{
+		{"master.app.mysql.db.", dns.TypeA, []string{"1.1.1.1"}},
+		{"master.app.mysql.db.", dns.TypeAAAA, []string{"2001:db8::1"}},
+		{"_dbm._tcp.slave.app.mysql.db.", dns.TypeSRV, []string{"slave.app.mysql.db.:3306"}},
+	} {
+		m, _, err := c.Exchange(query(q.name, q.qtype), addr)
+		if err != nil {
+			t.Fatalf("query %s error: %v", q.name, err)
+		}
+		if got := rrValues(m.Answer); m.Rcode != dns.RcodeSuccess || !equalStrings(got, q.want) {
+			t.Errorf("query %s %s: rcode %d answer %v, want %v", q.name, dns.TypeToString[q.qtype],
+				m.Rcode, got, q.want)
+		}
+	}
+}
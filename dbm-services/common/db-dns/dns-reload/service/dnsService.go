@@ -5,8 +5,10 @@ import (
 	"dnsReload/config"
 	"dnsReload/dao"
 	"dnsReload/logger"
+	"dnsReload/util"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"os/exec"
 	"strings"
@@ -20,14 +22,7 @@ var (
 
 // zone文件生成规则
 func getZoneFileName(d dao.TbDnsBase) string {
-	domain := strings.Trim(strings.TrimSpace(strings.ToLower(d.DomainName)), ".")
-	t := strings.Split(domain, ".")
-	// 取后面三个字段作为zone name
-	if len(t) <= 3 || !strings.HasSuffix(domain, "db") {
-		return domain
-	}
-	beginIndex := len(t) - 3
-	return strings.Join(t[beginIndex:], ".")
+	return util.GetZoneName(d.DomainName)
 }
 
 // 生成zone文件
@@ -43,9 +38,26 @@ func makeZoneFile(d dao.TbDnsBase, head string) string {
 	}
 
 	domain := strings.TrimSpace(strings.ToLower(d.DomainName))
-	ip := strings.TrimSpace(d.Ip)
-	return fmt.Sprintf("%s\n@               IN      NS    %s\n%s    IN    A    %s",
-		head, domain, domain, ip)
+	ip := net.ParseIP(strings.TrimSpace(d.Ip))
+	if ip == nil {
+		// 非法ip写进zone文件会导致整个zone加载失败，直接跳过
+		logger.Warning.Printf("%s ip [%s] format error, skip it", domain, d.Ip)
+		return head
+	}
+	rrType := "A"
+	if ip.To4() == nil {
+		rrType = "AAAA"
+	}
+	zone := fmt.Sprintf("%s\n@               IN      NS    %s\n%s    IN    %s    %s",
+		head, domain, domain, rrType, ip.String())
+
+	// 端口为0表示只登记了ip，不生成SRV记录
+	srvPrefix := config.GetConfigDefault("srv_prefix", "_dbm._tcp")
+	if srvPrefix != "" && d.Port > 0 && d.Port <= 65535 {
+		zone = fmt.Sprintf("%s\n%s.%s    IN    SRV    0 10 %d %s",
+			zone, srvPrefix, domain, d.Port, domain)
+	}
+	return zone
 }
 
 // 替换forward ip
@@ -153,7 +165,7 @@ func Reload(localIp string) error {
 	}
 
 	if len(domainList) == 0 {
-		logger.Warning.Printf("domainList len is 0. skip this update.", len(domainList))
+		logger.Warning.Printf("domainList len is %d. skip this update.", len(domainList))
 		return nil
 	}
 
@@ -173,7 +185,7 @@ func Reload(localIp string) error {
 	}
 
 	if len(zoneFileMap) == 0 {
-		logger.Warning.Printf("zoneFileMap len is 0. skip this update.", len(zoneFileMap))
+		logger.Warning.Printf("zoneFileMap len is %d. skip this update.", len(zoneFileMap))
 		return nil
 	}
 
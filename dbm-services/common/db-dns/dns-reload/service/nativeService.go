@@ -0,0 +1,103 @@
+package service
+
+import (
+	"dnsReload/api"
+	"dnsReload/config"
+	"dnsReload/logger"
+	"dnsReload/server"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ServerModeBind 生成bind zone文件并rndc reload
+	ServerModeBind = "bind"
+	// ServerModeNative 内置dns服务，不依赖bind
+	ServerModeNative = "native"
+)
+
+var (
+	nativeIndex  *server.DomainIndex
+	lastFullSync time.Time
+)
+
+// IsNativeMode 是否内置dns服务模式
+func IsNativeMode() bool {
+	return config.GetConfigDefault("server_mode", ServerModeBind) == ServerModeNative
+}
+
+// StartNativeServer 全量加载域名后启动内置dns服务
+// 首次加载失败不启动服务，避免空索引把db域名的查询都转发出去
+func StartNativeServer(interval time.Duration) {
+	nativeIndex = server.NewDomainIndex()
+	for {
+		if err := fullSync(); err == nil {
+			break
+		} else {
+			logger.Error.Printf("native dns first full sync error [%+v]", err)
+		}
+		time.Sleep(interval)
+	}
+
+	ttl, err := strconv.Atoi(config.GetConfigDefault("native_ttl", "6"))
+	if err != nil || ttl < 0 {
+		ttl = 6
+	}
+	forwarders := strings.FieldsFunc(api.QueryForwardIp(""), func(r rune) bool {
+		return r == ',' || r == ';'
+	})
+	s := server.NewDnsServer(nativeIndex, config.GetConfigDefault("native_listen", ":53"),
+		uint32(ttl), forwarders)
+	go func() {
+		if err := s.Run(); err != nil {
+			logger.Error.Fatalf("native dns server exit [%+v]", err)
+		}
+	}()
+}
+
+// SyncNative 增量刷新内存索引，新增/修改按uid覆盖，删除从变更记录中获取
+// 按full_sync_interval定期全量同步，兜底修正增量刷新可能的遗漏
+func SyncNative() error {
+	fullSyncInterval, err := strconv.Atoi(config.GetConfigDefault("full_sync_interval", "600"))
+	if err != nil || fullSyncInterval <= 0 {
+		fullSyncInterval = 600
+	}
+	lastChangeTime := nativeIndex.LastChangeTime()
+	if lastChangeTime.IsZero() || time.Since(lastFullSync) >= time.Duration(fullSyncInterval)*time.Second {
+		return fullSync()
+	}
+
+	// 向前多取1秒，避免同一秒内提交的变更被漏掉，upsert是幂等的
+	since := lastChangeTime.Add(-time.Second).Local().Format("2006-01-02 15:04:05")
+	domainList, err := api.QueryDomainPost(since)
+	if err != nil {
+		return fmt.Errorf("incremental query domain since %s error:%s", since, err.Error())
+	}
+	deletedList, err := api.QueryDeletedDomainPost(since)
+	if err != nil {
+		return fmt.Errorf("incremental query deleted domain since %s error:%s", since, err.Error())
+	}
+	// 先覆盖再删除，同一周期内修改后又删除的记录不会残留
+	nativeIndex.Upsert(domainList)
+	deleted := nativeIndex.Delete(deletedList)
+	logger.Info.Printf("native dns incremental sync since %s, %d changed, %d deleted",
+		since, len(domainList), deleted)
+	return nil
+}
+
+func fullSync() error {
+	domainList, err := api.QueryAllDomainPost()
+	if err != nil {
+		return err
+	}
+	// 与bind模式一致，查询结果为空时不覆盖，避免dns-api异常时清空所有记录
+	if len(domainList) == 0 {
+		return fmt.Errorf("domainList len is 0. skip this full sync")
+	}
+	nativeIndex.Replace(domainList)
+	lastFullSync = time.Now()
+	logger.Info.Printf("native dns full sync, %d records", nativeIndex.Len())
+	return nil
+}
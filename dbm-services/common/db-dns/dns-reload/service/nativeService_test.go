@@ -0,0 +1,148 @@
+package service
+
+import (
+	"dnsReload/config"
+	"dnsReload/dao"
+	"dnsReload/logger"
+	"dnsReload/server"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDnsApi 模拟dns-api透传接口，记录每次请求的last_change_time
+type fakeDnsApi struct {
+	mu       sync.Mutex
+	domains  []dao.TbDnsBase
+	deleted  []dao.TbDnsChangeLog
+	requests []string
+}
+
+func (f *fakeDnsApi) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body map[string]string
+	_ = json.NewDecoder(r.Body).Decode(&body)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.URL.Path+"?"+body["last_change_time"])
+	var detail interface{}
+	switch r.URL.Path {
+	case "/apis/proxypass/dns/domain/all/":
+		detail = f.domains
+	case "/apis/proxypass/dns/domain/deleted/":
+		detail = f.deleted
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 0, "data": map[string]interface{}{
+		"detail": detail,
+	}})
+}
+
+func (f *fakeDnsApi) set(domains []dao.TbDnsBase, deleted []dao.TbDnsChangeLog) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.domains = domains
+	f.deleted = deleted
+	f.requests = nil
+}
+
+func newFakeDnsApi(t *testing.T) *fakeDnsApi {
+	f := &fakeDnsApi{}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	logger.Info = log.New(io.Discard, "", 0)
+	logger.Warning = log.New(io.Discard, "", 0)
+	logger.Error = log.New(io.Discard, "", 0)
+	config.ConfigMap = map[string]string{
+		"bk_dns_api_url":     srv.URL,
+		"db_cloud_token":     "token",
+		"bk_cloud_id":        "0",
+		"full_sync_interval": "600",
+	}
+	nativeIndex = server.NewDomainIndex()
+	lastFullSync = time.Time{}
+	return f
+}
+
+func indexIps(name string) []string {
+	records, _ := nativeIndex.Lookup(name)
+	var ips []string
+	for _, d := range records {
+		ips = append(ips, d.Ip)
+	}
+	sort.Strings(ips)
+	return ips
+}
+
+func TestSyncNative(t *testing.T) {
+	f := newFakeDnsApi(t)
+	changeTime := time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local)
+	at := func(d time.Duration) string { return changeTime.Add(d).Format("2006-01-02 15:04:05") }
+
+	// 首次同步查询结果为空不覆盖，返回错误
+	if err := SyncNative(); err == nil {
+		t.Fatal("full sync with empty domain list should fail")
+	}
+
+	f.set([]dao.TbDnsBase{
+		{Uid: 1, DomainName: "master.app.mysql.db", Ip: "1.1.1.1", Port: 3306, LastChangeTime: at(0)},
+		{Uid: 2, DomainName: "slave.app.mysql.db", Ip: "2.2.2.2", Port: 3306, LastChangeTime: at(0)},
+	}, nil)
+	if err := SyncNative(); err != nil {
+		t.Fatalf("full sync failed: %v", err)
+	}
+	if nativeIndex.Len() != 2 || len(f.requests) != 1 || f.requests[0] != "/apis/proxypass/dns/domain/all/?" {
+		t.Fatalf("full sync: len %d, requests %v", nativeIndex.Len(), f.requests)
+	}
+
+	// 增量同步从上次变更时间前1秒开始拉取，新增/修改覆盖，删除按变更记录
+	f.set([]dao.TbDnsBase{
+		{Uid: 1, DomainName: "master.app.mysql.db", Ip: "3.3.3.3", Port: 3306, LastChangeTime: at(time.Minute)},
+	}, []dao.TbDnsChangeLog{
+		{DomainName: "slave.app.mysql.db", OpType: "delete", BeforeIp: "2.2.2.2", BeforePort: 3306,
+			ChangeTime: at(time.Minute)},
+	})
+	if err := SyncNative(); err != nil {
+		t.Fatalf("incremental sync failed: %v", err)
+	}
+	since := at(-time.Second)
+	wantRequests := []string{"/apis/proxypass/dns/domain/all/?" + since,
+		"/apis/proxypass/dns/domain/deleted/?" + since}
+	if len(f.requests) != 2 || f.requests[0] != wantRequests[0] || f.requests[1] != wantRequests[1] {
+		t.Errorf("incremental requests %v, want %v", f.requests, wantRequests)
+	}
+	if ips := indexIps("master.app.mysql.db"); len(ips) != 1 || ips[0] != "3.3.3.3" {
+		t.Errorf("master ips %v, want [3.3.3.3]", ips)
+	}
+	if ips := indexIps("slave.app.mysql.db"); len(ips) != 0 {
+		t.Errorf("slave ips %v, should be deleted", ips)
+	}
+
+	// 增量拉取到空结果不影响已有记录
+	f.set(nil, nil)
+	if err := SyncNative(); err != nil {
+		t.Fatalf("empty incremental sync failed: %v", err)
+	}
+	if nativeIndex.Len() != 1 {
+		t.Errorf("len %d after empty incremental sync, want 1", nativeIndex.Len())
+	}
+
+	// 超过全量同步间隔后走全量，空结果时保留原索引
+	f.set(nil, nil)
+	lastFullSync = time.Now().Add(-time.Hour)
+	if err := SyncNative(); err == nil {
+		t.Error("full sync with empty domain list should fail")
+	}
+	if nativeIndex.Len() != 1 || len(f.requests) != 1 || f.requests[0] != "/apis/proxypass/dns/domain/all/?" {
+		t.Errorf("full sync after interval: len %d, requests %v", nativeIndex.Len(), f.requests)
+	}
+}
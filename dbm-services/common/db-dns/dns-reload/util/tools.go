@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net"
 	"os"
+	"strings"
 )
 
 // GetClientIp 获取本机IP
@@ -35,3 +36,14 @@ func CheckFileIsExist(filename string) bool {
 	}
 	return true
 }
+
+// GetZoneName 获取域名所属zone，db结尾的域名取后面三个字段作为zone name
+func GetZoneName(domainName string) string {
+	domain := strings.Trim(strings.TrimSpace(strings.ToLower(domainName)), ".")
+	t := strings.Split(domain, ".")
+	if len(t) <= 3 || !strings.HasSuffix(domain, "db") {
+		return domain
+	}
+	beginIndex := len(t) - 3
+	return strings.Join(t[beginIndex:], ".")
+}
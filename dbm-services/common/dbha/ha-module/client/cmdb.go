@@ -80,6 +80,19 @@ type SwapSqlserverRoleRequest struct {
 	Payloads     []SwapSqlserverRolePayload `json:"payloads"`
 }
 
+// SwapMongoDBRolePayload mongodb replica set member need to swap role
+type SwapMongoDBRolePayload struct {
+	Instance1 DBInstanceInfo `json:"instance1"`
+	Instance2 DBInstanceInfo `json:"instance2"`
+}
+
+// SwapMongoDBRoleRequest swap mongodb member's role in cmdb
+type SwapMongoDBRoleRequest struct {
+	DBCloudToken string                   `json:"db_cloud_token"`
+	BKCloudID    int                      `json:"bk_cloud_id"`
+	Payloads     []SwapMongoDBRolePayload `json:"payloads"`
+}
+
 // UpdateInstanceStatusPayload update instance status
 type UpdateInstanceStatusPayload struct {
 	IP     string `json:"ip"`
@@ -327,6 +340,38 @@ func (c *CmDBClient) SwapSqlserverRole(masterIp string, masterPort int, slaveIp
 	return nil
 }
 
+// SwapMongoDBRole swap old primary and new primary's cmdb info
+func (c *CmDBClient) SwapMongoDBRole(primaryIp string, primaryPort int, newPrimaryIp string, newPrimaryPort int) error {
+	payload := SwapMongoDBRolePayload{
+		Instance1: DBInstanceInfo{
+			IP:   primaryIp,
+			Port: primaryPort,
+		},
+		Instance2: DBInstanceInfo{
+			IP:   newPrimaryIp,
+			Port: newPrimaryPort,
+		},
+	}
+
+	req := SwapMongoDBRoleRequest{
+		DBCloudToken: c.Conf.BKConf.BkToken,
+		BKCloudID:    c.CloudId,
+		Payloads:     []SwapMongoDBRolePayload{payload},
+	}
+
+	log.Logger.Debugf("SwapMongoDBRole param:%v", req)
+
+	response, err := c.DoNew(
+		http.MethodPost, c.SpliceUrlByPrefix(c.Conf.UrlPre, constvar.CmDBMongoDBSwapRoleUrl, ""), req, nil)
+	if err != nil {
+		return err
+	}
+	if response.Code != 0 {
+		return fmt.Errorf("%s failed, return code:%d, msg:%s", util.AtWhere(), response.Code, response.Msg)
+	}
+	return nil
+}
+
 // SwapRedisRole swap redis master and slave's role info
 func (c *CmDBClient) SwapRedisRole(domain string, masterIp string,
 	masterPort int, slaveIp string, slavePort int) error {
@@ -116,6 +116,23 @@ type ShieldConfigRequest struct {
 	SetArgs      *model.HAShield `json:"set_args,omitempty"`
 }
 
+// GMLeaseRequest request gm lease
+type GMLeaseRequest struct {
+	DBCloudToken string           `json:"db_cloud_token"`
+	BKCloudID    int              `json:"bk_cloud_id"`
+	Name         string           `json:"name"`
+	QueryArgs    *model.HAGMLease `json:"query_args,omitempty"`
+	SetArgs      *model.HAGMLease `json:"set_args,omitempty"`
+}
+
+// GMLeaseResponse acquire gm lease response
+type GMLeaseResponse struct {
+	Acquired bool            `json:"acquired"`
+	Lease    model.HAGMLease `json:"lease"`
+	// milliseconds before the lease expire, computed by hadb clock
+	RemainMs int64 `json:"remain_ms"`
+}
+
 // AgentIp agent ip info
 type AgentIp struct {
 	Ip string `json:"ip"`
@@ -503,6 +520,40 @@ func (c *HaDBClient) QuerySingleTotal(ip string, port int, interval int) (int, e
 	return result.Count, nil
 }
 
+// QueryShadowTotal check same instance's shadow switch number in a given time period
+func (c *HaDBClient) QueryShadowTotal(ip string, port int, interval int) (int, error) {
+	var result struct {
+		Count int `json:"count"`
+	}
+	confirmTime := time.Now().Add(-time.Second * time.Duration(interval))
+	req := SwitchQueueRequest{
+		DBCloudToken: c.Conf.BKConf.BkToken,
+		BKCloudID:    c.CloudId,
+		Name:         constvar.QueryShadowTotal,
+		QueryArgs: &model.HASwitchQueue{
+			IP:               ip,
+			Port:             port,
+			ConfirmCheckTime: &confirmTime,
+		},
+	}
+
+	log.Logger.Debugf("QueryShadowTotal param:%#v", req)
+
+	response, err := c.DoNew(http.MethodPost,
+		c.SpliceUrlByPrefix(c.Conf.UrlPre, constvar.SwitchQueueUrl, ""), req, nil)
+	if err != nil {
+		return 0, err
+	}
+	if response.Code != 0 {
+		return 0, fmt.Errorf("%s failed, return code:%d, msg:%s", util.AtWhere(), response.Code, response.Msg)
+	}
+	err = json.Unmarshal(response.Data, &result)
+	if err != nil {
+		return 0, err
+	}
+	return result.Count, nil
+}
+
 // QueryIntervalTotal get total switch number in a given time period
 func (c *HaDBClient) QueryIntervalTotal(interval int) (int, error) {
 	var result struct {
@@ -790,3 +841,99 @@ func (c *HaDBClient) GetShieldConfig(shield *model.HAShield) (map[string]model.H
 	}
 	return shieldConfigMap, nil
 }
+
+// AcquireGMLease acquire or renew the leader lease of city
+// return whether gm hold the lease and the current lease info
+func (c *HaDBClient) AcquireGMLease(gmIP string, gmPort int, cityID int, leaseTime int) (*GMLeaseResponse, error) {
+	var result GMLeaseResponse
+
+	req := GMLeaseRequest{
+		DBCloudToken: c.Conf.BKConf.BkToken,
+		BKCloudID:    c.CloudId,
+		Name:         constvar.AcquireGMLease,
+		SetArgs: &model.HAGMLease{
+			CityID:     cityID,
+			CloudID:    c.CloudId,
+			HolderIP:   gmIP,
+			HolderPort: gmPort,
+			LeaseTime:  leaseTime,
+		},
+	}
+
+	log.Logger.Debugf("AcquireGMLease param:%#v", req)
+
+	response, err := c.DoNew(http.MethodPost,
+		c.SpliceUrlByPrefix(c.Conf.UrlPre, constvar.GMLeaseUrl, ""), req, nil)
+	if err != nil {
+		return nil, err
+	}
+	if response.Code != 0 {
+		return nil, fmt.Errorf("%s failed, return code:%d, msg:%s", util.AtWhere(), response.Code, response.Msg)
+	}
+	err = json.Unmarshal(response.Data, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ReleaseGMLease release the leader lease, only take effect if fencing token not changed
+func (c *HaDBClient) ReleaseGMLease(gmIP string, gmPort int, cityID int, fencingToken int64) error {
+	var result HaStatusResponse
+
+	req := GMLeaseRequest{
+		DBCloudToken: c.Conf.BKConf.BkToken,
+		BKCloudID:    c.CloudId,
+		Name:         constvar.ReleaseGMLease,
+		QueryArgs: &model.HAGMLease{
+			CityID:       cityID,
+			CloudID:      c.CloudId,
+			HolderIP:     gmIP,
+			HolderPort:   gmPort,
+			FencingToken: fencingToken,
+		},
+	}
+
+	log.Logger.Debugf("ReleaseGMLease param:%#v", req)
+
+	response, err := c.DoNew(http.MethodPost,
+		c.SpliceUrlByPrefix(c.Conf.UrlPre, constvar.GMLeaseUrl, ""), req, nil)
+	if err != nil {
+		return err
+	}
+	if response.Code != 0 {
+		return fmt.Errorf("%s failed, return code:%d, msg:%s", util.AtWhere(), response.Code, response.Msg)
+	}
+	return json.Unmarshal(response.Data, &result)
+}
+
+// GetGMLease query the leader lease of city, return nil if not exist
+func (c *HaDBClient) GetGMLease(cityID int) (*model.HAGMLease, error) {
+	var result *model.HAGMLease
+
+	req := GMLeaseRequest{
+		DBCloudToken: c.Conf.BKConf.BkToken,
+		BKCloudID:    c.CloudId,
+		Name:         constvar.QueryGMLease,
+		QueryArgs: &model.HAGMLease{
+			CityID:  cityID,
+			CloudID: c.CloudId,
+		},
+	}
+
+	log.Logger.Debugf("GetGMLease param:%#v", req)
+
+	response, err := c.DoNew(http.MethodPost,
+		c.SpliceUrlByPrefix(c.Conf.UrlPre, constvar.GMLeaseUrl, ""), req, nil)
+	if err != nil {
+		return nil, err
+	}
+	if response.Code != 0 {
+		return nil, fmt.Errorf("%s failed, return code:%d, msg:%s", util.AtWhere(), response.Code, response.Msg)
+	}
+	err = json.Unmarshal(response.Data, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -173,6 +173,31 @@ type PolarisClbGWResp struct {
 	Ips []string `json:"ips,omitempty"`
 }
 
+// ClbRegister register address to clb
+func (c *NameServiceClient) ClbRegister(
+	region string, lbid string, listenid string, addr string) error {
+	req := map[string]interface{}{
+		"db_cloud_token": c.Conf.BKConf.BkToken,
+		"bk_cloud_id":    c.CloudId,
+		"region":         region,
+		"loadbalancerid": lbid,
+		"listenerid":     listenid,
+		"ips":            []string{addr},
+	}
+
+	log.Logger.Debugf("ClbRegister param:%v", req)
+	response, err := c.DoNew(http.MethodPost,
+		c.SpliceUrlByPrefix(c.Conf.UrlPre, constvar.CLBRegisterUrl, ""),
+		req, nil)
+	if err != nil {
+		log.Logger.Errorf("ClbRegister failed,%s", err.Error())
+		return err
+	}
+
+	log.Logger.Debugf("ClbRegister:%v", response)
+	return nil
+}
+
 // ClbDeRegister un-register address to clb
 func (c *NameServiceClient) ClbDeRegister(
 	region string, lbid string, listenid string, addr string) error {
@@ -255,6 +280,29 @@ func (c *NameServiceClient) GetPolarisTargets(servicename string) ([]string, err
 	return gwResp.Ips, nil
 }
 
+// PolarisBindTarget bind address to polaris
+func (c *NameServiceClient) PolarisBindTarget(
+	servicename string, servertoken string, addr string) error {
+	req := map[string]interface{}{
+		"db_cloud_token": c.Conf.BKConf.BkToken,
+		"bk_cloud_id":    c.CloudId,
+		"servicename":    servicename,
+		"servicetoken":   servertoken,
+		"ips":            []string{addr},
+	}
+
+	log.Logger.Debugf("PolarisBindTarget param:%v", req)
+	response, err := c.DoNew(http.MethodPost,
+		c.SpliceUrlByPrefix(c.Conf.UrlPre, constvar.PolarisBindUrl, ""),
+		req, nil)
+	if err != nil {
+		log.Logger.Errorf("PolarisBindTarget failed,%s", err.Error())
+		return err
+	}
+	log.Logger.Debugf("PolarisBindTarget response:%v", response)
+	return nil
+}
+
 // PolarisUnBindTarget unbind address from polaris
 func (c *NameServiceClient) PolarisUnBindTarget(
 	servicename string, servertoken string, addr string) error {
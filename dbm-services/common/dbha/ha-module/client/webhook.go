@@ -0,0 +1,106 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"dbm-services/common/dbha/ha-module/config"
+	"dbm-services/common/dbha/ha-module/constvar"
+	"dbm-services/common/dbha/ha-module/log"
+)
+
+// WebhookClient client to request switch approval webhook
+type WebhookClient struct {
+	Client
+}
+
+// SwitchApprovalRequest payload send to webhook
+type SwitchApprovalRequest struct {
+	DBCloudToken string `json:"db_cloud_token"`
+	BKCloudID    int    `json:"bk_cloud_id"`
+	Hook         string `json:"hook"`
+	GMIp         string `json:"gm_ip"`
+	Ip           string `json:"ip"`
+	Port         int    `json:"port"`
+	App          string `json:"app"`
+	Cluster      string `json:"cluster"`
+	ClusterType  string `json:"cluster_type"`
+	MetaType     string `json:"meta_type"`
+	Role         string `json:"role"`
+	IdcID        int    `json:"idc_id"`
+}
+
+// SwitchApprovalResponse webhook response data
+type SwitchApprovalResponse struct {
+	// allow, delay or reject
+	Result string `json:"result"`
+	Reason string `json:"reason"`
+}
+
+// NewWebhookClient create new webhook client, request timeout default WebhookDefaultTimeout
+// and no more than WebhookMaxTimeout
+func NewWebhookClient(conf *config.APIConfig, cloudId int) *WebhookClient {
+	webhookConf := *conf
+	if webhookConf.Timeout <= 0 {
+		webhookConf.Timeout = constvar.WebhookDefaultTimeout
+	}
+	webhookConf.Timeout = min(webhookConf.Timeout, constvar.WebhookMaxTimeout)
+	c := NewAPIClient(&webhookConf, constvar.WebhookName, cloudId)
+	return &WebhookClient{c}
+}
+
+// Timeout request timeout of webhook
+func (c *WebhookClient) Timeout() time.Duration {
+	return time.Duration(c.Conf.Timeout) * time.Second
+}
+
+// ApproveSwitch ask webhook whether allow the switch.
+// request only once without retry, gqa would use fail action if failed
+func (c *WebhookClient) ApproveSwitch(
+	ctx context.Context, req *SwitchApprovalRequest,
+) (*SwitchApprovalResponse, error) {
+	var result SwitchApprovalResponse
+	req.DBCloudToken = c.Conf.BKConf.BkToken
+	req.BKCloudID = c.CloudId
+
+	log.Logger.Debugf("ApproveSwitch param:%#v", req)
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiServers[0]+c.Conf.UrlPre,
+		bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	c.setHeader(httpReq, nil)
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("webhook response status code:%d", resp.StatusCode)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	response, err := APIBodyParseCB(b)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(response.(*APIServerResponse).Data, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io/ioutil"
 
+	"dbm-services/common/dbha/ha-module/constvar"
+
 	"github.com/go-playground/validator/v10"
 	"gopkg.in/yaml.v2"
 )
@@ -77,6 +79,28 @@ type GMConfig struct {
 	GMM            GMMConfig `yaml:"GMM"`
 	GQA            GQAConfig `yaml:"GQA"`
 	GCM            GCMConfig `yaml:"GCM"`
+	// Lease leader lease among gm in the same city
+	Lease LeaseConfig `yaml:"lease"`
+}
+
+// LeaseConfig configure for gm leader lease
+type LeaseConfig struct {
+	// disabled, every gm do gqa/gcm for the instance reported to itself
+	Enable bool `yaml:"enable"`
+	// lease time in seconds, the lease expire if holder not renew in time
+	LeaseTime int `yaml:"lease_time"`
+	// renew interval in seconds, should much less than lease_time
+	RenewInterval int `yaml:"renew_interval"`
+	// timeout in seconds to forward agent report to the holder
+	ForwardTimeout int `yaml:"forward_timeout"`
+}
+
+// GetForwardTimeout return forward timeout, default 5 seconds
+func (c *LeaseConfig) GetForwardTimeout() int {
+	if c.ForwardTimeout <= 0 {
+		return constvar.GMForwardTimeout
+	}
+	return c.ForwardTimeout
 }
 
 // GDMConfig configure for GDM component
@@ -97,6 +121,27 @@ type GQAConfig struct {
 	SingleSwitchLimit    int `yaml:"single_switch_limit"`
 	AllHostSwitchLimit   int `yaml:"all_host_switch_limit"`
 	AllSwitchInterval    int `yaml:"all_switch_interval"`
+	// ApprovalHooks check in order before switch, stop at the first not allowed hook
+	ApprovalHooks []ApprovalHookConfig `yaml:"approval_hooks"`
+}
+
+// ApprovalHookConfig configure for gqa switch approval hook
+type ApprovalHookConfig struct {
+	Name string `yaml:"name"`
+	// freeze_window, maintenance_window or webhook
+	Type string `yaml:"type"`
+	// only check the instance of these apps(bk_biz_id), empty means all
+	Apps []string `yaml:"apps"`
+	// only check the instance of these cluster types, empty means all
+	ClusterTypes []string `yaml:"cluster_types"`
+	// window hook: "2006-01-02 15:04:05~2006-01-02 15:04:05" or daily "15:04~15:04"
+	Windows []string `yaml:"windows"`
+	// window hook: delay or reject while in window, default delay
+	Action string `yaml:"action"`
+	// webhook: api address, url_pre is the request path
+	Webhook APIConfig `yaml:"webhook"`
+	// webhook: allow, delay or reject if webhook request failed, default delay
+	FailAction string `yaml:"fail_action"`
 }
 
 // GCMConfig configure for GCM component
@@ -105,6 +150,23 @@ type GCMConfig struct {
 	AllowedSlaveDelayMax     int `yaml:"allowed_slave_delay_max"`
 	AllowedTimeDelayMax      int `yaml:"allowed_time_delay_max"`
 	ExecSlowKBytes           int `yaml:"exec_slow_kbytes"`
+	// shadow mode for all cluster type, gcm only check switch and report the switch plan
+	ShadowMode bool `yaml:"shadow_mode"`
+	// cluster type run in shadow mode, take effect even if ShadowMode is false
+	ShadowClusterTypes []string `yaml:"shadow_cluster_types"`
+}
+
+// IsShadow whether the cluster type run in shadow mode
+func (c *GCMConfig) IsShadow(clusterType string) bool {
+	if c.ShadowMode {
+		return true
+	}
+	for _, t := range c.ShadowClusterTypes {
+		if t == clusterType {
+			return true
+		}
+	}
+	return false
 }
 
 // DBConfig configure for database component
@@ -121,6 +183,8 @@ type DBConfig struct {
 	Riak RiakConfig `yaml:"riak"`
 	// Sqlserver instance detect info
 	Sqlserver SqlserverConfig `yaml:"sqlserver"`
+	// MongoDB instance detect info
+	MongoDB MongoDBConfig `yaml:"mongodb"`
 }
 
 // MySQLConfig mysql instance connect info
@@ -149,6 +213,17 @@ type SqlserverConfig struct {
 	Timeout int    `yaml:"timeout"`
 }
 
+// MongoDBConfig mongodb detect configure
+type MongoDBConfig struct {
+	User string `yaml:"user"`
+	Pass string `yaml:"pass"`
+	// authentication database, admin if not set
+	AuthDB  string `yaml:"auth_db"`
+	Timeout int    `yaml:"timeout"`
+	// seconds to wait candidate become primary while switch, 60 if not set
+	ElectionTimeout int `yaml:"election_timeout"`
+}
+
 // SSHConfig ssh detect configure
 type SSHConfig struct {
 	Port             int    `yaml:"port"`
@@ -13,6 +13,10 @@ const (
 	GQA = "gqa"
 	// GDM component name
 	GDM = "gdm"
+	// GMLease gm leader lease, use as module name in ha logs
+	GMLease = "gm_lease"
+	// GMForwardTimeout default timeout in seconds to forward agent report to the lease holder
+	GMForwardTimeout = 5
 
 	// MONITOR global monitor
 	MONITOR = "monitor"
@@ -34,6 +38,10 @@ const (
 	TendisplusCluster = "PredixyTendisplusCluster"
 	// TwemproxyTendisSSDInstance cluster with tendisssd component
 	TendisSSDCluster = "TwemproxyTendisSSDInstance"
+	// MongoReplicaSet cluster with mongodb replica set member only
+	MongoReplicaSet = "MongoReplicaSet"
+	// MongoShardedCluster cluster with mongos, config server and shard member
+	MongoShardedCluster = "MongoShardedCluster"
 )
 
 // instance type name in cmdb
@@ -61,6 +69,13 @@ const (
 	TendisplusMetaType = "tendisplus"
 	// SqlserverMetatype storage layer type name in SqlserverHa
 	SqlserverMetatype = "sqlserver_ha"
+
+	// MongoDBMetaType replica set member(shardsvr, configsvr or replica set) type name
+	MongoDBMetaType = "mongodb"
+	// MongosMetaType proxy layer type name in MongoShardedCluster
+	MongosMetaType = "mongos"
+	// MongoConfigMetaType config server type name in MongoShardedCluster
+	MongoConfigMetaType = "mongo_config"
 )
 
 // instance role in cmdb
@@ -80,6 +95,9 @@ const (
 	TenDBClusterProxyMaster = "spider_master"
 	// TenDBClusterProxySlave tendbcluster remoteslave role
 	TenDBClusterProxySlave = "spider_slave"
+
+	// MongoBackupRole hidden member used for backup, never be elected as primary
+	MongoBackupRole = "mongo_backup"
 )
 
 // detect type in config.yaml
@@ -108,6 +126,10 @@ const (
 	Riak = "riak"
 	// SqlserverHA TODO
 	SqlserverHA = "sqlserver_ha"
+	// DetectMongoReplicaSet detect mongodb replica set member
+	DetectMongoReplicaSet = "MongoReplicaSet"
+	// DetectMongoShardedCluster detect mongos, config server and shard member
+	DetectMongoShardedCluster = "MongoShardedCluster"
 )
 
 // wrapper name in TenDBCluster
@@ -166,6 +188,8 @@ const (
 	QueryIntervalTotal = "query_interval_total"
 	// QuerySingleIDC TODO
 	QuerySingleIDC = "query_single_idc"
+	// QueryShadowTotal query single instance shadow switch number
+	QueryShadowTotal = "query_shadow_total"
 	// UpdateTimeDelay TODO
 	UpdateTimeDelay = "update_time_delay"
 	// InsertSwitchQueue TODO
@@ -176,6 +200,12 @@ const (
 	UpdateSwitchQueue = "update_switch_queue"
 	// InsertSwitchLog TODO
 	InsertSwitchLog = "insert_switch_log"
+	// AcquireGMLease acquire or renew gm leader lease
+	AcquireGMLease = "acquire_gm_lease"
+	// ReleaseGMLease release gm leader lease
+	ReleaseGMLease = "release_gm_lease"
+	// QueryGMLease query gm leader lease
+	QueryGMLease = "query_gm_lease"
 
 	// HaStatusUrl TODO
 	HaStatusUrl = "hastatus/"
@@ -189,6 +219,8 @@ const (
 	SwitchLogUrl = "switchlogs/"
 	// ShieldConfigUrl api route to request ha_shield_config table
 	ShieldConfigUrl = "shieldconfig/"
+	// GMLeaseUrl api route to request ha_gm_lease table
+	GMLeaseUrl = "gmlease/"
 )
 
 const (
@@ -212,12 +244,16 @@ const (
 	CmDBRedisSwapUrl = "dbmeta/dbha/tendis_cluster_swap/"
 	// CmDBEntryDetailUrl TODO
 	CmDBEntryDetailUrl = "dbmeta/dbha/entry_detail/"
+	// CLBRegisterUrl register address to clb
+	CLBRegisterUrl = "clb_register_part_target/"
 	// CLBDeRegisterUrl TODO
 	CLBDeRegisterUrl = "clb_deregister_part_target/"
 	// CLBGetTargetsUrl TODO
 	CLBGetTargetsUrl = "clb_get_target_private_ips/"
 	// PolarisTargetsUrl TODO
 	PolarisTargetsUrl = "polaris_describe_targets/"
+	// PolarisBindUrl bind address to polaris
+	PolarisBindUrl = "polaris_bind_part_targets/"
 	// PolarisUnBindUrl TODO
 	PolarisUnBindUrl = "polaris_unbind_part_targets/"
 	// BKConfigBatchUrl TODO
@@ -228,6 +264,8 @@ const (
 	BKPasswdQueryUrl = "dbpriv/proxy_password/"
 	//CmDBSqlserverSwapRoleUrl TODO
 	CmDBSqlserverSwapRoleUrl = "dbmeta/dbha/sqlserver_cluster_swap/"
+	//CmDBMongoDBSwapRoleUrl swap mongodb replica set member's role
+	CmDBMongoDBSwapRoleUrl = "dbmeta/dbha/mongodb_cluster_swap/"
 )
 
 // name service's type
@@ -248,6 +286,8 @@ const (
 	ApiGWName = "apigw"
 	// DBConfigName TODO
 	DBConfigName = "db_config"
+	// WebhookName switch approval webhook
+	WebhookName = "webhook"
 
 	// BkApiAuthorization TODO
 	BkApiAuthorization = "x-bkapi-authorization"
@@ -351,6 +391,10 @@ const (
 	DBHAEventRiakSwitchSucc = "dbha_riak_switch_ok"
 	// DBHAEventMysqlSwitchErr TODO
 	DBHAEventRiakSwitchErr = "dbha_riak_switch_err"
+	// DBHAEventMongoDBSwitchSucc mongodb switch success event
+	DBHAEventMongoDBSwitchSucc = "dbha_mongodb_switch_ok"
+	// DBHAEventMongoDBSwitchErr mongodb switch failed event
+	DBHAEventMongoDBSwitchErr = "dbha_mongodb_switch_err"
 	// DBHAEventDetectAuth TODO
 	DBHAEventDetectAuth = "dbha_detect_auth_fail"
 	// DBHAEventDetectSSH TODO
@@ -390,6 +434,32 @@ const (
 	SwitchStart   = "doing"
 	SwitchFailed  = "failed"
 	SwitchSuccess = "success"
+	// SwitchShadow switch run in shadow mode, nothing changed
+	SwitchShadow = "shadow"
+)
+
+// gqa switch approval hook type
+const (
+	// HookFreezeWindow reject/delay switch during change freeze window
+	HookFreezeWindow = "freeze_window"
+	// HookMaintenanceWindow reject/delay switch during business declared maintenance window
+	HookMaintenanceWindow = "maintenance_window"
+	// HookWebhook ask remote http api whether allow switch
+	HookWebhook = "webhook"
+	// WebhookDefaultTimeout seconds to wait webhook response if timeout not configured
+	WebhookDefaultTimeout = 3
+	// WebhookMaxTimeout gqa process instance one by one, webhook could not block it too long
+	WebhookMaxTimeout = 10
+)
+
+// gqa switch approval result
+const (
+	// ApprovalAllow allow switch, check next hook
+	ApprovalAllow = "allow"
+	// ApprovalDelay delay switch, instance would be processed again if still reported
+	ApprovalDelay = "delay"
+	// ApprovalReject reject switch
+	ApprovalReject = "reject"
 )
 
 // gcm use blow switch key to set/get switch instance info
@@ -403,6 +473,8 @@ const (
 	SlaveIpKey = "slave_ip"
 	// SlavePortKey use to set slave port
 	SlavePortKey = "slave_port"
+	// FencingTokenKey gcm use to set the gm lease fencing token before switch
+	FencingTokenKey = "fencing_token"
 )
 
 // checksum sql
@@ -436,3 +508,12 @@ const (
 const (
 	RiakHttpPort = 8098
 )
+
+const (
+	// MongoDefaultAuthDB default authentication database of mongodb
+	MongoDefaultAuthDB = "admin"
+	// MongoDefaultElectionTimeout default seconds to wait candidate become primary
+	MongoDefaultElectionTimeout = 60
+	// MongoStepDownSecs seconds the old primary ineligible to be primary after step down
+	MongoStepDownSecs = 60
+)
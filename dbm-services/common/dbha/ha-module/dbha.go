@@ -4,6 +4,8 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"dbm-services/common/dbha/ha-module/agent"
@@ -77,6 +79,15 @@ func main() {
 		<-c
 	case constvar.GM:
 		GM := gm.NewGM(conf)
+		go func() {
+			// release lease before exit, so that other gm take over immediately
+			sigChan := make(chan os.Signal, 1)
+			signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+			sig := <-sigChan
+			log.Logger.Infof("GM receive signal %s, exit", sig.String())
+			GM.GetLease().Release()
+			os.Exit(0)
+		}()
 		if err = GM.Run(); err != nil {
 			log.Logger.Fatalf("GM run failed. err:%s", err.Error())
 			os.Exit(1)
@@ -138,6 +138,32 @@ func (ins *MySQLSwitch) DoSwitch() error {
 	return nil
 }
 
+// ShowSwitchPlan show what DoSwitch would do, shadow mode used
+func (ins *MySQLSwitch) ShowSwitchPlan() ([]string, error) {
+	masterStatus, err := ins.GetStandbyMasterStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	var plan []string
+	for _, proxyIns := range ins.Proxy {
+		plan = append(plan, fmt.Sprintf("flush proxy:[%s:%d]'s backends to 1.1.1.1",
+			proxyIns.Ip, proxyIns.Port))
+	}
+	plan = append(plan, fmt.Sprintf("reset slave on [%s:%d], current binlog info:%s,%d",
+		ins.StandBySlave.Ip, ins.StandBySlave.Port, masterStatus.File, masterStatus.Position))
+	for _, proxyIns := range ins.Proxy {
+		plan = append(plan, fmt.Sprintf("flush proxy[%s:%d]'s backend to [%s:%d]",
+			proxyIns.Ip, proxyIns.Port, ins.StandBySlave.Ip, ins.StandBySlave.Port))
+	}
+	plan = append(plan, fmt.Sprintf("swap role of master[%s:%d] and slave[%s:%d] in cmdb",
+		ins.Ip, ins.Port, ins.StandBySlave.Ip, ins.StandBySlave.Port))
+	for _, dumper := range ins.Dumper {
+		plan = append(plan, fmt.Sprintf("switch tbinlogdumper[%s:%d] to new master", dumper.Ip, dumper.Port))
+	}
+	return plan, nil
+}
+
 // RollBack do switch rollback
 func (ins *MySQLSwitch) RollBack() error {
 	return nil
@@ -26,6 +26,11 @@ func (ins *MySQLProxySwitch) DoSwitch() error {
 	return ins.DeleteNameService(ins.Entry)
 }
 
+// ShowSwitchPlan show which entry would be released, shadow mode used
+func (ins *MySQLProxySwitch) ShowSwitchPlan() ([]string, error) {
+	return ins.ShowNameServicePlan(ins.Entry), nil
+}
+
 // ShowSwitchInstanceInfo display switch proxy info
 func (ins *MySQLProxySwitch) ShowSwitchInstanceInfo() string {
 	str := fmt.Sprintf("<%s#%d IDC:%d Status:%s Bzid:%s ClusterType:%s MachineType:%s> switch",
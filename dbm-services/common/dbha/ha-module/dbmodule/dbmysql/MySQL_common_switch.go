@@ -620,6 +620,34 @@ func (ins *MySQLCommonSwitch) ResetSlave() (string, uint64, error) {
 	return masterStatus.File, masterStatus.Position, nil
 }
 
+// GetStandbyMasterStatus get standby slave's binlog position without any change, shadow mode used
+func (ins *MySQLCommonSwitch) GetStandbyMasterStatus() (MasterStatus, error) {
+	slaveIp := ins.StandBySlave.Ip
+	slavePort := ins.StandBySlave.Port
+	user := ins.Config.DBConf.MySQL.User
+	pass := ins.Config.DBConf.MySQL.Pass
+
+	connParam := fmt.Sprintf("%s:%s@(%s:%d)/%s", user, pass, slaveIp, slavePort, "infodba_schema")
+	db, err := gorm.Open(mysql.Open(connParam), &gorm.Config{
+		Logger: log.GormLogger,
+	})
+	if err != nil {
+		log.Logger.Errorf("open mysql failed. ip:%s, port:%d, err:%s", slaveIp, slavePort, err.Error())
+		return MasterStatus{}, err
+	}
+	defer func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	}()
+
+	var masterStatus MasterStatus
+	if err = db.Raw("show master status").Scan(&masterStatus).Error; err != nil {
+		return MasterStatus{}, fmt.Errorf("show master status failed, err:%s", err.Error())
+	}
+	return masterStatus, nil
+}
+
 // UpdateMetaInfo swap master, slave 's meta info in cmdb
 func (ins *MySQLCommonSwitch) UpdateMetaInfo() error {
 	return nil
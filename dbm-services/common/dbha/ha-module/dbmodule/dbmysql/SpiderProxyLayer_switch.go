@@ -178,6 +178,23 @@ func (ins *SpiderProxyLayerSwitch) DoSwitch() error {
 	return nil
 }
 
+// ShowSwitchPlan show what DoSwitch would do, shadow mode used
+func (ins *SpiderProxyLayerSwitch) ShowSwitchPlan() ([]string, error) {
+	if err := ins.SetRoutes(); err != nil {
+		return nil, err
+	}
+
+	plan := ins.ShowNameServicePlan(ins.Entry)
+	if ins.PrimaryTdbctl.CurrentServer == 1 {
+		plan = append(plan, "primary tdbctl broken-down, elect a new one")
+	}
+	plan = append(plan,
+		fmt.Sprintf("remove spider node[%s#%d] from route table", ins.Ip, ins.Port),
+		fmt.Sprintf("remove tdbctl node[%s#%d] from route table", ins.Ip, ins.AdminPort),
+		"flush route table")
+	return plan, nil
+}
+
 // RollBack proxy do rollback
 func (ins *SpiderProxyLayerSwitch) RollBack() error {
 	return nil
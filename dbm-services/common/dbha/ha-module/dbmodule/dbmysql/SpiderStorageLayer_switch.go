@@ -127,6 +127,32 @@ func (ins *SpiderStorageSwitch) DoSwitch() error {
 	return nil
 }
 
+// ShowSwitchPlan show what DoSwitch would do, shadow mode used
+func (ins *SpiderStorageSwitch) ShowSwitchPlan() ([]string, error) {
+	masterStatus, err := ins.GetStandbyMasterStatus()
+	if err != nil {
+		return nil, err
+	}
+	if err = ins.SetRoutes(); err != nil {
+		return nil, err
+	}
+	oldMaster := ins.GetRouteInfo(ins.Ip, ins.Port)
+	if oldMaster == nil {
+		return nil, fmt.Errorf("no master's record found in route table")
+	}
+
+	return []string{
+		fmt.Sprintf("new master[%s#%d] current binlog info:%s,%d",
+			ins.StandBySlave.Ip, ins.StandBySlave.Port, masterStatus.File, masterStatus.Position),
+		fmt.Sprintf("alter route node[%s] from [%s#%d] to [%s#%d] on primary tdbctl[%s#%d]",
+			oldMaster.ServerName, ins.Ip, ins.Port, ins.StandBySlave.Ip, ins.StandBySlave.Port,
+			ins.PrimaryTdbctl.Host, ins.PrimaryTdbctl.Port),
+		"flush route table",
+		fmt.Sprintf("swap role of master[%s:%d] and slave[%s:%d] in cmdb",
+			ins.Ip, ins.Port, ins.StandBySlave.Ip, ins.StandBySlave.Port),
+	}, nil
+}
+
 // ShowSwitchInstanceInfo show db-mysql instance's switch info
 func (ins *SpiderStorageSwitch) ShowSwitchInstanceInfo() string {
 	str := fmt.Sprintf("<%s#%d IDC:%d Role:%s Status:%s Bzid:%s ClusterType:%s MachineType:%s>",
@@ -0,0 +1,292 @@
+// Package mongodb detect and switch MongoReplicaSet/MongoShardedCluster instance
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"dbm-services/common/dbha/ha-module/constvar"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/auth"
+)
+
+// member state in replSetGetStatus
+// refer to https://www.mongodb.com/docs/manual/reference/replica-states/
+const (
+	StatePrimary    = 1
+	StateSecondary  = 2
+	StateRecovering = 3
+	StateStartup2   = 5
+	StateUnknown    = 6
+	StateArbiter    = 7
+	StateDown       = 8
+	StateRollback   = 9
+	StateRemoved    = 10
+)
+
+// IgnoreErrorCode mongodb detect ignore error code
+// 13:Unauthorized
+// 18:AuthenticationFailed
+var IgnoreErrorCode = []int32{13, 18}
+
+// HelloResult part of hello/isMaster command response
+type HelloResult struct {
+	IsWritablePrimary bool   `bson:"isWritablePrimary"`
+	IsMaster          bool   `bson:"ismaster"`
+	Secondary         bool   `bson:"secondary"`
+	ArbiterOnly       bool   `bson:"arbiterOnly"`
+	Hidden            bool   `bson:"hidden"`
+	SetName           string `bson:"setName"`
+	Primary           string `bson:"primary"`
+	Me                string `bson:"me"`
+	Msg               string `bson:"msg"`
+}
+
+// IsPrimary whether the instance is writable primary
+// isWritablePrimary only return by hello(5.0+), ismaster return by isMaster
+func (h *HelloResult) IsPrimary() bool {
+	return h.IsWritablePrimary || h.IsMaster
+}
+
+// ReplSetMemberStatus member info in replSetGetStatus
+type ReplSetMemberStatus struct {
+	Id         int       `bson:"_id"`
+	Name       string    `bson:"name"`
+	Health     float64   `bson:"health"`
+	State      int       `bson:"state"`
+	StateStr   string    `bson:"stateStr"`
+	OptimeDate time.Time `bson:"optimeDate"`
+	Self       bool      `bson:"self"`
+}
+
+// ReplSetStatus part of replSetGetStatus command response
+type ReplSetStatus struct {
+	Set     string                `bson:"set"`
+	MyState int                   `bson:"myState"`
+	Members []ReplSetMemberStatus `bson:"members"`
+}
+
+// Primary return the primary member in replica set, nil if not found
+func (s *ReplSetStatus) Primary() *ReplSetMemberStatus {
+	for i := range s.Members {
+		if s.Members[i].State == StatePrimary {
+			return &s.Members[i]
+		}
+	}
+	return nil
+}
+
+// GetMember return member by host:port, nil if not found
+func (s *ReplSetStatus) GetMember(host string) *ReplSetMemberStatus {
+	for i := range s.Members {
+		if s.Members[i].Name == host {
+			return &s.Members[i]
+		}
+	}
+	return nil
+}
+
+// LatestOptime return the newest optime of all healthy members
+func (s *ReplSetStatus) LatestOptime() time.Time {
+	var latest time.Time
+	for _, m := range s.Members {
+		if m.Health == 1 && m.OptimeDate.After(latest) {
+			latest = m.OptimeDate
+		}
+	}
+	return latest
+}
+
+// NewMongoClient connect to mongod/mongos directly, never discover other member
+func NewMongoClient(ip string, port int, user string, pass string, authDB string,
+	timeout int) (*mongo.Client, error) {
+	uri := fmt.Sprintf("mongodb://%s:%d/?directConnection=true", ip, port)
+	opts := options.Client().ApplyURI(uri).
+		SetConnectTimeout(time.Duration(timeout) * time.Second).
+		SetServerSelectionTimeout(time.Duration(timeout) * time.Second).
+		SetSocketTimeout(time.Duration(timeout) * time.Second)
+	if user != "" {
+		if authDB == "" {
+			authDB = constvar.MongoDefaultAuthDB
+		}
+		opts.SetAuth(options.Credential{
+			AuthSource: authDB,
+			Username:   user,
+			Password:   pass,
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+	return mongo.Connect(ctx, opts)
+}
+
+// CloseMongoClient disconnect mongo client
+func CloseMongoClient(client *mongo.Client, timeout int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+	return client.Disconnect(ctx)
+}
+
+// RunAdminCommand run command in admin database and decode response into result
+func RunAdminCommand(client *mongo.Client, timeout int, cmd bson.D, result interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+	res := client.Database("admin").RunCommand(ctx, cmd)
+	if res.Err() != nil {
+		return res.Err()
+	}
+	if result == nil {
+		return nil
+	}
+	return res.Decode(result)
+}
+
+// Hello run hello command, fallback to isMaster if mongod not support hello(before 4.4.2)
+func Hello(client *mongo.Client, timeout int) (*HelloResult, error) {
+	result := &HelloResult{}
+	err := RunAdminCommand(client, timeout, bson.D{{Key: "hello", Value: 1}}, result)
+	if err == nil {
+		return result, nil
+	}
+	var cmdErr mongo.CommandError
+	// 59:CommandNotFound
+	if errors.As(err, &cmdErr) && cmdErr.Code == 59 {
+		err = RunAdminCommand(client, timeout, bson.D{{Key: "isMaster", Value: 1}}, result)
+		if err == nil {
+			return result, nil
+		}
+	}
+	return nil, err
+}
+
+// GetReplSetStatus run replSetGetStatus command
+func GetReplSetStatus(client *mongo.Client, timeout int) (*ReplSetStatus, error) {
+	status := &ReplSetStatus{}
+	if err := RunAdminCommand(client, timeout, bson.D{{Key: "replSetGetStatus", Value: 1}}, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// GetReplSetConfig run replSetGetConfig command, keep the raw document to reconfig
+func GetReplSetConfig(client *mongo.Client, timeout int) (bson.D, error) {
+	var res struct {
+		Config bson.D `bson:"config"`
+	}
+	if err := RunAdminCommand(client, timeout, bson.D{{Key: "replSetGetConfig", Value: 1}}, &res); err != nil {
+		return nil, err
+	}
+	if len(res.Config) == 0 {
+		return nil, fmt.Errorf("replSetGetConfig return empty config")
+	}
+	return res.Config, nil
+}
+
+// IsIgnoreError whether the error could be ignored while detect
+// authenticate failed means mongod alive, the same as mysql detect
+func IsIgnoreError(err error) bool {
+	var authErr *auth.Error
+	if errors.As(err, &authErr) {
+		return true
+	}
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) {
+		for _, code := range IgnoreErrorCode {
+			if cmdErr.Code == code {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsHealthyState whether member's state means the instance alive
+func IsHealthyState(state int) bool {
+	switch state {
+	case StatePrimary, StateSecondary, StateArbiter, StateRecovering, StateStartup2, StateRollback:
+		return true
+	default:
+		return false
+	}
+}
+
+// splitHost split member name host:port
+func splitHost(host string) (string, int, error) {
+	ip, portStr, err := net.SplitHostPort(host)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid member host %s:%s", host, err.Error())
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid member port %s:%s", host, err.Error())
+	}
+	return ip, port, nil
+}
+
+// hasIp whether ip in the bind ip list
+func hasIp(ips []string, ip string) bool {
+	for _, v := range ips {
+		if v == ip {
+			return true
+		}
+	}
+	return false
+}
+
+// docValue return the value of key in document, nil if not found
+func docValue(doc primitive.D, key string) interface{} {
+	for _, e := range doc {
+		if e.Key == key {
+			return e.Value
+		}
+	}
+	return nil
+}
+
+// setDocValue set the value of key in document, append if not found
+func setDocValue(doc primitive.D, key string, value interface{}) primitive.D {
+	for i, e := range doc {
+		if e.Key == key {
+			doc[i].Value = value
+			return doc
+		}
+	}
+	return append(doc, primitive.E{Key: key, Value: value})
+}
+
+// electable whether the member in replica set config could be primary
+// priority default 1 if not set, hidden member must be priority 0
+func electable(doc primitive.D) bool {
+	if hidden, _ := docValue(doc, "hidden").(bool); hidden {
+		return false
+	}
+	if arbiter, _ := docValue(doc, "arbiterOnly").(bool); arbiter {
+		return false
+	}
+	priority := docValue(doc, "priority")
+	return priority == nil || toFloat(priority) > 0
+}
+
+// toFloat convert bson number to float64, the number type in replica set config
+// depend on the client which last reconfig
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	default:
+		return 0
+	}
+}
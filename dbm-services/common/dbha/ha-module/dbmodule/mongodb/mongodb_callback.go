@@ -0,0 +1,164 @@
+package mongodb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"dbm-services/common/dbha/ha-module/client"
+	"dbm-services/common/dbha/ha-module/config"
+	"dbm-services/common/dbha/ha-module/constvar"
+	"dbm-services/common/dbha/ha-module/dbutil"
+	"dbm-services/common/dbha/ha-module/log"
+)
+
+// UnMarshalMongoDBInstanceByCmdb convert cmdb instance info to MongoDBDetectInstanceInfoFromCmDB
+func UnMarshalMongoDBInstanceByCmdb(instances []interface{},
+	clusterType string) ([]*MongoDBDetectInstanceInfoFromCmDB, error) {
+	var (
+		ret []*MongoDBDetectInstanceInfoFromCmDB
+	)
+	cache := map[string]*MongoDBDetectInstanceInfoFromCmDB{}
+
+	for _, v := range instances {
+		ins := dbutil.DBInstanceInfoDetail{}
+		rawData, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("marshal instance info failed:%s", err.Error())
+		}
+		if err = json.Unmarshal(rawData, &ins); err != nil {
+			return nil, fmt.Errorf("unmarshal instance info failed:%s", err.Error())
+		}
+		if ins.ClusterType != clusterType || (ins.Status != constvar.RUNNING && ins.Status != constvar.AVAILABLE) {
+			continue
+		}
+		cacheIns, ok := cache[ins.IP]
+		//only need detect the minimum port instance
+		if !ok || ok && ins.Port < cacheIns.Port {
+			cache[ins.IP] = &MongoDBDetectInstanceInfoFromCmDB{
+				Ip:          ins.IP,
+				Port:        ins.Port,
+				App:         strconv.Itoa(ins.BKBizID),
+				ClusterType: ins.ClusterType,
+				MetaType:    ins.MachineType,
+				Cluster:     ins.Cluster,
+				ClusterId:   ins.ClusterId,
+			}
+		}
+	}
+
+	for _, cacheIns := range cache {
+		ret = append(ret, cacheIns)
+	}
+
+	return ret, nil
+}
+
+// NewMongoReplicaSetByCmDB unmarshal cmdb instances to detect instance struct
+// filter only MongoReplicaSet
+func NewMongoReplicaSetByCmDB(instances []interface{}, conf *config.Config) ([]dbutil.DataBaseDetect, error) {
+	return newMongoDBDetectInstanceByCmDB(instances, constvar.DetectMongoReplicaSet, conf)
+}
+
+// NewMongoShardedClusterByCmDB unmarshal cmdb instances to detect instance struct
+// filter only MongoShardedCluster
+func NewMongoShardedClusterByCmDB(instances []interface{}, conf *config.Config) ([]dbutil.DataBaseDetect, error) {
+	return newMongoDBDetectInstanceByCmDB(instances, constvar.DetectMongoShardedCluster, conf)
+}
+
+func newMongoDBDetectInstanceByCmDB(instances []interface{}, clusterType string,
+	conf *config.Config) ([]dbutil.DataBaseDetect, error) {
+	var (
+		err          error
+		unmarshalIns []*MongoDBDetectInstanceInfoFromCmDB
+		ret          []dbutil.DataBaseDetect
+	)
+
+	unmarshalIns, err = UnMarshalMongoDBInstanceByCmdb(instances, clusterType)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, uIns := range unmarshalIns {
+		ret = append(ret, AgentNewMongoDBDetectInstance(uIns, conf))
+	}
+
+	return ret, err
+}
+
+// DeserializeMongoDB gdm convert agent report info into DataBaseDetect
+func DeserializeMongoDB(jsonInfo []byte, conf *config.Config) (dbutil.DataBaseDetect, error) {
+	response := MongoDBDetectResponse{}
+	err := json.Unmarshal(jsonInfo, &response)
+	if err != nil {
+		log.Logger.Errorf("json unmarshal failed. jsoninfo:\n%s\n, err:%s", string(jsonInfo), err.Error())
+		return nil, err
+	}
+
+	ret := GMNewMongoDBDetectInstance(&response, conf)
+	return ret, nil
+}
+
+// NewMongoDBSwitchInstance unmarshal cmdb instances to switch instance
+// GQA call this and send to gcm switch
+func NewMongoDBSwitchInstance(instances []interface{}, conf *config.Config) ([]dbutil.DataBaseSwitch, error) {
+	var ret []dbutil.DataBaseSwitch
+	initFunc := func(ins dbutil.DBInstanceInfoDetail) (dbutil.DataBaseSwitch, error) {
+		log.Logger.Debugf("mongodb instance detail info:%#v", ins)
+		baseSwitch := dbutil.BaseSwitch{
+			Ip:          ins.IP,
+			Port:        ins.Port,
+			IdcID:       ins.BKIdcCityID,
+			Status:      ins.Status,
+			App:         strconv.Itoa(ins.BKBizID),
+			ClusterType: ins.ClusterType,
+			MetaType:    ins.MachineType,
+			Cluster:     ins.Cluster,
+			ClusterId:   ins.ClusterId,
+			Config:      conf,
+			CmDBClient:  client.NewCmDBClient(&conf.DBConf.CMDB, conf.GetCloudId()),
+			HaDBClient:  client.NewHaDBClient(&conf.DBConf.HADB, conf.GetCloudId()),
+		}
+
+		switch ins.MachineType {
+		case constvar.MongoDBMetaType, constvar.MongoConfigMetaType:
+			swIns := &MongoDBSwitch{
+				BaseSwitch:      baseSwitch,
+				Role:            ins.InstanceRole,
+				Entry:           ins.BindEntry,
+				User:            conf.DBConf.MongoDB.User,
+				Pass:            conf.DBConf.MongoDB.Pass,
+				AuthDB:          conf.DBConf.MongoDB.AuthDB,
+				Timeout:         conf.DBConf.MongoDB.Timeout,
+				ElectionTimeout: conf.DBConf.MongoDB.ElectionTimeout,
+			}
+			swIns.SetStandbySlave(ins.Receiver)
+			return swIns, nil
+		case constvar.MongosMetaType:
+			return &MongosSwitch{
+				BaseSwitch: baseSwitch,
+				Entry:      ins.BindEntry,
+			}, nil
+		default:
+			return nil, fmt.Errorf("unsupport MongoDB meta type:%s", ins.MachineType)
+		}
+	}
+
+	for _, v := range instances {
+		ins := dbutil.DBInstanceInfoDetail{}
+		rawData, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("marshal instance info failed:%s", err.Error())
+		}
+		if err = json.Unmarshal(rawData, &ins); err != nil {
+			return nil, fmt.Errorf("unmarshal instance info failed:%s", err.Error())
+		}
+		swIns, err := initFunc(ins)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, swIns)
+	}
+
+	return ret, nil
+}
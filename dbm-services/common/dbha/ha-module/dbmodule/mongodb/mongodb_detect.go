@@ -0,0 +1,252 @@
+package mongodb
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"dbm-services/common/dbha/ha-module/config"
+	"dbm-services/common/dbha/ha-module/constvar"
+	"dbm-services/common/dbha/ha-module/dbutil"
+	"dbm-services/common/dbha/ha-module/log"
+	"dbm-services/common/dbha/ha-module/types"
+	"dbm-services/common/dbha/ha-module/util"
+)
+
+// MongoDBDetectInstance mongodb instance detect struct
+type MongoDBDetectInstance struct {
+	dbutil.BaseDetectDB
+	MetaType string
+	User     string
+	Pass     string
+	AuthDB   string
+	Timeout  int
+	dbMutex  sync.Mutex
+}
+
+// MongoDBDetectResponse mongodb instance response struct
+type MongoDBDetectResponse struct {
+	dbutil.BaseDetectDBResponse
+	MetaType string `json:"meta_type"`
+}
+
+// MongoDBDetectInstanceInfoFromCmDB mongodb instance detect struct in cmdb
+type MongoDBDetectInstanceInfoFromCmDB struct {
+	Ip          string
+	Port        int
+	App         string
+	ClusterType string
+	MetaType    string
+	Cluster     string
+	ClusterId   int
+}
+
+// AgentNewMongoDBDetectInstance convert CmDBInstanceUrl response info to MongoDBDetectInstance
+func AgentNewMongoDBDetectInstance(ins *MongoDBDetectInstanceInfoFromCmDB,
+	conf *config.Config) *MongoDBDetectInstance {
+	return &MongoDBDetectInstance{
+		BaseDetectDB: dbutil.BaseDetectDB{
+			Ip:             ins.Ip,
+			Port:           ins.Port,
+			App:            ins.App,
+			DBType:         types.DBType(fmt.Sprintf("%s:%s", ins.ClusterType, ins.MetaType)),
+			ReporterTime:   time.Unix(0, 0),
+			ReportInterval: conf.AgentConf.ReportInterval + rand.Intn(20),
+			Status:         constvar.DBCheckSuccess,
+			Cluster:        ins.Cluster,
+			ClusterType:    ins.ClusterType,
+			ClusterId:      ins.ClusterId,
+			SshInfo: dbutil.Ssh{
+				Port:    conf.SSH.Port,
+				User:    conf.SSH.User,
+				Pass:    conf.SSH.Pass,
+				Dest:    conf.SSH.Dest,
+				Timeout: conf.SSH.Timeout,
+			},
+		},
+		MetaType: ins.MetaType,
+		User:     conf.DBConf.MongoDB.User,
+		Pass:     conf.DBConf.MongoDB.Pass,
+		AuthDB:   conf.DBConf.MongoDB.AuthDB,
+		Timeout:  conf.DBConf.MongoDB.Timeout,
+	}
+}
+
+// GMNewMongoDBDetectInstance GDM convert agent report info into MongoDBDetectInstance
+func GMNewMongoDBDetectInstance(ins *MongoDBDetectResponse, conf *config.Config) *MongoDBDetectInstance {
+	return &MongoDBDetectInstance{
+		BaseDetectDB: dbutil.BaseDetectDB{
+			Ip:             ins.DBIp,
+			Port:           ins.DBPort,
+			App:            ins.App,
+			DBType:         types.DBType(ins.DBType),
+			ReporterTime:   time.Unix(0, 0),
+			ReportInterval: conf.AgentConf.ReportInterval + rand.Intn(20),
+			Status:         types.CheckStatus(ins.Status),
+			Cluster:        ins.Cluster,
+			ClusterType:    ins.ClusterType,
+			ClusterId:      ins.ClusterId,
+			SshInfo: dbutil.Ssh{
+				Port:    conf.SSH.Port,
+				User:    conf.SSH.User,
+				Pass:    conf.SSH.Pass,
+				Dest:    conf.SSH.Dest,
+				Timeout: conf.SSH.Timeout,
+			},
+		},
+		MetaType: ins.MetaType,
+		User:     conf.DBConf.MongoDB.User,
+		Pass:     conf.DBConf.MongoDB.Pass,
+		AuthDB:   conf.DBConf.MongoDB.AuthDB,
+		Timeout:  conf.DBConf.MongoDB.Timeout,
+	}
+}
+
+// GetType return dbType
+func (m *MongoDBDetectInstance) GetType() types.DBType {
+	return m.DBType
+}
+
+// GetDetectType return clusterType
+func (m *MongoDBDetectInstance) GetDetectType() string {
+	return m.ClusterType
+}
+
+// Detection agent, gmm call this do lived detect
+// return error:
+//
+//	not nil: check db failed or do ssh failed
+//	nil:     check db success
+func (m *MongoDBDetectInstance) Detection() error {
+	recheck := 1
+	var mongoErr error
+	needRecheck := true
+	for i := 0; i <= recheck && needRecheck; i++ {
+		// 设置缓冲为1防止没有接收者导致阻塞，即Detection已经超时返回
+		errChan := make(chan error, 2)
+		// 同MySQL探测一样，如果协程阻塞在连接mongodb上，这里超时返回后协程依然存在，
+		// 因此mongo客户端连接也需要设置超时时间
+		go m.CheckMongoDB(errChan)
+		select {
+		case mongoErr = <-errChan:
+			if mongoErr != nil {
+				if IsIgnoreError(mongoErr) {
+					log.Logger.Warnf("ignore error:%s, check mongodb ok. ip:%s, port:%d, app:%s",
+						mongoErr.Error(), m.Ip, m.Port, m.App)
+					m.Status = constvar.DBCheckSuccess
+					return nil
+				}
+				log.Logger.Warnf("check mongodb failed:%s. ip:%s, port:%d, app:%s",
+					mongoErr.Error(), m.Ip, m.Port, m.App)
+				m.Status = constvar.DBCheckFailed
+				needRecheck = false
+			} else {
+				m.Status = constvar.DBCheckSuccess
+				return nil
+			}
+		case <-time.After(time.Second * time.Duration(m.Timeout)):
+			mongoErr = fmt.Errorf("connect MongoDB timeout recheck:%d", recheck)
+			log.Logger.Warnf(mongoErr.Error())
+			m.Status = constvar.DBCheckFailed
+		}
+	}
+
+	sshErr := m.CheckSSH()
+	if sshErr != nil {
+		if util.CheckSSHErrIsAuthFail(sshErr) {
+			m.Status = constvar.AUTHCheckFailed
+			log.Logger.Warnf("check ssh auth failed. ip:%s, port:%d, app:%s, status:%s",
+				m.Ip, m.Port, m.App, m.Status)
+		} else {
+			m.Status = constvar.SSHCheckFailed
+			log.Logger.Warnf("check ssh failed. ip:%s, port:%d, app:%s, status:%s",
+				m.Ip, m.Port, m.App, m.Status)
+		}
+		return sshErr
+	} else {
+		log.Logger.Infof("check ssh success. ip:%s, port:%d, app:%s", m.Ip, m.Port, m.App)
+		m.Status = constvar.SSHCheckSuccess
+	}
+	return mongoErr
+}
+
+// CheckMongoDB check whether mongod/mongos alive
+// mongos only need hello success, replica set member also need a healthy state
+func (m *MongoDBDetectInstance) CheckMongoDB(errChan chan error) {
+	m.dbMutex.Lock()
+	defer m.dbMutex.Unlock()
+
+	client, err := NewMongoClient(m.Ip, m.Port, m.User, m.Pass, m.AuthDB, m.Timeout)
+	if err != nil {
+		log.Logger.Warnf("connect mongodb failed. ip:%s, port:%d, err:%s", m.Ip, m.Port, err.Error())
+		errChan <- err
+		return
+	}
+	defer func() {
+		if err := CloseMongoClient(client, m.Timeout); err != nil {
+			log.Logger.Warnf("close connect[%s#%d] failed:%s", m.Ip, m.Port, err.Error())
+		}
+	}()
+
+	hello, err := Hello(client, m.Timeout)
+	if err != nil {
+		log.Logger.Warnf("mongodb hello failed. ip:%s, port:%d, err:%s", m.Ip, m.Port, err.Error())
+		errChan <- err
+		return
+	}
+
+	if m.MetaType == constvar.MongosMetaType || hello.SetName == "" {
+		errChan <- nil
+		return
+	}
+
+	status, err := GetReplSetStatus(client, m.Timeout)
+	if err != nil {
+		log.Logger.Warnf("mongodb replSetGetStatus failed. ip:%s, port:%d, err:%s", m.Ip, m.Port, err.Error())
+		errChan <- err
+		return
+	}
+	if !IsHealthyState(status.MyState) {
+		err = fmt.Errorf("replica set member state abnormal, set:%s, state:%d", status.Set, status.MyState)
+		log.Logger.Warnf("ip:%s, port:%d, err:%s", m.Ip, m.Port, err.Error())
+		errChan <- err
+		return
+	}
+
+	errChan <- nil
+}
+
+// CheckSSH use ssh check whether machine alived
+func (m *MongoDBDetectInstance) CheckSSH() error {
+	touchFile := fmt.Sprintf("%s_%s_%d", m.SshInfo.Dest, "agent", m.Port)
+
+	touchStr := fmt.Sprintf("touch %s && if [ -d \"/data1/dbha/\" ]; then touch /data1/dbha/%s ; fi "+
+		"&& if [ -d \"/data/dbha/\" ]; then touch /data/dbha/%s ; fi", touchFile, touchFile, touchFile)
+
+	if err := m.DoSSH(touchStr); err != nil {
+		log.Logger.Warnf("do ssh failed. err:%s", err.Error())
+		return err
+	}
+	return nil
+}
+
+// Serialization serialize mongodb instance info
+func (m *MongoDBDetectInstance) Serialization() ([]byte, error) {
+	baseResponse := m.NewDBResponse()
+	baseResponse.ClusterId = m.ClusterId
+	response := MongoDBDetectResponse{
+		BaseDetectDBResponse: baseResponse,
+		MetaType:             m.MetaType,
+	}
+
+	resByte, err := json.Marshal(&response)
+
+	if err != nil {
+		log.Logger.Errorf("mongodb serialization failed. err:%s", err.Error())
+		return []byte{}, err
+	}
+
+	return resByte, nil
+}
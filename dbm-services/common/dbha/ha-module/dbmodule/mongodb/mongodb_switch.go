@@ -0,0 +1,576 @@
+package mongodb
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"dbm-services/common/dbha/ha-module/client"
+	"dbm-services/common/dbha/ha-module/constvar"
+	"dbm-services/common/dbha/ha-module/dbutil"
+	"dbm-services/common/dbha/ha-module/log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MongoDBSwitch replica set member(include shardsvr and configsvr) switch struct
+type MongoDBSwitch struct {
+	dbutil.BaseSwitch
+	//instance role type in cmdb
+	Role string
+	//standby member which preferred to step up
+	StandBySlave dbutil.SlaveInfo
+	Entry        dbutil.BindEntry
+	User         string
+	Pass         string
+	AuthDB       string
+	Timeout      int
+	//seconds to wait candidate become primary
+	ElectionTimeout int
+	//the member would be primary after switch
+	newPrimary dbutil.SlaveInfo
+	//the member need step up by dbha, otherwise replica set elected itself
+	needStepUp bool
+	//hidden backup member in the cluster, never choose as candidate
+	backupMembers map[string]bool
+	//majority of members unreachable, replica set could not elect without force reconfig
+	forceReconfig bool
+	//members unhealthy in replica set status, ignore their votes while force reconfig
+	downMembers map[string]bool
+}
+
+// GetRole get mongodb role type
+func (ins *MongoDBSwitch) GetRole() string {
+	return ins.Role
+}
+
+// SetStandbySlave set standby member from cmdb receiver
+func (ins *MongoDBSwitch) SetStandbySlave(slaves []dbutil.SlaveInfo) {
+	ins.StandBySlave = dbutil.SlaveInfo{}
+	for _, slave := range slaves {
+		if slave.IsStandBy {
+			ins.StandBySlave = slave
+			break
+		}
+	}
+}
+
+// ShowSwitchInstanceInfo show mongodb instance's switch info
+func (ins *MongoDBSwitch) ShowSwitchInstanceInfo() string {
+	str := fmt.Sprintf("<%s#%d IDC:%d Role:%s Status:%s Bzid:%s ClusterType:%s MachineType:%s>",
+		ins.Ip, ins.Port, ins.IdcID, ins.Role, ins.Status, ins.App, ins.ClusterType,
+		ins.MetaType)
+	if ins.newPrimary != (dbutil.SlaveInfo{}) {
+		str = fmt.Sprintf("%s Switch from PRIMARY:<%s#%d> to SECONDARY:<%s#%d>",
+			str, ins.Ip, ins.Port, ins.newPrimary.Ip, ins.newPrimary.Port)
+	}
+	return str
+}
+
+// CheckSwitch find the live member in the same replica set, and decide which member would be primary
+// 1. any live member still see broken-down instance as healthy primary: abort
+// 2. replica set already elected a new primary: only need rebind entry
+// 3. no primary found: choose standby or the newest secondary to step up
+// 4. broken-down instance not primary and no entry bind to it: needn't switch
+func (ins *MongoDBSwitch) CheckSwitch() (bool, error) {
+	if ins.Role == constvar.MongoBackupRole {
+		ins.ReportLogs(constvar.InfoResult, "instance is hidden backup member, needn't switch")
+		return false, nil
+	}
+
+	statuses, err := ins.findLivePeers()
+	if err != nil {
+		ins.ReportLogs(constvar.FailResult, fmt.Sprintf("find live member failed:%s", err.Error()))
+		return false, err
+	}
+
+	self := fmt.Sprintf("%s:%d", ins.Ip, ins.Port)
+	var status *ReplSetStatus
+	for peer, st := range statuses {
+		if primary := st.Primary(); primary != nil && primary.Name == self && primary.Health == 1 {
+			err = fmt.Errorf("member %s still see %s as healthy primary", peer, self)
+			ins.ReportLogs(constvar.FailResult, fmt.Sprintf("abort switch:%s", err.Error()))
+			return false, err
+		}
+		if status == nil || st.Primary() != nil {
+			status = st
+		}
+	}
+	ins.ReportLogs(constvar.InfoResult, fmt.Sprintf("found %d live member in replica set[%s]",
+		len(statuses), status.Set))
+
+	if primary := status.Primary(); primary != nil && primary.Name != self {
+		if !ins.entryBindSelf() {
+			ins.ReportLogs(constvar.InfoResult, fmt.Sprintf("primary is %s and no entry bind to instance, "+
+				"needn't switch", primary.Name))
+			return false, nil
+		}
+		ip, port, err := splitHost(primary.Name)
+		if err != nil {
+			return false, err
+		}
+		ins.newPrimary = dbutil.SlaveInfo{Ip: ip, Port: port}
+		ins.needStepUp = false
+		ins.ReportLogs(constvar.InfoResult, fmt.Sprintf("replica set already elected primary %s", primary.Name))
+	} else {
+		candidate, err := ins.chooseCandidate(status)
+		if err != nil {
+			ins.ReportLogs(constvar.FailResult, fmt.Sprintf("choose candidate failed:%s", err.Error()))
+			return false, err
+		}
+		ins.newPrimary = *candidate
+		ins.needStepUp = true
+		ins.markDownMembers(status)
+		ins.ReportLogs(constvar.InfoResult, fmt.Sprintf("no primary found, choose %s:%d to step up, force reconfig:%t",
+			candidate.Ip, candidate.Port, ins.forceReconfig))
+	}
+
+	ins.SetInfo(constvar.SlaveIpKey, ins.newPrimary.Ip)
+	ins.SetInfo(constvar.SlavePortKey, ins.newPrimary.Port)
+	ins.ReportLogs(constvar.InfoResult, "mongodb check switch ok")
+	return true, nil
+}
+
+// DoSwitch step up the candidate if needed, then rebind the entry to new primary
+func (ins *MongoDBSwitch) DoSwitch() error {
+	if ins.needStepUp {
+		if err := ins.stepUp(); err != nil {
+			ins.ReportLogs(constvar.FailResult, fmt.Sprintf("step up %s:%d failed:%s",
+				ins.newPrimary.Ip, ins.newPrimary.Port, err.Error()))
+			return err
+		}
+		ins.ReportLogs(constvar.InfoResult, fmt.Sprintf("step up %s:%d success",
+			ins.newPrimary.Ip, ins.newPrimary.Port))
+	}
+
+	if err := ins.bindNewPrimary(); err != nil {
+		return err
+	}
+	return ins.DeleteNameService(ins.Entry)
+}
+
+// ShowSwitchPlan show what DoSwitch would do, shadow mode used
+func (ins *MongoDBSwitch) ShowSwitchPlan() ([]string, error) {
+	var plan []string
+	if ins.needStepUp {
+		plan = append(plan, fmt.Sprintf("replSetStepDown on %s:%d if reachable", ins.Ip, ins.Port))
+		if ins.forceReconfig {
+			plan = append(plan,
+				fmt.Sprintf("set priority of %s:%d to the highest, %s:%d to 0 and votes of %d down member to 0, "+
+					"force reconfig", ins.newPrimary.Ip, ins.newPrimary.Port, ins.Ip, ins.Port, len(ins.downMembers)))
+		}
+		plan = append(plan, fmt.Sprintf("replSetStepUp on %s:%d", ins.newPrimary.Ip, ins.newPrimary.Port))
+	}
+	newAddr := fmt.Sprintf("%s:%d", ins.newPrimary.Ip, ins.newPrimary.Port)
+	for _, dns := range ins.Entry.Dns {
+		if hasIp(dns.BindIps, ins.Ip) && !hasIp(dns.BindIps, ins.newPrimary.Ip) {
+			plan = append(plan, fmt.Sprintf("create ip[%s] to domain[%s]", ins.newPrimary.Ip, dns.DomainName))
+		}
+	}
+	for _, clb := range ins.Entry.Clb {
+		if ins.bindSelf(clb.BindIps, clb.BindPort) && !hasIp(clb.BindIps, ins.newPrimary.Ip) {
+			plan = append(plan, fmt.Sprintf("register %s to clb[%s:%s:%s]",
+				newAddr, clb.Region, clb.LoadBalanceId, clb.ListenId))
+		}
+	}
+	for _, polaris := range ins.Entry.Polaris {
+		if ins.bindSelf(polaris.BindIps, polaris.BindPort) && !hasIp(polaris.BindIps, ins.newPrimary.Ip) {
+			plan = append(plan, fmt.Sprintf("bind %s to polaris[%s]", newAddr, polaris.Service))
+		}
+	}
+	plan = append(plan, ins.ShowNameServicePlan(ins.Entry)...)
+	plan = append(plan, fmt.Sprintf("swap role of primary[%s:%d] and new primary[%s:%d] in cmdb",
+		ins.Ip, ins.Port, ins.newPrimary.Ip, ins.newPrimary.Port))
+	return plan, nil
+}
+
+// RollBack do switch rollback
+func (ins *MongoDBSwitch) RollBack() error {
+	return nil
+}
+
+// UpdateMetaInfo swap old primary and new primary's meta info in cmdb
+func (ins *MongoDBSwitch) UpdateMetaInfo() error {
+	if ins.newPrimary == (dbutil.SlaveInfo{}) {
+		return nil
+	}
+	if err := ins.CmDBClient.SwapMongoDBRole(ins.Ip, ins.Port,
+		ins.newPrimary.Ip, ins.newPrimary.Port); err != nil {
+		updateErrLog := fmt.Sprintf("swap mongodb role failed. err:%s", err.Error())
+		ins.ReportLogs(constvar.FailResult, updateErrLog)
+		return err
+	}
+	ins.ReportLogs(constvar.InfoResult, "mongodb switch update meta info success")
+	return nil
+}
+
+// findLivePeers fetch the member of the same cluster from cmdb, return replica set status of all live
+// member whose replica set config contains the broken-down instance, keyed by member host:port
+func (ins *MongoDBSwitch) findLivePeers() (map[string]*ReplSetStatus, error) {
+	rawInfo, err := ins.CmDBClient.GetDBInstanceInfoByCluster(ins.Cluster)
+	if err != nil {
+		return nil, fmt.Errorf("get cluster[%s] instance from cmdb failed:%s", ins.Cluster, err.Error())
+	}
+
+	self := fmt.Sprintf("%s:%d", ins.Ip, ins.Port)
+	var peers []dbutil.DBInstanceInfoDetail
+	ins.backupMembers = make(map[string]bool)
+	for _, v := range rawInfo {
+		peer := dbutil.DBInstanceInfoDetail{}
+		rawData, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("marshal instance info failed:%s", err.Error())
+		}
+		if err = json.Unmarshal(rawData, &peer); err != nil {
+			return nil, fmt.Errorf("unmarshal instance info failed:%s", err.Error())
+		}
+		if peer.MachineType != ins.MetaType || (peer.IP == ins.Ip && peer.Port == ins.Port) {
+			continue
+		}
+		if peer.InstanceRole == constvar.MongoBackupRole {
+			ins.backupMembers[fmt.Sprintf("%s:%d", peer.IP, peer.Port)] = true
+		}
+		peers = append(peers, peer)
+	}
+
+	statuses := make(map[string]*ReplSetStatus)
+	for _, peer := range peers {
+		status, err := ins.getStatus(peer.IP, peer.Port)
+		if err != nil {
+			log.Logger.Warnf("get replica set status from %s:%d failed:%s", peer.IP, peer.Port, err.Error())
+			continue
+		}
+		if status.GetMember(self) == nil {
+			continue
+		}
+		statuses[fmt.Sprintf("%s:%d", peer.IP, peer.Port)] = status
+	}
+	if len(statuses) == 0 {
+		return nil, fmt.Errorf("no live member found in the same replica set with %s", self)
+	}
+	return statuses, nil
+}
+
+// markDownMembers record unhealthy members, force reconfig only if the majority of members is down
+func (ins *MongoDBSwitch) markDownMembers(status *ReplSetStatus) {
+	ins.downMembers = make(map[string]bool)
+	for _, m := range status.Members {
+		if m.Health != 1 {
+			ins.downMembers[m.Name] = true
+		}
+	}
+	ins.forceReconfig = len(ins.downMembers)*2 >= len(status.Members)
+}
+
+// chooseCandidate prefer standby member, otherwise the secondary with the newest optime
+func (ins *MongoDBSwitch) chooseCandidate(status *ReplSetStatus) (*dbutil.SlaveInfo, error) {
+	var candidate *ReplSetMemberStatus
+	if ins.StandBySlave != (dbutil.SlaveInfo{}) {
+		standby := status.GetMember(fmt.Sprintf("%s:%d", ins.StandBySlave.Ip, ins.StandBySlave.Port))
+		if standby != nil && standby.Health == 1 && standby.State == StateSecondary {
+			candidate = standby
+		}
+	}
+	if candidate == nil {
+		for i, m := range status.Members {
+			if m.Health != 1 || m.State != StateSecondary || ins.backupMembers[m.Name] {
+				continue
+			}
+			if candidate == nil || m.OptimeDate.After(candidate.OptimeDate) {
+				candidate = &status.Members[i]
+			}
+		}
+	}
+	if candidate == nil {
+		return nil, fmt.Errorf("no healthy secondary found in replica set[%s]", status.Set)
+	}
+
+	maxDelay := ins.Config.GMConf.GCM.AllowedSlaveDelayMax
+	delay := status.LatestOptime().Sub(candidate.OptimeDate)
+	if maxDelay > 0 && delay > time.Duration(maxDelay)*time.Second {
+		return nil, fmt.Errorf("candidate %s delay %s, exceed allowed_slave_delay_max:%d",
+			candidate.Name, delay.String(), maxDelay)
+	}
+
+	ip, port, err := splitHost(candidate.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &dbutil.SlaveInfo{Ip: ip, Port: port}, nil
+}
+
+// stepUp step down the broken-down instance if it is reachable, then step up the candidate
+// replica set could elect by itself while majority alive, force reconfig only if majority is down
+func (ins *MongoDBSwitch) stepUp() error {
+	ins.stepDown()
+
+	cli, err := NewMongoClient(ins.newPrimary.Ip, ins.newPrimary.Port, ins.User, ins.Pass, ins.AuthDB, ins.Timeout)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := CloseMongoClient(cli, ins.Timeout); err != nil {
+			log.Logger.Warnf("close connect[%s#%d] failed:%s", ins.newPrimary.Ip, ins.newPrimary.Port, err.Error())
+		}
+	}()
+
+	if ins.forceReconfig {
+		rsConf, err := GetReplSetConfig(cli, ins.Timeout)
+		if err != nil {
+			return fmt.Errorf("get replica set config failed:%s", err.Error())
+		}
+		if err = ins.adjustPriority(rsConf); err != nil {
+			return err
+		}
+		reconfig := bson.D{{Key: "replSetReconfig", Value: rsConf}, {Key: "force", Value: true}}
+		if err = RunAdminCommand(cli, ins.Timeout, reconfig, nil); err != nil {
+			return fmt.Errorf("replSetReconfig failed:%s", err.Error())
+		}
+		ins.ReportLogs(constvar.InfoResult, fmt.Sprintf("majority down, force reconfig to make %s:%d electable",
+			ins.newPrimary.Ip, ins.newPrimary.Port))
+	}
+
+	// the candidate may be elected already, ignore step up error and check by hello
+	if err = RunAdminCommand(cli, ins.Timeout, bson.D{{Key: "replSetStepUp", Value: 1}}, nil); err != nil {
+		log.Logger.Warnf("replSetStepUp on %s:%d failed:%s", ins.newPrimary.Ip, ins.newPrimary.Port, err.Error())
+	}
+
+	return ins.waitPrimary(cli)
+}
+
+// stepDown step down the broken-down instance in case it still think itself primary, ignore any error
+// because the instance is unreachable in most cases
+func (ins *MongoDBSwitch) stepDown() {
+	cli, err := NewMongoClient(ins.Ip, ins.Port, ins.User, ins.Pass, ins.AuthDB, ins.Timeout)
+	if err != nil {
+		log.Logger.Warnf("connect %s:%d to step down failed:%s", ins.Ip, ins.Port, err.Error())
+		return
+	}
+	defer func() {
+		if err := CloseMongoClient(cli, ins.Timeout); err != nil {
+			log.Logger.Warnf("close connect[%s#%d] failed:%s", ins.Ip, ins.Port, err.Error())
+		}
+	}()
+
+	cmd := bson.D{{Key: "replSetStepDown", Value: constvar.MongoStepDownSecs}}
+	if err = RunAdminCommand(cli, ins.Timeout, cmd, nil); err != nil {
+		log.Logger.Warnf("replSetStepDown on %s:%d failed:%s", ins.Ip, ins.Port, err.Error())
+		return
+	}
+	ins.ReportLogs(constvar.InfoResult, fmt.Sprintf("replSetStepDown on %s:%d success", ins.Ip, ins.Port))
+}
+
+// adjustPriority set candidate's priority to the max priority+1, broken-down instance's to 0,
+// and votes of down members to 0, so that the live minority could elect.
+// hidden and priority 0 members never be primary, skip them while computing max priority
+func (ins *MongoDBSwitch) adjustPriority(rsConf bson.D) error {
+	self := fmt.Sprintf("%s:%d", ins.Ip, ins.Port)
+	candidate := fmt.Sprintf("%s:%d", ins.newPrimary.Ip, ins.newPrimary.Port)
+
+	var members primitive.A
+	for i, e := range rsConf {
+		switch e.Key {
+		case "members":
+			members, _ = e.Value.(primitive.A)
+		case "version":
+			rsConf[i].Value = int64(toFloat(e.Value)) + 1
+		}
+	}
+	if len(members) == 0 {
+		return fmt.Errorf("no members found in replica set config")
+	}
+
+	maxPriority := float64(0)
+	for _, m := range members {
+		if doc, ok := m.(primitive.D); ok && electable(doc) {
+			maxPriority = max(maxPriority, toFloat(docValue(doc, "priority")))
+		}
+	}
+
+	found := false
+	for idx, m := range members {
+		doc, ok := m.(primitive.D)
+		if !ok {
+			continue
+		}
+		host := docValue(doc, "host")
+		switch {
+		case host == candidate:
+			if !electable(doc) {
+				return fmt.Errorf("candidate %s is hidden or priority 0, could not be primary", candidate)
+			}
+			doc = setDocValue(doc, "priority", maxPriority+1)
+			found = true
+		case host == self:
+			doc = setDocValue(doc, "priority", float64(0))
+		}
+		if name, _ := host.(string); ins.downMembers[name] && name != candidate {
+			// member with votes 0 must be priority 0
+			doc = setDocValue(doc, "priority", float64(0))
+			doc = setDocValue(doc, "votes", int32(0))
+		}
+		members[idx] = doc
+	}
+	if !found {
+		return fmt.Errorf("candidate %s not found in replica set config", candidate)
+	}
+	return nil
+}
+
+// waitPrimary wait until the candidate become writable primary
+// election may take much longer than connect timeout, use election timeout as deadline
+func (ins *MongoDBSwitch) waitPrimary(cli *mongo.Client) error {
+	timeout := ins.ElectionTimeout
+	if timeout <= 0 {
+		timeout = constvar.MongoDefaultElectionTimeout
+	}
+	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+	for time.Now().Before(deadline) {
+		hello, err := Hello(cli, ins.Timeout)
+		if err == nil && hello.IsPrimary() {
+			return nil
+		}
+		time.Sleep(time.Second)
+	}
+	return fmt.Errorf("wait %s:%d become primary timeout after %ds", ins.newPrimary.Ip, ins.newPrimary.Port, timeout)
+}
+
+// bindNewPrimary bind new primary to the dns, clb and polaris entry bind to broken-down instance,
+// must be done before DeleteNameService, otherwise the entry would have no backend
+func (ins *MongoDBSwitch) bindNewPrimary() error {
+	if !ins.entryBindSelf() {
+		return nil
+	}
+	conf := ins.Config
+	if len(ins.Entry.Dns) > 0 {
+		ins.ReportLogs(constvar.InfoResult, fmt.Sprintf("try to create dns entry [%s:%d]",
+			ins.newPrimary.Ip, ins.newPrimary.Port))
+		dnsClient := client.NewNameServiceClient(&conf.NameServices.DnsConf, conf.GetCloudId())
+		for _, dns := range ins.Entry.Dns {
+			if !hasIp(dns.BindIps, ins.Ip) || hasIp(dns.BindIps, ins.newPrimary.Ip) {
+				continue
+			}
+			if err := dnsClient.CreateDomain(
+				dns.DomainName, ins.GetApp(), ins.newPrimary.Ip, ins.newPrimary.Port,
+			); err != nil {
+				ins.ReportLogs(constvar.FailResult, fmt.Sprintf("create ip[%s] to domain[%s] failed:%s",
+					ins.newPrimary.Ip, dns.DomainName, err.Error()))
+				return err
+			}
+		}
+	}
+
+	addr := fmt.Sprintf("%s:%d", ins.newPrimary.Ip, ins.newPrimary.Port)
+	if len(ins.Entry.Clb) > 0 {
+		ins.ReportLogs(constvar.InfoResult, fmt.Sprintf("try to register clb entry [%s]", addr))
+		clbClient := client.NewNameServiceClient(&conf.NameServices.ClbConf, conf.GetCloudId())
+		for _, clb := range ins.Entry.Clb {
+			if !ins.bindSelf(clb.BindIps, clb.BindPort) || hasIp(clb.BindIps, ins.newPrimary.Ip) {
+				continue
+			}
+			if err := clbClient.ClbRegister(clb.Region, clb.LoadBalanceId, clb.ListenId, addr); err != nil {
+				ins.ReportLogs(constvar.FailResult, fmt.Sprintf("register %s to clb[%s:%s:%s] failed:%s",
+					addr, clb.Region, clb.LoadBalanceId, clb.ListenId, err.Error()))
+				return err
+			}
+		}
+	}
+
+	if len(ins.Entry.Polaris) > 0 {
+		ins.ReportLogs(constvar.InfoResult, fmt.Sprintf("try to bind polaris entry [%s]", addr))
+		polarisClient := client.NewNameServiceClient(&conf.NameServices.PolarisConf, conf.GetCloudId())
+		for _, polaris := range ins.Entry.Polaris {
+			if !ins.bindSelf(polaris.BindIps, polaris.BindPort) || hasIp(polaris.BindIps, ins.newPrimary.Ip) {
+				continue
+			}
+			if err := polarisClient.PolarisBindTarget(polaris.Service, polaris.Token, addr); err != nil {
+				ins.ReportLogs(constvar.FailResult, fmt.Sprintf("bind %s to polaris[%s] failed:%s",
+					addr, polaris.Service, err.Error()))
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// bindSelf whether clb or polaris entry bind to broken-down instance, match ip and port
+// the same as DeleteNameService
+func (ins *MongoDBSwitch) bindSelf(ips []string, port int) bool {
+	return hasIp(ips, ins.Ip) && port == ins.Port
+}
+
+// entryBindSelf whether any entry bind to broken-down instance
+func (ins *MongoDBSwitch) entryBindSelf() bool {
+	for _, dns := range ins.Entry.Dns {
+		if hasIp(dns.BindIps, ins.Ip) {
+			return true
+		}
+	}
+	for _, clb := range ins.Entry.Clb {
+		if hasIp(clb.BindIps, ins.Ip) {
+			return true
+		}
+	}
+	for _, polaris := range ins.Entry.Polaris {
+		if hasIp(polaris.BindIps, ins.Ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// getStatus connect to member and get replica set status
+func (ins *MongoDBSwitch) getStatus(ip string, port int) (*ReplSetStatus, error) {
+	cli, err := NewMongoClient(ip, port, ins.User, ins.Pass, ins.AuthDB, ins.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := CloseMongoClient(cli, ins.Timeout); err != nil {
+			log.Logger.Warnf("close connect[%s#%d] failed:%s", ip, port, err.Error())
+		}
+	}()
+	return GetReplSetStatus(cli, ins.Timeout)
+}
+
+// MongosSwitch mongos switch struct
+type MongosSwitch struct {
+	dbutil.BaseSwitch
+	Entry dbutil.BindEntry
+}
+
+// CheckSwitch check whether mongos allowed switch, always true at present
+func (ins *MongosSwitch) CheckSwitch() (bool, error) {
+	return true, nil
+}
+
+// DoSwitch mongos do switch
+// delete ip under the entry
+func (ins *MongosSwitch) DoSwitch() error {
+	ins.ReportLogs(constvar.InfoResult, fmt.Sprintf("try to release ip[%s] from all cluster entry", ins.Ip))
+	return ins.DeleteNameService(ins.Entry)
+}
+
+// ShowSwitchPlan show which entry would be released, shadow mode used
+func (ins *MongosSwitch) ShowSwitchPlan() ([]string, error) {
+	return ins.ShowNameServicePlan(ins.Entry), nil
+}
+
+// ShowSwitchInstanceInfo display switch mongos info
+func (ins *MongosSwitch) ShowSwitchInstanceInfo() string {
+	str := fmt.Sprintf("<%s#%d IDC:%d Status:%s Bzid:%s ClusterType:%s MachineType:%s> switch",
+		ins.Ip, ins.Port, ins.IdcID, ins.Status, ins.App, ins.ClusterType, ins.MetaType)
+	return str
+}
+
+// RollBack mongos do rollback
+func (ins *MongosSwitch) RollBack() error {
+	return nil
+}
+
+// UpdateMetaInfo mongos needn't update meta info
+func (ins *MongosSwitch) UpdateMetaInfo() error {
+	return nil
+}
@@ -0,0 +1,201 @@
+package mongodb
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"dbm-services/common/dbha/ha-module/config"
+	"dbm-services/common/dbha/ha-module/dbutil"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newTestSwitch(delayMax int) *MongoDBSwitch {
+	ins := &MongoDBSwitch{}
+	ins.Ip = "1.1.1.1"
+	ins.Port = 27017
+	ins.Config = &config.Config{GMConf: &config.GMConfig{GCM: config.GCMConfig{AllowedSlaveDelayMax: delayMax}}}
+	return ins
+}
+
+func TestChooseCandidate(t *testing.T) {
+	now := time.Now()
+	member := func(name string, health float64, state int, delay int) ReplSetMemberStatus {
+		return ReplSetMemberStatus{Name: name, Health: health, State: state,
+			OptimeDate: now.Add(-time.Duration(delay) * time.Second)}
+	}
+	cases := []struct {
+		name     string
+		standby  dbutil.SlaveInfo
+		backup   map[string]bool
+		delayMax int
+		members  []ReplSetMemberStatus
+		want     string
+	}{
+		{
+			name: "newest secondary",
+			members: []ReplSetMemberStatus{member("1.1.1.1:27017", 0, StateDown, 0),
+				member("2.2.2.2:27017", 1, StateSecondary, 10), member("3.3.3.3:27017", 1, StateSecondary, 1)},
+			want: "3.3.3.3:27017",
+		},
+		{
+			name:    "prefer standby",
+			standby: dbutil.SlaveInfo{Ip: "2.2.2.2", Port: 27017},
+			members: []ReplSetMemberStatus{member("2.2.2.2:27017", 1, StateSecondary, 10),
+				member("3.3.3.3:27017", 1, StateSecondary, 1)},
+			want: "2.2.2.2:27017",
+		},
+		{
+			name:    "unhealthy standby",
+			standby: dbutil.SlaveInfo{Ip: "2.2.2.2", Port: 27017},
+			members: []ReplSetMemberStatus{member("2.2.2.2:27017", 0, StateDown, 10),
+				member("3.3.3.3:27017", 1, StateSecondary, 1)},
+			want: "3.3.3.3:27017",
+		},
+		{
+			name:   "skip backup member",
+			backup: map[string]bool{"3.3.3.3:27017": true},
+			members: []ReplSetMemberStatus{member("2.2.2.2:27017", 1, StateSecondary, 10),
+				member("3.3.3.3:27017", 1, StateSecondary, 1)},
+			want: "2.2.2.2:27017",
+		},
+		{
+			name:    "no secondary",
+			members: []ReplSetMemberStatus{member("2.2.2.2:27017", 1, StateArbiter, 0)},
+		},
+		{
+			name:     "delay exceed",
+			delayMax: 5,
+			members: []ReplSetMemberStatus{member("2.2.2.2:27017", 1, StateSecondary, 10),
+				member("3.3.3.3:27017", 1, StateArbiter, 0)},
+		},
+	}
+	for _, c := range cases {
+		ins := newTestSwitch(c.delayMax)
+		ins.StandBySlave = c.standby
+		ins.backupMembers = c.backup
+		got, err := ins.chooseCandidate(&ReplSetStatus{Set: "rs", Members: c.members})
+		if c.want == "" {
+			if err == nil {
+				t.Errorf("%s: expect error, got %+v", c.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", c.name, err.Error())
+			continue
+		}
+		if name := fmt.Sprintf("%s:%d", got.Ip, got.Port); name != c.want {
+			t.Errorf("%s: got %+v, want %s", c.name, got, c.want)
+		}
+	}
+}
+
+func TestAdjustPriority(t *testing.T) {
+	rsConf := bson.D{
+		{Key: "_id", Value: "rs"},
+		{Key: "version", Value: int32(3)},
+		{Key: "members", Value: primitive.A{
+			primitive.D{{Key: "host", Value: "1.1.1.1:27017"}, {Key: "priority", Value: float64(2)}},
+			primitive.D{{Key: "host", Value: "2.2.2.2:27017"}, {Key: "priority", Value: float64(1)}},
+			primitive.D{{Key: "host", Value: "3.3.3.3:27017"}, {Key: "priority", Value: float64(1)}},
+			primitive.D{{Key: "host", Value: "4.4.4.4:27017"}, {Key: "priority", Value: float64(0)},
+				{Key: "hidden", Value: true}},
+		}},
+	}
+	ins := newTestSwitch(0)
+	ins.newPrimary = dbutil.SlaveInfo{Ip: "2.2.2.2", Port: 27017}
+	ins.downMembers = map[string]bool{"1.1.1.1:27017": true, "3.3.3.3:27017": true}
+	if err := ins.adjustPriority(rsConf); err != nil {
+		t.Fatal(err)
+	}
+	if v := docValue(rsConf, "version"); v != int64(4) {
+		t.Errorf("version %v, want 4", v)
+	}
+	members := docValue(rsConf, "members").(primitive.A)
+	want := []struct {
+		priority float64
+		votes    interface{}
+	}{
+		{0, int32(0)},
+		{3, nil},
+		{0, int32(0)},
+		{0, nil},
+	}
+	for i, w := range want {
+		doc := members[i].(primitive.D)
+		if p := toFloat(docValue(doc, "priority")); p != w.priority {
+			t.Errorf("member %v priority %v, want %v", docValue(doc, "host"), p, w.priority)
+		}
+		if v := docValue(doc, "votes"); v != w.votes {
+			t.Errorf("member %v votes %v, want %v", docValue(doc, "host"), v, w.votes)
+		}
+	}
+
+	ins.newPrimary = dbutil.SlaveInfo{Ip: "4.4.4.4", Port: 27017}
+	if err := ins.adjustPriority(rsConf); err == nil {
+		t.Errorf("hidden member should not be candidate")
+	}
+	ins.newPrimary = dbutil.SlaveInfo{Ip: "5.5.5.5", Port: 27017}
+	if err := ins.adjustPriority(rsConf); err == nil {
+		t.Errorf("candidate not in config should fail")
+	}
+	if err := ins.adjustPriority(bson.D{{Key: "version", Value: int32(1)}}); err == nil {
+		t.Errorf("config without members should fail")
+	}
+}
+
+func TestElectable(t *testing.T) {
+	cases := []struct {
+		doc  primitive.D
+		want bool
+	}{
+		{primitive.D{{Key: "host", Value: "a"}}, true},
+		{primitive.D{{Key: "priority", Value: int32(1)}}, true},
+		{primitive.D{{Key: "priority", Value: float64(0.5)}}, true},
+		{primitive.D{{Key: "priority", Value: int64(0)}}, false},
+		{primitive.D{{Key: "priority", Value: float64(0)}, {Key: "hidden", Value: true}}, false},
+		{primitive.D{{Key: "hidden", Value: true}}, false},
+		{primitive.D{{Key: "arbiterOnly", Value: true}}, false},
+		{primitive.D{{Key: "hidden", Value: false}, {Key: "arbiterOnly", Value: false}}, true},
+	}
+	for _, c := range cases {
+		if got := electable(c.doc); got != c.want {
+			t.Errorf("electable(%v) = %v, want %v", c.doc, got, c.want)
+		}
+	}
+}
+
+func TestShowSwitchPlanBindBeforeRelease(t *testing.T) {
+	ins := newTestSwitch(0)
+	ins.newPrimary = dbutil.SlaveInfo{Ip: "2.2.2.2", Port: 27018}
+	ins.Entry = dbutil.BindEntry{
+		Dns: []dbutil.DnsInfo{{DomainName: "m.db", BindIps: []string{"1.1.1.1"}, BindPort: 27017}},
+		Clb: []dbutil.ClbInfo{{Region: "gz", LoadBalanceId: "lb", ListenId: "l",
+			BindIps: []string{"1.1.1.1"}, BindPort: 27017}},
+		Polaris: []dbutil.PolarisInfo{{Service: "svc", BindIps: []string{"1.1.1.1"}, BindPort: 27017}},
+	}
+	plan, err := ins.ShowSwitchPlan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(plan, "\n")
+	steps := []string{
+		"create ip[2.2.2.2] to domain[m.db]",
+		"register 2.2.2.2:27018 to clb[gz:lb:l]",
+		"bind 2.2.2.2:27018 to polaris[svc]",
+		"delete 1.1.1.1:27017 from clb[gz:lb:l]",
+		"delete 1.1.1.1:27017 from polaris[svc]",
+	}
+	last := -1
+	for _, step := range steps {
+		idx := strings.Index(got, step)
+		if idx < 0 || idx < last {
+			t.Errorf("plan missing or out of order %q:\n%s", step, got)
+		}
+		last = idx
+	}
+}
@@ -49,6 +49,11 @@ func (ins *PredixySwitch) DoSwitch() error {
 	return nil
 }
 
+// ShowSwitchPlan show which entry would be kicked off, shadow mode used
+func (ins *PredixySwitch) ShowSwitchPlan() ([]string, error) {
+	return ins.ShowKickOffPlan(), nil
+}
+
 // RollBack TODO
 func (ins *PredixySwitch) RollBack() error {
 	return nil
@@ -157,6 +157,21 @@ func (ins *RedisProxySwitchInfo) ShowSwitchInstanceInfo() string {
 	return str
 }
 
+// ShowKickOffPlan show which entry would be kicked off, shadow mode used
+func (ins *RedisProxySwitchInfo) ShowKickOffPlan() []string {
+	entry := dbutil.BindEntry{}
+	if ins.ApiGw.DNSFlag {
+		entry.Dns = ins.ApiGw.ServiceEntry.Dns
+	}
+	if ins.ApiGw.CLBFlag {
+		entry.Clb = ins.ApiGw.ServiceEntry.Clb
+	}
+	if ins.ApiGw.PolarisFlag {
+		entry.Polaris = ins.ApiGw.ServiceEntry.Polaris
+	}
+	return ins.ShowNameServicePlan(entry)
+}
+
 // KickOffDns kick instance from dns
 func (ins *RedisProxySwitchInfo) KickOffDns() error {
 	if !ins.ApiGw.DNSFlag {
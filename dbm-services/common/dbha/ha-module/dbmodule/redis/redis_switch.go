@@ -131,6 +131,30 @@ func (ins *RedisSwitch) DoSwitch() error {
 	return nil
 }
 
+// ShowSwitchPlan show what DoSwitch and UpdateMetaInfo would do, shadow mode used.
+// file lock taken by CheckSwitch released here, because DoSwitch and UpdateMetaInfo never called in shadow mode
+func (ins *RedisSwitch) ShowSwitchPlan() ([]string, error) {
+	if ins.IsSkipSwitch {
+		return []string{fmt.Sprintf("instance[%s:%d] is slave, nothing to switch", ins.Ip, ins.Port)}, nil
+	}
+	if ins.FLock != nil {
+		defer ins.DoUnLockByFile()
+	}
+	if len(ins.Slave) < 1 {
+		return nil, fmt.Errorf("redis have invald slave[%d]", len(ins.Slave))
+	}
+
+	slave := ins.Slave[0]
+	plan := []string{fmt.Sprintf("exec slaveof no one on slave[%s:%d]", slave.Ip, slave.Port)}
+	if ins.ClusterType != constvar.RedisInstance {
+		plan = append(plan, fmt.Sprintf("switch backend of %d twemproxy from [%s:%d] to [%s:%d]",
+			len(ins.Proxy), ins.Ip, ins.Port, slave.Ip, slave.Port))
+	}
+	plan = append(plan, fmt.Sprintf("swap role of master[%s:%d] and slave[%s:%d] in cmdb",
+		ins.Ip, ins.Port, slave.Ip, slave.Port))
+	return plan, nil
+}
+
 // ShowSwitchInstanceInfo show switch instance information
 func (ins *RedisSwitch) ShowSwitchInstanceInfo() string {
 	format := `<%s#%d IDC:%d Status:%s App:%s ClusterType:%s MachineType:%s Cluster:%s>`
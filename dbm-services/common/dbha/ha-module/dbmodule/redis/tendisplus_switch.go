@@ -55,6 +55,19 @@ func (ins *TendisplusSwitch) DoSwitch() error {
 	}
 }
 
+// ShowSwitchPlan tendisplus cluster failover by itself, dbha only check the slave's role
+func (ins *TendisplusSwitch) ShowSwitchPlan() ([]string, error) {
+	plan := make([]string, 0, len(ins.Slave))
+	for _, slave := range ins.Slave {
+		plan = append(plan, fmt.Sprintf("check whether slave[%s:%d] become master, nothing would be changed",
+			slave.Ip, slave.Port))
+	}
+	if len(plan) == 0 {
+		plan = append(plan, "no slave found, nothing would be changed")
+	}
+	return plan, nil
+}
+
 // ShowSwitchInstanceInfo show switch instance
 func (ins *TendisplusSwitch) ShowSwitchInstanceInfo() string {
 	format := `<%s#%d IDC:%d Status:%s App:%s ClusterType:%s MachineType:%s Cluster:%s> switch`
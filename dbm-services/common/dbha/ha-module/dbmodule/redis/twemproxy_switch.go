@@ -48,6 +48,11 @@ func (ins *TwemproxySwitch) DoSwitch() error {
 	return nil
 }
 
+// ShowSwitchPlan show which entry would be kicked off, shadow mode used
+func (ins *TwemproxySwitch) ShowSwitchPlan() ([]string, error) {
+	return ins.ShowKickOffPlan(), nil
+}
+
 // RollBack TODO
 func (ins *TwemproxySwitch) RollBack() error {
 	return nil
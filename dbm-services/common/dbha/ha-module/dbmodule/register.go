@@ -4,6 +4,7 @@ import (
 	"dbm-services/common/dbha/ha-module/config"
 	"dbm-services/common/dbha/ha-module/constvar"
 	"dbm-services/common/dbha/ha-module/dbmodule/dbmysql"
+	"dbm-services/common/dbha/ha-module/dbmodule/mongodb"
 	"dbm-services/common/dbha/ha-module/dbmodule/redis"
 	"dbm-services/common/dbha/ha-module/dbmodule/riak"
 	"dbm-services/common/dbha/ha-module/dbmodule/sqlserver"
@@ -96,4 +97,18 @@ func init() {
 		DeserializeCallback:          sqlserver.DeserializeSqlserver,
 		GetSwitchInstanceInformation: sqlserver.NewSqlserverSwitchInstance,
 	}
+
+	//MongoReplicaSet used
+	DBCallbackMap[constvar.DetectMongoReplicaSet] = Callback{
+		FetchDBCallback:              mongodb.NewMongoReplicaSetByCmDB,
+		DeserializeCallback:          mongodb.DeserializeMongoDB,
+		GetSwitchInstanceInformation: mongodb.NewMongoDBSwitchInstance,
+	}
+
+	//MongoShardedCluster used
+	DBCallbackMap[constvar.DetectMongoShardedCluster] = Callback{
+		FetchDBCallback:              mongodb.NewMongoShardedClusterByCmDB,
+		DeserializeCallback:          mongodb.DeserializeMongoDB,
+		GetSwitchInstanceInformation: mongodb.NewMongoDBSwitchInstance,
+	}
 }
@@ -45,6 +45,11 @@ func (ins *RiakSwitch) DoSwitch() error {
 	return nil
 }
 
+// ShowSwitchPlan riak never switch, shadow mode used
+func (ins *RiakSwitch) ShowSwitchPlan() ([]string, error) {
+	return []string{fmt.Sprintf("riak[%s:%d] needn't switch, nothing would be changed", ins.Ip, ins.Port)}, nil
+}
+
 // RollBack do switch rollback
 func (ins *RiakSwitch) RollBack() error {
 	return nil
@@ -168,6 +168,19 @@ func (ins *SqlserverSwitch) DoSwitch() error {
 	return nil
 }
 
+// ShowSwitchPlan show what DoSwitch would do, shadow mode used
+func (ins *SqlserverSwitch) ShowSwitchPlan() ([]string, error) {
+	plan := []string{fmt.Sprintf("exec Sys_AutoSwitch_LossOver on instance [%s:%d]",
+		ins.StandBySlave.Ip, ins.StandBySlave.Port)}
+	for _, dns := range ins.Entry.Dns {
+		plan = append(plan, fmt.Sprintf("create ip[%s] to domain[%s] if not exist",
+			ins.StandBySlave.Ip, dns.DomainName))
+	}
+	plan = append(plan, fmt.Sprintf("swap role of master[%s:%d] and slave[%s:%d] in cmdb",
+		ins.Ip, ins.Port, ins.StandBySlave.Ip, ins.StandBySlave.Port))
+	return plan, nil
+}
+
 // RollBack todo
 func (ins *SqlserverSwitch) RollBack() error {
 	return nil
@@ -36,6 +36,12 @@ type DataBaseSwitch interface {
 	ReportLogs(result string, comment string) bool
 }
 
+// DataBaseShadowSwitch switch instance implement this to describe what DoSwitch would do.
+// In shadow mode, gcm call ShowSwitchPlan instead of DoSwitch, and never change anything
+type DataBaseShadowSwitch interface {
+	ShowSwitchPlan() ([]string, error)
+}
+
 // PolarisInfo polaris detail info, response by cmdb api
 type PolarisInfo struct {
 	Service string `json:"polaris_name"`
@@ -271,6 +277,40 @@ func (ins *BaseSwitch) DeleteNameService(entry BindEntry) error {
 	return nil
 }
 
+// ShowNameServicePlan show which entry DeleteNameService would release, used by shadow mode
+func (ins *BaseSwitch) ShowNameServicePlan(entry BindEntry) []string {
+	var plan []string
+	for _, dns := range entry.Dns {
+		for _, ip := range dns.BindIps {
+			if ip == ins.Ip {
+				plan = append(plan, fmt.Sprintf("delete ip[%s] from domain[%s]", ip, dns.DomainName))
+				break
+			}
+		}
+	}
+	for _, clb := range entry.Clb {
+		for _, ip := range clb.BindIps {
+			if ip == ins.Ip && clb.BindPort == ins.Port {
+				plan = append(plan, fmt.Sprintf("delete %s:%d from clb[%s:%s:%s]",
+					ip, clb.BindPort, clb.Region, clb.LoadBalanceId, clb.ListenId))
+				break
+			}
+		}
+	}
+	for _, pinfo := range entry.Polaris {
+		for _, ip := range pinfo.BindIps {
+			if ip == ins.Ip && pinfo.BindPort == ins.Port {
+				plan = append(plan, fmt.Sprintf("delete %s:%d from polaris[%s]", ip, pinfo.BindPort, pinfo.Service))
+				break
+			}
+		}
+	}
+	if len(plan) == 0 {
+		plan = append(plan, fmt.Sprintf("no entry bind to [%s:%d], needn't release", ins.Ip, ins.Port))
+	}
+	return plan
+}
+
 // ReportLogs report switch logs to hadb
 // Input param
 // result: constvar.FailResult, etc. in constvar
@@ -0,0 +1,243 @@
+package gm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"dbm-services/common/dbha/ha-module/client"
+	"dbm-services/common/dbha/ha-module/config"
+	"dbm-services/common/dbha/ha-module/constvar"
+	"dbm-services/common/dbha/ha-module/dbutil"
+	"dbm-services/common/dbha/ha-module/log"
+	"dbm-services/common/dbha/ha-module/util"
+)
+
+// SwitchApprovalHook gqa call the hooks in order before push instance to gcm
+type SwitchApprovalHook interface {
+	// GetName return hook name, use in ha logs
+	GetName() string
+	// Approve return allow, delay or reject, and the reason
+	Approve(ins dbutil.DataBaseSwitch) (string, string)
+}
+
+// NewSwitchApprovalHooks init approval hooks by GQA config, keep the order in config
+func NewSwitchApprovalHooks(conf *config.Config) ([]SwitchApprovalHook, error) {
+	var hooks []SwitchApprovalHook
+	for i, hookConf := range conf.GMConf.GQA.ApprovalHooks {
+		base := baseHook{
+			name:         hookConf.Name,
+			apps:         hookConf.Apps,
+			clusterTypes: hookConf.ClusterTypes,
+		}
+		if base.name == "" {
+			base.name = fmt.Sprintf("%s_%d", hookConf.Type, i)
+		}
+
+		switch hookConf.Type {
+		case constvar.HookFreezeWindow, constvar.HookMaintenanceWindow:
+			hook, err := newWindowHook(base, hookConf)
+			if err != nil {
+				return nil, fmt.Errorf("init approval hook %s failed:%s", base.name, err.Error())
+			}
+			hooks = append(hooks, hook)
+		case constvar.HookWebhook:
+			hook, err := newWebhookHook(base, hookConf, conf)
+			if err != nil {
+				return nil, fmt.Errorf("init approval hook %s failed:%s", base.name, err.Error())
+			}
+			hooks = append(hooks, hook)
+		default:
+			return nil, fmt.Errorf("unknown approval hook type:%s", hookConf.Type)
+		}
+		log.Logger.Infof("init approval hook %s, type:%s", base.name, hookConf.Type)
+	}
+	return hooks, nil
+}
+
+// baseHook common info of approval hook
+type baseHook struct {
+	name         string
+	apps         []string
+	clusterTypes []string
+}
+
+// GetName return hook name
+func (h *baseHook) GetName() string {
+	return h.name
+}
+
+// match whether the instance should be checked by this hook
+func (h *baseHook) match(ins dbutil.DataBaseSwitch) bool {
+	if len(h.apps) > 0 && !util.HasElem(ins.GetApp(), h.apps) {
+		return false
+	}
+	if len(h.clusterTypes) > 0 && !util.HasElem(ins.GetClusterType(), h.clusterTypes) {
+		return false
+	}
+	return true
+}
+
+// switchWindow time window, absolute or daily
+type switchWindow struct {
+	raw   string
+	daily bool
+	start time.Time
+	end   time.Time
+	// minutes of day for daily window
+	startMinute int
+	endMinute   int
+}
+
+// parseSwitchWindow parse "2006-01-02 15:04:05~2006-01-02 15:04:05" or daily "15:04~15:04"
+func parseSwitchWindow(raw string) (*switchWindow, error) {
+	parts := strings.Split(raw, "~")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid window %s, should be start~end", raw)
+	}
+	startStr, endStr := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	w := &switchWindow{raw: raw}
+
+	start, errStart := time.ParseInLocation("15:04", startStr, time.Local)
+	end, errEnd := time.ParseInLocation("15:04", endStr, time.Local)
+	if errStart == nil && errEnd == nil {
+		w.daily = true
+		w.startMinute = start.Hour()*60 + start.Minute()
+		w.endMinute = end.Hour()*60 + end.Minute()
+		return w, nil
+	}
+
+	var err error
+	if w.start, err = time.ParseInLocation("2006-01-02 15:04:05", startStr, time.Local); err != nil {
+		return nil, fmt.Errorf("invalid window start %s:%s", startStr, err.Error())
+	}
+	if w.end, err = time.ParseInLocation("2006-01-02 15:04:05", endStr, time.Local); err != nil {
+		return nil, fmt.Errorf("invalid window end %s:%s", endStr, err.Error())
+	}
+	if !w.end.After(w.start) {
+		return nil, fmt.Errorf("invalid window %s, end should after start", raw)
+	}
+	return w, nil
+}
+
+// contains whether the time in window, daily window may cross midnight
+func (w *switchWindow) contains(now time.Time) bool {
+	if !w.daily {
+		return !now.Before(w.start) && now.Before(w.end)
+	}
+	minute := now.Hour()*60 + now.Minute()
+	if w.startMinute <= w.endMinute {
+		return minute >= w.startMinute && minute < w.endMinute
+	}
+	return minute >= w.startMinute || minute < w.endMinute
+}
+
+// WindowHook delay or reject switch during change freeze window or maintenance window
+type WindowHook struct {
+	baseHook
+	hookType string
+	windows  []*switchWindow
+	action   string
+}
+
+func newWindowHook(base baseHook, hookConf config.ApprovalHookConfig) (*WindowHook, error) {
+	hook := &WindowHook{
+		baseHook: base,
+		hookType: hookConf.Type,
+		action:   hookConf.Action,
+	}
+	if hook.action == "" {
+		hook.action = constvar.ApprovalDelay
+	}
+	if hook.action != constvar.ApprovalDelay && hook.action != constvar.ApprovalReject {
+		return nil, fmt.Errorf("invalid window hook action:%s", hook.action)
+	}
+	if len(hookConf.Windows) == 0 {
+		return nil, fmt.Errorf("no window configured")
+	}
+	for _, raw := range hookConf.Windows {
+		w, err := parseSwitchWindow(raw)
+		if err != nil {
+			return nil, err
+		}
+		hook.windows = append(hook.windows, w)
+	}
+	return hook, nil
+}
+
+// Approve not allow switch if now in any window
+func (h *WindowHook) Approve(ins dbutil.DataBaseSwitch) (string, string) {
+	if !h.match(ins) {
+		return constvar.ApprovalAllow, ""
+	}
+	now := time.Now()
+	for _, w := range h.windows {
+		if w.contains(now) {
+			return h.action, fmt.Sprintf("in %s [%s]", h.hookType, w.raw)
+		}
+	}
+	return constvar.ApprovalAllow, ""
+}
+
+// WebhookHook ask remote api whether allow switch, e.g. whether an open ticket exists in flow system
+type WebhookHook struct {
+	baseHook
+	gmIp       string
+	client     *client.WebhookClient
+	failAction string
+}
+
+func newWebhookHook(base baseHook, hookConf config.ApprovalHookConfig, conf *config.Config) (*WebhookHook, error) {
+	if hookConf.Webhook.Host == "" {
+		return nil, fmt.Errorf("webhook host not configured")
+	}
+	webhookConf := hookConf.Webhook
+	hook := &WebhookHook{
+		baseHook:   base,
+		gmIp:       conf.GMConf.LocalIP,
+		client:     client.NewWebhookClient(&webhookConf, conf.GetCloudId()),
+		failAction: hookConf.FailAction,
+	}
+	// webhook unavailable should not let switch through
+	if hook.failAction == "" {
+		hook.failAction = constvar.ApprovalDelay
+	}
+	if !isApprovalAction(hook.failAction) {
+		return nil, fmt.Errorf("invalid webhook fail action:%s", hook.failAction)
+	}
+	return hook, nil
+}
+
+// Approve request webhook, use fail action if request failed or result invalid
+func (h *WebhookHook) Approve(ins dbutil.DataBaseSwitch) (string, string) {
+	if !h.match(ins) {
+		return constvar.ApprovalAllow, ""
+	}
+	ip, port := ins.GetAddress()
+	ctx, cancel := context.WithTimeout(context.Background(), h.client.Timeout())
+	defer cancel()
+	resp, err := h.client.ApproveSwitch(ctx, &client.SwitchApprovalRequest{
+		Hook:        h.name,
+		GMIp:        h.gmIp,
+		Ip:          ip,
+		Port:        port,
+		App:         ins.GetApp(),
+		Cluster:     ins.GetCluster(),
+		ClusterType: ins.GetClusterType(),
+		MetaType:    ins.GetMetaType(),
+		Role:        ins.GetRole(),
+		IdcID:       ins.GetIdcID(),
+	})
+	if err != nil {
+		return h.failAction, fmt.Sprintf("request webhook failed:%s", err.Error())
+	}
+	if !isApprovalAction(resp.Result) {
+		return h.failAction, fmt.Sprintf("webhook return invalid result:%s", resp.Result)
+	}
+	return resp.Result, resp.Reason
+}
+
+func isApprovalAction(action string) bool {
+	return action == constvar.ApprovalAllow || action == constvar.ApprovalDelay || action == constvar.ApprovalReject
+}
@@ -0,0 +1,176 @@
+package gm
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"dbm-services/common/dbha/ha-module/config"
+	"dbm-services/common/dbha/ha-module/constvar"
+	"dbm-services/common/dbha/ha-module/dbmodule/mongodb"
+	"dbm-services/common/dbha/ha-module/dbutil"
+	"dbm-services/common/dbha/ha-module/log"
+
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	log.Logger = zap.NewNop().Sugar()
+	os.Exit(m.Run())
+}
+
+func TestParseSwitchWindow(t *testing.T) {
+	cases := []struct {
+		raw   string
+		daily bool
+		fail  bool
+	}{
+		{"02:00~04:00", true, false},
+		{" 23:00 ~ 01:00 ", true, false},
+		{"2024-02-09 00:00:00~2024-02-18 00:00:00", false, false},
+		{"2024-02-18 00:00:00~2024-02-09 00:00:00", false, true},
+		{"2024-02-09 00:00:00~2024-02-09 00:00:00", false, true},
+		{"02:00", false, true},
+		{"02:00~04:00~06:00", false, true},
+		{"02:00~2024-02-18 00:00:00", false, true},
+		{"25:00~26:00", false, true},
+		{"2024-02-09~2024-02-18", false, true},
+	}
+	for _, c := range cases {
+		w, err := parseSwitchWindow(c.raw)
+		if (err != nil) != c.fail {
+			t.Errorf("parseSwitchWindow(%q) err %v, want fail %v", c.raw, err, c.fail)
+			continue
+		}
+		if err == nil && w.daily != c.daily {
+			t.Errorf("parseSwitchWindow(%q) daily %v, want %v", c.raw, w.daily, c.daily)
+		}
+	}
+}
+
+func TestSwitchWindowContains(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	cases := []struct {
+		raw  string
+		now  string
+		want bool
+	}{
+		{"02:00~04:00", "2024-01-01 01:59:59", false},
+		{"02:00~04:00", "2024-01-01 02:00:00", true},
+		{"02:00~04:00", "2024-01-01 03:59:59", true},
+		{"02:00~04:00", "2024-01-01 04:00:00", false},
+		// 跨零点
+		{"23:00~01:00", "2024-01-01 23:30:00", true},
+		{"23:00~01:00", "2024-01-02 00:30:00", true},
+		{"23:00~01:00", "2024-01-02 01:00:00", false},
+		{"23:00~01:00", "2024-01-01 12:00:00", false},
+		{"2024-02-09 00:00:00~2024-02-18 00:00:00", "2024-02-08 23:59:59", false},
+		{"2024-02-09 00:00:00~2024-02-18 00:00:00", "2024-02-09 00:00:00", true},
+		{"2024-02-09 00:00:00~2024-02-18 00:00:00", "2024-02-18 00:00:00", false},
+	}
+	for _, c := range cases {
+		w, err := parseSwitchWindow(c.raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := w.contains(at(c.now)); got != c.want {
+			t.Errorf("window %s contains %s = %v, want %v", c.raw, c.now, got, c.want)
+		}
+	}
+}
+
+// newTestWebhook start a webhook server handled by handler, return hook config point to it
+func newTestWebhook(t *testing.T, handler http.HandlerFunc) config.ApprovalHookConfig {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	host, portStr, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, _ := strconv.Atoi(portStr)
+	return config.ApprovalHookConfig{
+		Type:    constvar.HookWebhook,
+		Webhook: config.APIConfig{Host: host, Port: port, UrlPre: "/approve/", Timeout: 1},
+	}
+}
+
+func TestWebhookHookApprove(t *testing.T) {
+	conf := &config.Config{GMConf: &config.GMConfig{LocalIP: "127.0.0.1"}}
+	ins := &mongodb.MongosSwitch{BaseSwitch: dbutil.BaseSwitch{Ip: "1.1.1.1", Port: 27017, App: "100"}}
+
+	requests := 0
+	cases := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string
+	}{
+		{"reject", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"code":0,"data":{"result":"reject","reason":"ticket open"}}`))
+		}, constvar.ApprovalReject},
+		{"invalid result", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"code":0,"data":{"result":"ok"}}`))
+		}, constvar.ApprovalDelay},
+		{"server error not retried", func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusInternalServerError)
+		}, constvar.ApprovalDelay},
+		{"timeout", func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(3 * time.Second):
+			}
+		}, constvar.ApprovalDelay},
+	}
+	for _, c := range cases {
+		hook, err := newWebhookHook(baseHook{name: c.name}, newTestWebhook(t, c.handler), conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := time.Now()
+		action, reason := hook.Approve(ins)
+		if action != c.want {
+			t.Errorf("%s: action %s(%s), want %s", c.name, action, reason, c.want)
+		}
+		if cost := time.Since(start); cost > 2*time.Second {
+			t.Errorf("%s: approve cost %s, should stop at webhook timeout", c.name, cost)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("webhook requested %d times, want 1", requests)
+	}
+}
+
+func TestNewWebhookTimeout(t *testing.T) {
+	conf := &config.Config{GMConf: &config.GMConfig{}}
+	cases := []struct {
+		timeout int
+		want    time.Duration
+	}{
+		{0, constvar.WebhookDefaultTimeout * time.Second},
+		{5, 5 * time.Second},
+		{600, constvar.WebhookMaxTimeout * time.Second},
+	}
+	for _, c := range cases {
+		hookConf := config.ApprovalHookConfig{Webhook: config.APIConfig{Host: "127.0.0.1", Timeout: c.timeout}}
+		hook, err := newWebhookHook(baseHook{}, hookConf, conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hook.client.Timeout(); got != c.want {
+			t.Errorf("timeout %d: got %s, want %s", c.timeout, got, c.want)
+		}
+		if hookConf.Webhook.Timeout != c.timeout {
+			t.Errorf("config should not be modified")
+		}
+	}
+}
@@ -24,10 +24,11 @@ type GCM struct {
 	AllowedTimeDelayMax      int
 	ExecSlowKBytes           int
 	reporter                 *HAReporter
+	lease                    *GMLease
 }
 
 // NewGCM init new gcm
-func NewGCM(conf *config.Config, ch chan dbutil.DataBaseSwitch, reporter *HAReporter) *GCM {
+func NewGCM(conf *config.Config, ch chan dbutil.DataBaseSwitch, reporter *HAReporter, lease *GMLease) *GCM {
 	return &GCM{
 		GQAChan:                  ch,
 		Conf:                     conf,
@@ -36,6 +37,7 @@ func NewGCM(conf *config.Config, ch chan dbutil.DataBaseSwitch, reporter *HARepo
 		AllowedSlaveDelayMax:     conf.GMConf.GCM.AllowedSlaveDelayMax,
 		ExecSlowKBytes:           conf.GMConf.GCM.ExecSlowKBytes,
 		reporter:                 reporter,
+		lease:                    lease,
 		CmDBClient:               client.NewCmDBClient(&conf.DBConf.CMDB, conf.GetCloudId()),
 		HaDBClient:               client.NewHaDBClient(&conf.DBConf.HADB, conf.GetCloudId()),
 	}
@@ -66,8 +68,21 @@ func (gcm *GCM) Process(switchInstance dbutil.DataBaseSwitch) {
 func (gcm *GCM) DoSwitchSingle(switchInstance dbutil.DataBaseSwitch) {
 	var err error
 	log.Logger.Debugf("switch instance detail info:%#v", switchInstance)
+	if gcm.Conf.GMConf.GCM.IsShadow(switchInstance.GetClusterType()) {
+		gcm.DoShadowSwitch(switchInstance)
+		return
+	}
 	switchQueueInfo := &model.HASwitchQueue{}
 
+	// 切换过程中lease可能被其他gm接管，fencing token用于确认切换前lease仍由本gm持有
+	fencingToken := gcm.lease.GetFencingToken()
+	if err = gcm.lease.CheckFencing(fencingToken); err != nil {
+		log.Logger.Errorf("check gm lease failed, skip switch. err:%s, info{%s}", err.Error(),
+			switchInstance.ShowSwitchInstanceInfo())
+		return
+	}
+	switchInstance.SetInfo(constvar.FencingTokenKey, fencingToken)
+
 	// 这里先将实例获取锁设为unavailable，再插入switch_queue。原因是如果先插switch_queue，如果其他gm同时更新，则会有多条
 	// switch_queue记录，则更新switch_queue会同时更新多条记录，因为我们没有无法区分哪条记录是哪个gm插入的
 	log.Logger.Infof("get instance lock and set unavailable")
@@ -91,50 +106,7 @@ func (gcm *GCM) DoSwitchSingle(switchInstance dbutil.DataBaseSwitch) {
 	//only after insert switch queue, unique switch uid generated
 	switchInstance.ReportLogs(constvar.InfoResult, "set instance unavailable success")
 
-	for i := 0; i < 1; i++ {
-		switchInstance.ReportLogs(constvar.InfoResult, "do pre-check before switch")
-
-		var needContinue bool
-		needContinue, err = switchInstance.CheckSwitch()
-
-		if err != nil {
-			log.Logger.Errorf("check switch failed. err:%s, info{%s}", err.Error(),
-				switchInstance.ShowSwitchInstanceInfo())
-			err = fmt.Errorf("check switch failed:%s", err.Error())
-			break
-		}
-		switchInstance.ReportLogs(constvar.InfoResult, "pre-check ok")
-
-		if !needContinue {
-			break
-		}
-
-		switchInstance.ReportLogs(constvar.InfoResult, "start do switch")
-		err = switchInstance.DoSwitch()
-		if err != nil {
-			log.Logger.Errorf("do switch failed. err:%s, info{%s}", err.Error(),
-				switchInstance.ShowSwitchInstanceInfo())
-			err = fmt.Errorf("do switch failed:%s", err.Error())
-			break
-		}
-		switchInstance.ReportLogs(constvar.InfoResult, "do switch success")
-		switchInstance.ReportLogs(constvar.InfoResult, "last step, try to update meta info")
-
-		log.Logger.Infof("do update meta info. info{%s}", switchInstance.ShowSwitchInstanceInfo())
-		err = switchInstance.UpdateMetaInfo()
-		if err != nil {
-			log.Logger.Errorf("do update meta info failed. err:%s, info{%s}", err.Error(),
-				switchInstance.ShowSwitchInstanceInfo())
-			err = fmt.Errorf("do update meta info failed:%s", err.Error())
-			break
-		}
-		switchInstance.ReportLogs(constvar.InfoResult, "update meta info success")
-		err = switchInstance.DoFinal()
-		if err != nil {
-			log.Logger.Errorf("switch do final failed:%s", err.Error())
-			break
-		}
-	}
+	err = gcm.runSwitch(switchInstance, fencingToken)
 	if err != nil {
 		monitor.MonitorSendSwitch(switchInstance, err.Error(), false)
 		log.Logger.Errorf("switch instance failed. info:{%s}", switchInstance.ShowSwitchInstanceInfo())
@@ -171,6 +143,127 @@ func (gcm *GCM) DoSwitchSingle(switchInstance dbutil.DataBaseSwitch) {
 	}
 }
 
+// runSwitch do pre-check, switch and update meta info, check the lease still hold by gm with fencingToken
+// before each step that change anything, so that the switch stop once other gm take over the lease
+func (gcm *GCM) runSwitch(switchInstance dbutil.DataBaseSwitch, fencingToken int64) error {
+	switchInstance.ReportLogs(constvar.InfoResult, "do pre-check before switch")
+	needContinue, err := switchInstance.CheckSwitch()
+	if err != nil {
+		log.Logger.Errorf("check switch failed. err:%s, info{%s}", err.Error(),
+			switchInstance.ShowSwitchInstanceInfo())
+		return fmt.Errorf("check switch failed:%s", err.Error())
+	}
+	switchInstance.ReportLogs(constvar.InfoResult, "pre-check ok")
+	if !needContinue {
+		return nil
+	}
+
+	if err = gcm.lease.CheckFencing(fencingToken); err != nil {
+		return fmt.Errorf("check gm lease before switch failed:%s", err.Error())
+	}
+	switchInstance.ReportLogs(constvar.InfoResult, "start do switch")
+	if err = switchInstance.DoSwitch(); err != nil {
+		log.Logger.Errorf("do switch failed. err:%s, info{%s}", err.Error(),
+			switchInstance.ShowSwitchInstanceInfo())
+		return fmt.Errorf("do switch failed:%s", err.Error())
+	}
+	switchInstance.ReportLogs(constvar.InfoResult, "do switch success")
+	switchInstance.ReportLogs(constvar.InfoResult, "last step, try to update meta info")
+
+	if err = gcm.lease.CheckFencing(fencingToken); err != nil {
+		return fmt.Errorf("check gm lease before update meta info failed:%s", err.Error())
+	}
+	log.Logger.Infof("do update meta info. info{%s}", switchInstance.ShowSwitchInstanceInfo())
+	if err = switchInstance.UpdateMetaInfo(); err != nil {
+		log.Logger.Errorf("do update meta info failed. err:%s, info{%s}", err.Error(),
+			switchInstance.ShowSwitchInstanceInfo())
+		return fmt.Errorf("do update meta info failed:%s", err.Error())
+	}
+	switchInstance.ReportLogs(constvar.InfoResult, "update meta info success")
+	if err = switchInstance.DoFinal(); err != nil {
+		log.Logger.Errorf("switch do final failed:%s", err.Error())
+		return err
+	}
+	return nil
+}
+
+// DoShadowSwitch only do pre-check and report the switch plan into switch logs
+// instance status, name service and meta info would not be changed
+func (gcm *GCM) DoShadowSwitch(switchInstance dbutil.DataBaseSwitch) {
+	// instance status not changed in shadow mode, gqa would push it again in next cycle
+	ip, port := switchInstance.GetAddress()
+	shadowTotal, err := gcm.HaDBClient.QueryShadowTotal(ip, port, gcm.Conf.GMConf.GQA.SingleSwitchInterval)
+	if err != nil {
+		log.Logger.Errorf("query shadow total failed. err:%s, info{%s}", err.Error(),
+			switchInstance.ShowSwitchInstanceInfo())
+		return
+	}
+	if shadowTotal > 0 {
+		log.Logger.Debugf("instance already handled in shadow mode, skip. info{%s}",
+			switchInstance.ShowSwitchInstanceInfo())
+		return
+	}
+
+	log.Logger.Infof("shadow mode, insert tb_mon_switch_queue. info:{%s}", switchInstance.ShowSwitchInstanceInfo())
+	if err = gcm.InsertSwitchQueue(switchInstance); err != nil {
+		log.Logger.Errorf("insert switch queue failed. err:%s, info{%s}", err.Error(),
+			switchInstance.ShowSwitchInstanceInfo())
+		return
+	}
+	switchInstance.ReportLogs(constvar.InfoResult, "shadow mode, nothing would be changed")
+
+	switchQueueInfo := &model.HASwitchQueue{
+		Uid:    switchInstance.GetSwitchUid(),
+		Status: constvar.SwitchShadow,
+	}
+	switchInstance.ReportLogs(constvar.InfoResult, "do pre-check before switch")
+	needContinue, err := switchInstance.CheckSwitch()
+	switch {
+	case err != nil:
+		switchQueueInfo.SwitchResult = fmt.Sprintf("shadow switch check failed:%s", err.Error())
+	case !needContinue:
+		switchQueueInfo.SwitchResult = "shadow switch check ok, needn't switch"
+	default:
+		switchInstance.ReportLogs(constvar.InfoResult, "pre-check ok")
+		switchQueueInfo.SwitchResult = "shadow switch done"
+		if err = gcm.ReportSwitchPlan(switchInstance); err != nil {
+			switchQueueInfo.SwitchResult = fmt.Sprintf("shadow switch show plan failed:%s", err.Error())
+		}
+	}
+	switchInstance.ReportLogs(constvar.InfoResult, switchQueueInfo.SwitchResult)
+	log.Logger.Infof("%s. info:{%s}", switchQueueInfo.SwitchResult, switchInstance.ShowSwitchInstanceInfo())
+
+	if ok, slaveIp := switchInstance.GetInfo(constvar.SlaveIpKey); ok {
+		_, slavePort := switchInstance.GetInfo(constvar.SlavePortKey)
+		switchQueueInfo.SlaveIP = slaveIp.(string)
+		switchQueueInfo.SlavePort = slavePort.(int)
+	}
+	if updateErr := gcm.UpdateSwitchQueue(switchQueueInfo); updateErr != nil {
+		log.Logger.Errorf("update Switch queue failed. err:%s", updateErr.Error())
+	}
+}
+
+// ReportSwitchPlan report what DoSwitch would do into switch logs
+func (gcm *GCM) ReportSwitchPlan(switchInstance dbutil.DataBaseSwitch) error {
+	shadowIns, ok := switchInstance.(dbutil.DataBaseShadowSwitch)
+	if !ok {
+		switchInstance.ReportLogs(constvar.InfoResult,
+			fmt.Sprintf("[shadow] switch plan not support, would do switch %s",
+				switchInstance.ShowSwitchInstanceInfo()))
+		return nil
+	}
+
+	plan, err := shadowIns.ShowSwitchPlan()
+	if err != nil {
+		switchInstance.ReportLogs(constvar.FailResult, fmt.Sprintf("[shadow] show switch plan failed:%s", err.Error()))
+		return err
+	}
+	for i, step := range plan {
+		switchInstance.ReportLogs(constvar.InfoResult, fmt.Sprintf("[shadow] step %d: %s", i+1, step))
+	}
+	return nil
+}
+
 // InsertSwitchQueue insert switch info to tb_mon_switch_queue
 func (gcm *GCM) InsertSwitchQueue(instance dbutil.DataBaseSwitch) error {
 	log.Logger.Debugf("switch instance info:%#v", instance)
@@ -181,11 +274,21 @@ func (gcm *GCM) InsertSwitchQueue(instance dbutil.DataBaseSwitch) error {
 			confirmTime = t
 		}
 	}
+	var fencingToken int64
+	if ok, value := instance.GetInfo(constvar.FencingTokenKey); ok {
+		fencingToken, _ = value.(int64)
+	}
 	doubleCheckInfo := "unknown"
 	if ok, value := instance.GetInfo(constvar.DoubleCheckInfoKey); ok {
 		doubleCheckInfo = value.(string)
 	}
 
+	// shadow record insert with shadow status directly, so that never count into switch quota
+	status := constvar.SwitchStart
+	if gcm.Conf.GMConf.GCM.IsShadow(instance.GetClusterType()) {
+		status = constvar.SwitchShadow
+	}
+
 	currentTime := time.Now()
 	req := &client.SwitchQueueRequest{
 		DBCloudToken: gcm.Conf.DBConf.HADB.BKConf.BkToken,
@@ -200,10 +303,11 @@ func (gcm *GCM) InsertSwitchQueue(instance dbutil.DataBaseSwitch) error {
 			DbType:           instance.GetMetaType(),
 			CloudID:          gcm.Conf.GetCloudId(),
 			Cluster:          instance.GetCluster(),
-			Status:           constvar.SwitchStart,
+			Status:           status,
 			SwitchStartTime:  &currentTime,
 			DbRole:           instance.GetRole(),
 			ConfirmResult:    doubleCheckInfo,
+			FencingToken:     fencingToken,
 		},
 	}
 
@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"dbm-services/common/dbha/ha-module/agent"
 	"dbm-services/common/dbha/ha-module/config"
 	"dbm-services/common/dbha/ha-module/constvar"
 	"dbm-services/common/dbha/ha-module/log"
@@ -22,11 +23,14 @@ type GDM struct {
 	ScanInterval  int
 	Conf          *config.Config
 	reporter      *HAReporter
+	lease         *GMLease
+	// connection to the lease holder, use to forward agent report
+	leaderConn *agent.GMConnection
 }
 
 // NewGDM init gdm
 func NewGDM(conf *config.Config, ch chan DoubleCheckInstanceInfo,
-	reporter *HAReporter) *GDM {
+	reporter *HAReporter, lease *GMLease) *GDM {
 	return &GDM{
 		AgentChan:     make(chan DoubleCheckInstanceInfo, 10),
 		GMMChan:       ch,
@@ -37,6 +41,7 @@ func NewGDM(conf *config.Config, ch chan DoubleCheckInstanceInfo,
 		ScanInterval:  conf.GMConf.GDM.ScanInterval,
 		Conf:          conf,
 		reporter:      reporter,
+		lease:         lease,
 	}
 }
 
@@ -66,6 +71,10 @@ func (gdm *GDM) Init() {
 
 // Process gdm process instance
 func (gdm *GDM) Process(ins DoubleCheckInstanceInfo) {
+	if !gdm.lease.IsLeader() {
+		gdm.forwardToLeader(ins)
+		return
+	}
 	if !gdm.isReporterRecently(&ins) {
 		gdm.PushInstance2Next(ins)
 	}
@@ -83,6 +92,68 @@ func (gdm *GDM) PushInstance2Next(ins DoubleCheckInstanceInfo) {
 	return
 }
 
+// forwardToLeader fan in agent report to the lease holder, the holder do double check and switch
+func (gdm *GDM) forwardToLeader(ins DoubleCheckInstanceInfo) {
+	ip, port := ins.db.GetAddress()
+	leaderIp, leaderPort, ok := gdm.lease.GetLeader()
+	if !ok {
+		log.Logger.Warnf("no alive lease holder, drop report of instance[%s#%d]", ip, port)
+		return
+	}
+	if leaderIp == gdm.Conf.GMConf.LocalIP && leaderPort == gdm.ListenPort {
+		// lease not renewed in time, wait renew rather than forward to myself
+		log.Logger.Warnf("lease of myself expired, drop report of instance[%s#%d]", ip, port)
+		return
+	}
+
+	jsonInfo, err := ins.db.Serialization()
+	if err != nil {
+		log.Logger.Errorf("serialize instance[%s#%d] failed:%s", ip, port, err.Error())
+		return
+	}
+
+	if gdm.leaderConn != nil && (gdm.leaderConn.Ip != leaderIp || gdm.leaderConn.Port != leaderPort) {
+		gdm.closeLeaderConn()
+	}
+	timeout := time.Duration(gdm.Conf.GMConf.Lease.GetForwardTimeout()) * time.Second
+	if gdm.leaderConn == nil {
+		address := net.JoinHostPort(leaderIp, strconv.Itoa(leaderPort))
+		netConn, err := net.DialTimeout("tcp", address, timeout)
+		if err != nil {
+			log.Logger.Errorf("connect lease holder %s#%d failed:%s", leaderIp, leaderPort, err.Error())
+			return
+		}
+		gdm.leaderConn = &agent.GMConnection{
+			Ip:            leaderIp,
+			Port:          leaderPort,
+			NetConnection: netConn,
+			IsConnection:  true,
+		}
+	}
+
+	// the holder may hang, never block gdm on it
+	if err = gdm.leaderConn.NetConnection.SetDeadline(time.Now().Add(timeout)); err != nil {
+		log.Logger.Errorf("set deadline of connection to %s#%d failed:%s", leaderIp, leaderPort, err.Error())
+		gdm.closeLeaderConn()
+		return
+	}
+	if err = gdm.leaderConn.ReportInstance(ins.db.GetDetectType(), jsonInfo); err != nil {
+		log.Logger.Errorf("forward instance[%s#%d] to lease holder %s#%d failed:%s",
+			ip, port, leaderIp, leaderPort, err.Error())
+		gdm.closeLeaderConn()
+		return
+	}
+	log.Logger.Infof("forward instance[%s#%d] to lease holder %s#%d", ip, port, leaderIp, leaderPort)
+}
+
+func (gdm *GDM) closeLeaderConn() {
+	if err := gdm.leaderConn.NetConnection.Close(); err != nil {
+		log.Logger.Warnf("close connection to %s#%d failed:%s",
+			gdm.leaderConn.Ip, gdm.leaderConn.Port, err.Error())
+	}
+	gdm.leaderConn = nil
+}
+
 // listenAndDoAccept TODO
 // gdm do listen
 func (gdm *GDM) listenAndDoAccept() {
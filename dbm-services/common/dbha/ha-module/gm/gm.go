@@ -45,6 +45,7 @@ type GM struct {
 	gmm            *GMM
 	gqa            *GQA
 	gcm            *GCM
+	lease          *GMLease
 	HaDBClient     *client.HaDBClient
 	Conf           *config.Config
 	reportChan     chan ModuleReportInfo
@@ -66,10 +67,11 @@ func NewGM(conf *config.Config) *GM {
 		gm:             gm,
 		lastReportTime: time.Now(),
 	}
-	gm.gdm = NewGDM(conf, gdmToGmmChan, haReporter)
+	gm.lease = NewGMLease(conf, haReporter)
+	gm.gdm = NewGDM(conf, gdmToGmmChan, haReporter, gm.lease)
 	gm.gmm = NewGMM(gm.gdm, conf, gdmToGmmChan, gmmToGqaChan, haReporter)
-	gm.gqa = NewGQA(gm.gdm, conf, gmmToGqaChan, gqaToGcmChan, haReporter)
-	gm.gcm = NewGCM(conf, gqaToGcmChan, haReporter)
+	gm.gqa = NewGQA(gm.gdm, conf, gmmToGqaChan, gqaToGcmChan, haReporter, gm.lease)
+	gm.gcm = NewGCM(conf, gqaToGcmChan, haReporter, gm.lease)
 	return gm
 }
 
@@ -80,6 +82,14 @@ func (gm *GM) Run() error {
 		return err
 	}
 
+	if gm.lease.Enable() {
+		// acquire once before gdm start, so that reports are not forwarded to nowhere
+		gm.lease.Renew()
+		go func() {
+			gm.lease.Run()
+		}()
+	}
+
 	if err := gm.HaDBClient.RegisterDBHAInfo(gm.Conf.GMConf.LocalIP, gm.Conf.GMConf.ListenPort, constvar.GDM,
 		gm.Conf.GMConf.CityID, gm.Conf.GMConf.Campus, "N/A"); err != nil {
 		log.Logger.Errorf("GM register gcm module failed,err:%s", err.Error())
@@ -106,6 +116,11 @@ func (gm *GM) Run() error {
 		return err
 	}
 
+	if err := gm.gqa.InitApprovalHooks(); err != nil {
+		log.Logger.Errorf("GM init gqa approval hooks failed,err:%s", err.Error())
+		return err
+	}
+
 	go func() {
 		gm.gqa.Run()
 	}()
@@ -124,6 +139,11 @@ func (gm *GM) Run() error {
 	return nil
 }
 
+// GetLease return gm lease object
+func (gm *GM) GetLease() *GMLease {
+	return gm.lease
+}
+
 // GetGDM return gdm object
 func (gm *GM) GetGDM() *GDM {
 	return gm.gdm
@@ -209,3 +229,11 @@ func (reporter *HAReporter) DoReport(reportInfo ModuleReportInfo) {
 		reporter.gm.DoDBHAReport(reportInfo.Module)
 	}
 }
+
+// ReportLease report lease expiry, handover and fencing token change to ha logs
+func (reporter *HAReporter) ReportLease(event string) {
+	gmConf := reporter.gm.Conf.GMConf
+	log.Logger.Infof("gm[%s#%d] city[%d] %s", gmConf.LocalIP, gmConf.ListenPort, gmConf.CityID, event)
+	reporter.gm.HaDBClient.ReportHaLog(gmConf.LocalIP, "", gmConf.LocalIP, gmConf.ListenPort,
+		constvar.GMLease, event)
+}
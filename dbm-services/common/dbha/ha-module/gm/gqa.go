@@ -28,12 +28,14 @@ type GQA struct {
 	AllSwitchLimit       int
 	SingleSwitchIDCLimit int
 	reporter             *HAReporter
+	lease                *GMLease
+	approvalHooks        []SwitchApprovalHook
 }
 
 // NewGQA init GQA object
 func NewGQA(gdm *GDM, conf *config.Config,
 	gmmCh chan DoubleCheckInstanceInfo,
-	gcmCh chan dbutil.DataBaseSwitch, reporter *HAReporter) *GQA {
+	gcmCh chan dbutil.DataBaseSwitch, reporter *HAReporter, lease *GMLease) *GQA {
 	return &GQA{
 		GMMChan:              gmmCh,
 		GCMChan:              gcmCh,
@@ -47,16 +49,33 @@ func NewGQA(gdm *GDM, conf *config.Config,
 		AllSwitchLimit:       conf.GMConf.GQA.AllHostSwitchLimit,
 		SingleSwitchIDCLimit: conf.GMConf.GQA.SingleSwitchIDC,
 		reporter:             reporter,
+		lease:                lease,
 		CmDBClient:           client.NewCmDBClient(&conf.DBConf.CMDB, conf.GetCloudId()),
 		HaDBClient:           client.NewHaDBClient(&conf.DBConf.HADB, conf.GetCloudId()),
 	}
 }
 
+// InitApprovalHooks init switch approval hooks by config
+func (gqa *GQA) InitApprovalHooks() error {
+	hooks, err := NewSwitchApprovalHooks(gqa.Conf)
+	if err != nil {
+		return err
+	}
+	gqa.approvalHooks = hooks
+	return nil
+}
+
 // Run GQA main entry
 func (gqa *GQA) Run() {
 	for {
 		select {
 		case ins := <-gqa.GMMChan:
+			if !gqa.lease.IsLeader() {
+				ip, port := ins.db.GetAddress()
+				log.Logger.Warnf("gm not hold the lease, skip instance. ip:%s, port:%d", ip, port)
+				gqa.gdm.InstanceSwitchDone(ip, port, ins.db.GetDetectType())
+				continue
+			}
 			instances := gqa.PreProcess(ins)
 			gqa.Process(instances)
 		case <-time.After(time.Duration(gqa.Conf.GMConf.ReportInterval) * time.Second):
@@ -188,6 +207,26 @@ func (gqa *GQA) Process(cmdbInfos []dbutil.DataBaseSwitch) {
 			continue
 		}
 
+		// check approval hooks in order
+		action, reason := gqa.approveSwitch(instanceInfo)
+		if action == constvar.ApprovalDelay {
+			err = gqa.delaySwitch(instanceInfo)
+			if err != nil {
+				errInfo := fmt.Sprintf("delay switch failed. err:%s", err.Error())
+				log.Logger.Errorf(errInfo)
+				gqa.HaDBClient.ReportHaLog(gmIP, instanceInfo.GetApp(), ip, port, "gqa", errInfo)
+			} else {
+				gqa.HaDBClient.ReportHaLog(gmIP, instanceInfo.GetApp(), ip, port, "gqa",
+					fmt.Sprintf("%s, delay switch", reason))
+			}
+			continue
+		}
+		if action == constvar.ApprovalReject {
+			gqa.HaDBClient.ReportHaLog(gmIP, instanceInfo.GetApp(), ip, port, "gqa",
+				fmt.Sprintf("%s, reject switch", reason))
+			continue
+		}
+
 		// query instance and proxy info
 		log.Logger.Infof("start switch. ip:%s, port:%d, cluster_Type:%s, app:%s",
 			ip, port, instanceInfo.GetClusterType(), instanceInfo.GetApp())
@@ -236,6 +275,22 @@ func (gqa *GQA) getAllInstanceFromCMDB(
 	return ret, nil
 }
 
+// approveSwitch check approval hooks in order, stop at the first hook not allow
+func (gqa *GQA) approveSwitch(instance dbutil.DataBaseSwitch) (string, string) {
+	for _, hook := range gqa.approvalHooks {
+		action, reason := hook.Approve(instance)
+		if action != constvar.ApprovalAllow {
+			return action, fmt.Sprintf("approval hook[%s]: %s", hook.GetName(), reason)
+		}
+		if reason != "" {
+			ip, port := instance.GetAddress()
+			log.Logger.Infof("approval hook[%s] allow switch. ip:%s, port:%d, reason:%s",
+				hook.GetName(), ip, port, reason)
+		}
+	}
+	return constvar.ApprovalAllow, ""
+}
+
 func (gqa *GQA) delaySwitch(instance dbutil.DataBaseSwitch) error {
 	ip, port := instance.GetAddress()
 	log.Logger.Infof("start delay switch. ip:%s, port:%d, app:%s",
@@ -0,0 +1,182 @@
+package gm
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"dbm-services/common/dbha/ha-module/client"
+	"dbm-services/common/dbha/ha-module/config"
+	"dbm-services/common/dbha/ha-module/log"
+)
+
+// GMLease leader lease among gm in the same city, only the holder do gqa/gcm,
+// other gm forward the agent report to the holder
+type GMLease struct {
+	Conf       *config.Config
+	HaDBClient *client.HaDBClient
+	reporter   *HAReporter
+	mutex      sync.RWMutex
+	isLeader   bool
+	holderIP   string
+	holderPort int
+	// fencing token increase every time the lease change holder
+	fencingToken int64
+	// local deadline of the lease, computed by the time before acquire request sent plus the
+	// remain time return by hadb, so it never later than the deadline in hadb and not affected by clock skew
+	expireTime time.Time
+}
+
+// NewGMLease init gm lease
+func NewGMLease(conf *config.Config, reporter *HAReporter) *GMLease {
+	return &GMLease{
+		Conf:       conf,
+		HaDBClient: client.NewHaDBClient(&conf.DBConf.HADB, conf.GetCloudId()),
+		reporter:   reporter,
+	}
+}
+
+// Enable whether lease enabled
+func (l *GMLease) Enable() bool {
+	return l.Conf.GMConf.Lease.Enable
+}
+
+// Run lease main entry, keep acquire/renew the lease
+func (l *GMLease) Run() {
+	interval := l.Conf.GMConf.Lease.RenewInterval
+	if interval <= 0 {
+		interval = 1
+	}
+	for {
+		l.Renew()
+		time.Sleep(time.Duration(interval) * time.Second)
+	}
+}
+
+// Renew acquire the lease if no holder, renew it if gm is the holder
+func (l *GMLease) Renew() {
+	gmConf := l.Conf.GMConf
+	start := time.Now()
+	resp, err := l.HaDBClient.AcquireGMLease(gmConf.LocalIP, gmConf.ListenPort, gmConf.CityID,
+		gmConf.Lease.LeaseTime)
+	if err != nil {
+		log.Logger.Errorf("acquire gm lease failed:%s", err.Error())
+	}
+	if event := l.refresh(start, resp, err); event != "" {
+		l.reporter.ReportLease(event)
+	}
+}
+
+// refresh update local lease info by acquire result, return the lease event if holder changed
+func (l *GMLease) refresh(start time.Time, resp *client.GMLeaseResponse, err error) string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if err != nil {
+		if l.isLeader && !time.Now().Before(l.expireTime) {
+			l.isLeader = false
+			return fmt.Sprintf("lease expired, renew failed:%s, token:%d", err.Error(), l.fencingToken)
+		}
+		return ""
+	}
+
+	var event string
+	lease := resp.Lease
+	switch {
+	case resp.Acquired && !l.isLeader && l.holderIP == "":
+		event = fmt.Sprintf("lease acquired, token:%d", lease.FencingToken)
+	case resp.Acquired && !l.isLeader:
+		event = fmt.Sprintf("lease acquired, handover from %s#%d(token:%d), token:%d",
+			l.holderIP, l.holderPort, l.fencingToken, lease.FencingToken)
+	case resp.Acquired && l.fencingToken != lease.FencingToken:
+		// lease expired in hadb before renew, re-acquired with new token
+		event = fmt.Sprintf("lease expired and re-acquired, token:%d->%d", l.fencingToken, lease.FencingToken)
+	case !resp.Acquired && l.isLeader:
+		event = fmt.Sprintf("lease lost, new holder %s#%d, token:%d",
+			lease.HolderIP, lease.HolderPort, lease.FencingToken)
+	}
+
+	l.isLeader = resp.Acquired
+	l.holderIP = lease.HolderIP
+	l.holderPort = lease.HolderPort
+	l.fencingToken = lease.FencingToken
+	l.expireTime = start.Add(time.Duration(resp.RemainMs) * time.Millisecond)
+	return event
+}
+
+// Release release the lease if gm is the holder, called before gm exit,
+// so that other gm take over without waiting the lease expire
+func (l *GMLease) Release() {
+	if !l.Enable() {
+		return
+	}
+	l.mutex.Lock()
+	isLeader, token := l.isLeader, l.fencingToken
+	l.isLeader = false
+	l.expireTime = time.Now()
+	l.mutex.Unlock()
+	if !isLeader {
+		return
+	}
+
+	gmConf := l.Conf.GMConf
+	if err := l.HaDBClient.ReleaseGMLease(gmConf.LocalIP, gmConf.ListenPort, gmConf.CityID, token); err != nil {
+		log.Logger.Errorf("release gm lease failed:%s", err.Error())
+		return
+	}
+	l.reporter.ReportLease(fmt.Sprintf("lease released, token:%d", token))
+}
+
+// IsLeader whether gm hold the lease now, always true if lease disabled
+func (l *GMLease) IsLeader() bool {
+	if !l.Enable() {
+		return true
+	}
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return l.isLeader && time.Now().Before(l.expireTime)
+}
+
+// GetLeader return the lease holder, ok is false if no alive holder
+func (l *GMLease) GetLeader() (ip string, port int, ok bool) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	if l.holderIP == "" || !time.Now().Before(l.expireTime) {
+		return "", 0, false
+	}
+	return l.holderIP, l.holderPort, true
+}
+
+// GetFencingToken return the fencing token gm hold, 0 if not the holder
+func (l *GMLease) GetFencingToken() int64 {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	if !l.isLeader {
+		return 0
+	}
+	return l.fencingToken
+}
+
+// CheckFencing check the lease in hadb still hold by gm with the given token,
+// gcm call this before change anything
+func (l *GMLease) CheckFencing(token int64) error {
+	if !l.Enable() {
+		return nil
+	}
+	if !l.IsLeader() {
+		return fmt.Errorf("gm not hold the lease")
+	}
+	gmConf := l.Conf.GMConf
+	lease, err := l.HaDBClient.GetGMLease(gmConf.CityID)
+	if err != nil {
+		return fmt.Errorf("query gm lease failed:%s", err.Error())
+	}
+	if lease == nil {
+		return fmt.Errorf("gm lease not exist")
+	}
+	if lease.HolderIP != gmConf.LocalIP || lease.HolderPort != gmConf.ListenPort ||
+		lease.FencingToken != token {
+		return fmt.Errorf("gm lease changed, holder %s#%d, token:%d, expect token:%d",
+			lease.HolderIP, lease.HolderPort, lease.FencingToken, token)
+	}
+	return nil
+}
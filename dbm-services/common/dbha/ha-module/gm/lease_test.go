@@ -0,0 +1,440 @@
+package gm
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"dbm-services/common/dbha/ha-module/client"
+	"dbm-services/common/dbha/ha-module/config"
+	"dbm-services/common/dbha/ha-module/constvar"
+	"dbm-services/common/dbha/ha-module/dbutil"
+	"dbm-services/common/dbha/hadb-api/model"
+)
+
+// fakeLeaseDB simulate the gm lease api of hadb
+type fakeLeaseDB struct {
+	mu     sync.Mutex
+	lease  *model.HAGMLease
+	expire time.Time
+	// lease duration in milliseconds, use lease_time of request if 0
+	leaseMs  int64
+	fail     bool
+	releases int
+	// comment of ha logs, the lease events
+	events []string
+}
+
+func (f *fakeLeaseDB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name      string          `json:"name"`
+		QueryArgs json.RawMessage `json:"query_args"`
+		SetArgs   json.RawMessage `json:"set_args"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if req.Name == constvar.ReporterHALog {
+		var l model.HaGMLogs
+		_ = json.Unmarshal(req.SetArgs, &l)
+		f.events = append(f.events, l.Comment)
+		f.reply(w, map[string]int{"rowsAffected": 1})
+		return
+	}
+	if f.fail {
+		_, _ = w.Write([]byte(`{"code":1,"msg":"hadb unavailable"}`))
+		return
+	}
+
+	now := time.Now()
+	switch req.Name {
+	case constvar.AcquireGMLease:
+		var args model.HAGMLease
+		_ = json.Unmarshal(req.SetArgs, &args)
+		expired := f.lease == nil || !now.Before(f.expire)
+		sameHolder := f.lease != nil && f.lease.HolderIP == args.HolderIP && f.lease.HolderPort == args.HolderPort
+		acquired := expired || sameHolder
+		if acquired {
+			if expired || !sameHolder {
+				f.takeover(args.HolderIP, args.HolderPort)
+			}
+			d := time.Duration(args.LeaseTime) * time.Second
+			if f.leaseMs > 0 {
+				d = time.Duration(f.leaseMs) * time.Millisecond
+			}
+			f.expire = now.Add(d)
+		}
+		f.reply(w, client.GMLeaseResponse{Acquired: acquired, Lease: *f.lease,
+			RemainMs: f.expire.Sub(now).Milliseconds()})
+	case constvar.ReleaseGMLease:
+		var args model.HAGMLease
+		_ = json.Unmarshal(req.QueryArgs, &args)
+		f.releases++
+		if f.lease != nil && f.lease.HolderIP == args.HolderIP && f.lease.HolderPort == args.HolderPort &&
+			f.lease.FencingToken == args.FencingToken {
+			f.expire = now
+		}
+		f.reply(w, map[string]int{"rowsAffected": 1})
+	case constvar.QueryGMLease:
+		f.reply(w, f.lease)
+	default:
+		_, _ = w.Write([]byte(`{"code":1,"msg":"unknown api"}`))
+	}
+}
+
+func (f *fakeLeaseDB) reply(w http.ResponseWriter, data interface{}) {
+	b, _ := json.Marshal(data)
+	_ = json.NewEncoder(w).Encode(client.APIServerResponse{Code: 0, Data: b})
+}
+
+// takeover change the holder and increase fencing token, caller should hold the mutex
+func (f *fakeLeaseDB) takeover(ip string, port int) {
+	var token int64 = 1
+	if f.lease != nil {
+		token = f.lease.FencingToken + 1
+	}
+	f.lease = &model.HAGMLease{CityID: 1, HolderIP: ip, HolderPort: port, FencingToken: token}
+	f.expire = time.Now().Add(time.Minute)
+}
+
+func (f *fakeLeaseDB) set(fn func(f *fakeLeaseDB)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fn(f)
+}
+
+func (f *fakeLeaseDB) takeEvents() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	events := f.events
+	f.events = nil
+	return events
+}
+
+func newFakeLeaseDB(t *testing.T) (*fakeLeaseDB, config.APIConfig) {
+	f := &fakeLeaseDB{}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	host, portStr, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, _ := strconv.Atoi(portStr)
+	return f, config.APIConfig{Host: host, Port: port, UrlPre: "/hadb/", Timeout: 1}
+}
+
+func newTestLease(hadb config.APIConfig, ip string, port int, enable bool) *GMLease {
+	conf := &config.Config{
+		GMConf: &config.GMConfig{LocalIP: ip, ListenPort: port, CityID: 1,
+			Lease: config.LeaseConfig{Enable: enable, LeaseTime: 10}},
+		DBConf: config.DBConfig{HADB: hadb},
+	}
+	gm := &GM{Conf: conf, HaDBClient: client.NewHaDBClient(&conf.DBConf.HADB, conf.GetCloudId())}
+	return NewGMLease(conf, &HAReporter{gm: gm})
+}
+
+func checkEvents(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("events %q, want %q", got, want)
+		return
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]) {
+			t.Errorf("events %q, want %q", got, want)
+			return
+		}
+	}
+}
+
+func TestGMLeaseRefresh(t *testing.T) {
+	lease := func(ip string, token int64) model.HAGMLease {
+		return model.HAGMLease{HolderIP: ip, HolderPort: 50000, FencingToken: token}
+	}
+	type state struct {
+		leader bool
+		holder string
+		token  int64
+	}
+	cases := []struct {
+		name   string
+		before state
+		resp   *client.GMLeaseResponse
+		err    error
+		event  string
+		after  state
+	}{
+		{"first acquire", state{}, &client.GMLeaseResponse{Acquired: true, Lease: lease("1.1.1.1", 1)},
+			nil, "lease acquired, token:1", state{true, "1.1.1.1", 1}},
+		{"handover", state{false, "2.2.2.2", 1}, &client.GMLeaseResponse{Acquired: true, Lease: lease("1.1.1.1", 2)},
+			nil, "lease acquired, handover from 2.2.2.2#50000(token:1), token:2", state{true, "1.1.1.1", 2}},
+		{"renew", state{true, "1.1.1.1", 2}, &client.GMLeaseResponse{Acquired: true, Lease: lease("1.1.1.1", 2)},
+			nil, "", state{true, "1.1.1.1", 2}},
+		{"re-acquire after expire", state{true, "1.1.1.1", 2},
+			&client.GMLeaseResponse{Acquired: true, Lease: lease("1.1.1.1", 3)},
+			nil, "lease expired and re-acquired, token:2->3", state{true, "1.1.1.1", 3}},
+		{"lost", state{true, "1.1.1.1", 3}, &client.GMLeaseResponse{Acquired: false, Lease: lease("2.2.2.2", 4)},
+			nil, "lease lost, new holder 2.2.2.2#50000, token:4", state{false, "2.2.2.2", 4}},
+		{"follower", state{false, "2.2.2.2", 4}, &client.GMLeaseResponse{Acquired: false, Lease: lease("2.2.2.2", 4)},
+			nil, "", state{false, "2.2.2.2", 4}},
+		// renew failed but lease not expired, keep the leadership
+		{"renew failed in lease", state{true, "1.1.1.1", 3}, nil, errors.New("timeout"),
+			"", state{true, "1.1.1.1", 3}},
+	}
+	for _, c := range cases {
+		l := &GMLease{isLeader: c.before.leader, holderIP: c.before.holder, holderPort: 50000,
+			fencingToken: c.before.token, expireTime: time.Now().Add(time.Minute)}
+		if c.resp != nil {
+			c.resp.RemainMs = 10000
+		}
+		if event := l.refresh(time.Now(), c.resp, c.err); event != c.event {
+			t.Errorf("%s: event %q, want %q", c.name, event, c.event)
+		}
+		if l.isLeader != c.after.leader || l.holderIP != c.after.holder || l.fencingToken != c.after.token {
+			t.Errorf("%s: state %v %s %d, want %+v", c.name, l.isLeader, l.holderIP, l.fencingToken, c.after)
+		}
+	}
+
+	// renew failed after the local deadline, drop the leadership
+	l := &GMLease{isLeader: true, holderIP: "1.1.1.1", fencingToken: 3, expireTime: time.Now().Add(-time.Millisecond)}
+	if event := l.refresh(time.Now(), nil, errors.New("timeout")); event != "lease expired, renew failed:timeout, token:3" {
+		t.Errorf("expired: event %q", event)
+	}
+	if l.isLeader {
+		t.Error("expired: should not be leader")
+	}
+
+	// local deadline computed from the time before request sent
+	l = &GMLease{}
+	start := time.Now().Add(-time.Second)
+	l.refresh(start, &client.GMLeaseResponse{Acquired: true, Lease: lease("1.1.1.1", 1), RemainMs: 3000}, nil)
+	if !l.expireTime.Equal(start.Add(3 * time.Second)) {
+		t.Errorf("expire time %s, want %s", l.expireTime, start.Add(3*time.Second))
+	}
+}
+
+func TestGMLeaseTakeover(t *testing.T) {
+	f, hadb := newFakeLeaseDB(t)
+	a := newTestLease(hadb, "1.1.1.1", 50000, true)
+	b := newTestLease(hadb, "2.2.2.2", 50000, true)
+
+	a.Renew()
+	b.Renew()
+	if !a.IsLeader() || b.IsLeader() {
+		t.Fatalf("a leader %v, b leader %v, want only a", a.IsLeader(), b.IsLeader())
+	}
+	if ip, _, ok := b.GetLeader(); !ok || ip != "1.1.1.1" {
+		t.Errorf("b get leader %s %v, want 1.1.1.1", ip, ok)
+	}
+	token := a.GetFencingToken()
+	if token != 1 || b.GetFencingToken() != 0 {
+		t.Errorf("token a %d b %d, want 1 0", token, b.GetFencingToken())
+	}
+	if err := a.CheckFencing(token); err != nil {
+		t.Errorf("a check fencing: %v", err)
+	}
+	if err := b.CheckFencing(1); err == nil {
+		t.Error("b not leader, check fencing should fail")
+	}
+	checkEvents(t, f.takeEvents(), "lease acquired, token:1")
+
+	// lease expire in hadb before a renew, b take over with new token
+	f.set(func(f *fakeLeaseDB) { f.expire = time.Now() })
+	b.Renew()
+	if !b.IsLeader() || b.GetFencingToken() != 2 {
+		t.Fatalf("b should take over with token 2, leader %v token %d", b.IsLeader(), b.GetFencingToken())
+	}
+	checkEvents(t, f.takeEvents(), "lease acquired, handover from 1.1.1.1#50000(token:1), token:2")
+
+	// a still leader by local deadline, but the stale token refused
+	if !a.IsLeader() {
+		t.Error("a local lease not expire yet")
+	}
+	if err := a.CheckFencing(token); err == nil || !strings.Contains(err.Error(), "lease changed") {
+		t.Errorf("a check stale token: %v", err)
+	}
+
+	a.Renew()
+	if a.IsLeader() || a.GetFencingToken() != 0 {
+		t.Errorf("a should lose the lease, leader %v token %d", a.IsLeader(), a.GetFencingToken())
+	}
+	if err := a.CheckFencing(token); err == nil {
+		t.Error("a lost lease, check fencing should fail")
+	}
+	checkEvents(t, f.takeEvents(), "lease lost, new holder 2.2.2.2#50000, token:2")
+}
+
+func TestGMLeaseRenewFailed(t *testing.T) {
+	f, hadb := newFakeLeaseDB(t)
+	f.leaseMs = 300
+	a := newTestLease(hadb, "1.1.1.1", 50000, true)
+	a.Renew()
+	if !a.IsLeader() {
+		t.Fatal("a should be leader")
+	}
+	f.takeEvents()
+
+	f.set(func(f *fakeLeaseDB) { f.fail = true })
+	a.Renew()
+	if !a.IsLeader() {
+		t.Error("renew failed in lease time, should keep leadership")
+	}
+
+	time.Sleep(400 * time.Millisecond)
+	if a.IsLeader() {
+		t.Error("local lease expired, should not be leader")
+	}
+	if _, _, ok := a.GetLeader(); ok {
+		t.Error("no alive leader after lease expired")
+	}
+	a.Renew()
+	if a.GetFencingToken() != 0 {
+		t.Errorf("token %d after renew failed, want 0", a.GetFencingToken())
+	}
+	checkEvents(t, f.takeEvents(), "lease expired, renew failed")
+}
+
+func TestGMLeaseRelease(t *testing.T) {
+	f, hadb := newFakeLeaseDB(t)
+	a := newTestLease(hadb, "1.1.1.1", 50000, true)
+	b := newTestLease(hadb, "2.2.2.2", 50000, true)
+
+	// not leader, nothing to release
+	b.Release()
+	if f.releases != 0 {
+		t.Errorf("follower should not request release")
+	}
+
+	a.Renew()
+	b.Renew()
+	a.Release()
+	if a.IsLeader() || f.releases != 1 {
+		t.Errorf("a leader %v after release, releases %d", a.IsLeader(), f.releases)
+	}
+	// b take over without waiting the lease expire
+	b.Renew()
+	if !b.IsLeader() || b.GetFencingToken() != 2 {
+		t.Errorf("b should take over after release, leader %v token %d", b.IsLeader(), b.GetFencingToken())
+	}
+	checkEvents(t, f.takeEvents(), "lease acquired, token:1", "lease released, token:1",
+		"lease acquired, handover from 1.1.1.1#50000(token:1), token:2")
+}
+
+func TestGMLeaseDisabled(t *testing.T) {
+	f, hadb := newFakeLeaseDB(t)
+	l := newTestLease(hadb, "1.1.1.1", 50000, false)
+	if !l.IsLeader() {
+		t.Error("always leader if lease disabled")
+	}
+	if err := l.CheckFencing(0); err != nil {
+		t.Errorf("check fencing with lease disabled: %v", err)
+	}
+	l.Release()
+	if f.releases != 0 {
+		t.Error("release should do nothing if lease disabled")
+	}
+}
+
+// fakeSwitch record the switch steps, onStep called after each step
+type fakeSwitch struct {
+	dbutil.DataBaseSwitch
+	steps  []string
+	onStep func(step string)
+	noNeed bool
+}
+
+func (s *fakeSwitch) step(name string) {
+	s.steps = append(s.steps, name)
+	if s.onStep != nil {
+		s.onStep(name)
+	}
+}
+
+func (s *fakeSwitch) CheckSwitch() (bool, error) {
+	s.step("check")
+	return !s.noNeed, nil
+}
+
+func (s *fakeSwitch) DoSwitch() error {
+	s.step("switch")
+	return nil
+}
+
+func (s *fakeSwitch) UpdateMetaInfo() error {
+	s.step("update")
+	return nil
+}
+
+func (s *fakeSwitch) DoFinal() error {
+	s.step("final")
+	return nil
+}
+
+func (s *fakeSwitch) ShowSwitchInstanceInfo() string         { return "fake" }
+func (s *fakeSwitch) ReportLogs(result, comment string) bool { return true }
+func (s *fakeSwitch) GetClusterType() string                 { return "fake" }
+
+func TestGCMRunSwitchFencing(t *testing.T) {
+	cases := []struct {
+		name     string
+		enable   bool
+		noNeed   bool
+		takeover string
+		steps    string
+		err      string
+	}{
+		{"lease hold", true, false, "", "check,switch,update,final", ""},
+		{"needn't switch", true, true, "", "check", ""},
+		{"takeover before switch", true, false, "check", "check", "before switch"},
+		{"takeover before update meta", true, false, "switch", "check,switch", "before update meta info"},
+		{"lease disabled", false, false, "check", "check,switch,update,final", ""},
+	}
+	for _, c := range cases {
+		f, hadb := newFakeLeaseDB(t)
+		lease := newTestLease(hadb, "1.1.1.1", 50000, c.enable)
+		lease.Renew()
+		gcm := &GCM{Conf: lease.Conf, lease: lease}
+
+		ins := &fakeSwitch{noNeed: c.noNeed, onStep: func(step string) {
+			if step == c.takeover {
+				f.set(func(f *fakeLeaseDB) { f.takeover("2.2.2.2", 50000) })
+			}
+		}}
+		err := gcm.runSwitch(ins, lease.GetFencingToken())
+		if steps := strings.Join(ins.steps, ","); steps != c.steps {
+			t.Errorf("%s: steps %s, want %s", c.name, steps, c.steps)
+		}
+		if (err == nil) != (c.err == "") || (err != nil && !strings.Contains(err.Error(), c.err)) {
+			t.Errorf("%s: err %v, want %q", c.name, err, c.err)
+		}
+	}
+}
+
+// TestGCMSkipSwitchWithoutLease gm not hold the lease should not touch the instance at all
+func TestGCMSkipSwitchWithoutLease(t *testing.T) {
+	f, hadb := newFakeLeaseDB(t)
+	f.set(func(f *fakeLeaseDB) { f.takeover("2.2.2.2", 50000) })
+	lease := newTestLease(hadb, "1.1.1.1", 50000, true)
+	lease.Renew()
+	if lease.IsLeader() {
+		t.Fatal("lease hold by other gm")
+	}
+	gcm := &GCM{Conf: lease.Conf, lease: lease}
+	// any method not implemented by fakeSwitch panic, the switch must stop before lock the instance
+	ins := &fakeSwitch{}
+	gcm.DoSwitchSingle(ins)
+	if len(ins.steps) != 0 {
+		t.Errorf("switch steps %v, want none", ins.steps)
+	}
+}
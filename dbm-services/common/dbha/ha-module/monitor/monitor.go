@@ -157,6 +157,12 @@ func GetMonitorInfoBySwitch(ins dbutil.DataBaseSwitch, succ bool) MonitorInfo {
 		} else {
 			eventName = constvar.DBHAEventRiakSwitchErr
 		}
+	case constvar.MongoDBMetaType, constvar.MongosMetaType, constvar.MongoConfigMetaType:
+		if succ {
+			eventName = constvar.DBHAEventMongoDBSwitchSucc
+		} else {
+			eventName = constvar.DBHAEventMongoDBSwitchErr
+		}
 	default:
 		if succ {
 			eventName = constvar.DBHAEventMysqlSwitchSucc
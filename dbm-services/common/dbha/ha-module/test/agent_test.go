@@ -19,7 +19,7 @@ func TestAgentNetTransfor(t *testing.T) {
 		return
 	}
 	ch := make(chan gm.DoubleCheckInstanceInfo, 0)
-	gdm := gm.NewGDM(GlobalConfig, ch, nil)
+	gdm := gm.NewGDM(GlobalConfig, ch, nil, gm.NewGMLease(GlobalConfig, nil))
 	go func() {
 		gdm.Run()
 	}()
@@ -0,0 +1,38 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+ * Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at https://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+ * an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package model
+
+import "time"
+
+// HAGMLease struct for ha_gm_lease table
+// gm in the same city compete for one lease, only the holder do gqa/gcm
+type HAGMLease struct {
+	Uid          uint       `gorm:"column:uid;type:bigint;primary_key;AUTO_INCREMENT" json:"uid,omitempty"`
+	CityID       int        `gorm:"column:city_id;type:int(11);uniqueIndex:uniq_city_cloud;NOT NULL;default:0" json:"city_id,omitempty"`
+	CloudID      int        `gorm:"column:cloud_id;type:int(11);uniqueIndex:uniq_city_cloud;NOT NULL;default:0" json:"cloud_id,omitempty"`
+	HolderIP     string     `gorm:"column:holder_ip;type:varchar(32);NOT NULL" json:"holder_ip,omitempty"`
+	HolderPort   int        `gorm:"column:holder_port;type:int(11)" json:"holder_port,omitempty"`
+	FencingToken int64      `gorm:"column:fencing_token;type:bigint;NOT NULL;default:0" json:"fencing_token,omitempty"`
+	LeaseTime    int        `gorm:"column:lease_time;type:int(11);NOT NULL;default:0" json:"lease_time,omitempty"`
+	AcquireTime  *time.Time `gorm:"column:acquire_time;type:datetime" json:"acquire_time,omitempty"`
+	RenewTime    *time.Time `gorm:"column:renew_time;type:datetime" json:"renew_time,omitempty"`
+	ExpireTime   *time.Time `gorm:"column:expire_time;type:datetime" json:"expire_time,omitempty"`
+}
+
+// TableName table name
+func (m *HAGMLease) TableName() string {
+	return "ha_gm_lease"
+}
+
+// IsExpired whether the lease expired at the given time
+func (m *HAGMLease) IsExpired(now time.Time) bool {
+	return m.ExpireTime == nil || !now.Before(*m.ExpireTime)
+}
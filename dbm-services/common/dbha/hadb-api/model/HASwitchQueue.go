@@ -14,6 +14,9 @@ import (
 	"time"
 )
 
+// SwitchStatusShadow switch queue record insert by shadow mode, never count into switch quota
+const SwitchStatusShadow = "shadow"
+
 // HASwitchQueue TODO
 type HASwitchQueue struct {
 	Uid                uint       `gorm:"column:uid;type:bigint;primary_key;AUTO_INCREMENT" json:"uid,omitempty"`
@@ -34,6 +37,7 @@ type HASwitchQueue struct {
 	IdcID              int        `gorm:"column:idc_id;type:int(11)" json:"idc_id,omitempty"`
 	CloudID            int        `gorm:"column:cloud_id;type:int(11);default:0" json:"cloud_id,omitempty"`
 	Cluster            string     `gorm:"column:cluster;type:varchar(64)" json:"cluster,omitempty"`
+	FencingToken       int64      `gorm:"column:fencing_token;type:bigint;default:0" json:"fencing_token,omitempty"`
 }
 
 // TableName TODO
@@ -101,7 +101,8 @@ func DoCreateDBIfNotExist() error {
 
 // DoAutoMigrate do gorm auto migrate
 func DoAutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(&HAAgentLogs{}, &HaGMLogs{}, &HaStatus{}, &HASwitchLogs{}, &HASwitchQueue{}, &HAShield{})
+	return db.AutoMigrate(&HAAgentLogs{}, &HaGMLogs{}, &HaStatus{}, &HASwitchLogs{}, &HASwitchQueue{}, &HAShield{},
+		&HAGMLease{})
 }
 
 // GenerateGormConfig generate GORM.config
@@ -0,0 +1,2 @@
+// Package gmlease gm leader lease, gm in the same city compete for one lease
+package gmlease
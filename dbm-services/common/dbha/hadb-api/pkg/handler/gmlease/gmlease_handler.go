@@ -0,0 +1,281 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+ * Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at https://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+ * an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package gmlease
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"dbm-services/common/dbha/hadb-api/log"
+	"dbm-services/common/dbha/hadb-api/model"
+	"dbm-services/common/dbha/hadb-api/pkg/api"
+
+	"github.com/valyala/fasthttp"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// AcquireLease acquire or renew gm lease
+	AcquireLease = "acquire_gm_lease"
+	// ReleaseLease release gm lease by holder
+	ReleaseLease = "release_gm_lease"
+	// GetLease query current gm lease
+	GetLease = "query_gm_lease"
+)
+
+// AcquireResult response for acquire lease
+type AcquireResult struct {
+	// Acquired whether the requester hold the lease after request
+	Acquired bool            `json:"acquired"`
+	Lease    model.HAGMLease `json:"lease"`
+	// RemainMs milliseconds before the lease expire, computed by hadb clock,
+	// gm compute local deadline by it rather than compare expire_time with local clock
+	RemainMs int64 `json:"remain_ms"`
+}
+
+// Handler dispatch gm lease api
+func Handler(ctx *fasthttp.RequestCtx) {
+	param := &api.RequestInfo{}
+	if err := json.Unmarshal(ctx.PostBody(), param); err != nil {
+		log.Logger.Errorf("parse request body failed:%s", err.Error())
+		api.SendResponse(ctx, api.ResponseInfo{
+			Data:    nil,
+			Code:    api.RespErr,
+			Message: err.Error(),
+		})
+		return
+	}
+	switch param.Name {
+	case AcquireLease:
+		AcquireGMLease(ctx, param.SetArgs)
+	case ReleaseLease:
+		ReleaseGMLease(ctx, param.QueryArgs)
+	case GetLease:
+		GetGMLease(ctx, param.QueryArgs)
+	default:
+		api.SendResponse(ctx, api.ResponseInfo{
+			Data:    nil,
+			Code:    api.RespErr,
+			Message: fmt.Sprintf("unknown api name[%s]", param.Name),
+		})
+	}
+}
+
+// AcquireGMLease acquire the lease of city, renew it if requester is the holder.
+// An expired lease is taken over by requester and the fencing token increase,
+// so the switch started by the old holder could be recognized.
+func AcquireGMLease(ctx *fasthttp.RequestCtx, setParam interface{}) {
+	var (
+		input    = &model.HAGMLease{}
+		result   = &AcquireResult{}
+		response = api.ResponseInfo{
+			Data:    nil,
+			Code:    api.RespOK,
+			Message: "",
+		}
+	)
+	defer func() { api.SendResponse(ctx, response) }()
+
+	if !ctx.IsPost() {
+		response.Code = api.RespErr
+		response.Message = "must be POST method"
+		return
+	}
+
+	if bytes, err := json.Marshal(setParam); err != nil {
+		log.Logger.Errorf("convert param failed:%s", err.Error())
+		response.Code = api.RespErr
+		response.Message = err.Error()
+		return
+	} else {
+		if err = json.Unmarshal(bytes, input); err != nil {
+			response.Code = api.RespErr
+			response.Message = err.Error()
+			return
+		}
+	}
+	if input.HolderIP == "" || input.LeaseTime <= 0 {
+		response.Code = api.RespErr
+		response.Message = "holder_ip and lease_time must be set"
+		return
+	}
+	log.Logger.Debugf("%+v", input)
+
+	err := model.HADB.Self.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		expire := now.Add(time.Duration(input.LeaseTime) * time.Second)
+		lease := model.HAGMLease{}
+		err := tx.Table(lease.TableName()).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("city_id = ? and cloud_id = ?", input.CityID, input.CloudID).
+			First(&lease).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			lease = model.HAGMLease{
+				CityID:       input.CityID,
+				CloudID:      input.CloudID,
+				HolderIP:     input.HolderIP,
+				HolderPort:   input.HolderPort,
+				FencingToken: 1,
+				LeaseTime:    input.LeaseTime,
+				AcquireTime:  &now,
+				RenewTime:    &now,
+				ExpireTime:   &expire,
+			}
+			if err = tx.Table(lease.TableName()).Create(&lease).Error; err != nil {
+				return err
+			}
+			result.Acquired = true
+			result.Lease = lease
+			result.RemainMs = expire.Sub(now).Milliseconds()
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		isHolder := lease.HolderIP == input.HolderIP && lease.HolderPort == input.HolderPort
+		switch {
+		case isHolder && !lease.IsExpired(now):
+			// renew, keep fencing token
+			lease.RenewTime = &now
+			lease.ExpireTime = &expire
+			lease.LeaseTime = input.LeaseTime
+		case lease.IsExpired(now):
+			// take over, even the old holder re-acquire need a new token
+			lease.HolderIP = input.HolderIP
+			lease.HolderPort = input.HolderPort
+			lease.FencingToken++
+			lease.LeaseTime = input.LeaseTime
+			lease.AcquireTime = &now
+			lease.RenewTime = &now
+			lease.ExpireTime = &expire
+		default:
+			result.Acquired = false
+			result.Lease = lease
+			result.RemainMs = lease.ExpireTime.Sub(now).Milliseconds()
+			return nil
+		}
+		if err = tx.Table(lease.TableName()).Where("uid = ?", lease.Uid).
+			Select("holder_ip", "holder_port", "fencing_token", "lease_time",
+				"acquire_time", "renew_time", "expire_time").
+			Updates(&lease).Error; err != nil {
+			return err
+		}
+		result.Acquired = true
+		result.Lease = lease
+		result.RemainMs = expire.Sub(now).Milliseconds()
+		return nil
+	})
+	if err != nil {
+		response.Code = api.RespErr
+		response.Message = err.Error()
+		log.Logger.Errorf("acquire gm lease failed:%s", err.Error())
+		return
+	}
+	response.Data = result
+	log.Logger.Debugf("%+v", result)
+}
+
+// ReleaseGMLease release the lease, only the holder with the same fencing token could release
+func ReleaseGMLease(ctx *fasthttp.RequestCtx, param interface{}) {
+	var (
+		result    = map[string]int64{}
+		whereCond = &model.HAGMLease{}
+		response  = api.ResponseInfo{
+			Data:    &result,
+			Code:    api.RespOK,
+			Message: "",
+		}
+	)
+	defer func() { api.SendResponse(ctx, response) }()
+
+	if !ctx.IsPost() {
+		response.Code = api.RespErr
+		response.Message = "must be POST method"
+		return
+	}
+
+	if bytes, err := json.Marshal(param); err != nil {
+		log.Logger.Errorf("convert param failed:%s", err.Error())
+		response.Code = api.RespErr
+		response.Message = err.Error()
+		return
+	} else {
+		if err = json.Unmarshal(bytes, whereCond); err != nil {
+			response.Code = api.RespErr
+			response.Message = err.Error()
+			return
+		}
+	}
+	log.Logger.Debugf("%+v", whereCond)
+
+	now := time.Now()
+	db := model.HADB.Self.Table(whereCond.TableName()).
+		Where("city_id = ? and cloud_id = ?", whereCond.CityID, whereCond.CloudID).
+		Where("holder_ip = ? and holder_port = ? and fencing_token = ?",
+			whereCond.HolderIP, whereCond.HolderPort, whereCond.FencingToken).
+		Update("expire_time", &now)
+	if err := db.Error; err != nil {
+		response.Code = api.RespErr
+		response.Message = err.Error()
+		response.Data = nil
+		log.Logger.Errorf("update table failed:%s", err.Error())
+		return
+	}
+	result[api.RowsAffect] = db.RowsAffected
+}
+
+// GetGMLease query the lease of city, return nil if no gm ever acquire
+func GetGMLease(ctx *fasthttp.RequestCtx, param interface{}) {
+	var (
+		result    = []model.HAGMLease{}
+		whereCond = &model.HAGMLease{}
+		response  = api.ResponseInfo{
+			Data:    nil,
+			Code:    api.RespOK,
+			Message: "",
+		}
+	)
+	defer func() { api.SendResponse(ctx, response) }()
+
+	if !ctx.IsPost() {
+		response.Code = api.RespErr
+		response.Message = "must be POST method"
+		return
+	}
+
+	if bytes, err := json.Marshal(param); err != nil {
+		log.Logger.Errorf("convert param failed:%s", err.Error())
+		response.Code = api.RespErr
+		response.Message = err.Error()
+		return
+	} else {
+		if err = json.Unmarshal(bytes, whereCond); err != nil {
+			response.Code = api.RespErr
+			response.Message = err.Error()
+			return
+		}
+	}
+	log.Logger.Debugf("%+v", whereCond)
+
+	if err := model.HADB.Self.Table(whereCond.TableName()).
+		Where("city_id = ? and cloud_id = ?", whereCond.CityID, whereCond.CloudID).
+		Find(&result).Error; err != nil {
+		response.Code = api.RespErr
+		response.Message = err.Error()
+		log.Logger.Errorf("query table failed:%s", err.Error())
+		return
+	}
+	if len(result) > 0 {
+		response.Data = result[0]
+	}
+}
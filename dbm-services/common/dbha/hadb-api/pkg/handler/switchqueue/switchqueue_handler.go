@@ -19,6 +19,8 @@ const (
 	GetIpTotalSwitch = "query_interval_total"
 	// GetIdcTotalSwitch query single idc switch total
 	GetIdcTotalSwitch = "query_single_idc"
+	// GetInsShadowTotal query single ins shadow switch total
+	GetInsShadowTotal = "query_shadow_total"
 	// UpdateQueue TODO
 	UpdateQueue = "update_switch_queue"
 	// PutQueue TODO
@@ -49,6 +51,7 @@ type TbMonSwitchQueueApi struct {
 	IdcID              int    `json:"idc_id"`
 	CloudID            int    `json:"cloud_id"`
 	Cluster            string `json:"cluster"`
+	FencingToken       int64  `json:"fencing_token"`
 }
 
 // Handler TODO
@@ -70,6 +73,8 @@ func Handler(ctx *fasthttp.RequestCtx) {
 		GetSingleIpTotal(ctx, param.QueryArgs)
 	case GetIdcTotalSwitch:
 		GetSingleIdcTotal(ctx, param.QueryArgs)
+	case GetInsShadowTotal:
+		GetSingleInsShadowTotal(ctx, param.QueryArgs)
 	case UpdateQueue:
 		UpdateSwitchQueue(ctx, param.QueryArgs, param.SetArgs)
 	case PutQueue:
@@ -256,6 +261,7 @@ func GetSingleInsTotal(ctx *fasthttp.RequestCtx, param interface{}) {
 
 	if err := model.HADB.Self.Table(whereCond.TableName()).
 		Where("confirm_check_time > ?", whereCond.ConfirmCheckTime).
+		Where("IFNULL(status, '') <> ?", model.SwitchStatusShadow).
 		Where("ip = ? and port = ?", whereCond.IP, whereCond.Port).
 		Count(&count).Error; err != nil {
 		response.Code = api.RespErr
@@ -266,6 +272,59 @@ func GetSingleInsTotal(ctx *fasthttp.RequestCtx, param interface{}) {
 	log.Logger.Debugf("%+v", count)
 }
 
+// GetSingleInsShadowTotal count single ins shadow switch, shadow mode use it to avoid handle
+// the same instance every gqa cycle
+func GetSingleInsShadowTotal(ctx *fasthttp.RequestCtx, param interface{}) {
+	var (
+		count  int64
+		result = map[string]*int64{
+			"count": &count,
+		}
+		whereCond = &model.HASwitchQueue{}
+		response  = api.ResponseInfo{
+			Data:    &result,
+			Code:    api.RespOK,
+			Message: "",
+		}
+	)
+	// NB:couldn't user api.SendResponse(ctx, response) directly, otherwise
+	// deepCopy response first
+	defer func() { api.SendResponse(ctx, response) }()
+
+	if !ctx.IsPost() {
+		response.Message = "must be POST request"
+		response.Code = api.RespErr
+		log.Logger.Errorf("must by post request, param:%+v", param)
+		return
+	}
+
+	if bytes, err := json.Marshal(param); err != nil {
+		log.Logger.Errorf("convert param failed:%s", err.Error())
+		response.Code = api.RespErr
+		response.Message = err.Error()
+		return
+	} else {
+		if err = json.Unmarshal(bytes, whereCond); err != nil {
+			response.Code = api.RespErr
+			response.Message = err.Error()
+			return
+		}
+	}
+	log.Logger.Debugf("%+v", whereCond)
+
+	if err := model.HADB.Self.Table(whereCond.TableName()).
+		Where("confirm_check_time > ?", whereCond.ConfirmCheckTime).
+		Where("ip = ? and port = ?", whereCond.IP, whereCond.Port).
+		Where("status = ?", model.SwitchStatusShadow).
+		Count(&count).Error; err != nil {
+		response.Code = api.RespErr
+		response.Message = err.Error()
+		response.Data = nil
+		log.Logger.Errorf("query table failed:%s", err.Error())
+	}
+	log.Logger.Debugf("%+v", count)
+}
+
 // GetSingleIpTotal TODO
 func GetSingleIpTotal(ctx *fasthttp.RequestCtx, param interface{}) {
 	var (
@@ -307,6 +366,7 @@ func GetSingleIpTotal(ctx *fasthttp.RequestCtx, param interface{}) {
 
 	if err := model.HADB.Self.Table(whereCond.TableName()).
 		Where("confirm_check_time > ?", whereCond.ConfirmCheckTime).
+		Where("IFNULL(status, '') <> ?", model.SwitchStatusShadow).
 		Distinct("ip").Count(&count).Error; err != nil {
 		response.Code = api.RespErr
 		response.Message = err.Error()
@@ -357,6 +417,7 @@ func GetSingleIdcTotal(ctx *fasthttp.RequestCtx, param interface{}) {
 
 	if err := model.HADB.Self.Table(whereCond.TableName()).
 		Where("confirm_check_time > ?", whereCond.ConfirmCheckTime).
+		Where("IFNULL(status, '') <> ?", model.SwitchStatusShadow).
 		Where("idc_id = ? and ip <> ?", whereCond.IdcID, whereCond.IP).
 		Distinct("ip").Count(&count).Error; err != nil {
 		response.Code = api.RespErr
@@ -517,6 +578,7 @@ func TransSwitchQueueToApi(result []model.HASwitchQueue) []TbMonSwitchQueueApi {
 			IdcID:              switchQueue.IdcID,
 			CloudID:            switchQueue.CloudID,
 			Cluster:            switchQueue.Cluster,
+			FencingToken:       switchQueue.FencingToken,
 		}
 
 		ApiResults = append(ApiResults, switchQueueApi)
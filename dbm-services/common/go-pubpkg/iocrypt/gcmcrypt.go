@@ -0,0 +1,456 @@
+package iocrypt
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/binary"
+	"io"
+	"os/exec"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/scrypt"
+)
+
+// 原生分块加密格式，不依赖外部命令
+//
+//	header: magic(8) | keyMode(1) | chunkSize(4) | noncePrefix(8) | keyMaterialLen(2) | keyMaterial
+//	chunk:  AES-256-GCM(plain chunk), 除最后一块外明文都是 chunkSize 字节
+//
+// keyMaterial 在 passphrase 模式下是 scrypt salt，在 rsa 模式下是用公钥加密后的数据密钥
+// 每一块的 nonce = noncePrefix | chunkIndex, AAD = header | chunkIndex | isLast，
+// 所以块的顺序被调换、被截断、header 被修改都能在解密时发现
+
+const (
+	// NativeCryptCmd 使用原生加密时 EncryptCmd 填这个值
+	NativeCryptCmd = "native"
+	// AlgoAES256GCM native algorithm
+	AlgoAES256GCM AlgoType = "aes-256-gcm"
+
+	// DefaultChunkSize 默认每块明文大小
+	DefaultChunkSize = 1024 * 1024
+
+	gcmMagic      = "DBMGCM01"
+	gcmKeyLen     = 32
+	gcmSaltLen    = 16
+	gcmPrefixLen  = 8
+	gcmHeaderBase = len(gcmMagic) + 1 + 4 + gcmPrefixLen + 2
+	maxChunkSize  = 64 * 1024 * 1024
+)
+
+// key mode
+const (
+	keyModePassphrase byte = 1
+	keyModeRSA        byte = 2
+)
+
+// GcmCrypt 原生 AES-256-GCM 分块加密工具
+// Passphrase 和 PublicKey 二选一，PublicKey 优先
+type GcmCrypt struct {
+	Passphrase string
+	PublicKey  *rsa.PublicKey
+	ChunkSize  int
+}
+
+// BuildCommand 原生加密没有外部命令
+func (e GcmCrypt) BuildCommand(ctx context.Context) (*exec.Cmd, error) {
+	return nil, errors.New("native encrypt tool has no command, use NewEncryptWriter")
+}
+
+// DefaultSuffix return suffix
+func (e GcmCrypt) DefaultSuffix() string {
+	return "gcm"
+}
+
+// Name return name
+func (e GcmCrypt) Name() string {
+	return NativeCryptCmd
+}
+
+// NewEncryptWriter 实现 StreamEncryptTool
+func (e GcmCrypt) NewEncryptWriter(w io.Writer) (io.WriteCloser, error) {
+	return NewGcmWriter(w, e)
+}
+
+// GcmKey 解密用的密钥, Passphrase 和 PrivateKey 按 header 中的模式使用
+type GcmKey struct {
+	Passphrase string
+	PrivateKey *rsa.PrivateKey
+}
+
+type gcmHeader struct {
+	keyMode     byte
+	chunkSize   int
+	noncePrefix []byte
+	keyMaterial []byte
+	raw         []byte
+}
+
+func (h *gcmHeader) marshal() []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, gcmHeaderBase+len(h.keyMaterial)))
+	buf.WriteString(gcmMagic)
+	buf.WriteByte(h.keyMode)
+	_ = binary.Write(buf, binary.BigEndian, uint32(h.chunkSize))
+	buf.Write(h.noncePrefix)
+	_ = binary.Write(buf, binary.BigEndian, uint16(len(h.keyMaterial)))
+	buf.Write(h.keyMaterial)
+	h.raw = buf.Bytes()
+	return h.raw
+}
+
+func readGcmHeader(r io.Reader) (*gcmHeader, error) {
+	base := make([]byte, gcmHeaderBase)
+	if _, err := io.ReadFull(r, base); err != nil {
+		return nil, errors.Wrap(err, "read header")
+	}
+	if string(base[:len(gcmMagic)]) != gcmMagic {
+		return nil, errors.New("not a native encrypted file")
+	}
+	pos := len(gcmMagic)
+	h := &gcmHeader{keyMode: base[pos]}
+	pos++
+	h.chunkSize = int(binary.BigEndian.Uint32(base[pos:]))
+	pos += 4
+	h.noncePrefix = base[pos : pos+gcmPrefixLen]
+	pos += gcmPrefixLen
+	keyLen := int(binary.BigEndian.Uint16(base[pos:]))
+	if h.chunkSize <= 0 || h.chunkSize > maxChunkSize {
+		return nil, errors.Errorf("invalid chunk size %d", h.chunkSize)
+	}
+	h.keyMaterial = make([]byte, keyLen)
+	if _, err := io.ReadFull(r, h.keyMaterial); err != nil {
+		return nil, errors.Wrap(err, "read header key")
+	}
+	h.raw = append(base, h.keyMaterial...)
+	return h, nil
+}
+
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("no passphrase provide")
+	}
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, gcmKeyLen)
+}
+
+// dataKey 从 header 中恢复数据密钥
+func (h *gcmHeader) dataKey(key GcmKey) ([]byte, error) {
+	switch h.keyMode {
+	case keyModePassphrase:
+		return deriveKey(key.Passphrase, h.keyMaterial)
+	case keyModeRSA:
+		if key.PrivateKey == nil {
+			return nil, errors.New("file key is wrapped by rsa, need private key")
+		}
+		k, err := DecryptWithPrivateKey(h.keyMaterial, key.PrivateKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "unwrap data key")
+		}
+		if len(k) != gcmKeyLen {
+			return nil, errors.New("invalid data key length")
+		}
+		return k, nil
+	default:
+		return nil, errors.Errorf("unknown key mode %d", h.keyMode)
+	}
+}
+
+func (h *gcmHeader) nonce(idx uint64) []byte {
+	n := make([]byte, 12)
+	copy(n, h.noncePrefix)
+	binary.BigEndian.PutUint32(n[gcmPrefixLen:], uint32(idx))
+	return n
+}
+
+func (h *gcmHeader) aad(idx uint64, last bool) []byte {
+	a := make([]byte, 0, len(h.raw)+9)
+	a = append(a, h.raw...)
+	a = binary.BigEndian.AppendUint64(a, idx)
+	if last {
+		return append(a, 1)
+	}
+	return append(a, 0)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// GcmWriter 分块加密 writer，必须 Close 才会写入最后一块
+type GcmWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	header *gcmHeader
+	buf    []byte
+	idx    uint64
+	closed bool
+}
+
+// NewGcmWriter 写入 header 并返回加密 writer
+func NewGcmWriter(w io.Writer, opt GcmCrypt) (*GcmWriter, error) {
+	chunkSize := opt.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if chunkSize < 0 || chunkSize > maxChunkSize {
+		return nil, errors.Errorf("invalid chunk size %d", chunkSize)
+	}
+
+	h := &gcmHeader{chunkSize: chunkSize, noncePrefix: make([]byte, gcmPrefixLen)}
+	if _, err := io.ReadFull(rand.Reader, h.noncePrefix); err != nil {
+		return nil, err
+	}
+
+	var key []byte
+	var err error
+	if opt.PublicKey != nil {
+		h.keyMode = keyModeRSA
+		key = make([]byte, gcmKeyLen)
+		if _, err = io.ReadFull(rand.Reader, key); err != nil {
+			return nil, err
+		}
+		if h.keyMaterial, err = EncryptWithPublicKey(key, opt.PublicKey); err != nil {
+			return nil, errors.Wrap(err, "wrap data key")
+		}
+	} else {
+		h.keyMode = keyModePassphrase
+		h.keyMaterial = make([]byte, gcmSaltLen)
+		if _, err = io.ReadFull(rand.Reader, h.keyMaterial); err != nil {
+			return nil, err
+		}
+		if key, err = deriveKey(opt.Passphrase, h.keyMaterial); err != nil {
+			return nil, err
+		}
+	}
+
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(h.marshal()); err != nil {
+		return nil, errors.Wrap(err, "write header")
+	}
+	return &GcmWriter{w: w, aead: aead, header: h, buf: make([]byte, 0, chunkSize)}, nil
+}
+
+// Write implement io.Writer
+func (g *GcmWriter) Write(p []byte) (int, error) {
+	if g.closed {
+		return 0, errors.New("write to closed writer")
+	}
+	written := 0
+	for len(p) > 0 {
+		n := copy(g.buf[len(g.buf):cap(g.buf)], p)
+		g.buf = g.buf[:len(g.buf)+n]
+		p = p[n:]
+		written += n
+		// 缓冲满了也不能马上写，需要知道是不是最后一块
+		if len(g.buf) == cap(g.buf) && len(p) > 0 {
+			if err := g.flush(false); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+func (g *GcmWriter) flush(last bool) error {
+	if g.idx > 0xFFFFFFFF {
+		return errors.New("too many chunks")
+	}
+	sealed := g.aead.Seal(nil, g.header.nonce(g.idx), g.buf, g.header.aad(g.idx, last))
+	if _, err := g.w.Write(sealed); err != nil {
+		return errors.Wrap(err, "write chunk")
+	}
+	g.idx++
+	g.buf = g.buf[:0]
+	return nil
+}
+
+// Close 写入最后一块，不会关闭底层 writer
+func (g *GcmWriter) Close() error {
+	if g.closed {
+		return nil
+	}
+	g.closed = true
+	return g.flush(true)
+}
+
+// GcmReader 顺序解密
+type GcmReader struct {
+	r      io.Reader
+	aead   cipher.AEAD
+	header *gcmHeader
+	// 多读一块用来判断当前块是不是最后一块
+	next    []byte
+	nextN   int
+	plain   []byte
+	idx     uint64
+	lastErr error
+	done    bool
+}
+
+// NewGcmReader 读取 header 并返回解密 reader
+func NewGcmReader(r io.Reader, key GcmKey) (*GcmReader, error) {
+	h, err := readGcmHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	dk, err := h.dataKey(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(dk)
+	if err != nil {
+		return nil, err
+	}
+	g := &GcmReader{r: r, aead: aead, header: h, next: make([]byte, h.chunkSize+aead.Overhead())}
+	g.nextN, g.lastErr = io.ReadFull(r, g.next)
+	return g, nil
+}
+
+// Read implement io.Reader
+func (g *GcmReader) Read(p []byte) (int, error) {
+	for len(g.plain) == 0 {
+		if g.done {
+			return 0, io.EOF
+		}
+		if err := g.readChunk(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, g.plain)
+	g.plain = g.plain[n:]
+	return n, nil
+}
+
+func (g *GcmReader) readChunk() error {
+	var last bool
+	switch g.lastErr {
+	case nil:
+		// 满块，再预读一块判断是否最后一块
+	case io.ErrUnexpectedEOF:
+		last = true
+	case io.EOF:
+		return errors.New("encrypted file truncated")
+	default:
+		return g.lastErr
+	}
+
+	cur := make([]byte, g.nextN)
+	copy(cur, g.next[:g.nextN])
+	if !last {
+		g.nextN, g.lastErr = io.ReadFull(g.r, g.next)
+		last = g.lastErr == io.EOF
+	}
+
+	plain, err := g.aead.Open(cur[:0], g.header.nonce(g.idx), cur, g.header.aad(g.idx, last))
+	if err != nil {
+		return errors.Errorf("chunk %d authentication failed", g.idx)
+	}
+	g.idx++
+	g.plain = plain
+	g.done = last
+	return nil
+}
+
+// GcmReaderAt 随机读取，按块解密
+type GcmReaderAt struct {
+	r         io.ReaderAt
+	aead      cipher.AEAD
+	header    *gcmHeader
+	chunks    int64
+	plainSize int64
+}
+
+// NewGcmReaderAt size 为加密文件总大小
+func NewGcmReaderAt(r io.ReaderAt, size int64, key GcmKey) (*GcmReaderAt, error) {
+	h, err := readGcmHeader(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, err
+	}
+	dk, err := h.dataKey(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(dk)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &GcmReaderAt{r: r, aead: aead, header: h}
+	body := size - int64(len(h.raw))
+	sealedSize := int64(h.chunkSize + aead.Overhead())
+	g.chunks = (body + sealedSize - 1) / sealedSize
+	lastSealed := body - (g.chunks-1)*sealedSize
+	if g.chunks == 0 || lastSealed < int64(aead.Overhead()) {
+		return nil, errors.New("encrypted file truncated")
+	}
+	g.plainSize = (g.chunks-1)*int64(h.chunkSize) + lastSealed - int64(aead.Overhead())
+	return g, nil
+}
+
+// Size 明文大小
+func (g *GcmReaderAt) Size() int64 {
+	return g.plainSize
+}
+
+// ChunkSize 每块明文大小
+func (g *GcmReaderAt) ChunkSize() int {
+	return g.header.chunkSize
+}
+
+// Chunks 块数
+func (g *GcmReaderAt) Chunks() int64 {
+	return g.chunks
+}
+
+// ReadChunk 解密第 idx 块
+func (g *GcmReaderAt) ReadChunk(idx int64) ([]byte, error) {
+	if idx < 0 || idx >= g.chunks {
+		return nil, errors.Errorf("chunk %d out of range [0, %d)", idx, g.chunks)
+	}
+	sealedSize := int64(g.header.chunkSize + g.aead.Overhead())
+	off := int64(len(g.header.raw)) + idx*sealedSize
+	n := sealedSize
+	last := idx == g.chunks-1
+	if last {
+		n = g.plainSize - idx*int64(g.header.chunkSize) + int64(g.aead.Overhead())
+	}
+	buf := make([]byte, n)
+	if _, err := g.r.ReadAt(buf, off); err != nil && err != io.EOF {
+		return nil, err
+	}
+	plain, err := g.aead.Open(buf[:0], g.header.nonce(uint64(idx)), buf, g.header.aad(uint64(idx), last))
+	if err != nil {
+		return nil, errors.Errorf("chunk %d authentication failed", idx)
+	}
+	return plain, nil
+}
+
+// ReadAt implement io.ReaderAt on plain text
+func (g *GcmReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	read := 0
+	for read < len(p) {
+		pos := off + int64(read)
+		if pos >= g.plainSize {
+			return read, io.EOF
+		}
+		idx := pos / int64(g.header.chunkSize)
+		plain, err := g.ReadChunk(idx)
+		if err != nil {
+			return read, err
+		}
+		read += copy(p[read:], plain[pos-idx*int64(g.header.chunkSize):])
+	}
+	return read, nil
+}
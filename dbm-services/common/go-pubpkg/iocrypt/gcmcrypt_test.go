@@ -0,0 +1,107 @@
+package iocrypt_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"dbm-services/common/go-pubpkg/iocrypt"
+)
+
+func gcmEncrypt(t *testing.T, tool iocrypt.GcmCrypt, plain []byte) []byte {
+	var buf bytes.Buffer
+	w, err := iocrypt.FileEncryptWriter(tool, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Write(plain); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestGcmRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 1, 100, 128, 129, 1000} {
+		plain := make([]byte, size)
+		_, _ = rand.Read(plain)
+		enc := gcmEncrypt(t, iocrypt.GcmCrypt{Passphrase: "aaa", ChunkSize: 64}, plain)
+
+		r, err := iocrypt.NewGcmReader(bytes.NewReader(enc), iocrypt.GcmKey{Passphrase: "aaa"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("size %d: %s", size, err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Fatalf("size %d: plain text mismatch", size)
+		}
+	}
+}
+
+func TestGcmRSAReaderAt(t *testing.T) {
+	priv, pub, err := iocrypt.GenerateKeyPair(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := make([]byte, 1000)
+	_, _ = rand.Read(plain)
+	enc := gcmEncrypt(t, iocrypt.GcmCrypt{PublicKey: pub, ChunkSize: 64}, plain)
+
+	ra, err := iocrypt.NewGcmReaderAt(bytes.NewReader(enc), int64(len(enc)), iocrypt.GcmKey{PrivateKey: priv})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ra.Size() != int64(len(plain)) || ra.Chunks() != 16 {
+		t.Fatalf("size %d chunks %d", ra.Size(), ra.Chunks())
+	}
+	chunk, err := ra.ReadChunk(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(chunk, plain[192:256]) {
+		t.Fatal("chunk 3 mismatch")
+	}
+	part := make([]byte, 100)
+	if _, err = ra.ReadAt(part, 950); err != io.EOF {
+		t.Fatalf("expect EOF, got %v", err)
+	}
+	if !bytes.Equal(part[:50], plain[950:]) {
+		t.Fatal("tail mismatch")
+	}
+}
+
+func TestGcmTamper(t *testing.T) {
+	plain := make([]byte, 200)
+	enc := gcmEncrypt(t, iocrypt.GcmCrypt{Passphrase: "aaa", ChunkSize: 64}, plain)
+	key := iocrypt.GcmKey{Passphrase: "aaa"}
+
+	// 截断最后一块
+	truncated := enc[:len(enc)-(8+16)]
+	r, err := iocrypt.NewGcmReader(bytes.NewReader(truncated), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = io.ReadAll(r); err == nil {
+		t.Fatal("truncated file should fail")
+	}
+
+	// 修改内容
+	modified := bytes.Clone(enc)
+	modified[len(modified)-1] ^= 1
+	r, _ = iocrypt.NewGcmReader(bytes.NewReader(modified), key)
+	if _, err = io.ReadAll(r); err == nil {
+		t.Fatal("modified file should fail")
+	}
+
+	// 错误密码
+	r, _ = iocrypt.NewGcmReader(bytes.NewReader(enc), iocrypt.GcmKey{Passphrase: "bbb"})
+	if _, err = io.ReadAll(r); err == nil {
+		t.Fatal("wrong passphrase should fail")
+	}
+}
@@ -15,6 +15,12 @@ type EncryptTool interface {
 	Name() string
 }
 
+// StreamEncryptTool 不依赖外部命令的加密工具, 直接包装 writer
+type StreamEncryptTool interface {
+	EncryptTool
+	NewEncryptWriter(w io.Writer) (io.WriteCloser, error)
+}
+
 // AlgoType algorithm type
 type AlgoType string
 
@@ -23,6 +29,9 @@ func FileEncryptWriter(cryptTool EncryptTool, w io.Writer) (io.WriteCloser, erro
 	if cryptTool == nil {
 		return nil, errors.New("no crypt tool provide")
 	}
+	if st, ok := cryptTool.(StreamEncryptTool); ok {
+		return st.NewEncryptWriter(w)
+	}
 	xbw := &FileEncrypter{CryptTool: cryptTool}
 	if err := xbw.InitWriter(w); err != nil {
 		return nil, err
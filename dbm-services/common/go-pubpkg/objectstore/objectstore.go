@@ -0,0 +1,24 @@
+// Package objectstore 读写 s3 兼容的对象存储，不依赖 aws sdk
+package objectstore
+
+import (
+	"context"
+	"io"
+)
+
+// Uploader 对象存储上传接口
+type Uploader interface {
+	// PutObject 一次请求上传一个小对象，如 index 文件
+	PutObject(ctx context.Context, key string, body []byte) error
+	// NewWriter 流式上传一个对象，写入的数据按分块上传，Close 时完成上传
+	NewWriter(ctx context.Context, key string) (ObjectWriter, error)
+}
+
+// ObjectWriter 流式上传一个对象
+type ObjectWriter interface {
+	io.WriteCloser
+	// Abort 放弃上传，Close 成功之后调用不做任何事
+	Abort() error
+	// Size 已写入的字节数
+	Size() int64
+}
@@ -0,0 +1,377 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// MinPartSize s3 要求除最后一块外，每个分块不小于 5MB
+	MinPartSize = 5 * 1024 * 1024
+	// DefaultPartSizeMB 默认分块大小
+	DefaultPartSizeMB = 64
+	// MaxParts 一个对象最多的分块数
+	MaxParts = 10000
+	// DefaultTimeoutSec 默认单个请求的超时时间
+	DefaultTimeoutSec = 600
+
+	defaultRegion  = "us-east-1"
+	amzDateLayout  = "20060102T150405Z"
+	signAlgorithm  = "AWS4-HMAC-SHA256"
+	unsignedSHA256 = "UNSIGNED-PAYLOAD"
+)
+
+// S3Config s3 兼容存储配置
+type S3Config struct {
+	// Endpoint 如 http://127.0.0.1:9000，使用 path style 访问 bucket
+	Endpoint string `json:"endpoint"`
+	// Region 为空时使用 us-east-1
+	Region    string `json:"region"`
+	Bucket    string `json:"bucket"`
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+	// PartSizeMB 分块大小，不能小于 5
+	PartSizeMB int `json:"part_size_mb"`
+	// Retries 每个请求失败后的重试次数
+	Retries int `json:"retries"`
+	// TimeoutSec 单个请求的超时时间，包括上传一个分块，默认 600
+	TimeoutSec int `json:"timeout_sec"`
+}
+
+// S3Client s3 兼容存储客户端，只实现了上传、下载、列出和删除对象需要的接口，使用 aws signature v4 签名
+type S3Client struct {
+	S3Config
+	endpoint      *url.URL
+	partSize      int
+	timeout       time.Duration
+	retryInterval time.Duration
+	httpClient    *http.Client
+}
+
+// NewS3Client 检查配置，返回 client
+func NewS3Client(cfg S3Config) (*S3Client, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("s3 endpoint and bucket are required")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, errors.New("s3 access_key and secret_key are required")
+	}
+	u, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse endpoint %s", cfg.Endpoint)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.Errorf("endpoint need http:// or https:// : %s", cfg.Endpoint)
+	}
+	if cfg.Region == "" {
+		cfg.Region = defaultRegion
+	}
+	if cfg.PartSizeMB == 0 {
+		cfg.PartSizeMB = DefaultPartSizeMB
+	}
+	if cfg.PartSizeMB*1024*1024 < MinPartSize {
+		return nil, errors.Errorf("part size %dMB is less than 5MB", cfg.PartSizeMB)
+	}
+	if cfg.TimeoutSec <= 0 {
+		cfg.TimeoutSec = DefaultTimeoutSec
+	}
+	timeout := time.Duration(cfg.TimeoutSec) * time.Second
+	// http.Client.Timeout 会限制读取 body 的时间，GetObject 下载大对象时不能用
+	// 这里只限制连接和等待响应头的时间，请求整体的超时由 do 里的 context 控制
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = timeout
+	return &S3Client{
+		S3Config:      cfg,
+		endpoint:      u,
+		partSize:      cfg.PartSizeMB * 1024 * 1024,
+		timeout:       timeout,
+		retryInterval: time.Second,
+		httpClient:    &http.Client{Transport: transport},
+	}, nil
+}
+
+// s3Error s3 返回的错误
+type s3Error struct {
+	XMLName    xml.Name `xml:"Error"`
+	Code       string   `xml:"Code"`
+	Message    string   `xml:"Message"`
+	StatusCode int      `xml:"-"`
+}
+
+func (e *s3Error) Error() string {
+	return fmt.Sprintf("s3 error %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Part 已上传的分块
+type Part struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+	Size       int64  `xml:"Size"`
+}
+
+type initiateMultipartUploadResult struct {
+	UploadId string `xml:"UploadId"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name `xml:"CompleteMultipartUpload"`
+	Parts   []Part   `xml:"Part"`
+}
+
+// uriEncode 按 aws 规则编码，只保留 A-Za-z0-9-_.~，keepSlash 时保留 /
+func uriEncode(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (keepSlash && c == '/') {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// canonicalQuery 按 key 排序后编码
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			pairs = append(pairs, uriEncode(k, false)+"="+uriEncode(v, false))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// signingKey 派生签名 key
+func signingKey(secretKey, date, region, service string) []byte {
+	k := hmacSHA256([]byte("AWS4"+secretKey), date)
+	k = hmacSHA256(k, region)
+	k = hmacSHA256(k, service)
+	return hmacSHA256(k, "aws4_request")
+}
+
+// signV4 计算签名，返回 Authorization 头
+// canonical 请求只签 host x-amz-content-sha256 x-amz-date 三个头
+func signV4(accessKey, secretKey, region, method, encodedPath, query, host, payloadHash string,
+	now time.Time) string {
+	amzDate := now.UTC().Format(amzDateLayout)
+	date := amzDate[:8]
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		method,
+		encodedPath,
+		query,
+		"host:" + host + "\n" + "x-amz-content-sha256:" + payloadHash + "\n" + "x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := signAlgorithm + "\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+	signature := hex.EncodeToString(hmacSHA256(signingKey(secretKey, date, region, "s3"), stringToSign))
+	return fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		signAlgorithm, accessKey, scope, signedHeaders, signature)
+}
+
+// request 发送一个签名的请求，状态码不是 2xx 时解析 s3 错误
+// 成功时由调用方关闭 resp.Body
+func (c *S3Client) request(ctx context.Context, method string, key string, query url.Values,
+	body []byte) (*http.Response, error) {
+	encodedPath := "/" + uriEncode(c.Bucket, false)
+	if key != "" {
+		encodedPath += "/" + uriEncode(key, true)
+	}
+	rawQuery := canonicalQuery(query)
+	u := *c.endpoint
+	u.Path, _ = url.PathUnescape(encodedPath)
+	u.RawPath = encodedPath
+	u.RawQuery = rawQuery
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	payloadHash := unsignedSHA256
+	if body != nil {
+		payloadHash = sha256Hex(body)
+	}
+	now := time.Now()
+	req.ContentLength = int64(len(body))
+	req.Header.Set("x-amz-date", now.UTC().Format(amzDateLayout))
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	req.Header.Set("Authorization",
+		signV4(c.AccessKey, c.SecretKey, c.Region, method, encodedPath, rawQuery, u.Host, payloadHash, now))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, parseError(resp.StatusCode, respBody)
+	}
+	return resp, nil
+}
+
+// parseError 解析 s3 错误，HEAD 请求没有 body
+func parseError(statusCode int, body []byte) error {
+	e := &s3Error{StatusCode: statusCode}
+	if xmlErr := xml.Unmarshal(body, e); xmlErr != nil {
+		e.Message = string(body)
+	}
+	if e.Code == "" && statusCode == http.StatusNotFound {
+		e.Code = "NotFound"
+	}
+	return e
+}
+
+// do 发送一个签名的请求，返回 body
+// 每次请求(包括读取 body)不超过 TimeoutSec，避免网络卡住时一直等待
+func (c *S3Client) do(ctx context.Context, method string, key string, query url.Values,
+	body []byte) (http.Header, []byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	resp, err := c.request(ctx, method, key, query, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	// CompleteMultipartUpload 可能返回 200 但是 body 里是错误
+	if bytes.Contains(respBody, []byte("<Error>")) {
+		return nil, nil, parseError(resp.StatusCode, respBody)
+	}
+	return resp.Header, respBody, nil
+}
+
+// retry 失败后重试 Retries 次，4xx 错误(除了超时和限流)不重试
+func (c *S3Client) retry(ctx context.Context, f func() error) error {
+	var err error
+	for i := 0; i <= c.Retries; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.retryInterval * time.Duration(i)):
+			}
+		}
+		if err = f(); err == nil {
+			return nil
+		}
+		var e *s3Error
+		if errors.As(err, &e) && e.StatusCode >= 400 && e.StatusCode < 500 &&
+			e.StatusCode != http.StatusRequestTimeout && e.StatusCode != http.StatusTooManyRequests {
+			return err
+		}
+	}
+	return err
+}
+
+// PutObject 一次请求上传一个对象
+func (c *S3Client) PutObject(ctx context.Context, key string, body []byte) error {
+	if body == nil {
+		body = []byte{}
+	}
+	return c.retry(ctx, func() error {
+		_, _, err := c.do(ctx, http.MethodPut, key, nil, body)
+		return errors.WithMessagef(err, "put object %s", key)
+	})
+}
+
+func (c *S3Client) createMultipartUpload(ctx context.Context, key string) (string, error) {
+	_, body, err := c.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return "", errors.WithMessagef(err, "create multipart upload %s", key)
+	}
+	var res initiateMultipartUploadResult
+	if err = xml.Unmarshal(body, &res); err != nil || res.UploadId == "" {
+		return "", errors.Errorf("create multipart upload %s: invalid response %s", key, string(body))
+	}
+	return res.UploadId, nil
+}
+
+func (c *S3Client) uploadPart(ctx context.Context, key, uploadId string, partNumber int, body []byte) (string, error) {
+	var etag string
+	err := c.retry(ctx, func() error {
+		query := url.Values{"partNumber": {strconv.Itoa(partNumber)}, "uploadId": {uploadId}}
+		header, _, err := c.do(ctx, http.MethodPut, key, query, body)
+		if err != nil {
+			return errors.WithMessagef(err, "upload part %d of %s", partNumber, key)
+		}
+		etag = header.Get("ETag")
+		return nil
+	})
+	return etag, err
+}
+
+func (c *S3Client) completeMultipartUpload(ctx context.Context, key, uploadId string, parts []Part) error {
+	// 请求里只需要 PartNumber ETag
+	reqParts := make([]Part, len(parts))
+	for i, p := range parts {
+		reqParts[i] = Part{PartNumber: p.PartNumber, ETag: p.ETag}
+	}
+	body, err := xml.Marshal(completeMultipartUpload{Parts: reqParts})
+	if err != nil {
+		return err
+	}
+	return c.retry(ctx, func() error {
+		_, _, err := c.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadId}}, body)
+		return errors.WithMessagef(err, "complete multipart upload %s", key)
+	})
+}
+
+func (c *S3Client) abortMultipartUpload(ctx context.Context, key, uploadId string) error {
+	_, _, err := c.do(ctx, http.MethodDelete, key, url.Values{"uploadId": {uploadId}}, nil)
+	return errors.WithMessagef(err, "abort multipart upload %s", key)
+}
+
+// NewWriter 开始一个分块上传
+// 每个分块失败时按 Retries 重试，整个对象失败后不支持续传，需要重新上传
+func (c *S3Client) NewWriter(ctx context.Context, key string) (ObjectWriter, error) {
+	w := &s3Writer{
+		ctx:    ctx,
+		client: c,
+		key:    key,
+		buf:    make([]byte, 0, c.partSize),
+	}
+	var err error
+	if w.uploadId, err = c.createMultipartUpload(ctx, key); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
@@ -0,0 +1,105 @@
+package objectstore
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ObjectInfo 对象元信息
+type ObjectInfo struct {
+	Key          string    `xml:"Key"`
+	Size         int64     `xml:"Size"`
+	ETag         string    `xml:"ETag"`
+	LastModified time.Time `xml:"LastModified"`
+}
+
+type listObjectsV2Result struct {
+	IsTruncated           bool         `xml:"IsTruncated"`
+	NextContinuationToken string       `xml:"NextContinuationToken"`
+	Contents              []ObjectInfo `xml:"Contents"`
+}
+
+// IsNotFound 对象或者 bucket 不存在
+func IsNotFound(err error) bool {
+	var e *s3Error
+	return errors.As(err, &e) && e.StatusCode == http.StatusNotFound
+}
+
+// HeadObject 查询对象元信息，对象不存在时 IsNotFound(err) 为 true
+func (c *S3Client) HeadObject(ctx context.Context, key string) (*ObjectInfo, error) {
+	var info *ObjectInfo
+	err := c.retry(ctx, func() error {
+		header, _, err := c.do(ctx, http.MethodHead, key, nil, nil)
+		if err != nil {
+			return errors.WithMessagef(err, "head object %s", key)
+		}
+		info = &ObjectInfo{Key: key, ETag: header.Get("ETag")}
+		info.Size, _ = strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+		info.LastModified, _ = http.ParseTime(header.Get("Last-Modified"))
+		return nil
+	})
+	return info, err
+}
+
+// GetObject 下载对象，调用方需要关闭返回的 ReadCloser
+// 只对建立请求重试，读取 body 出错需要调用方重新下载
+func (c *S3Client) GetObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	var body io.ReadCloser
+	err := c.retry(ctx, func() error {
+		resp, err := c.request(ctx, http.MethodGet, key, nil, nil)
+		if err != nil {
+			return errors.WithMessagef(err, "get object %s", key)
+		}
+		body = resp.Body
+		return nil
+	})
+	return body, err
+}
+
+// ListObjects 列出前缀为 prefix 的所有对象，按 key 排序
+func (c *S3Client) ListObjects(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		var body []byte
+		err := c.retry(ctx, func() error {
+			var err error
+			_, body, err = c.do(ctx, http.MethodGet, "", query, nil)
+			return errors.WithMessagef(err, "list objects %s", prefix)
+		})
+		if err != nil {
+			return nil, err
+		}
+		var res listObjectsV2Result
+		if err = xml.Unmarshal(body, &res); err != nil {
+			return nil, errors.Wrapf(err, "list objects %s", prefix)
+		}
+		objects = append(objects, res.Contents...)
+		if !res.IsTruncated || res.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = res.NextContinuationToken
+	}
+}
+
+// DeleteObject 删除对象，对象不存在不报错
+func (c *S3Client) DeleteObject(ctx context.Context, key string) error {
+	return c.retry(ctx, func() error {
+		_, _, err := c.do(ctx, http.MethodDelete, key, nil, nil)
+		if IsNotFound(err) {
+			return nil
+		}
+		return errors.WithMessagef(err, "delete object %s", key)
+	})
+}
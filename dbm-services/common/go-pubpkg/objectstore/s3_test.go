@@ -0,0 +1,401 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 内存里的 s3 兼容服务，只实现上传用到的接口，并校验签名
+type fakeS3 struct {
+	t         *testing.T
+	mu        sync.Mutex
+	objects   map[string][]byte
+	uploads   map[string]*fakeUpload
+	seq       int
+	partPuts  int
+	failParts map[int]int   // partNumber: 还要失败几次
+	pageSize  int           // ListObjectsV2 每页对象数
+	delay     time.Duration // 处理每个请求前等待，模拟网络卡住
+}
+
+type fakeUpload struct {
+	key   string
+	parts map[int][]byte
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{t: t, objects: map[string][]byte{}, uploads: map[string]*fakeUpload{}, failParts: map[int]int{},
+		pageSize: 1000}
+	return f, httptest.NewServer(f)
+}
+
+func etagOf(b []byte) string {
+	sum := md5.Sum(b)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f.delay > 0 {
+		time.Sleep(f.delay)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now, err := time.Parse(amzDateLayout, r.Header.Get("x-amz-date"))
+	if err != nil {
+		writeError(w, http.StatusForbidden, "AccessDenied")
+		return
+	}
+	expect := signV4("ak", "sk", defaultRegion, r.Method, r.URL.EscapedPath(), r.URL.RawQuery, r.Host,
+		r.Header.Get("x-amz-content-sha256"), now)
+	if r.Header.Get("Authorization") != expect {
+		f.t.Errorf("signature mismatch for %s %s", r.Method, r.URL.String())
+		writeError(w, http.StatusForbidden, "SignatureDoesNotMatch")
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	path := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	key := ""
+	if len(path) == 2 {
+		key = path[1]
+	}
+	q := r.URL.Query()
+	uploadId := q.Get("uploadId")
+	_, hasUploads := q["uploads"]
+
+	switch {
+	case r.Method == http.MethodPost && hasUploads:
+		f.seq++
+		id := fmt.Sprintf("upload-%d", f.seq)
+		f.uploads[id] = &fakeUpload{key: key, parts: map[int][]byte{}}
+		_, _ = fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", id)
+	case r.Method == http.MethodPut && uploadId != "":
+		u, ok := f.uploads[uploadId]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		n, _ := strconv.Atoi(q.Get("partNumber"))
+		f.partPuts++
+		if f.failParts[n] > 0 {
+			f.failParts[n]--
+			writeError(w, http.StatusInternalServerError, "InternalError")
+			return
+		}
+		u.parts[n] = body
+		w.Header().Set("ETag", etagOf(body))
+	case r.Method == http.MethodPost && uploadId != "":
+		u, ok := f.uploads[uploadId]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		var req completeMultipartUpload
+		if err := xml.Unmarshal(body, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "MalformedXML")
+			return
+		}
+		var obj []byte
+		for i, p := range req.Parts {
+			b, ok := u.parts[p.PartNumber]
+			if !ok || p.PartNumber != i+1 || etagOf(b) != p.ETag {
+				writeError(w, http.StatusBadRequest, "InvalidPart")
+				return
+			}
+			obj = append(obj, b...)
+		}
+		f.objects[u.key] = obj
+		delete(f.uploads, uploadId)
+		_, _ = fmt.Fprint(w, "<CompleteMultipartUploadResult></CompleteMultipartUploadResult>")
+	case r.Method == http.MethodDelete && uploadId != "":
+		delete(f.uploads, uploadId)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && key == "" && q.Get("list-type") == "2":
+		var keys []string
+		for k := range f.objects {
+			if strings.HasPrefix(k, q.Get("prefix")) && k > q.Get("continuation-token") {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		res := struct {
+			XMLName               xml.Name `xml:"ListBucketResult"`
+			IsTruncated           bool
+			NextContinuationToken string       `xml:",omitempty"`
+			Contents              []ObjectInfo `xml:"Contents"`
+		}{}
+		if len(keys) > f.pageSize {
+			keys = keys[:f.pageSize]
+			res.IsTruncated = true
+			res.NextContinuationToken = keys[len(keys)-1]
+		}
+		for _, k := range keys {
+			res.Contents = append(res.Contents, ObjectInfo{Key: k, Size: int64(len(f.objects[k])),
+				ETag: etagOf(f.objects[k]), LastModified: time.Now().UTC()})
+		}
+		_ = xml.NewEncoder(w).Encode(res)
+	case (r.Method == http.MethodGet || r.Method == http.MethodHead) && key != "":
+		obj, ok := f.objects[key]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("ETag", etagOf(obj))
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj)))
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(obj)
+		}
+	case r.Method == http.MethodDelete && key != "":
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		f.objects[key] = body
+		w.Header().Set("ETag", etagOf(body))
+	default:
+		writeError(w, http.StatusBadRequest, "NotImplemented")
+	}
+}
+
+func newTestClient(t *testing.T, endpoint string) *S3Client {
+	c, err := NewS3Client(S3Config{Endpoint: endpoint, Bucket: "backup", AccessKey: "ak", SecretKey: "sk",
+		Retries: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.partSize = 1024
+	c.retryInterval = time.Millisecond
+	return c
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return b
+}
+
+// writeChunks 每次写 700 字节，让写入和分块边界不对齐
+func writeChunks(t *testing.T, w io.Writer, data []byte) {
+	for i := 0; i < len(data); i += 700 {
+		end := i + 700
+		if end > len(data) {
+			end = len(data)
+		}
+		if _, err := w.Write(data[i:end]); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSigningKey(t *testing.T) {
+	// aws 文档里的示例
+	key := signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	expect := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if hex.EncodeToString(key) != expect {
+		t.Fatalf("signing key expect %s, got %x", expect, key)
+	}
+}
+
+func TestMultipartUpload(t *testing.T) {
+	f, srv := newFakeS3(t)
+	defer srv.Close()
+	c := newTestClient(t, srv.URL)
+
+	for _, size := range []int{0, 100, 1024, 3000} {
+		key := fmt.Sprintf("dir/a b+c_%d.tar", size)
+		data := randomBytes(size)
+		w, err := c.NewWriter(context.Background(), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeChunks(t, w, data)
+		if err = w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if w.Size() != int64(size) {
+			t.Fatalf("size expect %d, got %d", size, w.Size())
+		}
+		if !bytes.Equal(f.objects[key], data) {
+			t.Fatalf("object %s content mismatch", key)
+		}
+	}
+	if len(f.uploads) != 0 {
+		t.Fatalf("expect no pending uploads, got %d", len(f.uploads))
+	}
+}
+
+func TestUploadPartRetry(t *testing.T) {
+	f, srv := newFakeS3(t)
+	defer srv.Close()
+	c := newTestClient(t, srv.URL)
+	f.failParts[2] = 2
+
+	data := randomBytes(2500)
+	w, err := c.NewWriter(context.Background(), "retry.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeChunks(t, w, data)
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(f.objects["retry.tar"], data) {
+		t.Fatal("object content mismatch")
+	}
+
+	// 超过重试次数，上传失败并且放弃上传
+	f.failParts[1] = 3
+	w, err = c.NewWriter(context.Background(), "fail.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write(data)
+	if err = w.Close(); err == nil {
+		t.Fatal("expect upload failed")
+	}
+	if _, ok := f.objects["fail.tar"]; ok || len(f.uploads) != 0 {
+		t.Fatal("failed upload should be aborted")
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	f, srv := newFakeS3(t)
+	defer srv.Close()
+	c := newTestClient(t, srv.URL)
+	c.timeout = 50 * time.Millisecond
+	f.delay = 300 * time.Millisecond
+
+	start := time.Now()
+	err := c.PutObject(context.Background(), "slow.index", []byte("index"))
+	if err == nil || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect deadline exceeded, got %v", err)
+	}
+	// 3 次请求都应该在超时后立刻返回，不等服务端处理完
+	if cost := time.Since(start); cost > 3*f.delay {
+		t.Fatalf("request should time out, cost %s", cost)
+	}
+}
+
+func TestPutObjectAndAbort(t *testing.T) {
+	f, srv := newFakeS3(t)
+	defer srv.Close()
+	c := newTestClient(t, srv.URL)
+
+	if err := c.PutObject(context.Background(), "x.index", []byte(`{"a":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if string(f.objects["x.index"]) != `{"a":1}` {
+		t.Fatal("object content mismatch")
+	}
+
+	w, err := c.NewWriter(context.Background(), "abort.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write(randomBytes(2000))
+	if err = w.Abort(); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err == nil {
+		t.Fatal("expect error when closing aborted writer")
+	}
+	if _, ok := f.objects["abort.tar"]; ok || len(f.uploads) != 0 {
+		t.Fatal("aborted upload should be removed")
+	}
+}
+
+func TestObjectOperations(t *testing.T) {
+	f, srv := newFakeS3(t)
+	defer srv.Close()
+	c := newTestClient(t, srv.URL)
+	f.pageSize = 2
+	ctx := context.Background()
+
+	data := randomBytes(1500)
+	for _, key := range []string{"p/a.1", "p/a.2", "p/a b.3", "q/a.1"} {
+		if err := c.PutObject(ctx, key, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	objects, err := c.ListObjects(ctx, "p/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, o := range objects {
+		keys = append(keys, o.Key)
+		if o.Size != int64(len(data)) {
+			t.Fatalf("object %s size expect %d, got %d", o.Key, len(data), o.Size)
+		}
+	}
+	if strings.Join(keys, ",") != "p/a b.3,p/a.1,p/a.2" {
+		t.Fatalf("list objects got %v", keys)
+	}
+
+	info, err := c.HeadObject(ctx, "p/a b.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size != int64(len(data)) || info.ETag != etagOf(data) {
+		t.Fatalf("head object got %+v", info)
+	}
+	if _, err = c.HeadObject(ctx, "p/none"); !IsNotFound(err) {
+		t.Fatalf("expect not found, got %v", err)
+	}
+
+	r, err := c.GetObject(ctx, "p/a b.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	_ = r.Close()
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("get object content mismatch: %v", err)
+	}
+	if _, err = c.GetObject(ctx, "p/none"); !IsNotFound(err) {
+		t.Fatalf("expect not found, got %v", err)
+	}
+
+	if err = c.DeleteObject(ctx, "p/a.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.DeleteObject(ctx, "p/a.1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.objects["p/a.1"]; ok {
+		t.Fatal("object should be deleted")
+	}
+}
+
+func TestNewS3ClientCheck(t *testing.T) {
+	cases := []S3Config{
+		{Bucket: "b", AccessKey: "a", SecretKey: "s"},
+		{Endpoint: "127.0.0.1:9000", Bucket: "b", AccessKey: "a", SecretKey: "s"},
+		{Endpoint: "http://127.0.0.1:9000", Bucket: "b"},
+		{Endpoint: "http://127.0.0.1:9000", Bucket: "b", AccessKey: "a", SecretKey: "s", PartSizeMB: 1},
+	}
+	for i, cfg := range cases {
+		if _, err := NewS3Client(cfg); err == nil {
+			t.Errorf("case %d: expect error", i)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package objectstore
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// s3Writer 写满一个分块就上传，Close 时上传最后一块并完成上传
+type s3Writer struct {
+	ctx       context.Context
+	client    *S3Client
+	key       string
+	uploadId  string
+	buf       []byte
+	parts     []Part
+	size      int64
+	closed    bool
+	completed bool
+	err       error
+}
+
+// Write 写入数据，写满分块时同步上传
+func (w *s3Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.Errorf("write to closed object writer %s", w.key)
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	written := 0
+	for len(p) > 0 {
+		n := w.client.partSize - len(w.buf)
+		if n > len(p) {
+			n = len(p)
+		}
+		w.buf = append(w.buf, p[:n]...)
+		p = p[n:]
+		written += n
+		w.size += int64(n)
+		if len(w.buf) == w.client.partSize {
+			if w.err = w.flush(); w.err != nil {
+				return written, w.err
+			}
+		}
+	}
+	return written, nil
+}
+
+// flush 上传 buf 作为下一个分块
+func (w *s3Writer) flush() error {
+	partNumber := len(w.parts) + 1
+	if partNumber > MaxParts {
+		return errors.Errorf("object %s has more than %d parts, increase part size", w.key, MaxParts)
+	}
+	etag, err := w.client.uploadPart(w.ctx, w.key, w.uploadId, partNumber, w.buf)
+	if err != nil {
+		return err
+	}
+	w.parts = append(w.parts, Part{PartNumber: partNumber, ETag: etag, Size: int64(len(w.buf))})
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// Close 上传剩余数据并完成上传，出错时放弃上传
+func (w *s3Writer) Close() error {
+	if w.closed {
+		return w.err
+	}
+	w.closed = true
+	if w.err == nil && (len(w.buf) > 0 || len(w.parts) == 0) {
+		w.err = w.flush()
+	}
+	if w.err == nil {
+		w.err = w.client.completeMultipartUpload(w.ctx, w.key, w.uploadId, w.parts)
+	}
+	if w.err != nil {
+		_ = w.client.abortMultipartUpload(w.ctx, w.key, w.uploadId)
+		return w.err
+	}
+	w.completed = true
+	return nil
+}
+
+// Abort 放弃上传，删除已上传的分块
+func (w *s3Writer) Abort() error {
+	if w.completed {
+		return nil
+	}
+	w.closed = true
+	if w.err == nil {
+		w.err = errors.Errorf("upload of %s aborted", w.key)
+	}
+	return w.client.abortMultipartUpload(w.ctx, w.key, w.uploadId)
+}
+
+// Size 已写入的字节数
+func (w *s3Writer) Size() int64 {
+	return w.size
+}
@@ -0,0 +1,226 @@
+// Package promexporter 以 prometheus 文本格式暴露监控项的指标, 事件通过 json 接口查询
+// 给 mysql-monitor, riak-monitor 这类原本只能推送到 bkmonitorbeat 的工具使用
+package promexporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxEvents 内存中保留的事件数
+	DefaultMaxEvents = 1000
+	// EventMetricName 事件计数指标, 带 name 标签
+	EventMetricName = "monitor_event_total"
+	// MetricsPath 指标接口
+	MetricsPath = "/metrics"
+	// EventsPath 事件接口
+	EventsPath = "/events"
+)
+
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+
+// Event 监控事件
+type Event struct {
+	Name   string            `json:"name"`
+	Msg    string            `json:"msg"`
+	Labels map[string]string `json:"labels"`
+	Time   time.Time         `json:"time"`
+}
+
+type sample struct {
+	labels map[string]string
+	value  float64
+}
+
+type metricFamily struct {
+	metricType string
+	samples    map[string]*sample
+}
+
+// Registry 保存最新的指标值和最近的事件, 并发安全
+type Registry struct {
+	mu        sync.RWMutex
+	metrics   map[string]*metricFamily
+	events    []*Event
+	maxEvents int
+}
+
+// NewRegistry maxEvents <= 0 时使用 DefaultMaxEvents
+func NewRegistry(maxEvents int) *Registry {
+	if maxEvents <= 0 {
+		maxEvents = DefaultMaxEvents
+	}
+	return &Registry{
+		metrics:   make(map[string]*metricFamily),
+		maxEvents: maxEvents,
+	}
+}
+
+// SetGauge 设置指标当前值
+func (r *Registry) SetGauge(name string, value float64, labels map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sample(name, "gauge", labels).value = value
+}
+
+// AddEvent 记录事件, 同时累加 monitor_event_total
+func (r *Registry) AddEvent(name string, msg string, labels map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, &Event{Name: name, Msg: msg, Labels: labels, Time: time.Now()})
+	if len(r.events) > r.maxEvents {
+		r.events = slices.Clone(r.events[len(r.events)-r.maxEvents:])
+	}
+
+	counterLabels := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		counterLabels[k] = v
+	}
+	counterLabels["name"] = name
+	r.sample(EventMetricName, "counter", counterLabels).value++
+}
+
+func (r *Registry) sample(name string, metricType string, labels map[string]string) *sample {
+	name = sanitizeName(name)
+	family, ok := r.metrics[name]
+	if !ok {
+		family = &metricFamily{metricType: metricType, samples: make(map[string]*sample)}
+		r.metrics[name] = family
+	}
+
+	sanitized := make(map[string]string, len(labels))
+	for k, v := range labels {
+		sanitized[sanitizeName(k)] = v
+	}
+	key := formatLabels(sanitized)
+	s, ok := family.samples[key]
+	if !ok {
+		s = &sample{labels: sanitized}
+		family.samples[key] = s
+	}
+	return s
+}
+
+// Events 按时间顺序返回事件, name 为空返回全部
+func (r *Registry) Events(name string, since time.Time) (events []*Event) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, e := range r.events {
+		if (name == "" || e.Name == name) && !e.Time.Before(since) {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// WriteMetrics 输出 prometheus 文本格式
+func (r *Registry) WriteMetrics(w io.Writer) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		family := r.metrics[name]
+		if _, err := fmt.Fprintf(w, "# TYPE %s %s\n", name, family.metricType); err != nil {
+			return err
+		}
+
+		keys := make([]string, 0, len(family.samples))
+		for k := range family.samples {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			s := family.samples[k]
+			// 不带时间戳, 低频的监控项带旧时间戳会被 prometheus 丢弃
+			_, err := fmt.Fprintf(w, "%s%s %s\n", name, k, strconv.FormatFloat(s.value, 'g', -1, 64))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Handler 提供 /metrics 和 /events
+// /events 支持 name 和 since(RFC3339) 参数
+func (r *Registry) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(MetricsPath, func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.WriteMetrics(w)
+	})
+	mux.HandleFunc(EventsPath, func(w http.ResponseWriter, req *http.Request) {
+		var since time.Time
+		if s := req.URL.Query().Get("since"); s != "" {
+			var err error
+			since, err = time.Parse(time.RFC3339, s)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid since: %s", err.Error()), http.StatusBadRequest)
+				return
+			}
+		}
+		events := r.Events(req.URL.Query().Get("name"), since)
+		if events == nil {
+			events = []*Event{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(events)
+	})
+	return mux
+}
+
+// LabelsFromDimension 把上报 bkmonitorbeat 用的维度转换成标签
+func LabelsFromDimension(dimension map[string]interface{}) map[string]string {
+	labels := make(map[string]string, len(dimension))
+	for k, v := range dimension {
+		labels[k] = fmt.Sprintf("%v", v)
+	}
+	return labels
+}
+
+func sanitizeName(name string) string {
+	name = invalidNameChars.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	var parts []string
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, k, escapeLabelValue(labels[k])))
+	}
+	return fmt.Sprintf("{%s}", strings.Join(parts, ","))
+}
+
+func escapeLabelValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(v)
+}
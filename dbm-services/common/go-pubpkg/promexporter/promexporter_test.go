@@ -0,0 +1,83 @@
+package promexporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	r := NewRegistry(0)
+	r.SetGauge("mysql-monitor-heart-beat", 1, map[string]string{"instance_port": "20000", "cluster_domain": "a.b"})
+	r.SetGauge("mysql-monitor-heart-beat", 2, map[string]string{"instance_port": "20000", "cluster_domain": "a.b"})
+	r.SetGauge("ibd_size", 1024, map[string]string{"db": `x"y\z`})
+	r.AddEvent("slave-status", "io thread stopped", map[string]string{"instance_port": "20000"})
+	r.AddEvent("slave-status", "io thread stopped", map[string]string{"instance_port": "20000"})
+
+	var b bytes.Buffer
+	if err := r.WriteMetrics(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		"# TYPE ibd_size gauge\n",
+		`ibd_size{db="x\"y\\z"} 1024
+`,
+		"# TYPE monitor_event_total counter\n",
+		`monitor_event_total{instance_port="20000",name="slave-status"} 2
+`,
+		"# TYPE mysql_monitor_heart_beat gauge\n",
+		`mysql_monitor_heart_beat{cluster_domain="a.b",instance_port="20000"} 2
+`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics output missing %q\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "mysql_monitor_heart_beat{") != 1 {
+		t.Errorf("same labels should overwrite\n%s", out)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	r := NewRegistry(2)
+	r.AddEvent("a", "1", nil)
+	r.AddEvent("b", "2", nil)
+	r.AddEvent("a", "3", nil)
+
+	events := r.Events("", time.Time{})
+	if len(events) != 2 || events[0].Msg != "2" || events[1].Msg != "3" {
+		t.Fatalf("unexpected events %+v", events)
+	}
+
+	srv := httptest.NewServer(r.Handler())
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL + EventsPath + "?name=a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	var got []*Event
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Msg != "3" {
+		t.Fatalf("unexpected events %+v", got)
+	}
+
+	resp2, err := srv.Client().Get(srv.URL + EventsPath + "?since=bad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp2.Body.Close()
+	if resp2.StatusCode != 400 {
+		t.Fatalf("expect 400, got %d", resp2.StatusCode)
+	}
+}
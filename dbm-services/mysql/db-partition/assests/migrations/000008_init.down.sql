@@ -0,0 +1 @@
+SET NAMES utf8;
@@ -0,0 +1,9 @@
+SET NAMES utf8;
+alter table mysql_partition_config add column archive_mode varchar(16) NOT NULL DEFAULT '' COMMENT '过期分区归档方式: 空--不归档;table--保留为独立表;file--导出文件上传到介质中心',
+    add column archive_keep_days int NOT NULL DEFAULT 0 COMMENT 'table方式归档表保留天数';
+alter table spider_partition_config add column archive_mode varchar(16) NOT NULL DEFAULT '' COMMENT '过期分区归档方式: 空--不归档;table--保留为独立表;file--导出文件上传到介质中心',
+    add column archive_keep_days int NOT NULL DEFAULT 0 COMMENT 'table方式归档表保留天数';
+alter table mysql_partition_cron_log add column archive_rows bigint NOT NULL DEFAULT 0 COMMENT '归档行数',
+    add column archive_location varchar(512) NOT NULL DEFAULT '' COMMENT '归档表或者归档文件位置';
+alter table spider_partition_cron_log add column archive_rows bigint NOT NULL DEFAULT 0 COMMENT '归档行数',
+    add column archive_location varchar(512) NOT NULL DEFAULT '' COMMENT '归档表或者归档文件位置';
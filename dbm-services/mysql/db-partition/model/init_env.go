@@ -52,6 +52,11 @@ func InitEnv() {
 	viper.BindEnv("bkrepo.password", "BKREPO_PASSWORD")
 	viper.BindEnv("bkrepo.endpoint_url", "BKREPO_ENDPOINT_URL")
 
+	// 过期分区归档参数, 可选参数
+	viper.BindEnv("archive.timeout", "ARCHIVE_TIMEOUT")
+	viper.BindEnv("archive.page_size", "ARCHIVE_PAGE_SIZE")
+	viper.BindEnv("archive.expires", "ARCHIVE_EXPIRES")
+
 	flag.Bool("migrate", false,
 		"run migrate to databases, not exit.")
 	viper.BindPFlags(flag.CommandLine)
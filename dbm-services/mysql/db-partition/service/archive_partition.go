@@ -0,0 +1,530 @@
+package service
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"dbm-services/mysql/db-partition/model"
+
+	"github.com/spf13/viper"
+)
+
+// ArchiveNone 过期分区直接删除，不归档
+const ArchiveNone = ""
+
+// ArchiveTable 过期分区交换到独立的归档表，归档表保留ArchiveKeepDays天
+const ArchiveTable = "table"
+
+// ArchiveFile 过期分区交换到独立的归档表，导出为压缩文件上传到介质中心后删除归档表
+const ArchiveFile = "file"
+
+// Archived 归档记录在定时任务日志表中的状态
+const Archived = "archived"
+
+// ArchiveKept 归档失败，数据保留在归档表中，下次定时任务从归档表继续归档
+const ArchiveKept = "archive_kept"
+
+const archiveTableInfix = "_arc_"
+const maxTableNameLength = 64
+
+// 归档超时时间、分页大小的默认值
+const defaultArchiveTimeout = 3600
+const defaultArchivePageSize = 10000
+const archiveQueryTimeout = 600
+
+// 每条insert语句包含的行数
+const archiveInsertRows = 1000
+
+// 这些类型的字段以hex导出，避免经过json传输后数据损坏
+var archiveBinaryTypes = []string{"binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob", "bit",
+	"geometry", "point", "linestring", "polygon", "multipoint", "multilinestring", "multipolygon",
+	"geometrycollection"}
+
+var archiveEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\x00", `\0`, "\n", `\n`, "\r", `\r`,
+	"\x1a", `\Z`)
+
+var likeEscaper = strings.NewReplacer(`_`, `\_`, `%`, `\%`)
+
+// archiveColumn 归档表的字段
+type archiveColumn struct {
+	name   string
+	binary bool
+}
+
+// archivePlan 归档一个分区需要执行的步骤
+type archivePlan struct {
+	// 把分区交换到归档表
+	exchange bool
+	// 导出归档表并上传
+	export bool
+}
+
+// CheckArchiveMode 检查分区规则的归档配置
+func CheckArchiveMode(mode string, keepDays int) error {
+	switch mode {
+	case ArchiveNone, ArchiveFile:
+		return nil
+	case ArchiveTable:
+		if keepDays < 1 {
+			return fmt.Errorf("table方式归档，归档表保留天数不能小于1")
+		}
+		return nil
+	default:
+		return fmt.Errorf("不支持的归档方式：%s，可选：table、file", mode)
+	}
+}
+
+// CheckTimeout 检查一个分区规则的超时时间，定时任务需要在删除前归档过期分区，超时时间更长
+func (config *PartitionConfig) CheckTimeout(fromCron bool) time.Duration {
+	if fromCron && config.ArchiveMode != ArchiveNone {
+		timeout := viper.GetInt("archive.timeout")
+		if timeout <= 0 {
+			timeout = defaultArchiveTimeout
+		}
+		return time.Duration(timeout) * time.Second
+	}
+	return 30 * time.Second
+}
+
+// ArchivePartitions 删除过期分区前，把分区中的数据交换到独立的归档表，按照归档方式保留归档表或者导出为文件
+func (m *ConfigDetail) ArchivePartitions(host Host, partitions []string) error {
+	for _, pname := range partitions {
+		rows, location, err := m.archivePartition(host, pname)
+		if err != nil {
+			slog.Error("msg", "archive partition error", err, "db", m.DbName, "tb", m.TbName, "partition", pname)
+			// 数据已经在归档表中，记录位置，便于找回数据
+			if location != "" {
+				info := fmt.Sprintf("instance is %s#%d. archive partition %s of `%s`.`%s` fail, data kept in %s, "+
+					"rows: %d, error: %s", host.Ip, host.Port, pname, m.DbName, m.TbName, location, rows, err.Error())
+				if errLog := AddArchiveLog(m.ClusterType, m.ID, info, ArchiveKept, rows, location); errLog != nil {
+					msg := "add archive log fail"
+					SendMonitor(msg, errLog)
+					slog.Error("msg", msg, errLog)
+				}
+			}
+			return fmt.Errorf("archive partition %s of `%s`.`%s` error, partition not dropped: %s",
+				pname, m.DbName, m.TbName, err.Error())
+		}
+		// 空分区不需要归档
+		if location == "" {
+			continue
+		}
+		info := fmt.Sprintf("instance is %s#%d. archive partition %s of `%s`.`%s` to %s, rows: %d",
+			host.Ip, host.Port, pname, m.DbName, m.TbName, location, rows)
+		slog.Info(info)
+		// 数据已经归档，记录日志失败不影响删除分区
+		if err = AddArchiveLog(m.ClusterType, m.ID, info, Archived, rows, location); err != nil {
+			msg := "add archive log fail"
+			SendMonitor(msg, err)
+			slog.Error("msg", msg, err)
+		}
+	}
+	return nil
+}
+
+// archivePartition 归档一个分区，返回归档行数和归档位置，分区为空时归档位置为空
+// 数据交换到归档表之后失败时，同时返回归档表的位置
+// 上次归档失败留下的归档表，这次从归档表继续
+func (m *ConfigDetail) archivePartition(host Host, pname string) (int64, string, error) {
+	address := fmt.Sprintf("%s:%d", host.Ip, host.Port)
+	arcTb := m.TbName + archiveTableInfix + pname
+	if len(arcTb) > maxTableNameLength {
+		return 0, "", fmt.Errorf("archive table name %s is longer than %d", arcTb, maxTableNameLength)
+	}
+	kept := fmt.Sprintf("%s.%s", m.DbName, arcTb)
+	partRows, err := countRows(host, fmt.Sprintf("select count(*) as COUNT from `%s`.`%s` partition (%s)",
+		m.DbName, m.TbName, pname))
+	if err != nil {
+		return 0, "", err
+	}
+	exists, err := countRows(host, fmt.Sprintf("select count(*) as COUNT from information_schema.TABLES "+
+		"where TABLE_SCHEMA='%s' and TABLE_NAME='%s'", m.DbName, arcTb))
+	if err != nil {
+		return 0, "", err
+	}
+	var arcRows int64
+	if exists > 0 {
+		arcRows, err = countRows(host, fmt.Sprintf("select count(*) as COUNT from `%s`.`%s`", m.DbName, arcTb))
+		if err != nil {
+			return 0, "", err
+		}
+		slog.Info("msg", "archive table exists", kept, "rows", arcRows, "partition rows", partRows)
+	}
+	plan, err := planArchive(m.ArchiveMode, partRows, exists > 0, arcRows)
+	if err != nil {
+		return arcRows, kept, err
+	}
+
+	if plan.exchange {
+		// 新建的归档表和原表一样是分区表，上次留下的归档表可能还没有去掉分区
+		partitioned := int64(1)
+		var cmds []string
+		if exists == 0 {
+			cmds = append(cmds, fmt.Sprintf("create table `%s`.`%s` like `%s`.`%s`", m.DbName, arcTb, m.DbName,
+				m.TbName))
+		} else {
+			partitioned, err = countRows(host, fmt.Sprintf("select count(*) as COUNT from "+
+				"information_schema.PARTITIONS where TABLE_SCHEMA='%s' and TABLE_NAME='%s' "+
+				"and PARTITION_NAME is not null", m.DbName, arcTb))
+			if err != nil {
+				return 0, "", err
+			}
+		}
+		if partitioned > 0 {
+			cmds = append(cmds, fmt.Sprintf("alter table `%s`.`%s` remove partitioning", m.DbName, arcTb))
+		}
+		cmds = append(cmds, fmt.Sprintf("alter table `%s`.`%s` exchange partition %s with table `%s`.`%s`",
+			m.DbName, m.TbName, pname, m.DbName, arcTb))
+		var queryRequest = QueryRequest{Addresses: []string{address}, Cmds: cmds, Force: false,
+			QueryTimeout: archiveQueryTimeout, BkCloudId: host.BkCloudId}
+		if _, err = OneAddressExecuteSql(queryRequest); err != nil {
+			// 交换失败时数据还在原分区中，下次定时任务复用已经建好的归档表
+			return 0, "", err
+		}
+		arcRows, err = countRows(host, fmt.Sprintf("select count(*) as COUNT from `%s`.`%s`", m.DbName, arcTb))
+		if err != nil {
+			return partRows, kept, err
+		}
+	}
+	if !plan.export {
+		if !plan.exchange {
+			// 空分区，或者table方式已经归档过；file方式不会清理归档表，删掉上次留下的空归档表
+			if m.ArchiveMode == ArchiveFile && exists > 0 && arcRows == 0 {
+				m.dropArchiveTable(host, arcTb)
+			}
+			return 0, "", nil
+		}
+		return arcRows, kept, nil
+	}
+
+	// 导出或上传失败时，数据保留在归档表中
+	filename := fmt.Sprintf("partition_archive_%s_%d_%s_%s_%s.sql.gz", host.Ip, host.Port, m.DbName, arcTb,
+		time.Now().Format("20060102150405"))
+	defer func() {
+		_ = os.Remove(filename)
+	}()
+	dumped, err := m.dumpArchiveTable(host, arcTb, pname, filename)
+	if err != nil {
+		return arcRows, kept, fmt.Errorf("dump archive table `%s`.`%s` error: %s", m.DbName, arcTb, err.Error())
+	}
+	// 导出的行数和归档表不一致时不能删除归档表和分区
+	if dumped != arcRows {
+		return arcRows, kept, fmt.Errorf("dump archive table `%s`.`%s` rows %d not equal to table rows %d",
+			m.DbName, arcTb, dumped, arcRows)
+	}
+	location, resp, err := UploadArchiveToBkRepo(filename)
+	if err != nil {
+		return arcRows, kept, fmt.Errorf("upload %s to bkrepo error: %s", filename, err.Error())
+	}
+	if resp.Code != 0 {
+		return arcRows, kept, fmt.Errorf("upload %s to bkrepo respone error. respone code is %d,respone msg:%s,"+
+			"traceId:%s", filename, resp.Code, resp.Message, resp.RequestId)
+	}
+	m.dropArchiveTable(host, arcTb)
+	return arcRows, location, nil
+}
+
+// planArchive 根据分区和归档表中的数据决定归档步骤
+// 归档表已存在说明上次归档没有完成：分区有数据时归档表必须为空，重新交换；分区为空时从归档表继续导出
+func planArchive(mode string, partRows int64, arcExists bool, arcRows int64) (archivePlan, error) {
+	file := mode == ArchiveFile
+	switch {
+	case partRows > 0 && arcExists && arcRows > 0:
+		return archivePlan{}, fmt.Errorf("both partition and archive table have data, " +
+			"archive table need to be handled manually")
+	case partRows > 0:
+		return archivePlan{exchange: true, export: file}, nil
+	case arcExists && arcRows > 0:
+		return archivePlan{export: file}, nil
+	default:
+		return archivePlan{}, nil
+	}
+}
+
+// dropArchiveTable 文件已经上传，归档表删除失败不影响删除分区
+func (m *ConfigDetail) dropArchiveTable(host Host, arcTb string) {
+	address := fmt.Sprintf("%s:%d", host.Ip, host.Port)
+	var queryRequest = QueryRequest{Addresses: []string{address},
+		Cmds: []string{fmt.Sprintf("drop table `%s`.`%s`", m.DbName, arcTb)}, Force: false,
+		QueryTimeout: archiveQueryTimeout, BkCloudId: host.BkCloudId}
+	if _, err := OneAddressExecuteSql(queryRequest); err != nil {
+		msg := fmt.Sprintf("drop archive table `%s`.`%s` on %s fail", m.DbName, arcTb, address)
+		SendMonitor(msg, err)
+		slog.Error("msg", msg, err)
+	}
+}
+
+// dumpArchiveTable 导出归档表的建表语句，按主键分页读取归档表，生成insert语句写入gzip压缩文件，返回导出的行数
+func (m *ConfigDetail) dumpArchiveTable(host Host, arcTb, pname, filename string) (int64, error) {
+	address := fmt.Sprintf("%s:%d", host.Ip, host.Port)
+	columns, err := archiveColumns(host, m.DbName, arcTb)
+	if err != nil {
+		return 0, err
+	}
+	keys, err := archiveKey(host, m.DbName, arcTb, columns)
+	if err != nil {
+		return 0, err
+	}
+	ddl, err := showCreateTable(host, m.DbName, arcTb)
+	if err != nil {
+		return 0, err
+	}
+	var names []string
+	for _, c := range columns {
+		names = append(names, fmt.Sprintf("`%s`", c.name))
+	}
+
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	zw := gzip.NewWriter(f)
+	w := bufio.NewWriter(zw)
+	_, _ = fmt.Fprintf(w, "-- archive of partition %s of `%s`.`%s` on %s\n", pname, m.DbName, m.TbName, address)
+	_, _ = fmt.Fprintf(w, "-- restore: load this file into database `%s`, "+
+		"then alter table `%s` exchange partition %s with table `%s`;\n", m.DbName, m.TbName, pname, arcTb)
+	_, _ = fmt.Fprintf(w, "%s;\n", ddl)
+
+	pageSize := viper.GetInt("archive.page_size")
+	if pageSize <= 0 {
+		pageSize = defaultArchivePageSize
+	}
+	var last map[string]interface{}
+	var rows int64
+	for offset := 0; ; offset += pageSize {
+		vsql := archivePageSql(m.DbName, arcTb, columns, keys, last, offset, pageSize)
+		var queryRequest = QueryRequest{Addresses: []string{address}, Cmds: []string{vsql}, Force: false,
+			QueryTimeout: archiveQueryTimeout, BkCloudId: host.BkCloudId, MaxRows: pageSize}
+		output, err := OneAddressExecuteSql(queryRequest)
+		if err != nil {
+			return rows, err
+		}
+		// db-remote-service的max_rows小于page_size时每页都会被截断，继续翻页会漏掉数据
+		if output.CmdResults[0].Truncated {
+			return rows, fmt.Errorf("query result truncated by db-remote-service max_rows, "+
+				"archive.page_size %d is too large", pageSize)
+		}
+		data := output.CmdResults[0].TableData
+		rows += int64(len(data))
+		for start := 0; start < len(data); start += archiveInsertRows {
+			var values []string
+			for _, row := range data[start:min(start+archiveInsertRows, len(data))] {
+				vals := make([]string, len(columns))
+				for k, c := range columns {
+					vals[k] = archiveValue(row[c.name], c.binary)
+				}
+				values = append(values, "("+strings.Join(vals, ",")+")")
+			}
+			_, _ = fmt.Fprintf(w, "insert into `%s` (%s) values %s;\n", arcTb, strings.Join(names, ","),
+				strings.Join(values, ","))
+		}
+		if len(data) < pageSize {
+			break
+		}
+		last = data[len(data)-1]
+	}
+	if err = w.Flush(); err != nil {
+		return rows, err
+	}
+	return rows, zw.Close()
+}
+
+// archivePageSql 有主键或者非空唯一键时按键值翻页，否则按offset翻页
+// 二进制字段以hex导出，排序和翻页条件使用表名限定的字段，避免用到同名的hex别名
+func archivePageSql(dbName, tbName string, columns, keys []archiveColumn, last map[string]interface{},
+	offset, pageSize int) string {
+	var selects []string
+	for _, c := range columns {
+		if c.binary {
+			selects = append(selects, fmt.Sprintf("hex(`%s`) as `%s`", c.name, c.name))
+		} else {
+			selects = append(selects, fmt.Sprintf("`%s`", c.name))
+		}
+	}
+	vsql := fmt.Sprintf("select %s from `%s`.`%s`", strings.Join(selects, ","), dbName, tbName)
+	if len(keys) == 0 {
+		return fmt.Sprintf("%s limit %d offset %d", vsql, pageSize, offset)
+	}
+
+	var orders, conds, equals []string
+	for _, k := range keys {
+		col := fmt.Sprintf("`%s`.`%s`", tbName, k.name)
+		orders = append(orders, col)
+		if last != nil {
+			value := archiveValue(last[k.name], k.binary)
+			conds = append(conds, "("+strings.Join(append(equals, fmt.Sprintf("%s > %s", col, value)), " and ")+")")
+			equals = append(equals, fmt.Sprintf("%s = %s", col, value))
+		}
+	}
+	if len(conds) > 0 {
+		vsql = fmt.Sprintf("%s where %s", vsql, strings.Join(conds, " or "))
+	}
+	return fmt.Sprintf("%s order by %s limit %d", vsql, strings.Join(orders, ","), pageSize)
+}
+
+// archiveKey 获取翻页用的主键，没有主键时用非空唯一键，都没有时返回空
+func archiveKey(host Host, dbName, tbName string, columns []archiveColumn) ([]archiveColumn, error) {
+	address := fmt.Sprintf("%s:%d", host.Ip, host.Port)
+	vsql := fmt.Sprintf("select INDEX_NAME as INDEX_NAME, COLUMN_NAME as COLUMN_NAME, NON_UNIQUE as NON_UNIQUE, "+
+		"NULLABLE as NULLABLE from information_schema.STATISTICS where TABLE_SCHEMA='%s' and TABLE_NAME='%s' "+
+		"order by INDEX_NAME='PRIMARY' desc, INDEX_NAME, SEQ_IN_INDEX", dbName, tbName)
+	var queryRequest = QueryRequest{Addresses: []string{address}, Cmds: []string{vsql}, Force: false,
+		QueryTimeout: 30, BkCloudId: host.BkCloudId}
+	output, err := OneAddressExecuteSql(queryRequest)
+	if err != nil {
+		return nil, err
+	}
+	keys := pickArchiveKey(output.CmdResults[0].TableData, columns)
+	if len(keys) == 0 {
+		slog.Warn("msg", "no primary key or not null unique key, dump by offset", fmt.Sprintf("%s.%s", dbName, tbName))
+	}
+	return keys, nil
+}
+
+// pickArchiveKey 从索引信息中选出主键或者非空唯一键，键中的字段都需要导出
+func pickArchiveKey(indexes tableDataType, columns []archiveColumn) []archiveColumn {
+	var order []string
+	keys := make(map[string][]archiveColumn)
+	invalid := make(map[string]bool)
+	for _, row := range indexes {
+		name := fmt.Sprintf("%v", row["INDEX_NAME"])
+		if _, ok := keys[name]; !ok {
+			order = append(order, name)
+		}
+		idx := slices.IndexFunc(columns, func(c archiveColumn) bool {
+			return c.name == fmt.Sprintf("%v", row["COLUMN_NAME"])
+		})
+		if idx < 0 || fmt.Sprintf("%v", row["NON_UNIQUE"]) != "0" || fmt.Sprintf("%v", row["NULLABLE"]) == "YES" {
+			invalid[name] = true
+			continue
+		}
+		keys[name] = append(keys[name], columns[idx])
+	}
+	for _, name := range order {
+		if !invalid[name] {
+			return keys[name]
+		}
+	}
+	return nil
+}
+
+// showCreateTable 获取建表语句
+func showCreateTable(host Host, dbName, tbName string) (string, error) {
+	vsql := fmt.Sprintf("show create table `%s`.`%s`", dbName, tbName)
+	var queryRequest = QueryRequest{Addresses: []string{fmt.Sprintf("%s:%d", host.Ip, host.Port)},
+		Cmds: []string{vsql}, Force: false, QueryTimeout: 30, BkCloudId: host.BkCloudId}
+	output, err := OneAddressExecuteSql(queryRequest)
+	if err != nil {
+		return "", err
+	}
+	if len(output.CmdResults[0].TableData) == 0 {
+		return "", fmt.Errorf("execute [%s] nothing return", vsql)
+	}
+	ddl, ok := output.CmdResults[0].TableData[0]["Create Table"].(string)
+	if !ok {
+		return "", fmt.Errorf("execute [%s] no create table statement", vsql)
+	}
+	return ddl, nil
+}
+
+// archiveColumns 获取归档表需要导出的字段，生成列不导出
+func archiveColumns(host Host, dbName, tbName string) ([]archiveColumn, error) {
+	address := fmt.Sprintf("%s:%d", host.Ip, host.Port)
+	vsql := fmt.Sprintf("select COLUMN_NAME as COLUMN_NAME, DATA_TYPE as DATA_TYPE from information_schema.COLUMNS "+
+		"where TABLE_SCHEMA='%s' and TABLE_NAME='%s' and EXTRA not like '%%GENERATED%%' order by ORDINAL_POSITION",
+		dbName, tbName)
+	var queryRequest = QueryRequest{Addresses: []string{address}, Cmds: []string{vsql}, Force: false,
+		QueryTimeout: 30, BkCloudId: host.BkCloudId}
+	output, err := OneAddressExecuteSql(queryRequest)
+	if err != nil {
+		return nil, err
+	}
+	var columns []archiveColumn
+	for _, row := range output.CmdResults[0].TableData {
+		dataType := strings.ToLower(row["DATA_TYPE"].(string))
+		columns = append(columns, archiveColumn{name: row["COLUMN_NAME"].(string),
+			binary: slices.Contains(archiveBinaryTypes, dataType)})
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no column found in `%s`.`%s`", dbName, tbName)
+	}
+	return columns, nil
+}
+
+// archiveValue 把查询结果转换为insert语句中的值
+func archiveValue(v interface{}, binary bool) string {
+	if v == nil {
+		return "NULL"
+	}
+	s := fmt.Sprintf("%v", v)
+	if binary {
+		if s == "" {
+			return "''"
+		}
+		return "0x" + s
+	}
+	return "'" + archiveEscaper.Replace(s) + "'"
+}
+
+// countRows 执行count(*) as COUNT的查询
+func countRows(host Host, vsql string) (int64, error) {
+	var queryRequest = QueryRequest{Addresses: []string{fmt.Sprintf("%s:%d", host.Ip, host.Port)},
+		Cmds: []string{vsql}, Force: false, QueryTimeout: archiveQueryTimeout, BkCloudId: host.BkCloudId}
+	output, err := OneAddressExecuteSql(queryRequest)
+	if err != nil {
+		return 0, err
+	}
+	if len(output.CmdResults[0].TableData) == 0 {
+		return 0, fmt.Errorf("execute [%s] nothing return", vsql)
+	}
+	return strconv.ParseInt(output.CmdResults[0].TableData[0]["COUNT"].(string), 10, 64)
+}
+
+// GetDropArchiveTableSqls 生成删除超过保留天数的归档表的sql
+func (m *ConfigDetail) GetDropArchiveTableSqls(host Host) ([]string, error) {
+	var dropSqls []string
+	if m.ArchiveKeepDays <= 0 {
+		return dropSqls, nil
+	}
+	prefix := m.TbName + archiveTableInfix
+	vsql := fmt.Sprintf("select TABLE_NAME as TABLE_NAME from information_schema.TABLES "+
+		"where TABLE_SCHEMA='%s' and TABLE_NAME like '%s%%' and CREATE_TIME < date_sub(now(), interval %d day)",
+		m.DbName, likeEscaper.Replace(prefix), m.ArchiveKeepDays)
+	var queryRequest = QueryRequest{Addresses: []string{fmt.Sprintf("%s:%d", host.Ip, host.Port)},
+		Cmds: []string{vsql}, Force: true, QueryTimeout: 30, BkCloudId: host.BkCloudId}
+	output, err := OneAddressExecuteSql(queryRequest)
+	if err != nil {
+		return dropSqls, err
+	}
+	reg := regexp.MustCompile(fmt.Sprintf("^%sp[0-9]{8}$", regexp.QuoteMeta(prefix)))
+	for _, row := range output.CmdResults[0].TableData {
+		name := row["TABLE_NAME"].(string)
+		if reg.MatchString(name) {
+			dropSqls = append(dropSqls, fmt.Sprintf("drop table if exists `%s`.`%s`", m.DbName, name))
+		}
+	}
+	return dropSqls, nil
+}
+
+// AddArchiveLog 归档信息记录到定时任务日志表中，用于找回归档数据
+func AddArchiveLog(clusterType string, configId int, checkInfo string, status string, rows int64,
+	location string) error {
+	tb := MysqlPartitionCronLogTable
+	if clusterType == Tendbcluster {
+		tb = SpiderPartitionCronLogTable
+	}
+	log := &PartitionCronLog{ConfigId: configId, CronDate: time.Now().Format("20060102"), Scheduler: Scheduler,
+		CheckInfo: checkInfo, Status: status, ArchiveRows: rows, ArchiveLocation: location}
+	return model.DB.Self.Table(tb).Create(log).Error
+}
@@ -0,0 +1,334 @@
+package service
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// fakeDrs 模拟db-remote-service，按sql返回查询结果，记录执行过的sql
+func fakeDrs(t *testing.T, handle func(cmd string) tableDataType) *[]string {
+	return fakeDrsResult(t, func(_ QueryRequest, cmd string) cmdResult {
+		return cmdResult{Cmd: cmd, TableData: handle(cmd)}
+	})
+}
+
+// fakeDrsResult 按请求和sql返回完整的执行结果，用于模拟截断等情况
+func fakeDrsResult(t *testing.T, handle func(req QueryRequest, cmd string) cmdResult) *[]string {
+	var cmds []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req QueryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %s", err.Error())
+		}
+		result := oneAddressResult{Address: req.Addresses[0]}
+		for _, cmd := range req.Cmds {
+			cmds = append(cmds, cmd)
+			result.CmdResults = append(result.CmdResults, handle(req, cmd))
+		}
+		data, _ := json.Marshal(queryResponseData{result})
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 0, "data": json.RawMessage(data)})
+	}))
+	t.Cleanup(srv.Close)
+	viper.Set("db_remote_service", srv.URL+"/")
+	return &cmds
+}
+
+func TestArchiveValue(t *testing.T) {
+	cases := []struct {
+		v      interface{}
+		binary bool
+		want   string
+	}{
+		{nil, false, "NULL"},
+		{nil, true, "NULL"},
+		{"abc", false, "'abc'"},
+		{float64(12), false, "'12'"},
+		{"it's", false, `'it\'s'`},
+		{`a\b`, false, `'a\\b'`},
+		{"a\nb\r\x00\x1a", false, `'a\nb\r\0\Z'`},
+		{"", false, "''"},
+		{"0A1B", true, "0x0A1B"},
+		{"", true, "''"},
+	}
+	for _, c := range cases {
+		if got := archiveValue(c.v, c.binary); got != c.want {
+			t.Errorf("archiveValue(%q, %v) = %s, want %s", c.v, c.binary, got, c.want)
+		}
+	}
+}
+
+func TestCheckArchiveMode(t *testing.T) {
+	cases := []struct {
+		mode     string
+		keepDays int
+		ok       bool
+	}{
+		{ArchiveNone, 0, true},
+		{ArchiveFile, 0, true},
+		{ArchiveTable, 1, true},
+		{ArchiveTable, 0, false},
+		{"binlog", 1, false},
+	}
+	for _, c := range cases {
+		if err := CheckArchiveMode(c.mode, c.keepDays); (err == nil) != c.ok {
+			t.Errorf("CheckArchiveMode(%q, %d) = %v, want ok %v", c.mode, c.keepDays, err, c.ok)
+		}
+	}
+}
+
+func TestPlanArchive(t *testing.T) {
+	cases := []struct {
+		name      string
+		mode      string
+		partRows  int64
+		arcExists bool
+		arcRows   int64
+		want      archivePlan
+		fail      bool
+	}{
+		{"empty partition", ArchiveFile, 0, false, 0, archivePlan{}, false},
+		{"table", ArchiveTable, 10, false, 0, archivePlan{exchange: true}, false},
+		{"file", ArchiveFile, 10, false, 0, archivePlan{exchange: true, export: true}, false},
+		{"create ok exchange failed", ArchiveFile, 10, true, 0, archivePlan{exchange: true, export: true}, false},
+		{"upload failed", ArchiveFile, 0, true, 10, archivePlan{export: true}, false},
+		{"table already archived", ArchiveTable, 0, true, 10, archivePlan{}, false},
+		{"both have data", ArchiveFile, 10, true, 10, archivePlan{}, true},
+	}
+	for _, c := range cases {
+		got, err := planArchive(c.mode, c.partRows, c.arcExists, c.arcRows)
+		if (err != nil) != c.fail || got != c.want {
+			t.Errorf("%s: got %+v %v, want %+v fail %v", c.name, got, err, c.want, c.fail)
+		}
+	}
+}
+
+func TestArchivePageSql(t *testing.T) {
+	columns := []archiveColumn{{name: "id"}, {name: "uid", binary: true}, {name: "v"}}
+	cases := []struct {
+		keys   []archiveColumn
+		last   map[string]interface{}
+		offset int
+		want   string
+	}{
+		{
+			nil, nil, 20,
+			"select `id`,hex(`uid`) as `uid`,`v` from `db`.`t_arc_p20240101` limit 10 offset 20",
+		},
+		{
+			columns[:1], nil, 0,
+			"select `id`,hex(`uid`) as `uid`,`v` from `db`.`t_arc_p20240101` " +
+				"order by `t_arc_p20240101`.`id` limit 10",
+		},
+		{
+			columns[:1], map[string]interface{}{"id": "5"}, 10,
+			"select `id`,hex(`uid`) as `uid`,`v` from `db`.`t_arc_p20240101` " +
+				"where (`t_arc_p20240101`.`id` > '5') order by `t_arc_p20240101`.`id` limit 10",
+		},
+		{
+			columns[:2], map[string]interface{}{"id": "5", "uid": "0AFF"}, 10,
+			"select `id`,hex(`uid`) as `uid`,`v` from `db`.`t_arc_p20240101` " +
+				"where (`t_arc_p20240101`.`id` > '5') or " +
+				"(`t_arc_p20240101`.`id` = '5' and `t_arc_p20240101`.`uid` > 0x0AFF) " +
+				"order by `t_arc_p20240101`.`id`,`t_arc_p20240101`.`uid` limit 10",
+		},
+	}
+	for _, c := range cases {
+		if got := archivePageSql("db", "t_arc_p20240101", columns, c.keys, c.last, c.offset, 10); got != c.want {
+			t.Errorf("got  %s\nwant %s", got, c.want)
+		}
+	}
+}
+
+func TestPickArchiveKey(t *testing.T) {
+	columns := []archiveColumn{{name: "id"}, {name: "a"}, {name: "b"}}
+	index := func(name, column, nonUnique, nullable string) map[string]interface{} {
+		return map[string]interface{}{"INDEX_NAME": name, "COLUMN_NAME": column, "NON_UNIQUE": nonUnique,
+			"NULLABLE": nullable}
+	}
+	cases := []struct {
+		name    string
+		indexes tableDataType
+		want    []string
+	}{
+		{"no index", nil, nil},
+		{"primary", tableDataType{index("PRIMARY", "id", "0", ""), index("PRIMARY", "a", "0", ""),
+			index("uk", "b", "0", "")}, []string{"id", "a"}},
+		{"not null unique", tableDataType{index("idx", "a", "1", ""), index("uk", "b", "0", "")}, []string{"b"}},
+		{"nullable unique", tableDataType{index("uk", "b", "0", "YES")}, nil},
+		// 生成列不导出，不能用于翻页
+		{"generated column", tableDataType{index("uk", "g", "0", "")}, nil},
+	}
+	for _, c := range cases {
+		var got []string
+		for _, k := range pickArchiveKey(c.indexes, columns) {
+			got = append(got, k.name)
+		}
+		if strings.Join(got, ",") != strings.Join(c.want, ",") {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestGetDropArchiveTableSqls(t *testing.T) {
+	cmds := fakeDrs(t, func(cmd string) tableDataType {
+		return tableDataType{
+			{"TABLE_NAME": "t_1_arc_p20240101"},
+			{"TABLE_NAME": "t_1_arc_p20240102"},
+			{"TABLE_NAME": "t_1_arc_p2024"},
+			{"TABLE_NAME": "t_1_arc_p20240101_bak"},
+		}
+	})
+	host := Host{Ip: "127.0.0.1", Port: 3306}
+
+	m := &ConfigDetail{DbName: "db", TbName: "t_1"}
+	sqls, err := m.GetDropArchiveTableSqls(host)
+	if err != nil || len(sqls) != 0 || len(*cmds) != 0 {
+		t.Fatalf("keep days 0 should not drop: %v %v %v", sqls, err, *cmds)
+	}
+
+	m.ArchiveKeepDays = 7
+	sqls, err = m.GetDropArchiveTableSqls(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"drop table if exists `db`.`t_1_arc_p20240101`", "drop table if exists `db`.`t_1_arc_p20240102`"}
+	if strings.Join(sqls, ";") != strings.Join(want, ";") {
+		t.Errorf("got %v, want %v", sqls, want)
+	}
+	if len(*cmds) != 1 || !strings.Contains((*cmds)[0], `TABLE_NAME like 't\_1\_arc\_%'`) ||
+		!strings.Contains((*cmds)[0], "interval 7 day") {
+		t.Errorf("unexpected query %v", *cmds)
+	}
+}
+
+func TestDumpArchiveTable(t *testing.T) {
+	viper.Set("archive.page_size", 2)
+	defer viper.Set("archive.page_size", 0)
+	rows := tableDataType{
+		{"id": "1", "v": "a"},
+		{"id": "2", "v": "it's"},
+		{"id": "3", "v": nil},
+	}
+	cmds := fakeDrs(t, func(cmd string) tableDataType {
+		switch {
+		case strings.Contains(cmd, "information_schema.COLUMNS"):
+			return tableDataType{{"COLUMN_NAME": "id", "DATA_TYPE": "int"}, {"COLUMN_NAME": "v", "DATA_TYPE": "varchar"}}
+		case strings.Contains(cmd, "information_schema.STATISTICS"):
+			return tableDataType{{"INDEX_NAME": "PRIMARY", "COLUMN_NAME": "id", "NON_UNIQUE": "0", "NULLABLE": ""}}
+		case strings.HasPrefix(cmd, "show create table"):
+			return tableDataType{{"Table": "t_arc_p20240101", "Create Table": "CREATE TABLE `t_arc_p20240101` (...)"}}
+		case strings.Contains(cmd, "`id` > '2'"):
+			return rows[2:]
+		default:
+			return rows[:2]
+		}
+	})
+
+	m := &ConfigDetail{DbName: "db", TbName: "t"}
+	filename := filepath.Join(t.TempDir(), "archive.sql.gz")
+	dumped, err := m.dumpArchiveTable(Host{Ip: "127.0.0.1", Port: 3306}, "t_arc_p20240101", "p20240101", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dumped != 3 {
+		t.Errorf("dumped %d rows, want 3", dumped)
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(b)
+	for _, want := range []string{
+		"CREATE TABLE `t_arc_p20240101` (...);\n",
+		"insert into `t_arc_p20240101` (`id`,`v`) values ('1','a'),('2','it\\'s');\n",
+		"insert into `t_arc_p20240101` (`id`,`v`) values ('3',NULL);\n",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("dump file missing %q:\n%s", want, content)
+		}
+	}
+	if strings.Contains(strings.Join(*cmds, "\n"), "offset") {
+		t.Errorf("table with primary key should not page by offset: %v", *cmds)
+	}
+}
+
+// archiveDrs 模拟上次上传失败留下的3行归档表，分区已经为空，page返回每页的数据
+func archiveDrs(t *testing.T, page func(req QueryRequest) cmdResult) *[]string {
+	return fakeDrsResult(t, func(req QueryRequest, cmd string) cmdResult {
+		result := cmdResult{Cmd: cmd}
+		switch {
+		case strings.Contains(cmd, "partition (p20240101)"):
+			result.TableData = tableDataType{{"COUNT": "0"}}
+		case strings.Contains(cmd, "information_schema.TABLES"):
+			result.TableData = tableDataType{{"COUNT": "1"}}
+		case strings.HasPrefix(cmd, "select count(*)"):
+			result.TableData = tableDataType{{"COUNT": "3"}}
+		case strings.Contains(cmd, "information_schema.COLUMNS"):
+			result.TableData = tableDataType{{"COLUMN_NAME": "id", "DATA_TYPE": "int"}}
+		case strings.Contains(cmd, "information_schema.STATISTICS"):
+			result.TableData = tableDataType{{"INDEX_NAME": "PRIMARY", "COLUMN_NAME": "id", "NON_UNIQUE": "0",
+				"NULLABLE": ""}}
+		case strings.HasPrefix(cmd, "show create table"):
+			result.TableData = tableDataType{{"Create Table": "CREATE TABLE `t_arc_p20240101` (...)"}}
+		case strings.HasPrefix(cmd, "select `id`"):
+			return page(req)
+		}
+		return result
+	})
+}
+
+func TestArchivePartitionShortDump(t *testing.T) {
+	viper.Set("archive.page_size", 2)
+	defer viper.Set("archive.page_size", 0)
+	cases := []struct {
+		name string
+		page func(req QueryRequest) cmdResult
+	}{
+		{"silently cut page", func(req QueryRequest) cmdResult {
+			return cmdResult{TableData: tableDataType{{"id": "1"}}}
+		}},
+		{"truncated page", func(req QueryRequest) cmdResult {
+			if req.MaxRows != 2 {
+				t.Errorf("page request max_rows %d, want 2", req.MaxRows)
+			}
+			return cmdResult{TableData: tableDataType{{"id": "1"}}, Truncated: true}
+		}},
+	}
+	for _, c := range cases {
+		cmds := archiveDrs(t, c.page)
+		m := &ConfigDetail{PartitionConfig: PartitionConfig{ArchiveMode: ArchiveFile}, DbName: "db", TbName: "t"}
+		rows, location, err := m.archivePartition(Host{Ip: "127.0.0.1", Port: 3306}, "p20240101")
+		if err == nil {
+			t.Errorf("%s: short dump should abort the archive", c.name)
+		}
+		if rows != 3 || location != "db.t_arc_p20240101" {
+			t.Errorf("%s: got rows %d location %s, want data kept in archive table", c.name, rows, location)
+		}
+		for _, cmd := range *cmds {
+			if strings.HasPrefix(cmd, "drop") {
+				t.Errorf("%s: archive table dropped after short dump: %s", c.name, cmd)
+			}
+		}
+	}
+}
@@ -12,13 +12,30 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"strconv"
+
+	"github.com/spf13/viper"
 )
 
 // UploadDirectToBkRepo 上传文件到介质中心
 func UploadDirectToBkRepo(filename string) (*BkRepoRespone, error) {
+	return uploadToBkRepo(filename, path.Join("mysql", "partition", filename), "15")
+}
+
+// UploadArchiveToBkRepo 上传过期分区的归档文件到介质中心，返回文件在介质中心的路径
+func UploadArchiveToBkRepo(filename string) (string, *BkRepoRespone, error) {
+	// 保留天数为0表示永久保存
+	expires := strconv.Itoa(viper.GetInt("archive.expires"))
+	repoPath := path.Join("mysql", "partition_archive", path.Base(filename))
+	resp, err := uploadToBkRepo(filename, repoPath, expires)
+	return path.Join(model.BkRepo.Project, model.BkRepo.PublicBucket, repoPath), resp, err
+}
+
+// uploadToBkRepo 上传文件到介质中心的repoPath，expires为文件保留天数
+func uploadToBkRepo(filename string, repoPath string, expires string) (*BkRepoRespone, error) {
 	// 路径需要包含文件名称
 	targetURL, err := url.JoinPath(model.BkRepo.EndPointUrl,
-		path.Join("generic", model.BkRepo.Project, model.BkRepo.PublicBucket, "mysql", "partition", filename))
+		path.Join("generic", model.BkRepo.Project, model.BkRepo.PublicBucket, repoPath))
 	if err != nil {
 		slog.Error("get url fail")
 		return nil, err
@@ -31,6 +48,9 @@ func UploadDirectToBkRepo(filename string) (*BkRepoRespone, error) {
 		slog.Error("opening file error", "err", err)
 		return nil, err
 	}
+	defer func() {
+		_ = fh.Close()
+	}()
 	boundary := bodyWriter.Boundary()
 	closeBuf := bytes.NewBufferString("")
 
@@ -49,8 +69,8 @@ func UploadDirectToBkRepo(filename string) (*BkRepoRespone, error) {
 	req.Header.Set("Content-Type", "multipart/form-data; boundary="+boundary)
 	// 文件是否可以被覆盖，默认false
 	req.Header.Set("X-BKREPO-OVERWRITE", "True")
-	// 文件默认保留半年
-	req.Header.Set("X-BKREPO-EXPIRES", "15")
+	// 文件保留天数
+	req.Header.Set("X-BKREPO-EXPIRES", expires)
 	req.ContentLength = fi.Size() + int64(bodyBuf.Len()) + int64(closeBuf.Len())
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
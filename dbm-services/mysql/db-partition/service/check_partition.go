@@ -51,6 +51,9 @@ func (m *Checker) DryRun() ([]PartitionObject, error) {
 	if len(configs) == 0 {
 		return objects, errno.PartitionConfigNotExisted
 	}
+	for _, config := range configs {
+		config.ClusterType = m.ClusterType
+	}
 
 	slog.Info(fmt.Sprintf("configs:%v", configs))
 	switch m.ClusterType {
@@ -126,7 +129,7 @@ func CheckPartitionConfigs(configs []*PartitionConfig, dbtype string, splitCnt i
 	limiter := rate.NewLimiter(limit, burst)
 	for _, config := range configs {
 		wg.Add(1)
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), config.CheckTimeout(fromCron))
 		go func(config *PartitionConfig) {
 			err := limiter.Wait(context.Background())
 			if err != nil {
@@ -30,7 +30,7 @@ func (config *PartitionConfig) GetPartitionDbLikeTbLike(dbtype string, splitCnt
 
 	tbs, errOuter := config.GetDbTableInfo(fromCron, host)
 	if errOuter != nil {
-		slog.Error("GetDbTableInfo error", errOuter)
+		slog.Error("GetDbTableInfo error", "error", errOuter)
 		return nil, nil, nil, fmt.Errorf("get database and table info failed：%s", errOuter.Error())
 	}
 	var sql string
@@ -56,16 +56,29 @@ func (config *PartitionConfig) GetPartitionDbLikeTbLike(dbtype string, splitCnt
 					return
 				}
 				AddString(&addSqls, sql)
-				if tb.Phase == online {
+				if tb.Phase == online && (tb.ArchiveMode == ArchiveNone || fromCron) {
 					// 启用的分区规则，会执行删除历史分区
 					// 禁用的分区规则，会新增分区，但是不会删除历史分区
-					sql, err = tb.GetDropPartitionSql(host)
+					// 配置了归档的分区规则，只由定时任务在归档后删除历史分区；tdbctl上没有数据，不需要归档
+					archive := tb.ArchiveMode != ArchiveNone && dbtype == "mysql"
+					sql, err = tb.GetDropPartitionSql(host, archive)
 					if err != nil {
 						slog.Error("msg", "GetDropPartitionSql error", err)
 						AddString(&errs, err.Error())
 						return
 					}
 					AddString(&dropSqls, sql)
+					if archive && tb.ArchiveMode == ArchiveTable {
+						sqls, errInner := tb.GetDropArchiveTableSqls(host)
+						if errInner != nil {
+							slog.Error("msg", "GetDropArchiveTableSqls error", errInner)
+							AddString(&errs, errInner.Error())
+							return
+						}
+						for _, s := range sqls {
+							AddString(&dropSqls, s)
+						}
+					}
 				}
 			} else {
 				sql, needSize, err = tb.GetInitPartitionSql(dbtype, splitCnt, host)
@@ -100,8 +113,8 @@ func (config *PartitionConfig) GetDbTableInfo(fromCron bool, host Host) (ptlist
 		`select TABLE_SCHEMA as TABLE_SCHEMA,TABLE_NAME as TABLE_NAME,CREATE_OPTIONS as CREATE_OPTIONS `+
 			` from information_schema.tables where TABLE_SCHEMA like '%s' and TABLE_NAME like '%s';`,
 		config.DbLike, config.TbLike)
-	var queryRequest = QueryRequest{[]string{address}, []string{sql}, true, 30,
-		int(host.BkCloudId)}
+	var queryRequest = QueryRequest{Addresses: []string{address}, Cmds: []string{sql}, Force: true,
+		QueryTimeout: 30, BkCloudId: int(host.BkCloudId)}
 	output, err = OneAddressExecuteSql(queryRequest)
 	if err != nil {
 		slog.Error("GetDbTableInfo", sql, err.Error())
@@ -118,8 +131,8 @@ func (config *PartitionConfig) GetDbTableInfo(fromCron bool, host Host) (ptlist
 			` where TABLE_SCHEMA like '%s' and TABLE_NAME like '%s' and `+
 			` CONSTRAINT_TYPE in ('UNIQUE','PRIMARY KEY');`,
 		config.DbLike, config.TbLike)
-	queryRequest = QueryRequest{[]string{address}, []string{uniqueKeySql}, true,
-		30, int(host.BkCloudId)}
+	queryRequest = QueryRequest{Addresses: []string{address}, Cmds: []string{uniqueKeySql}, Force: true,
+		QueryTimeout: 30, BkCloudId: int(host.BkCloudId)}
 	hasUniqueKey, err := OneAddressExecuteSql(queryRequest)
 	if err != nil {
 		slog.Error("get", sql, err.Error())
@@ -139,8 +152,8 @@ func (config *PartitionConfig) GetDbTableInfo(fromCron bool, host Host) (ptlist
 				" information_schema.PARTITIONS where TABLE_SCHEMA like '%s' and TABLE_NAME like '%s' "+
 				" order by PARTITION_DESCRIPTION asc limit 2;",
 				db, tb)
-			queryRequest = QueryRequest{[]string{address}, []string{sql}, true, 30,
-				int(host.BkCloudId)}
+			queryRequest = QueryRequest{Addresses: []string{address}, Cmds: []string{sql}, Force: true,
+				QueryTimeout: 30, BkCloudId: int(host.BkCloudId)}
 			output, err = OneAddressExecuteSql(queryRequest)
 			if err != nil {
 				slog.Error("GetDbTableInfo", sql, err.Error())
@@ -283,8 +296,8 @@ func CalculateInterval(firstName, secondName string, interval int) (bool, error)
 	return true, nil
 }
 
-// GetDropPartitionSql 生成删除分区的sql
-func (m *ConfigDetail) GetDropPartitionSql(host Host) (string, error) {
+// GetDropPartitionSql 生成删除分区的sql，archive为true时先归档过期分区，归档失败则不删除
+func (m *ConfigDetail) GetDropPartitionSql(host Host, archive bool) (string, error) {
 	var sql, dropSql, fx string
 	// 保留时间+1天，考虑时区差异引起的时间计算不稳定
 	reserve := m.ReservedPartition*m.PartitionTimeInterval + 1
@@ -329,6 +342,11 @@ func (m *ConfigDetail) GetDropPartitionSql(host Host) (string, error) {
 		}
 	}
 	if len(expired) != 0 {
+		if archive {
+			if err = m.ArchivePartitions(host, expired); err != nil {
+				return dropSql, err
+			}
+		}
 		dropSql = fmt.Sprintf("alter table `%s`.`%s` drop partition %s", m.DbName, m.TbName, strings.Join(expired, ","))
 	}
 	return dropSql, nil
@@ -670,6 +688,9 @@ func NeedPartition(cronType string, clusterType string, zoneOffset int, cronDate
 		slog.Error("msg", fmt.Sprintf("query %s err", configTb), err)
 		return nil, err
 	}
+	for _, item := range all {
+		item.ClusterType = clusterType
+	}
 	if cronType == "daily" {
 		return all, nil
 	}
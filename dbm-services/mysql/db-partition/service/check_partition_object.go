@@ -67,6 +67,9 @@ type PartitionCronLog struct {
 	CronDate  string `json:"cron_date" grom:"column:cron_date"`
 	CheckInfo string `json:"check_info" gorm:"column:check_info"`
 	Status    string `json:"status" gorm:"column:status"`
+	// 归档行数以及归档表或者归档文件的位置
+	ArchiveRows     int64  `json:"archive_rows" gorm:"column:archive_rows"`
+	ArchiveLocation string `json:"archive_location" gorm:"column:archive_location"`
 }
 
 // CreatePartitionCronLog 分区的定时任务日志表，区分集群类型
@@ -81,6 +84,9 @@ type PartitionLog struct {
 	ExecuteTime time.Time `json:"execute_time" gorm:"execute_time"`
 	CheckInfo   string    `json:"check_info" gorm:"check_info"`
 	Status      string    `json:"status" gorm:"status"`
+	// 归档行数以及归档表或者归档文件的位置
+	ArchiveRows     int64  `json:"archive_rows" gorm:"archive_rows"`
+	ArchiveLocation string `json:"archive_location" gorm:"archive_location"`
 }
 
 // InitMessages 初始化分区的sql数组以及其互斥锁
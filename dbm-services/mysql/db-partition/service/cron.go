@@ -84,7 +84,7 @@ func RegisterCron() ([]*cron.Cron, error) {
 		}
 		// 启动分区定时任务
 		c.Start()
-		slog.Info("msg", zone, c.Entries())
+		slog.Info("msg", "zone", zone, "entries", c.Entries())
 		CronList = append(CronList, c)
 	}
 	return CronList, nil
@@ -315,7 +315,7 @@ func UploadObejct(objects []PartitionObject, filename string) error {
 			resp.RequestId,
 		)
 		SendMonitor(msg, fmt.Errorf("upload error"))
-		slog.Error("msg", msg)
+		slog.Error(msg)
 		return fmt.Errorf("upload error")
 	}
 	_ = os.Remove(filename)
@@ -387,7 +387,7 @@ func GetTendbclusterInstances(cluster string) (map[string][]SpiderNode, int, err
 			slog.Error(fmt.Sprintf("execute [%s] nothing return", getTdbctlPrimary))
 			return nil, splitCnt, fmt.Errorf("execute [%s] nothing return", getTdbctlPrimary)
 		}
-		slog.Info("data:", primary.CmdResults[0].TableData)
+		slog.Info("msg", "data", primary.CmdResults[0].TableData)
 		tdbctlPrimary = primary.CmdResults[0].TableData[0]["SERVER_NAME"].(string)
 		break
 	}
@@ -74,7 +74,7 @@ func CreateDbmTicket(config Ticket) (int, error) {
 	c := util.NewClientByHosts(viper.GetString("dbm_ticket_service"))
 	result, err := c.Do(http.MethodPost, "tickets/", config)
 	if err != nil {
-		slog.Error("msg", err)
+		slog.Error("msg", "error", err)
 		return ticketId, err
 	}
 	if err = json.Unmarshal(result.Data, &resp); err != nil {
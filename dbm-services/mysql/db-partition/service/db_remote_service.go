@@ -27,11 +27,11 @@ func OneAddressExecuteSqlBasic(vtype string, queryRequest QueryRequest) (oneAddr
 	}
 	apiResp, err := c.Do(http.MethodPost, url, queryRequest)
 	if err != nil {
-		slog.Error("drs err", err)
+		slog.Error("drs err", "error", err)
 		return result, err
 	}
 	if apiResp.Code != 0 {
-		slog.Error("remote service api", fmt.Errorf(apiResp.Message))
+		slog.Error("remote service api", "error", apiResp.Message)
 		return result, fmt.Errorf(apiResp.Message)
 	} else {
 		if err := json.Unmarshal(apiResp.Data, &temp); err != nil {
@@ -50,7 +50,7 @@ func OneAddressExecuteSqlBasic(vtype string, queryRequest QueryRequest) (oneAddr
 	}
 
 	if len(errMsg) > 0 {
-		slog.Error("msg", fmt.Errorf(strings.Join(errMsg, "\n")))
+		slog.Error("msg", "error", strings.Join(errMsg, "\n"))
 		return result, fmt.Errorf(strings.Join(errMsg, "\n"))
 	}
 	slog.Info("msg", "OneAddressExecuteSqlBasic", "end")
@@ -81,6 +81,8 @@ type QueryRequest struct {
 	*/
 	QueryTimeout int `form:"query_timeout" json:"query_timeout" url:"query_timeout"` // sql执行超时时间
 	BkCloudId    int `form:"bk_cloud_id" json:"bk_cloud_id" url:"bk_cloud_id"`       // mysql服务所在的云域
+	// MaxRows 查询最多返回的行数，不设置时使用db-remote-service配置的max_rows，超过时结果被截断
+	MaxRows int `form:"max_rows" json:"max_rows,omitempty" url:"max_rows"`
 }
 
 // queryResponse db-remote-service服务/mysql/rpc接口返回的结构
@@ -107,6 +109,7 @@ type cmdResult struct {
 	TableData    tableDataType `json:"table_data"`
 	RowsAffected int           `json:"rows_affected"`
 	ErrorMsg     string        `json:"error_msg"`
+	Truncated    bool          `json:"truncated"` // 查询结果超过max_rows被截断
 }
 
 // tableDataType 查询返回记录
@@ -30,11 +30,17 @@ type PartitionConfig struct {
 	// 集群所在的时区
 	TimeZone string `json:"time_zone"`
 	// 分区规则启用或者禁用
-	Phase      string    `json:"phase" gorm:"column:phase"`
-	Creator    string    `json:"creator" gorm:"column:creator"`
-	Updator    string    `json:"updator" gorm:"column:updator"`
-	CreateTime time.Time `json:"create_time" gorm:"column:create_time"`
-	UpdateTime time.Time `json:"update_time" gorm:"column:update_time"`
+	Phase string `json:"phase" gorm:"column:phase"`
+	// 过期分区归档方式，为空不归档
+	ArchiveMode string `json:"archive_mode" gorm:"column:archive_mode"`
+	// table方式归档表的保留天数
+	ArchiveKeepDays int       `json:"archive_keep_days" gorm:"column:archive_keep_days"`
+	Creator         string    `json:"creator" gorm:"column:creator"`
+	Updator         string    `json:"updator" gorm:"column:updator"`
+	CreateTime      time.Time `json:"create_time" gorm:"column:create_time"`
+	UpdateTime      time.Time `json:"update_time" gorm:"column:update_time"`
+	// 规则所属的集群类型，不入库，用于归档时选择日志表
+	ClusterType string `json:"-" gorm:"-"`
 }
 
 // PartitionConfigWithLog 分区配置以及执行日志
@@ -144,7 +144,7 @@ func (m *QueryLogInput) GetPartitionLog() ([]*PartitionLog, int64, error) {
 	}
 
 	vsql = fmt.Sprintf("select id, create_time as execute_time, "+
-		"check_info, status from %s where %s order by execute_time desc %s",
+		"check_info, status, archive_rows, archive_location from %s where %s order by execute_time desc %s",
 		logTb, where, limitCondition)
 	err = model.DB.Self.Raw(vsql).Scan(&allResults).Error
 	if err != nil {
@@ -281,6 +281,9 @@ func (m *CreatePartitionsInput) CreatePartitionsConfig() (error, []int) {
 	if m.ExpireTime%m.PartitionTimeInterval != 0 {
 		return errors.New("过期时间必须是分区间隔的整数倍"), []int{}
 	}
+	if err := CheckArchiveMode(m.ArchiveMode, m.ArchiveKeepDays); err != nil {
+		return err, []int{}
+	}
 	reservedPartition := m.ExpireTime / m.PartitionTimeInterval
 	partitionType := 0
 	// 普通分区类型0 5 101
@@ -340,6 +343,8 @@ func (m *CreatePartitionsInput) CreatePartitionsConfig() (error, []int) {
 				Creator:               m.Creator,
 				Updator:               m.Updator,
 				Phase:                 online,
+				ArchiveMode:           m.ArchiveMode,
+				ArchiveKeepDays:       m.ArchiveKeepDays,
 				CreateTime:            time.Now(),
 				UpdateTime:            time.Now(),
 			}
@@ -392,6 +397,9 @@ func (m *CreatePartitionsInput) UpdatePartitionsConfig() error {
 	if m.ExpireTime%m.PartitionTimeInterval != 0 {
 		return errors.New("过期时间必须是分区间隔的整数倍")
 	}
+	if err := CheckArchiveMode(m.ArchiveMode, m.ArchiveKeepDays); err != nil {
+		return err
+	}
 
 	reservedPartition := m.ExpireTime / m.PartitionTimeInterval
 	partitionType := 0
@@ -444,6 +452,8 @@ func (m *CreatePartitionsInput) UpdatePartitionsConfig() error {
 				"partition_time_interval": m.PartitionTimeInterval,
 				"partition_type":          partitionType,
 				"expire_time":             m.ExpireTime,
+				"archive_mode":            m.ArchiveMode,
+				"archive_keep_days":       m.ArchiveKeepDays,
 				"updator":                 m.Updator,
 				"update_time":             time.Now(),
 			}
@@ -81,6 +81,8 @@ type CreatePartitionsInput struct {
 	Creator               string   `json:"creator"`
 	Updator               string   `json:"updator"`
 	RemoteHashAlgorithm   string   `json:"remote_hash_algorithm"`
+	ArchiveMode           string   `json:"archive_mode"`      // 过期分区归档方式：空、table、file
+	ArchiveKeepDays       int      `json:"archive_keep_days"` // table方式归档表保留天数
 }
 
 // DeletePartitionConfigByIds TODO
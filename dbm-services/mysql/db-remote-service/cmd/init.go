@@ -5,6 +5,7 @@ import "github.com/spf13/viper"
 
 func init() {
 	rootCmd.PersistentFlags().Int("concurrent", 500, "concurrent")
+	rootCmd.PersistentFlags().Int("max_rows", 100000, "max rows returned by one query, 0 means unlimited")
 	rootCmd.PersistentFlags().String("mysql_admin_password", "123", "mysql password")
 	rootCmd.PersistentFlags().String("mysql_admin_user", "root", "mysql user")
 
@@ -18,6 +19,9 @@ func init() {
 	rootCmd.PersistentFlags().String("sqlserver_admin_password", "123", "sqlserver password")
 	rootCmd.PersistentFlags().String("sqlserver_admin_user", "root", "sqlserver user")
 
+	rootCmd.PersistentFlags().String("mongodb_admin_password", "123", "mongodb password")
+	rootCmd.PersistentFlags().String("mongodb_admin_user", "root", "mongodb user")
+
 	rootCmd.PersistentFlags().Int("port", 8888, "port")
 
 	rootCmd.PersistentFlags().Bool("log_json", true, "json format log")
@@ -35,6 +39,11 @@ func init() {
 	rootCmd.PersistentFlags().String("key_file", "", "key file")
 	rootCmd.PersistentFlags().Bool("tls", false, "use tls")
 
+	rootCmd.PersistentFlags().String("audit_dir", "audit", "audit log dir, empty means disable")
+	rootCmd.PersistentFlags().Int("audit_keep_days", 30, "audit log keep days")
+	rootCmd.PersistentFlags().String("policy_file", "", "policy file, empty means disable")
+	rootCmd.PersistentFlags().String("caller_tokens", "", "caller tokens, format: name1:token1,name2:token2")
+
 	viper.SetEnvPrefix("DRS")
 	viper.AutomaticEnv()
 	_ = viper.BindEnv("mysql_admin_user", "MYSQL_ADMIN_USER")
@@ -48,7 +57,10 @@ func init() {
 
 	_ = viper.BindEnv("sqlserver_admin_user", "SQLSERVER_ADMIN_USER")
 	_ = viper.BindEnv("sqlserver_admin_password", "SQLSERVER_ADMIN_PASSWORD")
+	_ = viper.BindEnv("mongodb_admin_user", "MONGODB_ADMIN_USER")
+	_ = viper.BindEnv("mongodb_admin_password", "MONGODB_ADMIN_PASSWORD")
 	_ = viper.BindEnv("concurrent", "CONCURRENT")
+	_ = viper.BindEnv("max_rows", "MAX_ROWS")
 	_ = viper.BindEnv("port", "PORT")
 	_ = viper.BindEnv("tmysqlparser_bin", "TMYSQLPARSER_BIN")
 	_ = viper.BindEnv("redis_cli_bin", "REDIS_CLI_BIN")
@@ -64,5 +76,10 @@ func init() {
 	_ = viper.BindEnv("key_file", "KEY_FILE")
 	_ = viper.BindEnv("tls", "TLS")
 
+	_ = viper.BindEnv("audit_dir", "AUDIT_DIR")
+	_ = viper.BindEnv("audit_keep_days", "AUDIT_KEEP_DAYS")
+	_ = viper.BindEnv("policy_file", "POLICY_FILE")
+	_ = viper.BindEnv("caller_tokens", "CALLER_TOKENS")
+
 	_ = viper.BindPFlags(rootCmd.PersistentFlags())
 }
@@ -15,7 +15,9 @@ import (
 	"dbm-services/common/go-pubpkg/apm/metric"
 	"dbm-services/common/go-pubpkg/apm/trace"
 	"dbm-services/mysql/db-remote-service/pkg/apm"
+	"dbm-services/mysql/db-remote-service/pkg/audit"
 	"dbm-services/mysql/db-remote-service/pkg/config"
+	"dbm-services/mysql/db-remote-service/pkg/policy"
 	"dbm-services/mysql/db-remote-service/pkg/service"
 
 	"github.com/gin-gonic/gin"
@@ -30,12 +32,24 @@ var rootCmd = &cobra.Command{
 	Short: "start db remote service",
 	Long:  `start db remote service`,
 	Run: func(cmd *cobra.Command, args []string) {
-		config.InitConfig()
+		if err := config.InitConfig(); err != nil {
+			slog.Error("init config", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
 		initLogger()
 
 		slog.Debug("run", slog.Any("runtime config", config.RuntimeConfig))
 		slog.Debug("run", slog.Any("log config", config.LogConfig))
 
+		if err := audit.Init(config.RuntimeConfig.AuditDir, config.RuntimeConfig.AuditKeepDays); err != nil {
+			slog.Error("init audit", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		if err := policy.Init(config.RuntimeConfig.PolicyFile, config.RuntimeConfig.ParserBin); err != nil {
+			slog.Error("init policy", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+
 		r := gin.Default()
 
 		// setup trace
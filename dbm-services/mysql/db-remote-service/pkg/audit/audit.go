@@ -0,0 +1,86 @@
+// Package audit 执行审计
+// 每条命令一条记录, 按天写入本地 json lines 文件, 只追加不修改
+package audit
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"time"
+)
+
+// Caller 请求来源
+type Caller struct {
+	RequestId   string
+	Name        string
+	ClientIP    string
+	RPCType     string
+	ClusterType string
+}
+
+// Record 审计记录
+type Record struct {
+	Time         time.Time `json:"time"`
+	RequestId    string    `json:"request_id"`
+	Caller       string    `json:"caller"`
+	ClientIP     string    `json:"client_ip"`
+	RPCType      string    `json:"rpc_type"`
+	ClusterType  string    `json:"cluster_type"`
+	Address      string    `json:"address"`
+	Cmd          string    `json:"cmd"`
+	Kind         string    `json:"kind"`
+	Category     string    `json:"category"`
+	RowsAffected int64     `json:"rows_affected"`
+	RowsReturned int       `json:"rows_returned"`
+	DurationMs   int64     `json:"duration_ms"`
+	DeniedBy     string    `json:"denied_by,omitempty"`
+	ErrorMsg     string    `json:"error_msg"`
+}
+
+// 命令类型
+const (
+	KindQuery       = "query"
+	KindExecute     = "execute"
+	KindUnsupported = "unsupported"
+)
+
+// DefaultStore 全局审计存储, 为 nil 时不记录
+var DefaultStore *Store
+
+// Init 初始化全局审计存储, dir 为空时不启用
+func Init(dir string, keepDays int) error {
+	if dir == "" {
+		slog.Info("audit disabled")
+		return nil
+	}
+
+	s, err := NewStore(dir, keepDays)
+	if err != nil {
+		return err
+	}
+	DefaultStore = s
+	return nil
+}
+
+// Append 写入全局审计存储, 失败只记日志, 不影响命令执行
+func Append(r *Record) {
+	if DefaultStore == nil {
+		return
+	}
+
+	if err := DefaultStore.Append(r); err != nil {
+		slog.Error(
+			"append audit record",
+			slog.String("error", err.Error()),
+			slog.String("request_id", r.RequestId),
+			slog.String("address", r.Address),
+		)
+	}
+}
+
+// NewRequestId 生成请求 id, 同一个请求的记录用它关联
+func NewRequestId() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
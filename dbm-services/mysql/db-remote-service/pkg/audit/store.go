@@ -0,0 +1,205 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	fileDayLayout = "20060102"
+	filePrefix    = "audit."
+	fileSuffix    = ".log"
+	// 单条记录最大长度, 超长的命令也能读出来
+	maxRecordSize = 64 * 1024 * 1024
+)
+
+// Store 本地审计存储
+type Store struct {
+	dir      string
+	keepDays int
+
+	mu   sync.Mutex
+	day  string
+	file *os.File
+}
+
+// Query 审计查询条件, 字符串条件为空表示不过滤
+type Query struct {
+	Start     time.Time
+	End       time.Time
+	RequestId string
+	Caller    string
+	Address   string
+	RPCType   string
+	Limit     int
+}
+
+// NewStore 新建审计存储
+func NewStore(dir string, keepDays int) (*Store, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, errors.Wrap(err, "create audit dir")
+	}
+	return &Store{dir: dir, keepDays: keepDays}, nil
+}
+
+func (s *Store) fileName(day string) string {
+	return filepath.Join(s.dir, filePrefix+day+fileSuffix)
+}
+
+// Append 追加一条记录
+func (s *Store) Append(r *Record) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day := r.Time.Format(fileDayLayout)
+	if s.file == nil || day != s.day {
+		if s.file != nil {
+			_ = s.file.Close()
+			s.file = nil
+		}
+
+		f, err := os.OpenFile(s.fileName(day), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return errors.Wrap(err, "open audit file")
+		}
+		s.file = f
+		s.day = day
+		s.clean(r.Time)
+	}
+
+	_, err = s.file.Write(b)
+	return err
+}
+
+// clean 删除过期的文件, 只在换天时调用
+func (s *Store) clean(now time.Time) {
+	if s.keepDays <= 0 {
+		return
+	}
+
+	expire := now.AddDate(0, 0, -s.keepDays).Format(fileDayLayout)
+	days, err := s.days()
+	if err != nil {
+		return
+	}
+	for _, day := range days {
+		if day < expire {
+			_ = os.Remove(s.fileName(day))
+		}
+	}
+}
+
+// days 已有的审计文件日期, 升序
+func (s *Store) days() ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(s.dir, filePrefix+"*"+fileSuffix))
+	if err != nil {
+		return nil, err
+	}
+
+	var days []string
+	for _, m := range matches {
+		day := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(m), filePrefix), fileSuffix)
+		if _, err := time.Parse(fileDayLayout, day); err == nil {
+			days = append(days, day)
+		}
+	}
+	sort.Strings(days)
+	return days, nil
+}
+
+// Query 按时间顺序返回符合条件的最近 Limit 条记录
+func (s *Store) Query(q *Query) ([]*Record, error) {
+	days, err := s.days()
+	if err != nil {
+		return nil, err
+	}
+
+	// 只保留最近的 Limit 条, 内存有上限
+	var res []*Record
+	for _, day := range days {
+		if !q.Start.IsZero() && day < q.Start.Format(fileDayLayout) {
+			continue
+		}
+		if !q.End.IsZero() && day > q.End.Format(fileDayLayout) {
+			continue
+		}
+
+		err = s.scanFile(
+			s.fileName(day), func(r *Record) {
+				if !q.match(r) {
+					return
+				}
+				res = append(res, r)
+				if q.Limit > 0 && len(res) > q.Limit {
+					res = res[1:]
+				}
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (s *Store) scanFile(name string, fn func(r *Record)) error {
+	f, err := os.Open(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordSize)
+	for scanner.Scan() {
+		var r Record
+		// 进程被杀时最后一行可能不完整, 跳过
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+		fn(&r)
+	}
+	return scanner.Err()
+}
+
+func (q *Query) match(r *Record) bool {
+	if !q.Start.IsZero() && r.Time.Before(q.Start) {
+		return false
+	}
+	if !q.End.IsZero() && r.Time.After(q.End) {
+		return false
+	}
+	if q.RequestId != "" && r.RequestId != q.RequestId {
+		return false
+	}
+	if q.Caller != "" && r.Caller != q.Caller {
+		return false
+	}
+	if q.Address != "" && r.Address != q.Address {
+		return false
+	}
+	if q.RPCType != "" && r.RPCType != q.RPCType {
+		return false
+	}
+	return true
+}
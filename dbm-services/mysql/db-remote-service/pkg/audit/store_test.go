@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStoreQuery(t *testing.T) {
+	s, err := NewStore(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2024, 3, 1, 23, 59, 0, 0, time.Local)
+	records := []*Record{
+		{Time: base.AddDate(0, 0, -1), RequestId: "r1", Caller: "a", Address: "1.1.1.1:3306", RPCType: "mysql"},
+		{Time: base, RequestId: "r2", Caller: "a", Address: "1.1.1.1:3306", RPCType: "mysql"},
+		{Time: base.Add(time.Minute), RequestId: "r3", Caller: "b", Address: "2.2.2.2:3306", RPCType: "webconsole"},
+		{Time: base.Add(2 * time.Minute), RequestId: "r4", Caller: "a", Address: "2.2.2.2:3306", RPCType: "mysql"},
+	}
+	for _, r := range records {
+		if err := s.Append(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"all", Query{}, []string{"r1", "r2", "r3", "r4"}},
+		{"limit keep latest", Query{Limit: 2}, []string{"r3", "r4"}},
+		{"start", Query{Start: base}, []string{"r2", "r3", "r4"}},
+		{"end", Query{End: base.Add(time.Minute)}, []string{"r1", "r2", "r3"}},
+		{"range across day", Query{Start: base, End: base.Add(time.Minute)}, []string{"r2", "r3"}},
+		{"caller", Query{Caller: "a"}, []string{"r1", "r2", "r4"}},
+		{"address", Query{Address: "2.2.2.2:3306"}, []string{"r3", "r4"}},
+		{"rpc type", Query{RPCType: "webconsole"}, []string{"r3"}},
+		{"request id", Query{RequestId: "r2"}, []string{"r2"}},
+		{"no match", Query{Caller: "c"}, nil},
+		{"before all files", Query{End: base.AddDate(0, 0, -2)}, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			res, err := s.Query(&c.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range res {
+				got = append(got, r.RequestId)
+			}
+			if len(got) != len(c.want) {
+				t.Fatalf("got %v, want %v", got, c.want)
+			}
+			for i := range got {
+				if got[i] != c.want[i] {
+					t.Fatalf("got %v, want %v", got, c.want)
+				}
+			}
+		})
+	}
+}
@@ -2,8 +2,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/viper"
 )
@@ -16,6 +18,7 @@ var LogConfig *logConfig
 
 type runtimeConfig struct {
 	Concurrent             int
+	MaxRows                int
 	MySQLAdminUser         string
 	MySQLAdminPassword     string
 	ProxyAdminUser         string
@@ -25,12 +28,19 @@ type runtimeConfig struct {
 	Timezone               string
 	SqlserverAdminUser     string
 	SqlserverAdminPassword string
+	MongoDBAdminUser       string
+	MongoDBAdminPassword   string
 	Port                   int
 	ParserBin              string
 	CAFile                 string
 	CertFile               string
 	KeyFile                string
 	TLS                    bool
+	AuditDir               string
+	AuditKeepDays          int
+	PolicyFile             string
+	// 调用方名字到 token 的映射, 见 callerTokens
+	CallerTokens map[string]string
 }
 
 type logConfig struct {
@@ -42,9 +52,15 @@ type logConfig struct {
 }
 
 // InitConfig 初始化配置
-func InitConfig() {
+func InitConfig() error {
+	tokens, err := callerTokens(viper.GetString("caller_tokens"))
+	if err != nil {
+		return err
+	}
+
 	RuntimeConfig = &runtimeConfig{
 		Concurrent:             viper.GetInt("concurrent"),
+		MaxRows:                viper.GetInt("max_rows"),
 		MySQLAdminUser:         viper.GetString("mysql_admin_user"),
 		MySQLAdminPassword:     viper.GetString("mysql_admin_password"),
 		ProxyAdminUser:         viper.GetString("proxy_admin_user"),
@@ -54,12 +70,18 @@ func InitConfig() {
 		Timezone:               viper.GetString("time_zone"),
 		SqlserverAdminUser:     viper.GetString("sqlserver_admin_user"),
 		SqlserverAdminPassword: viper.GetString("sqlserver_admin_password"),
+		MongoDBAdminUser:       viper.GetString("mongodb_admin_user"),
+		MongoDBAdminPassword:   viper.GetString("mongodb_admin_password"),
 		Port:                   viper.GetInt("port"),
 		ParserBin:              viper.GetString("tmysqlparser_bin"),
 		TLS:                    viper.GetBool("tls"),
 		CAFile:                 viper.GetString("ca_file"),
 		CertFile:               viper.GetString("cert_file"),
 		KeyFile:                viper.GetString("key_file"),
+		AuditDir:               viper.GetString("audit_dir"),
+		AuditKeepDays:          viper.GetInt("audit_keep_days"),
+		PolicyFile:             viper.GetString("policy_file"),
+		CallerTokens:           tokens,
 	}
 
 	if !filepath.IsAbs(RuntimeConfig.ParserBin) {
@@ -67,6 +89,11 @@ func InitConfig() {
 		RuntimeConfig.ParserBin = filepath.Join(filepath.Dir(executable), RuntimeConfig.ParserBin)
 	}
 
+	if RuntimeConfig.AuditDir != "" && !filepath.IsAbs(RuntimeConfig.AuditDir) {
+		executable, _ := os.Executable()
+		RuntimeConfig.AuditDir = filepath.Join(filepath.Dir(executable), RuntimeConfig.AuditDir)
+	}
+
 	LogConfig = &logConfig{
 		Console:    viper.GetBool("log_console"),
 		LogFileDir: viper.GetString("log_file_dir"),
@@ -74,4 +101,23 @@ func InitConfig() {
 		Source:     viper.GetBool("log_source"),
 		Json:       viper.GetBool("log_json"),
 	}
+	return nil
+}
+
+// callerTokens 解析 name1:token1,name2:token2, 名字和 token 都不能为空
+func callerTokens(s string) (map[string]string, error) {
+	res := make(map[string]string)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, token, _ := strings.Cut(item, ":")
+		name, token = strings.TrimSpace(name), strings.TrimSpace(token)
+		if name == "" || token == "" {
+			return nil, fmt.Errorf("caller token %q: name and token can not be empty", name)
+		}
+		res[name] = token
+	}
+	return res, nil
 }
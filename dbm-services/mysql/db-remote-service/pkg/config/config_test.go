@@ -0,0 +1,28 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCallerTokens(t *testing.T) {
+	cases := []struct {
+		s    string
+		want map[string]string
+		fail bool
+	}{
+		{"", map[string]string{}, false},
+		{"a:1, b : 2,", map[string]string{"a": "1", "b": "2"}, false},
+		{"a:1:2", map[string]string{"a": "1:2"}, false},
+		{"a:", nil, true},
+		{"a", nil, true},
+		{":1", nil, true},
+		{"a:1,b: ", nil, true},
+	}
+	for _, c := range cases {
+		got, err := callerTokens(c.s)
+		if (err != nil) != c.fail || (!c.fail && !reflect.DeepEqual(got, c.want)) {
+			t.Errorf("callerTokens(%q) = %v, %v, want %v fail %v", c.s, got, err, c.want, c.fail)
+		}
+	}
+}
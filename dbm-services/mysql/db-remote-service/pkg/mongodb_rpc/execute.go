@@ -0,0 +1,216 @@
+package mongodb_rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"dbm-services/mysql/db-remote-service/pkg/audit"
+	"dbm-services/mysql/db-remote-service/pkg/policy"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (c *MongoDBRPCWrapper) makeConnection(address string) (*mongo.Client, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(c.connectTimeout))
+	defer cancel()
+
+	// 直连单个 mongod/mongos, 不做副本集发现
+	opts := options.Client().
+		ApplyURI(fmt.Sprintf("mongodb://%s/?directConnection=true", address)).
+		SetConnectTimeout(time.Second * time.Duration(c.connectTimeout)).
+		SetServerSelectionTimeout(time.Second * time.Duration(c.connectTimeout)).
+		SetMaxPoolSize(1)
+	if c.user != "" {
+		opts.SetAuth(options.Credential{
+			AuthSource: defaultDB,
+			Username:   c.user,
+			Password:   c.password,
+		})
+	}
+
+	client, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.Ping(ctx, nil)
+	if err != nil {
+		_ = client.Disconnect(context.Background())
+		return nil, err
+	}
+	return client, nil
+}
+
+func (c *MongoDBRPCWrapper) executeOneAddr(address string) (res []cmdResult, err error) {
+	client, err := c.makeConnection(address)
+	if err != nil {
+		slog.Error("make connection", slog.String("error", err.Error()), slog.String("address", address))
+		return nil, err
+	}
+	defer func() {
+		_ = client.Disconnect(context.Background())
+	}()
+
+	for idx, command := range c.commands {
+		pc, err := parseCommand(command)
+		if err != nil {
+			slog.Error("parse command", slog.String("error", err.Error()))
+			return nil, err
+		}
+
+		start := time.Now()
+		kind := audit.KindUnsupported
+		if isQueryCommand(pc) {
+			kind = audit.KindQuery
+		} else if isExecuteCommand(pc) {
+			kind = audit.KindExecute
+		}
+
+		var cr cmdResult
+		checkReq := &policy.CheckRequest{
+			Caller:      c.caller.Name,
+			RPCType:     c.caller.RPCType,
+			ClusterType: c.caller.ClusterType,
+			Command:     command,
+		}
+		err = policy.Check(checkReq)
+		if err == nil {
+			err = checkSystemCollection(pc, checkReq)
+		}
+		if err == nil {
+			switch kind {
+			case audit.KindQuery:
+				cr, err = c.queryCmd(client, pc)
+			case audit.KindExecute:
+				cr, err = c.executeCmd(client, pc)
+			default:
+				err = errors.Errorf("commands[%d]: %s not support", idx, command)
+			}
+		}
+		cr.Cmd = command
+
+		if err != nil {
+			slog.Error(
+				"run command",
+				slog.String("error", err.Error()),
+				slog.String("address", address), slog.String("command", command),
+			)
+			cr.TableData = nil
+			cr.ErrorMsg = err.Error()
+		}
+		c.audit(address, pc, &cr, kind, time.Since(start), err)
+		res = append(res, cr)
+
+		if err != nil && !c.force {
+			return res, err
+		}
+	}
+	return
+}
+
+func (c *MongoDBRPCWrapper) queryCmd(client *mongo.Client, pc *parsedCommand) (cr cmdResult, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(c.queryTimeout))
+	defer cancel()
+
+	db := client.Database(pc.DB)
+	if !isCursorCommand(pc) {
+		var raw bson.Raw
+		raw, err = db.RunCommand(ctx, pc.Doc).DecodeBytes()
+		if err != nil {
+			return cr, err
+		}
+		row, err := toMap(raw)
+		if err != nil {
+			return cr, err
+		}
+		cr.TableData = tableDataType{row}
+		return cr, nil
+	}
+
+	cursor, err := db.RunCommandCursor(ctx, pc.Doc)
+	if err != nil {
+		return cr, err
+	}
+	defer func() {
+		_ = cursor.Close(context.Background())
+	}()
+
+	cr.TableData = make(tableDataType, 0)
+	for cursor.Next(ctx) {
+		if len(cr.TableData) >= maxCursorDocs {
+			slog.Warn("too many documents, truncated", slog.Int("max", maxCursorDocs))
+			break
+		}
+		row, err := toMap(cursor.Current)
+		if err != nil {
+			return cr, err
+		}
+		cr.TableData = append(cr.TableData, row)
+	}
+	return cr, cursor.Err()
+}
+
+func (c *MongoDBRPCWrapper) executeCmd(client *mongo.Client, pc *parsedCommand) (cr cmdResult, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(c.queryTimeout))
+	defer cancel()
+
+	raw, err := client.Database(pc.DB).RunCommand(ctx, pc.Doc).DecodeBytes()
+	if err != nil {
+		return cr, err
+	}
+
+	// 有 n 字段的命令当作影响行数
+	if v, err := raw.LookupErr("n"); err == nil {
+		if n, ok := v.AsInt64OK(); ok {
+			cr.RowsAffected = n
+		}
+	}
+	return cr, nil
+}
+
+// toMap 用 relaxed extjson 转换, 保证 ObjectId/Timestamp 等类型能正常 json 序列化
+func toMap(raw bson.Raw) (map[string]interface{}, error) {
+	b, err := bson.MarshalExtJSON(raw, false, false)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]interface{})
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *MongoDBRPCWrapper) audit(
+	address string, pc *parsedCommand, cr *cmdResult, kind string, duration time.Duration, err error,
+) {
+	r := &audit.Record{
+		Time:         time.Now(),
+		RequestId:    c.caller.RequestId,
+		Caller:       c.caller.Name,
+		ClientIP:     c.caller.ClientIP,
+		RPCType:      c.caller.RPCType,
+		ClusterType:  c.caller.ClusterType,
+		Address:      address,
+		Cmd:          cr.Cmd,
+		Kind:         kind,
+		Category:     pc.Name,
+		RowsAffected: cr.RowsAffected,
+		RowsReturned: len(cr.TableData),
+		DurationMs:   duration.Milliseconds(),
+		ErrorMsg:     cr.ErrorMsg,
+	}
+
+	var de *policy.DeniedError
+	if errors.As(err, &de) {
+		r.DeniedBy = de.Rule
+	}
+	audit.Append(r)
+}
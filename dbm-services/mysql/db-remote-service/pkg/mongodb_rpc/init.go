@@ -0,0 +1,83 @@
+package mongodb_rpc
+
+type tableDataType []map[string]interface{}
+
+// cmdResult 与 rpc_core 的返回结构保持一致
+type cmdResult struct {
+	Cmd          string        `json:"cmd"`
+	TableData    tableDataType `json:"table_data"`
+	RowsAffected int64         `json:"rows_affected"`
+	ErrorMsg     string        `json:"error_msg"`
+}
+
+type oneAddressResult struct {
+	Address    string      `json:"address"`
+	CmdResults []cmdResult `json:"cmd_results"`
+	ErrorMsg   string      `json:"error_msg"`
+}
+
+// 命令中用来指定库的字段, 不指定时在 admin 库执行
+const dbField = "$db"
+
+const defaultDB = "admin"
+
+// 返回游标的命令最多读取的文档数
+const maxCursorDocs = 10000
+
+var queryParseCommands = []string{
+	"aggregate",
+	"balancerstatus",
+	"buildinfo",
+	"collstats",
+	"connectionstatus",
+	"connpoolstats",
+	"count",
+	"currentop",
+	"datasize",
+	"dbstats",
+	"distinct",
+	"find",
+	"getcmdlineopts",
+	"getlog",
+	"getparameter",
+	"getshardmap",
+	"hello",
+	"hostinfo",
+	"ismaster",
+	"listcollections",
+	"listdatabases",
+	"listindexes",
+	"listshards",
+	"ping",
+	"replsetgetconfig",
+	"replsetgetstatus",
+	"serverstatus",
+	"shardconnpoolstats",
+	"top",
+}
+
+var executeParseCommands = []string{
+	"balancerstart",
+	"balancerstop",
+	"flushrouterconfig",
+	"killcursors",
+	"killop",
+	"killsessions",
+	"logrotate",
+	"profile",
+	"setparameter",
+}
+
+// 返回游标的命令
+var cursorCommands = []string{
+	"aggregate",
+	"find",
+	"listcollections",
+	"listindexes",
+}
+
+// aggregate 中会写数据的 stage
+var writeStages = []string{
+	"$out",
+	"$merge",
+}
@@ -0,0 +1,84 @@
+// Package mongodb_rpc mongodb rpc 实现
+package mongodb_rpc
+
+import (
+	"log/slog"
+	"sync"
+
+	"dbm-services/mysql/db-remote-service/pkg/audit"
+	"dbm-services/mysql/db-remote-service/pkg/config"
+)
+
+// MongoDBRPCWrapper mongodb RPC 对象
+type MongoDBRPCWrapper struct {
+	addresses      []string
+	commands       []string
+	user           string
+	password       string
+	connectTimeout int
+	queryTimeout   int
+	force          bool
+	caller         audit.Caller
+}
+
+// NewMongoDBRPCWrapper 新建 mongodb RPC 对象
+func NewMongoDBRPCWrapper(
+	addresses []string,
+	commands []string,
+	connectTimeout int,
+	queryTimeout int,
+	force bool,
+) *MongoDBRPCWrapper {
+	return &MongoDBRPCWrapper{
+		addresses:      addresses,
+		commands:       commands,
+		user:           config.RuntimeConfig.MongoDBAdminUser,
+		password:       config.RuntimeConfig.MongoDBAdminPassword,
+		connectTimeout: connectTimeout,
+		queryTimeout:   queryTimeout,
+		force:          force,
+	}
+}
+
+// SetCaller 请求来源, 用于策略检查和审计
+func (c *MongoDBRPCWrapper) SetCaller(caller audit.Caller) {
+	c.caller = caller
+}
+
+// Run 执行
+func (c *MongoDBRPCWrapper) Run() (res []oneAddressResult) {
+	addrResChan := make(chan oneAddressResult)
+	tokenBulkChan := make(chan struct{}, config.RuntimeConfig.Concurrent)
+	slog.Debug("init bulk chan", slog.Int("concurrent", config.RuntimeConfig.Concurrent))
+
+	go func() {
+		var wg sync.WaitGroup
+		wg.Add(len(c.addresses))
+
+		for _, address := range c.addresses {
+			tokenBulkChan <- struct{}{}
+			go func(address string) {
+				addrRes, err := c.executeOneAddr(address)
+				<-tokenBulkChan
+
+				var errMsg string
+				if err != nil {
+					errMsg = err.Error()
+				}
+				addrResChan <- oneAddressResult{
+					Address:    address,
+					CmdResults: addrRes,
+					ErrorMsg:   errMsg,
+				}
+				wg.Done()
+			}(address)
+		}
+		wg.Wait()
+		close(addrResChan)
+	}()
+
+	for addrRes := range addrResChan {
+		res = append(res, addrRes)
+	}
+	return
+}
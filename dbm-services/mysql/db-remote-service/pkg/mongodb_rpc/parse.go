@@ -0,0 +1,174 @@
+package mongodb_rpc
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"dbm-services/mysql/db-remote-service/pkg/policy"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// parsedCommand 解析后的命令
+type parsedCommand struct {
+	Name string
+	DB   string
+	Doc  bson.D
+}
+
+// parseCommand 解析 json 格式的命令, 如 {"collStats": "c1", "$db": "db1"}
+func parseCommand(command string) (*parsedCommand, error) {
+	var doc bson.D
+	err := bson.UnmarshalExtJSON([]byte(command), false, &doc)
+	if err != nil {
+		return nil, errors.Wrap(err, "command must be json document")
+	}
+
+	pc := &parsedCommand{DB: defaultDB}
+	for _, e := range doc {
+		if e.Key == dbField {
+			db, ok := e.Value.(string)
+			if !ok || db == "" {
+				return nil, errors.Errorf("%s must be non-empty string", dbField)
+			}
+			pc.DB = db
+			continue
+		}
+		pc.Doc = append(pc.Doc, e)
+	}
+
+	if len(pc.Doc) == 0 {
+		return nil, errors.Errorf("empty command")
+	}
+	// mongodb 以第一个字段作为命令名
+	pc.Name = strings.ToLower(pc.Doc[0].Key)
+	return pc, nil
+}
+
+// isQueryCommand 只读命令
+func isQueryCommand(pc *parsedCommand) bool {
+	if !slices.Contains(queryParseCommands, pc.Name) {
+		return false
+	}
+	if pc.Name == "aggregate" {
+		return !hasWriteStage(pc.Doc)
+	}
+	return true
+}
+
+// isExecuteCommand 写命令
+func isExecuteCommand(pc *parsedCommand) bool {
+	return slices.Contains(executeParseCommands, pc.Name)
+}
+
+func isCursorCommand(pc *parsedCommand) bool {
+	return slices.Contains(cursorCommands, pc.Name)
+}
+
+func hasWriteStage(doc bson.D) bool {
+	for _, e := range doc {
+		if e.Key != "pipeline" {
+			continue
+		}
+		stages, ok := e.Value.(bson.A)
+		if !ok {
+			// pipeline 格式不对时交给服务端报错, 但不当作只读
+			return true
+		}
+		for _, stage := range stages {
+			sd, ok := stage.(bson.D)
+			if !ok {
+				return true
+			}
+			for _, se := range sd {
+				if slices.Contains(writeStages, se.Key) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// systemCollectionRule 访问 system.* 集合被拒绝时 DeniedError 的规则名
+const systemCollectionRule = "mongo-system-collection"
+
+// checkSystemCollection 访问 system.* 集合(如 system.users)需要策略明确允许
+func checkSystemCollection(pc *parsedCommand, req *policy.CheckRequest) error {
+	for _, c := range referencedCollections(pc) {
+		if !strings.HasPrefix(c, "system.") {
+			continue
+		}
+		if policy.AllowMongoSystemCollections(req) {
+			return nil
+		}
+		return &policy.DeniedError{
+			Rule:   systemCollectionRule,
+			Reason: fmt.Sprintf("collection %s.%s not allowed", pc.DB, c),
+		}
+	}
+	return nil
+}
+
+// referencedCollections 命令访问的集合: 命令名字段的值, 以及 pipeline 中 $lookup 等 stage 引用的集合
+func referencedCollections(pc *parsedCommand) []string {
+	var colls []string
+	if name, ok := pc.Doc[0].Value.(string); ok {
+		// dataSize 的参数是 db.collection
+		if pc.Name == "datasize" {
+			if _, c, found := strings.Cut(name, "."); found {
+				name = c
+			}
+		}
+		colls = append(colls, name)
+	}
+	for _, e := range pc.Doc {
+		if e.Key == "pipeline" {
+			colls = append(colls, pipelineCollections(e.Value)...)
+		}
+	}
+	return colls
+}
+
+// pipelineCollections 递归查找 pipeline 引用的集合
+func pipelineCollections(pipeline interface{}) []string {
+	stages, ok := pipeline.(bson.A)
+	if !ok {
+		return nil
+	}
+	var colls []string
+	for _, stage := range stages {
+		sd, ok := stage.(bson.D)
+		if !ok {
+			continue
+		}
+		for _, se := range sd {
+			switch v := se.Value.(type) {
+			case string:
+				// {"$unionWith": "c1"}
+				if se.Key == "$unionWith" {
+					colls = append(colls, v)
+				}
+			case bson.D:
+				for _, opt := range v {
+					switch opt.Key {
+					case "from", "coll":
+						if c, ok := opt.Value.(string); ok {
+							colls = append(colls, c)
+						}
+					case "pipeline":
+						colls = append(colls, pipelineCollections(opt.Value)...)
+					default:
+						// $facet 的每个字段都是 pipeline
+						if se.Key == "$facet" {
+							colls = append(colls, pipelineCollections(opt.Value)...)
+						}
+					}
+				}
+			}
+		}
+	}
+	return colls
+}
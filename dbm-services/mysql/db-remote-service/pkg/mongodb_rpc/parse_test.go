@@ -0,0 +1,191 @@
+package mongodb_rpc
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"dbm-services/mysql/db-remote-service/pkg/policy"
+
+	"github.com/pkg/errors"
+)
+
+func TestParseCommand(t *testing.T) {
+	cases := []struct {
+		command string
+		name    string
+		db      string
+		fields  int
+		fail    bool
+	}{
+		{`{"serverStatus": 1}`, "serverstatus", "admin", 1, false},
+		{`{"collStats": "c1", "$db": "db1"}`, "collstats", "db1", 1, false},
+		// $db 可以在任意位置, 不计入命令文档
+		{`{"$db": "db1", "find": "c1", "filter": {"a": 1}}`, "find", "db1", 2, false},
+		{`{"find": "c1", "limit": {"$numberLong": "10"}}`, "find", "admin", 2, false},
+		{`{"$db": "db1"}`, "", "", 0, true},
+		{`{"find": "c1", "$db": ""}`, "", "", 0, true},
+		{`{"find": "c1", "$db": 1}`, "", "", 0, true},
+		{`{}`, "", "", 0, true},
+		{`db.c1.find()`, "", "", 0, true},
+		{`["find", "c1"]`, "", "", 0, true},
+	}
+	for _, c := range cases {
+		pc, err := parseCommand(c.command)
+		if (err != nil) != c.fail {
+			t.Errorf("parseCommand(%s) err %v, want fail %v", c.command, err, c.fail)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if pc.Name != c.name || pc.DB != c.db || len(pc.Doc) != c.fields {
+			t.Errorf("parseCommand(%s) = %s %s %v, want %s %s %d fields",
+				c.command, pc.Name, pc.DB, pc.Doc, c.name, c.db, c.fields)
+		}
+		for _, e := range pc.Doc {
+			if e.Key == dbField {
+				t.Errorf("parseCommand(%s) should remove %s from command", c.command, dbField)
+			}
+		}
+	}
+}
+
+func TestCommandKind(t *testing.T) {
+	cases := []struct {
+		command string
+		query   bool
+		execute bool
+		cursor  bool
+	}{
+		{`{"find": "c1"}`, true, false, true},
+		{`{"FIND": "c1"}`, true, false, true},
+		{`{"count": "c1"}`, true, false, false},
+		{`{"listIndexes": "c1"}`, true, false, true},
+		{`{"aggregate": "c1", "pipeline": [{"$match": {}}], "cursor": {}}`, true, false, true},
+		{`{"aggregate": "c1", "pipeline": [{"$out": "c2"}], "cursor": {}}`, false, false, true},
+		{`{"killOp": 1, "op": 123}`, false, true, false},
+		{`{"setParameter": 1, "logLevel": 1}`, false, true, false},
+		{`{"insert": "c1", "documents": [{}]}`, false, false, false},
+		{`{"dropDatabase": 1}`, false, false, false},
+		// 只有命令名字段决定命令, 后面的字段不影响
+		{`{"ping": 1, "find": "c1"}`, true, false, false},
+	}
+	for _, c := range cases {
+		pc, err := parseCommand(c.command)
+		if err != nil {
+			t.Fatalf("parseCommand(%s): %v", c.command, err)
+		}
+		if isQueryCommand(pc) != c.query || isExecuteCommand(pc) != c.execute || isCursorCommand(pc) != c.cursor {
+			t.Errorf("%s: query %v execute %v cursor %v, want %v %v %v", c.command,
+				isQueryCommand(pc), isExecuteCommand(pc), isCursorCommand(pc), c.query, c.execute, c.cursor)
+		}
+	}
+}
+
+func TestHasWriteStage(t *testing.T) {
+	cases := []struct {
+		command string
+		want    bool
+	}{
+		{`{"aggregate": "c1", "pipeline": []}`, false},
+		{`{"aggregate": "c1", "pipeline": [{"$match": {"a": 1}}, {"$group": {"_id": "$a"}}]}`, false},
+		{`{"aggregate": "c1"}`, false},
+		{`{"aggregate": "c1", "pipeline": [{"$match": {}}, {"$out": "c2"}]}`, true},
+		{`{"aggregate": "c1", "pipeline": [{"$merge": {"into": "c2"}}]}`, true},
+		// 字段值里出现 $out 不算
+		{`{"aggregate": "c1", "pipeline": [{"$match": {"k": "$out"}}]}`, false},
+		// pipeline 格式不对时不当作只读
+		{`{"aggregate": "c1", "pipeline": {"$out": "c2"}}`, true},
+		{`{"aggregate": "c1", "pipeline": "x"}`, true},
+		{`{"aggregate": "c1", "pipeline": [1]}`, true},
+		{`{"aggregate": "c1", "pipeline": [[{"$out": "c2"}]]}`, true},
+	}
+	for _, c := range cases {
+		pc, err := parseCommand(c.command)
+		if err != nil {
+			t.Fatalf("parseCommand(%s): %v", c.command, err)
+		}
+		if got := hasWriteStage(pc.Doc); got != c.want {
+			t.Errorf("hasWriteStage(%s) = %v, want %v", c.command, got, c.want)
+		}
+	}
+}
+
+func TestReferencedCollections(t *testing.T) {
+	cases := []struct {
+		command string
+		want    []string
+	}{
+		{`{"serverStatus": 1}`, nil},
+		{`{"find": "system.users", "$db": "admin"}`, []string{"system.users"}},
+		{`{"dataSize": "admin.system.users"}`, []string{"system.users"}},
+		{`{"aggregate": "c1", "pipeline": [{"$lookup": {"from": "system.users", "as": "u"}}]}`,
+			[]string{"c1", "system.users"}},
+		{`{"aggregate": "c1", "pipeline": [{"$unionWith": "system.roles"}]}`, []string{"c1", "system.roles"}},
+		{`{"aggregate": "c1", "pipeline": [{"$unionWith": {"coll": "c2", "pipeline": [
+			{"$lookup": {"from": "system.js", "pipeline": [], "as": "j"}}]}}]}`,
+			[]string{"c1", "c2", "system.js"}},
+		{`{"aggregate": 1, "pipeline": [{"$facet": {"a": [{"$lookup": {"from": "system.users", "as": "u"}}]}}]}`,
+			[]string{"system.users"}},
+	}
+	for _, c := range cases {
+		pc, err := parseCommand(c.command)
+		if err != nil {
+			t.Fatalf("parseCommand(%s): %v", c.command, err)
+		}
+		if got := referencedCollections(pc); !slices.Equal(got, c.want) {
+			t.Errorf("referencedCollections(%s) = %v, want %v", c.command, got, c.want)
+		}
+	}
+}
+
+func TestCheckSystemCollection(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "policy.yaml")
+	err := os.WriteFile(file, []byte(`
+rules:
+  - name: dba-system-collections
+    callers: [dba]
+    rpc_types: [mongodb]
+    allow_mongo_system_collections: true
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := policy.Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		policy  *policy.Policy
+		caller  string
+		command string
+		denied  bool
+	}{
+		{nil, "dba", `{"find": "c1", "$db": "db1"}`, false},
+		{nil, "dba", `{"find": "system.users", "$db": "admin"}`, true},
+		{nil, "dba", `{"aggregate": "c1", "pipeline": [{"$lookup": {"from": "system.users", "as": "u"}}]}`, true},
+		{p, "dba", `{"find": "system.users", "$db": "admin"}`, false},
+		{p, "other", `{"find": "system.users", "$db": "admin"}`, true},
+		// 匿名调用方不能用指定了 callers 的放行规则
+		{p, policy.AnonymousCaller, `{"find": "system.users", "$db": "admin"}`, true},
+		{p, "other", `{"listCollections": 1, "$db": "admin"}`, false},
+	}
+	defer func() {
+		policy.DefaultPolicy = nil
+	}()
+	for _, c := range cases {
+		policy.DefaultPolicy = c.policy
+		pc, err := parseCommand(c.command)
+		if err != nil {
+			t.Fatalf("parseCommand(%s): %v", c.command, err)
+		}
+		err = checkSystemCollection(pc, &policy.CheckRequest{Caller: c.caller, RPCType: "mongodb"})
+		var de *policy.DeniedError
+		if errors.As(err, &de) != c.denied {
+			t.Errorf("caller %s %s: err %v, want denied %v", c.caller, c.command, err, c.denied)
+		}
+	}
+}
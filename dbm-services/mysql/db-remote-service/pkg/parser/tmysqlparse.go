@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// parseTimeout 单条命令的解析超时
+const parseTimeout = 10 * time.Second
+
+// CommandType 用 tmysqlparse 解析单条 sql, 返回 tmysqlparse 的命令类型, 如 select, create_table
+// 语法错误或者包含多条语句时返回错误
+func CommandType(bin string, command string) (string, error) {
+	dir, err := os.MkdirTemp("", "drs-parse-")
+	if err != nil {
+		return "", errors.Wrap(err, "create parse dir")
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	in := filepath.Join(dir, "input.sql")
+	out := filepath.Join(dir, "output.json")
+	if err = os.WriteFile(in, []byte(command), 0600); err != nil {
+		return "", errors.Wrap(err, "write parse input")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), parseTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(
+		ctx, bin,
+		"--sql-file="+in, "--output-path="+out,
+		"--print-query-mode=2", "--output-format=JSON_LINE_PER_OBJECT",
+	).CombinedOutput()
+	if err != nil {
+		return "", errors.Wrapf(err, "run tmysqlparse: %s", string(output))
+	}
+
+	content, err := os.ReadFile(out)
+	if err != nil {
+		return "", errors.Wrap(err, "read tmysqlparse output")
+	}
+
+	var results []ParseQueryBase
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var r ParseQueryBase
+		if err = json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return "", errors.Wrap(err, "unmarshal tmysqlparse output")
+		}
+		// 末尾的分号会多解析出一条空语句
+		if r.Command == "empty_query" {
+			continue
+		}
+		results = append(results, r)
+	}
+	if err = scanner.Err(); err != nil {
+		return "", errors.Wrap(err, "read tmysqlparse output")
+	}
+
+	if len(results) != 1 {
+		return "", errors.Errorf("expect 1 statement, got %d", len(results))
+	}
+	if results[0].ErrorCode != 0 {
+		return "", errors.Errorf("tmysqlparse error %d: %s", results[0].ErrorCode, results[0].ErrorMsg)
+	}
+	return results[0].Command, nil
+}
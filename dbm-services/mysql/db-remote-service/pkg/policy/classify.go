@@ -0,0 +1,250 @@
+package policy
+
+import (
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+
+	"dbm-services/mysql/db-remote-service/pkg/parser"
+)
+
+// 命令分类
+const (
+	CategoryQuery = "query"
+	CategoryDML   = "dml"
+	CategoryDDL   = "ddl"
+	CategoryDCL   = "dcl"
+	CategoryKill  = "kill"
+	CategoryAdmin = "admin"
+	CategoryOther = "other"
+)
+
+// mysqlRPCTypes 这些 rpc 的命令是 mysql 语法, 用 tmysqlparse 分类
+var mysqlRPCTypes = []string{"mysql", "webconsole"}
+
+// parserBin tmysqlparse 路径, 见 Init
+var parserBin string
+
+// parseCommandType 返回 tmysqlparse 的命令类型
+var parseCommandType = func(command string) (string, error) {
+	return parser.CommandType(parserBin, command)
+}
+
+var spacePattern = regexp.MustCompile(`\s+`)
+
+var tdbctlExecutePattern = regexp.MustCompile(`(?mi)^.*execute\s+['"](.*)['"]$`)
+
+var versionCommentPattern = regexp.MustCompile(`^!\d*`)
+
+var queryWords = []string{"select", "show", "explain", "desc", "describe", "use", "help"}
+
+var dmlWords = []string{"insert", "update", "delete", "replace", "load", "call"}
+
+var ddlWords = []string{"create", "alter", "drop", "truncate", "rename"}
+
+var dclWords = []string{"grant", "revoke"}
+
+// ddl 关键字后面跟着这些时是账号权限操作
+var dclObjects = []string{"user", "role"}
+
+var adminWords = []string{
+	"flush", "reset", "change", "start", "stop", "slave", "purge", "set",
+	"lock", "unlock", "install", "uninstall", "optimize", "analyze", "repair", "check", "checksum",
+}
+
+// tmysqlparse 命令类型的分类, show_ 开头的都是 query, 不在这里的是 other
+var parsedCategories = map[string]string{
+	"select": CategoryQuery, "explain_other": CategoryQuery, "change_db": CategoryQuery, "help": CategoryQuery,
+	"do": CategoryQuery, "ha_open": CategoryQuery, "ha_read": CategoryQuery, "ha_close": CategoryQuery,
+
+	"insert": CategoryDML, "insert_select": CategoryDML, "update": CategoryDML, "update_multi": CategoryDML,
+	"delete": CategoryDML, "delete_multi": CategoryDML, "replace": CategoryDML, "replace_select": CategoryDML,
+	"load": CategoryDML, "call": CategoryDML,
+
+	"create_table": CategoryDDL, "alter_table": CategoryDDL, "drop_table": CategoryDDL, "rename_table": CategoryDDL,
+	"truncate": CategoryDDL, "create_index": CategoryDDL, "drop_index": CategoryDDL,
+	"create_db": CategoryDDL, "alter_db": CategoryDDL, "drop_db": CategoryDDL, "alter_db_upgrade": CategoryDDL,
+	"create_view": CategoryDDL, "drop_view": CategoryDDL,
+	"create_trigger": CategoryDDL, "drop_trigger": CategoryDDL,
+	"create_function": CategoryDDL, "create_spfunction": CategoryDDL, "alter_function": CategoryDDL,
+	"drop_function":    CategoryDDL,
+	"create_procedure": CategoryDDL, "alter_procedure": CategoryDDL, "drop_procedure": CategoryDDL,
+	"create_event": CategoryDDL, "alter_event": CategoryDDL, "drop_event": CategoryDDL,
+	"create_server": CategoryDDL, "alter_server": CategoryDDL, "drop_server": CategoryDDL,
+	"alter_tablespace": CategoryDDL, "create_compression_dictionary": CategoryDDL,
+	"drop_compression_dictionary": CategoryDDL,
+
+	"grant": CategoryDCL, "revoke": CategoryDCL, "revoke_all": CategoryDCL,
+	"create_user": CategoryDCL, "alter_user": CategoryDCL, "drop_user": CategoryDCL, "rename_user": CategoryDCL,
+
+	"kill": CategoryKill,
+
+	"set_option": CategoryAdmin, "flush": CategoryAdmin, "reset": CategoryAdmin, "purge": CategoryAdmin,
+	"purge_before": CategoryAdmin, "lock_tables": CategoryAdmin, "unlock_tables": CategoryAdmin,
+	"lock_tables_for_backup": CategoryAdmin, "lock_binlog_for_backup": CategoryAdmin, "unlock_binlog": CategoryAdmin,
+	"repair": CategoryAdmin, "optimize": CategoryAdmin, "check": CategoryAdmin, "analyze": CategoryAdmin,
+	"checksum": CategoryAdmin, "assign_to_keycache": CategoryAdmin, "preload_keys": CategoryAdmin,
+	"slave_start": CategoryAdmin, "slave_stop": CategoryAdmin, "change_master": CategoryAdmin,
+	"change_replication_filter": CategoryAdmin, "start_group_replication": CategoryAdmin,
+	"stop_group_replication": CategoryAdmin, "install_plugin": CategoryAdmin, "uninstall_plugin": CategoryAdmin,
+	"shutdown": CategoryAdmin, "alter_instance": CategoryAdmin,
+	"begin": CategoryAdmin, "commit": CategoryAdmin, "rollback": CategoryAdmin, "savepoint": CategoryAdmin,
+	"rollback_to_savepoint": CategoryAdmin, "release_savepoint": CategoryAdmin,
+	"xa_start": CategoryAdmin, "xa_end": CategoryAdmin, "xa_prepare": CategoryAdmin, "xa_commit": CategoryAdmin,
+	"xa_rollback": CategoryAdmin, "xa_recover": CategoryAdmin,
+}
+
+// Classify 给命令分类
+// mysql 语法的命令按 tmysqlparse 的解析结果分类, 解析失败为 other; 其他 rpc 按关键字分类
+func Classify(rpcType string, command string) string {
+	if !slices.Contains(mysqlRPCTypes, rpcType) {
+		return ClassifyKeyword(command)
+	}
+
+	words := splitWords(command)
+	if len(words) > 1 && words[0] == "tdbctl" {
+		return classifyTDBCTL(command, words[1], func(inner string) string {
+			return Classify(rpcType, inner)
+		})
+	}
+
+	commandType, err := parseCommandType(command)
+	if err != nil {
+		slog.Warn("classify command", slog.String("error", err.Error()), slog.String("command", command))
+		return CategoryOther
+	}
+	return classifyParsed(commandType, command)
+}
+
+func classifyParsed(commandType string, command string) string {
+	if strings.HasPrefix(commandType, "show_") {
+		return CategoryQuery
+	}
+	category, ok := parsedCategories[commandType]
+	if !ok {
+		return CategoryOther
+	}
+	// SET PASSWORD 也是 set_option
+	if commandType == "set_option" && ClassifyKeyword(command) == CategoryDCL {
+		return CategoryDCL
+	}
+	return category
+}
+
+// ClassifyKeyword 去掉注释后按前两个关键字给命令分类
+func ClassifyKeyword(command string) string {
+	words := splitWords(command)
+	if len(words) == 0 {
+		return CategoryOther
+	}
+
+	first := words[0]
+	var second string
+	if len(words) > 1 {
+		second = words[1]
+	}
+
+	switch {
+	case first == "tdbctl":
+		return classifyTDBCTL(command, second, ClassifyKeyword)
+	case slices.Contains(queryWords, first):
+		return CategoryQuery
+	case slices.Contains(dmlWords, first):
+		return CategoryDML
+	case slices.Contains(dclWords, first):
+		return CategoryDCL
+	case slices.Contains(ddlWords, first):
+		if slices.Contains(dclObjects, second) {
+			return CategoryDCL
+		}
+		return CategoryDDL
+	case first == "kill":
+		return CategoryKill
+	case first == "set" && second == "password":
+		return CategoryDCL
+	case slices.Contains(adminWords, first):
+		return CategoryAdmin
+	default:
+		return CategoryOther
+	}
+}
+
+func classifyTDBCTL(command string, second string, classify func(string) string) string {
+	switch second {
+	case "get", "show":
+		return CategoryQuery
+	case "connect":
+		m := tdbctlExecutePattern.FindStringSubmatch(command)
+		if len(m) == 2 {
+			return classify(m[1])
+		}
+	}
+	return CategoryAdmin
+}
+
+// splitWords 去掉注释后, 返回小写的前两个关键字
+func splitWords(command string) []string {
+	command = strings.TrimLeft(StripComments(command), "( \t\r\n")
+	var words []string
+	for _, w := range spacePattern.Split(strings.ToLower(command), 3) {
+		if w != "" {
+			words = append(words, strings.TrimRight(w, ";("))
+		}
+	}
+	if len(words) > 2 {
+		words = words[:2]
+	}
+	return words
+}
+
+// StripComments 去掉注释, 引号中的内容不处理
+// /*!50000 ... */ 这样的版本注释 mysql 会执行, 保留其中的内容
+func StripComments(command string) string {
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		if quote != 0 {
+			b.WriteByte(c)
+			if c == '\\' && quote != '`' && i+1 < len(command) {
+				i++
+				b.WriteByte(command[i])
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			b.WriteByte(c)
+		case c == '/' && strings.HasPrefix(command[i:], "/*"):
+			end := strings.Index(command[i+2:], "*/")
+			body := command[i+2:]
+			next := len(command)
+			if end >= 0 {
+				body = command[i+2 : i+2+end]
+				next = i + 2 + end + 2
+			}
+			if strings.HasPrefix(body, "!") {
+				b.WriteString(StripComments(versionCommentPattern.ReplaceAllString(body, "")))
+			}
+			b.WriteByte(' ')
+			i = next - 1
+		case c == '#' || (c == '-' && strings.HasPrefix(command[i:], "--") &&
+			(i+2 == len(command) || strings.ContainsRune(" \t\r\n", rune(command[i+2])))):
+			end := strings.IndexByte(command[i:], '\n')
+			if end < 0 {
+				i = len(command)
+			} else {
+				i += end - 1
+			}
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,148 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	parsed := map[string]string{
+		"select 1":                        "select",
+		"/* x */ drop table t":            "drop_table",
+		"set password for a = 'x'":        "set_option",
+		"set global read_only = 1":        "set_option",
+		"show databases":                  "show_databases",
+		"prepare s from 'drop table t'":   "prepare",
+		"create user a identified by 'x'": "create_user",
+		"/*!50000 drop */ table t":        "drop_table",
+		"kill 10":                         "kill",
+		"drop table t":                    "drop_table",
+		"insert into t select * from t2":  "insert_select",
+		"xa recover":                      "xa_recover",
+		"truncate table t":                "truncate",
+		"grant all on *.* to a":           "grant",
+		"alter user a identified by 'x'":  "alter_user",
+	}
+	old := parseCommandType
+	defer func() {
+		parseCommandType = old
+	}()
+	parseCommandType = func(command string) (string, error) {
+		if v := parsed[command]; v != "" {
+			return v, nil
+		}
+		return "", errors.New("syntax error")
+	}
+
+	cases := []struct {
+		rpcType string
+		command string
+		want    string
+	}{
+		{"mysql", "select 1", CategoryQuery},
+		{"mysql", "/* x */ drop table t", CategoryDDL},
+		{"mysql", "/*!50000 drop */ table t", CategoryDDL},
+		{"mysql", "set password for a = 'x'", CategoryDCL},
+		{"mysql", "set global read_only = 1", CategoryAdmin},
+		{"mysql", "show databases", CategoryQuery},
+		{"mysql", "prepare s from 'drop table t'", CategoryOther},
+		{"mysql", "create user a identified by 'x'", CategoryDCL},
+		{"mysql", "alter user a identified by 'x'", CategoryDCL},
+		{"mysql", "kill 10", CategoryKill},
+		{"mysql", "insert into t select * from t2", CategoryDML},
+		{"mysql", "xa recover", CategoryAdmin},
+		{"mysql", "truncate table t", CategoryDDL},
+		// 解析失败
+		{"mysql", "select 1 -- \ndrop table t", CategoryOther},
+		{"webconsole", "grant all on *.* to a", CategoryDCL},
+		{"webconsole", "tdbctl get routing", CategoryQuery},
+		{"webconsole", `tdbctl connect node SPT0 execute "select 1"`, CategoryQuery},
+		{"webconsole", `tdbctl connect node SPT0 execute "drop table t"`, CategoryDDL},
+		{"webconsole", "tdbctl flush routing", CategoryAdmin},
+		// 其他 rpc 按关键字
+		{"proxy-admin", "refresh_users('a', '+')", CategoryOther},
+		{"proxy-admin", "select * from backends", CategoryQuery},
+		{"sqlserver", "/* select */ drop table t", CategoryDDL},
+		{"sqlserver", "-- select\ndelete from t", CategoryDML},
+	}
+	for _, c := range cases {
+		if got := Classify(c.rpcType, c.command); got != c.want {
+			t.Errorf("Classify(%s, %q) = %s, want %s", c.rpcType, c.command, got, c.want)
+		}
+	}
+}
+
+func TestClassifyKeyword(t *testing.T) {
+	cases := []struct {
+		command string
+		want    string
+	}{
+		{"", CategoryOther},
+		{"  SELECT 1", CategoryQuery},
+		{"(select 1) union (select 2)", CategoryQuery},
+		{"desc t", CategoryQuery},
+		{"replace into t values (1)", CategoryDML},
+		{"create table t (id int)", CategoryDDL},
+		{"create user a", CategoryDCL},
+		{"drop role r", CategoryDCL},
+		{"revoke all on *.* from a", CategoryDCL},
+		{"kill query 1", CategoryKill},
+		{"set password = 'x'", CategoryDCL},
+		{"set names utf8", CategoryAdmin},
+		{"/* hide */ drop table t", CategoryDDL},
+		{"# hide\ndrop table t", CategoryDDL},
+		{"/*!40101 drop */ table t", CategoryDDL},
+		{"handler t open", CategoryOther},
+	}
+	for _, c := range cases {
+		if got := ClassifyKeyword(c.command); got != c.want {
+			t.Errorf("ClassifyKeyword(%q) = %s, want %s", c.command, got, c.want)
+		}
+	}
+}
+
+func TestStripComments(t *testing.T) {
+	cases := []struct {
+		command string
+		want    string
+	}{
+		{"select 1", "select 1"},
+		{"/* a */select 1", " select 1"},
+		{"select 1 -- a", "select 1  "},
+		{"select 1 --a", "select 1 --a"},
+		{"select 1 # a\n, 2", "select 1  \n, 2"},
+		{"select '/* a */', \"# b\", `-- c`", "select '/* a */', \"# b\", `-- c`"},
+		{"select 'it\\'s /* a */'", "select 'it\\'s /* a */'"},
+		{"/*!50000 select */ 1", " select   1"},
+		{"/*!select 1*/", "select 1 "},
+		{"select 1 /* unclosed", "select 1  "},
+	}
+	for _, c := range cases {
+		if got := StripComments(c.command); got != c.want {
+			t.Errorf("StripComments(%q) = %q, want %q", c.command, got, c.want)
+		}
+	}
+}
+
+func TestKillPattern(t *testing.T) {
+	cases := []struct {
+		command string
+		id      string
+	}{
+		{"kill 10", "10"},
+		{"KILL QUERY 10;", "10"},
+		{"/* x */ kill connection 10", "10"},
+		{"kill /* x */ 10", "10"},
+		{"kill 10 -- x", "10"},
+		{"kill @id", ""},
+	}
+	for _, c := range cases {
+		var id string
+		if m := killPattern.FindStringSubmatch(StripComments(c.command)); len(m) == 2 {
+			id = m[1]
+		}
+		if id != c.id {
+			t.Errorf("kill id of %q = %q, want %q", c.command, id, c.id)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+// Package policy 执行前的策略检查
+package policy
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule 一条策略, 匹配条件都满足时检查各项限制, 任一限制不通过就拒绝
+// 匹配条件为空表示匹配全部
+type Rule struct {
+	Name         string   `yaml:"name"`
+	Callers      []string `yaml:"callers"`
+	RPCTypes     []string `yaml:"rpc_types"`
+	ClusterTypes []string `yaml:"cluster_types"`
+
+	// 拒绝这些分类的命令, 见 Classify
+	DenyCategories []string `yaml:"deny_categories"`
+	// 拒绝匹配正则的命令, 不区分大小写
+	DenyPattern string `yaml:"deny_pattern"`
+	// > 0 时, 不带 LIMIT 的 SELECT 预估扫描行数超过该值则拒绝
+	SelectLimitTableRows int64 `yaml:"select_limit_table_rows"`
+	// 拒绝 KILL 系统线程
+	DenyKillSystemThread bool `yaml:"deny_kill_system_thread"`
+	// 系统线程的用户, 为空时用 defaultSystemUsers
+	SystemUsers []string `yaml:"system_users"`
+	// 允许访问 mongodb 的 system.* 集合, 默认拒绝
+	AllowMongoSystemCollections bool `yaml:"allow_mongo_system_collections"`
+
+	denyPattern *regexp.Regexp
+}
+
+// AnonymousCaller 没有通过 token 校验的调用方, 匹配所有指定了 callers 的规则
+const AnonymousCaller = "<anonymous>"
+
+// Policy 策略配置文件
+type Policy struct {
+	Rules []*Rule `yaml:"rules"`
+}
+
+// CheckRequest 待检查的命令
+type CheckRequest struct {
+	Caller      string
+	RPCType     string
+	ClusterType string
+	Command     string
+	// 命令分类, 见 Classify; 为空时不做分类相关的检查
+	Category string
+	// 需要查询实例的检查会用到, 为 nil 时跳过这些检查
+	Conn    *sqlx.Conn
+	Timeout time.Duration
+}
+
+// DeniedError 被策略拒绝
+type DeniedError struct {
+	Rule   string
+	Reason string
+}
+
+// Error 实现 error
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("denied by policy %s: %s", e.Rule, e.Reason)
+}
+
+var defaultSystemUsers = []string{"system user", "event_scheduler"}
+
+// 这些线程不管是什么用户都不能 kill
+var systemThreadCommands = []string{"Binlog Dump", "Binlog Dump GTID", "Daemon"}
+
+var killPattern = regexp.MustCompile(`(?i)^\s*kill\s+(?:connection\s+|query\s+)?(\d+)\s*;?\s*$`)
+
+var limitPattern = regexp.MustCompile(`(?is)\blimit\s+\d+(\s*,\s*\d+)?(\s+offset\s+\d+)?\s*;?\s*$`)
+
+// DefaultPolicy 全局策略, 为 nil 时不检查
+var DefaultPolicy *Policy
+
+// Init 加载全局策略, file 为空时不启用
+// 启用时 mysql 命令用 tmysqlparse 分类, bin 必须存在
+func Init(file string, bin string) error {
+	if file == "" {
+		slog.Info("policy disabled")
+		return nil
+	}
+
+	if _, err := os.Stat(bin); err != nil {
+		return errors.Wrap(err, "tmysqlparse required by policy")
+	}
+	parserBin = bin
+
+	p, err := Load(file)
+	if err != nil {
+		return err
+	}
+	DefaultPolicy = p
+	slog.Info("policy loaded", slog.String("file", file), slog.Int("rules", len(p.Rules)))
+	return nil
+}
+
+// Load 加载策略文件
+func Load(file string) (*Policy, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "read policy file")
+	}
+
+	var p Policy
+	err = yaml.Unmarshal(b, &p)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse policy file")
+	}
+
+	for idx, r := range p.Rules {
+		if r.Name == "" {
+			return nil, errors.Errorf("rules[%d] name required", idx)
+		}
+		if r.DenyPattern != "" {
+			r.denyPattern, err = regexp.Compile(`(?i)` + r.DenyPattern)
+			if err != nil {
+				return nil, errors.Wrapf(err, "rule %s deny_pattern", r.Name)
+			}
+		}
+		if len(r.SystemUsers) == 0 {
+			r.SystemUsers = defaultSystemUsers
+		}
+	}
+	return &p, nil
+}
+
+// Check 检查全局策略
+func Check(req *CheckRequest) error {
+	if DefaultPolicy == nil {
+		return nil
+	}
+	return DefaultPolicy.Check(req)
+}
+
+// Check 依次检查所有匹配的规则
+func (p *Policy) Check(req *CheckRequest) error {
+	for _, r := range p.Rules {
+		if !r.match(req) {
+			continue
+		}
+
+		if reason := r.check(req); reason != "" {
+			slog.Warn(
+				"command denied",
+				slog.String("rule", r.Name),
+				slog.String("reason", reason),
+				slog.String("caller", req.Caller),
+				slog.String("command", req.Command),
+			)
+			return &DeniedError{Rule: r.Name, Reason: reason}
+		}
+	}
+	return nil
+}
+
+// AllowMongoSystemCollections 是否有匹配的规则允许访问 mongodb 的 system.* 集合
+// 放行不能因为调用方未校验而扩大, 匿名调用方只匹配没有指定 callers 的规则
+func AllowMongoSystemCollections(req *CheckRequest) bool {
+	if DefaultPolicy == nil {
+		return false
+	}
+	for _, r := range DefaultPolicy.Rules {
+		if r.AllowMongoSystemCollections && r.matchStrict(req) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchStrict(req *CheckRequest) bool {
+	return matchOrEmpty(r.Callers, req.Caller) &&
+		matchOrEmpty(r.RPCTypes, req.RPCType) &&
+		matchOrEmpty(r.ClusterTypes, req.ClusterType)
+}
+
+func (r *Rule) match(req *CheckRequest) bool {
+	return (req.Caller == AnonymousCaller || matchOrEmpty(r.Callers, req.Caller)) &&
+		matchOrEmpty(r.RPCTypes, req.RPCType) &&
+		matchOrEmpty(r.ClusterTypes, req.ClusterType)
+}
+
+func matchOrEmpty(list []string, v string) bool {
+	return len(list) == 0 || slices.Contains(list, v)
+}
+
+// check 返回拒绝原因, 通过时为空
+// 有拒绝条件的规则遇到无法分类的命令直接拒绝
+func (r *Rule) check(req *CheckRequest) string {
+	category := req.Category
+	if category == CategoryOther && (len(r.DenyCategories) > 0 || r.denyPattern != nil) {
+		return "can not classify command"
+	}
+
+	if slices.Contains(r.DenyCategories, category) {
+		return fmt.Sprintf("%s command not allowed", category)
+	}
+
+	// 注释里可以放任意内容, 同时匹配原命令和去掉注释的命令
+	if r.denyPattern != nil &&
+		(r.denyPattern.MatchString(req.Command) || r.denyPattern.MatchString(StripComments(req.Command))) {
+		return fmt.Sprintf("command match %s", r.DenyPattern)
+	}
+
+	if req.Conn == nil {
+		return ""
+	}
+
+	if r.SelectLimitTableRows > 0 && category == CategoryQuery {
+		if reason := r.checkSelectLimit(req); reason != "" {
+			return reason
+		}
+	}
+
+	if r.DenyKillSystemThread && category == CategoryKill {
+		if reason := r.checkKill(req); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+// checkSelectLimit 用 explain 的预估行数判断
+func (r *Rule) checkSelectLimit(req *CheckRequest) string {
+	words := splitWords(req.Command)
+	if len(words) == 0 || words[0] != "select" || limitPattern.MatchString(StripComments(req.Command)) {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), req.Timeout)
+	defer cancel()
+
+	rows, err := req.Conn.QueryxContext(ctx, "EXPLAIN "+req.Command)
+	if err != nil {
+		// explain 失败时无法判断, 拒绝
+		return fmt.Sprintf("explain failed: %s", err.Error())
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var maxRows int64
+	for rows.Next() {
+		data := make(map[string]interface{})
+		if err := rows.MapScan(data); err != nil {
+			return fmt.Sprintf("explain failed: %s", err.Error())
+		}
+		var n int64
+		switch v := data["rows"].(type) {
+		case []byte:
+			_, _ = fmt.Sscan(string(v), &n)
+		case int64:
+			n = v
+		}
+		maxRows = max(maxRows, n)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Sprintf("explain failed: %s", err.Error())
+	}
+
+	if maxRows > r.SelectLimitTableRows {
+		return fmt.Sprintf(
+			"select without limit scan about %d rows, more than %d",
+			maxRows, r.SelectLimitTableRows,
+		)
+	}
+	return ""
+}
+
+// checkKill 查询被 kill 线程的用户和状态
+func (r *Rule) checkKill(req *CheckRequest) string {
+	m := killPattern.FindStringSubmatch(StripComments(req.Command))
+	if len(m) != 2 {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), req.Timeout)
+	defer cancel()
+
+	var user, command string
+	// m[1] 只有数字, 直接拼接, 不走 prepare
+	err := req.Conn.QueryRowxContext(
+		ctx,
+		`SELECT IFNULL(USER, ''), IFNULL(COMMAND, '') FROM information_schema.PROCESSLIST WHERE ID = `+m[1],
+	).Scan(&user, &command)
+	if errors.Is(err, sql.ErrNoRows) {
+		// 线程不存在时交给 kill 自己报错
+		return ""
+	}
+	if err != nil {
+		return fmt.Sprintf("query processlist failed: %s", err.Error())
+	}
+
+	if slices.Contains(r.SystemUsers, user) || slices.Contains(systemThreadCommands, command) {
+		return fmt.Sprintf("thread %s is system thread, user: %s, command: %s", m[1], user, command)
+	}
+	return ""
+}
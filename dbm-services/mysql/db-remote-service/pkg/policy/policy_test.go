@@ -0,0 +1,110 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func loadRules(t *testing.T, content string) *Policy {
+	file := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestRuleCheck(t *testing.T) {
+	p := loadRules(t, `
+rules:
+  - name: no-ddl
+    deny_categories: [ddl]
+  - name: no-drop-database
+    deny_pattern: "^\\s*drop\\s+database"
+  - name: select-limit
+    select_limit_table_rows: 10
+`)
+	noDDL, noDrop, selectLimit := p.Rules[0], p.Rules[1], p.Rules[2]
+
+	cases := []struct {
+		rule     *Rule
+		command  string
+		category string
+		denied   bool
+	}{
+		{noDDL, "select 1", CategoryQuery, false},
+		{noDDL, "create table t (id int)", CategoryDDL, true},
+		{noDDL, "prepare s from 'drop table t'", CategoryOther, true},
+		// mongodb 不分类
+		{noDDL, "db.dropDatabase()", "", false},
+		{noDrop, "DROP DATABASE db1", CategoryDDL, true},
+		{noDrop, "/* x */ drop database db1", CategoryDDL, true},
+		{noDrop, "drop table t", CategoryDDL, false},
+		{noDrop, "handler t open", CategoryOther, true},
+		{noDrop, "db.dropDatabase()", "", false},
+		// 没有拒绝条件的规则不因为无法分类拒绝
+		{selectLimit, "handler t open", CategoryOther, false},
+		// 没有连接时跳过需要查询实例的检查
+		{selectLimit, "select * from t", CategoryQuery, false},
+	}
+	for _, c := range cases {
+		reason := c.rule.check(&CheckRequest{Command: c.command, Category: c.category})
+		if (reason != "") != c.denied {
+			t.Errorf("rule %s check %q: reason %q, want denied %v", c.rule.Name, c.command, reason, c.denied)
+		}
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	p := loadRules(t, `
+rules:
+  - name: webconsole-no-dml
+    callers: [bkdbm]
+    rpc_types: [webconsole]
+    deny_categories: [dml]
+`)
+	cases := []struct {
+		caller  string
+		rpcType string
+		denied  bool
+	}{
+		{"bkdbm", "webconsole", true},
+		{"other", "webconsole", false},
+		// 未校验的调用方不能绕过指定了 callers 的规则
+		{AnonymousCaller, "webconsole", true},
+		{AnonymousCaller, "mysql", false},
+		{"bkdbm", "mysql", false},
+	}
+	for _, c := range cases {
+		err := p.Check(&CheckRequest{
+			Caller: c.caller, RPCType: c.rpcType, Command: "delete from t", Category: CategoryDML,
+		})
+		if (err != nil) != c.denied {
+			t.Errorf("caller %q rpc %s: err %v, want denied %v", c.caller, c.rpcType, err, c.denied)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "policy.yaml")
+	for _, content := range []string{
+		"rules:\n  - deny_categories: [ddl]\n",
+		"rules:\n  - name: bad\n    deny_pattern: \"(\"\n",
+	} {
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(file); err == nil {
+			t.Errorf("load %q should fail", content)
+		}
+	}
+
+	p := loadRules(t, "rules:\n  - name: kill\n    deny_kill_system_thread: true\n")
+	if len(p.Rules[0].SystemUsers) != len(defaultSystemUsers) {
+		t.Errorf("default system users not set: %v", p.Rules[0].SystemUsers)
+	}
+}
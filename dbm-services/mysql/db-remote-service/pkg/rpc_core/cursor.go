@@ -0,0 +1,123 @@
+package rpc_core
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// pageCursor 分页游标, 不在服务端保存状态
+// 记录上一页最后一行的排序键, 下一页按 order_by 从这一行之后继续, 翻页期间有写入也不会重复或者跳过
+type pageCursor struct {
+	Digest string   `json:"digest"`
+	After  []string `json:"after"`
+}
+
+// requestDigest 游标只能用于相同的地址, 命令和排序键
+func requestDigest(addresses []string, commands []string, orderBy []string) string {
+	h := sha1.New()
+	for _, l := range [][]string{addresses, commands, orderBy} {
+		h.Write([]byte(strings.Join(l, "\x00")))
+		h.Write([]byte{0xff})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// EncodeCursor 生成游标
+func EncodeCursor(addresses []string, commands []string, orderBy []string, after []string) string {
+	b, _ := json.Marshal(
+		pageCursor{
+			Digest: requestDigest(addresses, commands, orderBy),
+			After:  after,
+		},
+	)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor 解析游标, 返回上一页最后一行的排序键
+func DecodeCursor(cursor string, addresses []string, commands []string, orderBy []string) ([]string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid cursor")
+	}
+
+	var pc pageCursor
+	if err = json.Unmarshal(b, &pc); err != nil {
+		return nil, errors.Wrap(err, "invalid cursor")
+	}
+	if pc.Digest != requestDigest(addresses, commands, orderBy) {
+		return nil, errors.Errorf("cursor not match addresses, cmds and order_by")
+	}
+	if len(pc.After) != len(orderBy) {
+		return nil, errors.Errorf("invalid cursor keys %v", pc.After)
+	}
+	return pc.After, nil
+}
+
+func quoteIdent(name string) string {
+	return fmt.Sprintf("`%s`", strings.ReplaceAll(name, "`", "``"))
+}
+
+// pageQuery 把查询包成子查询, 按 orderBy 排序后取 after 之后的 limit+1 行, 多取的一行用来判断是否还有下一页
+// (a, b) > (?, ?) 展开成 a > ? OR (a = ? AND b > ?), 这样才能用上索引
+func pageQuery(command string, orderBy []string, after []string, limit int) (string, []interface{}) {
+	var cols []string
+	for _, col := range orderBy {
+		cols = append(cols, quoteIdent(col))
+	}
+
+	query := fmt.Sprintf(
+		"SELECT * FROM (%s) AS drs_page",
+		strings.TrimRight(strings.TrimSpace(command), "; \t\r\n"),
+	)
+	var args []interface{}
+	if after != nil {
+		var ors []string
+		for i := range cols {
+			var ands []string
+			for j := 0; j < i; j++ {
+				ands = append(ands, fmt.Sprintf("%s = ?", cols[j]))
+				args = append(args, after[j])
+			}
+			ands = append(ands, fmt.Sprintf("%s > ?", cols[i]))
+			args = append(args, after[i])
+			ors = append(ors, fmt.Sprintf("(%s)", strings.Join(ands, " AND ")))
+		}
+		query = fmt.Sprintf("%s WHERE %s", query, strings.Join(ors, " OR "))
+	}
+	return fmt.Sprintf("%s ORDER BY %s LIMIT %d", query, strings.Join(cols, ", "), limit+1), args
+}
+
+// rowKeys 取出一行的排序键, 排序键为 NULL 时无法翻页
+func rowKeys(row map[string]interface{}, orderBy []string) ([]string, error) {
+	var keys []string
+	for _, col := range orderBy {
+		switch v := row[col].(type) {
+		case nil:
+			return nil, errors.Errorf("order_by column %s is null or not selected, can not paginate", col)
+		case string:
+			keys = append(keys, v)
+		case time.Time:
+			keys = append(keys, v.Format("2006-01-02 15:04:05.999999"))
+		default:
+			keys = append(keys, fmt.Sprintf("%v", v))
+		}
+	}
+	return keys, nil
+}
+
+// nextCursor 分页模式下查询还有剩余行时返回下一页游标
+func (c *RPCWrapper) nextCursor(res []cmdResult) string {
+	for _, r := range res {
+		if r.nextCursor != "" {
+			return r.nextCursor
+		}
+	}
+	return ""
+}
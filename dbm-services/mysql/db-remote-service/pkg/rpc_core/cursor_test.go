@@ -0,0 +1,73 @@
+package rpc_core
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPageQuery(t *testing.T) {
+	cases := []struct {
+		orderBy []string
+		after   []string
+		query   string
+		args    []interface{}
+	}{
+		{
+			[]string{"id"}, nil,
+			"SELECT * FROM (select * from t) AS drs_page ORDER BY `id` LIMIT 11", nil,
+		},
+		{
+			[]string{"id"}, []string{"5"},
+			"SELECT * FROM (select * from t) AS drs_page WHERE (`id` > ?) ORDER BY `id` LIMIT 11",
+			[]interface{}{"5"},
+		},
+		{
+			[]string{"a", "b"}, []string{"x", "2"},
+			"SELECT * FROM (select * from t) AS drs_page WHERE (`a` > ?) OR (`a` = ? AND `b` > ?) ORDER BY `a`, `b` LIMIT 11",
+			[]interface{}{"x", "x", "2"},
+		},
+	}
+	for _, c := range cases {
+		// 末尾的分号会被去掉
+		query, args := pageQuery("select * from t; ", c.orderBy, c.after, 10)
+		if query != c.query || !reflect.DeepEqual(args, c.args) {
+			t.Errorf("pageQuery(%v, %v) = %s %v, want %s %v", c.orderBy, c.after, query, args, c.query, c.args)
+		}
+	}
+}
+
+func TestRowKeys(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	row := map[string]interface{}{"id": int64(10), "name": "a", "ts": ts, "n": nil}
+
+	keys, err := rowKeys(row, []string{"id", "name", "ts"})
+	if err != nil || !reflect.DeepEqual(keys, []string{"10", "a", "2024-01-02 03:04:05"}) {
+		t.Errorf("rowKeys = %v, %v", keys, err)
+	}
+	if _, err = rowKeys(row, []string{"n"}); err == nil {
+		t.Error("null key should fail")
+	}
+	if _, err = rowKeys(row, []string{"missing"}); err == nil {
+		t.Error("missing key should fail")
+	}
+}
+
+func TestCursor(t *testing.T) {
+	addrs, cmds, orderBy := []string{"127.0.0.1:3306"}, []string{"select * from t"}, []string{"a", "b"}
+	cursor := EncodeCursor(addrs, cmds, orderBy, []string{"x,y", "2"})
+
+	after, err := DecodeCursor(cursor, addrs, cmds, orderBy)
+	if err != nil || !reflect.DeepEqual(after, []string{"x,y", "2"}) {
+		t.Fatalf("DecodeCursor = %v, %v", after, err)
+	}
+	if _, err = DecodeCursor(cursor, addrs, []string{"select * from t2"}, orderBy); err == nil {
+		t.Error("cursor of other command should fail")
+	}
+	if _, err = DecodeCursor(cursor, addrs, cmds, []string{"a"}); err == nil {
+		t.Error("cursor of other order_by should fail")
+	}
+	if _, err = DecodeCursor("!!", addrs, cmds, orderBy); err == nil {
+		t.Error("broken cursor should fail")
+	}
+}
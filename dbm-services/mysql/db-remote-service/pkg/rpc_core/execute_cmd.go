@@ -22,28 +22,35 @@ func executeCmd(conn *sqlx.Conn, cmd string, timeout time.Duration) (int64, erro
 	return result.RowsAffected()
 }
 
-// queryCmd TODO
-// func queryCmd(db *sqlx.DB, cmd string, timeout int) (tableDataType, error) {
-func queryCmd(conn *sqlx.Conn, cmd string, timeout time.Duration) (tableDataType, error) {
+// queryCmd 最多读取 limit 行, 逐行交给 onRow 处理
+// limit <= 0 时不限制; 还有未读取的行时 truncated 为 true
+func queryCmd(
+	conn *sqlx.Conn, cmd string, args []interface{}, timeout time.Duration,
+	limit int, onRow func(map[string]interface{}) error,
+) (truncated bool, err error) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	rows, err := conn.QueryxContext(ctx, cmd)
+	rows, err := conn.QueryxContext(ctx, cmd, args...)
 	if err != nil {
-		return nil, err
+		return false, err
 	}
 
 	defer func() {
 		_ = rows.Close()
 	}()
 
-	tableData := make(tableDataType, 0)
-
+	count := 0
 	for rows.Next() {
+		if limit > 0 && count >= limit {
+			truncated = true
+			break
+		}
+
 		data := make(map[string]interface{})
 		err := rows.MapScan(data)
 		if err != nil {
-			return nil, err
+			return false, err
 		}
 
 		slog.Debug("scan row map", slog.Any("map", data))
@@ -57,13 +64,15 @@ func queryCmd(conn *sqlx.Conn, cmd string, timeout time.Duration) (tableDataType
 				data[k] = string(value)
 			}
 		}
-		tableData = append(tableData, data)
-
+		if err = onRow(data); err != nil {
+			return false, err
+		}
+		count++
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return false, err
 	}
 
-	return tableData, nil
+	return truncated, nil
 }
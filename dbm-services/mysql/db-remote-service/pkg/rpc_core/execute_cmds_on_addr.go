@@ -5,10 +5,18 @@ import (
 	"log/slog"
 	"time"
 
+	"dbm-services/mysql/db-remote-service/pkg/audit"
+	"dbm-services/mysql/db-remote-service/pkg/parser"
+	"dbm-services/mysql/db-remote-service/pkg/policy"
+
+	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 )
 
-func (c *RPCWrapper) executeOneAddr(address string) (res []cmdResult, err error) {
+// executeOneAddr onRow 为 nil 时查询结果放在 TableData 中返回, 否则逐行交给 onRow
+func (c *RPCWrapper) executeOneAddr(
+	address string, onRow func(idx int, row map[string]interface{}) error,
+) (res []cmdResult, err error) {
 	db, err := c.MakeConnection(address, c.user, c.password, c.connectTimeout, c.timezone)
 
 	if err != nil {
@@ -39,74 +47,121 @@ func (c *RPCWrapper) executeOneAddr(address string) (res []cmdResult, err error)
 			return nil, err
 		}
 
-		if c.IsQueryCommand(pc) {
-			tableData, err := queryCmd(conn, command, time.Second*time.Duration(c.queryTimeout))
-			if err != nil {
-				slog.Error(
-					"query command",
-					slog.String("error", err.Error()),
-					slog.String("address", address), slog.String("command", command),
-				)
-				res = append(
-					res, cmdResult{
-						Cmd:          command,
-						RowsAffected: 0,
-						TableData:    nil,
-						ErrorMsg:     err.Error(),
-					},
-				)
-				if !c.force {
-					return res, err
-				}
-				continue
-			}
-			res = append(
-				res, cmdResult{
-					Cmd:          command,
-					TableData:    tableData,
-					RowsAffected: 0,
-					ErrorMsg:     "",
-				},
+		start := time.Now()
+		kind := c.commandKind(pc)
+
+		var cr cmdResult
+		var rowsReturned int
+		category := policy.Classify(c.caller.RPCType, command)
+		err = policy.Check(
+			&policy.CheckRequest{
+				Caller:      c.caller.Name,
+				RPCType:     c.caller.RPCType,
+				ClusterType: c.caller.ClusterType,
+				Command:     command,
+				Category:    category,
+				Conn:        conn,
+				Timeout:     time.Second * time.Duration(c.queryTimeout),
+			},
+		)
+		if err == nil {
+			cr, rowsReturned, err = c.executeOneCmd(conn, idx, pc, kind, onRow)
+		}
+		if err != nil {
+			slog.Error(
+				"run command",
+				slog.String("error", err.Error()),
+				slog.String("address", address), slog.String("command", command),
 			)
-		} else if c.IsExecuteCommand(pc) {
-			rowsAffected, err := executeCmd(conn, command, time.Second*time.Duration(c.queryTimeout))
-			if err != nil {
-				slog.Error(
-					"execute command",
-					slog.String("error", err.Error()),
-					slog.String("address", address), slog.String("command", command),
-				)
-				res = append(
-					res, cmdResult{
-						Cmd:          command,
-						TableData:    nil,
-						RowsAffected: 0,
-						ErrorMsg:     err.Error(),
-					},
-				)
-				if !c.force {
-					return res, err
+			cr = cmdResult{Cmd: command, TableData: nil, RowsAffected: 0, ErrorMsg: err.Error()}
+		}
+
+		c.audit(address, &cr, kind, category, rowsReturned, time.Since(start), err)
+		res = append(res, cr)
+
+		if err != nil && !c.force {
+			return res, err
+		}
+	}
+	return
+}
+
+func (c *RPCWrapper) commandKind(pc *parser.ParseQueryBase) string {
+	if c.IsQueryCommand(pc) {
+		return audit.KindQuery
+	} else if c.IsExecuteCommand(pc) {
+		return audit.KindExecute
+	}
+	return audit.KindUnsupported
+}
+
+func (c *RPCWrapper) executeOneCmd(
+	conn *sqlx.Conn, idx int, pc *parser.ParseQueryBase, kind string,
+	onRow func(idx int, row map[string]interface{}) error,
+) (cr cmdResult, rowsReturned int, err error) {
+	command := pc.Command
+	cr.Cmd = command
+
+	switch kind {
+	case audit.KindQuery:
+		if onRow == nil {
+			cr.TableData = make(tableDataType, 0)
+		}
+		query, args := command, []interface{}(nil)
+		if c.paginate {
+			query, args = pageQuery(command, c.orderBy, c.after, c.rowLimit)
+		}
+		var lastRow map[string]interface{}
+		cr.Truncated, err = queryCmd(
+			conn, query, args, time.Second*time.Duration(c.queryTimeout), c.rowLimit,
+			func(row map[string]interface{}) error {
+				rowsReturned++
+				lastRow = row
+				if onRow != nil {
+					return onRow(idx, row)
 				}
-				continue
-			}
-			res = append(
-				res, cmdResult{
-					Cmd:          command,
-					TableData:    nil,
-					RowsAffected: rowsAffected,
-					ErrorMsg:     "",
-				},
-			)
-		} else {
-			err = errors.Errorf("commands[%d]: %s not support", idx, command)
-			slog.Error("dispatch command", slog.String("error", err.Error()))
-			res = append(
-				res, cmdResult{Cmd: command, TableData: nil, RowsAffected: 0, ErrorMsg: err.Error()},
-			)
-			if !c.force {
-				return res, err
+				cr.TableData = append(cr.TableData, row)
+				return nil
+			},
+		)
+		if err == nil && c.paginate && cr.Truncated {
+			var after []string
+			after, err = rowKeys(lastRow, c.orderBy)
+			if err == nil {
+				cr.nextCursor = EncodeCursor(c.addresses, c.commands, c.orderBy, after)
 			}
 		}
+	case audit.KindExecute:
+		cr.RowsAffected, err = executeCmd(conn, command, time.Second*time.Duration(c.queryTimeout))
+	default:
+		err = errors.Errorf("commands[%d]: %s not support", idx, command)
 	}
-	return
+	return cr, rowsReturned, err
+}
+
+func (c *RPCWrapper) audit(
+	address string, cr *cmdResult, kind string, category string, rowsReturned int, duration time.Duration, err error,
+) {
+	r := &audit.Record{
+		Time:         time.Now(),
+		RequestId:    c.caller.RequestId,
+		Caller:       c.caller.Name,
+		ClientIP:     c.caller.ClientIP,
+		RPCType:      c.caller.RPCType,
+		ClusterType:  c.caller.ClusterType,
+		Address:      address,
+		Cmd:          cr.Cmd,
+		Kind:         kind,
+		Category:     category,
+		RowsAffected: cr.RowsAffected,
+		RowsReturned: rowsReturned,
+		DurationMs:   duration.Milliseconds(),
+		ErrorMsg:     cr.ErrorMsg,
+	}
+
+	var de *policy.DeniedError
+	if errors.As(err, &de) {
+		r.DeniedBy = de.Rule
+	}
+	audit.Append(r)
 }
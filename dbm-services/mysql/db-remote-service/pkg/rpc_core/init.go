@@ -6,11 +6,14 @@ type cmdResult struct {
 	Cmd          string        `json:"cmd"`
 	TableData    tableDataType `json:"table_data"`
 	RowsAffected int64         `json:"rows_affected"`
+	Truncated    bool          `json:"truncated"`
 	ErrorMsg     string        `json:"error_msg"`
+	nextCursor   string
 }
 
 type oneAddressResult struct {
 	Address    string      `json:"address"`
 	CmdResults []cmdResult `json:"cmd_results"`
 	ErrorMsg   string      `json:"error_msg"`
+	NextCursor string      `json:"next_cursor,omitempty"`
 }
@@ -1,5 +1,7 @@
 package rpc_core
 
+import "dbm-services/mysql/db-remote-service/pkg/audit"
+
 // RPCWrapper RPC 对象
 type RPCWrapper struct {
 	addresses      []string
@@ -10,6 +12,11 @@ type RPCWrapper struct {
 	queryTimeout   int
 	timezone       string
 	force          bool
+	rowLimit       int
+	paginate       bool
+	orderBy        []string
+	after          []string
+	caller         audit.Caller
 	RPCEmbedInterface
 }
 
@@ -37,3 +44,21 @@ func NewRPCWrapper(
 		RPCEmbedInterface: em,
 	}
 }
+
+// SetRowLimit 每个查询最多返回的行数, <= 0 不限制
+func (c *RPCWrapper) SetRowLimit(limit int) {
+	c.rowLimit = limit
+}
+
+// SetPage 分页查询, 按 orderBy 排序后返回 after 之后的 pageSize 行, after 为 nil 表示第一页
+func (c *RPCWrapper) SetPage(orderBy []string, after []string, pageSize int) {
+	c.paginate = true
+	c.orderBy = orderBy
+	c.after = after
+	c.rowLimit = pageSize
+}
+
+// SetCaller 请求来源, 用于策略检查和审计
+func (c *RPCWrapper) SetCaller(caller audit.Caller) {
+	c.caller = caller
+}
@@ -20,7 +20,7 @@ func (c *RPCWrapper) Run() (res []oneAddressResult) {
 		for _, address := range c.addresses {
 			tokenBulkChan <- struct{}{}
 			go func(address string) {
-				addrRes, err := c.executeOneAddr(address)
+				addrRes, err := c.executeOneAddr(address, nil)
 				<-tokenBulkChan
 
 				var errMsg string
@@ -31,6 +31,7 @@ func (c *RPCWrapper) Run() (res []oneAddressResult) {
 					Address:    address,
 					CmdResults: addrRes,
 					ErrorMsg:   errMsg,
+					NextCursor: c.nextCursor(addrRes),
 				}
 				wg.Done()
 			}(address)
@@ -0,0 +1,118 @@
+package rpc_core
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+
+	"dbm-services/mysql/db-remote-service/pkg/config"
+)
+
+// 每写这么多行刷新一次
+const streamFlushRows = 100
+
+const (
+	streamTypeRow    = "row"
+	streamTypeResult = "result"
+	streamTypeDone   = "done"
+)
+
+type streamRow struct {
+	Type     string                 `json:"type"`
+	Address  string                 `json:"address"`
+	CmdIndex int                    `json:"cmd_index"`
+	Row      map[string]interface{} `json:"row"`
+}
+
+type streamResult struct {
+	Type string `json:"type"`
+	oneAddressResult
+}
+
+type streamDone struct {
+	Type string `json:"type"`
+}
+
+type flusher interface {
+	Flush()
+}
+
+// streamWriter 多个地址并发写, 每条记录一行 json
+type streamWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	enc     *json.Encoder
+	pending int
+}
+
+func newStreamWriter(w io.Writer) *streamWriter {
+	return &streamWriter{w: w, enc: json.NewEncoder(w)}
+}
+
+func (s *streamWriter) write(v interface{}, flush bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.enc.Encode(v); err != nil {
+		return err
+	}
+	s.pending++
+	if flush || s.pending >= streamFlushRows {
+		if f, ok := s.w.(flusher); ok {
+			f.Flush()
+		}
+		s.pending = 0
+	}
+	return nil
+}
+
+// RunStream 流式执行, 查询结果逐行写入 w, 不在内存中保存
+// 每个地址执行完后写一条 result 记录, 全部结束后写一条 done 记录
+func (c *RPCWrapper) RunStream(w io.Writer) {
+	sw := newStreamWriter(w)
+	tokenBulkChan := make(chan struct{}, config.RuntimeConfig.Concurrent)
+
+	var wg sync.WaitGroup
+	wg.Add(len(c.addresses))
+	for _, address := range c.addresses {
+		tokenBulkChan <- struct{}{}
+		go func(address string) {
+			defer wg.Done()
+
+			addrRes, err := c.executeOneAddr(
+				address, func(idx int, row map[string]interface{}) error {
+					return sw.write(
+						streamRow{Type: streamTypeRow, Address: address, CmdIndex: idx, Row: row},
+						false,
+					)
+				},
+			)
+			<-tokenBulkChan
+
+			var errMsg string
+			if err != nil {
+				errMsg = err.Error()
+			}
+			err = sw.write(
+				streamResult{
+					Type: streamTypeResult,
+					oneAddressResult: oneAddressResult{
+						Address:    address,
+						CmdResults: addrRes,
+						ErrorMsg:   errMsg,
+					},
+				},
+				true,
+			)
+			if err != nil {
+				slog.Error("write stream result", slog.String("error", err.Error()), slog.String("address", address))
+			}
+		}(address)
+	}
+	wg.Wait()
+
+	if err := sw.write(streamDone{Type: streamTypeDone}, true); err != nil {
+		slog.Error("write stream done", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,88 @@
+// Package handler_audit 审计查询
+package handler_audit
+
+import (
+	"net/http"
+	"time"
+
+	"dbm-services/mysql/db-remote-service/pkg/audit"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultLimit = 1000
+	maxLimit     = 10000
+)
+
+type queryRequest struct {
+	StartTime string `form:"start_time"`
+	EndTime   string `form:"end_time"`
+	RequestId string `form:"request_id"`
+	Caller    string `form:"caller"`
+	Address   string `form:"address"`
+	RPCType   string `form:"rpc_type"`
+	Limit     int    `form:"limit"`
+}
+
+// QueryHandler 查询审计记录, 时间为 RFC3339 格式, 默认最近 1 小时
+func QueryHandler(c *gin.Context) {
+	if audit.DefaultStore == nil {
+		response(c, http.StatusBadRequest, nil, "audit disabled")
+		return
+	}
+
+	var req queryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response(c, http.StatusBadRequest, nil, err.Error())
+		return
+	}
+
+	q := &audit.Query{
+		Start:     time.Now().Add(-time.Hour),
+		RequestId: req.RequestId,
+		Caller:    req.Caller,
+		Address:   req.Address,
+		RPCType:   req.RPCType,
+		Limit:     req.Limit,
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultLimit
+	}
+	q.Limit = min(q.Limit, maxLimit)
+
+	var err error
+	if req.StartTime != "" {
+		if q.Start, err = time.Parse(time.RFC3339, req.StartTime); err != nil {
+			response(c, http.StatusBadRequest, nil, err.Error())
+			return
+		}
+	}
+	if req.EndTime != "" {
+		if q.End, err = time.Parse(time.RFC3339, req.EndTime); err != nil {
+			response(c, http.StatusBadRequest, nil, err.Error())
+			return
+		}
+	}
+
+	records, err := audit.DefaultStore.Query(q)
+	if err != nil {
+		response(c, http.StatusInternalServerError, nil, err.Error())
+		return
+	}
+	response(c, http.StatusOK, records, "")
+}
+
+func response(c *gin.Context, status int, data interface{}, msg string) {
+	code := 0
+	if status != http.StatusOK {
+		code = 1
+	}
+	c.JSON(
+		status, gin.H{
+			"code": code,
+			"data": data,
+			"msg":  msg,
+		},
+	)
+}
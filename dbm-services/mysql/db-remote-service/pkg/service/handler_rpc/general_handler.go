@@ -10,6 +10,7 @@ import (
 	"dbm-services/mysql/db-remote-service/pkg/rpc_core"
 
 	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
 )
 
 func generalHandler(rpcEmbed rpc_core.RPCEmbedInterface) func(*gin.Context) {
@@ -40,6 +41,8 @@ func generalHandler(rpcEmbed rpc_core.RPCEmbedInterface) func(*gin.Context) {
 			slog.Bool("force", req.Force),
 			slog.Int("connect_timeout", req.ConnectTimeout),
 			slog.Int("query_timeout", req.QueryTimeout),
+			slog.String("caller", c.GetHeader(CallerHeader)),
+			slog.String("cluster_type", req.ClusterType),
 		)
 		dupAddrs := findDuplicateAddresses(req.Addresses)
 		slog.Info("duplicate address", slog.String("addresses", strings.Join(dupAddrs, ",")))
@@ -52,6 +55,7 @@ func generalHandler(rpcEmbed rpc_core.RPCEmbedInterface) func(*gin.Context) {
 					"msg":  fmt.Sprintf("duplicate addresses %s", dupAddrs),
 				},
 			)
+			return
 		}
 
 		rpcWrapper := rpc_core.NewRPCWrapper(
@@ -60,6 +64,33 @@ func generalHandler(rpcEmbed rpc_core.RPCEmbedInterface) func(*gin.Context) {
 			req.ConnectTimeout, req.QueryTimeout, req.Timezone, req.Force,
 			rpcEmbed,
 		)
+		caller := newCaller(c, req.ClusterType)
+		rpcWrapper.SetCaller(caller)
+		c.Header("X-Request-Id", caller.RequestId)
+
+		if req.Stream {
+			if req.paginate() {
+				badRequest(c, "stream and pagination can not be used together")
+				return
+			}
+			// 流式返回不在内存中保存结果, 只按请求限制行数
+			rpcWrapper.SetRowLimit(req.MaxRows)
+			c.Header("Content-Type", "application/x-ndjson")
+			c.Status(http.StatusOK)
+			rpcWrapper.RunStream(c.Writer)
+			return
+		}
+
+		if req.paginate() {
+			after, err := checkPageRequest(&req, rpcEmbed)
+			if err != nil {
+				badRequest(c, err.Error())
+				return
+			}
+			rpcWrapper.SetPage(req.OrderBy, after, req.rowLimit())
+		} else {
+			rpcWrapper.SetRowLimit(req.rowLimit())
+		}
 
 		resp := rpcWrapper.Run()
 
@@ -72,3 +103,42 @@ func generalHandler(rpcEmbed rpc_core.RPCEmbedInterface) func(*gin.Context) {
 		)
 	}
 }
+
+func badRequest(c *gin.Context, msg string) {
+	c.JSON(
+		http.StatusBadRequest, gin.H{
+			"code": 1,
+			"data": "",
+			"msg":  msg,
+		},
+	)
+}
+
+// checkPageRequest 分页只支持单个地址上的单条 select, 按 order_by 指定的唯一键翻页
+func checkPageRequest(req *queryRequest, rpcEmbed rpc_core.RPCEmbedInterface) (after []string, err error) {
+	if len(req.Addresses) != 1 {
+		return nil, errors.Errorf("pagination only support one address")
+	}
+	if len(req.Cmds) != 1 {
+		return nil, errors.Errorf("pagination only support one command")
+	}
+	if req.PageSize <= 0 {
+		return nil, errors.Errorf("page_size is required for pagination")
+	}
+	if len(req.OrderBy) == 0 {
+		return nil, errors.Errorf("order_by is required for pagination")
+	}
+
+	pc, err := rpcEmbed.ParseCommand(req.Cmds[0])
+	if err != nil {
+		return nil, err
+	}
+	if !rpcEmbed.IsQueryCommand(pc) || !strings.HasPrefix(strings.ToLower(strings.TrimSpace(pc.Command)), "select") {
+		return nil, errors.Errorf("pagination only support select command: %s", req.Cmds[0])
+	}
+
+	if req.Cursor == "" {
+		return nil, nil
+	}
+	return rpc_core.DecodeCursor(req.Cursor, req.Addresses, req.Cmds, req.OrderBy)
+}
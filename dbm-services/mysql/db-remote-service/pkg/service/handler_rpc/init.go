@@ -1,9 +1,23 @@
 package handler_rpc
 
 import (
+	"crypto/subtle"
+	"log/slog"
 	"strings"
+
+	"dbm-services/mysql/db-remote-service/pkg/audit"
+	"dbm-services/mysql/db-remote-service/pkg/config"
+	"dbm-services/mysql/db-remote-service/pkg/policy"
+
+	"github.com/gin-gonic/gin"
 )
 
+// CallerHeader 调用方标识
+const CallerHeader = "X-Drs-Caller"
+
+// CallerTokenHeader 调用方 token, 和配置的 caller_tokens 一致时 CallerHeader 才生效
+const CallerTokenHeader = "X-Drs-Caller-Token"
+
 type queryRequest struct {
 	Addresses      []string `form:"addresses" json:"addresses" binding:"required"`
 	Cmds           []string `form:"cmds" json:"cmds" binding:"required"`
@@ -11,6 +25,12 @@ type queryRequest struct {
 	ConnectTimeout int      `form:"connect_timeout" json:"connect_timeout"`
 	QueryTimeout   int      `form:"query_timeout" json:"query_timeout"`
 	Timezone       string   `form:"time_zone" json:"time_zone"`
+	ClusterType    string   `form:"cluster_type" json:"cluster_type"`
+	MaxRows        int      `form:"max_rows" json:"max_rows"`
+	Stream         bool     `form:"stream" json:"stream"`
+	PageSize       int      `form:"page_size" json:"page_size"`
+	Cursor         string   `form:"cursor" json:"cursor"`
+	OrderBy        []string `form:"order_by" json:"order_by"`
 }
 
 // TrimSpace delete space around address
@@ -19,3 +39,45 @@ func (r *queryRequest) TrimSpace() {
 		r.Addresses[idx] = strings.TrimSpace(val)
 	}
 }
+
+// rowLimit 请求的 max_rows/page_size 不能超过配置的 max_rows
+func (r *queryRequest) rowLimit() int {
+	limit := config.RuntimeConfig.MaxRows
+	for _, n := range []int{r.MaxRows, r.PageSize} {
+		if n > 0 && (limit <= 0 || n < limit) {
+			limit = n
+		}
+	}
+	return limit
+}
+
+// paginate 分页模式
+func (r *queryRequest) paginate() bool {
+	return r.PageSize > 0 || r.Cursor != ""
+}
+
+// newCaller rpc 类型取自路由的第一段, 如 /webconsole/rpc 为 webconsole
+func newCaller(c *gin.Context, clusterType string) audit.Caller {
+	return audit.Caller{
+		RequestId:   audit.NewRequestId(),
+		Name:        verifiedCaller(c),
+		ClientIP:    c.ClientIP(),
+		RPCType:     strings.Split(strings.Trim(c.FullPath(), "/"), "/")[0],
+		ClusterType: clusterType,
+	}
+}
+
+// verifiedCaller 没有带调用方或 token 校验不通过时为 policy.AnonymousCaller, 匹配所有指定了 callers 的策略
+func verifiedCaller(c *gin.Context) string {
+	name := c.GetHeader(CallerHeader)
+	if name == "" {
+		return policy.AnonymousCaller
+	}
+
+	token, ok := config.RuntimeConfig.CallerTokens[name]
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(c.GetHeader(CallerTokenHeader))) != 1 {
+		slog.Warn("caller not verified", slog.String("caller", name), slog.String("client_ip", c.ClientIP()))
+		return policy.AnonymousCaller
+	}
+	return name
+}
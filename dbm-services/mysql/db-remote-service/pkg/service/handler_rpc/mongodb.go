@@ -0,0 +1,72 @@
+package handler_rpc
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"dbm-services/mysql/db-remote-service/pkg/mongodb_rpc"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MongoDBRPCHandler mongodb 请求响应
+func MongoDBRPCHandler(c *gin.Context) {
+	req := queryRequest{
+		ConnectTimeout: 2,
+		QueryTimeout:   600,
+		Force:          false,
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(
+			http.StatusBadRequest, gin.H{
+				"code": 1,
+				"data": "",
+				"msg":  err.Error(),
+			},
+		)
+		return
+	}
+	req.TrimSpace()
+
+	slog.Info(
+		"enter mongodb rpc handler",
+		slog.String("addresses", strings.Join(req.Addresses, ",")),
+		slog.String("cmds", strings.Join(req.Cmds, ",")),
+		slog.Bool("force", req.Force),
+		slog.Int("connect_timeout", req.ConnectTimeout),
+		slog.Int("query_timeout", req.QueryTimeout),
+	)
+
+	dupAddrs := findDuplicateAddresses(req.Addresses)
+	if len(dupAddrs) > 0 {
+		c.JSON(
+			http.StatusBadRequest, gin.H{
+				"code": 1,
+				"data": "",
+				"msg":  fmt.Sprintf("duplicate addresses %s", dupAddrs),
+			},
+		)
+		return
+	}
+
+	rpcWrapper := mongodb_rpc.NewMongoDBRPCWrapper(
+		req.Addresses, req.Cmds,
+		req.ConnectTimeout, req.QueryTimeout, req.Force,
+	)
+	caller := newCaller(c, req.ClusterType)
+	rpcWrapper.SetCaller(caller)
+	c.Header("X-Request-Id", caller.RequestId)
+
+	resp := rpcWrapper.Run()
+
+	c.JSON(
+		http.StatusOK, gin.H{
+			"code": 0,
+			"data": resp,
+			"msg":  "",
+		},
+	)
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"dbm-services/mysql/db-remote-service/pkg/service/handler_audit"
 	"dbm-services/mysql/db-remote-service/pkg/service/handler_rpc"
 
 	"github.com/gin-gonic/gin"
@@ -23,6 +24,12 @@ func RegisterRouter(engine *gin.Engine) {
 	sqlserverGroup := engine.Group("/sqlserver")
 	sqlserverGroup.POST("/rpc", handler_rpc.SqlserverRPCHandler)
 
+	mongodbGroup := engine.Group("/mongodb")
+	mongodbGroup.POST("/rpc", handler_rpc.MongoDBRPCHandler)
+
 	webConsoleGroup := engine.Group("/webconsole")
 	webConsoleGroup.POST("/rpc", handler_rpc.WebConsoleRPCHandler)
+
+	auditGroup := engine.Group("/audit")
+	auditGroup.GET("/records", handler_audit.QueryHandler)
 }
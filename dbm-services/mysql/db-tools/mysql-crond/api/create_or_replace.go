@@ -11,10 +11,20 @@ type JobDefine struct {
 	Name     string   `json:"name" validate:"required"`
 	Command  string   `json:"command" validate:"required"`
 	Args     []string `json:"args"`
-	Schedule string   `json:"schedule" validate:"required"`
+	Schedule string   `json:"schedule" validate:"required_without=After"`
 	Creator  string   `json:"creator"`
 	Enable   bool     `json:"enable"`
 	WorkDir  string   `json:"work_dir"`
+	// Timeout 单次运行超时, 如 30m
+	Timeout string `json:"timeout,omitempty"`
+	// ConcurrencyPolicy allow/forbid/replace
+	ConcurrencyPolicy string `json:"concurrency_policy,omitempty"`
+	// KillProcessGroup 超时或被替换时结束整个进程组
+	KillProcessGroup bool `json:"kill_process_group,omitempty"`
+	// After 上游任务, 上游运行结束后触发
+	After []string `json:"after,omitempty"`
+	// AfterCondition success/failure/always
+	AfterCondition string `json:"after_condition,omitempty"`
 }
 
 // CreateOrReplace TODO
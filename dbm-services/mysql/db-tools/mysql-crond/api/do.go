@@ -12,10 +12,14 @@ import (
 )
 
 func (m *Manager) do(action string, method string, payLoad interface{}) ([]byte, error) {
+	action, rawQuery, _ := strings.Cut(action, "?")
 	apiUrl, err := url.JoinPath(m.apiUrl, action)
 	if err != nil {
 		return nil, errors.Wrap(err, "join api url")
 	}
+	if rawQuery != "" {
+		apiUrl = apiUrl + "?" + rawQuery
+	}
 
 	body, err := json.Marshal(payLoad)
 	if err != nil {
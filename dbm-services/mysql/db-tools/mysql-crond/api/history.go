@@ -0,0 +1,34 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"dbm-services/mysql/db-tools/mysql-crond/pkg/history"
+
+	"github.com/pkg/errors"
+)
+
+// History 查询任务执行历史, name 为空查询全部任务
+func (m *Manager) History(name string, limit int) ([]*history.Record, error) {
+	q := url.Values{}
+	if name != "" {
+		q.Set("name", name)
+	}
+	q.Set("limit", fmt.Sprintf("%d", limit))
+	resp, err := m.do("/history?"+q.Encode(), "GET", nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "manager call /history")
+	}
+
+	var res struct {
+		History []*history.Record `json:"history"`
+	}
+	err = json.Unmarshal(resp, &res)
+	if err != nil {
+		return nil, errors.Wrap(err, "manager unmarshal /history response")
+	}
+
+	return res.History, nil
+}
@@ -31,14 +31,24 @@ var addJobCmd = &cobra.Command{
 			jobWorkDir, _ := cmd.Flags().GetString("work_dir")
 			jobCreator, _ := cmd.Flags().GetString("creator")
 			jobEnable, _ := cmd.Flags().GetBool("enable")
+			jobTimeout, _ := cmd.Flags().GetString("timeout")
+			jobPolicy, _ := cmd.Flags().GetString("concurrency_policy")
+			jobKillGroup, _ := cmd.Flags().GetBool("kill_process_group")
+			jobAfter, _ := cmd.Flags().GetStringSlice("after")
+			jobAfterCondition, _ := cmd.Flags().GetString("after_condition")
 			jobEntry = api.JobDefine{
-				Name:     jobName,
-				Command:  jobCommand,
-				Args:     jobArgs,
-				Schedule: jobSchedule,
-				WorkDir:  jobWorkDir,
-				Creator:  jobCreator,
-				Enable:   jobEnable,
+				Name:              jobName,
+				Command:           jobCommand,
+				Args:              jobArgs,
+				Schedule:          jobSchedule,
+				WorkDir:           jobWorkDir,
+				Creator:           jobCreator,
+				Enable:            jobEnable,
+				Timeout:           jobTimeout,
+				ConcurrencyPolicy: jobPolicy,
+				KillProcessGroup:  jobKillGroup,
+				After:             jobAfter,
+				AfterCondition:    jobAfterCondition,
 			}
 		}
 		return addEntry(jobEntry)
@@ -57,6 +67,11 @@ func init() {
 	addJobCmd.Flags().StringP("work_dir", "d", "", "work dir")
 	addJobCmd.Flags().StringP("creator", "r", "", "creator")
 	addJobCmd.Flags().BoolP("enable", "e", true, "enable")
+	addJobCmd.Flags().String("timeout", "", "timeout for one run, like 30m")
+	addJobCmd.Flags().String("concurrency_policy", "", "allow, forbid or replace, default forbid")
+	addJobCmd.Flags().Bool("kill_process_group", false, "kill the whole process group on timeout or replace")
+	addJobCmd.Flags().StringSlice("after", []string{}, "upstream job names, comma separate")
+	addJobCmd.Flags().String("after_condition", "", "success, failure or always, default success")
 	addJobCmd.Flags().String("body", "", "json body for api /create_or_replace")
 	addJobCmd.MarkFlagsMutuallyExclusive("command", "body")
 	addJobCmd.MarkFlagsMutuallyExclusive("name", "body")
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"dbm-services/mysql/db-tools/mysql-crond/api"
+	"dbm-services/mysql/db-tools/mysql-crond/pkg/config"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cast"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "list job run history",
+	Long:  `list job run history, include skipped and timeout runs`,
+	Run: func(cmd *cobra.Command, args []string) {
+		listHistory(cmd)
+	},
+}
+
+func init() {
+	historyCmd.PersistentFlags().StringP("config", "c", "", "config file")
+	_ = historyCmd.MarkPersistentFlagRequired("config")
+	_ = viper.BindPFlag("history-config", historyCmd.PersistentFlags().Lookup("config"))
+
+	historyCmd.Flags().StringP("name", "n", "", "job name, empty for all jobs")
+	historyCmd.Flags().IntP("limit", "l", 20, "max records to show")
+	historyCmd.Flags().Bool("output", false, "show stdout and stderr")
+
+	rootCmd.AddCommand(historyCmd)
+}
+
+func listHistory(cmd *cobra.Command) {
+	var err error
+	apiUrl := ""
+	if apiUrl, err = config.GetApiUrlFromConfig(viper.GetString("history-config")); err != nil {
+		fmt.Fprintln(os.Stderr, "read config error", err.Error())
+		os.Exit(1)
+	}
+
+	name, _ := cmd.Flags().GetString("name")
+	limit, _ := cmd.Flags().GetInt("limit")
+	showOutput, _ := cmd.Flags().GetBool("output")
+
+	manager := api.NewManager(apiUrl)
+	records, err := manager.History(name, limit)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "fail to list history", err.Error())
+		os.Exit(1)
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(true)
+	table.SetRowLine(true)
+	table.SetAutoFormatHeaders(false)
+
+	header := []string{"JobName", "StartTime", "Cost", "Status", "ExitCode", "Message"}
+	if showOutput {
+		header = append(header, "Stdout", "Stderr")
+	}
+	table.SetHeader(header)
+	for _, r := range records {
+		row := []string{
+			r.Name,
+			r.StartTime.Format("2006-01-02 15:04:05"),
+			r.EndTime.Sub(r.StartTime).Round(time.Millisecond).String(),
+			r.Status,
+			cast.ToString(r.ExitCode),
+			r.Message,
+		}
+		if showOutput {
+			row = append(row, strings.TrimSpace(r.Stdout), strings.TrimSpace(r.Stderr))
+		}
+		table.Append(row)
+	}
+
+	table.Render()
+}
@@ -54,7 +54,7 @@ func listEntries(cmd *cobra.Command) {
 	table.SetRowLine(true)
 	table.SetAutoFormatHeaders(false)
 
-	table.SetHeader([]string{"ID", "JobName", "Schedule", "Command", "Args", "WorkDir", "Enable"})
+	table.SetHeader([]string{"ID", "JobName", "Schedule", "Command", "Args", "WorkDir", "Enable", "After"})
 	for _, e := range entries {
 		table.Append([]string{
 			cast.ToString(e.ID),
@@ -63,8 +63,75 @@ func listEntries(cmd *cobra.Command) {
 			e.Job.Command,
 			strings.Join(e.Job.Args, " "),
 			e.Job.WorkDir,
-			cast.ToString(e.Job.Enable)})
+			cast.ToString(e.Job.Enable),
+			afterString(&e.Job)})
 	}
 
 	table.Render()
+	printDependencyGraph(entries)
+}
+
+func afterString(j *config.ExternalJob) string {
+	if len(j.After) == 0 {
+		return ""
+	}
+	condition := j.AfterCondition
+	if condition == "" {
+		condition = config.AfterSuccess
+	}
+	return fmt.Sprintf("%s (on %s)", strings.Join(j.After, ","), condition)
+}
+
+// printDependencyGraph 从没有上游的任务开始, 按树形打印依赖关系
+// 有多个上游的任务会在每个上游下面各出现一次
+func printDependencyGraph(entries []*api.SimpleEntry) {
+	downstream := make(map[string][]*config.ExternalJob)
+	jobs := make(map[string]*config.ExternalJob)
+	for _, e := range entries {
+		jobs[e.Job.Name] = &e.Job
+		for _, up := range e.Job.After {
+			downstream[up] = append(downstream[up], &e.Job)
+		}
+	}
+	if len(downstream) == 0 {
+		return
+	}
+
+	var roots []string
+	for up := range downstream {
+		if j, ok := jobs[up]; !ok || len(j.After) == 0 {
+			roots = append(roots, up)
+		}
+	}
+	sort.Strings(roots)
+
+	var printTree func(name string, prefix string)
+	printTree = func(name string, prefix string) {
+		children := downstream[name]
+		sort.Slice(children, func(i, k int) bool {
+			return children[i].Name < children[k].Name
+		})
+		for i, c := range children {
+			branch, next := "├── ", "│   "
+			if i == len(children)-1 {
+				branch, next = "└── ", "    "
+			}
+			condition := c.AfterCondition
+			if condition == "" {
+				condition = config.AfterSuccess
+			}
+			fmt.Printf("%s%s%s (on %s)\n", prefix, branch, c.Name, condition)
+			printTree(c.Name, prefix+next)
+		}
+	}
+
+	fmt.Println("\ndependency graph:")
+	for _, r := range roots {
+		if _, ok := jobs[r]; ok {
+			fmt.Println(r)
+		} else {
+			fmt.Printf("%s (not active)\n", r)
+		}
+		printTree(r, "")
+	}
 }
@@ -6,8 +6,11 @@ import (
 	"log/slog"
 	"os"
 	"os/user"
+	"path"
 	"strconv"
 
+	"dbm-services/mysql/db-tools/mysql-crond/pkg/history"
+
 	"github.com/go-playground/validator/v10"
 	"gopkg.in/yaml.v2"
 )
@@ -51,6 +54,11 @@ func InitConfig(configFilePath string) error {
 		return err
 	}
 
+	err = initRunHistory()
+	if err != nil {
+		return err
+	}
+
 	err = InitJobsConfig()
 	if err != nil {
 		return err
@@ -59,6 +67,22 @@ func InitConfig(configFilePath string) error {
 	return nil
 }
 
+func initRunHistory() error {
+	hc := RuntimeConfig.RunHistory
+	if hc == nil {
+		hc = &RunHistoryConfig{}
+	}
+	if hc.Dir == "" {
+		hc.Dir = path.Join(RuntimeConfig.PidPath, "history")
+	}
+	if !path.IsAbs(hc.Dir) {
+		err := fmt.Errorf("run_history.dir need absolute path")
+		slog.Error("init run history", slog.String("error", err.Error()))
+		return err
+	}
+	return history.Init(hc.Dir, hc.Keep, hc.OutputLimit)
+}
+
 func initConfig(configFilePath string) error {
 	content, err := os.ReadFile(configFilePath)
 	if err != nil {
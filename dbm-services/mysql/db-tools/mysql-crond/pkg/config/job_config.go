@@ -2,12 +2,18 @@ package config
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/exec"
 	"path"
+	"sync"
 	"syscall"
+	"time"
+
+	"dbm-services/mysql/db-tools/mysql-crond/pkg/history"
 
 	"github.com/go-playground/validator/v10"
 	"gopkg.in/yaml.v2"
@@ -21,20 +27,73 @@ type jobsConfig struct {
 	// Role         *string        `yaml:"role,omitempty"`
 }
 
+// 同一个任务上一轮还没结束时的调度策略
+const (
+	// ConcurrencyAllow 允许同时运行多个
+	ConcurrencyAllow = "allow"
+	// ConcurrencyForbid 跳过本轮, 默认策略
+	ConcurrencyForbid = "forbid"
+	// ConcurrencyReplace 结束上一轮, 启动本轮
+	ConcurrencyReplace = "replace"
+)
+
+// 上游任务满足什么结果时触发下游
+const (
+	AfterSuccess = "success"
+	AfterFailure = "failure"
+	AfterAlways  = "always"
+)
+
+// JobFinishedHook 任务运行结束后回调, 用于触发下游任务
+var JobFinishedHook func(j *ExternalJob, status string)
+
+// 超时或被替换时, 先发 SIGTERM, 等待一段时间后仍未退出则 SIGKILL
+const killWaitDelay = 10 * time.Second
+
 // ExternalJob TODO
 type ExternalJob struct {
 	Name     string   `yaml:"name" json:"name" binding:"required" validate:"required"`
 	Enable   *bool    `yaml:"enable" json:"enable" binding:"required" validate:"required"`
 	Command  string   `yaml:"command" json:"command" binding:"required" validate:"required"`
 	Args     []string `yaml:"args" json:"args" binding:"required" validate:"required"`
-	Schedule string   `yaml:"schedule" json:"schedule" binding:"required" validate:"required"`
+	Schedule string   `yaml:"schedule" json:"schedule" binding:"required_without=After" validate:"required_without=After"`
 	Creator  string   `yaml:"creator" json:"creator" binding:"required" validate:"required"`
 	WorkDir  string   `yaml:"work_dir" json:"work_dir"`
-	ch       chan struct{}
+	// Timeout 单次运行超时, 如 30m, 为空不限制
+	Timeout string `yaml:"timeout,omitempty" json:"timeout"`
+	// ConcurrencyPolicy allow/forbid/replace, 为空等同 forbid
+	ConcurrencyPolicy string `yaml:"concurrency_policy,omitempty" json:"concurrency_policy" validate:"omitempty,oneof=allow forbid replace"`
+	// KillProcessGroup 任务在独立进程组中运行, 超时或被替换时结束整个进程组
+	// 默认不开启, 只结束任务进程本身
+	KillProcessGroup bool `yaml:"kill_process_group,omitempty" json:"kill_process_group"`
+	// After 上游任务, 全部上游运行结束且满足 AfterCondition 时触发本任务
+	// 可以和 Schedule 同时使用, 没有 Schedule 时只由上游触发
+	After []string `yaml:"after,omitempty" json:"after" validate:"dive,required"`
+	// AfterCondition success/failure/always, 为空等同 success
+	AfterCondition string `yaml:"after_condition,omitempty" json:"after_condition" validate:"omitempty,oneof=success failure always"`
+	ch             chan struct{}
+	running        *runningState
+}
+
+// runningState 记录正在运行的实例, replace 策略用来结束上一轮
+type runningState struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
 }
 
 func (j *ExternalJob) run() {
-	cmd := exec.Command(j.Command, j.Args...)
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	j.setRunning(cancel)
+	defer j.setRunning(nil)
+
+	if timeout := j.timeout(); timeout > 0 {
+		var tCancel context.CancelFunc
+		ctx, tCancel = context.WithTimeoutCause(ctx, timeout, errTimeout)
+		defer tCancel()
+	}
+
+	cmd := exec.CommandContext(ctx, j.Command, j.Args...)
 	if j.WorkDir != "" {
 		cmd.Dir = j.WorkDir
 	}
@@ -43,20 +102,52 @@ func (j *ExternalJob) run() {
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
+	// 开启后使用独立进程组, 超时时连同子进程一起结束
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: j.KillProcessGroup}
 	if currentUser.Uid != jobsUser.Uid {
-		cmd.SysProcAttr = &syscall.SysProcAttr{
-			Credential: &syscall.Credential{
-				Uid: uint32(JobsUserUid),
-				Gid: uint32(JobsUserGid),
-			},
+		cmd.SysProcAttr.Credential = &syscall.Credential{
+			Uid: uint32(JobsUserUid),
+			Gid: uint32(JobsUserGid),
 		}
 	}
+	cmd.Cancel = func() error {
+		if j.KillProcessGroup {
+			return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+		}
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = killWaitDelay
 
+	record := &history.Record{
+		Name:      j.Name,
+		StartTime: time.Now(),
+		Status:    history.StatusSuccess,
+	}
 	err := cmd.Run()
+	defer j.finished(record)
+	record.EndTime = time.Now()
+	record.ExitCode = cmd.ProcessState.ExitCode()
+	record.Stdout = stdout.String()
+	record.Stderr = stderr.String()
+
 	if err != nil {
+		record.Status = history.StatusFailed
+		record.Message = err.Error()
+		if ctx.Err() != nil {
+			switch context.Cause(ctx) {
+			case errTimeout:
+				record.Status = history.StatusTimeout
+				record.Message = fmt.Sprintf("timeout after %s: %s", j.Timeout, err.Error())
+			case errReplaced:
+				record.Status = history.StatusReplaced
+				record.Message = fmt.Sprintf("replaced by next round: %s", err.Error())
+			}
+		}
+		history.Add(record)
+
 		slog.Error(
 			"external job",
-			slog.String("error", err.Error()),
+			slog.String("error", record.Message),
 			slog.String("name", j.Name),
 			slog.String("stderr", stderr.String()),
 		)
@@ -64,7 +155,7 @@ func (j *ExternalJob) run() {
 			mysqlCrondEventName,
 			fmt.Sprintf(
 				"execute job %s failed: %s [%s]",
-				j.Name, err.Error(), stderr.String(),
+				j.Name, record.Message, stderr.String(),
 			),
 			map[string]interface{}{
 				"job_name": j.Name,
@@ -74,6 +165,7 @@ func (j *ExternalJob) run() {
 			slog.Error("send event", slog.String("error", err.Error()))
 		}
 	} else {
+		history.Add(record)
 		slog.Info(
 			"external job",
 			slog.String("name", j.Name),
@@ -82,14 +174,47 @@ func (j *ExternalJob) run() {
 	}
 }
 
+// finished 通知下游, 被跳过的调度不会走到这里
+func (j *ExternalJob) finished(record *history.Record) {
+	if JobFinishedHook != nil {
+		JobFinishedHook(j, record.Status)
+	}
+}
+
+var errTimeout = errors.New("job timeout")
+var errReplaced = errors.New("job replaced")
+
 // Run TODO
 func (j *ExternalJob) Run() {
+	switch j.ConcurrencyPolicy {
+	case ConcurrencyAllow:
+		j.run()
+		return
+	case ConcurrencyReplace:
+		if j.cancelRunning() {
+			slog.Warn("replace running job", slog.String("name", j.Name))
+		}
+		// 等上一轮退出后再启动
+		v := <-j.ch
+		j.run()
+		j.ch <- v
+		return
+	}
+
 	select {
 	case v := <-j.ch:
 		j.run()
 		j.ch <- v
 	default:
 		slog.Warn("skip job", slog.String("name", j.Name))
+		history.Add(&history.Record{
+			Name:      j.Name,
+			StartTime: time.Now(),
+			EndTime:   time.Now(),
+			Status:    history.StatusSkipped,
+			ExitCode:  -1,
+			Message:   "last round still running",
+		})
 		err := SendEvent(
 			mysqlCrondEventName,
 			fmt.Sprintf("%s skipt for last round use too much time", j.Name),
@@ -97,19 +222,64 @@ func (j *ExternalJob) Run() {
 				"job_name": j.Name,
 			},
 		)
-		slog.Error("send event", slog.String("error", err.Error()))
+		if err != nil {
+			slog.Error("send event", slog.String("error", err.Error()))
+		}
+	}
+}
+
+func (j *ExternalJob) setRunning(cancel context.CancelCauseFunc) {
+	j.running.mu.Lock()
+	defer j.running.mu.Unlock()
+	if cancel == nil {
+		j.running.cancel = nil
+		return
+	}
+	j.running.cancel = func() { cancel(errReplaced) }
+}
+
+// cancelRunning 结束正在运行的一轮, 没有在运行时返回 false
+func (j *ExternalJob) cancelRunning() bool {
+	j.running.mu.Lock()
+	defer j.running.mu.Unlock()
+	if j.running.cancel == nil {
+		return false
 	}
+	j.running.cancel()
+	j.running.cancel = nil
+	return true
+}
+
+func (j *ExternalJob) timeout() time.Duration {
+	d, _ := time.ParseDuration(j.Timeout)
+	return d
 }
 
 // SetupChannel TODO
 func (j *ExternalJob) SetupChannel( /*ip string*/ ) {
 	j.ch = make(chan struct{}, 1)
 	j.ch <- struct{}{}
+	j.running = &runningState{}
 }
 
 func (j *ExternalJob) validate() error {
 	validate := validator.New()
-	return validate.Struct(j)
+	err := validate.Struct(j)
+	if err != nil {
+		return err
+	}
+	if j.Timeout != "" {
+		d, err := time.ParseDuration(j.Timeout)
+		if err != nil || d < 0 {
+			return fmt.Errorf("job %s invalid timeout: %s", j.Name, j.Timeout)
+		}
+	}
+	return nil
+}
+
+// Validate 校验任务定义
+func (j *ExternalJob) Validate() error {
+	return j.validate()
 }
 
 // InitJobsConfig TODO
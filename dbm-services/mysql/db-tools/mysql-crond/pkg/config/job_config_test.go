@@ -0,0 +1,128 @@
+package config
+
+import (
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"dbm-services/mysql/db-tools/mysql-crond/pkg/history"
+)
+
+// setupTestJob 告警命令用 true 代替, 任务以当前用户运行
+func setupTestJob(t *testing.T, name string, command string, args ...string) *ExternalJob {
+	u, err := user.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	currentUser, jobsUser = u, u
+	cloudId := 0
+	RuntimeConfig = &runtimeConfig{
+		BkCloudID:     &cloudId,
+		BkMonitorBeat: &BkMonitorBeat{BeatPath: "true"},
+	}
+	JobsConfig = &jobsConfig{}
+	if err := history.Init("", 0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	enable := true
+	j := &ExternalJob{
+		Name:     name,
+		Enable:   &enable,
+		Command:  command,
+		Args:     append([]string{}, args...),
+		Schedule: "@every 1m",
+		Creator:  "test",
+	}
+	j.SetupChannel()
+	return j
+}
+
+func lastRecord(t *testing.T, name string) *history.Record {
+	rs := history.List(name, 1)
+	if len(rs) != 1 {
+		t.Fatalf("expect history of %s, got %d records", name, len(rs))
+	}
+	return rs[0]
+}
+
+func TestRunStatus(t *testing.T) {
+	j := setupTestJob(t, "ok", "true")
+	j.Run()
+	if r := lastRecord(t, "ok"); r.Status != history.StatusSuccess || r.ExitCode != 0 {
+		t.Errorf("expect success, got %+v", r)
+	}
+
+	j = setupTestJob(t, "fail", "false")
+	j.Run()
+	if r := lastRecord(t, "fail"); r.Status != history.StatusFailed || r.ExitCode != 1 {
+		t.Errorf("expect failed, got %+v", r)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	j := setupTestJob(t, "slow", "sleep", "30")
+	j.Timeout = "100ms"
+	start := time.Now()
+	j.Run()
+	if cost := time.Since(start); cost > killWaitDelay {
+		t.Errorf("job should be killed on timeout, cost %s", cost)
+	}
+	if r := lastRecord(t, "slow"); r.Status != history.StatusTimeout {
+		t.Errorf("expect timeout, got %+v", r)
+	}
+}
+
+func TestRunSkipped(t *testing.T) {
+	j := setupTestJob(t, "busy", "true")
+	// 模拟上一轮还在运行
+	v := <-j.ch
+	j.Run()
+	j.ch <- v
+	if r := lastRecord(t, "busy"); r.Status != history.StatusSkipped {
+		t.Errorf("expect skipped, got %+v", r)
+	}
+}
+
+// TestRunProcessGroup 只有开启 kill_process_group 的任务才使用独立进程组
+func TestRunProcessGroup(t *testing.T) {
+	for _, killGroup := range []bool{false, true} {
+		j := setupTestJob(t, "pgid", "sh", "-c", "cut -d ' ' -f 5 /proc/$$/stat; echo $$")
+		j.KillProcessGroup = killGroup
+		j.Run()
+		r := lastRecord(t, "pgid")
+		lines := strings.Fields(r.Stdout)
+		if r.Status != history.StatusSuccess || len(lines) != 2 {
+			t.Fatalf("unexpected record %+v", r)
+		}
+		pgid, _ := strconv.Atoi(lines[0])
+		pid, _ := strconv.Atoi(lines[1])
+		if killGroup && pgid != pid {
+			t.Errorf("job should lead its own process group, pgid %d pid %d", pgid, pid)
+		}
+		if !killGroup && pgid != syscall.Getpgrp() {
+			t.Errorf("job should stay in crond process group, pgid %d want %d", pgid, syscall.Getpgrp())
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	j := setupTestJob(t, "v", "true")
+	if err := j.validate(); err != nil {
+		t.Fatalf("valid job: %v", err)
+	}
+
+	j.Timeout = "abc"
+	if err := j.validate(); err == nil {
+		t.Error("invalid timeout should fail")
+	}
+	j.Timeout = ""
+
+	j.ConcurrencyPolicy = "queue"
+	if err := j.validate(); err == nil {
+		t.Error("invalid concurrency_policy should fail")
+	}
+}
@@ -1,12 +1,23 @@
 package config
 
 type runtimeConfig struct {
-	Ip             string         `yaml:"ip" validate:"required,ipv4"`
-	Port           int            `yaml:"port" validate:"required,gt=1024,lte=65535"`
-	BkCloudID      *int           `yaml:"bk_cloud_id" validate:"required,gte=0"`
-	BkMonitorBeat  *BkMonitorBeat `yaml:"bk_monitor_beat" validate:"required"`
-	Log            *LogConfig     `yaml:"log"`
-	PidPath        string         `yaml:"pid_path" validate:"required,dir"`
-	JobsUser       string         `yaml:"jobs_user" validate:"required"`
-	JobsConfigFile string         `yaml:"jobs_config" validate:"required"`
+	Ip             string            `yaml:"ip" validate:"required,ipv4"`
+	Port           int               `yaml:"port" validate:"required,gt=1024,lte=65535"`
+	BkCloudID      *int              `yaml:"bk_cloud_id" validate:"required,gte=0"`
+	BkMonitorBeat  *BkMonitorBeat    `yaml:"bk_monitor_beat" validate:"required"`
+	Log            *LogConfig        `yaml:"log"`
+	PidPath        string            `yaml:"pid_path" validate:"required,dir"`
+	JobsUser       string            `yaml:"jobs_user" validate:"required"`
+	JobsConfigFile string            `yaml:"jobs_config" validate:"required"`
+	RunHistory     *RunHistoryConfig `yaml:"run_history"`
+}
+
+// RunHistoryConfig 任务执行历史配置
+type RunHistoryConfig struct {
+	// Dir 历史文件目录, 默认 pid_path/history
+	Dir string `yaml:"dir"`
+	// Keep 每个任务保留的记录数, 默认 100
+	Keep int `yaml:"keep" validate:"gte=0"`
+	// OutputLimit stdout, stderr 各保留的最大字节数, 默认 4096
+	OutputLimit int `yaml:"output_limit" validate:"gte=0"`
 }
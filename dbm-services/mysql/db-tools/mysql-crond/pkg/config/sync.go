@@ -112,7 +112,7 @@ func SyncDelete(name string) error {
 			"target job %s not found in %s",
 			name, RuntimeConfig.JobsConfigFile,
 		)
-		slog.Error("sync job enable seek target job", err)
+		slog.Error("sync job enable seek target job", slog.String("error", err.Error()))
 		return err
 	}
 
@@ -40,6 +40,15 @@ func Stop() {
 
 // Start TODO
 func Start() error {
+	jobs := make(map[string]*config.ExternalJob)
+	for _, j := range config.JobsConfig.Jobs {
+		jobs[j.Name] = j
+	}
+	if err := checkCycle(jobs); err != nil {
+		slog.Error("check jobs dependency", slog.String("error", err.Error()))
+		return err
+	}
+
 	for _, j := range config.JobsConfig.Jobs {
 		entryID, err := Add(j, false)
 		if err != nil {
@@ -107,6 +116,9 @@ func Reload() error {
 			return true
 		},
 	)
+	dependState.Lock()
+	clear(dependState.satisfied)
+	dependState.Unlock()
 
 	err := config.InitJobsConfig()
 	if err != nil {
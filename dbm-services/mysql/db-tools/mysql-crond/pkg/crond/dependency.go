@@ -0,0 +1,178 @@
+package crond
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"dbm-services/mysql/db-tools/mysql-crond/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-crond/pkg/history"
+	"dbm-services/mysql/db-tools/mysql-crond/pkg/schedule"
+
+	"github.com/robfig/cron/v3"
+)
+
+// dependState 记录下游任务已经满足条件的上游, 全部满足后触发并清空
+var dependState = struct {
+	sync.Mutex
+	satisfied map[string]map[string]struct{}
+}{
+	satisfied: make(map[string]map[string]struct{}),
+}
+
+func init() {
+	config.JobFinishedHook = triggerDownstream
+}
+
+// scheduleJob 没有 schedule 的任务只由上游触发
+func scheduleJob(j *config.ExternalJob) (cron.EntryID, error) {
+	if j.Schedule == "" {
+		return cronJob.Schedule(schedule.NewDependSchedule(), j), nil
+	}
+	return cronJob.AddJob(j.Schedule, j)
+}
+
+// triggerDownstream 上游结束后检查所有下游
+func triggerDownstream(upstream *config.ExternalJob, status string) {
+	var jobs []*config.ExternalJob
+	for _, entry := range ListEntry() {
+		j, _ := entry.Job.(*config.ExternalJob)
+		jobs = append(jobs, j)
+	}
+
+	for _, j := range satisfiedDownstream(jobs, upstream.Name, status) {
+		slog.Info(
+			"trigger downstream job",
+			slog.String("upstream", upstream.Name),
+			slog.String("status", status),
+			slog.String("name", j.Name),
+		)
+		go j.Run()
+	}
+}
+
+// satisfiedDownstream 记录上游的结果, 返回全部上游都满足条件的下游
+// 上游最近一次结果不满足条件时, 之前记下的满足状态要清掉, 避免用过期结果触发下游
+func satisfiedDownstream(jobs []*config.ExternalJob, upstream string, status string) []*config.ExternalJob {
+	dependState.Lock()
+	defer dependState.Unlock()
+
+	var triggered []*config.ExternalJob
+	for _, j := range jobs {
+		if !slices.Contains(j.After, upstream) {
+			continue
+		}
+
+		s, ok := dependState.satisfied[j.Name]
+		if !conditionMatch(j.AfterCondition, status) {
+			if ok {
+				delete(s, upstream)
+			}
+			continue
+		}
+		if !ok {
+			s = make(map[string]struct{})
+			dependState.satisfied[j.Name] = s
+		}
+		s[upstream] = struct{}{}
+		if len(s) < len(j.After) {
+			continue
+		}
+		delete(dependState.satisfied, j.Name)
+		triggered = append(triggered, j)
+	}
+	return triggered
+}
+
+func conditionMatch(condition string, status string) bool {
+	switch status {
+	case history.StatusSuccess:
+		return condition == "" || condition == config.AfterSuccess || condition == config.AfterAlways
+	case history.StatusFailed, history.StatusTimeout:
+		return condition == config.AfterFailure || condition == config.AfterAlways
+	default:
+		// 被跳过或者被替换的不算运行结束
+		return false
+	}
+}
+
+// allJobs 包括已禁用的任务
+func allJobs() map[string]*config.ExternalJob {
+	res := make(map[string]*config.ExternalJob)
+	for _, entry := range ListEntry() {
+		j, _ := entry.Job.(*config.ExternalJob)
+		res[j.Name] = j
+	}
+	for _, j := range ListDisabledJob() {
+		res[j.Name] = j
+	}
+	return res
+}
+
+// checkCycle 检查依赖是否有环
+// jobs 为任务名到任务的映射, 不存在的上游不检查
+func checkCycle(jobs map[string]*config.ExternalJob) error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		j, ok := jobs[name]
+		if !ok {
+			return nil
+		}
+		switch state[name] {
+		case visiting:
+			idx := slices.Index(path, name)
+			return DependencyCycleError(fmt.Sprintf(
+				"dependency cycle found: %s -> %s",
+				strings.Join(path[idx:], " -> "), name,
+			))
+		case visited:
+			return nil
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, up := range j.After {
+			if err := visit(up); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+
+	for name := range jobs {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkNewJobCycle 用新任务替换同名任务后检查是否有环
+func checkNewJobCycle(j *config.ExternalJob) error {
+	jobs := allJobs()
+	jobs[j.Name] = j
+	err := checkCycle(jobs)
+	if err != nil {
+		slog.Error("check job dependency", slog.String("name", j.Name), slog.String("error", err.Error()))
+		return err
+	}
+
+	for _, up := range j.After {
+		if _, ok := jobs[up]; !ok {
+			slog.Warn("upstream job not found", slog.String("name", j.Name), slog.String("upstream", up))
+		}
+	}
+	return nil
+}
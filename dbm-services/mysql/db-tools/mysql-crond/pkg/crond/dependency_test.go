@@ -0,0 +1,139 @@
+package crond
+
+import (
+	"errors"
+	"testing"
+
+	"dbm-services/mysql/db-tools/mysql-crond/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-crond/pkg/history"
+)
+
+func newJob(name string, condition string, after ...string) *config.ExternalJob {
+	return &config.ExternalJob{Name: name, After: after, AfterCondition: condition}
+}
+
+func names(jobs []*config.ExternalJob) []string {
+	var res []string
+	for _, j := range jobs {
+		res = append(res, j.Name)
+	}
+	return res
+}
+
+func TestSatisfiedDownstream(t *testing.T) {
+	type step struct {
+		upstream string
+		status   string
+		want     []string
+	}
+	cases := []struct {
+		name  string
+		jobs  []*config.ExternalJob
+		steps []step
+	}{
+		{
+			name: "single upstream success",
+			jobs: []*config.ExternalJob{newJob("c", "", "a")},
+			steps: []step{
+				{"a", history.StatusSuccess, []string{"c"}},
+				{"a", history.StatusFailed, nil},
+			},
+		},
+		{
+			name: "wait for all upstreams",
+			jobs: []*config.ExternalJob{newJob("c", config.AfterSuccess, "a", "b")},
+			steps: []step{
+				{"a", history.StatusSuccess, nil},
+				{"b", history.StatusSuccess, []string{"c"}},
+				// 触发后清空, 需要重新满足
+				{"b", history.StatusSuccess, nil},
+			},
+		},
+		{
+			name: "stale success is cleared by later failure",
+			jobs: []*config.ExternalJob{newJob("c", config.AfterSuccess, "a", "b")},
+			steps: []step{
+				{"a", history.StatusSuccess, nil},
+				{"a", history.StatusFailed, nil},
+				{"b", history.StatusSuccess, nil},
+				{"a", history.StatusSuccess, []string{"c"}},
+			},
+		},
+		{
+			name: "failure condition",
+			jobs: []*config.ExternalJob{newJob("alert", config.AfterFailure, "a")},
+			steps: []step{
+				{"a", history.StatusSuccess, nil},
+				{"a", history.StatusTimeout, []string{"alert"}},
+			},
+		},
+		{
+			name: "always condition ignores replaced",
+			jobs: []*config.ExternalJob{newJob("c", config.AfterAlways, "a")},
+			steps: []step{
+				{"a", history.StatusReplaced, nil},
+				{"a", history.StatusFailed, []string{"c"}},
+			},
+		},
+		{
+			name: "unrelated job not triggered",
+			jobs: []*config.ExternalJob{newJob("c", "", "a"), newJob("d", "", "b")},
+			steps: []step{
+				{"a", history.StatusSuccess, []string{"c"}},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dependState.satisfied = make(map[string]map[string]struct{})
+			for i, s := range c.steps {
+				got := names(satisfiedDownstream(c.jobs, s.upstream, s.status))
+				if len(got) != len(s.want) {
+					t.Fatalf("step %d %s %s: got %v, want %v", i, s.upstream, s.status, got, s.want)
+				}
+				for k := range got {
+					if got[k] != s.want[k] {
+						t.Fatalf("step %d %s %s: got %v, want %v", i, s.upstream, s.status, got, s.want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCheckCycle(t *testing.T) {
+	cases := []struct {
+		name  string
+		jobs  []*config.ExternalJob
+		cycle bool
+	}{
+		{"no dependency", []*config.ExternalJob{newJob("a", ""), newJob("b", "")}, false},
+		{"chain", []*config.ExternalJob{newJob("a", ""), newJob("b", "", "a"), newJob("c", "", "b")}, false},
+		{"diamond", []*config.ExternalJob{
+			newJob("a", ""), newJob("b", "", "a"), newJob("c", "", "a"), newJob("d", "", "b", "c"),
+		}, false},
+		{"missing upstream", []*config.ExternalJob{newJob("a", "", "x")}, false},
+		{"self", []*config.ExternalJob{newJob("a", "", "a")}, true},
+		{"loop", []*config.ExternalJob{newJob("a", "", "c"), newJob("b", "", "a"), newJob("c", "", "b")}, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			jobs := make(map[string]*config.ExternalJob)
+			for _, j := range c.jobs {
+				jobs[j.Name] = j
+			}
+			err := checkCycle(jobs)
+			if !c.cycle && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if c.cycle {
+				var e DependencyCycleError
+				if !errors.As(err, &e) {
+					t.Fatalf("expect DependencyCycleError, got %v", err)
+				}
+			}
+		})
+	}
+}
@@ -7,3 +7,11 @@ type NotFoundError string
 func (r NotFoundError) Error() string {
 	return string(r)
 }
+
+// DependencyCycleError 任务依赖有环
+type DependencyCycleError string
+
+// Error 用于错误处理
+func (r DependencyCycleError) Error() string {
+	return string(r)
+}
@@ -30,7 +30,7 @@ func Add(j *config.ExternalJob, permanent bool) (int, error) {
 }
 
 func addActivate(j *config.ExternalJob, permanent bool) (int, error) {
-	entryID, err := cronJob.AddJob(j.Schedule, j)
+	entryID, err := scheduleJob(j)
 	if err != nil {
 		slog.Error("add job", slog.String("error", err.Error()))
 		return 0, err
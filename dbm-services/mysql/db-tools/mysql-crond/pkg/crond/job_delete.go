@@ -5,6 +5,7 @@ import (
 	"log/slog"
 
 	"dbm-services/mysql/db-tools/mysql-crond/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-crond/pkg/history"
 
 	"github.com/robfig/cron/v3"
 )
@@ -37,9 +38,10 @@ func deleteActivate(entry *cron.Entry, permanent bool) (int, error) {
 	if permanent {
 		err := config.SyncDelete(j.Name)
 		if err != nil {
-			_, _ = cronJob.AddJob(j.Schedule, j)
+			_, _ = scheduleJob(j)
 			return 0, err
 		}
+		history.Delete(j.Name)
 	}
 	slog.Info("delete activity success", slog.String("name", j.Name))
 	return 0, nil
@@ -60,6 +62,7 @@ func deleteDisabled(name string, permanent bool) (int, error) {
 			DisabledJobs.Store(name, job)
 			return 0, err
 		}
+		history.Delete(name)
 	}
 	slog.Info("delete disabled success", slog.String("name", name))
 	return 0, nil
@@ -39,7 +39,7 @@ func Disable(name string, permanent bool) (int, error) {
 			*j.Enable = true
 			// 本来就是错误处理, 这里再出错也不知道咋办了
 			// 但是想来也不太可能出错
-			_, _ = cronJob.AddJob(j.Schedule, j)
+			_, _ = scheduleJob(j)
 			DisabledJobs.Delete(j.Name)
 			return 0, err
 		}
@@ -25,7 +25,7 @@ func Pause(name string, du time.Duration) (int, error) {
 		schedule.NewOnceSchedule(time.Now().Add(du)),
 		cron.FuncJob(
 			func() {
-				_, _ = cronJob.AddJob(j.Schedule, j)
+				_, _ = scheduleJob(j)
 			},
 		),
 	)
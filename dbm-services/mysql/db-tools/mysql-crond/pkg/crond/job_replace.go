@@ -10,7 +10,12 @@ import (
 
 // CreateOrReplace TODO
 func CreateOrReplace(j *config.ExternalJob, permanent bool) (int, error) {
-	_, err := Delete(j.Name, permanent)
+	err := checkNewJobCycle(j)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = Delete(j.Name, permanent)
 
 	if err != nil {
 		var notFoundError NotFoundError
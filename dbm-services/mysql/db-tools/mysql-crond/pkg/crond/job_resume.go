@@ -13,7 +13,7 @@ func Resume(name string, permanent bool) (int, error) {
 		if j, ok := value.(*config.ExternalJob); ok {
 			*j.Enable = true
 
-			entryID, err := cronJob.AddJob(j.Schedule, j)
+			entryID, err := scheduleJob(j)
 			if err != nil {
 				slog.Error("resume job", slog.String("error", err.Error()))
 				return 0, err
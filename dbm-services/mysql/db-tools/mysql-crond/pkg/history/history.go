@@ -0,0 +1,180 @@
+// Package history 任务执行历史, 按任务保存最近若干次执行记录到本地文件
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 执行结果
+const (
+	StatusSuccess  = "success"
+	StatusFailed   = "failed"
+	StatusTimeout  = "timeout"
+	StatusSkipped  = "skipped"
+	StatusReplaced = "replaced"
+)
+
+// Record 一次执行记录
+type Record struct {
+	Name      string    `json:"name"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Status    string    `json:"status"`
+	ExitCode  int       `json:"exit_code"`
+	Stdout    string    `json:"stdout"`
+	Stderr    string    `json:"stderr"`
+	Message   string    `json:"message"`
+}
+
+type store struct {
+	mu          sync.Mutex
+	dir         string
+	keep        int
+	outputLimit int
+	records     map[string][]*Record
+}
+
+var s = &store{
+	keep:        100,
+	outputLimit: 4096,
+	records:     make(map[string][]*Record),
+}
+
+// Init 加载已有历史
+// dir 为空时只保存在内存
+func Init(dir string, keep int, outputLimit int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dir = dir
+	if keep > 0 {
+		s.keep = keep
+	}
+	if outputLimit > 0 {
+		s.outputLimit = outputLimit
+	}
+	s.records = make(map[string][]*Record)
+	if dir == "" {
+		return nil
+	}
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		slog.Error("init history", slog.String("error", err.Error()))
+		return err
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		slog.Error("init history", slog.String("error", err.Error()))
+		return err
+	}
+	for _, f := range files {
+		content, err := os.ReadFile(f)
+		if err != nil {
+			slog.Warn("init history read file", slog.String("file", f), slog.String("error", err.Error()))
+			continue
+		}
+		var rs []*Record
+		if err := json.Unmarshal(content, &rs); err != nil {
+			slog.Warn("init history unmarshal", slog.String("file", f), slog.String("error", err.Error()))
+			continue
+		}
+		if len(rs) > 0 {
+			s.records[rs[0].Name] = rs
+		}
+	}
+	return nil
+}
+
+// Add 追加一条记录, 超出保留条数的最老记录会被丢弃
+func Add(r *Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r.Stdout = truncate(r.Stdout, s.outputLimit)
+	r.Stderr = truncate(r.Stderr, s.outputLimit)
+
+	rs := append(s.records[r.Name], r)
+	if len(rs) > s.keep {
+		rs = rs[len(rs)-s.keep:]
+	}
+	s.records[r.Name] = rs
+
+	if s.dir == "" {
+		return
+	}
+	if err := s.flush(r.Name); err != nil {
+		slog.Error("flush history", slog.String("name", r.Name), slog.String("error", err.Error()))
+	}
+}
+
+// List 按开始时间倒序返回执行记录
+// name 为空时返回全部任务, limit <= 0 不限制条数
+func List(name string, limit int) []*Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res []*Record
+	for n, rs := range s.records {
+		if name != "" && n != name {
+			continue
+		}
+		res = append(res, rs...)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].StartTime.After(res[j].StartTime)
+	})
+	if limit > 0 && len(res) > limit {
+		res = res[:limit]
+	}
+	return res
+}
+
+// Delete 删除任务的全部历史
+func Delete(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, name)
+	if s.dir != "" {
+		_ = os.Remove(s.fileName(name))
+	}
+}
+
+func (s *store) flush(name string) error {
+	content, err := json.Marshal(s.records[name])
+	if err != nil {
+		return err
+	}
+
+	// 先写临时文件再改名, 避免写一半时进程退出导致文件损坏
+	f := s.fileName(name)
+	err = os.WriteFile(f+".tmp", content, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(f+".tmp", f)
+}
+
+// fileName 任务名里可能有 / 和空格之类的字符, 不能直接当文件名
+func (s *store) fileName(name string) string {
+	r := strings.NewReplacer("/", "_", " ", "_", "\\", "_")
+	return filepath.Join(s.dir, fmt.Sprintf("%s.json", r.Replace(name)))
+}
+
+// truncate 保留输出的最后 limit 字节, 出错信息一般在最后
+func truncate(o string, limit int) string {
+	if len(o) <= limit {
+		return o
+	}
+	return fmt.Sprintf("...(truncated %d bytes)...%s", len(o)-limit, o[len(o)-limit:])
+}
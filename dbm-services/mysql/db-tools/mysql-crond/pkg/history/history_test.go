@@ -0,0 +1,74 @@
+package history
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddAndList(t *testing.T) {
+	if err := Init("", 2, 8); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for i, name := range []string{"a", "a", "b", "a"} {
+		Add(&Record{Name: name, StartTime: now.Add(time.Duration(i) * time.Second), ExitCode: i})
+	}
+
+	rs := List("a", 0)
+	if len(rs) != 2 {
+		t.Fatalf("keep 2 records per job, got %d", len(rs))
+	}
+	if rs[0].ExitCode != 3 || rs[1].ExitCode != 1 {
+		t.Fatalf("records should be newest first and oldest dropped, got %d, %d", rs[0].ExitCode, rs[1].ExitCode)
+	}
+	if rs = List("", 0); len(rs) != 3 {
+		t.Fatalf("list all jobs expect 3, got %d", len(rs))
+	}
+	if rs = List("", 1); len(rs) != 1 || rs[0].Name != "a" {
+		t.Fatalf("limit 1 expect newest record, got %+v", rs)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if err := Init("", 0, 4); err != nil {
+		t.Fatal(err)
+	}
+	Add(&Record{Name: "t", Stdout: "abc", Stderr: "0123456789"})
+	r := List("t", 1)[0]
+	if r.Stdout != "abc" {
+		t.Errorf("short output should be kept, got %q", r.Stdout)
+	}
+	if !strings.HasSuffix(r.Stderr, "6789") || !strings.Contains(r.Stderr, "truncated 6 bytes") {
+		t.Errorf("long output should keep tail, got %q", r.Stderr)
+	}
+}
+
+func TestPersistAndDelete(t *testing.T) {
+	dir := t.TempDir()
+	if err := Init(dir, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	Add(&Record{Name: "backup/full", StartTime: time.Now(), Status: StatusSuccess})
+	Add(&Record{Name: "checksum", StartTime: time.Now(), Status: StatusFailed})
+
+	// 重新加载后历史还在
+	if err := Init(dir, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if rs := List("backup/full", 0); len(rs) != 1 || rs[0].Status != StatusSuccess {
+		t.Fatalf("history should be reloaded from dir, got %+v", rs)
+	}
+
+	Delete("backup/full")
+	if rs := List("backup/full", 0); len(rs) != 0 {
+		t.Fatalf("history should be deleted, got %+v", rs)
+	}
+	if _, err := os.Stat(s.fileName("backup/full")); !os.IsNotExist(err) {
+		t.Fatalf("history file should be removed, stat err: %v", err)
+	}
+	if rs := List("checksum", 0); len(rs) != 1 {
+		t.Fatalf("other job history should be kept, got %+v", rs)
+	}
+}
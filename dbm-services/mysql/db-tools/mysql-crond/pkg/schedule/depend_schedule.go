@@ -0,0 +1,17 @@
+package schedule
+
+import "time"
+
+// DependSchedule 只由上游任务触发, 自己永远不会被调度
+type DependSchedule struct {
+}
+
+// Next 返回零值, cron 不会调度零值的 entry
+func (s *DependSchedule) Next(t time.Time) time.Time {
+	return time.Time{}
+}
+
+// NewDependSchedule TODO
+func NewDependSchedule() *DependSchedule {
+	return &DependSchedule{}
+}
@@ -2,14 +2,17 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
 	"dbm-services/mysql/db-tools/mysql-crond/pkg/config"
 	"dbm-services/mysql/db-tools/mysql-crond/pkg/crond"
+	"dbm-services/mysql/db-tools/mysql-crond/pkg/history"
 
 	"github.com/gin-gonic/gin"
 )
@@ -132,6 +135,11 @@ func Start(version string, buildStamp string, gitHash string, quit chan struct{}
 				context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
 				return
 			}
+			err = body.Job.Validate()
+			if err != nil {
+				context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+				return
+			}
 			m.Lock()
 			defer func() {
 				m.Unlock()
@@ -139,6 +147,11 @@ func Start(version string, buildStamp string, gitHash string, quit chan struct{}
 			body.Job.SetupChannel( /*config.RuntimeConfig.Ip*/ )
 			entryID, err := crond.CreateOrReplace(body.Job, *body.Permanent)
 			if err != nil {
+				var cycleErr crond.DependencyCycleError
+				if errors.As(err, &cycleErr) {
+					context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+					return
+				}
 				_ = context.AbortWithError(http.StatusInternalServerError, err)
 				return
 			}
@@ -259,6 +272,20 @@ func Start(version string, buildStamp string, gitHash string, quit chan struct{}
 			context.JSON(http.StatusOK, gin.H{})
 		},
 	)
+	r.GET(
+		"/history", func(context *gin.Context) {
+			limit, err := strconv.Atoi(context.DefaultQuery("limit", "0"))
+			if err != nil {
+				context.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+				return
+			}
+			context.JSON(
+				http.StatusOK, gin.H{
+					"history": history.List(context.Query("name"), limit),
+				},
+			)
+		},
+	)
 	r.GET(
 		"/config/jobs-config", func(context *gin.Context) {
 			context.JSON(
@@ -42,6 +42,7 @@ func init() {
 	rootCmd.AddCommand(loadCmd)
 	rootCmd.AddCommand(spiderCmd)
 	rootCmd.AddCommand(migrateOldCmd)
+	rootCmd.AddCommand(verifyCmd)
 }
 
 // initConfig parse the configuration file of dbbackup to init a cfg
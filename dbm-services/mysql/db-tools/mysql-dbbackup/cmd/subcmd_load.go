@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
 	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/config"
 	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/backupexe"
@@ -12,12 +13,24 @@ func init() {
 	// loadCmd
 	loadCmd.Flags().StringVarP(&cnfFile, "config", "c", "", "one config file to load")
 	_ = loadCmd.MarkFlagRequired("config")
+	loadCmd.Flags().String("incremental-dir", "", "overwrite PhysicalLoad.IncrementalLoadDir")
+	_ = viper.BindPFlag("PhysicalLoad.IncrementalLoadDir", loadCmd.Flags().Lookup("incremental-dir"))
+	loadCmd.Flags().String("tables", "", "overwrite LogicalLoad.Tables, db1.tb1,db1.tb2=db2.tb2_bak")
+	_ = viper.BindPFlag("LogicalLoad.Tables", loadCmd.Flags().Lookup("tables"))
+	loadCmd.Flags().String("target-db", "", "overwrite LogicalLoad.TargetDb")
+	_ = viper.BindPFlag("LogicalLoad.TargetDb", loadCmd.Flags().Lookup("target-db"))
+	loadCmd.Flags().String("row-filter", "", "overwrite LogicalLoad.RowFilter, where condition")
+	_ = viper.BindPFlag("LogicalLoad.RowFilter", loadCmd.Flags().Lookup("row-filter"))
 }
 
 var loadCmd = &cobra.Command{
 	Use:   "loadbackup",
 	Short: "Run load backup",
-	Long:  `Run load backup using config, include logical and physical`,
+	Long: `Run load backup using config, include logical and physical
+If IndexFilePath is an incremental backup, the full backup in MysqlLoadDir and the incremental chain
+found by parent_backup_id next to IndexFilePath will be applied in order
+If LogicalLoad.Tables is set, only these tables are loaded from mydumper backup without myloader,
+they can be renamed to other db/table, and filtered by LogicalLoad.RowFilter through a staging table`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		var err error
 		if err = logger.InitLog("dbbackup_load.log"); err != nil {
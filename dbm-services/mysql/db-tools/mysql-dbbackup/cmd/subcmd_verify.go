@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/backupexe"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/dbareport"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/logger"
+)
+
+var verifyOpt = backupexe.VerifyOption{}
+
+func init() {
+	// verifyCmd
+	verifyCmd.Flags().StringVarP(&cnfFile, "config", "c", "", "one config file")
+	_ = verifyCmd.MarkFlagRequired("config")
+	verifyCmd.Flags().StringVar(&verifyOpt.IndexFile, "index-file", "",
+		"index file of the backup to verify, default the latest one of this instance in BackupDir")
+	verifyCmd.Flags().StringVar(&verifyOpt.WorkDir, "work-dir", "",
+		"dir to extract backup, default BackupDir/verify_<target_name>")
+	verifyCmd.Flags().StringVar(&verifyOpt.Passphrase, "passphrase", "",
+		"passphrase of encrypted backup, default find it in ReportPath/result")
+	verifyCmd.Flags().StringVar(&verifyOpt.PrivateKey, "private-key", "",
+		"rsa private key file to decrypt the reported encrypted passphrase")
+	verifyCmd.Flags().BoolVar(&verifyOpt.KeepFiles, "keep-files", false, "keep extracted files after verify")
+	verifyCmd.MarkFlagsMutuallyExclusive("passphrase", "private-key")
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify backup",
+	Long: `Verify a finished backup: check md5 of backup files, decrypt and extract them,
+prepare physical backup, or check schema and rows of logical backup.
+Result is written to ReportPath/verify and local_backup_report.verify_status`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+		if err = logger.InitLog("dbbackup_verify.log"); err != nil {
+			return err
+		}
+		var cnf = config.BackupConfig{}
+		if err = initConfig(cnfFile, &cnf); err != nil {
+			return err
+		}
+		if err = dbareport.InitReporter(cnf.Public.ReportPath); err != nil {
+			return err
+		}
+		if verifyOpt.IndexFile == "" {
+			if verifyOpt.IndexFile, err = backupexe.LatestIndexFile(&cnf.Public); err != nil {
+				return err
+			}
+		}
+		result, err := backupexe.ExecuteVerify(&cnf, &verifyOpt)
+		if result != nil {
+			if reportErr := dbareport.ReportVerifyResult(&cnf.Public, result); reportErr != nil {
+				logger.Log.Warnf("report verify result to db failed: %s", reportErr.Error())
+			}
+		}
+		if err != nil {
+			logger.Log.Error("Verify Dbbackup: Failure")
+			return err
+		}
+		logger.Log.Infof("Verify Success: %s", verifyOpt.IndexFile)
+		return nil
+	},
+}
@@ -12,4 +12,5 @@ type BackupConfig struct {
 	LogicalLoadMysqldump   LogicalLoadMysqldump   `ini:"LogicalLoadMysqldump"`
 	PhysicalBackup         PhysicalBackup         `ini:"PhysicalBackup"`
 	PhysicalLoad           PhysicalLoad           `ini:"PhysicalLoad"`
+	StreamUpload           StreamUpload           `ini:"StreamUpload"`
 }
@@ -43,6 +43,15 @@ type LogicalLoad struct {
 	DBListDropIfExists string `ini:"DBListDropIfExists"`
 	// CreateTableIfNotExists true will add --append-if-not-exist for myloader
 	CreateTableIfNotExists bool `ini:"CreateTableIfNotExists"`
+
+	// Tables 按表恢复，不为空时不使用 myloader，只从备份里取这些表的文件导入。逗号分隔
+	//  db1.tb1 恢复到 TargetDb.tb1，db1.tb2=db2.tb2_bak 恢复到指定的库表
+	Tables string `ini:"Tables"`
+	// TargetDb 按表恢复时的目标库，为空时恢复到原库
+	TargetDb string `ini:"TargetDb"`
+	// RowFilter 按表恢复时只恢复满足条件的行，如 id > 100 and ctime < '2024-01-01'
+	//  会先导入到临时表，再 insert into target select * from staging where RowFilter，不能包含 ;
+	RowFilter string `ini:"RowFilter"`
 }
 
 // LogicalBackupMysqldump the config of logical backup with mysqldump
@@ -27,4 +27,7 @@ type PhysicalLoad struct {
 	IndexFilePath string `ini:"IndexFilePath" validate:"required,file"`
 	DefaultsFile  string `ini:"DefaultsFile" validate:"required"`
 	ExtraOpt      string `ini:"ExtraOpt"` // other xtrabackup recover options string to be appended
+	// IncrementalLoadDir 增量备份解压所在目录，每个增量备份解压到 IncrementalLoadDir/<targetName>
+	// 为空时使用 IndexFilePath 所在目录
+	IncrementalLoadDir string `ini:"IncrementalLoadDir"`
 }
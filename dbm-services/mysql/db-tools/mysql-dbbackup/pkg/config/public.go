@@ -52,7 +52,8 @@ type Public struct {
 	MysqlCharset string `ini:"MysqlCharset"`
 	// BackupTimeOut 备份时间阈值，格式 09:00:01
 	BackupTimeOut string `ini:"BackupTimeout"`
-	// BackupType backup type,  oneof=logical physical auto
+	// BackupType backup type,  oneof=logical physical incremental auto
+	// BackupIncremental 基于 ReportPath 里记录的上一次成功物理备份做增量
 	// BackupTypeAuto 自动选择备份方式
 	// 磁盘空间数据量大于 BackupTypeAutoDataSizeGB ，物理备份
 	// glibc 版本小于 2.14，物理备份
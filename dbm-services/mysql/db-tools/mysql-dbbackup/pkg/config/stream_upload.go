@@ -0,0 +1,36 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// StreamUpload 打包时把 tar 直接流式上传到 s3 兼容的对象存储，不在 BackupDir 下生成 tar 文件
+// 开启后不再使用 backup_client 上传，index 文件最后上传
+type StreamUpload struct {
+	Enable bool `ini:"Enable"`
+	// Endpoint 如 http://127.0.0.1:9000
+	Endpoint  string `ini:"Endpoint"`
+	Region    string `ini:"Region"`
+	Bucket    string `ini:"Bucket"`
+	AccessKey string `ini:"AccessKey"`
+	SecretKey string `ini:"SecretKey"`
+	// KeyPrefix 对象名前缀，对象名为 KeyPrefix + 文件名
+	KeyPrefix string `ini:"KeyPrefix"`
+	// PartSizeMB 分块上传的分块大小，默认 64，最小 5
+	PartSizeMB int `ini:"PartSizeMB"`
+	// Retries 每个分块上传失败后的重试次数
+	Retries int `ini:"Retries"`
+	// TimeoutSec 单个请求(包括上传一个分块)的超时时间，默认 600
+	TimeoutSec int `ini:"TimeoutSec"`
+}
+
+// ObjectKey 文件对应的对象名
+func (s *StreamUpload) ObjectKey(fileName string) string {
+	return s.KeyPrefix + filepath.Base(fileName)
+}
+
+// ObjectURL 上报用的对象地址
+func (s *StreamUpload) ObjectURL(fileName string) string {
+	return fmt.Sprintf("s3://%s/%s", s.Bucket, s.ObjectKey(fileName))
+}
@@ -29,6 +29,9 @@ const (
 const (
 	BackupPhysical = "physical"
 	BackupLogical  = "logical"
+	// BackupIncremental 基于上一次物理备份(全备或增量)的 lsn 做增量物理备份
+	// 找不到可用的上一次物理备份时，退化为 physical 全备
+	BackupIncremental = "incremental"
 	// BackupTypeAuto 自动选择备份方式
 	// 磁盘空间数据量大于 BackupTypeAutoDataSizeGB ，物理备份
 	// glibc 版本小于 2.14，物理备份
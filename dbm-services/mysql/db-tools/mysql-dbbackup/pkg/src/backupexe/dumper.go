@@ -42,7 +42,8 @@ func BuildDumper(cnf *config.BackupConfig) (dumper Dumper, err error) {
 				cnf: cnf,
 			}
 		}
-	} else if strings.ToLower(cnf.Public.BackupType) == cst.BackupPhysical {
+	} else if strings.ToLower(cnf.Public.BackupType) == cst.BackupPhysical ||
+		strings.ToLower(cnf.Public.BackupType) == cst.BackupIncremental {
 		if err := validate.GoValidateStruct(cnf.PhysicalBackup, false, false); err != nil {
 			return nil, err
 		}
@@ -32,6 +32,8 @@ type PhysicalDumper struct {
 	backupStartTime             time.Time
 	backupEndTime               time.Time
 	tmpDisableSlaveMultiThreads bool
+	// baseBackup 增量备份所基于的上一次物理备份
+	baseBackup *dbareport.IndexContent
 }
 
 func (p *PhysicalDumper) initConfig(mysqlVerStr string) error {
@@ -76,6 +78,19 @@ func (p *PhysicalDumper) initConfig(mysqlVerStr string) error {
 		return err
 	}
 
+	if strings.ToLower(p.cnf.Public.BackupType) == cst.BackupIncremental {
+		if p.baseBackup, err = dbareport.LastPhysicalBackup(&p.cnf.Public); err != nil {
+			return err
+		} else if p.baseBackup == nil {
+			return errors.Errorf("no physical backup found in %s as incremental base", p.cnf.Public.ReportPath)
+		}
+		if p.baseBackup.MysqlVersion != mysqlVerStr {
+			return errors.Errorf("mysql version changed from %s to %s since base backup %s, need a physical backup",
+				p.baseBackup.MysqlVersion, mysqlVerStr, p.baseBackup.BackupId)
+		}
+		logger.Log.Infof("incremental backup base on backup_id=%s to_lsn=%s",
+			p.baseBackup.BackupId, p.baseBackup.ToLsn)
+	}
 	return nil
 }
 
@@ -95,6 +110,14 @@ func (p *PhysicalDumper) buildArgs() []string {
 		"--wait-last-flush=2",
 	}
 
+	// 基础备份已经打包上传，这里只用它的 lsn，不需要 --incremental-basedir
+	if p.baseBackup != nil {
+		if strings.Compare(p.mysqlVersion, "005007000") < 0 {
+			args = append(args, "--incremental")
+		}
+		args = append(args, fmt.Sprintf("--incremental-lsn=%s", p.baseBackup.ToLsn))
+	}
+
 	targetPath := filepath.Join(p.cnf.Public.BackupDir, p.cnf.Public.TargetName())
 	if strings.Compare(p.mysqlVersion, "005007000") < 0 {
 		args = append(args, targetPath)
@@ -158,7 +181,7 @@ func (p *PhysicalDumper) Execute(enableTimeOut bool) error {
 			_ = db.Close()
 		}()
 		if originVal, err := mysqlconn.SetGlobalVarAndReturnOrigin("slave_parallel_workers", "0", db); err != nil {
-			logger.Log.Error("set global slave_parallel_workers=0 failed, err: %s", err.Error())
+			logger.Log.Errorf("set global slave_parallel_workers=0 failed, err: %s", err.Error())
 			return err
 		} else {
 			logger.Log.Infof("will set global slave_parallel_workers=%s after backup finished", originVal)
@@ -240,6 +263,8 @@ func (p *PhysicalDumper) PrepareBackupMetaInfo(cnf *config.BackupConfig) (*dbare
 		"xtrabackup_binlog_info")
 	xtrabackupSlaveInfoFileName := filepath.Join(cnf.Public.BackupDir, cnf.Public.TargetName(),
 		"xtrabackup_slave_info")
+	xtrabackupCheckpointsFileName := filepath.Join(cnf.Public.BackupDir, cnf.Public.TargetName(),
+		"xtrabackup_checkpoints")
 
 	tmpFileName := filepath.Join(cnf.Public.BackupDir, cnf.Public.TargetName(), "tmp_dbbackup_go.txt")
 
@@ -265,6 +290,19 @@ func (p *PhysicalDumper) PrepareBackupMetaInfo(cnf *config.BackupConfig) (*dbare
 		logger.Log.Warnf("xtrabackup_timestamp_info file not found, use current time as Consistent Time")
 		metaInfo.BackupConsistentTime, _ = time.Parse(time.DateTime, p.backupEndTime.Format(time.DateTime))
 	}
+	// parse xtrabackup_checkpoints 记录 lsn 范围，用于后续增量备份
+	if checkpoints, err := parseXtraCheckpoints(xtrabackupCheckpointsFileName); err != nil {
+		if p.baseBackup != nil {
+			return nil, err
+		}
+		logger.Log.Warnf("parse xtrabackup_checkpoints failed, can not be used as incremental base: %s", err.Error())
+	} else {
+		metaInfo.FromLsn = checkpoints["from_lsn"]
+		metaInfo.ToLsn = checkpoints["to_lsn"]
+	}
+	if p.baseBackup != nil {
+		metaInfo.ParentBackupId = p.baseBackup.BackupId
+	}
 	// parse xtrabackup_binlog_info 本机的 binlog file,pos
 	if masterStatus, err := parseXtraBinlogInfo(qpressPath, xtrabackupBinlogInfoFileName, tmpFileName); err != nil {
 		return nil, err
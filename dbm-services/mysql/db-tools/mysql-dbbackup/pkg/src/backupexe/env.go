@@ -101,7 +101,8 @@ func SetEnv(backupType string, mysqlVersionStr string) error {
 	var binPath []string
 	if strings.ToLower(backupType) == cst.BackupLogical {
 		libPath = append(libPath, filepath.Join(ExecuteHome, "lib/libmydumper"))
-	} else if strings.ToLower(backupType) == cst.BackupPhysical {
+	} else if strings.ToLower(backupType) == cst.BackupPhysical ||
+		strings.ToLower(backupType) == cst.BackupIncremental {
 		_, isOfficial := util.VersionParser(mysqlVersionStr)
 		if !isOfficial {
 			libPath = append(libPath, filepath.Join(ExecuteHome, "lib/libxtra"))
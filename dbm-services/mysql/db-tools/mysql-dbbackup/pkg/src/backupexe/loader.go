@@ -24,8 +24,14 @@ func BuildLoader(cnf *config.BackupConfig, backupType string, useMysqldump bool)
 			if err := validate.GoValidateStruct(cnf.LogicalLoad, false, false); err != nil {
 				return nil, err
 			}
-			loader = &LogicalLoader{
-				cnf: cnf,
+			if strings.TrimSpace(cnf.LogicalLoad.Tables) != "" {
+				loader = &LogicalTableLoader{
+					cnf: cnf,
+				}
+			} else {
+				loader = &LogicalLoader{
+					cnf: cnf,
+				}
 			}
 		} else {
 			if err := validate.GoValidateStruct(cnf.LogicalLoadMysqldump, false, false); err != nil {
@@ -35,7 +41,8 @@ func BuildLoader(cnf *config.BackupConfig, backupType string, useMysqldump bool)
 				cnf: cnf,
 			}
 		}
-	} else if strings.ToLower(backupType) == cst.BackupPhysical {
+	} else if strings.ToLower(backupType) == cst.BackupPhysical ||
+		strings.ToLower(backupType) == cst.BackupIncremental {
 		if err := validate.GoValidateStruct(cnf.PhysicalLoad, false, false); err != nil {
 			return nil, err
 		}
@@ -0,0 +1,474 @@
+package backupexe
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"dbm-services/common/go-pubpkg/cmutil"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/cst"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/dbareport"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/logger"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/mysqlconn"
+)
+
+var (
+	reCreateTable = regexp.MustCompile("(?i)^\\s*CREATE\\s+TABLE\\s+(IF\\s+NOT\\s+EXISTS\\s+)?(`[^`]+`\\.)?`[^`]+`")
+	reInsertInto  = regexp.MustCompile("(?i)^\\s*(INSERT|REPLACE)\\s+(IGNORE\\s+)?INTO\\s+(`[^`]+`\\.)?`[^`]+`")
+	reInsertStmt  = regexp.MustCompile("(?i)^\\s*(INSERT|REPLACE)\\s")
+)
+
+// LogicalTableLoader 按表恢复 mydumper 备份
+// 只取指定表的 schema 和数据文件，可以恢复到其它库表，可以按条件过滤行
+type LogicalTableLoader struct {
+	cnf       *config.BackupConfig
+	index     *dbareport.IndexContent
+	tables    []*tableMapping
+	backupDir string
+	dbConn    *sql.DB
+}
+
+// tableMapping 源表和目标表
+type tableMapping struct {
+	SourceDb    string
+	SourceTable string
+	TargetDb    string
+	TargetTable string
+}
+
+// TableLoadResult 单表恢复结果
+type TableLoadResult struct {
+	SourceTable string `json:"source_table"`
+	TargetTable string `json:"target_table"`
+	RowFilter   string `json:"row_filter,omitempty"`
+	// LoadedRows 从备份导入的行数，有 RowFilter 时是导入临时表的行数
+	LoadedRows int64 `json:"loaded_rows"`
+	// RestoredRows 最终写入目标表的行数
+	RestoredRows int64 `json:"restored_rows"`
+}
+
+func (t *tableMapping) sourceName() string {
+	return t.SourceDb + "." + t.SourceTable
+}
+
+func (t *tableMapping) targetName() string {
+	return t.TargetDb + "." + t.TargetTable
+}
+
+// parseTableMappings 解析 db1.tb1,db1.tb2=db2.tb2_bak
+func parseTableMappings(tables string, targetDb string) ([]*tableMapping, error) {
+	var mappings []*tableMapping
+	for _, item := range strings.Split(tables, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if strings.Contains(item, "`") {
+			return nil, errors.Errorf("Tables has invalid character: %s", item)
+		}
+		srcTarget := strings.SplitN(item, "=", 2)
+		src := strings.SplitN(strings.TrimSpace(srcTarget[0]), ".", 2)
+		if len(src) != 2 || src[0] == "" || src[1] == "" {
+			return nil, errors.Errorf("Tables item should be db.table: %s", item)
+		}
+		t := &tableMapping{SourceDb: src[0], SourceTable: src[1], TargetDb: src[0], TargetTable: src[1]}
+		if targetDb != "" {
+			t.TargetDb = targetDb
+		}
+		if len(srcTarget) == 2 {
+			dst := strings.SplitN(strings.TrimSpace(srcTarget[1]), ".", 2)
+			if len(dst) != 2 || dst[0] == "" || dst[1] == "" {
+				return nil, errors.Errorf("Tables target should be db.table: %s", item)
+			}
+			t.TargetDb, t.TargetTable = dst[0], dst[1]
+		}
+		mappings = append(mappings, t)
+	}
+	if len(mappings) == 0 {
+		return nil, errors.New("no table to load")
+	}
+	return mappings, nil
+}
+
+func (l *LogicalTableLoader) initConfig(indexContent *dbareport.IndexContent) (err error) {
+	if l.cnf == nil {
+		return errors.New("logical table loader params is nil")
+	}
+	if l.cnf.LogicalLoad.MysqlLoadDir == "" {
+		return errors.New("MysqlLoadDir is required")
+	}
+	l.index = indexContent
+	l.backupDir = filepath.Dir(l.cnf.LogicalLoad.IndexFilePath)
+	if l.tables, err = parseTableMappings(l.cnf.LogicalLoad.Tables, l.cnf.LogicalLoad.TargetDb); err != nil {
+		return err
+	}
+	// RowFilter 直接拼接到 where 后面，不允许带多条语句
+	if strings.Contains(l.cnf.LogicalLoad.RowFilter, ";") {
+		return errors.Errorf("RowFilter should not contain ';': %s", l.cnf.LogicalLoad.RowFilter)
+	}
+	return nil
+}
+
+// wantFile 是否是需要恢复的表的 schema 或者数据文件
+func (l *LogicalTableLoader) wantFile(fileName string) bool {
+	name := strings.TrimSuffix(filepath.Base(fileName), cst.ZstdSuffix)
+	for _, t := range l.tables {
+		if name == t.sourceName()+"-schema.sql" {
+			return true
+		}
+		if m := reDataFile.FindStringSubmatch(name); len(m) == 3 && m[1] == t.sourceName() {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaFile 返回表结构文件，没有找到时返回空
+func (l *LogicalTableLoader) schemaFile(t *tableMapping) string {
+	fileName := filepath.Join(l.cnf.LogicalLoad.MysqlLoadDir, t.sourceName()+"-schema.sql")
+	if cmutil.FileExists(fileName) {
+		return fileName
+	} else if cmutil.FileExists(fileName + cst.ZstdSuffix) {
+		return fileName + cst.ZstdSuffix
+	}
+	return ""
+}
+
+// dataFiles 返回表的数据文件，按 chunk 顺序
+func (l *LogicalTableLoader) dataFiles(t *tableMapping) ([]string, error) {
+	entries, err := os.ReadDir(l.cnf.LogicalLoad.MysqlLoadDir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, f := range entries {
+		name := strings.TrimSuffix(f.Name(), cst.ZstdSuffix)
+		if m := reDataFile.FindStringSubmatch(name); len(m) == 3 && m[1] == t.sourceName() {
+			files = append(files, filepath.Join(l.cnf.LogicalLoad.MysqlLoadDir, f.Name()))
+		}
+	}
+	return files, nil
+}
+
+// prepareFiles MysqlLoadDir 已经是解包后的备份目录时直接使用
+// 否则根据 index 里记录的 contain_files，只从包含这些表的 tar 文件里解出需要的文件到 MysqlLoadDir
+func (l *LogicalTableLoader) prepareFiles() error {
+	loadDir := l.cnf.LogicalLoad.MysqlLoadDir
+	if cmutil.FileExists(filepath.Join(loadDir, "metadata")) {
+		logger.Log.Infof("use backup files in MysqlLoadDir %s", loadDir)
+		return nil
+	}
+	if err := os.MkdirAll(loadDir, 0755); err != nil {
+		return err
+	}
+	var selected, others []string
+	for _, f := range l.index.FileList {
+		if f.FileType != cst.FileTar {
+			continue
+		}
+		matched := false
+		for _, c := range f.ContainFiles {
+			if l.wantFile(c) {
+				matched = true
+				break
+			}
+		}
+		if matched {
+			selected = append(selected, f.FileName)
+		} else {
+			others = append(others, f.FileName)
+		}
+	}
+	for _, f := range selected {
+		if err := l.extractTar(f); err != nil {
+			return err
+		}
+	}
+	// 老版本打包时 contain_files 没有记录每个 tar 的第一个文件，找不到表结构时解其它的 tar
+	for _, t := range l.tables {
+		if l.schemaFile(t) != "" {
+			continue
+		}
+		logger.Log.Warnf("schema file for %s not found in selected tar files, try others", t.sourceName())
+		for _, f := range others {
+			if err := l.extractTar(f); err != nil {
+				return err
+			}
+		}
+		break
+	}
+	return nil
+}
+
+// extractTar 从一个 tar 文件里解出需要的文件
+func (l *LogicalTableLoader) extractTar(tarFile string) error {
+	if !strings.HasSuffix(tarFile, ".tar") {
+		return errors.Errorf("%s is encrypted, decrypt and extract it to MysqlLoadDir first", tarFile)
+	}
+	logger.Log.Infof("extract table files from %s to %s", tarFile, l.cnf.LogicalLoad.MysqlLoadDir)
+	f, err := os.Open(filepath.Join(l.backupDir, tarFile))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return untar(f, l.cnf.LogicalLoad.MysqlLoadDir, l.cnf.Public.IOLimitMBPerSec, func(name string) string {
+		if l.wantFile(name) {
+			return filepath.Base(name)
+		}
+		return ""
+	})
+}
+
+// Execute 按表恢复
+func (l *LogicalTableLoader) Execute() (err error) {
+	cnfPublic := config.Public{
+		MysqlHost:    l.cnf.LogicalLoad.MysqlHost,
+		MysqlPort:    l.cnf.LogicalLoad.MysqlPort,
+		MysqlUser:    l.cnf.LogicalLoad.MysqlUser,
+		MysqlPasswd:  l.cnf.LogicalLoad.MysqlPasswd,
+		MysqlCharset: l.cnf.LogicalLoad.MysqlCharset,
+	}
+	l.dbConn, err = mysqlconn.InitConn(&cnfPublic)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = l.dbConn.Close()
+	}()
+	if err = l.prepareFiles(); err != nil {
+		return err
+	}
+	for _, t := range l.tables {
+		if l.schemaFile(t) == "" {
+			return errors.Errorf("schema file for %s not found in backup", t.sourceName())
+		}
+	}
+
+	var totalRows int64
+	for _, t := range l.tables {
+		result, err := l.loadTable(t)
+		if err != nil {
+			return errors.WithMessagef(err, "load table %s to %s", t.sourceName(), t.targetName())
+		}
+		totalRows += result.RestoredRows
+		logger.Log.Infof("load table %s to %s, loaded rows %d, restored rows %d",
+			result.SourceTable, result.TargetTable, result.LoadedRows, result.RestoredRows)
+		// 结果输出到标准输出，给调用方解析
+		b, _ := json.Marshal(result)
+		fmt.Println(string(b))
+	}
+	logger.Log.Infof("load %d tables success, total restored rows %d", len(l.tables), totalRows)
+	return nil
+}
+
+// loadTable 恢复一个表
+// 没有 RowFilter 时直接导入目标表，有 RowFilter 时先导入临时表，再按条件写入目标表
+func (l *LogicalTableLoader) loadTable(t *tableMapping) (*TableLoadResult, error) {
+	result := &TableLoadResult{
+		SourceTable: t.sourceName(),
+		TargetTable: t.targetName(),
+		RowFilter:   l.cnf.LogicalLoad.RowFilter,
+	}
+	ctx := context.Background()
+	conn, err := l.dbConn.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if !l.cnf.LogicalLoad.EnableBinlog {
+		if _, err = conn.ExecContext(ctx, "set session sql_log_bin=off"); err != nil {
+			return nil, err
+		}
+	}
+	if _, err = conn.ExecContext(ctx, "set session foreign_key_checks=0"); err != nil {
+		return nil, err
+	}
+	if _, err = conn.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", t.TargetDb)); err != nil {
+		return nil, err
+	}
+
+	var exists int
+	err = conn.QueryRowContext(ctx,
+		"SELECT count(*) FROM information_schema.tables WHERE table_schema=? AND table_name=?",
+		t.TargetDb, t.TargetTable).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if exists > 0 && !l.cnf.LogicalLoad.CreateTableIfNotExists {
+		return nil, errors.Errorf("target table %s already exists, set CreateTableIfNotExists to append", t.targetName())
+	}
+
+	loadTable := t.TargetTable
+	if l.cnf.LogicalLoad.RowFilter != "" && !l.cnf.LogicalLoad.SchemaOnly {
+		loadTable = stagingTableName(t.TargetTable)
+		defer func() {
+			dropSql := fmt.Sprintf("DROP TABLE IF EXISTS `%s`.`%s`", t.TargetDb, loadTable)
+			if _, err := conn.ExecContext(ctx, dropSql); err != nil {
+				logger.Log.Warnf("drop staging table failed: %s", err.Error())
+			}
+		}()
+	}
+	qualifiedName := fmt.Sprintf("`%s`.`%s`", t.TargetDb, loadTable)
+	if loadTable != t.TargetTable || exists == 0 {
+		logger.Log.Infof("create table %s from %s", qualifiedName, l.schemaFile(t))
+		found := false
+		_, err := execSqlFile(ctx, conn, l.schemaFile(t), func(stmt []byte) ([]byte, error) {
+			newStmt, ok := rewriteCreateTable(stmt, qualifiedName)
+			found = found || ok
+			return newStmt, nil
+		})
+		if err != nil {
+			return nil, err
+		} else if !found {
+			return nil, errors.Errorf("no CREATE TABLE found in %s", l.schemaFile(t))
+		}
+	}
+	if l.cnf.LogicalLoad.SchemaOnly {
+		return result, nil
+	}
+
+	files, err := l.dataFiles(t)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		logger.Log.Infof("load %s into %s", filepath.Base(f), qualifiedName)
+		rows, err := execSqlFile(ctx, conn, f, func(stmt []byte) ([]byte, error) {
+			return rewriteInsert(stmt, qualifiedName)
+		})
+		if err != nil {
+			return nil, errors.WithMessagef(err, "load %s", filepath.Base(f))
+		}
+		result.LoadedRows += rows
+	}
+	if l.cnf.LogicalLoad.RowFilter == "" {
+		result.RestoredRows = result.LoadedRows
+		return result, nil
+	}
+
+	if exists == 0 {
+		createSql := fmt.Sprintf("CREATE TABLE `%s`.`%s` LIKE %s", t.TargetDb, t.TargetTable, qualifiedName)
+		if _, err = conn.ExecContext(ctx, createSql); err != nil {
+			return nil, err
+		}
+	}
+	insertSql := fmt.Sprintf("INSERT INTO `%s`.`%s` SELECT * FROM %s WHERE %s",
+		t.TargetDb, t.TargetTable, qualifiedName, l.cnf.LogicalLoad.RowFilter)
+	logger.Log.Infof("restore rows: %s", insertSql)
+	res, err := conn.ExecContext(ctx, insertSql)
+	if err != nil {
+		return nil, err
+	}
+	result.RestoredRows, _ = res.RowsAffected()
+	return result, nil
+}
+
+// stagingTableName 临时表名，表名最长 64 个字符
+func stagingTableName(table string) string {
+	suffix := fmt.Sprintf("_stg%d", time.Now().Unix())
+	if name := []rune(table); len(name)+len(suffix) > 64 {
+		table = string(name[:64-len(suffix)])
+	}
+	return table + suffix
+}
+
+// rewriteCreateTable 把 CREATE TABLE 语句的表名换成 qualifiedName，不是 CREATE TABLE 语句时原样返回 false
+func rewriteCreateTable(stmt []byte, qualifiedName string) ([]byte, bool) {
+	if loc := reCreateTable.FindIndex(stmt); loc != nil {
+		return spliceName(stmt, loc[1], "CREATE TABLE "+qualifiedName), true
+	}
+	return stmt, false
+}
+
+// rewriteInsert 把 INSERT/REPLACE 语句的表名换成 qualifiedName，保留 IGNORE。其它语句原样返回
+func rewriteInsert(stmt []byte, qualifiedName string) ([]byte, error) {
+	if !reInsertStmt.Match(stmt) {
+		return stmt, nil
+	}
+	loc := reInsertInto.FindSubmatchIndex(stmt)
+	if loc == nil {
+		return nil, errors.New("cannot find table name in insert statement")
+	}
+	verb := string(bytes.ToUpper(stmt[loc[2]:loc[3]]))
+	if loc[4] >= 0 {
+		verb += " IGNORE"
+	}
+	return spliceName(stmt, loc[1], verb+" INTO "+qualifiedName), nil
+}
+
+// spliceName 把语句开头 end 之前的部分替换成 prefix
+func spliceName(stmt []byte, end int, prefix string) []byte {
+	newStmt := make([]byte, 0, len(prefix)+len(stmt)-end)
+	newStmt = append(newStmt, prefix...)
+	return append(newStmt, stmt[end:]...)
+}
+
+// execSqlFile 逐条执行 mydumper 生成的 sql 文件，返回 insert 影响的行数
+func execSqlFile(ctx context.Context, conn *sql.Conn, fileName string,
+	rewrite func(stmt []byte) ([]byte, error)) (int64, error) {
+	f, err := openSqlFile(fileName)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var rows int64
+	err = splitSqlStatements(f, func(stmt []byte) error {
+		newStmt, err := rewrite(stmt)
+		if err != nil {
+			return err
+		}
+		res, err := conn.ExecContext(ctx, string(newStmt))
+		if err != nil {
+			return errors.Wrapf(err, "exec sql in %s", filepath.Base(fileName))
+		}
+		if reInsertStmt.Match(newStmt) {
+			affected, _ := res.RowsAffected()
+			rows += affected
+		}
+		return nil
+	})
+	return rows, err
+}
+
+// splitSqlStatements 按语句拆分 mydumper 生成的 sql，空语句跳过
+// mydumper 每条语句以行尾的 ; 结束，数据里的换行会被转义。stmt 在 fn 返回后会被复用
+func splitSqlStatements(r io.Reader, fn func(stmt []byte) error) error {
+	var stmt []byte
+	flush := func() error {
+		defer func() { stmt = stmt[:0] }()
+		if len(bytes.TrimSpace(stmt)) == 0 {
+			return nil
+		}
+		return fn(stmt)
+	}
+
+	br := bufio.NewReaderSize(r, 1024*1024)
+	for {
+		line, err := br.ReadBytes('\n')
+		stmt = append(stmt, line...)
+		if bytes.HasSuffix(bytes.TrimRight(line, " \t\r\n"), []byte(";")) {
+			if fnErr := flush(); fnErr != nil {
+				return fnErr
+			}
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return errors.WithMessage(err, "read sql statement")
+		}
+	}
+	return flush()
+}
@@ -0,0 +1,229 @@
+package backupexe
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/config"
+)
+
+func TestParseTableMappings(t *testing.T) {
+	cases := []struct {
+		tables   string
+		targetDb string
+		want     []string
+		fail     bool
+	}{
+		{"db1.tb1", "", []string{"db1.tb1=db1.tb1"}, false},
+		{" db1.tb1 , db1.tb2=db2.tb2_bak,", "", []string{"db1.tb1=db1.tb1", "db1.tb2=db2.tb2_bak"}, false},
+		{"db1.tb1,db1.tb2=db2.tb2_bak", "db3", []string{"db1.tb1=db3.tb1", "db1.tb2=db2.tb2_bak"}, false},
+		// 表名可以带点，只按第一个点拆分
+		{"db1.tb.1", "", []string{"db1.tb.1=db1.tb.1"}, false},
+		{"", "", nil, true},
+		{" , ", "", nil, true},
+		{"tb1", "", nil, true},
+		{"db1.", "", nil, true},
+		{".tb1", "", nil, true},
+		{"db1.tb1=db2", "", nil, true},
+		{"db1.tb1=.tb2", "", nil, true},
+		{"db1.`tb1`", "", nil, true},
+	}
+	for _, c := range cases {
+		mappings, err := parseTableMappings(c.tables, c.targetDb)
+		if (err != nil) != c.fail {
+			t.Errorf("parseTableMappings(%q, %q) err %v, want fail %v", c.tables, c.targetDb, err, c.fail)
+			continue
+		}
+		var got []string
+		for _, m := range mappings {
+			got = append(got, m.sourceName()+"="+m.targetName())
+		}
+		if strings.Join(got, ",") != strings.Join(c.want, ",") {
+			t.Errorf("parseTableMappings(%q, %q) = %v, want %v", c.tables, c.targetDb, got, c.want)
+		}
+	}
+}
+
+func TestInitConfigRowFilter(t *testing.T) {
+	cases := []struct {
+		rowFilter string
+		fail      bool
+	}{
+		{"", false},
+		{"id > 100 and ctime < '2024-01-01'", false},
+		{"id > 100; drop table db1.tb1", true},
+		{"id > 100;", true},
+	}
+	for _, c := range cases {
+		cnf := &config.BackupConfig{}
+		cnf.LogicalLoad.MysqlLoadDir = "/data/load"
+		cnf.LogicalLoad.IndexFilePath = "/data/backup/a.index"
+		cnf.LogicalLoad.Tables = "db1.tb1"
+		cnf.LogicalLoad.RowFilter = c.rowFilter
+		l := &LogicalTableLoader{cnf: cnf}
+		if err := l.initConfig(nil); (err != nil) != c.fail {
+			t.Errorf("RowFilter %q err %v, want fail %v", c.rowFilter, err, c.fail)
+		}
+	}
+}
+
+func TestWantFile(t *testing.T) {
+	tables, err := parseTableMappings("db1.tb1,db1.tb2=db2.tb2", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &LogicalTableLoader{tables: tables}
+	cases := []struct {
+		name string
+		want bool
+	}{
+		{"db1.tb1-schema.sql", true},
+		{"db1.tb1-schema.sql.zst", true},
+		{"backup_dir/db1.tb1-schema.sql", true},
+		{"db1.tb1.00000.sql", true},
+		{"db1.tb1.00001.sql.zst", true},
+		{"db1.tb1.00000.00002.sql", true},
+		{"db1.tb2.00000.sql", true},
+		{"db1.tb1-schema-triggers.sql", false},
+		{"db1.tb10.00000.sql", false},
+		{"db1.tb10-schema.sql", false},
+		{"db1-schema-create.sql", false},
+		{"db2.tb2.00000.sql", false},
+		{"db1.tb1.sql", false},
+		{"metadata", false},
+	}
+	for _, c := range cases {
+		if got := l.wantFile(c.name); got != c.want {
+			t.Errorf("wantFile(%s) = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestSpliceName(t *testing.T) {
+	stmt := []byte("INSERT INTO `tb1` VALUES(1);")
+	got := spliceName(stmt, len("INSERT INTO `tb1`"), "INSERT INTO `db2`.`tb2`")
+	if string(got) != "INSERT INTO `db2`.`tb2` VALUES(1);" {
+		t.Errorf("spliceName got %s", got)
+	}
+	if string(stmt) != "INSERT INTO `tb1` VALUES(1);" {
+		t.Errorf("spliceName should not modify source statement, got %s", stmt)
+	}
+}
+
+func TestRewriteCreateTable(t *testing.T) {
+	const name = "`db2`.`tb2_stg1`"
+	cases := []struct {
+		stmt string
+		want string
+		ok   bool
+	}{
+		{"CREATE TABLE `tb1` (\n  `id` int\n);", "CREATE TABLE " + name + " (\n  `id` int\n);", true},
+		{"/*!40101 SET NAMES binary*/;", "/*!40101 SET NAMES binary*/;", false},
+		{"create table if not exists `db1`.`tb1` (`id` int);", "CREATE TABLE " + name + " (`id` int);", true},
+		{"  CREATE   TABLE `a b`(`id` int);", "CREATE TABLE " + name + "(`id` int);", true},
+		{"CREATE TABLE tb1 (`id` int);", "CREATE TABLE tb1 (`id` int);", false},
+	}
+	for _, c := range cases {
+		got, ok := rewriteCreateTable([]byte(c.stmt), name)
+		if string(got) != c.want || ok != c.ok {
+			t.Errorf("rewriteCreateTable(%q) = %q %v, want %q %v", c.stmt, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestRewriteInsert(t *testing.T) {
+	const name = "`db2`.`tb2`"
+	cases := []struct {
+		stmt string
+		want string
+		fail bool
+	}{
+		{"INSERT INTO `tb1` VALUES(1),(2);", "INSERT INTO " + name + " VALUES(1),(2);", false},
+		{"insert into `db1`.`tb1` (`id`) VALUES(1);", "INSERT INTO " + name + " (`id`) VALUES(1);", false},
+		{"INSERT IGNORE INTO `tb1` VALUES(1);", "INSERT IGNORE INTO " + name + " VALUES(1);", false},
+		{"replace into `tb1` VALUES(1);", "REPLACE INTO " + name + " VALUES(1);", false},
+		// 数据里出现的表名不受影响
+		{"INSERT INTO `tb1` VALUES('INSERT INTO `tb1`');", "INSERT INTO " + name + " VALUES('INSERT INTO `tb1`');",
+			false},
+		{"/*!40014 SET FOREIGN_KEY_CHECKS=0*/;", "/*!40014 SET FOREIGN_KEY_CHECKS=0*/;", false},
+		{"SET NAMES binary;", "SET NAMES binary;", false},
+		{"INSERT INTO tb1 VALUES(1);", "", true},
+	}
+	for _, c := range cases {
+		got, err := rewriteInsert([]byte(c.stmt), name)
+		if (err != nil) != c.fail {
+			t.Errorf("rewriteInsert(%q) err %v, want fail %v", c.stmt, err, c.fail)
+			continue
+		}
+		if string(got) != c.want {
+			t.Errorf("rewriteInsert(%q) = %q, want %q", c.stmt, got, c.want)
+		}
+	}
+}
+
+func TestStagingTableName(t *testing.T) {
+	cases := []struct {
+		table  string
+		prefix string
+	}{
+		{"tb1", "tb1_stg"},
+		{strings.Repeat("a", 64), strings.Repeat("a", 50) + "_stg"},
+		{strings.Repeat("a", 50), strings.Repeat("a", 50) + "_stg"},
+		// 表名长度按字符计算，不能截断在多字节字符中间
+		{strings.Repeat("表", 60), strings.Repeat("表", 50) + "_stg"},
+	}
+	for _, c := range cases {
+		got := stagingTableName(c.table)
+		if !strings.HasPrefix(got, c.prefix) || utf8.RuneCountInString(got) > 64 || !utf8.ValidString(got) {
+			t.Errorf("stagingTableName(%d chars) = %s, want prefix %s and at most 64 chars",
+				utf8.RuneCountInString(c.table), got, c.prefix)
+		}
+	}
+}
+
+func TestSplitSqlStatements(t *testing.T) {
+	cases := []struct {
+		name  string
+		sql   string
+		stmts []string
+	}{
+		{"one per line", "SET NAMES binary;\nINSERT INTO `t` VALUES(1);\n",
+			[]string{"SET NAMES binary;\n", "INSERT INTO `t` VALUES(1);\n"}},
+		{"multi line statement", "CREATE TABLE `t` (\n  `id` int\n) ENGINE=InnoDB;\n",
+			[]string{"CREATE TABLE `t` (\n  `id` int\n) ENGINE=InnoDB;\n"}},
+		{"multi line insert", "INSERT INTO `t` VALUES\n(1,'a;'),\n(2,'b');\n",
+			[]string{"INSERT INTO `t` VALUES\n(1,'a;'),\n(2,'b');\n"}},
+		{"trailing blank and crlf", "SET NAMES binary; \r\n\n\nSET a=1;\t\n",
+			[]string{"SET NAMES binary; \r\n", "\n\nSET a=1;\t\n"}},
+		{"no newline at end", "SET a=1;\nSET b=2;", []string{"SET a=1;\n", "SET b=2;"}},
+		{"last statement without semicolon", "SET a=1;\nSET b=2\n", []string{"SET a=1;\n", "SET b=2\n"}},
+		{"empty", "\n\n", nil},
+	}
+	for _, c := range cases {
+		var got []string
+		err := splitSqlStatements(strings.NewReader(c.sql), func(stmt []byte) error {
+			got = append(got, string(stmt))
+			return nil
+		})
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if strings.Join(got, "|") != strings.Join(c.stmts, "|") || len(got) != len(c.stmts) {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.stmts)
+		}
+	}
+
+	// 回调出错时停止
+	calls := 0
+	wantErr := errors.New("exec failed")
+	err := splitSqlStatements(strings.NewReader("SET a=1;\nSET b=2;\n"), func(stmt []byte) error {
+		calls++
+		return wantErr
+	})
+	if !errors.Is(err, wantErr) || calls != 1 {
+		t.Errorf("split should stop at first error, err %v calls %d", err, calls)
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"dbm-services/common/go-pubpkg/cmutil"
 	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/cst"
 	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/dbareport"
 	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/logger"
 	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/util"
@@ -25,6 +26,16 @@ type PhysicalLoader struct {
 	storageEngine string
 	innodbCmd     InnodbCommand
 	isOfficial    bool
+	// fullBackup 增量恢复时链路最前面的全备，对应 MysqlLoadDir
+	fullBackup *dbareport.IndexContent
+	// incrementals 增量备份链，按恢复顺序排列，不包含全备
+	incrementals []*incrementalBackup
+}
+
+// incrementalBackup 一个增量备份和它解压后的目录
+type incrementalBackup struct {
+	index   *dbareport.IndexContent
+	loadDir string
 }
 
 func (p *PhysicalLoader) initConfig(indexContent *dbareport.IndexContent) error {
@@ -42,6 +53,70 @@ func (p *PhysicalLoader) initConfig(indexContent *dbareport.IndexContent) error
 	if err := p.innodbCmd.ChooseXtrabackupTool(p.mysqlVersion, p.isOfficial); err != nil {
 		return err
 	}
+	if strings.ToLower(indexContent.BackupType) == cst.BackupIncremental {
+		if err := p.buildIncrementalChain(indexContent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildIncrementalChain 从增量备份的 index 开始，按 parent_backup_id 在 index 文件所在目录里向上查找，直到全备
+// 要求上下两个备份的 lsn 是连续的
+func (p *PhysicalLoader) buildIncrementalChain(indexContent *dbareport.IndexContent) error {
+	indexDir := filepath.Dir(p.cnf.PhysicalLoad.IndexFilePath)
+	loadDir := p.cnf.PhysicalLoad.IncrementalLoadDir
+	if loadDir == "" {
+		loadDir = indexDir
+	}
+	indexFiles, err := filepath.Glob(filepath.Join(indexDir, "*.index"))
+	if err != nil {
+		return err
+	}
+	backups := make(map[string]*incrementalBackup)
+	for _, f := range indexFiles {
+		index, err := ParseJsonFile(f)
+		if err != nil {
+			logger.Log.Warnf("skip index file %s: %s", f, err.Error())
+			continue
+		}
+		backups[index.BackupId] = &incrementalBackup{
+			index:   index,
+			loadDir: filepath.Join(loadDir, strings.TrimSuffix(filepath.Base(f), ".index")),
+		}
+	}
+
+	current := backups[indexContent.BackupId]
+	if current == nil {
+		return errors.Errorf("backup_id %s not found in %s", indexContent.BackupId, indexDir)
+	}
+	for strings.ToLower(current.index.BackupType) == cst.BackupIncremental {
+		if len(p.incrementals) > len(backups) {
+			return errors.Errorf("incremental chain loop found from backup_id %s", indexContent.BackupId)
+		}
+		p.incrementals = append([]*incrementalBackup{current}, p.incrementals...)
+		parent := backups[current.index.ParentBackupId]
+		if parent == nil {
+			return errors.Errorf("parent backup %s of %s not found in %s",
+				current.index.ParentBackupId, current.index.BackupId, indexDir)
+		}
+		if parent.index.ToLsn != current.index.FromLsn {
+			return errors.Errorf("lsn not continuous: backup %s to_lsn=%s, backup %s from_lsn=%s",
+				parent.index.BackupId, parent.index.ToLsn, current.index.BackupId, current.index.FromLsn)
+		}
+		current = parent
+	}
+	if strings.ToLower(current.index.BackupType) != cst.BackupPhysical {
+		return errors.Errorf("backup %s with type %s can not be used as incremental base",
+			current.index.BackupId, current.index.BackupType)
+	}
+	p.fullBackup = current.index
+	for _, inc := range p.incrementals {
+		logger.Log.Infof("incremental chain: %s from_lsn=%s to_lsn=%s dir=%s",
+			inc.index.BackupId, inc.index.FromLsn, inc.index.ToLsn, inc.loadDir)
+	}
+	logger.Log.Infof("incremental chain base on physical backup %s to_lsn=%s dir=%s",
+		p.fullBackup.BackupId, p.fullBackup.ToLsn, p.cnf.PhysicalLoad.MysqlLoadDir)
 	return nil
 }
 
@@ -53,12 +128,16 @@ func (p *PhysicalLoader) Execute() error {
 		return err
 	}
 
-	err := p.decompress()
+	if len(p.incrementals) > 0 {
+		return p.executeIncremental()
+	}
+
+	err := p.decompress(p.cnf.PhysicalLoad.MysqlLoadDir)
 	if err != nil {
 		return err
 	}
 
-	err = p.apply()
+	err = p.apply("", false)
 	if err != nil {
 		return err
 	}
@@ -71,7 +150,39 @@ func (p *PhysicalLoader) Execute() error {
 	return nil
 }
 
-func (p *PhysicalLoader) decompress() error {
+// executeIncremental 先对全备 prepare(只 redo)，再依次合并增量备份，最后一个增量合并时做完整的 prepare
+func (p *PhysicalLoader) executeIncremental() error {
+	if err := p.decompress(p.cnf.PhysicalLoad.MysqlLoadDir); err != nil {
+		return err
+	}
+	// 确认 MysqlLoadDir 确实是增量链路的全备
+	checkpoints, err := parseXtraCheckpoints(filepath.Join(p.cnf.PhysicalLoad.MysqlLoadDir, "xtrabackup_checkpoints"))
+	if err != nil {
+		return err
+	}
+	if checkpoints["to_lsn"] != p.fullBackup.ToLsn {
+		return errors.Errorf("MysqlLoadDir %s to_lsn=%s does not match physical backup %s to_lsn=%s",
+			p.cnf.PhysicalLoad.MysqlLoadDir, checkpoints["to_lsn"], p.fullBackup.BackupId, p.fullBackup.ToLsn)
+	}
+	for _, inc := range p.incrementals {
+		if err = p.decompress(inc.loadDir); err != nil {
+			return err
+		}
+	}
+
+	if err = p.apply("", true); err != nil {
+		return err
+	}
+	for i, inc := range p.incrementals {
+		if err = p.apply(inc.loadDir, i < len(p.incrementals)-1); err != nil {
+			return err
+		}
+	}
+	return p.load()
+}
+
+// decompress 解压 targetDir 里 xtrabackup 压缩的文件
+func (p *PhysicalLoader) decompress(targetDir string) error {
 	binPath := filepath.Join(p.dbbackupHome, p.innodbCmd.innobackupexBin)
 
 	args := []string{
@@ -80,10 +191,10 @@ func (p *PhysicalLoader) decompress() error {
 		fmt.Sprintf("--parallel=%d", p.cnf.PhysicalLoad.Threads),
 	}
 	if strings.Compare(p.mysqlVersion, "005007000") < 0 {
-		args = append(args, p.cnf.PhysicalLoad.MysqlLoadDir)
+		args = append(args, targetDir)
 	} else {
 		args = append(args, []string{
-			fmt.Sprintf("--target-dir=%s", p.cnf.PhysicalLoad.MysqlLoadDir),
+			fmt.Sprintf("--target-dir=%s", targetDir),
 		}...)
 	}
 	if strings.Compare(p.mysqlVersion, "008000000") >= 0 && p.isOfficial {
@@ -106,7 +217,10 @@ func (p *PhysicalLoader) decompress() error {
 	return nil
 }
 
-func (p *PhysicalLoader) apply() error {
+// apply prepare MysqlLoadDir
+// incrementalDir 不为空时，把这个增量备份合并到 MysqlLoadDir
+// redoOnly 只应用 redo 不回滚未提交事务，后面还有增量备份要合并时需要
+func (p *PhysicalLoader) apply(incrementalDir string, redoOnly bool) error {
 	binPath := filepath.Join(p.dbbackupHome, p.innodbCmd.innobackupexBin)
 
 	args := []string{
@@ -118,8 +232,17 @@ func (p *PhysicalLoader) apply() error {
 
 	if strings.Compare(p.mysqlVersion, "005007000") < 0 {
 		args = append(args, "--apply-log")
+		if redoOnly {
+			args = append(args, "--redo-only")
+		}
 	} else {
 		args = append(args, "--prepare")
+		if redoOnly {
+			args = append(args, "--apply-log-only")
+		}
+	}
+	if incrementalDir != "" {
+		args = append(args, fmt.Sprintf("--incremental-dir=%s", incrementalDir))
 	}
 
 	if strings.Compare(p.mysqlVersion, "005007000") < 0 {
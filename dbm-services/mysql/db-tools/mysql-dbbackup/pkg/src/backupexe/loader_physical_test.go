@@ -0,0 +1,158 @@
+package backupexe
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/cst"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/dbareport"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/logger"
+)
+
+func TestParseXtraCheckpoints(t *testing.T) {
+	logger.Log = logrus.New()
+	dir := t.TempDir()
+	cases := []struct {
+		name    string
+		content string
+		toLsn   string
+		fromLsn string
+		wantErr bool
+	}{
+		{
+			name:    "full",
+			content: "backup_type = full-backuped\nfrom_lsn = 0\nto_lsn = 2618726\nlast_lsn = 2618735\n",
+			toLsn:   "2618726",
+			fromLsn: "0",
+		},
+		{
+			name:    "incremental without spaces",
+			content: "backup_type=incremental\nfrom_lsn=2618726\nto_lsn=2700001\n",
+			toLsn:   "2700001",
+			fromLsn: "2618726",
+		},
+		{
+			name:    "no to_lsn",
+			content: "backup_type = full-backuped\nfrom_lsn = 0\n",
+			wantErr: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := filepath.Join(dir, strings.ReplaceAll(c.name, " ", "_"))
+			if err := os.WriteFile(f, []byte(c.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			checkpoints, err := parseXtraCheckpoints(f)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("expect error, got %v", checkpoints)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if checkpoints["to_lsn"] != c.toLsn || checkpoints["from_lsn"] != c.fromLsn {
+				t.Fatalf("unexpected checkpoints %v", checkpoints)
+			}
+		})
+	}
+
+	if _, err := parseXtraCheckpoints(filepath.Join(dir, "not_exists")); err == nil {
+		t.Fatal("missing file should fail")
+	}
+}
+
+type testBackup struct {
+	id, backupType, parent, fromLsn, toLsn string
+}
+
+// writeIndexFiles 每个备份写一个 <id>.index
+func writeIndexFiles(t *testing.T, dir string, backups ...testBackup) {
+	for _, b := range backups {
+		index := dbareport.IndexContent{}
+		index.BackupId = b.id
+		index.BackupType = b.backupType
+		index.ParentBackupId = b.parent
+		index.FromLsn = b.fromLsn
+		index.ToLsn = b.toLsn
+		buf, err := json.Marshal(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(filepath.Join(dir, b.id+".index"), buf, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBuildIncrementalChain(t *testing.T) {
+	logger.Log = logrus.New()
+	full := testBackup{"full", cst.BackupPhysical, "", "0", "100"}
+	inc1 := testBackup{"inc1", cst.BackupIncremental, "full", "100", "200"}
+	inc2 := testBackup{"inc2", cst.BackupIncremental, "inc1", "200", "300"}
+
+	cases := []struct {
+		name    string
+		backups []testBackup
+		target  string
+		chain   []string
+		wantErr string
+	}{
+		{"single incremental", []testBackup{full, inc1}, "inc1", []string{"inc1"}, ""},
+		{"chain", []testBackup{full, inc1, inc2}, "inc2", []string{"inc1", "inc2"}, ""},
+		{"stop at target", []testBackup{full, inc1, inc2}, "inc1", []string{"inc1"}, ""},
+		{"missing parent", []testBackup{full, inc2}, "inc2", nil, "parent backup inc1"},
+		{"lsn gap", []testBackup{full, inc1, {"inc2", cst.BackupIncremental, "inc1", "250", "300"}},
+			"inc2", nil, "lsn not continuous"},
+		{"base not physical", []testBackup{{"full", cst.BackupLogical, "", "", "100"}, inc1},
+			"inc1", nil, "can not be used as incremental base"},
+		{"loop", []testBackup{{"inc1", cst.BackupIncremental, "inc2", "200", "200"},
+			{"inc2", cst.BackupIncremental, "inc1", "200", "200"}}, "inc2", nil, "loop"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeIndexFiles(t, dir, c.backups...)
+			p := &PhysicalLoader{cnf: &config.BackupConfig{}}
+			p.cnf.PhysicalLoad.IndexFilePath = filepath.Join(dir, c.target+".index")
+			p.cnf.PhysicalLoad.IncrementalLoadDir = "/data/load"
+
+			target, err := ParseJsonFile(p.cnf.PhysicalLoad.IndexFilePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = p.buildIncrementalChain(target)
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Fatalf("expect error contains %q, got %v", c.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.fullBackup.BackupId != "full" {
+				t.Fatalf("expect base full, got %s", p.fullBackup.BackupId)
+			}
+			var got []string
+			for _, inc := range p.incrementals {
+				got = append(got, inc.index.BackupId)
+				if inc.loadDir != filepath.Join("/data/load", inc.index.BackupId) {
+					t.Errorf("unexpected load dir %s", inc.loadDir)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(c.chain, ",") {
+				t.Fatalf("chain got %v, want %v", got, c.chain)
+			}
+		})
+	}
+}
@@ -252,3 +252,27 @@ func parseXtraSlaveInfo(qpress string, fileName string, tmpFileName string) (*db
 	logger.Log.Warnf("parseXtraSlaveInfo=%+v", showSlaveStatus)
 	return showSlaveStatus, nil
 }
+
+// parseXtraCheckpoints parse xtrabackup_checkpoints to get lsn info
+// xtrabackup 不会压缩这个文件，内容如 backup_type = full-backuped, from_lsn = 0, to_lsn = 123
+func parseXtraCheckpoints(fileName string) (map[string]string, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	checkpoints := map[string]string{}
+	buf := bufio.NewScanner(f)
+	for buf.Scan() {
+		kv := strings.SplitN(buf.Text(), "=", 2)
+		if len(kv) == 2 {
+			checkpoints[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+	if checkpoints["to_lsn"] == "" {
+		return nil, errors.Errorf("to_lsn not found in %s", fileName)
+	}
+	return checkpoints, nil
+}
@@ -2,7 +2,11 @@ package backupexe
 
 import (
 	"archive/tar"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
 	"io/fs"
 	"math"
@@ -13,6 +17,8 @@ import (
 	"strings"
 
 	"dbm-services/common/go-pubpkg/cmutil"
+	"dbm-services/common/go-pubpkg/iocrypt"
+	"dbm-services/common/go-pubpkg/objectstore"
 	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/config"
 	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/cst"
 	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/dbareport"
@@ -33,6 +39,13 @@ type PackageFile struct {
 	cnf           *config.BackupConfig
 	indexFile     *dbareport.IndexContent
 	indexFilePath string
+
+	// uploader 开启 StreamUpload 时，打包文件直接写到对象存储
+	uploader objectstore.Uploader
+	// uploadWriters 流式上传的文件，文件名: writer
+	uploadWriters map[string]objectstore.ObjectWriter
+	// checksums 打包时边写边算的 md5，文件名: hash
+	checksums map[string]hash.Hash
 }
 
 // MappingPackage Package multiple backup files
@@ -50,7 +63,7 @@ func (p *PackageFile) MappingPackage() (string, error) {
 
 	var tarSize uint64 = 0
 	tarFileNum := 0
-	var tarUtil = util.TarWriter{IOLimitMB: p.cnf.Public.IOLimitMBPerSec}
+	var tarUtil = util.TarWriter{IOLimitMB: p.cnf.Public.IOLimitMBPerSec, CreateFile: p.createFile}
 	var dstTarName = fmt.Sprintf(`%s_%d.tar`, p.dstDir, tarFileNum)
 	if p.cnf.Public.EncryptOpt.EncryptEnable {
 		logger.Log.Infof("tar file encrypt enabled for port: %d", p.cnf.Public.MysqlPort)
@@ -92,10 +105,9 @@ func (p *PackageFile) MappingPackage() (string, error) {
 		tarFileName := filepath.Base(dstTarName)
 		if _, ok := tarFiles[tarFileName]; !ok {
 			tarFiles[tarFileName] = &dbareport.TarFileItem{FileName: tarFileName, FileType: cst.FileTar}
-		} else {
-			tarFiles[tarFileName].ContainFiles = append(tarFiles[tarFileName].ContainFiles,
-				strings.TrimPrefix(strings.TrimPrefix(filename, p.srcDir), "/"))
 		}
+		tarFiles[tarFileName].ContainFiles = append(tarFiles[tarFileName].ContainFiles,
+			strings.TrimPrefix(strings.TrimPrefix(filename, p.srcDir), "/"))
 		tarFiles[tarFileName].FileSize += written
 
 		if totalSizeUncompress > -1 && strings.HasSuffix(filename, cst.ZstdSuffix) {
@@ -135,6 +147,9 @@ func (p *PackageFile) MappingPackage() (string, error) {
 		return "", walkErr
 	}
 	logger.Log.Infof("need to tar file, accumulated tar size: %d bytes, dstFile: %s", tarSize, dstTarName)
+	if err := tarUtil.Close(); err != nil {
+		return "", err
+	}
 	p.indexFile.TotalSizeKBUncompress = totalSizeUncompress / 1024
 	p.indexFile.TotalFilesize = backupTotalFileSize + tarSize
 
@@ -195,7 +210,7 @@ func (p *PackageFile) SplittingPackage2() (string, error) {
 // remove srcDir if success
 func (p *PackageFile) tarballDir() error {
 	logger.Log.Infof("Tarball Package: src dir %s, iolimit %d MB/s", p.srcDir, p.cnf.Public.IOLimitMBPerSec)
-	var tarUtil = util.TarWriter{IOLimitMB: p.cnf.Public.IOLimitMBPerSec}
+	var tarUtil = util.TarWriter{IOLimitMB: p.cnf.Public.IOLimitMBPerSec, CreateFile: p.createFile}
 	if err := tarUtil.New(p.dstTarFile); err != nil {
 		return err
 	}
@@ -247,7 +262,7 @@ func (p *PackageFile) tarballDir() error {
 func (p *PackageFile) tarAndSplit() (string, error) {
 	logger.Log.Infof("Tarball Package: src dir %s, iolimit %d MB/s", p.srcDir, p.cnf.Public.IOLimitMBPerSec)
 
-	var tarUtil = util.TarWriter{IOLimitMB: p.cnf.Public.IOLimitMBPerSec}
+	var tarUtil = util.TarWriter{IOLimitMB: p.cnf.Public.IOLimitMBPerSec, CreateFile: p.createFile}
 	var dstTarName = fmt.Sprintf(`%s.tar`, p.dstDir)
 	if p.cnf.Public.EncryptOpt.EncryptEnable {
 		logger.Log.Infof("tar file encrypt enabled for port: %d", p.cnf.Public.MysqlPort)
@@ -294,6 +309,10 @@ func (p *PackageFile) tarAndSplit() (string, error) {
 		logger.Log.Error("walk dir, err: ", walkErr)
 		return "", walkErr
 	}
+	// 关闭后最后一个分片的大小才是准确的
+	if err := tarUtil.Close(); err != nil {
+		return "", err
+	}
 	for filename, filesize := range tarUtil.GetSplitTars() {
 		tarFileName := filepath.Base(filename)
 		tarFile := &dbareport.TarFileItem{FileName: tarFileName, FileType: cst.FilePart, FileSize: int64(filesize)}
@@ -356,10 +375,11 @@ func (p *PackageFile) splitTarFile(destFile string) error {
 	for i := 0; i < partNum; i++ {
 		dstTarName := strings.TrimSuffix(destFile, ".tar")
 		partTarName := fmt.Sprintf(`%s.part_%0*d`, dstTarName, paddingSize, i) // need to be same with ReSplitPart
-		destFileWriter, err := os.OpenFile(partTarName, os.O_CREATE|os.O_WRONLY, os.ModePerm)
+		partFile, err := os.OpenFile(partTarName, os.O_CREATE|os.O_WRONLY, os.ModePerm)
 		if err != nil {
 			return err
 		}
+		destFileWriter := p.hashFile(partTarName, partFile)
 		// io.Copy will record fi Seek Position
 		if written, err := cmutil.IOLimitRateWithChunk(destFileWriter, fi, splitSpeed, filePartSize); err == nil {
 			_ = destFileWriter.Close()
@@ -399,8 +419,19 @@ func PackageBackupFiles(cnf *config.BackupConfig, metaInfo *dbareport.IndexConte
 		dstTarFile: targetDir + ".tar",
 		cnf:        cnf,
 		indexFile:  metaInfo,
+		checksums:  make(map[string]hash.Hash),
 	}
 	logger.Log.Infof("Index BackupMetaInfo:%+v", metaInfo)
+	if cnf.StreamUpload.Enable {
+		if err = packageFile.initStreamUpload(); err != nil {
+			return "", err
+		}
+		defer func() {
+			if err != nil {
+				packageFile.abortUploads()
+			}
+		}()
+	}
 
 	// package files, and produce the index file at the same time
 	if strings.ToLower(cnf.Public.BackupType) == cst.BackupLogical {
@@ -413,17 +444,145 @@ func PackageBackupFiles(cnf *config.BackupConfig, metaInfo *dbareport.IndexConte
 				return "", err
 			}
 		}
-	} else if strings.ToLower(cnf.Public.BackupType) == cst.BackupPhysical {
+	} else if strings.ToLower(cnf.Public.BackupType) == cst.BackupPhysical ||
+		strings.ToLower(cnf.Public.BackupType) == cst.BackupIncremental {
 		if indexFilePath, err = packageFile.SplittingPackage2(); err != nil {
 			return "", err
 		}
 	}
+	// 打包的文件都已经关闭，记录 md5 后重新保存 index，用于 verify 校验
+	if err = packageFile.checksumFiles(); err != nil {
+		return "", err
+	}
+	if indexFilePath, err = metaInfo.SaveIndexContent(&cnf.Public); err != nil {
+		return "", err
+	}
+	if packageFile.uploader != nil {
+		if err = packageFile.uploadMetaFiles(indexFilePath); err != nil {
+			return "", err
+		}
+	}
 	// 把 index file 本身的信息，也记录到 file_list，用于文件上报
 	packageFile.indexFilePath = indexFilePath
 	//packageFile.indexFile.AddIndexFileItem(packageFile.dstDir)
 	return packageFile.indexFilePath, nil
 }
 
+// checksumFiles 记录 index file_list 里每个文件的 md5
+// 打包文件在写入时已经算好，只有 priv 文件等没有经过打包写入的文件需要重新读一遍
+func (p *PackageFile) checksumFiles() error {
+	for _, f := range p.indexFile.FileList {
+		if h, ok := p.checksums[f.FileName]; ok {
+			f.Md5 = hex.EncodeToString(h.Sum(nil))
+			continue
+		}
+		logger.Log.Infof("checksum %s, io is limited to: %d MB/s", f.FileName, p.cnf.Public.IOLimitMBPerSec)
+		md5sum, err := util.FileMd5(filepath.Join(p.cnf.Public.BackupDir, f.FileName), p.cnf.Public.IOLimitMBPerSec)
+		if err != nil {
+			return err
+		}
+		f.Md5 = md5sum
+	}
+	return nil
+}
+
+// hashWriter 写入目标文件的同时计算 md5
+type hashWriter struct {
+	io.WriteCloser
+	w    io.Writer
+	hash hash.Hash
+}
+
+// Write 先写目标文件，写成功的部分才计入 md5
+func (w *hashWriter) Write(b []byte) (int, error) {
+	return w.w.Write(b)
+}
+
+// hashFile 包装目标文件，边写边算 md5
+// 外部命令加密时输出是异步写入的，写入过程不可控，这种情况打包后再读文件计算
+func (p *PackageFile) hashFile(fileName string, w io.WriteCloser) io.WriteCloser {
+	if p.uploader == nil && p.cnf.Public.EncryptOpt != nil && p.cnf.Public.EncryptOpt.EncryptEnable {
+		if _, ok := p.cnf.Public.EncryptOpt.GetEncryptTool().(iocrypt.StreamEncryptTool); !ok {
+			return w
+		}
+	}
+	h := md5.New()
+	p.checksums[filepath.Base(fileName)] = h
+	return &hashWriter{WriteCloser: w, w: io.MultiWriter(w, h), hash: h}
+}
+
+// initStreamUpload 初始化对象存储客户端
+func (p *PackageFile) initStreamUpload() error {
+	s := p.cnf.StreamUpload
+	client, err := objectstore.NewS3Client(objectstore.S3Config{
+		Endpoint:   s.Endpoint,
+		Region:     s.Region,
+		Bucket:     s.Bucket,
+		AccessKey:  s.AccessKey,
+		SecretKey:  s.SecretKey,
+		PartSizeMB: s.PartSizeMB,
+		Retries:    s.Retries,
+		TimeoutSec: s.TimeoutSec,
+	})
+	if err != nil {
+		return errors.WithMessage(err, "init StreamUpload")
+	}
+	p.uploader = client
+	p.uploadWriters = make(map[string]objectstore.ObjectWriter)
+	return nil
+}
+
+// createFile 用于 TarWriter.CreateFile，创建打包文件并边写边算 md5
+// 开启 StreamUpload 时打包文件不落盘，直接上传
+func (p *PackageFile) createFile(fileName string) (io.WriteCloser, error) {
+	if p.uploader == nil {
+		f, err := os.Create(fileName)
+		if err != nil {
+			return nil, err
+		}
+		return p.hashFile(fileName, f), nil
+	}
+	logger.Log.Infof("stream upload %s to %s", filepath.Base(fileName), p.cnf.StreamUpload.ObjectURL(fileName))
+	w, err := p.uploader.NewWriter(context.Background(), p.cnf.StreamUpload.ObjectKey(fileName))
+	if err != nil {
+		return nil, err
+	}
+	p.uploadWriters[filepath.Base(fileName)] = w
+	return p.hashFile(fileName, w), nil
+}
+
+// abortUploads 打包失败时放弃未完成的上传
+func (p *PackageFile) abortUploads() {
+	for fileName, w := range p.uploadWriters {
+		if err := w.Abort(); err != nil {
+			logger.Log.Warnf("abort upload %s failed: %s", fileName, err.Error())
+		}
+	}
+}
+
+// uploadMetaFiles 打包文件都上传完成后，上传 priv 文件，最后上传 index 文件
+// 对象存储里有 index 文件，说明这个备份是完整的
+func (p *PackageFile) uploadMetaFiles(indexFilePath string) error {
+	var metaFiles []string
+	for _, f := range p.indexFile.FileList {
+		if f.FileType == cst.FilePriv {
+			metaFiles = append(metaFiles, filepath.Join(p.cnf.Public.BackupDir, f.FileName))
+		}
+	}
+	metaFiles = append(metaFiles, indexFilePath)
+	for _, fileName := range metaFiles {
+		body, err := os.ReadFile(fileName)
+		if err != nil {
+			return err
+		}
+		logger.Log.Infof("upload %s to %s", filepath.Base(fileName), p.cnf.StreamUpload.ObjectURL(fileName))
+		if err = p.uploader.PutObject(context.Background(), p.cnf.StreamUpload.ObjectKey(fileName), body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // readUncompressSizeForZstd godoc
 // Frames  Skips  Compressed  Uncompressed  Ratio  Check  Filename
 //
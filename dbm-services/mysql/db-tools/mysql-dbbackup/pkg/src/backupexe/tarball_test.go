@@ -0,0 +1,61 @@
+package backupexe
+
+import (
+	"hash"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/cst"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/dbareport"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/logger"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/util"
+)
+
+// TestChecksumFiles 打包写入时算的 md5 要和落盘文件一致，没经过打包写入的文件重新读取计算
+func TestChecksumFiles(t *testing.T) {
+	logger.Log = logrus.New()
+	dir := t.TempDir()
+	cnf := &config.BackupConfig{}
+	cnf.Public.BackupDir = dir
+	p := &PackageFile{cnf: cnf, indexFile: &dbareport.IndexContent{}, checksums: make(map[string]hash.Hash)}
+
+	w, err := p.createFile(filepath.Join(dir, "a_0.tar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if _, err = w.Write([]byte("some backup content\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "a.priv"), []byte("grants"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p.indexFile.FileList = []*dbareport.TarFileItem{
+		{FileName: "a_0.tar", FileType: cst.FileTar},
+		{FileName: "a.priv", FileType: cst.FilePriv},
+	}
+	if err = p.checksumFiles(); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range p.indexFile.FileList {
+		want, err := util.FileMd5(filepath.Join(dir, f.FileName), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Md5 != want {
+			t.Errorf("%s md5 got %s, want %s", f.FileName, f.Md5, want)
+		}
+	}
+	if _, ok := p.checksums["a.priv"]; ok {
+		t.Error("priv file is not written by package, should not have write path checksum")
+	}
+}
@@ -0,0 +1,660 @@
+package backupexe
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	errs "errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+
+	"dbm-services/common/go-pubpkg/cmutil"
+	"dbm-services/common/go-pubpkg/iocrypt"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/cst"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/dbareport"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/logger"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/util"
+)
+
+// VerifyOption verify 子命令参数
+type VerifyOption struct {
+	// IndexFile 要校验的备份 index 文件，备份文件需要在同一个目录
+	IndexFile string
+	// WorkDir 解包目录，为空时使用 BackupDir/verify_<targetName>
+	WorkDir string
+	// Passphrase 备份加密密码，为空时从上报日志里找
+	Passphrase string
+	// PrivateKey 解密上报的加密密码用的 rsa 私钥文件
+	PrivateKey string
+	// KeepFiles 校验完成后保留解包目录
+	KeepFiles bool
+}
+
+var (
+	rePartNo   = regexp.MustCompile(`\.part_(\d+)$`)
+	reDataFile = regexp.MustCompile(`^(.+?)(\.\d{5})+\.sql$`)
+)
+
+// backupVerifier 校验一个已完成的备份
+type backupVerifier struct {
+	cnf        *config.BackupConfig
+	opt        *VerifyOption
+	index      *dbareport.IndexContent
+	backupDir  string
+	targetName string
+	passphrase string
+	// notes 校验通过但是跳过了的检查
+	notes []string
+}
+
+// ExecuteVerify 校验备份: 文件 md5、解密解包、物理备份 prepare、逻辑备份解析表结构和检查行数
+// 校验失败时 result 状态为 failed，同时返回 error
+func ExecuteVerify(cnf *config.BackupConfig, opt *VerifyOption) (*dbareport.VerifyResult, error) {
+	index, err := ParseJsonFile(opt.IndexFile)
+	if err != nil {
+		return nil, err
+	}
+	result := dbareport.NewVerifyResult(opt.IndexFile, index)
+	v := &backupVerifier{
+		cnf:        cnf,
+		opt:        opt,
+		index:      index,
+		backupDir:  filepath.Dir(opt.IndexFile),
+		targetName: strings.TrimSuffix(filepath.Base(opt.IndexFile), ".index"),
+	}
+	err = v.verify()
+	result.VerifyEndTime = time.Now()
+	if err != nil {
+		result.VerifyStatus = dbareport.VerifyStatusFailed
+		result.Message = err.Error()
+		return result, err
+	}
+	result.VerifyStatus = dbareport.VerifyStatusVerified
+	result.Message = strings.Join(v.notes, "; ")
+	return result, nil
+}
+
+// LatestIndexFile 找 BackupDir 里本实例最新的 index 文件
+func LatestIndexFile(cnf *config.Public) (string, error) {
+	filePrefix := fmt.Sprintf("%d_%d_%s_%d_", cnf.BkBizId, cnf.ClusterId, cnf.MysqlHost, cnf.MysqlPort)
+	files, err := filepath.Glob(filepath.Join(cnf.BackupDir, filePrefix+"*.index"))
+	if err != nil {
+		return "", err
+	}
+	var latest string
+	var latestTime time.Time
+	for _, f := range files {
+		st, err := os.Stat(f)
+		if err != nil {
+			continue
+		}
+		if st.ModTime().After(latestTime) {
+			latest, latestTime = f, st.ModTime()
+		}
+	}
+	if latest == "" {
+		return "", errors.Errorf("no index file %s*.index found in %s", filePrefix, cnf.BackupDir)
+	}
+	return latest, nil
+}
+
+func (v *backupVerifier) verify() (err error) {
+	logger.Log.Infof("verify backup %s backup_id=%s type=%s", v.opt.IndexFile, v.index.BackupId, v.index.BackupType)
+	if err = SetEnv(v.index.BackupType, v.index.MysqlVersion); err != nil {
+		return err
+	}
+	if err = v.checkFiles(); err != nil {
+		return err
+	}
+	if v.index.EncryptEnable {
+		if v.passphrase, err = v.getPassphrase(); err != nil {
+			return err
+		}
+	}
+
+	workDir := v.opt.WorkDir
+	if workDir == "" {
+		workDir = filepath.Join(v.cnf.Public.BackupDir, "verify_"+v.targetName)
+	}
+	if cmutil.FileExists(workDir) {
+		return errors.Errorf("work dir %s already exists", workDir)
+	}
+	if err = os.MkdirAll(workDir, 0755); err != nil {
+		return err
+	}
+	if !v.opt.KeepFiles {
+		defer func() {
+			logger.Log.Infof("remove verify work dir %s", workDir)
+			if err := cmutil.TruncateDir(workDir, v.cnf.Public.IOLimitMBPerSec); err != nil {
+				logger.Log.Warnf("remove verify work dir %s failed: %s", workDir, err.Error())
+			}
+		}()
+	}
+
+	if err = v.checkDiskSpace(workDir); err != nil {
+		return err
+	}
+	for _, stream := range v.tarStreams() {
+		if err = v.extract(stream, workDir); err != nil {
+			return errors.WithMessagef(err, "extract %s", strings.Join(stream, ","))
+		}
+	}
+	dataDir := filepath.Join(workDir, v.targetName)
+	if !cmutil.IsDirectory(dataDir) {
+		return errors.Errorf("backup dir %s not found after extract", dataDir)
+	}
+
+	switch strings.ToLower(v.index.BackupType) {
+	case cst.BackupPhysical, cst.BackupIncremental:
+		return v.verifyPhysical(dataDir)
+	case cst.BackupLogical:
+		return v.verifyLogical(dataDir)
+	default:
+		return errors.Errorf("unknown BackupType: %s", v.index.BackupType)
+	}
+}
+
+// checkFiles 检查文件是否存在，以及 md5 是否和备份时记录的一致
+func (v *backupVerifier) checkFiles() error {
+	var noMd5 []string
+	for _, f := range v.index.FileList {
+		if f.FileType == cst.FileIndex {
+			continue
+		}
+		fileName := filepath.Join(v.backupDir, f.FileName)
+		if !cmutil.FileExists(fileName) {
+			return errors.Errorf("backup file %s not found", fileName)
+		}
+		if f.Md5 == "" {
+			noMd5 = append(noMd5, f.FileName)
+			continue
+		}
+		md5sum, err := util.FileMd5(fileName, v.cnf.Public.IOLimitMBPerSec)
+		if err != nil {
+			return err
+		}
+		if md5sum != f.Md5 {
+			return errors.Errorf("md5 mismatch for %s, expect %s got %s", f.FileName, f.Md5, md5sum)
+		}
+		logger.Log.Infof("md5 ok: %s", f.FileName)
+	}
+	if len(noMd5) > 0 {
+		v.notes = append(v.notes, fmt.Sprintf("no md5 recorded for %s", strings.Join(noMd5, ",")))
+	}
+	return nil
+}
+
+// checkDiskSpace 解包前检查 workDir 所在磁盘空间，避免把备份机磁盘写满
+// 需要的空间按 tar 文件大小和压缩前大小取大的估算
+func (v *backupVerifier) checkDiskSpace(workDir string) error {
+	if v.cnf.Public.NotCheckDiskSpace {
+		logger.Log.Warnf("not check disk space for verify work dir %s", workDir)
+		return nil
+	}
+	var needSize uint64
+	for _, f := range v.index.FileList {
+		if f.FileType == cst.FileTar || f.FileType == cst.FilePart {
+			needSize += uint64(f.FileSize)
+		}
+	}
+	if uncompress := uint64(v.index.TotalSizeKBUncompress) * 1024; v.index.TotalSizeKBUncompress > 0 &&
+		uncompress > needSize {
+		needSize = uncompress
+	}
+	if _, err := util.CheckDiskSpace(workDir, v.cnf.Public.MysqlPort, needSize); err != nil {
+		return errors.WithMessagef(err, "check disk space for %s, need %d bytes", workDir, needSize)
+	}
+	return nil
+}
+
+// getPassphrase 优先使用参数，否则从上报的 dbareport_result 日志里找加密后的密码
+func (v *backupVerifier) getPassphrase() (string, error) {
+	if v.opt.Passphrase != "" {
+		return v.opt.Passphrase, nil
+	}
+	encryptedKey, err := dbareport.FindEncryptedKey(v.cnf.Public.ReportPath, v.index.BackupId)
+	if err != nil {
+		return "", errors.WithMessage(err, "need --passphrase or --private-key")
+	}
+	if v.opt.PrivateKey == "" {
+		// 没有设置 EncryptPublicKey 时，上报的就是明文密码，见 NewBackupLogReport
+		if len(encryptedKey) <= 32 {
+			return encryptedKey, nil
+		}
+		return "", errors.New("passphrase is encrypted by public key, need --private-key")
+	}
+	bs, err := os.ReadFile(v.opt.PrivateKey)
+	if err != nil {
+		return "", errors.Wrap(err, "read private key")
+	}
+	privateKey, err := iocrypt.BytesToPrivateKey(bs)
+	if err != nil {
+		return "", errors.Wrapf(err, "parse private key %s", v.opt.PrivateKey)
+	}
+	cipherText, err := base64.StdEncoding.DecodeString(encryptedKey)
+	if err != nil {
+		return "", errors.Wrap(err, "decode encrypted key")
+	}
+	plainText, err := iocrypt.DecryptWithPrivateKey(cipherText, privateKey)
+	if err != nil {
+		return "", errors.Wrap(err, "decrypt encrypted key")
+	}
+	return string(plainText), nil
+}
+
+// tarStreams 按打包方式把文件组织成 tar 流
+// 切分的 part 按序号拼接成一个流，每个 tar 文件单独一个流
+func (v *backupVerifier) tarStreams() [][]string {
+	var streams [][]string
+	var parts []string
+	for _, f := range v.index.FileList {
+		if f.FileType == cst.FilePart {
+			parts = append(parts, f.FileName)
+		} else if f.FileType == cst.FileTar {
+			streams = append(streams, []string{f.FileName})
+		}
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		return partNo(parts[i]) < partNo(parts[j])
+	})
+	if len(parts) > 0 {
+		streams = append(streams, parts)
+	}
+	return streams
+}
+
+func partNo(fileName string) int {
+	if m := rePartNo.FindStringSubmatch(fileName); len(m) == 2 {
+		return cast.ToInt(strings.TrimLeft(m[1], "0"))
+	}
+	return -1
+}
+
+// extract 解密并解包一个 tar 流到 workDir
+func (v *backupVerifier) extract(stream []string, workDir string) error {
+	var readers []io.Reader
+	for _, f := range stream {
+		fh, err := os.Open(filepath.Join(v.backupDir, f))
+		if err != nil {
+			return err
+		}
+		defer fh.Close()
+		readers = append(readers, fh)
+	}
+	logger.Log.Infof("extract %v to %s", stream, workDir)
+	r, wait, err := v.decryptReader(rePartNo.ReplaceAllString(stream[0], ""), io.MultiReader(readers...))
+	if err != nil {
+		return err
+	}
+	err = untar(r, workDir, v.cnf.Public.IOLimitMBPerSec, nil)
+	if waitErr := wait(); waitErr != nil {
+		err = errs.Join(err, waitErr)
+	}
+	return err
+}
+
+// decryptReader 根据文件后缀选择解密方式，返回解密后的流
+// wait 需要在流读完后调用，等待解密命令结束
+func (v *backupVerifier) decryptReader(fileName string, r io.Reader) (io.Reader, func() error, error) {
+	noWait := func() error { return nil }
+	suffix := strings.TrimPrefix(filepath.Ext(fileName), ".")
+	if suffix == "tar" {
+		return r, noWait, nil
+	}
+	if v.passphrase == "" {
+		return nil, nil, errors.Errorf("%s is encrypted but no passphrase", fileName)
+	}
+
+	encOpt := v.cnf.Public.EncryptOpt
+	if encOpt == nil {
+		encOpt = &cmutil.EncryptOpt{}
+	}
+	var cmd *exec.Cmd
+	switch suffix {
+	case iocrypt.GcmCrypt{}.DefaultSuffix():
+		gr, err := iocrypt.NewGcmReader(r, iocrypt.GcmKey{Passphrase: v.passphrase})
+		if err != nil {
+			return nil, nil, err
+		}
+		return gr, noWait, nil
+	case iocrypt.Openssl{}.DefaultSuffix():
+		cryptCmd, algo := "openssl", iocrypt.AlgoAES256CBC
+		if strings.Contains(encOpt.EncryptCmd, "openssl") {
+			cryptCmd = encOpt.EncryptCmd
+		}
+		if encOpt.EncryptAlgo != "" {
+			algo = encOpt.EncryptAlgo
+		}
+		cmd = exec.Command(cryptCmd, "enc", fmt.Sprintf("-%s", algo), "-d", "-k", v.passphrase)
+	case iocrypt.Xbcrypt{}.DefaultSuffix():
+		cryptCmd, algo := filepath.Join(ExecuteHome, "bin/xbcrypt"), iocrypt.AlgoAES256
+		if strings.Contains(encOpt.EncryptCmd, "/") {
+			cryptCmd = encOpt.EncryptCmd
+		}
+		if encOpt.EncryptAlgo != "" {
+			algo = encOpt.EncryptAlgo
+		}
+		cmd = exec.Command(cryptCmd, "-d", "-a", string(algo), "-k", v.passphrase)
+	default:
+		return nil, nil, errors.Errorf("unknown encrypt suffix for %s", fileName)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stdin = r
+	cmd.Stderr = &stderr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, nil, err
+	}
+	wait := func() error {
+		if err := cmd.Wait(); err != nil {
+			return errors.Wrapf(err, "decrypt with %s: %s", cmd.Path, stderr.String())
+		}
+		return nil
+	}
+	return out, wait, nil
+}
+
+// untar 解包到 dstDir，只处理目录和普通文件
+// rename 返回文件解包后的相对路径，返回空表示跳过这个文件，为 nil 时按 tar 里的路径全部解包
+func untar(r io.Reader, dstDir string, ioLimitMB int, rename func(name string) string) error {
+	dstDir = filepath.Clean(dstDir)
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		name := header.Name
+		if rename != nil {
+			if name = rename(name); name == "" {
+				continue
+			}
+		}
+		target := filepath.Join(dstDir, name)
+		if !strings.HasPrefix(target, dstDir+string(os.PathSeparator)) {
+			return errors.Errorf("invalid file name in tar: %s", header.Name)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err = os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode)|0600)
+			if err != nil {
+				return err
+			}
+			_, err = cmutil.IOLimitRate(f, tr, int64(ioLimitMB))
+			_ = f.Close()
+			if err != nil {
+				return errors.Wrapf(err, "extract %s", header.Name)
+			}
+		}
+	}
+	// 读完 tar 结尾的填充，解密命令才会正常结束
+	_, err := io.Copy(io.Discard, r)
+	return err
+}
+
+// verifyPhysical 解压后检查 lsn，全备做一次 prepare
+// 增量备份需要和全备一起才能 prepare，这里跳过
+func (v *backupVerifier) verifyPhysical(dataDir string) error {
+	cnf := *v.cnf
+	cnf.PhysicalLoad.MysqlLoadDir = dataDir
+	if cnf.PhysicalLoad.Threads <= 0 {
+		cnf.PhysicalLoad.Threads = cnf.PhysicalBackup.Threads
+	}
+	if cnf.PhysicalLoad.Threads <= 0 {
+		cnf.PhysicalLoad.Threads = 1
+	}
+	loader := &PhysicalLoader{cnf: &cnf, dbbackupHome: ExecuteHome}
+	loader.mysqlVersion, loader.isOfficial = util.VersionParser(v.index.MysqlVersion)
+	if err := loader.innodbCmd.ChooseXtrabackupTool(loader.mysqlVersion, loader.isOfficial); err != nil {
+		return err
+	}
+	if err := loader.decompress(dataDir); err != nil {
+		return err
+	}
+	checkpoints, err := parseXtraCheckpoints(filepath.Join(dataDir, "xtrabackup_checkpoints"))
+	if err != nil {
+		return err
+	}
+	if v.index.ToLsn != "" && checkpoints["to_lsn"] != v.index.ToLsn {
+		return errors.Errorf("to_lsn mismatch, index %s xtrabackup_checkpoints %s", v.index.ToLsn, checkpoints["to_lsn"])
+	}
+	if strings.ToLower(v.index.BackupType) == cst.BackupIncremental {
+		v.notes = append(v.notes, "prepare skipped for incremental backup")
+		return nil
+	}
+	return loader.apply("", false)
+}
+
+// verifyLogical mydumper 备份解析表结构和检查行数，mysqldump 备份检查文件是否完整
+func (v *backupVerifier) verifyLogical(dataDir string) error {
+	if cmutil.FileExists(filepath.Join(dataDir, "metadata")) {
+		return v.verifyMydumper(dataDir)
+	}
+	sqlFile := filepath.Join(dataDir, v.targetName+".sql")
+	if cmutil.FileExists(sqlFile) {
+		return verifyMysqldump(sqlFile)
+	}
+	return errors.Errorf("neither metadata nor %s.sql found in %s", v.targetName, dataDir)
+}
+
+func (v *backupVerifier) verifyMydumper(dataDir string) error {
+	metaRows, err := parseMydumperTableRows(filepath.Join(dataDir, "metadata"))
+	if err != nil {
+		return err
+	}
+	files, err := os.ReadDir(dataDir)
+	if err != nil {
+		return err
+	}
+	var errList []error
+	tableRows := make(map[string]int64)
+	for _, f := range files {
+		fileName := filepath.Join(dataDir, f.Name())
+		name := strings.TrimSuffix(f.Name(), cst.ZstdSuffix)
+		var keyword string
+		if strings.HasSuffix(name, "-schema-create.sql") {
+			keyword = "CREATE DATABASE"
+		} else if strings.HasSuffix(name, "-schema-view.sql") {
+			keyword = "VIEW"
+		} else if strings.HasSuffix(name, "-schema.sql") {
+			keyword = "CREATE TABLE"
+		}
+		if keyword != "" {
+			if err := checkSchemaFile(fileName, keyword); err != nil {
+				errList = append(errList, err)
+			}
+			continue
+		}
+		if m := reDataFile.FindStringSubmatch(name); len(m) == 3 {
+			rows, err := countDataFileRows(fileName)
+			if err != nil {
+				errList = append(errList, err)
+				continue
+			}
+			tableRows[m[1]] += rows
+		}
+	}
+	for table, rows := range metaRows {
+		if tableRows[table] != rows {
+			errList = append(errList, errors.Errorf("table %s rows mismatch, metadata %d data files %d",
+				table, rows, tableRows[table]))
+		}
+	}
+	if len(metaRows) == 0 {
+		v.notes = append(v.notes, "no rows in metadata, row count not compared")
+	}
+	logger.Log.Infof("verify logical backup: %d tables with data, errors %d", len(tableRows), len(errList))
+	return errs.Join(errList...)
+}
+
+// parseMydumperTableRows 解析 metadata 里每个表的 rows，老版本 mydumper 没有
+func parseMydumperTableRows(metadataFile string) (map[string]int64, error) {
+	f, err := os.Open(metadataFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	reTable := regexp.MustCompile("^\\[`(.+)`\\.`(.+)`\\]$")
+	tableRows := make(map[string]int64)
+	var table string
+	buf := bufio.NewScanner(f)
+	for buf.Scan() {
+		l := strings.TrimSpace(buf.Text())
+		if strings.HasPrefix(l, "[") {
+			table = ""
+			if m := reTable.FindStringSubmatch(l); len(m) == 3 {
+				table = m[1] + "." + m[2]
+			}
+			continue
+		}
+		if kv := strings.SplitN(l, "=", 2); table != "" && len(kv) == 2 && strings.TrimSpace(kv[0]) == "rows" {
+			tableRows[table] = cast.ToInt64(strings.TrimSpace(kv[1]))
+		}
+	}
+	return tableRows, buf.Err()
+}
+
+// openSqlFile .zst 文件用 zstd 解压读取
+func openSqlFile(fileName string) (io.ReadCloser, error) {
+	if !strings.HasSuffix(fileName, cst.ZstdSuffix) {
+		return os.Open(fileName)
+	}
+	cmd := exec.Command(CmdZstd, "-dc", fileName)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &cmdReadCloser{ReadCloser: out, cmd: cmd}, nil
+}
+
+type cmdReadCloser struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+// Close 等待命令结束
+func (c *cmdReadCloser) Close() error {
+	_, _ = io.Copy(io.Discard, c.ReadCloser)
+	if err := c.cmd.Wait(); err != nil {
+		return errors.Wrapf(err, "run %s", c.cmd.String())
+	}
+	return nil
+}
+
+// checkSchemaFile 表结构文件需要包含 keyword，并且以 ; 结尾
+func checkSchemaFile(fileName string, keyword string) error {
+	f, err := openSqlFile(fileName)
+	if err != nil {
+		return err
+	}
+	content, err := io.ReadAll(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.WithMessagef(err, "read %s", fileName)
+	}
+	content = bytes.TrimSpace(content)
+	if !bytes.Contains(bytes.ToUpper(content), []byte(keyword)) {
+		return errors.Errorf("%s has no %s", filepath.Base(fileName), keyword)
+	}
+	if !bytes.HasSuffix(content, []byte(";")) {
+		return errors.Errorf("%s is not ended with ;", filepath.Base(fileName))
+	}
+	return nil
+}
+
+// countDataFileRows 统计 mydumper 数据文件的行数，mydumper 每行数据单独一行
+// 文件需要以 ; 结尾，否则认为被截断
+func countDataFileRows(fileName string) (int64, error) {
+	f, err := openSqlFile(fileName)
+	if err != nil {
+		return 0, err
+	}
+	var rows int64
+	var last byte
+	lineStart := true
+	br := bufio.NewReaderSize(f, 1024*1024)
+	for {
+		line, isPrefix, err := br.ReadLine()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			_ = f.Close()
+			return 0, errors.WithMessagef(err, "read %s", fileName)
+		}
+		if lineStart {
+			l := bytes.TrimLeft(line, " \t")
+			if bytes.HasPrefix(l, []byte("(")) || bytes.HasPrefix(l, []byte(",(")) {
+				rows++
+			} else if bytes.HasPrefix(l, []byte("INSERT")) &&
+				(bytes.Contains(l, []byte("VALUES(")) || bytes.Contains(l, []byte("VALUES ("))) {
+				rows++
+			}
+		}
+		if t := bytes.TrimRight(line, " \t\r"); len(t) > 0 {
+			last = t[len(t)-1]
+		}
+		lineStart = !isPrefix
+	}
+	if err = f.Close(); err != nil {
+		return 0, err
+	}
+	if rows > 0 && last != ';' {
+		return 0, errors.Errorf("%s is truncated, not ended with ;", filepath.Base(fileName))
+	}
+	return rows, nil
+}
+
+// verifyMysqldump mysqldump 正常结束会在文件最后写 -- Dump completed
+func verifyMysqldump(sqlFile string) error {
+	f, err := os.Open(sqlFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	offset := st.Size() - 4096
+	if offset < 0 {
+		offset = 0
+	}
+	tail := make([]byte, st.Size()-offset)
+	if _, err = f.ReadAt(tail, offset); err != nil && err != io.EOF {
+		return err
+	}
+	if !bytes.Contains(tail, []byte("-- Dump completed")) {
+		return errors.Errorf("%s is not completed", filepath.Base(sqlFile))
+	}
+	return nil
+}
@@ -206,7 +206,12 @@ func (r *BackupLogReport) ReportBackupStatus(status string) error {
 
 // ExecuteBackupClient execute backup_client which sends files to backup system
 func (r *BackupLogReport) ExecuteBackupClient(fileName string) (taskid string, err error) {
-	if r.cfg.BackupClient.Enable {
+	if r.cfg.StreamUpload.Enable {
+		// 打包时已经上传到对象存储，上报对象地址
+		taskid = r.cfg.StreamUpload.ObjectURL(fileName)
+		logger.Log.Infof("file %s is uploaded by StreamUpload: %s", fileName, taskid)
+		return taskid, nil
+	} else if r.cfg.BackupClient.Enable {
 		backupClient, err := backupclient.New(
 			r.cfg.BackupClient.BackupClientBin,
 			"",
@@ -382,6 +387,12 @@ func (r *BackupLogReport) ReportBackupResult(indexFilePath string, index, upload
 			FileName: tf.FileName, FileSize: tf.FileSize, FileType: tf.FileType, TaskId: tf.TaskId})
 	}
 	metaInfo.FileList = fileListSimple
+	if upload {
+		metaInfo.UploadStatus = UploadSuccess
+		if err2 != nil {
+			metaInfo.UploadStatus = UploadFailed
+		}
+	}
 	Report().Result.Println(metaInfo)
 
 	if err = r.ReportToLocalBackup(indexFilePath, metaInfo); err != nil {
@@ -0,0 +1,64 @@
+package dbareport
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/cst"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/logger"
+)
+
+// 备份文件上传结果
+const (
+	UploadSuccess = "success"
+	UploadFailed  = "failed"
+)
+
+// LastPhysicalBackup 从 ReportPath 下的 backup_result 日志里找本实例最近一次成功的物理备份(全备或增量)
+// 用作增量备份的基础. 只有备份成功才会写 backup_result 日志，上传失败的备份恢复时拿不到，不能作为基础
+// 没有找到返回 nil
+func LastPhysicalBackup(cnf *config.Public) (*IndexContent, error) {
+	// 包含 lumberjack 切割后的 backup_result-xxx.log
+	files, err := filepath.Glob(filepath.Join(cnf.ReportPath, "backup_result*.log"))
+	if err != nil {
+		return nil, errors.WithMessage(err, "find backup_result log")
+	}
+	var last *IndexContent
+	for _, f := range files {
+		fh, err := os.Open(f)
+		if err != nil {
+			return nil, err
+		}
+		buf := bufio.NewScanner(fh)
+		buf.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for buf.Scan() {
+			var r IndexContent
+			if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+				logger.Log.Warnf("skip invalid line in %s: %s", f, err.Error())
+				continue
+			}
+			if r.BackupHost != cnf.MysqlHost || r.BackupPort != cnf.MysqlPort || r.ToLsn == "" {
+				continue
+			}
+			if r.BackupType != cst.BackupPhysical && r.BackupType != cst.BackupIncremental {
+				continue
+			}
+			if r.UploadStatus == UploadFailed {
+				continue
+			}
+			if last == nil || r.BackupConsistentTime.After(last.BackupConsistentTime) {
+				last = &r
+			}
+		}
+		_ = fh.Close()
+		if err = buf.Err(); err != nil {
+			return nil, errors.Wrapf(err, "read %s", f)
+		}
+	}
+	return last, nil
+}
@@ -0,0 +1,74 @@
+package dbareport
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/cst"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/logger"
+)
+
+func writeResultLog(t *testing.T, file string, items ...*IndexContent) {
+	var buf []byte
+	for _, it := range items {
+		b, err := json.Marshal(it)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf = append(buf, b...)
+		buf = append(buf, '\n')
+	}
+	buf = append(buf, []byte("not a json line\n")...)
+	if err := os.WriteFile(file, buf, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func resultItem(id string, backupType string, port int, toLsn string, uploadStatus string,
+	consistentTime time.Time) *IndexContent {
+	r := &IndexContent{UploadStatus: uploadStatus}
+	r.BackupId = id
+	r.BackupType = backupType
+	r.BackupHost = "127.0.0.1"
+	r.BackupPort = port
+	r.ToLsn = toLsn
+	r.BackupConsistentTime = consistentTime
+	return r
+}
+
+func TestLastPhysicalBackup(t *testing.T) {
+	logger.Log = logrus.New()
+	dir := t.TempDir()
+	now := time.Now().Truncate(time.Second)
+	writeResultLog(t, filepath.Join(dir, "backup_result-2024-01-01.log"),
+		resultItem("full", cst.BackupPhysical, 3306, "100", "", now.Add(-3*time.Hour)),
+		resultItem("inc1", cst.BackupIncremental, 3306, "200", UploadSuccess, now.Add(-2*time.Hour)),
+	)
+	writeResultLog(t, filepath.Join(dir, "backup_result.log"),
+		// 上传失败的不能作为基础
+		resultItem("inc2", cst.BackupIncremental, 3306, "300", UploadFailed, now.Add(-time.Hour)),
+		// 逻辑备份和其他实例的备份都跳过
+		resultItem("logical", cst.BackupLogical, 3306, "", UploadSuccess, now),
+		resultItem("other", cst.BackupPhysical, 3307, "400", UploadSuccess, now),
+	)
+
+	cnf := &config.Public{ReportPath: dir, MysqlHost: "127.0.0.1", MysqlPort: 3306}
+	last, err := LastPhysicalBackup(cnf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last == nil || last.BackupId != "inc1" {
+		t.Fatalf("expect inc1 as incremental base, got %+v", last)
+	}
+
+	cnf.MysqlPort = 3308
+	if last, err = LastPhysicalBackup(cnf); err != nil || last != nil {
+		t.Fatalf("expect no base backup, got %+v, %v", last, err)
+	}
+}
@@ -59,6 +59,13 @@ type BackupMetaFileBase struct {
 
 	// ConsistentBackupTime todo 为了字段兼容性，可以删掉
 	ConsistentBackupTime time.Time `json:"consistent_backup_time" db:"consistent_backup_time"`
+
+	// ParentBackupId 增量备份所基于的上一次物理备份 backup_id，全备为空
+	ParentBackupId string `json:"parent_backup_id,omitempty" db:"parent_backup_id"`
+	// FromLsn 物理备份 xtrabackup_checkpoints 里的 from_lsn，增量备份等于上一次备份的 ToLsn
+	FromLsn string `json:"from_lsn,omitempty" db:"from_lsn"`
+	// ToLsn 物理备份 xtrabackup_checkpoints 里的 to_lsn
+	ToLsn string `json:"to_lsn,omitempty" db:"to_lsn"`
 }
 
 // IndexContent the content of the index file
@@ -73,6 +80,9 @@ type IndexContent struct {
 
 	FileList []*TarFileItem `json:"file_list" db:"file_list"`
 
+	// UploadStatus 备份文件上传结果，只记录在 backup_result 日志里，不上传时为空
+	UploadStatus string `json:"upload_status,omitempty" db:"-"`
+
 	reData       *regexp.Regexp
 	reSchema     *regexp.Regexp
 	reSchemaDb   *regexp.Regexp
@@ -91,6 +101,8 @@ type TarFileItem struct {
 	ContainTables []string `json:"contain_tables"`
 	// TaskId backup task_id
 	TaskId string `json:"task_id"`
+	// Md5 打包完成后计算，用于备份校验
+	Md5 string `json:"md5,omitempty"`
 }
 
 func (f *TarFileItem) GetDBTables() {
@@ -130,6 +142,11 @@ func (i *IndexContent) JudgeIsFullBackup(cnf *config.Public) bool {
 		i.IsFullBackup = false
 		return i.IsFullBackup
 	}
+	// 增量备份需要和全备一起才能恢复
+	if i.BackupType == cst.BackupIncremental {
+		i.IsFullBackup = false
+		return i.IsFullBackup
+	}
 	if i.BackupType == cst.BackupPhysical {
 		i.IsFullBackup = true
 	}
@@ -78,6 +78,7 @@ CREATE TABLE IF NOT EXISTS %s (
 	file_list text,
 	extra_fields text,
 	backup_config_file text,
+	verify_status varchar(30) NOT NULL DEFAULT 'not_verified',
 	PRIMARY KEY (backup_id,mysql_role,shard_value)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
 `, ModelBackupReport{}.TableName())
@@ -14,6 +14,7 @@ type ReportLogger struct {
 	Result reportlog.Reporter
 	Files  reportlog.Reporter
 	Status reportlog.Reporter
+	Verify reportlog.Reporter
 }
 
 // reportLogger 全局可调用的 log reporter
@@ -39,25 +40,32 @@ func NewLogReporter(reportDir string) (*ReportLogger, error) {
 	}
 	resultReport, err := reportlog.NewReporter(reportDir, "backup_result.log", &logOpt)
 	if err != nil {
-		logger.Log.Warn("fail to init resultReporter:%s", err.Error())
+		logger.Log.Warnf("fail to init resultReporter:%s", err.Error())
 		//resultReport.Disable = true
 		return nil, errors.WithMessage(err, "fail to init resultReporter")
 	}
 	filesReport, err := reportlog.NewReporter(filepath.Join(reportDir, "result"), "dbareport_result.log", &logOpt)
 	if err != nil {
-		logger.Log.Warn("fail to init statusReporter:%s", err.Error())
+		logger.Log.Warnf("fail to init statusReporter:%s", err.Error())
 		//filesReport.Disable = true
 		return nil, errors.WithMessage(err, "fail to init statusReporter")
 	}
 	statusReport, err := reportlog.NewReporter(filepath.Join(reportDir, "status"), "backup_status.log", &logOpt)
 	if err != nil {
-		logger.Log.Warn("fail to init statusReporter:%s", err.Error())
+		logger.Log.Warnf("fail to init statusReporter:%s", err.Error())
 		//statusReport.Disable = true
 		return nil, errors.WithMessage(err, "fail to init statusReporter")
 	}
+	// verify 上报日志不影响备份本身，初始化失败时只禁用
+	verifyReport, err := reportlog.NewReporter(filepath.Join(reportDir, "verify"), "backup_verify.log", &logOpt)
+	if err != nil {
+		logger.Log.Warnf("fail to init verifyReporter:%s", err.Error())
+		verifyReport = &reportlog.Reporter{Disable: true}
+	}
 	return &ReportLogger{
 		Result: *resultReport,
 		Files:  *filesReport,
 		Status: *statusReport,
+		Verify: *verifyReport,
 	}, nil
 }
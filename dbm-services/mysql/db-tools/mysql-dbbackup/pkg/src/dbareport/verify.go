@@ -0,0 +1,124 @@
+package dbareport
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"dbm-services/common/go-pubpkg/cmutil"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/logger"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/mysqlconn"
+)
+
+// 备份校验状态
+const (
+	VerifyStatusNotVerified = "not_verified"
+	VerifyStatusVerified    = "verified"
+	VerifyStatusFailed      = "failed"
+)
+
+// VerifyResult 一次备份校验的结果
+type VerifyResult struct {
+	BackupId        string    `json:"backup_id"`
+	BackupType      string    `json:"backup_type"`
+	ClusterId       int       `json:"cluster_id"`
+	ClusterAddress  string    `json:"cluster_address"`
+	BackupHost      string    `json:"backup_host"`
+	BackupPort      int       `json:"backup_port"`
+	IndexFile       string    `json:"index_file"`
+	VerifyStatus    string    `json:"verify_status"`
+	VerifyBeginTime time.Time `json:"verify_begin_time"`
+	VerifyEndTime   time.Time `json:"verify_end_time"`
+	// Message 失败原因，或者跳过了哪些检查
+	Message string `json:"message"`
+}
+
+// NewVerifyResult 根据 index 初始化校验结果，状态为 not_verified
+func NewVerifyResult(indexFile string, index *IndexContent) *VerifyResult {
+	return &VerifyResult{
+		BackupId:        index.BackupId,
+		BackupType:      index.BackupType,
+		ClusterId:       index.ClusterId,
+		ClusterAddress:  index.ClusterAddress,
+		BackupHost:      index.BackupHost,
+		BackupPort:      index.BackupPort,
+		IndexFile:       indexFile,
+		VerifyStatus:    VerifyStatusNotVerified,
+		VerifyBeginTime: time.Now(),
+	}
+}
+
+// ReportVerifyResult 校验结果写入 verify 上报日志，并更新 local_backup_report.verify_status
+func ReportVerifyResult(cnf *config.Public, v *VerifyResult) error {
+	if !Report().Verify.Disable {
+		Report().Verify.Println(v)
+	}
+
+	db, err := mysqlconn.InitConn(cnf)
+	if err != nil {
+		return errors.WithMessage(err, "ReportVerifyResult to db")
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	ctx := context.Background()
+	_, _ = conn.ExecContext(ctx, "set session sql_log_bin=0;")
+
+	sqlStr := fmt.Sprintf("UPDATE %s SET verify_status=? WHERE backup_id=?", ModelBackupReport{}.TableName())
+	if _, err = conn.ExecContext(ctx, sqlStr, v.VerifyStatus, v.BackupId); err != nil {
+		// 老的表没有 verify_status 字段，只加字段，不能像 migrateLocalBackupSchema 那样重建表
+		if cmutil.NewMySQLError(err).Code != 1054 {
+			return errors.Wrap(err, "update verify_status")
+		}
+		logger.Log.Warnf("local_backup_report has no verify_status, add it")
+		alterSql := fmt.Sprintf("ALTER TABLE %s ADD COLUMN verify_status varchar(30) NOT NULL DEFAULT '%s'",
+			ModelBackupReport{}.TableName(), VerifyStatusNotVerified)
+		if _, err = conn.ExecContext(ctx, alterSql); err != nil {
+			return errors.Wrap(err, "add column verify_status")
+		}
+		if _, err = conn.ExecContext(ctx, sqlStr, v.VerifyStatus, v.BackupId); err != nil {
+			return errors.Wrap(err, "update verify_status again")
+		}
+	}
+	return nil
+}
+
+// FindEncryptedKey 从上报的文件记录 result/dbareport_result*.log 里找 backupId 对应的加密 key
+func FindEncryptedKey(reportPath string, backupId string) (string, error) {
+	files, err := filepath.Glob(filepath.Join(reportPath, "result", "dbareport_result*.log"))
+	if err != nil {
+		return "", err
+	}
+	for _, f := range files {
+		fh, err := os.Open(f)
+		if err != nil {
+			return "", err
+		}
+		buf := bufio.NewScanner(fh)
+		buf.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for buf.Scan() {
+			var r BackupLogReport
+			if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+				continue
+			}
+			if r.BackupId == backupId && r.EncryptedKey != "" {
+				_ = fh.Close()
+				return r.EncryptedKey, nil
+			}
+		}
+		_ = fh.Close()
+	}
+	return "", errors.Errorf("encrypted key for backup_id %s not found in %s", backupId, reportPath)
+}
@@ -2,8 +2,12 @@
 package precheck
 
 import (
+	"github.com/pkg/errors"
+
+	"dbm-services/common/go-pubpkg/iocrypt"
 	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/config"
 	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/cst"
+	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/dbareport"
 	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/logger"
 	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/src/mysqlconn"
 	"dbm-services/mysql/db-tools/mysql-dbbackup/pkg/util"
@@ -14,6 +18,9 @@ func BeforeDump(cnf *config.BackupConfig) error {
 	if err := CheckBackupType(cnf); err != nil {
 		return err
 	}
+	if err := CheckStreamUpload(cnf); err != nil {
+		return err
+	}
 	cnfPublic := &cnf.Public
 
 	dbh, err := mysqlconn.InitConn(cnfPublic)
@@ -66,5 +73,35 @@ func CheckBackupType(cnf *config.BackupConfig) error {
 			cnf.Public.BackupType = cst.BackupPhysical
 		}
 	}
+	if cnf.Public.BackupType == cst.BackupIncremental {
+		// 没有可以作为基础的物理备份，先做一次全备
+		if base, err := dbareport.LastPhysicalBackup(&cnf.Public); err != nil {
+			return err
+		} else if base == nil {
+			logger.Log.Infof("BackupType incremental but no physical backup found in %s for port %d, use physical",
+				cnf.Public.ReportPath, cnf.Public.MysqlPort)
+			cnf.Public.BackupType = cst.BackupPhysical
+		}
+	}
+	return nil
+}
+
+// CheckStreamUpload 流式上传只支持 tar 打包时不落盘的备份方式
+func CheckStreamUpload(cnf *config.BackupConfig) error {
+	if !cnf.StreamUpload.Enable {
+		return nil
+	}
+	if cnf.Public.BackupType == cst.BackupLogical && cnf.Public.UseMysqldump {
+		// mysqldump 备份先打成一个 tar 再切分，不支持流式上传，仍然使用本地文件
+		logger.Log.Warnf("StreamUpload is not supported for mysqldump backup, disable it")
+		cnf.StreamUpload.Enable = false
+		return nil
+	}
+	// 外部加密命令异步写输出，无法确认什么时候写完，只支持内置加密
+	if cnf.Public.EncryptOpt != nil && cnf.Public.EncryptOpt.EncryptEnable &&
+		cnf.Public.EncryptOpt.EncryptCmd != iocrypt.NativeCryptCmd {
+		return errors.Errorf("StreamUpload only supports EncryptCmd=%s, got %s",
+			iocrypt.NativeCryptCmd, cnf.Public.EncryptOpt.EncryptCmd)
+	}
 	return nil
 }
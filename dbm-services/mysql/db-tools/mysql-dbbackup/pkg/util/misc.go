@@ -2,6 +2,7 @@ package util
 
 import (
 	"bufio"
+	"crypto/md5"
 	"fmt"
 	"io"
 	"os"
@@ -277,3 +278,19 @@ func FindBackupConfigFiles(dir string) ([]string, error) {
 	}
 	return cnfFilesNew, nil
 }
+
+// FileMd5 计算文件 md5，ioLimitMB 限制读取速度，0 不限速
+func FileMd5(fileName string, ioLimitMB int) (string, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	h := md5.New()
+	if _, err = cmutil.IOLimitRate(h, f, int64(ioLimitMB)); err != nil {
+		return "", errors.Wrapf(err, "md5 %s", fileName)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
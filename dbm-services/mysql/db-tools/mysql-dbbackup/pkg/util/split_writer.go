@@ -21,6 +21,8 @@ type SplitWriter struct {
 	// FileName used to build target filename, usually absolute path
 	FileName  string
 	SplitSize int
+	// CreateFile 创建分片文件，为空时创建本地文件
+	CreateFile func(fileName string) (io.WriteCloser, error)
 	// fileSplitMap filename:size
 	fileSplitMap map[string]int
 	// seq split file seq no
@@ -42,7 +44,7 @@ func (r *SplitWriter) Write(p []byte) (int, error) {
 		if _, err := os.Stat(r.currentFile); !os.IsNotExist(err) {
 			return 0, err
 		}
-		r.currentWriter, err = os.Create(r.currentFile)
+		r.currentWriter, err = r.createFile(r.currentFile)
 		if err != nil {
 			return 0, err
 		}
@@ -74,7 +76,7 @@ func (r *SplitWriter) Write(p []byte) (int, error) {
 			return 0, err
 		}
 		// Create output file
-		r.currentWriter, err = os.Create(r.currentFile)
+		r.currentWriter, err = r.createFile(r.currentFile)
 		if err != nil {
 			return 0, err
 		}
@@ -86,13 +88,21 @@ func (r *SplitWriter) Write(p []byte) (int, error) {
 	return written, err
 }
 
+func (r *SplitWriter) createFile(fileName string) (io.WriteCloser, error) {
+	if r.CreateFile != nil {
+		return r.CreateFile(fileName)
+	}
+	return os.Create(fileName)
+}
+
 // Close implementation
 func (r *SplitWriter) Close() error {
 	// 最后一个 writer close
 	if r.currentWriter != nil {
 		r.fileSplitMap[r.currentFile] = r.currentWritten
-		_ = r.currentWriter.Close()
+		err := r.currentWriter.Close()
 		r.currentWriter = nil
+		return err
 	}
 	return nil
 }
@@ -35,9 +35,11 @@ type TarWriter struct {
 	IOLimitMB   int
 	EncryptTool iocrypt.EncryptTool
 	Encrypt     bool
+	// CreateFile 创建目标文件，为空时创建本地文件。流式上传时创建的是对象存储的 writer
+	CreateFile func(fileName string) (io.WriteCloser, error)
 
 	tarSize           uint64
-	destFileWriter    *os.File
+	destFileWriter    io.WriteCloser
 	destEncryptWriter io.WriteCloser
 	tarWriter         *tar.Writer
 	mu                sync.Mutex
@@ -60,6 +62,7 @@ func (t *TarWriter) NewSplit(dstTarName string, splitSize int) (err error) {
 	splitWriter := &SplitWriter{
 		FileName:        dstTarName,
 		SplitSize:       splitSize,
+		CreateFile:      t.CreateFile,
 		outFileNameTmpl: fmt.Sprintf(`%s.part_%s`, dstTarName, "%d"), // need to be same with const ReSplitPart
 	}
 	if err != nil {
@@ -90,7 +93,11 @@ func (t *TarWriter) GetSplitTars() map[string]int {
 // destFileWriter or destEncryptWriter need to close outside
 // need to call tarWriter.Close()
 func (t *TarWriter) New(dstTarName string) (err error) {
-	t.destFileWriter, err = os.Create(dstTarName)
+	if t.CreateFile != nil {
+		t.destFileWriter, err = t.CreateFile(dstTarName)
+	} else {
+		t.destFileWriter, err = os.Create(dstTarName)
+	}
 	if err != nil {
 		return err
 	}
@@ -137,22 +144,28 @@ func (t *TarWriter) WriteTar(header *tar.Header, srcFile string) (isFile bool, w
 // will close destFile
 // close won't reset IOLimitMB EncryptTool, could reuse it with new tarFilename
 func (t *TarWriter) Close() error {
-	defer func() {
-		if t.Encrypt {
-			t.destEncryptWriter.Close()
-		} else {
-			if t.splitWriter != nil {
-				t.splitWriter.Close() // err?
-			}
-			if t.destFileWriter != nil {
-				t.destFileWriter.Close()
-			}
+	err := t.tarWriter.Close()
+	if t.Encrypt {
+		if errClose := t.destEncryptWriter.Close(); err == nil {
+			err = errClose
+		}
+		// 外部加密命令是异步把输出写到目标文件的，这里不能关闭目标文件
+		// 内置的流式加密 Close 时已经写完，需要关闭目标文件，流式上传时关闭才会完成上传
+		if _, ok := t.EncryptTool.(iocrypt.StreamEncryptTool); !ok {
+			return err
 		}
-	}()
-	if err := t.tarWriter.Close(); err != nil {
-		return err
 	}
-	return nil
+	if t.splitWriter != nil {
+		if errClose := t.splitWriter.Close(); err == nil {
+			err = errClose
+		}
+	}
+	if t.destFileWriter != nil {
+		if errClose := t.destFileWriter.Close(); err == nil {
+			err = errClose
+		}
+	}
+	return err
 }
 
 /*func tarCmd(filepath string, cnf *parsecnf.CnfShared) error {
@@ -39,32 +39,7 @@ func reschedule(configFileDir, configFileName, staff string) error {
 		}
 	}
 
-	var hardCodeItems []*config.MonitorItem
-	itemGroups := make(map[string][]*config.MonitorItem)
-	for _, ele := range config.ItemsConfig {
-		// 硬编码监控项先排除掉
-		if ele.Name == "db-up" || ele.Name == config.HeartBeatName {
-			if ele.IsEnable() {
-				hardCodeItems = append(hardCodeItems, ele)
-			}
-			continue
-		}
-
-		if ele.IsEnable() && ele.IsMatchMachineType() && ele.IsMatchRole() {
-			var key string
-
-			if ele.Schedule == nil {
-				key = config.MonitorConfig.DefaultSchedule
-			} else {
-				key = *ele.Schedule
-			}
-
-			if _, ok := itemGroups[key]; !ok {
-				itemGroups[key] = []*config.MonitorItem{}
-			}
-			itemGroups[key] = append(itemGroups[key], ele)
-		}
-	}
+	itemGroups, hardCodeItems := groupItems()
 
 	for k, v := range itemGroups {
 		var itemNames []string
@@ -123,3 +98,30 @@ func reschedule(configFileDir, configFileName, staff string) error {
 
 	return nil
 }
+
+// groupItems 按照 schedule 分组, 硬编码监控项单独返回
+func groupItems() (itemGroups map[string][]*config.MonitorItem, hardCodeItems []*config.MonitorItem) {
+	itemGroups = make(map[string][]*config.MonitorItem)
+	for _, ele := range config.ItemsConfig {
+		// 硬编码监控项先排除掉
+		if ele.Name == "db-up" || ele.Name == config.HeartBeatName {
+			if ele.IsEnable() {
+				hardCodeItems = append(hardCodeItems, ele)
+			}
+			continue
+		}
+
+		if ele.IsEnable() && ele.IsMatchMachineType() && ele.IsMatchRole() {
+			var key string
+
+			if ele.Schedule == nil {
+				key = config.MonitorConfig.DefaultSchedule
+			} else {
+				key = *ele.Schedule
+			}
+
+			itemGroups[key] = append(itemGroups[key], ele)
+		}
+	}
+	return itemGroups, hardCodeItems
+}
@@ -1,23 +1,105 @@
 package cmd
 
-// var subCmdList = &cobra.Command{
-//	Use:   "list",
-//	Short: "list registered monitor items",
-//	Long:  "list registered monitor items",
-//	RunE: func(cmd *cobra.Command, args []string) error {
-//		err := config.InitConfig()
-//		if err != nil {
-//			return err
-//		}
-//		initLogger(config.MonitorConfig.Log)
-//
-//		for k, _ := range items_collect.RegisteredItemConstructor() {
-//			fmt.Println(k)
-//		}
-//		return nil
-//	},
-// }
-//
-// func init() {
-//	rootCmd.AddCommand(subCmdList)
-// }
+import (
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/history"
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/itemscollect"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var subCmdList = &cobra.Command{
+	Use:   "list",
+	Short: "list monitor items with last run result",
+	Long:  "list monitor items with last run result",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := config.InitConfig(viper.GetString("list-config"))
+		if err != nil {
+			return err
+		}
+		initLogger(config.MonitorConfig.Log)
+
+		err = config.LoadMonitorItemsConfig()
+		if err != nil {
+			slog.Error("list monitor load items", slog.String("error", err.Error()))
+			return err
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetAutoWrapText(false)
+		table.SetHeader([]string{
+			"name", "enable", "schedule", "timeout", "retry",
+			"last run", "status", "duration", "attempts", "last success", "error",
+		})
+
+		for _, ele := range config.ItemsConfig {
+			if !ele.IsMatchMachineType() || !ele.IsMatchRole() {
+				continue
+			}
+			if _, ok := itemscollect.RegisteredItemConstructor()[ele.Name]; !ok &&
+				!slices.Contains([]string{"db-up", config.HeartBeatName}, ele.Name) {
+				continue
+			}
+
+			schedule := config.MonitorConfig.DefaultSchedule
+			if ele.Schedule != nil {
+				schedule = *ele.Schedule
+			}
+			row := []string{
+				ele.Name,
+				strconv.FormatBool(ele.IsEnable()),
+				schedule,
+				ele.GetTimeout().String(),
+				strconv.Itoa(ele.Retry),
+			}
+
+			records, err := history.Load(config.MonitorConfig.HistoryDir, config.MonitorConfig.Port, ele.Name)
+			if err != nil {
+				return err
+			}
+			last, lastSuccess := history.Last(records)
+			if last == nil {
+				row = append(row, "-", "-", "-", "-", "-", "")
+			} else {
+				status := "success"
+				if last.TimedOut {
+					status = "timeout"
+				} else if !last.Success {
+					status = "failed"
+				}
+				lastSuccessAt := "-"
+				if lastSuccess != nil {
+					lastSuccessAt = lastSuccess.StartAt.Format(time.DateTime)
+				}
+				row = append(
+					row,
+					last.StartAt.Format(time.DateTime),
+					status,
+					last.Duration.Round(time.Millisecond).String(),
+					strconv.Itoa(last.Attempts),
+					lastSuccessAt,
+					last.Error,
+				)
+			}
+			table.Append(row)
+		}
+		table.Render()
+		return nil
+	},
+}
+
+func init() {
+	subCmdList.PersistentFlags().StringP("config", "c", "", "config file")
+	_ = subCmdList.MarkPersistentFlagRequired("config")
+	_ = viper.BindPFlag("list-config", subCmdList.PersistentFlags().Lookup("config"))
+
+	rootCmd.AddCommand(subCmdList)
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"dbm-services/common/go-pubpkg/promexporter"
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/mainloop"
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/utils"
+
+	"github.com/robfig/cron/v3"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+/*
+serve 常驻运行, 不依赖 mysql-crond
+按监控项配置的 schedule 运行, 指标以 prometheus 格式在 /metrics 暴露, 事件在 /events 查询
+*/
+var subCmdServe = &cobra.Command{
+	Use:   "serve",
+	Short: "run monitor items on schedule and expose prometheus metrics",
+	Long:  "run monitor items on schedule and expose prometheus metrics",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := config.InitConfig(viper.GetString("serve-config"))
+		if err != nil {
+			return err
+		}
+		initLogger(config.MonitorConfig.Log)
+
+		err = config.LoadMonitorItemsConfig()
+		if err != nil {
+			slog.Error("serve load items", slog.String("error", err.Error()))
+			return err
+		}
+		config.InjectHardCodeItem()
+
+		registry := promexporter.NewRegistry(viper.GetInt("serve-max-events"))
+		utils.EnableExporter(registry)
+
+		scheduler := cron.New(
+			cron.WithParser(
+				cron.NewParser(
+					cron.SecondOptional |
+						cron.Minute |
+						cron.Hour |
+						cron.Dom |
+						cron.Month |
+						cron.Dow |
+						cron.Descriptor,
+				),
+			),
+			// 上一次还没跑完就跳过, 和 crond 的行为一致
+			cron.WithChain(cron.SkipIfStillRunning(cron.DiscardLogger)),
+		)
+
+		itemGroups, hardCodeItems := groupItems()
+		for schedule, items := range itemGroups {
+			var itemNames []string
+			for _, j := range items {
+				itemNames = append(itemNames, j.Name)
+			}
+			_, err := scheduler.AddFunc(schedule, func() {
+				_ = mainloop.RunItems(itemNames, false)
+			})
+			if err != nil {
+				slog.Error("serve add schedule", slog.String("error", err.Error()), slog.String("schedule", schedule))
+				return err
+			}
+			slog.Info("serve add schedule", slog.String("schedule", schedule), slog.Any("items", itemNames))
+		}
+		for _, j := range hardCodeItems {
+			itemName := j.Name
+			_, err := scheduler.AddFunc(config.HardCodeSchedule, func() {
+				_ = mainloop.RunItems([]string{itemName}, true)
+			})
+			if err != nil {
+				slog.Error("serve add hardcode schedule", slog.String("error", err.Error()))
+				return err
+			}
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		server := &http.Server{
+			Addr:              viper.GetString("serve-address"),
+			Handler:           registry.Handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- server.ListenAndServe()
+		}()
+		scheduler.Start()
+		slog.Info("serve start", slog.String("address", server.Addr))
+
+		select {
+		case err = <-errChan:
+		case <-ctx.Done():
+		}
+		<-scheduler.Stop().Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("serve", slog.String("error", err.Error()))
+			return err
+		}
+		slog.Info("serve stopped")
+		return nil
+	},
+}
+
+func init() {
+	subCmdServe.PersistentFlags().StringP("config", "c", "", "config file")
+	_ = subCmdServe.MarkPersistentFlagRequired("config")
+	_ = viper.BindPFlag("serve-config", subCmdServe.PersistentFlags().Lookup("config"))
+
+	subCmdServe.PersistentFlags().StringP("address", "", "", "listen address, e.g. 127.0.0.1:9100")
+	_ = subCmdServe.MarkPersistentFlagRequired("address")
+	_ = viper.BindPFlag("serve-address", subCmdServe.PersistentFlags().Lookup("address"))
+
+	subCmdServe.PersistentFlags().Int("max-events", promexporter.DefaultMaxEvents, "events kept in memory")
+	_ = viper.BindPFlag("serve-max-events", subCmdServe.PersistentFlags().Lookup("max-events"))
+
+	rootCmd.AddCommand(subCmdServe)
+}
@@ -11,6 +11,7 @@ require (
 	github.com/olekukonko/tablewriter v0.0.5
 	github.com/pingcap/errors v0.11.4
 	github.com/pkg/errors v0.9.1
+	github.com/robfig/cron/v3 v3.0.1
 	github.com/shopspring/decimal v1.3.1
 	github.com/spf13/cast v1.5.1
 	github.com/spf13/cobra v1.7.0
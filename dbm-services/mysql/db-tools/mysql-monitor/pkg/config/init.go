@@ -15,6 +15,13 @@ var MonitorConfig *monitorConfig
 var ItemsConfig []*MonitorItem
 var HardCodeSchedule = "@every 10s"
 
+const (
+	// DefaultMaxConcurrency 同时运行的监控项数量
+	DefaultMaxConcurrency = 4
+	// DefaultHistoryDir 相对于可执行文件所在目录
+	DefaultHistoryDir = "history"
+)
+
 // InitConfig 配置初始化
 func InitConfig(configPath string) error {
 	fmt.Printf("config flag: %s\n", configPath)
@@ -49,6 +56,21 @@ func InitConfig(configPath string) error {
 		return err
 	}
 
+	if MonitorConfig.MaxConcurrency == 0 {
+		MonitorConfig.MaxConcurrency = DefaultMaxConcurrency
+	}
+	if MonitorConfig.HistoryDir == "" {
+		MonitorConfig.HistoryDir = DefaultHistoryDir
+	}
+	if !filepath.IsAbs(MonitorConfig.HistoryDir) {
+		executable, err := os.Executable()
+		if err != nil {
+			slog.Error("init config", slog.String("error", err.Error()))
+			return err
+		}
+		MonitorConfig.HistoryDir = filepath.Join(filepath.Dir(executable), MonitorConfig.HistoryDir)
+	}
+
 	return nil
 }
 
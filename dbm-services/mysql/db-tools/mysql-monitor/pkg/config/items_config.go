@@ -1,14 +1,37 @@
 package config
 
-import "slices"
+import (
+	"slices"
+	"time"
+)
 
 // MonitorItem 监控项
 type MonitorItem struct {
-	Name        string   `yaml:"name" validate:"required"`
-	Enable      *bool    `yaml:"enable" validate:"required"`
-	Schedule    *string  `yaml:"schedule"`
-	MachineType []string `yaml:"machine_type"`
-	Role        []string `yaml:"role"`
+	Name        string        `yaml:"name" validate:"required"`
+	Enable      *bool         `yaml:"enable" validate:"required"`
+	Schedule    *string       `yaml:"schedule"`
+	MachineType []string      `yaml:"machine_type"`
+	Role        []string      `yaml:"role"`
+	Timeout     time.Duration `yaml:"timeout,omitempty" validate:"gte=0"`
+	Retry       int           `yaml:"retry,omitempty" validate:"gte=0"`
+}
+
+// GetTimeout 监控项没有配置超时则使用 runtime 配置的 item_timeout, 都没有配置返回 0 表示不超时
+func (c *MonitorItem) GetTimeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return MonitorConfig.ItemTimeout
+}
+
+// FindMonitorItem 按名字查找监控项配置, 找不到返回默认配置
+func FindMonitorItem(name string) *MonitorItem {
+	for _, ele := range ItemsConfig {
+		if ele.Name == name {
+			return ele
+		}
+	}
+	return &MonitorItem{Name: name}
 }
 
 // IsEnable 监控项启用
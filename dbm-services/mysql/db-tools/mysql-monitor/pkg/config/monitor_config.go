@@ -33,4 +33,7 @@ type monitorConfig struct {
 	DBASysDbs       []string      `yaml:"dba_sys_dbs" validate:"required"`
 	InteractTimeout time.Duration `yaml:"interact_timeout" validate:"required"`
 	DefaultSchedule string        `yaml:"default_schedule" validate:"required"`
+	ItemTimeout     time.Duration `yaml:"item_timeout" validate:"gte=0"`
+	MaxConcurrency  int           `yaml:"max_concurrency" validate:"gte=0"`
+	HistoryDir      string        `yaml:"history_dir"`
 }
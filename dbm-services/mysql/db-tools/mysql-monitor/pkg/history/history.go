@@ -0,0 +1,125 @@
+// TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+// Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+// Package history 监控项运行记录
+// 每个实例每个监控项一个文件, 只保留最近的若干条
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// MaxRecords 每个监控项保留的记录数
+const MaxRecords = 20
+
+// Record 一次运行记录
+type Record struct {
+	Name     string        `json:"name"`
+	StartAt  time.Time     `json:"start_at"`
+	Duration time.Duration `json:"duration"`
+	Attempts int           `json:"attempts"`
+	Success  bool          `json:"success"`
+	TimedOut bool          `json:"timed_out"`
+	Msg      string        `json:"msg,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+func filePath(dir string, port int, name string) string {
+	return filepath.Join(dir, fmt.Sprintf("%d", port), fmt.Sprintf("%s.json", name))
+}
+
+// Load 读取监控项的运行记录, 按时间先后排列
+func Load(dir string, port int, name string) (records []*Record, err error) {
+	content, err := os.ReadFile(filePath(dir, port, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		slog.Error("load history", slog.String("error", err.Error()), slog.String("name", name))
+		return nil, err
+	}
+
+	err = json.Unmarshal(content, &records)
+	if err != nil {
+		slog.Error("unmarshal history", slog.String("error", err.Error()), slog.String("name", name))
+		return nil, err
+	}
+	return records, nil
+}
+
+// Append 追加一条记录
+// 先写临时文件再 rename, 同一个监控项并发写入时最多丢一条记录, 不会写坏文件
+func Append(dir string, port int, rec *Record) error {
+	records, err := Load(dir, port, rec.Name)
+	if err != nil {
+		// 文件损坏就丢掉旧记录重新开始
+		records = nil
+	}
+	records = append(records, rec)
+	if len(records) > MaxRecords {
+		records = records[len(records)-MaxRecords:]
+	}
+
+	content, err := json.Marshal(records)
+	if err != nil {
+		slog.Error("marshal history", slog.String("error", err.Error()))
+		return err
+	}
+
+	path := filePath(dir, port, rec.Name)
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		slog.Error("create history dir", slog.String("error", err.Error()))
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), fmt.Sprintf(".%s.*", rec.Name))
+	if err != nil {
+		slog.Error("create history temp file", slog.String("error", err.Error()))
+		return err
+	}
+	defer func() {
+		_ = os.Remove(f.Name())
+	}()
+
+	_, err = f.Write(content)
+	if err != nil {
+		_ = f.Close()
+		slog.Error("write history", slog.String("error", err.Error()))
+		return err
+	}
+	if err = f.Close(); err != nil {
+		slog.Error("close history", slog.String("error", err.Error()))
+		return err
+	}
+
+	err = os.Rename(f.Name(), path)
+	if err != nil {
+		slog.Error("rename history", slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// Last 最近一次记录和最近一次成功的记录
+func Last(records []*Record) (last *Record, lastSuccess *Record) {
+	for i := len(records) - 1; i >= 0; i-- {
+		if last == nil {
+			last = records[i]
+		}
+		if records[i].Success {
+			return last, records[i]
+		}
+	}
+	return last, nil
+}
@@ -0,0 +1,73 @@
+package history
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAppendAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	if rs, err := Load(dir, 3306, "ibd-statistic"); err != nil || rs != nil {
+		t.Fatalf("load without history: %v, %v", rs, err)
+	}
+
+	now := time.Now()
+	for i := 0; i < MaxRecords+5; i++ {
+		rec := &Record{Name: "ibd-statistic", StartAt: now.Add(time.Duration(i) * time.Second), Attempts: i}
+		if err := Append(dir, 3306, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = Append(dir, 3307, &Record{Name: "ibd-statistic"})
+
+	rs, err := Load(dir, 3306, "ibd-statistic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != MaxRecords {
+		t.Fatalf("keep %d records, got %d", MaxRecords, len(rs))
+	}
+	if rs[0].Attempts != 5 || rs[len(rs)-1].Attempts != MaxRecords+4 {
+		t.Fatalf("oldest records should be dropped, got first %d last %d", rs[0].Attempts, rs[len(rs)-1].Attempts)
+	}
+
+	// 文件损坏时丢掉旧记录
+	if err := os.WriteFile(filePath(dir, 3307, "ibd-statistic"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(dir, 3307, "ibd-statistic"); err == nil {
+		t.Fatal("load broken history should fail")
+	}
+	if err := Append(dir, 3307, &Record{Name: "ibd-statistic", Success: true}); err != nil {
+		t.Fatal(err)
+	}
+	if rs, err = Load(dir, 3307, "ibd-statistic"); err != nil || len(rs) != 1 || !rs[0].Success {
+		t.Fatalf("broken history should be replaced, got %v, %v", rs, err)
+	}
+}
+
+func TestLast(t *testing.T) {
+	ok1 := &Record{Name: "a", Success: true}
+	fail1 := &Record{Name: "a"}
+	ok2 := &Record{Name: "a", Success: true}
+	fail2 := &Record{Name: "a"}
+
+	cases := []struct {
+		name        string
+		records     []*Record
+		last        *Record
+		lastSuccess *Record
+	}{
+		{"empty", nil, nil, nil},
+		{"all failed", []*Record{fail1, fail2}, fail2, nil},
+		{"last success", []*Record{fail1, ok2}, ok2, ok2},
+		{"failed after success", []*Record{ok1, fail1, ok2, fail2}, fail2, ok2},
+	}
+	for _, c := range cases {
+		last, lastSuccess := Last(c.records)
+		if last != c.last || lastSuccess != c.lastSuccess {
+			t.Errorf("%s: got %+v %+v, want %+v %+v", c.name, last, lastSuccess, c.last, c.lastSuccess)
+		}
+	}
+}
@@ -9,6 +9,7 @@
 package ibdstatistic
 
 import (
+	"context"
 	"io/fs"
 	"log/slog"
 	"os"
@@ -16,11 +17,14 @@ import (
 	"strings"
 )
 
-func collectResult(dataDir string) (map[string]map[string]int64, error) {
+func collectResult(ctx context.Context, dataDir string) (map[string]map[string]int64, error) {
 	result := make(map[string]map[string]int64)
 
 	err := filepath.WalkDir(
 		dataDir, func(path string, d fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 			if err != nil {
 				return fs.SkipDir
 			}
@@ -49,11 +49,16 @@ type ibdStatistic struct {
 
 // Run TODO
 func (c *ibdStatistic) Run() (msg string, err error) {
-	ctx, cancel := context.WithTimeout(context.Background(), config.MonitorConfig.InteractTimeout)
+	return c.RunContext(context.Background())
+}
+
+// RunContext 超时后停止扫描 datadir
+func (c *ibdStatistic) RunContext(ctx context.Context) (msg string, err error) {
+	qCtx, cancel := context.WithTimeout(ctx, config.MonitorConfig.InteractTimeout)
 	defer cancel()
 
 	var dataDir sql.NullString
-	err = c.db.GetContext(ctx, &dataDir, `SELECT @@datadir`)
+	err = c.db.GetContext(qCtx, &dataDir, `SELECT @@datadir`)
 	if err != nil {
 		slog.Error("ibd-statistic", slog.String("error", err.Error()))
 		return "", err
@@ -65,7 +70,7 @@ func (c *ibdStatistic) Run() (msg string, err error) {
 		return "", err
 	}
 
-	result, err := collectResult(dataDir.String)
+	result, err := collectResult(ctx, dataDir.String)
 	if err != nil {
 		return "", err
 	}
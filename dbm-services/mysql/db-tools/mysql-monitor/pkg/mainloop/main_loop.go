@@ -10,12 +10,16 @@
 package mainloop
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"dbm-services/mysql/db-tools/mysql-monitor/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/history"
 	"dbm-services/mysql/db-tools/mysql-monitor/pkg/itemscollect"
 	"dbm-services/mysql/db-tools/mysql-monitor/pkg/monitoriteminterface"
 	"dbm-services/mysql/db-tools/mysql-monitor/pkg/utils"
@@ -33,6 +37,11 @@ func Run(hardcode bool) error {
 	} else {
 		iNames = viper.GetStringSlice("run-items")
 	}
+	return RunItems(iNames, hardcode)
+}
+
+// RunItems 运行指定的监控项, serve 模式按调度直接调用
+func RunItems(iNames []string, hardcode bool) error {
 	slog.Info("main loop", slog.String("items", strings.Join(iNames, ",")))
 	slog.Info("main loop", slog.Bool("hardcode", hardcode))
 
@@ -57,36 +66,133 @@ func Run(hardcode bool) error {
 		return nil
 	}
 
+	// 监控项之间互不依赖, 并发执行, 避免一个卡住的监控项拖住后面所有的
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, config.MonitorConfig.MaxConcurrency)
 	for _, iName := range iNames {
-
-		if constructor, ok := itemscollect.RegisteredItemConstructor()[iName]; ok {
-			msg, err := constructor(cc).Run()
-			if err != nil {
-				slog.Error("run monitor item", slog.String("error", err.Error()), slog.String("name", iName))
-				utils.SendMonitorEvent(
-					"monitor-internal-error",
-					fmt.Sprintf("run monitor item %s failed: %s", iName, err.Error()),
-				)
-				continue
-			}
-
-			if msg != "" {
-				slog.Info(
-					"run monitor items",
-					slog.String("name", iName),
-					slog.String("msg", msg),
-				)
-				utils.SendMonitorEvent(iName, msg)
-				continue
-			}
-
-			slog.Info("run monitor item pass", slog.String("name", iName))
-
-		} else {
+		constructor, ok := itemscollect.RegisteredItemConstructor()[iName]
+		if !ok {
 			err := errors.Errorf("%s not registered", iName)
 			slog.Error("run monitor item", slog.String("error", err.Error()))
 			continue
 		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(iName string, constructor monitoriteminterface.MonitorItemConstructorFuncType) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			runItem(cc, iName, constructor)
+		}(iName, constructor)
 	}
+	wg.Wait()
+
 	return nil
 }
+
+// itemExitWait 超时后等待监控项退出的时间
+var itemExitWait = 3 * time.Second
+
+func runItem(
+	cc *monitoriteminterface.ConnectionCollect,
+	iName string,
+	constructor monitoriteminterface.MonitorItemConstructorFuncType,
+) {
+	itemConfig := config.FindMonitorItem(iName)
+	timeout := itemConfig.GetTimeout()
+
+	rec := &history.Record{Name: iName, StartAt: time.Now()}
+	var msg string
+	var err error
+	for rec.Attempts <= itemConfig.Retry {
+		rec.Attempts++
+		var running bool
+		msg, running, err = runWithTimeout(constructor(cc), timeout)
+		if err == nil {
+			break
+		}
+		rec.TimedOut = errors.Is(err, context.DeadlineExceeded)
+		slog.Error(
+			"run monitor item",
+			slog.String("error", err.Error()),
+			slog.String("name", iName),
+			slog.Int("attempts", rec.Attempts),
+		)
+		// 上一次还在跑, 再重试就会有两个同时运行
+		if running {
+			slog.Warn("monitor item still running after timeout, skip retry", slog.String("name", iName))
+			break
+		}
+	}
+	rec.Duration = time.Since(rec.StartAt)
+	rec.Msg = msg
+	rec.Success = err == nil
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	_ = history.Append(config.MonitorConfig.HistoryDir, config.MonitorConfig.Port, rec)
+
+	if err != nil {
+		utils.SendMonitorEvent(
+			"monitor-internal-error",
+			fmt.Sprintf("run monitor item %s failed: %s", iName, err.Error()),
+		)
+		return
+	}
+
+	if msg != "" {
+		slog.Info(
+			"run monitor items",
+			slog.String("name", iName),
+			slog.String("msg", msg),
+		)
+		utils.SendMonitorEvent(iName, msg)
+		return
+	}
+
+	slog.Info("run monitor item pass", slog.String("name", iName), slog.Duration("duration", rec.Duration))
+}
+
+// runWithTimeout 超时返回 context.DeadlineExceeded, timeout 为 0 不超时
+// running 表示超时后等了 itemExitWait 监控项还没有退出
+func runWithTimeout(
+	item monitoriteminterface.MonitorItemInterface, timeout time.Duration,
+) (msg string, running bool, err error) {
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+
+	type result struct {
+		msg string
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		var r result
+		if ci, ok := item.(monitoriteminterface.ContextMonitorItemInterface); ok {
+			r.msg, r.err = ci.RunContext(ctx)
+		} else {
+			r.msg, r.err = item.Run()
+		}
+		ch <- r
+	}()
+
+	select {
+	case r := <-ch:
+		return r.msg, false, r.err
+	case <-ctx.Done():
+	}
+
+	// 能响应 ctx 的监控项给一点时间退出
+	select {
+	case <-ch:
+		running = false
+	case <-time.After(itemExitWait):
+		running = true
+	}
+	return "", running, errors.Wrapf(ctx.Err(), "timeout after %s", timeout)
+}
@@ -0,0 +1,60 @@
+package mainloop
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"dbm-services/mysql/db-tools/mysql-monitor/pkg/monitoriteminterface"
+)
+
+type fakeItem struct {
+	sleep time.Duration
+}
+
+func (f *fakeItem) Run() (string, error) {
+	time.Sleep(f.sleep)
+	return "done", nil
+}
+
+func (f *fakeItem) Name() string {
+	return "fake"
+}
+
+type fakeContextItem struct {
+	fakeItem
+}
+
+func (f *fakeContextItem) RunContext(ctx context.Context) (string, error) {
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case <-time.After(f.sleep):
+		return "done", nil
+	}
+}
+
+func TestRunWithTimeout(t *testing.T) {
+	itemExitWait = 100 * time.Millisecond
+
+	cases := []struct {
+		name    string
+		item    monitoriteminterface.MonitorItemInterface
+		timeout time.Duration
+		msg     string
+		running bool
+		timeOut bool
+	}{
+		{"finish in time", &fakeItem{sleep: 10 * time.Millisecond}, time.Second, "done", false, false},
+		{"no timeout", &fakeItem{sleep: 10 * time.Millisecond}, 0, "done", false, false},
+		{"plain item keeps running", &fakeItem{sleep: time.Second}, 10 * time.Millisecond, "", true, true},
+		{"context item stops", &fakeContextItem{fakeItem{sleep: time.Second}}, 10 * time.Millisecond, "", false, true},
+	}
+	for _, c := range cases {
+		msg, running, err := runWithTimeout(c.item, c.timeout)
+		if msg != c.msg || running != c.running || errors.Is(err, context.DeadlineExceeded) != c.timeOut {
+			t.Errorf("%s: got %q, running %v, err %v", c.name, msg, running, err)
+		}
+	}
+}
@@ -1,11 +1,20 @@
 // Package monitoriteminterface 监控项接口
 package monitoriteminterface
 
+import "context"
+
 // MonitorItemInterface TODO
 type MonitorItemInterface interface {
 	Run() (msg string, err error)
 	Name() string
 }
 
+// ContextMonitorItemInterface 能响应超时的监控项
+// 没有实现这个接口的监控项超时后不再等待, 放到后台直到进程退出
+type ContextMonitorItemInterface interface {
+	MonitorItemInterface
+	RunContext(ctx context.Context) (msg string, err error)
+}
+
 // MonitorItemConstructorFuncType TODO
 type MonitorItemConstructorFuncType func(cc *ConnectionCollect) MonitorItemInterface
@@ -0,0 +1,11 @@
+package utils
+
+import "dbm-services/common/go-pubpkg/promexporter"
+
+// serve 模式下指标和事件写到 exporter, 不再通过 crond 推送
+var exporter *promexporter.Registry
+
+// EnableExporter 切换到 serve 模式
+func EnableExporter(r *promexporter.Registry) {
+	exporter = r
+}
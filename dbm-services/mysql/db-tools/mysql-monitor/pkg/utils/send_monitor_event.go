@@ -4,6 +4,7 @@ import (
 	"log/slog"
 	"strconv"
 
+	"dbm-services/common/go-pubpkg/promexporter"
 	ma "dbm-services/mysql/db-tools/mysql-crond/api"
 	"dbm-services/mysql/db-tools/mysql-monitor/pkg/config"
 )
@@ -28,6 +29,12 @@ func SendMonitorEvent(name string, msg string) {
 		additionDimension["instance_role"] = *config.MonitorConfig.Role
 	}
 
+	if exporter != nil {
+		exporter.AddEvent(name, msg, promexporter.LabelsFromDimension(additionDimension))
+		slog.Info("add event", slog.String("name", name), slog.String("msg", msg))
+		return
+	}
+
 	err := crondManager.SendEvent(
 		name,
 		msg,
@@ -5,6 +5,7 @@ import (
 	"maps"
 	"strconv"
 
+	"dbm-services/common/go-pubpkg/promexporter"
 	ma "dbm-services/mysql/db-tools/mysql-crond/api"
 	"dbm-services/mysql/db-tools/mysql-monitor/pkg/config"
 )
@@ -31,6 +32,12 @@ func SendMonitorMetrics(name string, value int64, customDimension map[string]int
 		additionDimension["instance_role"] = *config.MonitorConfig.Role
 	}
 
+	if exporter != nil {
+		exporter.SetGauge(name, float64(value), promexporter.LabelsFromDimension(additionDimension))
+		slog.Info("set metrics", slog.String("name", name), slog.Int64("value", value))
+		return
+	}
+
 	err := crondManager.SendMetrics(
 		name,
 		value,
@@ -11,6 +11,8 @@
 package cmd
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 
 	sq "github.com/Masterminds/squirrel"
@@ -32,6 +34,9 @@ var queryCmd = &cobra.Command{
 			return err
 		}
 		defer models.DB.Conn.Close()
+		if cmd.Flags().Changed("start-time") || cmd.Flags().Changed("stop-time") || cmd.Flags().Changed("gtid") {
+			return queryBinlogRange(cmd)
+		}
 		binlogInst := models.BinlogFileModel{}
 		//var whereMap = make(map[string]interface{})
 		sqlBuilder := sq.Select(
@@ -91,6 +96,59 @@ var queryCmd = &cobra.Command{
 	},
 }
 
+// queryBinlogRange 查询覆盖时间范围或者 gtid 的 binlog
+func queryBinlogRange(cmd *cobra.Command) (err error) {
+	// 新增的 gtid 字段需要 migrate
+	if err = models.SetupTable(); err != nil {
+		return err
+	}
+	q := &models.BinlogRangeQuery{}
+	q.Port, _ = cmd.Flags().GetInt("port")
+	q.Host, _ = cmd.Flags().GetString("host")
+	q.GTIDSet, _ = cmd.Flags().GetString("gtid")
+	startTime, _ := cmd.Flags().GetString("start-time")
+	stopTime, _ := cmd.Flags().GetString("stop-time")
+	if q.StartTime, err = models.ParseQueryTime(startTime); err != nil {
+		return err
+	}
+	if q.StopTime, err = models.ParseQueryTime(stopTime); err != nil {
+		return err
+	}
+	res, err := q.Query(models.DB.Conn)
+	if err != nil {
+		return err
+	}
+	if viper.GetString("format") == "json" {
+		b, _ := json.MarshalIndent(res, "", "  ")
+		fmt.Println(string(b))
+		return nil
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(false)
+	table.SetRowLine(true)
+	table.SetHeader([]string{"Port", "Filename", "StartTime", "StopTime", "GTIDSet", "LocalFile",
+		"BackupTaskId", "BackupStatus", "StatusMsg"})
+	for _, f := range res.Files {
+		table.Append([]string{
+			cast.ToString(f.Port),
+			f.Filename,
+			f.StartTime,
+			f.StopTime,
+			f.GTIDSet,
+			f.LocalFile,
+			f.BackupTaskid,
+			cast.ToString(f.BackupStatus),
+			f.BackupStatusInfo,
+		})
+	}
+	table.Render()
+	if !res.Complete {
+		fmt.Printf("incomplete: %s\n", res.Message)
+	}
+	return nil
+}
+
 func init() {
 	//命令行的flag
 	queryCmd.Flags().StringP("filename-like", "n", "", "file name like query")
@@ -98,6 +156,11 @@ func init() {
 	queryCmd.Flags().Int("cluster-id", 0, "ClusterId filter")
 	queryCmd.Flags().Int("port", 0, "Port filter")
 	queryCmd.Flags().IntP("limit", "l", 10, "rows limit num")
+	queryCmd.Flags().String("host", "", "Host filter, used with start-time/stop-time/gtid")
+	queryCmd.Flags().String("start-time", "",
+		"query binlog files covering time range, need port. format 2006-01-02 15:04:05 or RFC3339")
+	queryCmd.Flags().String("stop-time", "", "query binlog files covering time range, need port")
+	queryCmd.Flags().String("gtid", "", "query binlog files containing gtid set, need port")
 
 	queryCmd.Flags().StringP("format", "m", "table", "output format, table | json")
 	// bind to viper
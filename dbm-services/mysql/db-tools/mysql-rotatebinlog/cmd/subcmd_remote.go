@@ -0,0 +1,125 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+ * Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at https://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+ * an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"dbm-services/common/go-pubpkg/logger"
+	"dbm-services/mysql/db-tools/mysql-rotatebinlog/pkg/backup"
+	"dbm-services/mysql/db-tools/mysql-rotatebinlog/pkg/log"
+	"dbm-services/mysql/db-tools/mysql-rotatebinlog/pkg/rotate"
+)
+
+var remoteCmd = &cobra.Command{
+	Use:   "remote",
+	Short: "list or download binlog from s3 or local backup_client",
+	Long:  `list or download binlog uploaded by s3 or local backup_client, filter by time range`,
+}
+
+var remoteListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "list uploaded binlog files",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		files, _, err := queryRemoteBinlogs(cmd)
+		if err != nil {
+			return err
+		}
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetAutoWrapText(false)
+		table.SetAutoFormatHeaders(false)
+		table.SetHeader([]string{"Host", "Filename", "Filesize", "StartTime", "StopTime", "UploadTime", "Md5"})
+		for _, f := range files {
+			table.Append([]string{f.Host, f.Filename, cast.ToString(f.Filesize), f.StartTime, f.StopTime,
+				f.UploadTime, f.Md5})
+		}
+		table.Render()
+		return nil
+	},
+}
+
+var remoteDownloadCmd = &cobra.Command{
+	Use:          "download",
+	Short:        "download uploaded binlog files to target dir",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		files, remoteClient, err := queryRemoteBinlogs(cmd)
+		if err != nil {
+			return err
+		}
+		targetDir, _ := cmd.Flags().GetString("target-dir")
+		if len(files) == 0 {
+			return errors.New("no binlog files found")
+		}
+		for _, f := range files {
+			fileName, err := remoteClient.Download(f, targetDir)
+			if err != nil {
+				return errors.WithMessagef(err, "download %s", f.Filename)
+			}
+			logger.Info("downloaded %s", fileName)
+			fmt.Println(fileName)
+		}
+		return nil
+	},
+}
+
+// queryRemoteBinlogs 按命令行的 host port 时间范围过滤已上传的 binlog
+func queryRemoteBinlogs(cmd *cobra.Command) ([]*backup.RemoteBinlog, backup.RemoteClient, error) {
+	if err := log.InitLogger(); err != nil {
+		return nil, nil, err
+	}
+	if _, err := rotate.InitConfig(viper.GetString("config")); err != nil {
+		return nil, nil, err
+	}
+	var startTime, stopTime time.Time
+	for flagName, t := range map[string]*time.Time{"start-time": &startTime, "stop-time": &stopTime} {
+		if v, _ := cmd.Flags().GetString(flagName); v != "" {
+			tt, err := time.ParseInLocation(time.DateTime, v, time.Local)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "invalid %s", flagName)
+			}
+			*t = tt
+		}
+	}
+	remoteClient, err := backup.InitRemoteClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	host, _ := cmd.Flags().GetString("host")
+	files, err := remoteClient.List(host)
+	if err != nil {
+		return nil, nil, err
+	}
+	port, _ := cmd.Flags().GetInt("port")
+	return backup.FilterBinlogs(files, port, startTime, stopTime), remoteClient, nil
+}
+
+func init() {
+	for _, c := range []*cobra.Command{remoteListCmd, remoteDownloadCmd} {
+		c.Flags().String("host", "", "binlog uploaded by host, default the host of backup_client config")
+		c.Flags().Int("port", 0, "Port filter")
+		c.Flags().String("start-time", "", "binlog overlap time range start, format 2006-01-02 15:04:05")
+		c.Flags().String("stop-time", "", "binlog overlap time range stop, format 2006-01-02 15:04:05")
+		remoteCmd.AddCommand(c)
+	}
+	remoteDownloadCmd.Flags().String("target-dir", ".", "directory to save downloaded binlog files")
+
+	rootCmd.AddCommand(remoteCmd)
+}
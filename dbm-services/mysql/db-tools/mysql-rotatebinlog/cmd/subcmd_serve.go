@@ -0,0 +1,89 @@
+/*
+ * TencentBlueKing is pleased to support the open source community by making 蓝鲸智云-DB管理系统(BlueKing-BK-DBM) available.
+ * Copyright (C) 2017-2023 THL A29 Limited, a Tencent company. All rights reserved.
+ * Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at https://opensource.org/licenses/MIT
+ * Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+ * an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/cobra"
+
+	"dbm-services/common/go-pubpkg/logger"
+	"dbm-services/mysql/db-tools/mysql-rotatebinlog/pkg/log"
+	"dbm-services/mysql/db-tools/mysql-rotatebinlog/pkg/models"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "http server to query binlog files by time range or gtid",
+	Long: `http server to query binlog files by time range or gtid. e.g.
+curl 'http://127.0.0.1:8090/binlog/range?port=20000&start_time=2024-01-01+03:00:00&stop_time=2024-01-01+03:15:00'
+curl 'http://127.0.0.1:8090/binlog/range?port=20000&gtid=3e11fa47-71ca-11e1-9e33-c80aa9429562:101-150'`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := log.InitLogger(); err != nil {
+			return err
+		}
+		if err := models.InitDB(); err != nil {
+			return err
+		}
+		defer models.DB.Conn.Close()
+		if err := models.SetupTable(); err != nil {
+			return err
+		}
+		address, _ := cmd.Flags().GetString("address")
+		mux := http.NewServeMux()
+		mux.HandleFunc("/binlog/range", handleBinlogRange)
+		logger.Info("binlog query server listen on %s", address)
+		return http.ListenAndServe(address, mux)
+	},
+}
+
+// handleBinlogRange GET /binlog/range?port=&host=&start_time=&stop_time=&gtid=
+func handleBinlogRange(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	writeError := func(code int, err error) {
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+	}
+	params := r.URL.Query()
+	q := &models.BinlogRangeQuery{
+		Host:    params.Get("host"),
+		Port:    cast.ToInt(params.Get("port")),
+		GTIDSet: params.Get("gtid"),
+	}
+	var err error
+	if q.StartTime, err = models.ParseQueryTime(params.Get("start_time")); err != nil {
+		writeError(http.StatusBadRequest, err)
+		return
+	}
+	if q.StopTime, err = models.ParseQueryTime(params.Get("stop_time")); err != nil {
+		writeError(http.StatusBadRequest, err)
+		return
+	}
+	if err = q.Validate(); err != nil {
+		writeError(http.StatusBadRequest, err)
+		return
+	}
+	res, err := q.Query(models.DB.Conn)
+	if err != nil {
+		logger.Error("query binlog range %+v failed: %s", q, err.Error())
+		writeError(http.StatusInternalServerError, err)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func init() {
+	serveCmd.Flags().String("address", "127.0.0.1:8090", "http listen address")
+	rootCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,168 @@
+package backup
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"dbm-services/common/go-pubpkg/logger"
+	"dbm-services/common/go-pubpkg/validate"
+	"dbm-services/mysql/db-tools/mysql-rotatebinlog/pkg/models"
+)
+
+// LocalBackupClient 复制 binlog 到本地目录，一般是挂载的 NFS
+// binlog 复制到 <backup_dir>/<host>/<filename>，task_id 就是目标文件路径
+type LocalBackupClient struct {
+	Enable    bool   `mapstructure:"enable" json:"enable"`
+	BackupDir string `mapstructure:"backup_dir" json:"backup_dir" validate:"required"`
+	// Host 区分不同机器的目录，为空时使用主机名
+	Host string `mapstructure:"host" json:"host"`
+	// RetentionDays 保留天数，0 表示不清理
+	RetentionDays int `mapstructure:"retention_days" json:"retention_days"`
+}
+
+// Init 检查 backup_dir 是否存在
+func (o *LocalBackupClient) Init() (err error) {
+	if err = validate.GoValidateStruct(o, false, false); err != nil {
+		return err
+	}
+	if o.Host, err = defaultHost(o.Host); err != nil {
+		return err
+	}
+	if st, err := os.Stat(o.BackupDir); err != nil {
+		return errors.Wrap(err, "backup_dir")
+	} else if !st.IsDir() {
+		return errors.Errorf("backup_dir %s is not a directory", o.BackupDir)
+	}
+	return nil
+}
+
+func (o *LocalBackupClient) hostDir(host string) string {
+	return filepath.Join(o.BackupDir, host)
+}
+
+// Upload 先复制到临时文件再改名，最后写 .meta 文件，返回目标文件路径作为 taskId
+func (o *LocalBackupClient) Upload(fileName string) (string, error) {
+	meta, err := newRemoteBinlog(fileName, o.Host)
+	if err != nil {
+		return "", err
+	}
+	dstDir := o.hostDir(o.Host)
+	if err = os.MkdirAll(dstDir, 0755); err != nil {
+		return "", err
+	}
+	dstFile := filepath.Join(dstDir, meta.Filename)
+	logger.Info("backup copy to local dir: %s -> %s", fileName, dstFile)
+	src, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	tmpFile := dstFile + ".tmp"
+	dst, err := os.OpenFile(tmpFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmpFile)
+	meta.Filesize, meta.Md5, err = copyWithMd5(dst, src)
+	if err == nil {
+		err = dst.Sync()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "copy %s", fileName)
+	}
+	if err = os.Rename(tmpFile, dstFile); err != nil {
+		return "", err
+	}
+	meta.UploadTime = time.Now().Format(time.RFC3339)
+	body, _ := json.Marshal(meta)
+	if err = os.WriteFile(dstFile+MetaFileSuffix, body, 0644); err != nil {
+		return "", err
+	}
+	return dstFile, nil
+}
+
+// Query binlog 和 .meta 都存在时上传成功，不存在时返回提交失败，下一轮重新上传
+func (o *LocalBackupClient) Query(taskId string) (int, error) {
+	for _, fileName := range []string{taskId, taskId + MetaFileSuffix} {
+		if _, err := os.Stat(fileName); os.IsNotExist(err) {
+			logger.Warn("local backup file %s not found", fileName)
+			return models.IBStatusClientFail, nil
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	return models.IBStatusSuccess, nil
+}
+
+// List 读取 host 目录下所有的 .meta 文件
+func (o *LocalBackupClient) List(host string) ([]*RemoteBinlog, error) {
+	if host == "" {
+		host = o.Host
+	}
+	entries, err := os.ReadDir(o.hostDir(host))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var files []*RemoteBinlog
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), MetaFileSuffix) {
+			continue
+		}
+		body, err := os.ReadFile(filepath.Join(o.hostDir(host), e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var f RemoteBinlog
+		if err = json.Unmarshal(body, &f); err != nil {
+			return nil, errors.Wrapf(err, "decode %s", e.Name())
+		}
+		files = append(files, &f)
+	}
+	return files, nil
+}
+
+// Download 复制到 dstDir 并校验
+func (o *LocalBackupClient) Download(f *RemoteBinlog, dstDir string) (string, error) {
+	r, err := os.Open(filepath.Join(o.hostDir(f.Host), f.Filename))
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	return saveDownload(f, r, dstDir)
+}
+
+// Purge 删除超过 retention_days 的 binlog，先删 binlog 再删 .meta
+func (o *LocalBackupClient) Purge() error {
+	if o.RetentionDays <= 0 {
+		return nil
+	}
+	files, err := o.List(o.Host)
+	if err != nil {
+		return err
+	}
+	expireTime := time.Now().AddDate(0, 0, -o.RetentionDays)
+	for _, f := range files {
+		if !f.expired(expireTime) {
+			continue
+		}
+		fileName := filepath.Join(o.hostDir(f.Host), f.Filename)
+		logger.Info("purge expired local backup file: %s", fileName)
+		if err = os.Remove(fileName); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err = os.Remove(fileName + MetaFileSuffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package backup
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"dbm-services/mysql/db-tools/mysql-rotatebinlog/pkg/models"
+)
+
+func TestLocalBackupClient(t *testing.T) {
+	binlogContent := "fe62696eb1db64630f70003604890000008d00000000000400352e362e32342d746d7973716c2d322e322e322d6c6f670000000000000000000000000000000000000000000000000000000000000013380d0008001200040404041200007100041a08000000080808020000000a0a0a19190000000000000000000000000000000d0808080a0a0a0102311b69e1dc6463047000360431000000cdee1a010000040000000000000062696e6c6f6732303030302e333530363738776eb630"
+	b, err := hex.DecodeString(binlogContent)
+	assert.Nil(t, err)
+	srcDir, backupDir, restoreDir := t.TempDir(), t.TempDir(), t.TempDir()
+	binlogFile := filepath.Join(srcDir, "binlog20000.000001")
+	assert.Nil(t, os.WriteFile(binlogFile, b, 0644))
+
+	client := &LocalBackupClient{BackupDir: backupDir, Host: "127.0.0.1", RetentionDays: 1}
+	assert.Nil(t, client.Init())
+	taskId, err := client.Upload(binlogFile)
+	assert.Nil(t, err)
+	status, err := client.Query(taskId)
+	assert.Nil(t, err)
+	assert.Equal(t, models.IBStatusSuccess, status)
+
+	files, err := client.List("")
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+	f := files[0]
+	assert.Equal(t, "binlog20000.000001", f.Filename)
+	assert.NotEmpty(t, f.StartTime)
+	assert.NotEmpty(t, f.StopTime)
+
+	startTime, _ := time.Parse(time.RFC3339, f.StartTime)
+	stopTime, _ := time.Parse(time.RFC3339, f.StopTime)
+	assert.Len(t, FilterBinlogs(files, 20000, startTime.Add(-time.Hour), startTime), 1)
+	assert.Len(t, FilterBinlogs(files, 20001, time.Time{}, time.Time{}), 0)
+	assert.Len(t, FilterBinlogs(files, 0, stopTime.Add(time.Second), time.Time{}), 0)
+
+	restored, err := client.Download(f, restoreDir)
+	assert.Nil(t, err)
+	got, err := os.ReadFile(restored)
+	assert.Nil(t, err)
+	assert.Equal(t, b, got)
+
+	// 测试 binlog 是 2023 年的，已经超过保留天数
+	assert.Nil(t, client.Purge())
+	status, err = client.Query(taskId)
+	assert.Nil(t, err)
+	assert.Equal(t, models.IBStatusClientFail, status)
+}
@@ -0,0 +1,158 @@
+package backup
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"dbm-services/common/go-pubpkg/logger"
+	binlog_parser "dbm-services/mysql/db-tools/mysql-rotatebinlog/pkg/binlog-parser"
+)
+
+const (
+	// MetaFileSuffix binlog 上传完成后，再上传一个同名加 .meta 后缀的 json 文件，记录 binlog 的时间范围
+	MetaFileSuffix = ".meta"
+	// PurgeInterval 远程存储过期清理的频率
+	PurgeInterval = 6 * time.Hour
+)
+
+// RemoteBinlog 已上传到远程存储的 binlog 元信息
+type RemoteBinlog struct {
+	Host      string `json:"host"`
+	Filename  string `json:"filename"`
+	Filesize  int64  `json:"filesize"`
+	Md5       string `json:"md5"`
+	StartTime string `json:"start_time"`
+	StopTime  string `json:"stop_time"`
+	// UploadTime 上传完成时间
+	UploadTime string `json:"upload_time"`
+}
+
+// RemoteClient 上传到 s3、本地目录这类可以直接读写的 backup_client
+// 除了上传，还可以按时间查询、下载已备份的 binlog，并清理过期的 binlog
+type RemoteClient interface {
+	BackupClient
+	// List 列出 host 上传的 binlog，host 为空时表示本机
+	List(host string) ([]*RemoteBinlog, error)
+	// Download 下载 binlog 到 dstDir，校验 md5，返回本地文件路径
+	Download(f *RemoteBinlog, dstDir string) (string, error)
+	// Purge 删除本机上传的、超过保留天数的 binlog
+	Purge() error
+}
+
+// defaultHost 远程存储上按 host 区分目录，没有配置时使用主机名
+func defaultHost(host string) (string, error) {
+	if host != "" {
+		return host, nil
+	}
+	return os.Hostname()
+}
+
+// newRemoteBinlog 解析 binlog 的起止时间
+func newRemoteBinlog(fileName string, host string) (*RemoteBinlog, error) {
+	st, err := os.Stat(fileName)
+	if err != nil {
+		return nil, err
+	}
+	f := &RemoteBinlog{Host: host, Filename: filepath.Base(fileName), Filesize: st.Size()}
+	bp, _ := binlog_parser.NewBinlogParse("", 0, time.RFC3339)
+	if events, err := bp.GetTimeIgnoreStopErr(fileName, true, true); err != nil {
+		logger.Warn("binlog %s GetTime failed: %s", fileName, err.Error())
+	} else if len(events) >= 2 {
+		f.StartTime = events[0].EventTime
+		f.StopTime = events[1].EventTime
+	}
+	return f, nil
+}
+
+// copyWithMd5 复制数据同时计算 md5
+func copyWithMd5(dst io.Writer, src io.Reader) (int64, string, error) {
+	h := md5.New()
+	n, err := io.Copy(io.MultiWriter(dst, h), src)
+	if err != nil {
+		return n, "", err
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checkDownload 校验下载的 binlog 大小和 md5
+func checkDownload(f *RemoteBinlog, size int64, md5sum string) error {
+	if size != f.Filesize {
+		return errors.Errorf("download %s size mismatch, expect %d, got %d", f.Filename, f.Filesize, size)
+	}
+	if f.Md5 != "" && md5sum != f.Md5 {
+		return errors.Errorf("download %s md5 mismatch, expect %s, got %s", f.Filename, f.Md5, md5sum)
+	}
+	return nil
+}
+
+// expired binlog 结束时间早于 expireTime，没有结束时间的使用上传时间
+func (f *RemoteBinlog) expired(expireTime time.Time) bool {
+	t := f.StopTime
+	if t == "" {
+		t = f.UploadTime
+	}
+	tt, err := time.Parse(time.RFC3339, t)
+	if err != nil {
+		logger.Warn("binlog %s/%s has invalid time %s", f.Host, f.Filename, t)
+		return false
+	}
+	return tt.Before(expireTime)
+}
+
+// FilterBinlogs 返回端口为 port、时间范围和 [startTime, stopTime] 有重叠的 binlog，按文件名排序
+// port 为 0 不过滤端口，startTime stopTime 为零值时不限制
+func FilterBinlogs(files []*RemoteBinlog, port int, startTime, stopTime time.Time) []*RemoteBinlog {
+	var ret []*RemoteBinlog
+	for _, f := range files {
+		if port != 0 && !strings.HasPrefix(f.Filename, fmt.Sprintf("binlog%d.", port)) {
+			continue
+		}
+		if !startTime.IsZero() && f.StopTime != "" {
+			if t, err := time.Parse(time.RFC3339, f.StopTime); err == nil && t.Before(startTime) {
+				continue
+			}
+		}
+		if !stopTime.IsZero() && f.StartTime != "" {
+			if t, err := time.Parse(time.RFC3339, f.StartTime); err == nil && t.After(stopTime) {
+				continue
+			}
+		}
+		ret = append(ret, f)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Filename < ret[j].Filename })
+	return ret
+}
+
+// saveDownload 把 r 写到 dstDir 下的临时文件，校验后改名为 binlog 文件名
+func saveDownload(f *RemoteBinlog, r io.Reader, dstDir string) (string, error) {
+	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		return "", err
+	}
+	dstFile := filepath.Join(dstDir, f.Filename)
+	tmpFile := dstFile + ".tmp"
+	w, err := os.OpenFile(tmpFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmpFile)
+	size, md5sum, err := copyWithMd5(w, r)
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "download %s", f.Filename)
+	}
+	if err = checkDownload(f, size, md5sum); err != nil {
+		return "", err
+	}
+	return dstFile, os.Rename(tmpFile, dstFile)
+}
@@ -0,0 +1,177 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"dbm-services/common/go-pubpkg/logger"
+	"dbm-services/common/go-pubpkg/objectstore"
+	"dbm-services/common/go-pubpkg/validate"
+	"dbm-services/mysql/db-tools/mysql-rotatebinlog/pkg/models"
+)
+
+// S3BackupClient 上传 binlog 到 s3 兼容的对象存储
+// binlog 的 key 是 <key_prefix>/<host>/<filename>，task_id 就是 key
+type S3BackupClient struct {
+	Enable     bool   `mapstructure:"enable" json:"enable"`
+	Endpoint   string `mapstructure:"endpoint" json:"endpoint" validate:"required"`
+	Region     string `mapstructure:"region" json:"region"`
+	Bucket     string `mapstructure:"bucket" json:"bucket" validate:"required"`
+	AccessKey  string `mapstructure:"access_key" json:"access_key" validate:"required"`
+	SecretKey  string `mapstructure:"secret_key" json:"secret_key" validate:"required"`
+	KeyPrefix  string `mapstructure:"key_prefix" json:"key_prefix"`
+	PartSizeMB int    `mapstructure:"part_size_mb" json:"part_size_mb"`
+	Retries    int    `mapstructure:"retries" json:"retries"`
+	// Host 区分不同机器的目录，为空时使用主机名
+	Host string `mapstructure:"host" json:"host"`
+	// RetentionDays 远程保留天数，0 表示不清理，由对象存储的生命周期规则来清理
+	RetentionDays int `mapstructure:"retention_days" json:"retention_days"`
+
+	client *objectstore.S3Client
+}
+
+// Init 检查配置
+func (o *S3BackupClient) Init() (err error) {
+	if err = validate.GoValidateStruct(o, false, false); err != nil {
+		return err
+	}
+	if o.Host, err = defaultHost(o.Host); err != nil {
+		return err
+	}
+	o.client, err = objectstore.NewS3Client(objectstore.S3Config{
+		Endpoint:   o.Endpoint,
+		Region:     o.Region,
+		Bucket:     o.Bucket,
+		AccessKey:  o.AccessKey,
+		SecretKey:  o.SecretKey,
+		PartSizeMB: o.PartSizeMB,
+		Retries:    o.Retries,
+	})
+	return err
+}
+
+func (o *S3BackupClient) hostPrefix(host string) string {
+	return strings.TrimPrefix(path.Join(o.KeyPrefix, host)+"/", "/")
+}
+
+// Upload 同步上传 binlog 和 .meta 文件，返回 key 作为 taskId
+func (o *S3BackupClient) Upload(fileName string) (string, error) {
+	meta, err := newRemoteBinlog(fileName, o.Host)
+	if err != nil {
+		return "", err
+	}
+	key := o.hostPrefix(o.Host) + meta.Filename
+	logger.Info("backup upload to s3: %s -> %s", fileName, key)
+	f, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	ctx := context.Background()
+	w, err := o.client.NewWriter(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	if meta.Filesize, meta.Md5, err = copyWithMd5(w, f); err != nil {
+		_ = w.Abort()
+		return "", errors.Wrapf(err, "upload %s", fileName)
+	}
+	if err = w.Close(); err != nil {
+		return "", err
+	}
+	meta.UploadTime = time.Now().Format(time.RFC3339)
+	body, _ := json.Marshal(meta)
+	if err = o.client.PutObject(ctx, key+MetaFileSuffix, body); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// Query binlog 和 .meta 都存在时上传成功，不存在时返回提交失败，下一轮重新上传
+func (o *S3BackupClient) Query(taskId string) (int, error) {
+	ctx := context.Background()
+	for _, key := range []string{taskId, taskId + MetaFileSuffix} {
+		if _, err := o.client.HeadObject(ctx, key); objectstore.IsNotFound(err) {
+			logger.Warn("s3 object %s not found", key)
+			return models.IBStatusClientFail, nil
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	return models.IBStatusSuccess, nil
+}
+
+// List 读取 host 目录下所有的 .meta 文件
+func (o *S3BackupClient) List(host string) ([]*RemoteBinlog, error) {
+	if host == "" {
+		host = o.Host
+	}
+	ctx := context.Background()
+	objects, err := o.client.ListObjects(ctx, o.hostPrefix(host))
+	if err != nil {
+		return nil, err
+	}
+	var files []*RemoteBinlog
+	for _, obj := range objects {
+		if !strings.HasSuffix(obj.Key, MetaFileSuffix) {
+			continue
+		}
+		r, err := o.client.GetObject(ctx, obj.Key)
+		if err != nil {
+			return nil, err
+		}
+		var f RemoteBinlog
+		err = json.NewDecoder(r).Decode(&f)
+		_ = r.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode %s", obj.Key)
+		}
+		files = append(files, &f)
+	}
+	return files, nil
+}
+
+// Download 下载到 dstDir，先写临时文件，校验通过后改名
+func (o *S3BackupClient) Download(f *RemoteBinlog, dstDir string) (string, error) {
+	key := o.hostPrefix(f.Host) + f.Filename
+	r, err := o.client.GetObject(context.Background(), key)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	return saveDownload(f, r, dstDir)
+}
+
+// Purge 删除超过 retention_days 的 binlog，先删 binlog 再删 .meta
+func (o *S3BackupClient) Purge() error {
+	if o.RetentionDays <= 0 {
+		return nil
+	}
+	files, err := o.List(o.Host)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	expireTime := time.Now().AddDate(0, 0, -o.RetentionDays)
+	for _, f := range files {
+		if !f.expired(expireTime) {
+			continue
+		}
+		key := o.hostPrefix(f.Host) + f.Filename
+		logger.Info("purge expired s3 object: %s", key)
+		if err = o.client.DeleteObject(ctx, key); err != nil {
+			return err
+		}
+		if err = o.client.DeleteObject(ctx, key+MetaFileSuffix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -33,6 +33,26 @@ func InitBackupClient() (backupClient BackupClient, err error) {
 			} else {
 				backupClient = &ibsClient
 			}
+		} else if name == "s3" { // s3 compatible object storage
+			if !viper.GetBool("backup_client.s3.enable") {
+				continue
+			}
+			var s3Client S3BackupClient
+			if err := mapstructure.Decode(cfgClient, &s3Client); err != nil {
+				return nil, err
+			} else {
+				backupClient = &s3Client
+			}
+		} else if name == "local" { // local directory, nfs mounted
+			if !viper.GetBool("backup_client.local.enable") {
+				continue
+			}
+			var localClient LocalBackupClient
+			if err := mapstructure.Decode(cfgClient, &localClient); err != nil {
+				return nil, err
+			} else {
+				backupClient = &localClient
+			}
 		} else {
 			logger.Error("unknown backup_client %s", name)
 			// return nil, errors.Errorf("unknown backup_client: %s", name)
@@ -46,3 +66,19 @@ func InitBackupClient() (backupClient BackupClient, err error) {
 	}
 	return backupClient, nil
 }
+
+// InitRemoteClient 初始化支持查询和下载的 backup_client，用于恢复 binlog
+func InitRemoteClient() (RemoteClient, error) {
+	backupClient, err := InitBackupClient()
+	if err != nil {
+		return nil, err
+	}
+	if backupClient == nil {
+		return nil, errors.New("no backup_client enabled")
+	}
+	remoteClient, ok := backupClient.(RemoteClient)
+	if !ok {
+		return nil, errors.Errorf("backup_client %T does not support list and download", backupClient)
+	}
+	return remoteClient, nil
+}
@@ -0,0 +1,70 @@
+package binlog_parser
+
+import (
+	"bufio"
+	"io"
+	"os"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/pkg/errors"
+)
+
+// GetPreviousGTIDs 获取 binlog 开头 PreviousGTIDsEvent 记录的 gtid set，即生成这个 binlog 之前已执行的 gtid
+// 没有 PreviousGTIDsEvent 时(如 5.5) 返回空
+func (b *BinlogParse) GetPreviousGTIDs(fileName string) (string, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return "", errors.Wrap(err, "get previous gtids from binlog")
+	}
+	defer f.Close()
+	if _, err = f.Seek(b.firstEventPos, io.SeekStart); err != nil {
+		return "", errors.Wrap(err, fileName)
+	}
+	r := bufio.NewReader(f)
+	var gtidSets string
+	// 第一个 event 是 FormatDescriptionEvent，第二个是 PreviousGTIDsEvent
+	for i := 0; i < 2; i++ {
+		var eventType replication.EventType
+		_, err = b.parser.ParseSingleEvent(r, func(e *replication.BinlogEvent) error {
+			eventType = e.Header.EventType
+			if ev, ok := e.Event.(*replication.PreviousGTIDsEvent); ok {
+				gtidSets = ev.GTIDSets
+			}
+			return nil
+		})
+		if err != nil {
+			return "", errors.Wrap(err, fileName)
+		}
+		if i == 0 && eventType != replication.FORMAT_DESCRIPTION_EVENT {
+			return "", errors.Errorf("%s: failed to find FormatDescriptionEvent at pos 4", fileName)
+		}
+	}
+	return gtidSets, nil
+}
+
+// GTIDSetMinus 返回 a - b
+// 当前 binlog 包含的 gtid = 下一个 binlog 的 previous gtids - 当前 binlog 的 previous gtids
+func GTIDSetMinus(a, b string) (string, error) {
+	setA, err := mysql.ParseMysqlGTIDSet(a)
+	if err != nil {
+		return "", errors.Wrapf(err, "parse gtid set %s", a)
+	}
+	setB, err := mysql.ParseMysqlGTIDSet(b)
+	if err != nil {
+		return "", errors.Wrapf(err, "parse gtid set %s", b)
+	}
+	_ = setA.(*mysql.MysqlGTIDSet).Minus(*setB.(*mysql.MysqlGTIDSet))
+	return setA.String(), nil
+}
+
+// GTIDSetOverlap a b 是否有相同的 gtid
+func GTIDSetOverlap(a, b string) (bool, error) {
+	diff, err := GTIDSetMinus(a, b)
+	if err != nil {
+		return false, err
+	}
+	setA, _ := mysql.ParseMysqlGTIDSet(a)
+	setDiff, _ := mysql.ParseMysqlGTIDSet(diff)
+	return !setA.Equal(setDiff), nil
+}
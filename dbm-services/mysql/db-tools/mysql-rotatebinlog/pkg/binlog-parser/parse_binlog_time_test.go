@@ -27,5 +27,28 @@ func TestGetTime(t *testing.T) {
 	binParse, _ := NewBinlogParse("mysql", 0, "")
 	_, err = binParse.GetTime(testFile, true, true)
 	assert.Nil(t, err)
+	// 5.6 没有开启 gtid，没有 PreviousGTIDsEvent
+	gtids, err := binParse.GetPreviousGTIDs(testFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "", gtids)
 	// fmt.Printf("%+v\n%+v\n", v[0], v[1])
 }
+
+func TestGTIDSet(t *testing.T) {
+	uuid := "3e11fa47-71ca-11e1-9e33-c80aa9429562"
+	prev := uuid + ":1-100"
+	next := uuid + ":1-150"
+	gtidSet, err := GTIDSetMinus(next, prev)
+	assert.Nil(t, err)
+	assert.Equal(t, uuid+":101-150", gtidSet)
+
+	overlap, err := GTIDSetOverlap(gtidSet, uuid+":150-160")
+	assert.Nil(t, err)
+	assert.True(t, overlap)
+	overlap, err = GTIDSetOverlap(gtidSet, uuid+":1-100")
+	assert.Nil(t, err)
+	assert.False(t, overlap)
+	overlap, err = GTIDSetOverlap("", uuid+":1-100")
+	assert.Nil(t, err)
+	assert.False(t, overlap)
+}
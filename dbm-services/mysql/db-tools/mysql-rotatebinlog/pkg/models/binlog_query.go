@@ -0,0 +1,225 @@
+package models
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+
+	"dbm-services/common/go-pubpkg/cmutil"
+	binlog_parser "dbm-services/mysql/db-tools/mysql-rotatebinlog/pkg/binlog-parser"
+)
+
+// BinlogRangeQuery 查询覆盖某个时间范围或者 gtid set 的 binlog，用于定点回档
+// 时间范围和 gtid 只能指定一个
+type BinlogRangeQuery struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+	// StartTime StopTime 为零值时表示不限制
+	StartTime time.Time `json:"start_time"`
+	StopTime  time.Time `json:"stop_time"`
+	GTIDSet   string    `json:"gtid_set"`
+}
+
+// BinlogLocation binlog 本地或者备份的位置
+type BinlogLocation struct {
+	Host      string `json:"host"`
+	Port      int    `json:"port"`
+	Filename  string `json:"filename"`
+	Filesize  int64  `json:"filesize"`
+	StartTime string `json:"start_time"`
+	StopTime  string `json:"stop_time"`
+	GTIDSet   string `json:"gtid_set"`
+	// LocalFile 本地文件还存在时，为文件完整路径
+	LocalFile        string `json:"local_file"`
+	BackupStatus     int    `json:"backup_status"`
+	BackupStatusInfo string `json:"backup_status_info"`
+	BackupTaskid     string `json:"task_id"`
+}
+
+// BinlogRangeResult 查询结果
+type BinlogRangeResult struct {
+	Files []*BinlogLocation `json:"files"`
+	// Complete binlog 编号连续，并且覆盖了查询的时间范围或者 gtid set
+	Complete bool `json:"complete"`
+	// Message 不完整的原因
+	Message string `json:"message"`
+}
+
+// Validate 检查查询条件
+func (q *BinlogRangeQuery) Validate() error {
+	if q.Port == 0 {
+		return errors.New("port is required")
+	}
+	if q.GTIDSet != "" && (!q.StartTime.IsZero() || !q.StopTime.IsZero()) {
+		return errors.New("time range and gtid set cannot be given at the same time")
+	}
+	if q.GTIDSet == "" && q.StartTime.IsZero() && q.StopTime.IsZero() {
+		return errors.New("need time range or gtid set")
+	}
+	if !q.StartTime.IsZero() && !q.StopTime.IsZero() && q.StartTime.After(q.StopTime) {
+		return errors.Errorf("start_time %s is after stop_time %s", q.StartTime, q.StopTime)
+	}
+	return nil
+}
+
+// Query 返回满足条件的最少的 binlog 集合，按文件名排序
+func (q *BinlogRangeQuery) Query(db *sqlx.DB) (*BinlogRangeResult, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	binlogInst := BinlogFileModel{}
+	sqlBuilder := sq.Select(
+		"host", "port", "filename", "filesize", "start_time", "stop_time", "backup_status", "backup_status_info",
+		"task_id", "binlog_dir", "gtid_set",
+	).From(binlogInst.TableName()).Where(sq.Eq{"port": q.Port}).OrderBy("filename asc")
+	if q.Host != "" {
+		sqlBuilder = sqlBuilder.Where(sq.Eq{"host": q.Host})
+	}
+	files, err := binlogInst.QueryWithBuildWhere(db, &sqlBuilder)
+	if err != nil {
+		return nil, err
+	}
+	var matched []*BinlogFileModel
+	for _, f := range files {
+		var ok bool
+		if q.GTIDSet != "" {
+			if ok, err = binlog_parser.GTIDSetOverlap(f.GTIDSet, q.GTIDSet); err != nil {
+				return nil, errors.WithMessage(err, f.Filename)
+			}
+		} else {
+			ok = q.timeOverlap(f)
+		}
+		if ok {
+			matched = append(matched, f)
+		}
+	}
+
+	res := &BinlogRangeResult{Complete: true}
+	for _, f := range matched {
+		res.Files = append(res.Files, newBinlogLocation(f))
+	}
+	var messages []string
+	if msg := checkContinuous(matched); msg != "" {
+		messages = append(messages, msg)
+	}
+	if q.GTIDSet != "" {
+		messages = append(messages, q.checkGTIDCovered(matched)...)
+	} else {
+		messages = append(messages, q.checkTimeCovered(matched)...)
+	}
+	if len(messages) > 0 {
+		res.Complete = false
+		res.Message = strings.Join(messages, "; ")
+	}
+	return res, nil
+}
+
+// timeOverlap binlog 时间范围和查询时间范围有重叠，没有解析到时间的 binlog 不选
+func (q *BinlogRangeQuery) timeOverlap(f *BinlogFileModel) bool {
+	startTime, err1 := time.Parse(time.RFC3339, f.StartTime)
+	stopTime, err2 := time.Parse(time.RFC3339, f.StopTime)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	if !q.StartTime.IsZero() && stopTime.Before(q.StartTime) {
+		return false
+	}
+	if !q.StopTime.IsZero() && startTime.After(q.StopTime) {
+		return false
+	}
+	return true
+}
+
+func (q *BinlogRangeQuery) checkTimeCovered(files []*BinlogFileModel) []string {
+	if len(files) == 0 {
+		return []string{"no binlog found in time range"}
+	}
+	var messages []string
+	firstStart, _ := time.Parse(time.RFC3339, files[0].StartTime)
+	lastStop, _ := time.Parse(time.RFC3339, files[len(files)-1].StopTime)
+	if !q.StartTime.IsZero() && firstStart.After(q.StartTime) {
+		messages = append(messages, fmt.Sprintf("earliest binlog starts at %s", files[0].StartTime))
+	}
+	if q.StopTime.IsZero() || lastStop.Before(q.StopTime) {
+		messages = append(messages,
+			fmt.Sprintf("binlog after %s not registered yet, need binlog in use", files[len(files)-1].StopTime))
+	}
+	return messages
+}
+
+func (q *BinlogRangeQuery) checkGTIDCovered(files []*BinlogFileModel) []string {
+	left := q.GTIDSet
+	for _, f := range files {
+		left, _ = binlog_parser.GTIDSetMinus(left, f.GTIDSet)
+	}
+	if left != "" {
+		return []string{fmt.Sprintf("gtid %s not found in registered binlog", left)}
+	}
+	return nil
+}
+
+// checkContinuous 检查 binlog 编号是否连续
+func checkContinuous(files []*BinlogFileModel) string {
+	var missing []string
+	for i := 1; i < len(files); i++ {
+		prev, err1 := binlogSeq(files[i-1].Filename)
+		cur, err2 := binlogSeq(files[i].Filename)
+		if err1 != nil || err2 != nil || cur != prev+1 {
+			missing = append(missing, fmt.Sprintf("%s..%s", files[i-1].Filename, files[i].Filename))
+		}
+	}
+	if len(missing) > 0 {
+		return "binlog not continuous between " + strings.Join(missing, ",")
+	}
+	return ""
+}
+
+// binlogSeq binlog20000.000123 返回 123
+func binlogSeq(fileName string) (int, error) {
+	return strconv.Atoi(strings.TrimLeft(filepath.Ext(fileName), "."))
+}
+
+func newBinlogLocation(f *BinlogFileModel) *BinlogLocation {
+	l := &BinlogLocation{
+		Host:             f.Host,
+		Port:             f.Port,
+		Filename:         f.Filename,
+		Filesize:         f.Filesize,
+		StartTime:        f.StartTime,
+		StopTime:         f.StopTime,
+		GTIDSet:          f.GTIDSet,
+		BackupStatus:     f.BackupStatus,
+		BackupStatusInfo: f.BackupStatusInfo,
+		BackupTaskid:     f.BackupTaskid,
+	}
+	if f.BackupStatusInfo == "" {
+		l.BackupStatusInfo = IBStatusMap[f.BackupStatus]
+	}
+	if f.BackupStatus != FileStatusRemoved && f.BinlogDir != "" {
+		if localFile := filepath.Join(f.BinlogDir, f.Filename); cmutil.FileExists(localFile) {
+			l.LocalFile = localFile
+		}
+	}
+	return l
+}
+
+// ParseQueryTime 解析查询时间，支持 2006-01-02 15:04:05 (本地时区) 和 RFC3339 格式，空字符串返回零值
+func ParseQueryTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation(time.DateTime, s, time.Local)
+	if err != nil {
+		return time.Time{}, errors.Errorf("invalid time %s, format should be 2006-01-02 15:04:05 or RFC3339", s)
+	}
+	return t, nil
+}
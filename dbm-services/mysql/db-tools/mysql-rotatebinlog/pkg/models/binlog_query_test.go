@@ -0,0 +1,69 @@
+package models
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBinlogRangeQuery(t *testing.T) {
+	db, err := sqlx.Open("sqlite", filepath.Join(t.TempDir(), "binlog_rotate.db"))
+	assert.Nil(t, err)
+	defer db.Close()
+	assert.Nil(t, DoMigrate(db))
+
+	uuid := "3e11fa47-71ca-11e1-9e33-c80aa9429562"
+	binlogDir := t.TempDir()
+	files := []*BinlogFileModel{
+		{Host: "127.0.0.1", Port: 20000, Filename: "binlog20000.000001", StartTime: "2024-01-01T02:00:00+08:00",
+			StopTime: "2024-01-01T02:59:00+08:00", GTIDSet: uuid + ":1-100", BinlogDir: binlogDir,
+			BackupStatus: IBStatusSuccess, BackupTaskid: "1001"},
+		{Host: "127.0.0.1", Port: 20000, Filename: "binlog20000.000002", StartTime: "2024-01-01T02:59:00+08:00",
+			StopTime: "2024-01-01T03:10:00+08:00", GTIDSet: uuid + ":101-200", BinlogDir: binlogDir,
+			BackupStatus: IBStatusSuccess, BackupTaskid: "1002"},
+		{Host: "127.0.0.1", Port: 20000, Filename: "binlog20000.000003", StartTime: "2024-01-01T03:10:00+08:00",
+			StopTime: "2024-01-01T03:30:00+08:00", GTIDSet: uuid + ":201-300", BinlogDir: binlogDir,
+			BackupStatus: IBStatusNew},
+		{Host: "127.0.0.1", Port: 20000, Filename: "binlog20000.000005", StartTime: "2024-01-01T03:40:00+08:00",
+			StopTime: "2024-01-01T03:50:00+08:00", GTIDSet: uuid + ":401-500", BinlogDir: binlogDir,
+			BackupStatus: IBStatusNew},
+	}
+	binlogInst := BinlogFileModel{}
+	assert.Nil(t, binlogInst.BatchSave(files, db))
+
+	loc := time.FixedZone("CST", 8*3600)
+	q := &BinlogRangeQuery{Port: 20000, StartTime: time.Date(2024, 1, 1, 3, 0, 0, 0, loc),
+		StopTime: time.Date(2024, 1, 1, 3, 15, 0, 0, loc)}
+	res, err := q.Query(db)
+	assert.Nil(t, err)
+	assert.True(t, res.Complete, res.Message)
+	assert.Len(t, res.Files, 2)
+	assert.Equal(t, "binlog20000.000002", res.Files[0].Filename)
+	assert.Equal(t, "1002", res.Files[0].BackupTaskid)
+	assert.Equal(t, "binlog20000.000003", res.Files[1].Filename)
+
+	// 跨过缺失的 000004
+	q.StopTime = time.Date(2024, 1, 1, 3, 45, 0, 0, loc)
+	res, err = q.Query(db)
+	assert.Nil(t, err)
+	assert.False(t, res.Complete)
+	assert.Len(t, res.Files, 3)
+
+	q = &BinlogRangeQuery{Port: 20000, GTIDSet: uuid + ":150-250"}
+	res, err = q.Query(db)
+	assert.Nil(t, err)
+	assert.True(t, res.Complete, res.Message)
+	assert.Len(t, res.Files, 2)
+
+	q.GTIDSet = uuid + ":250-350"
+	res, err = q.Query(db)
+	assert.Nil(t, err)
+	assert.False(t, res.Complete)
+	assert.Len(t, res.Files, 1)
+
+	q.StartTime = time.Now()
+	assert.NotNil(t, q.Validate())
+}
@@ -87,6 +87,12 @@ type BinlogFileModel struct {
 	BackupStatus     int    `json:"backup_status,omitempty" db:"backup_status"`
 	BackupStatusInfo string `json:"backup_status_info" db:"backup_status_info"`
 	BackupTaskid     string `json:"task_id,omitempty" db:"task_id"`
+	// BinlogDir binlog 所在目录
+	BinlogDir string `json:"binlog_dir" db:"binlog_dir"`
+	// PreviousGTIDs binlog 开头 PreviousGTIDsEvent 的 gtid set
+	PreviousGTIDs string `json:"previous_gtids" db:"previous_gtids"`
+	// GTIDSet binlog 包含的 gtid，等于下一个 binlog 的 PreviousGTIDs 减去当前的 PreviousGTIDs
+	GTIDSet string `json:"gtid_set" db:"gtid_set"`
 	*ModelAutoDatetime
 }
 
@@ -147,12 +153,12 @@ func (m *BinlogFileModel) Save(db *sqlx.DB, replace bool) error {
 		Columns(
 			"bk_biz_id", "cluster_id", "cluster_domain", "db_role", "host", "port", "filename",
 			"filesize", "start_time", "stop_time", "file_mtime", "backup_enable", "backup_status", "task_id",
-			"created_at", "updated_at",
+			"binlog_dir", "previous_gtids", "gtid_set", "created_at", "updated_at",
 		).
 		Values(
 			m.BkBizId, m.ClusterId, m.ClusterDomain, m.DBRole, m.Host, m.Port, m.Filename,
 			m.Filesize, m.StartTime, m.StopTime, m.FileMtime, m.BackupEnable, m.BackupStatus, m.BackupTaskid,
-			m.CreatedAt, m.UpdatedAt,
+			m.BinlogDir, m.PreviousGTIDs, m.GTIDSet, m.CreatedAt, m.UpdatedAt,
 		)
 	sqlStr, args, err := sqlBuilder.ToSql()
 	if err != nil {
@@ -180,13 +186,13 @@ func (m *BinlogFileModel) BatchSave(models []*BinlogFileModel, db *sqlx.DB) erro
 			Columns(
 				"bk_biz_id", "cluster_id", "cluster_domain", "db_role", "host", "port", "filename",
 				"filesize", "start_time", "stop_time", "file_mtime", "backup_enable", "backup_status", "task_id",
-				"created_at", "updated_at",
+				"binlog_dir", "previous_gtids", "gtid_set", "created_at", "updated_at",
 			)
 		o.autoTime()
 		sqlBuilder = sqlBuilder.Values(
 			o.BkBizId, o.ClusterId, o.ClusterDomain, o.DBRole, o.Host, o.Port, o.Filename,
 			o.Filesize, o.StartTime, o.StopTime, o.FileMtime, o.BackupEnable, o.BackupStatus, o.BackupTaskid,
-			o.CreatedAt, o.UpdatedAt,
+			o.BinlogDir, o.PreviousGTIDs, o.GTIDSet, o.CreatedAt, o.UpdatedAt,
 		)
 		sqlStr, args, err := sqlBuilder.ToSql()
 		if err != nil {
@@ -251,6 +257,9 @@ func (m *BinlogFileModel) Update(db *sqlx.DB) error {
 	if m.StopTime != "" {
 		sqlBuilder = sqlBuilder.Set("stop_time", m.StopTime)
 	}
+	if m.GTIDSet != "" {
+		sqlBuilder = sqlBuilder.Set("gtid_set", m.GTIDSet)
+	}
 	sqlBuilder = sqlBuilder.Where(
 		"host = ? and port = ? and filename = ? and cluster_id=?",
 		m.Host, m.Port, m.Filename, m.ClusterId,
@@ -376,6 +385,7 @@ func (m *BinlogFileModel) Query(db *sqlx.DB, pred interface{}, params ...interfa
 	sqlBuilder := sq.Select(
 		"bk_biz_id", "cluster_id", "cluster_domain", "db_role", "host", "port", "filename",
 		"filesize", "start_time", "stop_time", "file_mtime", "backup_enable", "backup_status", "task_id",
+		"binlog_dir", "previous_gtids", "gtid_set",
 	).
 		From(m.TableName()).Where(m.instanceWhere())
 	sqlBuilder = sqlBuilder.Where(pred, params...).OrderBy("filename asc")
@@ -0,0 +1,3 @@
+ALTER TABLE binlog_rotate ADD COLUMN binlog_dir varchar(256) NOT NULL DEFAULT '';
+ALTER TABLE binlog_rotate ADD COLUMN previous_gtids text NOT NULL DEFAULT '';
+ALTER TABLE binlog_rotate ADD COLUMN gtid_set text NOT NULL DEFAULT '';
@@ -125,9 +125,38 @@ func (c *RotateBinlogComp) Start() (err error) {
 			errRet = errors.Join(errRet, err)
 		}
 	}
+	for _, inst := range servers {
+		// 所有实例使用同一个 backup_client 配置，清理一次即可
+		if inst.backupClient != nil {
+			if err = purgeRemoteBackup(inst.backupClient); err != nil {
+				logger.Error("purge remote backup %+v", err)
+				errRet = errors.Join(errRet, err)
+			}
+			break
+		}
+	}
 	return errRet
 }
 
+// purgeRemoteBackup 清理 s3、本地目录上过期的 binlog，间隔 backup.PurgeInterval 执行一次
+func purgeRemoteBackup(backupClient backup.BackupClient) error {
+	remoteClient, ok := backupClient.(backup.RemoteClient)
+	if !ok {
+		return nil
+	}
+	timeIntvl := models.TimeInterval{TaskName: "purge_remote_backup", Tag: fmt.Sprintf("%T", remoteClient)}
+	if !timeIntvl.IntervalOut(models.DB.Conn, backup.PurgeInterval) {
+		return nil
+	}
+	if err := remoteClient.Purge(); err != nil {
+		return err
+	}
+	if err := timeIntvl.Update(models.DB.Conn); err != nil {
+		logger.Error(err.Error())
+	}
+	return nil
+}
+
 // RemoveConfig 删除某个 binlog 实例的 rotate 配置
 func (c *RotateBinlogComp) RemoveConfig(ports []string) (err error) {
 	if c.ConfigObj, err = InitConfig(c.Config); err != nil {
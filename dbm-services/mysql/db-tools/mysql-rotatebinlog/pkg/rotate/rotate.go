@@ -257,6 +257,11 @@ func (i *ServerObj) RegisterBinlog(lastFileBefore *models.BinlogFileModel) error
 			startTime = events[0].EventTime
 			stopTime = events[1].EventTime
 		}
+		// 最后一个 binlog 不登记，所以一定有下一个 binlog
+		previousGTIDs, gtidSet, err := i.getBinlogGTIDs(bp, fileObj.Filename, i.binlogFiles[j+1].Filename)
+		if err != nil {
+			logger.Warn("binlog %s get gtid set failed: %s", fileName, err.Error())
+		}
 		ff := &models.BinlogFileModel{
 			BkBizId:          i.Tags.BkBizId,
 			ClusterId:        i.Tags.ClusterId,
@@ -272,6 +277,9 @@ func (i *ServerObj) RegisterBinlog(lastFileBefore *models.BinlogFileModel) error
 			BackupStatusInfo: backupStatusInfo,
 			StartTime:        startTime,
 			StopTime:         stopTime,
+			BinlogDir:        i.binlogDir,
+			PreviousGTIDs:    previousGTIDs,
+			GTIDSet:          gtidSet,
 		}
 		filesModel = append(filesModel, ff)
 	}
@@ -285,6 +293,22 @@ func (i *ServerObj) RegisterBinlog(lastFileBefore *models.BinlogFileModel) error
 	return nil
 }
 
+// getBinlogGTIDs 返回 binlog 的 previous gtids，以及 binlog 包含的 gtid set
+func (i *ServerObj) getBinlogGTIDs(bp *binlog_parser.BinlogParse, fileName, nextFileName string) (
+	previousGTIDs string, gtidSet string, err error) {
+	if previousGTIDs, err = bp.GetPreviousGTIDs(filepath.Join(i.binlogDir, fileName)); err != nil {
+		return "", "", err
+	}
+	nextGTIDs, err := bp.GetPreviousGTIDs(filepath.Join(i.binlogDir, nextFileName))
+	if err != nil {
+		return previousGTIDs, "", err
+	}
+	if gtidSet, err = binlog_parser.GTIDSetMinus(nextGTIDs, previousGTIDs); err != nil {
+		return previousGTIDs, "", err
+	}
+	return previousGTIDs, gtidSet, nil
+}
+
 // Backup binlog 提交到备份系统
 // 下一轮运行时判断上一次以及之前的提交任务状态
 func (r *BinlogRotate) Backup(backupClient backup.BackupClient) error {
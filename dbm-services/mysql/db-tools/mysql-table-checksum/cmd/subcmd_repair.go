@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os/signal"
+	"syscall"
+
+	"dbm-services/mysql/db-tools/mysql-table-checksum/pkg/config"
+	"dbm-services/mysql/db-tools/mysql-table-checksum/pkg/repair"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var subCmdRepair = &cobra.Command{
+	Use:   "repair",
+	Short: "generate repair plan for inconsistent chunks",
+	Long:  "generate repair plan for inconsistent chunks, run on slave or repeater",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := config.InitConfig(viper.GetString("repair-config"))
+		if err != nil {
+			return err
+		}
+
+		initLogger(config.ChecksumConfig.Log, config.GeneralMode)
+
+		planner, err := repair.NewPlanner(
+			config.ChecksumConfig,
+			viper.GetString("repair-output-dir"),
+			viper.GetBool("repair-apply"),
+		)
+		if err != nil {
+			return err
+		}
+		defer planner.Close()
+
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+
+		summary, err := planner.Run(ctx)
+		if err != nil {
+			slog.Error("run repair", slog.String("error", err.Error()))
+			return err
+		}
+		fmt.Printf("chunks: %d, replaces: %d, deletes: %d, sql file: %s\n",
+			len(summary.Chunks), summary.Replaces, summary.Deletes, summary.SqlFile)
+		return nil
+	},
+}
+
+func init() {
+	subCmdRepair.PersistentFlags().StringP("config", "c", "", "config file")
+	_ = subCmdRepair.MarkPersistentFlagRequired("config")
+	_ = viper.BindPFlag("repair-config", subCmdRepair.PersistentFlags().Lookup("config"))
+
+	subCmdRepair.PersistentFlags().StringP("output-dir", "o", "", "repair plan dir, default report_path/repair")
+	_ = viper.BindPFlag("repair-output-dir", subCmdRepair.PersistentFlags().Lookup("output-dir"))
+
+	subCmdRepair.PersistentFlags().Bool("apply", false, "execute repair sql on slave with binlog disabled")
+	_ = viper.BindPFlag("repair-apply", subCmdRepair.PersistentFlags().Lookup("apply"))
+
+	rootCmd.AddCommand(subCmdRepair)
+}
@@ -47,8 +47,10 @@ func NewChecker(mode config.CheckMode) (*Checker, error) {
 		return nil, err
 	}
 
-	if err := checker.ptPrecheck(); err != nil {
-		return nil, err
+	if checker.Config.Engine == config.EnginePt {
+		if err := checker.ptPrecheck(); err != nil {
+			return nil, err
+		}
 	}
 
 	err := checker.prepareReplicateTable()
@@ -0,0 +1,447 @@
+package checker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"dbm-services/mysql/db-tools/mysql-table-checksum/pkg/sqlutil"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// nativeChunkRetries 单个分块失败重试次数, 一般是锁等待超时
+	nativeChunkRetries = 3
+	// nativeRateWeight 计算分块速率时历史速率的权重, 和 pt-table-checksum 一样
+	nativeRateWeight = 0.75
+)
+
+// nativeEngine go 实现的分块校验
+// 和 pt-table-checksum 一样在 master 上以 STATEMENT 格式执行 REPLACE INTO ... SELECT,
+// slave 回放时会用自己的数据重新计算 this_crc, this_cnt, 再由 UPDATE 带过去 master_crc, master_cnt
+type nativeEngine struct {
+	r        *Checker
+	opts     *nativeOptions
+	slaves   []*sqlx.DB
+	stderr   strings.Builder
+	exitCode int
+}
+
+// runNative 返回值和 runPtTableChecksum 保持一致
+func (r *Checker) runNative() (output *Output, err error, pterr error) {
+	opts, err := r.nativeOptions()
+	if err != nil {
+		slog.Error("native checksum options", slog.String("error", err.Error()))
+		return nil, err, nil
+	}
+
+	sigCtx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer cancel()
+	r.cancel = cancel
+
+	ctx := sigCtx
+	if opts.runTime > 0 {
+		var cancelRunTime context.CancelFunc
+		ctx, cancelRunTime = context.WithTimeout(sigCtx, opts.runTime)
+		defer cancelRunTime()
+	}
+
+	e := &nativeEngine{r: r, opts: opts}
+	defer e.closeSlaves()
+	if err := e.connectSlaves(); err != nil {
+		return nil, err, nil
+	}
+
+	// 分块锁等待超时就重试, 不要长时间阻塞业务
+	_, err = r.conn.ExecContext(context.Background(), `SET SESSION innodb_lock_wait_timeout = 1`)
+	if err != nil {
+		slog.Warn("set innodb_lock_wait_timeout", slog.String("error", err.Error()))
+	}
+
+	r.startTS = time.Now()
+	slog.Info("sleep 2s")
+	time.Sleep(2 * time.Second) // 故意休眠 2s, 让时间往前走一下, mysql 时间戳精度不够, 这里太快了会有问题
+
+	tables, err := r.nativeTables()
+	if err != nil {
+		return nil, err, nil
+	}
+	slog.Info("native checksum tables", slog.Int("count", len(tables)))
+
+	var summaries []ChecksumSummary
+	for _, t := range tables {
+		if ctx.Err() != nil {
+			slog.Info("native checksum stopped", slog.String("reason", ctx.Err().Error()))
+			break
+		}
+		cs, err := e.checksumTable(ctx, t)
+		if err != nil {
+			e.warn(fmt.Sprintf("Error checksumming table %s: %s", t, err.Error()))
+			e.exitCode |= 1
+		}
+		if cs != nil {
+			summaries = append(summaries, *cs)
+			slog.Info("native checksum table", slog.Any("summary", *cs))
+		}
+	}
+
+	if sigCtx.Err() != nil {
+		e.exitCode |= 4
+	}
+
+	output = &Output{
+		PtStderr:    e.stderr.String(),
+		Summaries:   summaries,
+		PtExitFlags: collectFlagsByCode(e.exitCode),
+	}
+	if output.PtExitFlags == nil {
+		output.PtExitFlags = make([]PtExitFlag, 0)
+	}
+
+	if e.exitCode&4 != 0 {
+		pterr = errors.New(output.String())
+		slog.Error("native checksum caught signal", slog.String("error", pterr.Error()))
+		_, _ = fmt.Fprint(os.Stderr, output.String())
+		return output, nil, pterr
+	}
+	return output, nil, nil
+}
+
+func (e *nativeEngine) warn(msg string) {
+	slog.Warn(msg)
+	e.stderr.WriteString(msg)
+	e.stderr.WriteString("\n")
+}
+
+// checksumTable 逐个分块校验一张表
+// 返回 nil summary 表示这张表被跳过或者在上次中断前已经完成
+func (e *nativeEngine) checksumTable(ctx context.Context, t *nativeTable) (*ChecksumSummary, error) {
+	if t.indexName == "" && float64(t.rows) > float64(e.opts.chunkSize)*e.opts.chunkSizeLimit {
+		e.warn(fmt.Sprintf(
+			"Skipping table %s because there is no good index and the table is oversized: %d rows",
+			t, t.rows,
+		))
+		e.exitCode |= 64
+		return nil, nil
+	}
+
+	chunkNo, lower, done, err := e.resumePoint(t)
+	if err != nil {
+		return nil, err
+	}
+	if done {
+		slog.Info("native checksum table already done", slog.String("table", t.String()))
+		return nil, nil
+	}
+
+	cs := &ChecksumSummary{Table: t.String()}
+	chunkSize := e.opts.chunkSize
+	rate := 0.0
+	started := time.Now()
+	defer func() {
+		cs.Ts = time.Now()
+		cs.Time = int(math.Round(time.Since(started).Seconds()))
+	}()
+
+	lastFailed := false
+	for {
+		if err := e.throttle(ctx); err != nil {
+			return cs, nil
+		}
+
+		var upper []string
+		if t.indexName != "" {
+			upper, err = e.nextBoundary(ctx, t, lower, chunkSize)
+			if err != nil {
+				if ctx.Err() != nil {
+					return cs, nil
+				}
+				cs.Errors++
+				return cs, err
+			}
+		}
+
+		cnt, elapsed, err := e.checksumChunk(ctx, t, chunkNo, lower, upper)
+		if err != nil {
+			if ctx.Err() != nil {
+				return cs, nil
+			}
+			e.warn(fmt.Sprintf("Skipping chunk %d of %s: %s", chunkNo, t, err.Error()))
+			cs.Errors++
+			cs.Skipped++
+			e.exitCode |= 32
+			lastFailed = true
+		} else {
+			cs.Rows += cnt
+			lastFailed = false
+		}
+		cs.Chunks++
+
+		// chunk-time 为 0 时不调整分块大小
+		if e.opts.chunkTime > 0 && elapsed > 0 && cnt > 0 {
+			current := float64(cnt) / elapsed.Seconds()
+			if rate == 0 {
+				rate = current
+			} else {
+				rate = rate*nativeRateWeight + current*(1-nativeRateWeight)
+			}
+			chunkSize = max(int(rate*e.opts.chunkTime), 1)
+			slog.Debug(
+				"native adjust chunk size",
+				slog.String("table", t.String()),
+				slog.Int("chunk size", chunkSize),
+			)
+		}
+
+		if upper == nil {
+			break
+		}
+		lower = upper
+		chunkNo++
+	}
+
+	if e.opts.replicateCheck {
+		cs.Diffs, err = e.slaveDiffs(ctx, t, chunkNo, !lastFailed)
+		if err != nil {
+			cs.Errors++
+			return cs, err
+		}
+		if cs.Diffs > 0 {
+			e.exitCode |= 16
+		}
+	}
+	return cs, nil
+}
+
+// resumePoint 从结果表找到上次中断的位置
+// 最后一个分块 upper_boundary 为 NULL 并且已经有 master_crc 说明这张表已经完成
+func (e *nativeEngine) resumePoint(t *nativeTable) (chunkNo int, lower []string, done bool, err error) {
+	r := e.r
+	if !e.opts.resume {
+		return 1, nil, false, e.cleanTable(t)
+	}
+
+	var last struct {
+		Chunk      int            `db:"chunk"`
+		ChunkIndex sql.NullString `db:"chunk_index"`
+		Lower      sql.NullString `db:"lower_boundary"`
+		Upper      sql.NullString `db:"upper_boundary"`
+		MasterCrc  sql.NullString `db:"master_crc"`
+	}
+	err = r.db.Get(
+		&last,
+		fmt.Sprintf(
+			`SELECT chunk, chunk_index, lower_boundary, upper_boundary, master_crc FROM %s.%s `+
+				`WHERE master_ip = ? AND master_port = ? AND db = ? AND tbl = ? ORDER BY chunk DESC LIMIT 1`,
+			r.resultDB, r.resultTbl,
+		),
+		r.Config.Ip, r.Config.Port, t.db, t.tbl,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 1, nil, false, nil
+	}
+	if err != nil {
+		slog.Error("native query resume point", slog.String("error", err.Error()), slog.String("table", t.String()))
+		return 0, nil, false, err
+	}
+
+	// 索引变了, 之前的边界不能用, 整表重新来
+	if last.ChunkIndex.String != t.indexName {
+		slog.Info("native chunk index changed, restart table", slog.String("table", t.String()))
+		return 1, nil, false, e.cleanTable(t)
+	}
+
+	if !last.MasterCrc.Valid {
+		if last.Lower.Valid {
+			lower = sqlutil.SplitBoundary(last.Lower.String)
+		}
+		slog.Info("native resume from unfinished chunk", slog.String("table", t.String()), slog.Int("chunk", last.Chunk))
+		return last.Chunk, lower, false, nil
+	}
+	if !last.Upper.Valid {
+		return 0, nil, true, nil
+	}
+	slog.Info("native resume from chunk", slog.String("table", t.String()), slog.Int("chunk", last.Chunk+1))
+	return last.Chunk + 1, sqlutil.SplitBoundary(last.Upper.String), false, nil
+}
+
+// cleanTable 清理这张表上次的校验结果
+func (e *nativeEngine) cleanTable(t *nativeTable) error {
+	r := e.r
+	_, err := r.conn.ExecContext(
+		context.Background(),
+		fmt.Sprintf(
+			`DELETE FROM %s.%s WHERE master_ip = ? AND master_port = ? AND db = ? AND tbl = ?`,
+			r.resultDB, r.resultTbl,
+		),
+		r.Config.Ip, r.Config.Port, t.db, t.tbl,
+	)
+	if err != nil {
+		slog.Error("native clean last result", slog.String("error", err.Error()), slog.String("table", t.String()))
+	}
+	return err
+}
+
+// nextBoundary 找到本分块的上边界
+// 返回 nil 表示剩下的数据都在这个分块里面
+func (e *nativeEngine) nextBoundary(
+	ctx context.Context, t *nativeTable, lower []string, chunkSize int,
+) ([]string, error) {
+	var selects []string
+	for _, c := range t.indexCols {
+		selects = append(selects, t.boundaryExpr(c))
+	}
+	where := "1=1"
+	var args []interface{}
+	if lower != nil {
+		where = t.boundaryCond(">")
+		args = sqlutil.RangeArgs(lower)
+	}
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s FORCE INDEX(%s) WHERE %s ORDER BY %s LIMIT %d, 2",
+		strings.Join(selects, ", "), t.quotedName(), sqlutil.QuoteIdent(t.indexName),
+		where, t.quotedIndexCols(), chunkSize-1,
+	)
+
+	var boundaries [][]string
+	var err error
+	for i := 0; i < nativeChunkRetries; i++ {
+		boundaries, err = e.queryBoundaries(ctx, query, args, len(t.indexCols))
+		if err == nil || ctx.Err() != nil {
+			break
+		}
+		slog.Warn("native query chunk boundary", slog.String("error", err.Error()), slog.Int("try", i+1))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 后面没有数据了, 这个分块就是最后一个, 不要留一个空分块
+	if len(boundaries) < 2 {
+		return nil, nil
+	}
+	return boundaries[0], nil
+}
+
+func (e *nativeEngine) queryBoundaries(
+	ctx context.Context, query string, args []interface{}, colCount int,
+) (res [][]string, err error) {
+	rows, err := e.r.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		values := make([]string, colCount)
+		dest := make([]interface{}, colCount)
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		res = append(res, values)
+	}
+	return res, rows.Err()
+}
+
+// checksumChunk 计算一个分块的 crc, 边界为 (lower, upper], nil 表示不限
+func (e *nativeEngine) checksumChunk(
+	ctx context.Context, t *nativeTable, chunkNo int, lower []string, upper []string,
+) (cnt int, elapsed time.Duration, err error) {
+	r := e.r
+
+	var conds []string
+	var args []interface{}
+	var lowerBoundary, upperBoundary, chunkIndex sql.NullString
+	if lower != nil {
+		conds = append(conds, t.boundaryCond(">"))
+		args = append(args, sqlutil.RangeArgs(lower)...)
+		lowerBoundary = sql.NullString{String: sqlutil.JoinBoundary(lower), Valid: true}
+	}
+	if upper != nil {
+		conds = append(conds, t.boundaryCond("<="))
+		args = append(args, sqlutil.RangeArgs(upper)...)
+		upperBoundary = sql.NullString{String: sqlutil.JoinBoundary(upper), Valid: true}
+	}
+	if len(conds) == 0 {
+		conds = append(conds, "1=1")
+	}
+	from := t.quotedName()
+	if t.indexName != "" {
+		from = fmt.Sprintf("%s FORCE INDEX(%s)", from, sqlutil.QuoteIdent(t.indexName))
+		chunkIndex = sql.NullString{String: t.indexName, Valid: true}
+	}
+
+	checksumSql := fmt.Sprintf(
+		"REPLACE INTO %s.%s "+
+			"(master_ip, master_port, db, tbl, chunk, chunk_index, lower_boundary, upper_boundary, this_cnt, this_crc) "+
+			"SELECT ?, ?, ?, ?, ?, ?, ?, ?, COUNT(*), %s FROM %s WHERE %s",
+		r.resultDB, r.resultTbl, t.crcExpr(), from, strings.Join(conds, " AND "),
+	)
+	checksumArgs := append(
+		[]interface{}{
+			r.Config.Ip, r.Config.Port, t.db, t.tbl, chunkNo, chunkIndex, lowerBoundary, upperBoundary,
+		}, args...,
+	)
+
+	for i := 0; i < nativeChunkRetries; i++ {
+		start := time.Now()
+		_, err = r.conn.ExecContext(ctx, checksumSql, checksumArgs...)
+		elapsed = time.Since(start)
+		if err == nil || ctx.Err() != nil {
+			break
+		}
+		slog.Warn(
+			"native checksum chunk",
+			slog.String("error", err.Error()),
+			slog.String("table", t.String()),
+			slog.Int("chunk", chunkNo),
+			slog.Int("try", i+1),
+		)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var crc string
+	err = r.conn.QueryRowContext(
+		ctx,
+		fmt.Sprintf(
+			`SELECT this_crc, this_cnt FROM %s.%s `+
+				`WHERE master_ip = ? AND master_port = ? AND db = ? AND tbl = ? AND chunk = ?`,
+			r.resultDB, r.resultTbl,
+		),
+		r.Config.Ip, r.Config.Port, t.db, t.tbl, chunkNo,
+	).Scan(&crc, &cnt)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	_, err = r.conn.ExecContext(
+		ctx,
+		fmt.Sprintf(
+			`UPDATE %s.%s SET chunk_time = ?, master_crc = ?, master_cnt = ? `+
+				`WHERE master_ip = ? AND master_port = ? AND db = ? AND tbl = ? AND chunk = ?`,
+			r.resultDB, r.resultTbl,
+		),
+		elapsed.Seconds(), crc, cnt,
+		r.Config.Ip, r.Config.Port, t.db, t.tbl, chunkNo,
+	)
+	if err != nil {
+		return 0, 0, err
+	}
+	return cnt, elapsed, nil
+}
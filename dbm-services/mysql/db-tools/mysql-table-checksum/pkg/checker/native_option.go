@@ -0,0 +1,165 @@
+package checker
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultChunkSize pt-table-checksum 默认初始分块行数
+	defaultChunkSize = 1000
+	// defaultChunkTime 每个分块的目标耗时
+	defaultChunkTime = 0.5
+	// defaultChunkSizeLimit 没有可用索引时，表行数超过 chunk-size * chunk-size-limit 跳过
+	defaultChunkSizeLimit = 2.0
+	// defaultMaxLoad pt-table-checksum 默认的 --max-load
+	defaultMaxLoad = "Threads_running=25"
+	// defaultMaxLag 从库延迟超过多少暂停校验
+	defaultMaxLag = time.Second
+)
+
+// nativeOptions native 引擎参数
+// 沿用 pt-table-checksum 的参数名，从 pt_checksum.args 和 switches 读取，这样 strategy 对两种引擎都生效
+type nativeOptions struct {
+	chunkSize      int
+	chunkTime      float64
+	chunkSizeLimit float64
+	maxLoadVar     string
+	maxLoadValue   int
+	maxLag         time.Duration
+	runTime        time.Duration
+	resume         bool
+	replicateCheck bool
+}
+
+func (r *Checker) ptArg(name string) (interface{}, bool) {
+	idx := slices.IndexFunc(
+		r.Config.PtChecksum.Args, func(kvArg map[string]interface{}) bool {
+			return kvArg["name"] == name
+		},
+	)
+	if idx == -1 {
+		return nil, false
+	}
+	return r.Config.PtChecksum.Args[idx]["value"], true
+}
+
+func (r *Checker) ptSwitch(name string) bool {
+	return slices.Contains(r.Config.PtChecksum.Switches, name)
+}
+
+func (r *Checker) nativeOptions() (opts *nativeOptions, err error) {
+	opts = &nativeOptions{
+		chunkSize:      defaultChunkSize,
+		chunkTime:      defaultChunkTime,
+		chunkSizeLimit: defaultChunkSizeLimit,
+		maxLag:         defaultMaxLag,
+		resume:         r.ptSwitch("resume"),
+		replicateCheck: r.ptSwitch("replicate-check"),
+	}
+	if v, ok := r.ptArg("chunk-size"); ok {
+		if opts.chunkSize, err = parseSize(v); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := r.ptArg("chunk-time"); ok {
+		if opts.chunkTime, err = parseSeconds(v); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := r.ptArg("chunk-size-limit"); ok {
+		if opts.chunkSizeLimit, err = strconv.ParseFloat(fmt.Sprintf("%v", v), 64); err != nil {
+			return nil, fmt.Errorf("invalid chunk-size-limit %v", v)
+		}
+	}
+	maxLoad := defaultMaxLoad
+	if v, ok := r.ptArg("max-load"); ok {
+		maxLoad = fmt.Sprintf("%v", v)
+	}
+	if opts.maxLoadVar, opts.maxLoadValue, err = parseMaxLoad(maxLoad); err != nil {
+		return nil, err
+	}
+	if v, ok := r.ptArg("max-lag"); ok {
+		sec, err := parseSeconds(v)
+		if err != nil {
+			return nil, err
+		}
+		opts.maxLag = time.Duration(sec * float64(time.Second))
+	}
+	if v, ok := r.ptArg("run-time"); ok {
+		sec, err := parseSeconds(v)
+		if err != nil {
+			return nil, err
+		}
+		opts.runTime = time.Duration(sec * float64(time.Second))
+	}
+	if opts.chunkSize < 1 {
+		return nil, fmt.Errorf("chunk-size must be greater than 0")
+	}
+	slog.Info("native checksum options", slog.String("options", fmt.Sprintf("%+v", *opts)))
+	return opts, nil
+}
+
+// parseSize 解析 1000, 10k, 1M 这样的行数
+func parseSize(v interface{}) (int, error) {
+	s := strings.TrimSpace(fmt.Sprintf("%v", v))
+	multi := 1
+	switch {
+	case strings.HasSuffix(s, "k"), strings.HasSuffix(s, "K"):
+		multi = 1000
+	case strings.HasSuffix(s, "M"):
+		multi = 1000 * 1000
+	case strings.HasSuffix(s, "G"):
+		multi = 1000 * 1000 * 1000
+	}
+	if multi > 1 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %v", v)
+	}
+	return n * multi, nil
+}
+
+var reSeconds = regexp.MustCompile(`^(\d+(?:\.\d+)?)([smhd]?)$`)
+
+// parseSeconds 解析秒数，支持 time.Duration, 数字, 1h 这样的 pt 时间格式以及 go duration 格式
+func parseSeconds(v interface{}) (float64, error) {
+	switch value := v.(type) {
+	case time.Duration:
+		return value.Seconds(), nil
+	case int:
+		return float64(value), nil
+	case float64:
+		return value, nil
+	}
+	s := strings.TrimSpace(fmt.Sprintf("%v", v))
+	if m := reSeconds.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.ParseFloat(m[1], 64)
+		unit := map[string]float64{"": 1, "s": 1, "m": 60, "h": 3600, "d": 86400}[m[2]]
+		return n * unit, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return d.Seconds(), nil
+	}
+	return 0, fmt.Errorf("invalid time value %v", v)
+}
+
+// parseMaxLoad 解析 Threads_running=25 或 Threads_running:25
+func parseMaxLoad(s string) (string, int, error) {
+	kv := strings.FieldsFunc(s, func(c rune) bool { return c == '=' || c == ':' })
+	if len(kv) != 2 {
+		return "", 0, fmt.Errorf("invalid max-load %s, format should be Threads_running=25", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(kv[1]))
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid max-load %s, format should be Threads_running=25", s)
+	}
+	return strings.TrimSpace(kv[0]), n, nil
+}
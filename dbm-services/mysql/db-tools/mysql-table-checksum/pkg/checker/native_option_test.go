@@ -0,0 +1,78 @@
+package checker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	cases := []struct {
+		v       interface{}
+		want    int
+		wantErr bool
+	}{
+		{1000, 1000, false},
+		{"1000", 1000, false},
+		{"10k", 10000, false},
+		{"10K", 10000, false},
+		{" 2M ", 2000000, false},
+		{"1G", 1000000000, false},
+		{"abc", 0, true},
+		{"1.5k", 0, true},
+		{"", 0, true},
+	}
+	for _, c := range cases {
+		got, err := parseSize(c.v)
+		if (err != nil) != c.wantErr || got != c.want {
+			t.Errorf("parseSize(%v) = %d, %v, want %d, err %v", c.v, got, err, c.want, c.wantErr)
+		}
+	}
+}
+
+func TestParseSeconds(t *testing.T) {
+	cases := []struct {
+		v       interface{}
+		want    float64
+		wantErr bool
+	}{
+		{2 * time.Minute, 120, false},
+		{3, 3, false},
+		{0.5, 0.5, false},
+		{"10", 10, false},
+		{"1.5", 1.5, false},
+		{"30s", 30, false},
+		{"2m", 120, false},
+		{"1h", 3600, false},
+		{"1d", 86400, false},
+		{"1h30m", 5400, false},
+		{"500ms", 0.5, false},
+		{"abc", 0, true},
+		{"1w", 0, true},
+	}
+	for _, c := range cases {
+		got, err := parseSeconds(c.v)
+		if (err != nil) != c.wantErr || got != c.want {
+			t.Errorf("parseSeconds(%v) = %v, %v, want %v, err %v", c.v, got, err, c.want, c.wantErr)
+		}
+	}
+}
+
+func TestParseMaxLoad(t *testing.T) {
+	cases := []struct {
+		s       string
+		name    string
+		value   int
+		wantErr bool
+	}{
+		{"Threads_running=25", "Threads_running", 25, false},
+		{"Threads_running:50", "Threads_running", 50, false},
+		{"Threads_running", "", 0, true},
+		{"Threads_running=abc", "", 0, true},
+	}
+	for _, c := range cases {
+		name, value, err := parseMaxLoad(c.s)
+		if (err != nil) != c.wantErr || name != c.name || value != c.value {
+			t.Errorf("parseMaxLoad(%s) = %s, %d, %v", c.s, name, value, err)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package checker
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+
+	"dbm-services/mysql/db-tools/mysql-table-checksum/pkg/sqlutil"
+)
+
+// nativeTable native 引擎待校验的表
+type nativeTable struct {
+	db        string
+	tbl       string
+	rows      int64 // information_schema 中的估算行数
+	indexName string
+	indexCols []string
+	columns   []nativeColumn
+}
+
+type nativeColumn struct {
+	name     string
+	dataType string
+	nullable bool
+}
+
+// 和 pt-table-checksum 一样, 系统库和一些日志表不校验
+var nativeIgnoreDatabases = []string{"information_schema", "performance_schema", "sys"}
+var nativeIgnoreMysqlTables = []string{
+	"general_log", "slow_log", "innodb_index_stats", "innodb_table_stats",
+	"slave_master_info", "slave_relay_log_info", "slave_worker_info",
+}
+
+func (t *nativeTable) String() string {
+	return fmt.Sprintf("%s.%s", t.db, t.tbl)
+}
+
+// nativeTables 按 Filter 过滤出需要校验的表
+func (r *Checker) nativeTables() (tables []*nativeTable, err error) {
+	var rows []struct {
+		Db        string `db:"TABLE_SCHEMA"`
+		Tbl       string `db:"TABLE_NAME"`
+		TableRows *int64 `db:"TABLE_ROWS"`
+	}
+	err = r.db.Select(
+		&rows,
+		`SELECT TABLE_SCHEMA, TABLE_NAME, TABLE_ROWS FROM INFORMATION_SCHEMA.TABLES `+
+			`WHERE TABLE_TYPE = 'BASE TABLE' ORDER BY TABLE_SCHEMA, TABLE_NAME`,
+	)
+	if err != nil {
+		slog.Error("native query tables", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	match, err := r.nativeFilter()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		if !match(row.Db, row.Tbl) {
+			continue
+		}
+		t := &nativeTable{db: row.Db, tbl: row.Tbl}
+		if row.TableRows != nil {
+			t.rows = *row.TableRows
+		}
+		if err := r.loadNativeTableMeta(t); err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
+
+// nativeFilter 实现 pt-table-checksum 的 --databases --tables --ignore-xxx 以及对应的 regex 过滤
+func (r *Checker) nativeFilter() (func(db, tbl string) bool, error) {
+	f := r.Config.Filter
+	compile := func(expr string) (*regexp.Regexp, error) {
+		if expr == "" {
+			return nil, nil
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			slog.Error("native compile filter regex", slog.String("error", err.Error()))
+		}
+		return re, err
+	}
+	dbRe, err := compile(f.DatabasesRegex)
+	if err != nil {
+		return nil, err
+	}
+	tblRe, err := compile(f.TablesRegex)
+	if err != nil {
+		return nil, err
+	}
+	ignoreDbRe, err := compile(f.IgnoreDatabasesRegex)
+	if err != nil {
+		return nil, err
+	}
+	ignoreTblRe, err := compile(f.IgnoreTablesRegex)
+	if err != nil {
+		return nil, err
+	}
+
+	// --tables 和 --ignore-tables 可以是 tbl 或 db.tbl
+	inTables := func(list []string, db, tbl string) bool {
+		return slices.Contains(list, tbl) || slices.Contains(list, fmt.Sprintf("%s.%s", db, tbl))
+	}
+
+	return func(db, tbl string) bool {
+		if slices.Contains(nativeIgnoreDatabases, db) {
+			return false
+		}
+		if db == "mysql" && slices.Contains(nativeIgnoreMysqlTables, tbl) {
+			return false
+		}
+		if db == r.resultDB && (tbl == r.resultTbl || tbl == r.resultHistoryTable || tbl == "dsns") {
+			return false
+		}
+		if len(f.Databases) > 0 && !slices.Contains(f.Databases, db) {
+			return false
+		}
+		if len(f.Tables) > 0 && !inTables(f.Tables, db, tbl) {
+			return false
+		}
+		if slices.Contains(f.IgnoreDatabases, db) || inTables(f.IgnoreTables, db, tbl) {
+			return false
+		}
+		if dbRe != nil && !dbRe.MatchString(db) {
+			return false
+		}
+		if tblRe != nil && !tblRe.MatchString(tbl) {
+			return false
+		}
+		if ignoreDbRe != nil && ignoreDbRe.MatchString(db) {
+			return false
+		}
+		if ignoreTblRe != nil && ignoreTblRe.MatchString(tbl) {
+			return false
+		}
+		return true
+	}, nil
+}
+
+// loadNativeTableMeta 读取列和分块用的索引
+// 分块索引优先用主键, 其次是列都 NOT NULL 的唯一索引, 都没有就整表一个分块
+func (r *Checker) loadNativeTableMeta(t *nativeTable) error {
+	var columns []struct {
+		Name       string `db:"COLUMN_NAME"`
+		DataType   string `db:"DATA_TYPE"`
+		IsNullable string `db:"IS_NULLABLE"`
+	}
+	err := r.db.Select(
+		&columns,
+		`SELECT COLUMN_NAME, DATA_TYPE, IS_NULLABLE FROM INFORMATION_SCHEMA.COLUMNS `+
+			`WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION`,
+		t.db, t.tbl,
+	)
+	if err != nil {
+		slog.Error("native query columns", slog.String("error", err.Error()), slog.String("table", t.String()))
+		return err
+	}
+	for _, c := range columns {
+		t.columns = append(t.columns, nativeColumn{
+			name:     c.Name,
+			dataType: strings.ToLower(c.DataType),
+			nullable: c.IsNullable == "YES",
+		})
+	}
+
+	var indexes []struct {
+		IndexName string  `db:"INDEX_NAME"`
+		Column    string  `db:"COLUMN_NAME"`
+		Nullable  string  `db:"NULLABLE"`
+		SubPart   *int64  `db:"SUB_PART"`
+		NonUnique int     `db:"NON_UNIQUE"`
+		Seq       int     `db:"SEQ_IN_INDEX"`
+		IndexType *string `db:"INDEX_TYPE"`
+	}
+	err = r.db.Select(
+		&indexes,
+		`SELECT INDEX_NAME, COLUMN_NAME, NULLABLE, SUB_PART, NON_UNIQUE, SEQ_IN_INDEX, INDEX_TYPE `+
+			`FROM INFORMATION_SCHEMA.STATISTICS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? `+
+			`ORDER BY INDEX_NAME = 'PRIMARY' DESC, INDEX_NAME, SEQ_IN_INDEX`,
+		t.db, t.tbl,
+	)
+	if err != nil {
+		slog.Error("native query indexes", slog.String("error", err.Error()), slog.String("table", t.String()))
+		return err
+	}
+
+	candidates := make(map[string][]string)
+	invalid := make(map[string]bool)
+	var order []string
+	for _, idx := range indexes {
+		if _, ok := candidates[idx.IndexName]; !ok {
+			order = append(order, idx.IndexName)
+		}
+		candidates[idx.IndexName] = append(candidates[idx.IndexName], idx.Column)
+		if idx.NonUnique != 0 || idx.Nullable == "YES" || idx.SubPart != nil ||
+			idx.Column == "" || (idx.IndexType != nil && *idx.IndexType != "BTREE") {
+			invalid[idx.IndexName] = true
+		}
+	}
+	for _, name := range order {
+		if !invalid[name] {
+			t.indexName = name
+			t.indexCols = candidates[name]
+			break
+		}
+	}
+	return nil
+}
+
+// crcExpr 拼接每行的 crc32 表达式, 和 pt-table-checksum 的做法一样把 NULL 标记也拼进去
+func (t *nativeTable) crcExpr() string {
+	var cols, nulls []string
+	for _, c := range t.columns {
+		col := sqlutil.QuoteIdent(c.name)
+		switch c.dataType {
+		case "timestamp":
+			col = fmt.Sprintf("UNIX_TIMESTAMP(%s)", col)
+		case "bit":
+			col = fmt.Sprintf("%s+0", col)
+		case "float", "double":
+			col = fmt.Sprintf("ROUND(%s, 10)", col)
+		}
+		cols = append(cols, col)
+		if c.nullable {
+			nulls = append(nulls, fmt.Sprintf("ISNULL(%s)", sqlutil.QuoteIdent(c.name)))
+		}
+	}
+	if len(nulls) > 0 {
+		cols = append(cols, fmt.Sprintf("CONCAT(%s)", strings.Join(nulls, ", ")))
+	}
+	return fmt.Sprintf(
+		"COALESCE(LOWER(CONV(BIT_XOR(CAST(CRC32(CONCAT_WS('#', %s)) AS UNSIGNED)), 10, 16)), 0)",
+		strings.Join(cols, ", "),
+	)
+}
+
+// boundaryExpr 读取边界值的表达式
+// 连接开了 parseTime, 时间类型转成字符串读出来, 否则拼回 sql 时格式不对
+func (t *nativeTable) boundaryExpr(name string) string {
+	for _, c := range t.columns {
+		if c.name != name {
+			continue
+		}
+		switch c.dataType {
+		case "date", "datetime", "timestamp", "time", "year":
+			return fmt.Sprintf("CAST(%s AS CHAR)", sqlutil.QuoteIdent(name))
+		}
+	}
+	return sqlutil.QuoteIdent(name)
+}
+
+func (t *nativeTable) quotedName() string {
+	return sqlutil.QuoteTable(t.db, t.tbl)
+}
+
+func (t *nativeTable) quotedIndexCols() string {
+	var cols []string
+	for _, c := range t.indexCols {
+		cols = append(cols, sqlutil.QuoteIdent(c))
+	}
+	return strings.Join(cols, ", ")
+}
+
+// boundaryCond 边界条件, op 只能是 > 或 <=
+func (t *nativeTable) boundaryCond(op string) string {
+	return sqlutil.RangeCond(t.indexCols, op)
+}
@@ -0,0 +1,49 @@
+package checker
+
+import (
+	"testing"
+)
+
+func TestBoundaryCond(t *testing.T) {
+	cases := []struct {
+		cols []string
+		op   string
+		want string
+	}{
+		{[]string{"id"}, ">", "((`id` > ?))"},
+		{[]string{"id"}, "<=", "((`id` <= ?))"},
+		{[]string{"a", "b"}, ">", "((`a` > ?) OR (`a` = ? AND `b` > ?))"},
+		{[]string{"a", "b", "c"}, "<=", "((`a` < ?) OR (`a` = ? AND `b` < ?) OR (`a` = ? AND `b` = ? AND `c` <= ?))"},
+		{[]string{"a`b"}, ">", "((`a``b` > ?))"},
+	}
+	for _, c := range cases {
+		tbl := &nativeTable{indexCols: c.cols}
+		if got := tbl.boundaryCond(c.op); got != c.want {
+			t.Errorf("boundaryCond(%v, %s) = %s, want %s", c.cols, c.op, got, c.want)
+		}
+	}
+
+}
+
+func TestCrcExpr(t *testing.T) {
+	tbl := &nativeTable{
+		columns: []nativeColumn{
+			{name: "id", dataType: "int"},
+			{name: "ts", dataType: "timestamp", nullable: true},
+			{name: "flag", dataType: "bit"},
+			{name: "f", dataType: "double", nullable: true},
+		},
+	}
+	want := "COALESCE(LOWER(CONV(BIT_XOR(CAST(CRC32(CONCAT_WS('#', " +
+		"`id`, UNIX_TIMESTAMP(`ts`), `flag`+0, ROUND(`f`, 10), CONCAT(ISNULL(`ts`), ISNULL(`f`))" +
+		")) AS UNSIGNED)), 10, 16)), 0)"
+	if got := tbl.crcExpr(); got != want {
+		t.Errorf("crcExpr = %s, want %s", got, want)
+	}
+
+	tbl = &nativeTable{columns: []nativeColumn{{name: "id", dataType: "int"}}}
+	want = "COALESCE(LOWER(CONV(BIT_XOR(CAST(CRC32(CONCAT_WS('#', `id`)) AS UNSIGNED)), 10, 16)), 0)"
+	if got := tbl.crcExpr(); got != want {
+		t.Errorf("crcExpr without nullable column = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,237 @@
+package checker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// nativeCheckInterval 负载, 延迟以及 slave 结果的检查间隔
+const nativeCheckInterval = time.Second
+
+// nativeSlaveWaitTimeout 等待 slave 回放最后一个分块的最长时间
+const nativeSlaveWaitTimeout = 10 * time.Minute
+
+// connectSlaves 只有单据校验会配置 slaves, 例行校验不检查 slave
+func (e *nativeEngine) connectSlaves() error {
+	for _, slave := range e.r.Config.Slaves {
+		db, err := sqlx.Connect(
+			"mysql",
+			fmt.Sprintf(
+				"%s:%s@tcp(%s:%d)/",
+				slave.User,
+				slave.Password,
+				slave.Ip,
+				slave.Port,
+			),
+		)
+		if err != nil {
+			slog.Error("native connect slave", slog.String("error", err.Error()))
+			return err
+		}
+		db.SetMaxOpenConns(1)
+		e.slaves = append(e.slaves, db)
+	}
+	return nil
+}
+
+func (e *nativeEngine) closeSlaves() {
+	for _, db := range e.slaves {
+		_ = db.Close()
+	}
+}
+
+// throttle 负载或者 slave 延迟超过阈值时等待, ctx 结束时返回错误
+func (e *nativeEngine) throttle(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		reason, err := e.overloaded(ctx)
+		if err != nil {
+			// 拿不到状态不阻塞校验, 和 pt-table-checksum 一致
+			slog.Warn("native check load", slog.String("error", err.Error()))
+			return nil
+		}
+		if reason == "" {
+			return nil
+		}
+
+		slog.Info("native checksum paused", slog.String("reason", reason))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(nativeCheckInterval):
+		}
+	}
+}
+
+func (e *nativeEngine) overloaded(ctx context.Context) (string, error) {
+	var name, value string
+	err := e.r.conn.QueryRowContext(
+		ctx, `SHOW GLOBAL STATUS LIKE ?`, e.opts.maxLoadVar,
+	).Scan(&name, &value)
+	if err != nil {
+		return "", err
+	}
+	load, err := strconv.Atoi(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid status %s=%s", name, value)
+	}
+	if load > e.opts.maxLoadValue {
+		return fmt.Sprintf("%s=%d exceeds %d", name, load, e.opts.maxLoadValue), nil
+	}
+
+	for i, slave := range e.slaves {
+		lag, err := slaveLag(ctx, slave)
+		if err != nil {
+			return "", err
+		}
+		if lag == nil {
+			return fmt.Sprintf("replication on slave %d is stopped", i), nil
+		}
+		if *lag > e.opts.maxLag {
+			return fmt.Sprintf("slave %d lag %s exceeds %s", i, *lag, e.opts.maxLag), nil
+		}
+	}
+	return "", nil
+}
+
+// slaveLag 返回 nil 表示复制没有运行
+func slaveLag(ctx context.Context, db *sqlx.DB) (*time.Duration, error) {
+	rows, err := db.QueryxContext(ctx, `SHOW REPLICA STATUS`)
+	if err != nil {
+		// 低版本没有 SHOW REPLICA STATUS
+		rows, err = db.QueryxContext(ctx, `SHOW SLAVE STATUS`)
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	status := make(map[string]interface{})
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("slave status is empty")
+	}
+	if err := rows.MapScan(status); err != nil {
+		return nil, err
+	}
+
+	for _, key := range []string{"Seconds_Behind_Source", "Seconds_Behind_Master"} {
+		v, ok := status[key]
+		if !ok {
+			continue
+		}
+		if v == nil {
+			return nil, nil
+		}
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+		seconds, err := strconv.Atoi(fmt.Sprintf("%v", v))
+		if err != nil {
+			return nil, err
+		}
+		lag := time.Duration(seconds) * time.Second
+		return &lag, nil
+	}
+	return nil, errors.New("seconds behind master not found in slave status")
+}
+
+// slaveDiffs 等 slave 回放完这张表最后一个分块后统计不一致的分块数
+// 多个 slave 的同一分块只算一次
+// 最后一个分块校验失败时 master_crc 不会写入, 不等待直接统计已有的分块
+func (e *nativeEngine) slaveDiffs(ctx context.Context, t *nativeTable, lastChunk int, wait bool) (int, error) {
+	r := e.r
+	if !wait {
+		e.warn(fmt.Sprintf("Last chunk %d of %s failed, not waiting for slaves", lastChunk, t))
+	}
+
+	diffChunks := make(map[int]struct{})
+	for i, slave := range e.slaves {
+		if wait {
+			err := e.waitSlaveChunk(ctx, slave, i, t, lastChunk)
+			if err != nil && ctx.Err() != nil {
+				return 0, ctx.Err()
+			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				e.warn(fmt.Sprintf(
+					"Timeout waiting for slave %d to replicate chunk %d of %s, diffs may be incomplete",
+					i, lastChunk, t,
+				))
+			} else if err != nil {
+				return 0, err
+			}
+		}
+
+		var chunks []int
+		err := slave.SelectContext(
+			ctx,
+			&chunks,
+			fmt.Sprintf(
+				`SELECT chunk FROM %s.%s WHERE master_ip = ? AND master_port = ? AND db = ? AND tbl = ? `+
+					`AND (this_crc <> master_crc OR this_cnt <> master_cnt)`,
+				r.resultDB, r.resultTbl,
+			),
+			r.Config.Ip, r.Config.Port, t.db, t.tbl,
+		)
+		if err != nil {
+			slog.Error("native query slave diff", slog.String("error", err.Error()), slog.Int("slave", i))
+			return 0, err
+		}
+		for _, c := range chunks {
+			diffChunks[c] = struct{}{}
+		}
+	}
+	return len(diffChunks), nil
+}
+
+// waitSlaveChunk 等 slave 上出现分块的 master_crc, 最多等 nativeSlaveWaitTimeout
+func (e *nativeEngine) waitSlaveChunk(ctx context.Context, slave *sqlx.DB, i int, t *nativeTable, chunk int) error {
+	r := e.r
+	waitCtx, cancel := context.WithTimeout(ctx, nativeSlaveWaitTimeout)
+	defer cancel()
+
+	for {
+		var masterCrc sql.NullString
+		err := slave.GetContext(
+			waitCtx,
+			&masterCrc,
+			fmt.Sprintf(
+				`SELECT master_crc FROM %s.%s `+
+					`WHERE master_ip = ? AND master_port = ? AND db = ? AND tbl = ? AND chunk = ?`,
+				r.resultDB, r.resultTbl,
+			),
+			r.Config.Ip, r.Config.Port, t.db, t.tbl, chunk,
+		)
+		if err != nil && waitCtx.Err() != nil {
+			return waitCtx.Err()
+		}
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			slog.Error("native wait slave checksum", slog.String("error", err.Error()), slog.Int("slave", i))
+			return err
+		}
+		if err == nil && masterCrc.Valid {
+			return nil
+		}
+
+		slog.Info("native wait slave checksum", slog.String("table", t.String()), slog.Int("slave", i))
+		select {
+		case <-waitCtx.Done():
+			return waitCtx.Err()
+		case <-time.After(nativeCheckInterval):
+		}
+	}
+}
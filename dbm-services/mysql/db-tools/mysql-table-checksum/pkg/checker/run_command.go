@@ -168,6 +168,13 @@ func (r *Checker) writeFakeResult(fakeDB string, fakeTbl string) error {
 }
 
 func (r *Checker) run() (output *Output, err error, pterr error) {
+	if r.Config.Engine == config.EnginePt {
+		return r.runPtTableChecksum()
+	}
+	return r.runNative()
+}
+
+func (r *Checker) runPtTableChecksum() (output *Output, err error, pterr error) {
 	var stdout, stderr bytes.Buffer
 
 	ctx, cancel := context.WithCancel(context.Background())
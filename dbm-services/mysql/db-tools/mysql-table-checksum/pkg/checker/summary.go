@@ -70,7 +70,11 @@ func summary(stdout string) (summaries []ChecksumSummary, err error) {
 }
 
 func collectFlags(exitErr *exec.ExitError) (ptFlags []PtExitFlag) {
-	exitCode := exitErr.ExitCode()
+	return collectFlagsByCode(exitErr.ExitCode())
+}
+
+// collectFlagsByCode native 引擎没有进程退出码, 直接按位拼出 flag
+func collectFlagsByCode(exitCode int) (ptFlags []PtExitFlag) {
 	for k, v := range PtExitFlagMap {
 		if exitCode&k != 0 {
 			ptFlags = append(ptFlags, v)
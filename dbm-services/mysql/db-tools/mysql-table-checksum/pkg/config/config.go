@@ -2,6 +2,7 @@
 package config
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
 	"path"
@@ -50,6 +51,16 @@ const (
 	RoleSlave InnerRoleEnum = "slave"
 )
 
+// EngineEnum 校验引擎
+type EngineEnum string
+
+const (
+	// EngineNative go 实现的分块 crc32 校验
+	EngineNative EngineEnum = "native"
+	// EnginePt 调用 pt-table-checksum, 默认引擎
+	EnginePt EngineEnum = "pt"
+)
+
 // Config 配置结构
 type Config struct {
 	BkBizId int `yaml:"bk_biz_id"`
@@ -63,9 +74,11 @@ type Config struct {
 	Slaves     []host        `yaml:"slaves"`
 	Filter     filter        `yaml:"filter"`
 	PtChecksum ptChecksum    `yaml:"pt_checksum"`
-	Log        *LogConfig    `yaml:"log"`
-	Schedule   string        `yaml:"schedule"`
-	ApiUrl     string        `yaml:"api_url"`
+	// Engine 为空时使用 pt, native 引擎也从 pt_checksum.args/switches 读取同名参数
+	Engine   EngineEnum `yaml:"engine"`
+	Log      *LogConfig `yaml:"log"`
+	Schedule string     `yaml:"schedule"`
+	ApiUrl   string     `yaml:"api_url"`
 }
 
 // InitConfig 初始化配置
@@ -91,6 +104,13 @@ func InitConfig(configPath string) error {
 		slog.Error("init config", slog.String("error", err.Error()))
 		return err
 	}
+	if ChecksumConfig.Engine == "" {
+		ChecksumConfig.Engine = EnginePt
+	} else if ChecksumConfig.Engine != EngineNative && ChecksumConfig.Engine != EnginePt {
+		err = fmt.Errorf("unknown engine %s, should be %s or %s", ChecksumConfig.Engine, EngineNative, EnginePt)
+		slog.Error("init config", slog.String("error", err.Error()))
+		return err
+	}
 
 	return nil
 }
@@ -0,0 +1,283 @@
+package repair
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"dbm-services/mysql/db-tools/mysql-table-checksum/pkg/sqlutil"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// compareTimes 对比次数, 只保留每次结果都一样的差异, 过滤掉对比期间业务写入造成的假差异
+const compareTimes = 2
+
+type tableMeta struct {
+	columns   []string
+	dataTypes []string
+	keyCols   []string
+	rangeCols []string
+}
+
+type rowDiff struct {
+	deleted bool
+	key     []string
+	row     []sql.NullString
+}
+
+func (d *rowDiff) equal(o *rowDiff) bool {
+	return d.deleted == o.deleted && slices.Equal(d.row, o.row)
+}
+
+// planChunk 对比一个分块并生成修复语句
+func (p *Planner) planChunk(ctx context.Context, c *ChunkPlan) error {
+	meta, skipped, err := p.tableMeta(ctx, c)
+	if err != nil {
+		return err
+	}
+	if skipped != "" {
+		c.Skipped = skipped
+		return nil
+	}
+	c.KeyColumns = meta.keyCols
+
+	var diffs map[string]*rowDiff
+	for i := 0; i < compareTimes; i++ {
+		current, err := p.compareChunk(ctx, c, meta)
+		if err != nil {
+			return err
+		}
+		if diffs == nil {
+			diffs = current
+			continue
+		}
+		for k, d := range diffs {
+			if cd, ok := current[k]; !ok || !cd.equal(d) {
+				delete(diffs, k)
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(diffs))
+	for k := range diffs {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		d := diffs[k]
+		if d.deleted {
+			c.statements = append(c.statements, deleteStatement(c, meta, d.key))
+			c.Deletes++
+		} else {
+			c.statements = append(c.statements, replaceStatement(c, meta, d.row))
+			c.Replaces++
+		}
+	}
+	return nil
+}
+
+// tableMeta 读取表结构
+// 行的唯一标识优先用主键, 其次是非空唯一索引; 分块范围用校验时的分块索引
+func (p *Planner) tableMeta(ctx context.Context, c *ChunkPlan) (meta *tableMeta, skipped string, err error) {
+	meta = &tableMeta{}
+	var columns []struct {
+		Name     string `db:"COLUMN_NAME"`
+		DataType string `db:"DATA_TYPE"`
+	}
+	// 生成列不能写入, 也不参与对比
+	err = p.slave.SelectContext(
+		ctx,
+		&columns,
+		`SELECT COLUMN_NAME, DATA_TYPE FROM INFORMATION_SCHEMA.COLUMNS `+
+			`WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND EXTRA NOT LIKE '%GENERATED%' ORDER BY ORDINAL_POSITION`,
+		c.Db, c.Tbl,
+	)
+	if err != nil {
+		slog.Error("repair query columns", slog.String("error", err.Error()))
+		return nil, "", err
+	}
+	if len(columns) == 0 {
+		return nil, "table not found on slave", nil
+	}
+	for _, col := range columns {
+		meta.columns = append(meta.columns, col.Name)
+		meta.dataTypes = append(meta.dataTypes, strings.ToLower(col.DataType))
+	}
+
+	var indexes []struct {
+		IndexName string `db:"INDEX_NAME"`
+		Column    string `db:"COLUMN_NAME"`
+		NonUnique int    `db:"NON_UNIQUE"`
+		Nullable  string `db:"NULLABLE"`
+	}
+	err = p.slave.SelectContext(
+		ctx,
+		&indexes,
+		`SELECT INDEX_NAME, COLUMN_NAME, NON_UNIQUE, NULLABLE FROM INFORMATION_SCHEMA.STATISTICS `+
+			`WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? `+
+			`ORDER BY INDEX_NAME = 'PRIMARY' DESC, INDEX_NAME, SEQ_IN_INDEX`,
+		c.Db, c.Tbl,
+	)
+	if err != nil {
+		slog.Error("repair query indexes", slog.String("error", err.Error()))
+		return nil, "", err
+	}
+
+	cols := make(map[string][]string)
+	invalidKey := make(map[string]bool)
+	var order []string
+	for _, idx := range indexes {
+		if _, ok := cols[idx.IndexName]; !ok {
+			order = append(order, idx.IndexName)
+		}
+		cols[idx.IndexName] = append(cols[idx.IndexName], idx.Column)
+		if idx.NonUnique != 0 || idx.Nullable == "YES" || idx.Column == "" {
+			invalidKey[idx.IndexName] = true
+		}
+	}
+	for _, name := range order {
+		if !invalidKey[name] {
+			meta.keyCols = cols[name]
+			break
+		}
+	}
+	if len(meta.keyCols) == 0 {
+		return nil, "no primary key or not null unique key", nil
+	}
+
+	if c.ChunkIndex != nil && (c.LowerBoundary != nil || c.UpperBoundary != nil) {
+		meta.rangeCols = cols[*c.ChunkIndex]
+		if len(meta.rangeCols) == 0 {
+			return nil, fmt.Sprintf("chunk index %s not found", *c.ChunkIndex), nil
+		}
+	}
+	return meta, "", nil
+}
+
+// compareChunk 读出分块范围内 master, slave 的数据逐行对比
+func (p *Planner) compareChunk(ctx context.Context, c *ChunkPlan, meta *tableMeta) (map[string]*rowDiff, error) {
+	query, args, err := chunkQuery(c, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	masterFile, masterPos, err := masterStatus(ctx, p.master)
+	if err != nil {
+		return nil, err
+	}
+	masterRows, masterOrder, err := fetchRows(ctx, p.master, query, args, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	// slave 追上读 master 之前的位点再读, 尽量减少复制延迟造成的差异
+	if err := waitSlave(ctx, p.slave, masterFile, masterPos); err != nil {
+		return nil, err
+	}
+	slaveRows, slaveOrder, err := fetchRows(ctx, p.slave, query, args, meta)
+	if err != nil {
+		return nil, err
+	}
+	c.MasterRows = len(masterOrder)
+	c.SlaveRows = len(slaveOrder)
+
+	diffs := make(map[string]*rowDiff)
+	for _, k := range masterOrder {
+		mr := masterRows[k]
+		if sr, ok := slaveRows[k]; !ok || !slices.Equal(sr.row, mr.row) {
+			diffs[k] = &rowDiff{key: mr.key, row: mr.row}
+		}
+	}
+	for _, k := range slaveOrder {
+		if _, ok := masterRows[k]; !ok {
+			diffs[k] = &rowDiff{deleted: true, key: slaveRows[k].key}
+		}
+	}
+	return diffs, nil
+}
+
+// chunkQuery 分块范围用 >= lower AND <= upper, 兼容 pt-table-checksum 和 native 引擎的边界
+func chunkQuery(c *ChunkPlan, meta *tableMeta) (string, []interface{}, error) {
+	var selects []string
+	for _, col := range meta.columns {
+		selects = append(selects, sqlutil.QuoteIdent(col))
+	}
+
+	var conds []string
+	var args []interface{}
+	for _, b := range []struct {
+		boundary *string
+		op       string
+	}{{c.LowerBoundary, ">="}, {c.UpperBoundary, "<="}} {
+		if b.boundary == nil || len(meta.rangeCols) == 0 {
+			continue
+		}
+		values := sqlutil.SplitBoundary(*b.boundary)
+		if len(values) != len(meta.rangeCols) {
+			return "", nil, fmt.Errorf(
+				"boundary %s does not match chunk index columns %v", *b.boundary, meta.rangeCols)
+		}
+		conds = append(conds, sqlutil.RangeCond(meta.rangeCols, b.op))
+		args = append(args, sqlutil.RangeArgs(values)...)
+	}
+	if len(conds) == 0 {
+		conds = append(conds, "1=1")
+	}
+
+	return fmt.Sprintf(
+		"SELECT %s FROM %s WHERE %s",
+		strings.Join(selects, ", "), sqlutil.QuoteTable(c.Db, c.Tbl), strings.Join(conds, " AND "),
+	), args, nil
+}
+
+type fetchedRow struct {
+	key []string
+	row []sql.NullString
+}
+
+func fetchRows(
+	ctx context.Context, db *sqlx.DB, query string, args []interface{}, meta *tableMeta,
+) (rows map[string]*fetchedRow, order []string, err error) {
+	keyIdx := make([]int, len(meta.keyCols))
+	for i, k := range meta.keyCols {
+		keyIdx[i] = slices.Index(meta.columns, k)
+	}
+
+	res, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		slog.Error("repair fetch rows", slog.String("error", err.Error()), slog.String("sql", query))
+		return nil, nil, err
+	}
+	defer func() {
+		_ = res.Close()
+	}()
+
+	rows = make(map[string]*fetchedRow)
+	for res.Next() {
+		row := make([]sql.NullString, len(meta.columns))
+		dest := make([]interface{}, len(row))
+		for i := range row {
+			dest[i] = &row[i]
+		}
+		if err := res.Scan(dest...); err != nil {
+			slog.Error("repair scan rows", slog.String("error", err.Error()))
+			return nil, nil, err
+		}
+
+		fr := &fetchedRow{row: row}
+		var hexKey []string
+		for _, i := range keyIdx {
+			fr.key = append(fr.key, row[i].String)
+			hexKey = append(hexKey, hex.EncodeToString([]byte(row[i].String)))
+		}
+		k := strings.Join(hexKey, ",")
+		rows[k] = fr
+		order = append(order, k)
+	}
+	return rows, order, res.Err()
+}
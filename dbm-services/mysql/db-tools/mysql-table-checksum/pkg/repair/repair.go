@@ -0,0 +1,301 @@
+// Package repair 根据校验结果生成修复 sql
+package repair
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"dbm-services/mysql/db-tools/mysql-table-checksum/pkg/config"
+
+	_ "github.com/go-sql-driver/mysql" // mysql
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	sqlFileName     = "repair.sql"
+	summaryFileName = "summary.json"
+)
+
+// Planner 在 slave 上为不一致的分块生成修复 sql
+// 按分块边界到 master 和 slave 重新逐行对比, master 有 slave 没有或者不同的行 REPLACE, slave 多出的行 DELETE
+type Planner struct {
+	cfg        *config.Config
+	apply      bool
+	outputDir  string
+	resultDB   string
+	resultTbl  string
+	masterIp   string
+	masterPort int
+	slave      *sqlx.DB
+	master     *sqlx.DB
+	applyConn  *sqlx.Conn
+}
+
+// ChunkPlan 单个分块的修复计划
+type ChunkPlan struct {
+	Db            string   `json:"db"`
+	Tbl           string   `json:"tbl"`
+	Chunk         int      `json:"chunk"`
+	ChunkIndex    *string  `json:"chunk_index"`
+	LowerBoundary *string  `json:"lower_boundary"`
+	UpperBoundary *string  `json:"upper_boundary"`
+	KeyColumns    []string `json:"key_columns"`
+	MasterRows    int      `json:"master_rows"`
+	SlaveRows     int      `json:"slave_rows"`
+	Replaces      int      `json:"replaces"`
+	Deletes       int      `json:"deletes"`
+	Applied       bool     `json:"applied"`
+	Skipped       string   `json:"skipped,omitempty"`
+	Error         string   `json:"error,omitempty"`
+	statements    []string
+}
+
+// Summary 修复计划汇总, 和 sql 文件放在一起方便审核
+type Summary struct {
+	MasterIp   string       `json:"master_ip"`
+	MasterPort int          `json:"master_port"`
+	SlaveIp    string       `json:"slave_ip"`
+	SlavePort  int          `json:"slave_port"`
+	CreatedAt  time.Time    `json:"created_at"`
+	SqlFile    string       `json:"sql_file"`
+	Apply      bool         `json:"apply"`
+	Replaces   int          `json:"replaces"`
+	Deletes    int          `json:"deletes"`
+	Chunks     []*ChunkPlan `json:"chunks"`
+}
+
+// NewPlanner 只能在 slave, repeater 上运行, master 的连接信息从复制状态获取
+func NewPlanner(cfg *config.Config, outputDir string, apply bool) (*Planner, error) {
+	if cfg.InnerRole == config.RoleMaster {
+		err := fmt.Errorf("repair plan should run on slave or repeater")
+		slog.Error("new repair planner", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	if outputDir == "" {
+		outputDir = filepath.Join(cfg.ReportPath, "repair")
+	}
+	splitR := strings.Split(cfg.PtChecksum.Replicate, ".")
+	if len(splitR) != 2 {
+		err := fmt.Errorf("bad pt_checksum.replicate: %s", cfg.PtChecksum.Replicate)
+		slog.Error("new repair planner", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	p := &Planner{
+		cfg:       cfg,
+		apply:     apply,
+		outputDir: outputDir,
+		resultDB:  splitR[0],
+		resultTbl: splitR[1],
+	}
+
+	var err error
+	p.slave, err = connect(cfg.Ip, cfg.Port, cfg.User, cfg.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	p.masterIp, p.masterPort, err = masterHost(p.slave)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("repair master host", slog.String("ip", p.masterIp), slog.Int("port", p.masterPort))
+
+	// 校验帐号在整个集群上是一样的
+	p.master, err = connect(p.masterIp, p.masterPort, cfg.User, cfg.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	if apply {
+		p.applyConn, err = p.slave.Connx(context.Background())
+		if err != nil {
+			slog.Error("get apply conn", slog.String("error", err.Error()))
+			return nil, err
+		}
+		_, err = p.applyConn.ExecContext(context.Background(), `SET SESSION sql_log_bin = 0`)
+		if err != nil {
+			slog.Error("disable binlog on slave", slog.String("error", err.Error()))
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// 不开 parseTime, 所有列都按照文本读出来对比和拼 sql
+func connect(ip string, port int, user string, password string) (*sqlx.DB, error) {
+	db, err := sqlx.Connect(
+		"mysql",
+		fmt.Sprintf("%s:%s@tcp(%s:%d)/?charset=utf8mb4", user, password, ip, port),
+	)
+	if err != nil {
+		slog.Error("repair connect", slog.String("error", err.Error()), slog.String("ip", ip), slog.Int("port", port))
+		return nil, err
+	}
+	return db, nil
+}
+
+// Close 关闭连接
+func (p *Planner) Close() {
+	if p.applyConn != nil {
+		_ = p.applyConn.Close()
+	}
+	if p.master != nil {
+		_ = p.master.Close()
+	}
+	if p.slave != nil {
+		_ = p.slave.Close()
+	}
+}
+
+// Run 生成修复计划, apply 时顺便在 slave 上执行
+func (p *Planner) Run(ctx context.Context) (*Summary, error) {
+	chunks, err := p.badChunks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("repair found bad chunks", slog.Int("count", len(chunks)))
+
+	dir := filepath.Join(p.outputDir, fmt.Sprintf("%d_%s", p.cfg.Port, time.Now().Format("20060102150405")))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		slog.Error("create repair dir", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	summary := &Summary{
+		MasterIp:   p.masterIp,
+		MasterPort: p.masterPort,
+		SlaveIp:    p.cfg.Ip,
+		SlavePort:  p.cfg.Port,
+		CreatedAt:  time.Now(),
+		SqlFile:    filepath.Join(dir, sqlFileName),
+		Apply:      p.apply,
+		Chunks:     chunks,
+	}
+
+	f, err := os.Create(summary.SqlFile)
+	if err != nil {
+		slog.Error("create repair sql file", slog.String("error", err.Error()))
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	w := bufio.NewWriter(f)
+	_, _ = fmt.Fprintf(w, "-- repair plan for slave %s:%d, master %s:%d, created at %s\n",
+		p.cfg.Ip, p.cfg.Port, p.masterIp, p.masterPort, summary.CreatedAt.Format(time.DateTime))
+	_, _ = fmt.Fprintf(w, "-- execute on slave only\nSET NAMES utf8mb4;\nSET SESSION sql_log_bin = 0;\n\n")
+
+	for _, c := range chunks {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		err := p.planChunk(ctx, c)
+		if err != nil {
+			c.Error = err.Error()
+			slog.Error("plan chunk", slog.String("error", err.Error()),
+				slog.String("table", fmt.Sprintf("%s.%s", c.Db, c.Tbl)), slog.Int("chunk", c.Chunk))
+		}
+		summary.Replaces += c.Replaces
+		summary.Deletes += c.Deletes
+
+		_, _ = fmt.Fprintf(w, "-- %s.%s chunk %d, replaces: %d, deletes: %d\n",
+			c.Db, c.Tbl, c.Chunk, c.Replaces, c.Deletes)
+		if c.Skipped != "" {
+			_, _ = fmt.Fprintf(w, "-- skipped: %s\n", c.Skipped)
+		}
+		for _, stmt := range c.statements {
+			_, _ = fmt.Fprintln(w, stmt)
+		}
+		_, _ = fmt.Fprintln(w)
+
+		if p.apply && c.Error == "" && len(c.statements) > 0 {
+			if err := p.applyChunk(ctx, c); err != nil {
+				c.Error = err.Error()
+			} else {
+				c.Applied = true
+			}
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		slog.Error("write repair sql file", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	b, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		slog.Error("marshal repair summary", slog.String("error", err.Error()))
+		return nil, err
+	}
+	err = os.WriteFile(filepath.Join(dir, summaryFileName), b, 0644)
+	if err != nil {
+		slog.Error("write repair summary", slog.String("error", err.Error()))
+		return nil, err
+	}
+	slog.Info("repair plan finish", slog.String("dir", dir))
+	return summary, nil
+}
+
+// badChunks 当前结果表里 crc 或行数不一致的分块
+func (p *Planner) badChunks(ctx context.Context) (chunks []*ChunkPlan, err error) {
+	rows, err := p.slave.QueryxContext(
+		ctx,
+		fmt.Sprintf(
+			`SELECT db, tbl, chunk, chunk_index, lower_boundary, upper_boundary FROM %s.%s `+
+				`WHERE master_ip = ? AND master_port = ? `+
+				`AND (this_crc <> master_crc OR this_cnt <> master_cnt) ORDER BY db, tbl, chunk`,
+			p.resultDB, p.resultTbl,
+		),
+		p.masterIp, p.masterPort,
+	)
+	if err != nil {
+		slog.Error("query bad chunks", slog.String("error", err.Error()))
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		c := &ChunkPlan{}
+		err := rows.Scan(&c.Db, &c.Tbl, &c.Chunk, &c.ChunkIndex, &c.LowerBoundary, &c.UpperBoundary)
+		if err != nil {
+			slog.Error("scan bad chunks", slog.String("error", err.Error()))
+			return nil, err
+		}
+		chunks = append(chunks, c)
+	}
+	return chunks, rows.Err()
+}
+
+// applyChunk 在关闭了 binlog 的 slave 会话上以事务执行一个分块的修复
+func (p *Planner) applyChunk(ctx context.Context, c *ChunkPlan) error {
+	tx, err := p.applyConn.BeginTxx(ctx, nil)
+	if err != nil {
+		slog.Error("apply chunk begin", slog.String("error", err.Error()))
+		return err
+	}
+	for _, stmt := range c.statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			_ = tx.Rollback()
+			slog.Error("apply chunk", slog.String("error", err.Error()), slog.String("sql", stmt))
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		slog.Error("apply chunk commit", slog.String("error", err.Error()))
+		return err
+	}
+	slog.Info("apply chunk", slog.String("table", fmt.Sprintf("%s.%s", c.Db, c.Tbl)), slog.Int("chunk", c.Chunk))
+	return nil
+}
@@ -0,0 +1,83 @@
+package repair
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	"dbm-services/mysql/db-tools/mysql-table-checksum/pkg/sqlutil"
+)
+
+var numericTypes = []string{
+	"tinyint", "smallint", "mediumint", "int", "integer", "bigint",
+	"decimal", "numeric", "float", "double", "real",
+}
+
+// 这些类型按字节原样写回, 用十六进制避免字符集转换
+var binaryTypes = []string{
+	"binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob", "bit",
+	"geometry", "point", "linestring", "polygon", "multipoint", "multilinestring", "multipolygon",
+	"geometrycollection",
+}
+
+func replaceStatement(c *ChunkPlan, meta *tableMeta, row []sql.NullString) string {
+	var cols, values []string
+	for i, col := range meta.columns {
+		cols = append(cols, sqlutil.QuoteIdent(col))
+		values = append(values, sqlValue(meta.dataTypes[i], row[i]))
+	}
+	return fmt.Sprintf(
+		"REPLACE INTO %s (%s) VALUES (%s);",
+		sqlutil.QuoteTable(c.Db, c.Tbl), strings.Join(cols, ", "), strings.Join(values, ", "),
+	)
+}
+
+func deleteStatement(c *ChunkPlan, meta *tableMeta, key []string) string {
+	var conds []string
+	for i, col := range meta.keyCols {
+		dataType := meta.dataTypes[slices.Index(meta.columns, col)]
+		conds = append(conds, fmt.Sprintf(
+			"%s = %s", sqlutil.QuoteIdent(col), sqlValue(dataType, sql.NullString{String: key[i], Valid: true})))
+	}
+	return fmt.Sprintf("DELETE FROM %s WHERE %s LIMIT 1;", sqlutil.QuoteTable(c.Db, c.Tbl), strings.Join(conds, " AND "))
+}
+
+func sqlValue(dataType string, v sql.NullString) string {
+	if !v.Valid {
+		return "NULL"
+	}
+	if slices.Contains(numericTypes, dataType) && v.String != "" {
+		return v.String
+	}
+	if slices.Contains(binaryTypes, dataType) {
+		return fmt.Sprintf("X'%s'", hex.EncodeToString([]byte(v.String)))
+	}
+	return quoteString(v.String)
+}
+
+func quoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case 0:
+			b.WriteString(`\0`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\x1a':
+			b.WriteString(`\Z`)
+		case '\'':
+			b.WriteString(`\'`)
+		case '\\':
+			b.WriteString(`\\`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
@@ -0,0 +1,110 @@
+package repair
+
+import (
+	"database/sql"
+	"slices"
+	"testing"
+)
+
+func valid(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: true}
+}
+
+func TestQuoteString(t *testing.T) {
+	cases := []struct {
+		s    string
+		want string
+	}{
+		{"abc", `'abc'`},
+		{"", `''`},
+		{"it's", `'it\'s'`},
+		{`a\b`, `'a\\b'`},
+		{"a\nb\rc", `'a\nb\rc'`},
+		{"a\x00b\x1a", `'a\0b\Z'`},
+		{"中文", `'中文'`},
+	}
+	for _, c := range cases {
+		if got := quoteString(c.s); got != c.want {
+			t.Errorf("quoteString(%q) = %s, want %s", c.s, got, c.want)
+		}
+	}
+}
+
+func TestSqlValue(t *testing.T) {
+	cases := []struct {
+		dataType string
+		v        sql.NullString
+		want     string
+	}{
+		{"int", sql.NullString{}, "NULL"},
+		{"varchar", sql.NullString{}, "NULL"},
+		{"bigint", valid("-12"), "-12"},
+		{"decimal", valid("1.50"), "1.50"},
+		{"int", valid(""), "''"},
+		{"varchar", valid("a'b"), `'a\'b'`},
+		{"datetime", valid("2024-01-01 00:00:00"), "'2024-01-01 00:00:00'"},
+		{"varbinary", valid("\x00\xff"), "X'00ff'"},
+		{"blob", valid(""), "X''"},
+	}
+	for _, c := range cases {
+		if got := sqlValue(c.dataType, c.v); got != c.want {
+			t.Errorf("sqlValue(%s, %+v) = %s, want %s", c.dataType, c.v, got, c.want)
+		}
+	}
+}
+
+func testMeta() *tableMeta {
+	return &tableMeta{
+		columns:   []string{"id", "name", "data"},
+		dataTypes: []string{"int", "varchar", "blob"},
+		keyCols:   []string{"id", "name"},
+		rangeCols: []string{"id"},
+	}
+}
+
+func TestStatement(t *testing.T) {
+	c := &ChunkPlan{Db: "db1", Tbl: "t1"}
+	meta := testMeta()
+
+	got := replaceStatement(c, meta, []sql.NullString{valid("1"), valid("o'k"), {}})
+	want := "REPLACE INTO `db1`.`t1` (`id`, `name`, `data`) VALUES (1, 'o\\'k', NULL);"
+	if got != want {
+		t.Errorf("replaceStatement = %s, want %s", got, want)
+	}
+
+	got = deleteStatement(c, meta, []string{"2", "x"})
+	want = "DELETE FROM `db1`.`t1` WHERE `id` = 2 AND `name` = 'x' LIMIT 1;"
+	if got != want {
+		t.Errorf("deleteStatement = %s, want %s", got, want)
+	}
+}
+
+func TestChunkQuery(t *testing.T) {
+	lower, upper := "1", "100"
+	meta := testMeta()
+
+	query, args, err := chunkQuery(&ChunkPlan{Db: "db1", Tbl: "t1", LowerBoundary: &lower, UpperBoundary: &upper}, meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SELECT `id`, `name`, `data` FROM `db1`.`t1` WHERE ((`id` >= ?)) AND ((`id` <= ?))"
+	if query != want || !slices.Equal(args, []interface{}{"1", "100"}) {
+		t.Errorf("chunkQuery = %s %v, want %s", query, args, want)
+	}
+
+	// 第一个分块没有下界, 没有索引的表整表对比
+	query, args, err = chunkQuery(&ChunkPlan{Db: "db1", Tbl: "t1", UpperBoundary: &upper}, meta)
+	if err != nil || query != "SELECT `id`, `name`, `data` FROM `db1`.`t1` WHERE ((`id` <= ?))" || len(args) != 1 {
+		t.Errorf("chunkQuery without lower = %s %v %v", query, args, err)
+	}
+	meta.rangeCols = nil
+	query, _, err = chunkQuery(&ChunkPlan{Db: "db1", Tbl: "t1", LowerBoundary: &lower}, meta)
+	if err != nil || query != "SELECT `id`, `name`, `data` FROM `db1`.`t1` WHERE 1=1" {
+		t.Errorf("chunkQuery without index = %s %v", query, err)
+	}
+
+	meta.rangeCols = []string{"id", "name"}
+	if _, _, err = chunkQuery(&ChunkPlan{Db: "db1", Tbl: "t1", LowerBoundary: &lower}, meta); err == nil {
+		t.Error("boundary not matching index columns should fail")
+	}
+}
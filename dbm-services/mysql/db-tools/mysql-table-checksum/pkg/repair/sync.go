@@ -0,0 +1,113 @@
+package repair
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// waitTimeout 等待 slave 追上 master 位点的超时时间, 秒
+const waitTimeout = 60
+
+// masterHost 从复制状态获取 master 地址
+func masterHost(db *sqlx.DB) (ip string, port int, err error) {
+	status, err := showStatus(db, "SHOW SLAVE STATUS", "SHOW REPLICA STATUS")
+	if err != nil {
+		slog.Error("repair query slave status", slog.String("error", err.Error()))
+		return "", 0, err
+	}
+	if len(status) == 0 {
+		err = fmt.Errorf("slave status is empty")
+		slog.Error("repair query slave status", slog.String("error", err.Error()))
+		return "", 0, err
+	}
+
+	ip = firstValue(status, "Master_Host", "Source_Host")
+	port, err = strconv.Atoi(firstValue(status, "Master_Port", "Source_Port"))
+	if err != nil {
+		slog.Error("parse master port", slog.String("error", err.Error()))
+		return "", 0, err
+	}
+	return ip, port, nil
+}
+
+// masterStatus 当前 binlog 位点
+func masterStatus(ctx context.Context, db *sqlx.DB) (file string, pos int64, err error) {
+	status, err := showStatus(db, "SHOW MASTER STATUS", "SHOW BINARY LOG STATUS")
+	if err != nil {
+		slog.Error("repair query master status", slog.String("error", err.Error()))
+		return "", 0, err
+	}
+	if ctx.Err() != nil {
+		return "", 0, ctx.Err()
+	}
+	if len(status) == 0 {
+		err = fmt.Errorf("master binlog is disabled")
+		slog.Error("repair query master status", slog.String("error", err.Error()))
+		return "", 0, err
+	}
+
+	file = firstValue(status, "File")
+	pos, err = strconv.ParseInt(firstValue(status, "Position"), 10, 64)
+	if err != nil {
+		slog.Error("parse master position", slog.String("error", err.Error()))
+		return "", 0, err
+	}
+	return file, pos, nil
+}
+
+// waitSlave 等待 slave sql 线程执行到 master 的位点
+func waitSlave(ctx context.Context, db *sqlx.DB, file string, pos int64) error {
+	var res sql.NullInt64
+	err := db.QueryRowContext(ctx, `SELECT MASTER_POS_WAIT(?, ?, ?)`, file, pos, waitTimeout).Scan(&res)
+	if err != nil {
+		// 8.4 之后只有 SOURCE_POS_WAIT
+		err = db.QueryRowContext(ctx, `SELECT SOURCE_POS_WAIT(?, ?, ?)`, file, pos, waitTimeout).Scan(&res)
+	}
+	if err != nil {
+		slog.Error("wait slave catch up", slog.String("error", err.Error()))
+		return err
+	}
+	if !res.Valid {
+		return fmt.Errorf("slave sql thread is not running")
+	}
+	if res.Int64 == -1 {
+		return fmt.Errorf("wait slave catch up %s:%d timeout", file, pos)
+	}
+	return nil
+}
+
+// showStatus 依次尝试新旧语法, 返回第一行
+func showStatus(db *sqlx.DB, queries ...string) (status map[string]interface{}, err error) {
+	for _, query := range queries {
+		var rows *sqlx.Rows
+		rows, err = db.Queryx(query)
+		if err != nil {
+			continue
+		}
+
+		status = make(map[string]interface{})
+		if rows.Next() {
+			err = rows.MapScan(status)
+		}
+		_ = rows.Close()
+		return status, err
+	}
+	return nil, err
+}
+
+func firstValue(status map[string]interface{}, keys ...string) string {
+	for _, k := range keys {
+		if v, ok := status[k]; ok && v != nil {
+			if b, ok := v.([]byte); ok {
+				return string(b)
+			}
+			return fmt.Sprintf("%v", v)
+		}
+	}
+	return ""
+}
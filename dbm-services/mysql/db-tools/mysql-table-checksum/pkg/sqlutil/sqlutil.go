@@ -0,0 +1,78 @@
+// Package sqlutil native 校验引擎和修复计划共用的 sql 拼接工具
+package sqlutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QuoteIdent 反引号包围标识符
+func QuoteIdent(name string) string {
+	return fmt.Sprintf("`%s`", strings.ReplaceAll(name, "`", "``"))
+}
+
+// QuoteTable 返回 `db`.`tbl`
+func QuoteTable(db string, tbl string) string {
+	return fmt.Sprintf("%s.%s", QuoteIdent(db), QuoteIdent(tbl))
+}
+
+// RangeCond 生成 (a, b) op (?, ?) 展开后的条件, 展开是为了能用上索引
+// op 为 >, >=, <, <=, 只有最后一列用 op 本身, 前面的列用去掉 = 的严格比较
+func RangeCond(cols []string, op string) string {
+	strict := strings.TrimSuffix(op, "=")
+	var ors []string
+	for i := range cols {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, fmt.Sprintf("%s = ?", QuoteIdent(cols[j])))
+		}
+		last := strict
+		if i == len(cols)-1 {
+			last = op
+		}
+		ands = append(ands, fmt.Sprintf("%s %s ?", QuoteIdent(cols[i]), last))
+		ors = append(ors, fmt.Sprintf("(%s)", strings.Join(ands, " AND ")))
+	}
+	return fmt.Sprintf("(%s)", strings.Join(ors, " OR "))
+}
+
+// RangeArgs 和 RangeCond 的占位符一一对应
+func RangeArgs(values []string) (args []interface{}) {
+	for i := range values {
+		for j := 0; j <= i; j++ {
+			args = append(args, values[j])
+		}
+	}
+	return args
+}
+
+// JoinBoundary 边界值以逗号拼接后写入 lower_boundary, upper_boundary, 值里面的 \ 和逗号用 \ 转义
+func JoinBoundary(values []string) string {
+	var escaped []string
+	for _, v := range values {
+		v = strings.ReplaceAll(v, `\`, `\\`)
+		escaped = append(escaped, strings.ReplaceAll(v, ",", `\,`))
+	}
+	return strings.Join(escaped, ",")
+}
+
+// SplitBoundary JoinBoundary 的逆操作, pt-table-checksum 写入的边界没有转义, 同样可以解析
+func SplitBoundary(s string) (values []string) {
+	var cur strings.Builder
+	escape := false
+	for _, c := range s {
+		switch {
+		case escape:
+			cur.WriteRune(c)
+			escape = false
+		case c == '\\':
+			escape = true
+		case c == ',':
+			values = append(values, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteRune(c)
+		}
+	}
+	return append(values, cur.String())
+}
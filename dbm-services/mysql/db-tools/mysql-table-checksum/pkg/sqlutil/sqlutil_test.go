@@ -0,0 +1,62 @@
+package sqlutil
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRangeCond(t *testing.T) {
+	cases := []struct {
+		cols []string
+		op   string
+		want string
+	}{
+		{[]string{"id"}, ">", "((`id` > ?))"},
+		{[]string{"id"}, ">=", "((`id` >= ?))"},
+		{[]string{"a", "b"}, ">=", "((`a` > ?) OR (`a` = ? AND `b` >= ?))"},
+		{[]string{"a", "b", "c"}, "<=", "((`a` < ?) OR (`a` = ? AND `b` < ?) OR (`a` = ? AND `b` = ? AND `c` <= ?))"},
+	}
+	for _, c := range cases {
+		if got := RangeCond(c.cols, c.op); got != c.want {
+			t.Errorf("RangeCond(%v, %s) = %s, want %s", c.cols, c.op, got, c.want)
+		}
+	}
+
+	args := RangeArgs([]string{"1", "2", "3"})
+	want := []interface{}{"1", "1", "2", "1", "2", "3"}
+	if !slices.Equal(args, want) {
+		t.Errorf("RangeArgs = %v, want %v", args, want)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	if got := QuoteIdent("a`b"); got != "`a``b`" {
+		t.Errorf("QuoteIdent = %s", got)
+	}
+	if got := QuoteTable("db", "t"); got != "`db`.`t`" {
+		t.Errorf("QuoteTable = %s", got)
+	}
+}
+
+func TestJoinSplitBoundary(t *testing.T) {
+	cases := []struct {
+		values []string
+		joined string
+	}{
+		{[]string{"1"}, "1"},
+		{[]string{"1", "abc"}, "1,abc"},
+		{[]string{"a,b", "c"}, `a\,b,c`},
+		{[]string{`a\`, "b"}, `a\\,b`},
+		{[]string{`\,`, ""}, `\\\,,`},
+		{[]string{"", ""}, ","},
+	}
+	for _, c := range cases {
+		joined := JoinBoundary(c.values)
+		if joined != c.joined {
+			t.Errorf("JoinBoundary(%q) = %q, want %q", c.values, joined, c.joined)
+		}
+		if got := SplitBoundary(joined); !slices.Equal(got, c.values) {
+			t.Errorf("SplitBoundary(%q) = %q, want %q", joined, got, c.values)
+		}
+	}
+}
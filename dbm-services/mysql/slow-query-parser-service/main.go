@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
@@ -23,8 +24,12 @@ var (
 
 	runCmd          = root.Command("run", "start service")
 	runCmdAddress   = runCmd.Flag("address", "service listen address").Required().Envar("SQ_ADDRESS").TCP()
-	tmysqlParsePath = runCmd.Flag("tmysqlparse-bin", "tmysqlparse bin path").Required().Envar("SQ_TMYSQLPARSER_BIN").
-			ExistingFile()
+	tmysqlParsePath = runCmd.Flag("tmysqlparse-bin", "tmysqlparse bin path, use native digest if empty").
+			Envar("SQ_TMYSQLPARSER_BIN").ExistingFile()
+
+	slowLogCmd     = root.Command("slowlog", "aggregate slow log file by fingerprint")
+	slowLogCmdFile = slowLogCmd.Flag("file", "slow log file").Required().ExistingFile()
+	slowLogCmdTop  = slowLogCmd.Flag("top", "only output top n by total query time, 0 means all").Default("0").Int()
 
 	versionCmd = root.Command("version", "print version")
 )
@@ -48,7 +53,7 @@ func main() {
 			slog.String("tmysqlparse-bin", *tmysqlParsePath),
 		)
 
-		if !filepath.IsAbs(*tmysqlParsePath) {
+		if *tmysqlParsePath != "" && !filepath.IsAbs(*tmysqlParsePath) {
 			cwd, _ := os.Getwd()
 			*tmysqlParsePath = filepath.Join(cwd, *tmysqlParsePath)
 			slog.Info("init run concat cwd to tmysqlparse-bin", slog.String("cwd", cwd))
@@ -56,7 +61,34 @@ func main() {
 
 		mysql.ParserPath = tmysqlParsePath
 		_ = service.Start((*runCmdAddress).String())
+	case slowLogCmd.FullCommand():
+		if err := aggregateSlowLog(*slowLogCmdFile, *slowLogCmdTop); err != nil {
+			slog.Error("aggregate slow log", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
 	case versionCmd.FullCommand():
 		fmt.Printf("Version: %s, GitHash: %s, BuildAt: %s\n", version, gitHash, buildStamp)
 	}
 }
+
+func aggregateSlowLog(file string, top int) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	res, err := mysql.AggregateSlowLog(f)
+	if err != nil {
+		return err
+	}
+	if top > 0 && len(res) > top {
+		res = res[:top]
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(res)
+}
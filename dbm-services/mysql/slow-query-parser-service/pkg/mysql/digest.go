@@ -0,0 +1,66 @@
+package mysql
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"slices"
+)
+
+// Digest 不依赖 tmysqlparse 生成指纹
+func Digest(query string) *Response {
+	tokens := normalizeTokens(query)
+	text := render(tokens)
+	sum := md5.Sum([]byte(text))
+
+	res := &Response{
+		QueryString:     query,
+		QueryDigestText: text,
+		QueryDigestMd5:  hex.EncodeToString(sum[:]),
+		QueryLength:     len(query),
+	}
+
+	for _, t := range tokens {
+		if t.kind == tokenWord {
+			res.Command = t.text
+			break
+		}
+	}
+	res.DbName, res.TableName = firstTable(tokens)
+	return res
+}
+
+// firstTable 取 from/into/update/table/join 后面的第一个表名
+func firstTable(tokens []token) (db string, table string) {
+	for i := 0; i < len(tokens)-1; i++ {
+		if tokens[i].kind != tokenWord || !slices.Contains(tableKeywords, tokens[i].text) {
+			continue
+		}
+
+		j := skipIfExists(tokens, i+1)
+		if j >= len(tokens) || !isName(tokens[j]) {
+			// 子查询等
+			continue
+		}
+		name := tokens[j]
+		if j+2 < len(tokens) && isPunct(tokens[j+1], ".") && isName(tokens[j+2]) {
+			return name.text, tokens[j+2].text
+		}
+		return "", name.text
+	}
+	return "", ""
+}
+
+// skipIfExists 跳过 create table if not exists / drop table if exists
+func skipIfExists(tokens []token, i int) int {
+	for _, w := range []string{"if", "not", "exists"} {
+		if i < len(tokens) && tokens[i].kind == tokenWord && tokens[i].text == w {
+			i++
+		}
+	}
+	return i
+}
+
+func isName(t token) bool {
+	return t.kind == tokenIdent || (t.kind == tokenWord && !slices.Contains(operatorKeywords, t.text) &&
+		!slices.Contains(spacedKeywords, t.text))
+}
@@ -0,0 +1,75 @@
+package mysql
+
+import "testing"
+
+func TestDigest(t *testing.T) {
+	cases := []struct {
+		query   string
+		command string
+		db      string
+		table   string
+	}{
+		{"select * from t1 where id = 1", "select", "", "t1"},
+		{"SELECT a FROM `db1`.`t1` JOIN t2 ON t1.id = t2.id", "select", "db1", "t1"},
+		{"select * from (select * from t2) as x", "select", "", "t2"},
+		{"/* c */ insert into db1.t1 values (1)", "insert", "db1", "t1"},
+		{"update t1 set a = 1", "update", "", "t1"},
+		{"delete from t1 where a = 1", "delete", "", "t1"},
+		{"create table if not exists t1 (id int)", "create", "", "t1"},
+		{"CREATE TABLE IF NOT EXISTS db1.t1 (id int)", "create", "db1", "t1"},
+		{"drop table if exists t1", "drop", "", "t1"},
+		{"alter table t1 add column a int", "alter", "", "t1"},
+		{"select 1", "select", "", ""},
+		{"", "", "", ""},
+	}
+	for _, c := range cases {
+		res := Digest(c.query)
+		if res.Command != c.command || res.DbName != c.db || res.TableName != c.table {
+			t.Errorf(
+				"Digest(%q) = %s %s.%s, want %s %s.%s",
+				c.query, res.Command, res.DbName, res.TableName, c.command, c.db, c.table,
+			)
+		}
+		if res.QueryLength != len(c.query) || res.QueryString != c.query {
+			t.Errorf("Digest(%q) query string/length mismatch: %+v", c.query, res)
+		}
+	}
+}
+
+func TestDigestMd5(t *testing.T) {
+	cases := []struct {
+		a    string
+		b    string
+		same bool
+	}{
+		{"select * from t where id = 1", "SELECT * FROM t WHERE id=2;", true},
+		{"select * from t where id in (1)", "select * from t where id in (1, 2, 3)", true},
+		{"select * from t where id = 1", "select * from t2 where id = 1", false},
+		{"select a from t", "select b from t", false},
+	}
+	for _, c := range cases {
+		a, b := Digest(c.a), Digest(c.b)
+		if (a.QueryDigestMd5 == b.QueryDigestMd5) != c.same {
+			t.Errorf("digest of %q and %q: %s %s, want same %v", c.a, c.b, a.QueryDigestMd5, b.QueryDigestMd5, c.same)
+		}
+	}
+}
+
+func TestDigestQuery(t *testing.T) {
+	old := ParserPath
+	defer func() {
+		ParserPath = old
+	}()
+
+	empty := ""
+	for _, p := range []*string{nil, &empty} {
+		ParserPath = p
+		res, err := DigestQuery("select 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := Digest("select 1"); res.QueryDigestMd5 != want.QueryDigestMd5 {
+			t.Errorf("DigestQuery without parser should use Digest, got %+v", res)
+		}
+	}
+}
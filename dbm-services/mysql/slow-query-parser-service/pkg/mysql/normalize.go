@@ -0,0 +1,367 @@
+package mysql
+
+import (
+	"slices"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenIdent
+	tokenLiteral
+	tokenVariable
+	tokenPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// 这些关键字后面的 +/- 是一元符号
+var operatorKeywords = []string{
+	"select", "where", "and", "or", "not", "xor", "in", "values", "value", "set",
+	"when", "then", "else", "between", "like", "by", "limit", "offset", "return",
+	"interval", "is", "on", "having", "div", "mod", "case", "regexp",
+}
+
+// 这些关键字后面的 ( 前要留空格, 其他单词后面的 ( 当作函数调用
+var spacedKeywords = []string{
+	"in", "values", "value", "from", "join", "exists", "and", "or", "not", "xor",
+	"on", "as", "using", "where", "select", "into", "union", "all", "any", "some",
+}
+
+// 表名前的关键字
+var tableKeywords = []string{"from", "into", "update", "table", "join"}
+
+var multiCharOperators = []string{"<=>", "->>", "<=", ">=", "<>", "!=", ":=", "||", "&&", "<<", ">>", "->"}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 ||
+		('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+// tokenize 切分 sql, 丢弃注释和空白
+func tokenize(query string) []token {
+	var tokens []token
+	n := len(query)
+	for i := 0; i < n; {
+		c := query[i]
+		switch {
+		case isSpace(c):
+			i++
+		case c == '#':
+			i = skipLine(query, i)
+		case c == '-' && i+1 < n && query[i+1] == '-' && (i+2 == n || isSpace(query[i+2])):
+			i = skipLine(query, i)
+		case c == '/' && i+1 < n && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				i = n
+			} else {
+				i = i + 2 + end + 2
+			}
+		case c == '\'' || c == '"':
+			i = skipQuoted(query, i, c)
+			tokens = append(tokens, token{kind: tokenLiteral})
+		case c == '`':
+			end := skipQuoted(query, i, c)
+			text := strings.ReplaceAll(query[i+1:max(end-1, i+1)], "``", "`")
+			tokens = append(tokens, token{kind: tokenIdent, text: strings.ToLower(text)})
+			i = end
+		case c == '?':
+			tokens = append(tokens, token{kind: tokenLiteral})
+			i++
+		case isDigit(c) || (c == '.' && i+1 < n && isDigit(query[i+1]) && !afterName(tokens)):
+			end := scanNumber(query, i)
+			if end < n && isWordByte(query[end]) {
+				// 数字开头的标识符, 如 1t
+				end = scanWord(query, end)
+				tokens = append(tokens, token{kind: tokenWord, text: strings.ToLower(query[i:end])})
+			} else {
+				tokens = append(tokens, token{kind: tokenLiteral})
+			}
+			i = end
+		case c == '@':
+			end := i + 1
+			for end < n && query[end] == '@' {
+				end++
+			}
+			if end < n && (query[end] == '\'' || query[end] == '"' || query[end] == '`') {
+				end = skipQuoted(query, end, query[end])
+			} else {
+				for end < n && (isWordByte(query[end]) || query[end] == '.') {
+					end++
+				}
+			}
+			tokens = append(tokens, token{kind: tokenVariable, text: strings.ToLower(query[i:end])})
+			i = end
+		case isWordByte(c):
+			end := scanWord(query, i)
+			word := strings.ToLower(query[i:end])
+			// x'..' b'..' n'..' _utf8mb4'..' 都是字面量
+			if end < n && query[end] == '\'' &&
+				(word == "x" || word == "b" || word == "n" || strings.HasPrefix(word, "_")) {
+				i = skipQuoted(query, end, '\'')
+				tokens = append(tokens, token{kind: tokenLiteral})
+				continue
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: word})
+			i = end
+		default:
+			op := string(c)
+			for _, m := range multiCharOperators {
+				if strings.HasPrefix(query[i:], m) {
+					op = m
+					break
+				}
+			}
+			tokens = append(tokens, token{kind: tokenPunct, text: op})
+			i += len(op)
+		}
+	}
+	return tokens
+}
+
+// afterName 紧跟在名字后面的 . 是 db.table 的分隔符
+func afterName(tokens []token) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+	return isName(tokens[len(tokens)-1])
+}
+
+func skipLine(query string, i int) int {
+	end := strings.IndexByte(query[i:], '\n')
+	if end < 0 {
+		return len(query)
+	}
+	return i + end + 1
+}
+
+// skipQuoted 返回引号结束后的位置, 支持反斜杠和重复引号转义
+func skipQuoted(query string, i int, quote byte) int {
+	n := len(query)
+	for j := i + 1; j < n; j++ {
+		switch query[j] {
+		case '\\':
+			if quote != '`' {
+				j++
+			}
+		case quote:
+			if j+1 < n && query[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return n
+}
+
+func scanWord(query string, i int) int {
+	for i < len(query) && isWordByte(query[i]) {
+		i++
+	}
+	return i
+}
+
+func scanNumber(query string, i int) int {
+	n := len(query)
+	if query[i] == '0' && i+1 < n && (query[i+1] == 'x' || query[i+1] == 'X' || query[i+1] == 'b' || query[i+1] == 'B') {
+		j := i + 2
+		for j < n && isWordByte(query[j]) {
+			j++
+		}
+		return j
+	}
+
+	j := i
+	for j < n && isDigit(query[j]) {
+		j++
+	}
+	if j < n && query[j] == '.' {
+		j++
+		for j < n && isDigit(query[j]) {
+			j++
+		}
+	}
+	if j < n && (query[j] == 'e' || query[j] == 'E') {
+		k := j + 1
+		if k < n && (query[k] == '+' || query[k] == '-') {
+			k++
+		}
+		if k < n && isDigit(query[k]) {
+			for k < n && isDigit(query[k]) {
+				k++
+			}
+			j = k
+		}
+	}
+	return j
+}
+
+// foldSigns 去掉字面量前的一元 +/-
+func foldSigns(tokens []token) []token {
+	res := make([]token, 0, len(tokens))
+	for i, t := range tokens {
+		if t.kind == tokenPunct && (t.text == "-" || t.text == "+") &&
+			i+1 < len(tokens) && tokens[i+1].kind == tokenLiteral && isUnaryPosition(res) {
+			continue
+		}
+		res = append(res, t)
+	}
+	return res
+}
+
+func isUnaryPosition(prev []token) bool {
+	if len(prev) == 0 {
+		return true
+	}
+	last := prev[len(prev)-1]
+	switch last.kind {
+	case tokenPunct:
+		return last.text != ")"
+	case tokenWord:
+		return slices.Contains(operatorKeywords, last.text)
+	default:
+		return false
+	}
+}
+
+// collapseLists 把 IN (?, ?, ...) 和 VALUES (?, ?), (?, ?) 合并成 (?+)
+func collapseLists(tokens []token) []token {
+	res := make([]token, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		res = append(res, t)
+		if t.kind != tokenWord {
+			continue
+		}
+
+		var end int
+		switch t.text {
+		case "in":
+			end = literalGroupEnd(tokens, i+1)
+		case "values", "value":
+			end = literalGroupEnd(tokens, i+1)
+			// 多组之间用逗号分隔
+			for end > 0 && end < len(tokens) && isPunct(tokens[end], ",") {
+				next := literalGroupEnd(tokens, end+1)
+				if next < 0 {
+					break
+				}
+				end = next
+			}
+		default:
+			continue
+		}
+		if end < 0 {
+			continue
+		}
+
+		res = append(
+			res,
+			token{kind: tokenPunct, text: "("},
+			token{kind: tokenLiteral, text: "?+"},
+			token{kind: tokenPunct, text: ")"},
+		)
+		i = end - 1
+	}
+	return res
+}
+
+// literalGroupEnd 从 start 开始是 ( 字面量, ... ) 时返回 ) 之后的位置, 否则返回 -1
+func literalGroupEnd(tokens []token, start int) int {
+	if start >= len(tokens) || !isPunct(tokens[start], "(") {
+		return -1
+	}
+
+	expectValue := true
+	for j := start + 1; j < len(tokens); j++ {
+		t := tokens[j]
+		if expectValue {
+			if !isListValue(t) {
+				return -1
+			}
+		} else {
+			if isPunct(t, ")") {
+				return j + 1
+			}
+			if !isPunct(t, ",") {
+				return -1
+			}
+		}
+		expectValue = !expectValue
+	}
+	return -1
+}
+
+func isListValue(t token) bool {
+	return t.kind == tokenLiteral || (t.kind == tokenWord && (t.text == "null" || t.text == "default"))
+}
+
+func isPunct(t token, text string) bool {
+	return t.kind == tokenPunct && t.text == text
+}
+
+// render 用单个空格连接, 括号和逗号按常见写法紧凑排列
+func render(tokens []token) string {
+	var b strings.Builder
+	for i, t := range tokens {
+		text := t.text
+		if t.kind == tokenLiteral && text == "" {
+			text = "?"
+		}
+
+		if i > 0 && needSpace(tokens[i-1], t) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(text)
+	}
+	return b.String()
+}
+
+func needSpace(prev token, cur token) bool {
+	if prev.kind == tokenPunct && (prev.text == "(" || prev.text == ".") {
+		return false
+	}
+	if cur.kind == tokenPunct {
+		switch cur.text {
+		case ",", ")", ".", ";":
+			return false
+		case "(":
+			// 函数调用
+			if prev.kind == tokenWord && !slices.Contains(spacedKeywords, prev.text) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Normalize 去掉注释, 字面量替换为 ?, 合并 IN/VALUES 列表, 关键字小写, 空白规整为单个空格
+func Normalize(query string) string {
+	tokens := normalizeTokens(query)
+	return render(tokens)
+}
+
+func normalizeTokens(query string) []token {
+	tokens := tokenize(query)
+	// 去掉结尾的分号
+	for len(tokens) > 0 && isPunct(tokens[len(tokens)-1], ";") {
+		tokens = tokens[:len(tokens)-1]
+	}
+	tokens = foldSigns(tokens)
+	return collapseLists(tokens)
+}
@@ -0,0 +1,33 @@
+package mysql
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT 1", "select ?"},
+		{"select * from `db1`.`T1` where id in (1,2,3) and name='abc' /* hint */", "select * from db1.t1 where id in (?+) and name = ?"},
+		{"select  *\n\tfrom t   where a = \"x\";", "select * from t where a = ?"},
+		{"select * from t where a = -1 and b = +2.5e3", "select * from t where a = ? and b = ?"},
+		{"select a - 1 from t", "select a - ? from t"},
+		{"select * from t where a = 0x1F or b = x'1f' or c = b'01' or d = _utf8mb4'x'", "select * from t where a = ? or b = ? or c = ? or d = ?"},
+		{"insert into t (a, b) values (1, 'a'), (2, NULL), (3, default)", "insert into t(a, b) values (?+)"},
+		{"insert into t values (1, now())", "insert into t values (?, now())"},
+		{"select * from t where id in (select id from t2)", "select * from t where id in (select id from t2)"},
+		{"select count(*), max(a) from t", "select count(*), max(a) from t"},
+		{"select * from t where a = ? -- c\n and b = 1 # d", "select * from t where a = ? and b = ?"},
+		{"select * from t where a = 'it''s' and b = 'a\\'b'", "select * from t where a = ? and b = ?"},
+		{"select @a, @@global.max_connections", "select @a, @@global.max_connections"},
+		{"select * from 1t where a <=> 1", "select * from 1t where a <=> ?"},
+		{"select `a``b` from t", "select a`b from t"},
+		{"select .5 from t", "select ? from t"},
+		{"select t.a from t", "select t.a from t"},
+	}
+	for _, c := range cases {
+		if got := Normalize(c.query); got != c.want {
+			t.Errorf("Normalize(%q)\n got %q\nwant %q", c.query, got, c.want)
+		}
+	}
+}
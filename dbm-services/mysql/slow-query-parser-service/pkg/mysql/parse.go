@@ -3,15 +3,31 @@ package mysql
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
 	"log/slog"
 	"os"
 	"os/exec"
 )
 
-// ParserPath TODO
+// ParserPath tmysqlparse 路径, 为空时使用 Digest
 var ParserPath *string
 
+// MaxBatchSize 批量接口一次最多的 sql 数
+const MaxBatchSize = 1000
+
+// MaxSlowLogSize 慢日志聚合接口请求体的最大字节数
+var MaxSlowLogSize int64 = 256 * 1024 * 1024
+
+// DigestQuery 单条接口生成指纹, 配置了 ParserPath 时兼容旧版本用 tmysqlparse, 否则用 Digest
+// 批量和慢日志聚合总是用 Digest
+func DigestQuery(query string) (*Response, error) {
+	if ParserPath == nil || *ParserPath == "" {
+		return Digest(query), nil
+	}
+	return parse(query)
+}
+
 func parse(query string) (*Response, error) {
 	slog.Info("mysql parse receive query", slog.String("query", query))
 
@@ -85,6 +101,9 @@ func parse(query string) (*Response, error) {
 		)
 		return nil, err
 	}
+	if len(cmdRet.Result) == 0 {
+		return nil, errors.New("tmysqlparse return empty result")
+	}
 	cmdRet.Result[0].QueryLength = len(query)
 
 	slog.Info("mysql parse unmarshal result", slog.Any("struct result", cmdRet))
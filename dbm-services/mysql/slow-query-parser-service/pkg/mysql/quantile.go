@@ -0,0 +1,74 @@
+package mysql
+
+import (
+	"math"
+	"sort"
+)
+
+// quantileAccuracy 分位数估算的相对误差
+const quantileAccuracy = 0.01
+
+// 小于这个值的耗时都算作最小值, 避免对数分桶过多
+const minQuantileValue = 1e-6
+
+var quantileGamma = (1 + quantileAccuracy) / (1 - quantileAccuracy)
+var quantileLogGamma = math.Log(quantileGamma)
+
+// quantileSketch 按对数分桶计数估算分位数, 桶的个数只和取值范围有关, 和记录数无关
+// 耗时在 1us 到 1e6s 之间时不超过 1500 个桶
+type quantileSketch struct {
+	buckets map[int]int64
+	// 小于 minQuantileValue 的个数
+	small int64
+	count int64
+	min   float64
+	max   float64
+}
+
+func (s *quantileSketch) add(v float64) {
+	if s.count == 0 || v < s.min {
+		s.min = v
+	}
+	if s.count == 0 || v > s.max {
+		s.max = v
+	}
+	s.count++
+	if v < minQuantileValue {
+		s.small++
+		return
+	}
+	if s.buckets == nil {
+		s.buckets = make(map[int]int64)
+	}
+	s.buckets[int(math.Ceil(math.Log(v)/quantileLogGamma))]++
+}
+
+// quantile 返回第 ceil(q*count) 小的值的估算, 结果在最小值和最大值之间
+func (s *quantileSketch) quantile(q float64) float64 {
+	if s.count == 0 {
+		return 0
+	}
+	rank := max(int64(math.Ceil(q*float64(s.count))), 1)
+	if rank <= s.small {
+		return s.min
+	}
+	if rank >= s.count {
+		return s.max
+	}
+
+	keys := make([]int, 0, len(s.buckets))
+	for k := range s.buckets {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+
+	seen := s.small
+	for _, k := range keys {
+		seen += s.buckets[k]
+		if seen >= rank {
+			v := 2 * math.Pow(quantileGamma, float64(k)) / (quantileGamma + 1)
+			return min(max(v, s.min), s.max)
+		}
+	}
+	return s.max
+}
@@ -0,0 +1,51 @@
+package mysql
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestQuantileSketch(t *testing.T) {
+	var empty quantileSketch
+	if v := empty.quantile(0.95); v != 0 {
+		t.Errorf("empty sketch quantile %v, want 0", v)
+	}
+
+	var one quantileSketch
+	one.add(2.5)
+	if v := one.quantile(0.95); v != 2.5 {
+		t.Errorf("single value quantile %v, want 2.5", v)
+	}
+
+	var zeros quantileSketch
+	for i := 0; i < 10; i++ {
+		zeros.add(0)
+	}
+	zeros.add(3)
+	if v := zeros.quantile(0.5); v != 0 {
+		t.Errorf("quantile of zeros %v, want 0", v)
+	}
+	if v := zeros.quantile(1); v != 3 {
+		t.Errorf("max quantile %v, want 3", v)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	var s quantileSketch
+	values := make([]float64, 100000)
+	for i := range values {
+		values[i] = math.Exp(r.NormFloat64()*3) * 0.5
+		s.add(values[i])
+	}
+	sort.Float64s(values)
+	for _, q := range []float64{0.5, 0.95, 0.99} {
+		want := values[int(math.Ceil(q*float64(len(values))))-1]
+		if got := s.quantile(q); math.Abs(got-want) > want*quantileAccuracy {
+			t.Errorf("quantile %v = %v, want %v", q, got, want)
+		}
+	}
+	if len(s.buckets) > 1500 {
+		t.Errorf("%d buckets, should be bounded", len(s.buckets))
+	}
+}
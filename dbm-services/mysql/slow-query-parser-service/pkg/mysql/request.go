@@ -4,3 +4,8 @@ package mysql
 type Request struct {
 	Content string `json:"content" binding:"required"`
 }
+
+// BatchRequest 批量请求
+type BatchRequest struct {
+	Contents []string `json:"contents" binding:"required"`
+}
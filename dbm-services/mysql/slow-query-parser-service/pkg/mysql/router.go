@@ -1,6 +1,8 @@
 package mysql
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 
@@ -15,20 +17,61 @@ func AddRouter(r *gin.Engine) {
 		body := Request{}
 		err := ctx.BindJSON(&body)
 		if err != nil {
-			slog.Error("mysql", err)
+			slog.Error("mysql", slog.String("error", err.Error()))
 			ctx.JSON(http.StatusBadRequest, err.Error())
 			return
 		}
 
 		slog.Info("mysql", slog.Any("body", body), slog.String("path", g.BasePath()))
 
-		res, err := parse(body.Content)
+		res, err := DigestQuery(body.Content)
 		if err != nil {
-			slog.Error("mysql", err)
+			slog.Error("mysql", slog.String("error", err.Error()))
 			ctx.JSON(http.StatusInternalServerError, err.Error())
 			return
 		}
 
 		ctx.JSON(http.StatusOK, res)
 	})
+
+	g.POST("/batch", func(ctx *gin.Context) {
+		body := BatchRequest{}
+		err := ctx.BindJSON(&body)
+		if err != nil {
+			slog.Error("mysql batch", slog.String("error", err.Error()))
+			ctx.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if len(body.Contents) > MaxBatchSize {
+			ctx.JSON(http.StatusBadRequest, fmt.Sprintf("too many contents, max %d", MaxBatchSize))
+			return
+		}
+		slog.Info("mysql batch", slog.Int("count", len(body.Contents)))
+
+		// 批量只用内置算法, 不为每条 sql 启动 tmysqlparse
+		res := make([]*Response, len(body.Contents))
+		for idx, content := range body.Contents {
+			res[idx] = Digest(content)
+		}
+		ctx.JSON(http.StatusOK, res)
+	})
+
+	// 请求体为原始的慢日志文件, 不能超过 MaxSlowLogSize
+	g.POST("/slowlog", func(ctx *gin.Context) {
+		res, err := AggregateSlowLog(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, MaxSlowLogSize))
+		if err != nil {
+			slog.Error("mysql slowlog", slog.String("error", err.Error()))
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				ctx.JSON(http.StatusRequestEntityTooLarge, fmt.Sprintf("slow log too large, max %d bytes", MaxSlowLogSize))
+				return
+			}
+			ctx.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		slog.Info("mysql slowlog", slog.Int("fingerprints", len(res)))
+		ctx.JSON(http.StatusOK, res)
+	})
 }
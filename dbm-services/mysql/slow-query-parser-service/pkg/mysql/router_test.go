@@ -0,0 +1,65 @@
+package mysql
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	AddRouter(r)
+	return r
+}
+
+func TestBatchUseNativeDigest(t *testing.T) {
+	old := ParserPath
+	defer func() {
+		ParserPath = old
+	}()
+	// 批量接口不会用到 tmysqlparse
+	notExists := "/not/exists/tmysqlparse"
+	ParserPath = &notExists
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/mysql/batch",
+		strings.NewReader(`{"contents":["select 1","select * from t where id = 2"]}`))
+	newTestRouter().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	var res []*Response
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 || res[1].QueryDigestMd5 != Digest("select * from t where id = 2").QueryDigestMd5 {
+		t.Errorf("unexpected result %s", w.Body.String())
+	}
+}
+
+func TestSlowLogBodyLimit(t *testing.T) {
+	old := MaxSlowLogSize
+	defer func() {
+		MaxSlowLogSize = old
+	}()
+
+	r := newTestRouter()
+	MaxSlowLogSize = int64(len(testSlowLog))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/mysql/slowlog", strings.NewReader(testSlowLog)))
+	if w.Code != http.StatusOK {
+		t.Errorf("status %d, want 200: %s", w.Code, w.Body.String())
+	}
+
+	MaxSlowLogSize = int64(len(testSlowLog)) - 1
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/mysql/slowlog", strings.NewReader(testSlowLog)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status %d, want 413: %s", w.Code, w.Body.String())
+	}
+}
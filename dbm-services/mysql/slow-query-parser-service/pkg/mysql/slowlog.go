@@ -0,0 +1,263 @@
+package mysql
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 单行最大长度, 超长的 sql 也能读出来
+const maxSlowLogLineSize = 64 * 1024 * 1024
+
+// 聚合结果中示例 sql 的最大长度
+const maxExampleLength = 4096
+
+// slowLogHeaderPattern 已知的记录头, 包括 mysql 8.0 log_slow_extra, percona 和 mariadb 的扩展
+// 其他 # 开头的行是 sql 中的注释
+var slowLogHeaderPattern = regexp.MustCompile(
+	`^#\s+(Time|User@Host|Query_time|Schema|Thread_id|Last_errno|Killed|Bytes_sent|Tmp_tables|` +
+		`InnoDB_\w+|QC_[Hh]it|Full_scan|Filesort|Log_slow_rate_type|Rows_affected|Pages_\w+|` +
+		`Stored_routine|Priority_queue):\s|^# No InnoDB statistics`,
+)
+
+// SlowLogEntry 慢日志中的一条记录
+type SlowLogEntry struct {
+	Time         time.Time
+	User         string
+	Host         string
+	DbName       string
+	QueryTime    float64
+	LockTime     float64
+	RowsSent     int64
+	RowsExamined int64
+	Query        string
+}
+
+// SlowLogAggregate 按指纹聚合的慢日志
+type SlowLogAggregate struct {
+	QueryDigestText   string    `json:"query_digest_text"`
+	QueryDigestMd5    string    `json:"query_digest_md5"`
+	Command           string    `json:"command"`
+	DbName            string    `json:"db_name"`
+	TableName         string    `json:"table_name"`
+	Example           string    `json:"example"`
+	Count             int64     `json:"count"`
+	QueryTimeTotal    float64   `json:"query_time_total"`
+	QueryTimeAvg      float64   `json:"query_time_avg"`
+	QueryTimeP95      float64   `json:"query_time_p95"`
+	QueryTimeMax      float64   `json:"query_time_max"`
+	LockTimeTotal     float64   `json:"lock_time_total"`
+	RowsSentTotal     int64     `json:"rows_sent_total"`
+	RowsExaminedTotal int64     `json:"rows_examined_total"`
+	RowsExaminedAvg   float64   `json:"rows_examined_avg"`
+	FirstSeen         time.Time `json:"first_seen"`
+	LastSeen          time.Time `json:"last_seen"`
+
+	queryTimes quantileSketch
+}
+
+// ParseSlowLog 逐条解析慢日志, 支持 mysql 5.x/8.0 和 percona 的格式
+// fn 返回错误时停止解析
+func ParseSlowLog(r io.Reader, fn func(entry *SlowLogEntry) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxSlowLogLineSize)
+
+	var entry *SlowLogEntry
+	var query strings.Builder
+	// 当前记录已经读到 sql 部分, 再遇到 # 开头的行就是下一条记录
+	inQuery := false
+
+	flush := func() error {
+		var err error
+		if entry != nil {
+			entry.Query = strings.TrimSpace(query.String())
+			if entry.Query != "" {
+				err = fn(entry)
+			}
+		}
+		entry = nil
+		query.Reset()
+		inQuery = false
+		return err
+	}
+	// mysqld 只在库变化时写 use db, 后续记录沿用
+	lastDb := ""
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if slowLogHeaderPattern.MatchString(line) {
+			if entry == nil || inQuery {
+				if err := flush(); err != nil {
+					return err
+				}
+				entry = &SlowLogEntry{DbName: lastDb}
+			}
+			parseSlowLogHeader(entry, line[2:])
+			continue
+		}
+
+		if entry == nil || isSlowLogBanner(line) {
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		lower := strings.ToLower(trimmed)
+		if !inQuery {
+			// sql 前面的 use db; 和 SET timestamp=...;
+			if strings.HasPrefix(lower, "use ") {
+				entry.DbName = strings.Trim(strings.TrimSuffix(trimmed[4:], ";"), " `")
+				lastDb = entry.DbName
+				continue
+			}
+			if strings.HasPrefix(lower, "set timestamp=") {
+				if ts, err := strconv.ParseInt(strings.TrimSuffix(trimmed[len("set timestamp="):], ";"), 10, 64); err == nil && entry.Time.IsZero() {
+					entry.Time = time.Unix(ts, 0)
+				}
+				continue
+			}
+		}
+
+		inQuery = true
+		query.WriteString(line)
+		query.WriteByte('\n')
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	return scanner.Err()
+}
+
+// isSlowLogBanner mysqld 启动时写入的文件头
+func isSlowLogBanner(line string) bool {
+	return strings.Contains(line, ", Version: ") ||
+		strings.HasPrefix(line, "Tcp port: ") ||
+		strings.HasPrefix(line, "Time                 Id Command    Argument")
+}
+
+func parseSlowLogHeader(entry *SlowLogEntry, header string) {
+	switch {
+	case strings.HasPrefix(header, "Time: "):
+		v := strings.TrimSpace(header[len("Time: "):])
+		for _, layout := range []string{time.RFC3339Nano, "060102 15:04:05", "060102  15:04:05"} {
+			if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+				entry.Time = t
+				break
+			}
+		}
+	case strings.HasPrefix(header, "User@Host: "):
+		// # User@Host: root[root] @ localhost [127.0.0.1]  Id:     8
+		v := header[len("User@Host: "):]
+		if idx := strings.Index(v, "["); idx > 0 {
+			entry.User = strings.TrimSpace(v[:idx])
+		}
+		if idx := strings.Index(v, " @ "); idx >= 0 {
+			fields := strings.Fields(v[idx+3:])
+			for _, f := range fields {
+				f = strings.Trim(f, "[]")
+				if f != "" && f != "Id:" {
+					entry.Host = f
+					break
+				}
+			}
+		}
+	default:
+		// # Query_time: 2.000123  Lock_time: 0.000000 Rows_sent: 1  Rows_examined: 0
+		// percona 还有 Schema: db  Last_errno: 0 等
+		fields := strings.Fields(header)
+		for i := 0; i+1 < len(fields); i++ {
+			v := fields[i+1]
+			switch fields[i] {
+			case "Query_time:":
+				entry.QueryTime, _ = strconv.ParseFloat(v, 64)
+			case "Lock_time:":
+				entry.LockTime, _ = strconv.ParseFloat(v, 64)
+			case "Rows_sent:":
+				entry.RowsSent, _ = strconv.ParseInt(v, 10, 64)
+			case "Rows_examined:":
+				entry.RowsExamined, _ = strconv.ParseInt(v, 10, 64)
+			case "Schema:":
+				entry.DbName = v
+			}
+		}
+	}
+}
+
+// AggregateSlowLog 解析慢日志并按指纹聚合, 按总耗时倒序返回
+func AggregateSlowLog(r io.Reader) ([]*SlowLogAggregate, error) {
+	aggs := make(map[string]*SlowLogAggregate)
+	err := ParseSlowLog(
+		r, func(entry *SlowLogEntry) error {
+			d := Digest(entry.Query)
+			agg, ok := aggs[d.QueryDigestMd5]
+			if !ok {
+				agg = &SlowLogAggregate{
+					QueryDigestText: d.QueryDigestText,
+					QueryDigestMd5:  d.QueryDigestMd5,
+					Command:         d.Command,
+					DbName:          d.DbName,
+					TableName:       d.TableName,
+					Example:         entry.Query,
+					FirstSeen:       entry.Time,
+				}
+				if agg.DbName == "" {
+					agg.DbName = entry.DbName
+				}
+				if len(agg.Example) > maxExampleLength {
+					agg.Example = agg.Example[:maxExampleLength]
+				}
+				aggs[d.QueryDigestMd5] = agg
+			}
+			agg.add(entry)
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*SlowLogAggregate, 0, len(aggs))
+	for _, agg := range aggs {
+		agg.finish()
+		res = append(res, agg)
+	}
+	sort.Slice(
+		res, func(i, j int) bool {
+			return res[i].QueryTimeTotal > res[j].QueryTimeTotal
+		},
+	)
+	return res, nil
+}
+
+func (a *SlowLogAggregate) add(entry *SlowLogEntry) {
+	a.Count++
+	a.QueryTimeTotal += entry.QueryTime
+	a.QueryTimeMax = max(a.QueryTimeMax, entry.QueryTime)
+	a.LockTimeTotal += entry.LockTime
+	a.RowsSentTotal += entry.RowsSent
+	a.RowsExaminedTotal += entry.RowsExamined
+	a.queryTimes.add(entry.QueryTime)
+
+	if !entry.Time.IsZero() {
+		if a.FirstSeen.IsZero() || entry.Time.Before(a.FirstSeen) {
+			a.FirstSeen = entry.Time
+		}
+		if entry.Time.After(a.LastSeen) {
+			a.LastSeen = entry.Time
+		}
+	}
+}
+
+func (a *SlowLogAggregate) finish() {
+	if a.Count == 0 {
+		return
+	}
+	a.QueryTimeAvg = a.QueryTimeTotal / float64(a.Count)
+	a.RowsExaminedAvg = float64(a.RowsExaminedTotal) / float64(a.Count)
+	a.QueryTimeP95 = a.queryTimes.quantile(0.95)
+}
@@ -0,0 +1,137 @@
+package mysql
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSlowLog = `/usr/sbin/mysqld, Version: 8.0.30 (MySQL Community Server - GPL). started with:
+Tcp port: 3306  Unix socket: /tmp/mysql.sock
+Time                 Id Command    Argument
+# Time: 2026-10-18T01:00:00.123456Z
+# User@Host: root[root] @ localhost [127.0.0.1]  Id:     8
+# Query_time: 2.000123  Lock_time: 0.000100 Rows_sent: 1  Rows_examined: 1000
+use shop;
+SET timestamp=1792285200;
+select * from orders where id = 5;
+# Time: 2026-10-18T01:00:01.123456Z
+# User@Host: app[app] @  [10.0.0.1]  Id:     9
+# Query_time: 4.000000  Lock_time: 0.000000 Rows_sent: 1  Rows_examined: 3000
+SET timestamp=1792285201;
+select *
+# comment in query
+from orders
+where id = 6;
+# Time: 2026-10-18T01:00:02.000000Z
+# User@Host: app[app] @  [10.0.0.1]  Id:     9
+# Schema: crm  Last_errno: 0  Killed: 0
+# Query_time: 1.000000  Lock_time: 0.000000 Rows_sent: 0  Rows_examined: 10
+# Bytes_sent: 56  Tmp_tables: 0  Tmp_disk_tables: 0  Tmp_table_sizes: 0
+# QC_Hit: No  Full_scan: No  Full_join: No  Tmp_table: No  Tmp_table_on_disk: No
+# Filesort: No  Filesort_on_disk: No  Merge_passes: 0
+#   InnoDB_IO_r_ops: 0  InnoDB_IO_r_bytes: 0  InnoDB_IO_r_wait: 0.000000
+# No InnoDB statistics available for this query
+SET timestamp=1792285202;
+update customers set name = 'a' where id = 1;
+`
+
+func TestParseSlowLog(t *testing.T) {
+	var entries []*SlowLogEntry
+	err := ParseSlowLog(
+		strings.NewReader(testSlowLog), func(entry *SlowLogEntry) error {
+			entries = append(entries, entry)
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []SlowLogEntry{
+		{
+			Time: time.Date(2026, 10, 18, 1, 0, 0, 123456000, time.UTC), User: "root", Host: "localhost",
+			DbName: "shop", QueryTime: 2.000123, LockTime: 0.0001, RowsSent: 1, RowsExamined: 1000,
+			Query: "select * from orders where id = 5;",
+		},
+		{
+			Time: time.Date(2026, 10, 18, 1, 0, 1, 123456000, time.UTC), User: "app", Host: "10.0.0.1",
+			DbName: "shop", QueryTime: 4, RowsSent: 1, RowsExamined: 3000,
+			Query: "select *\n# comment in query\nfrom orders\nwhere id = 6;",
+		},
+		{
+			Time: time.Date(2026, 10, 18, 1, 0, 2, 0, time.UTC), User: "app", Host: "10.0.0.1",
+			DbName: "crm", QueryTime: 1, RowsExamined: 10,
+			Query: "update customers set name = 'a' where id = 1;",
+		},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for i, e := range entries {
+		w := want[i]
+		if !e.Time.Equal(w.Time) {
+			t.Errorf("entry %d time %s, want %s", i, e.Time, w.Time)
+		}
+		e.Time, w.Time = time.Time{}, time.Time{}
+		if *e != w {
+			t.Errorf("entry %d\n got %+v\nwant %+v", i, *e, w)
+		}
+	}
+}
+
+func TestParseSlowLogHeader(t *testing.T) {
+	cases := []struct {
+		line   string
+		header bool
+	}{
+		{"# Time: 2026-10-18T01:00:00.123456Z", true},
+		{"# Time: 261018  1:00:00", true},
+		{"# User@Host: root[root] @ localhost []  Id: 8", true},
+		{"# Query_time: 1.0  Lock_time: 0.0 Rows_sent: 0  Rows_examined: 0", true},
+		{"# Thread_id: 42  Schema: db  QC_hit: No", true},
+		{"#   InnoDB_pages_distinct: 56", true},
+		{"# No InnoDB statistics available for this query", true},
+		{"# comment", false},
+		{"# Time is money", false},
+		{"#Query_time: 1", false},
+	}
+	for _, c := range cases {
+		if got := slowLogHeaderPattern.MatchString(c.line); got != c.header {
+			t.Errorf("header %q = %v, want %v", c.line, got, c.header)
+		}
+	}
+
+	entry := &SlowLogEntry{}
+	parseSlowLogHeader(entry, "Time: 261018  1:00:00")
+	if want := time.Date(2026, 10, 18, 1, 0, 0, 0, time.Local); !entry.Time.Equal(want) {
+		t.Errorf("mysql 5.x time %s, want %s", entry.Time, want)
+	}
+}
+
+func TestAggregateSlowLog(t *testing.T) {
+	res, err := AggregateSlowLog(strings.NewReader(testSlowLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 {
+		t.Fatalf("got %d fingerprints, want 2", len(res))
+	}
+
+	orders := res[0]
+	if orders.QueryDigestText != "select * from orders where id = ?" || orders.Count != 2 ||
+		orders.DbName != "shop" || orders.TableName != "orders" {
+		t.Errorf("unexpected aggregate %+v", orders)
+	}
+	if orders.QueryTimeTotal != 6.000123 || orders.QueryTimeMax != 4 || orders.QueryTimeP95 != 4 ||
+		orders.RowsExaminedAvg != 2000 {
+		t.Errorf("unexpected statistics %+v", orders)
+	}
+	if orders.Example != "select * from orders where id = 5;" {
+		t.Errorf("unexpected example %q", orders.Example)
+	}
+
+	if res[1].TableName != "customers" || res[1].DbName != "crm" || res[1].Count != 1 {
+		t.Errorf("unexpected aggregate %+v", res[1])
+	}
+}
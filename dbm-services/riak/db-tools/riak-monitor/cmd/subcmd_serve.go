@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"dbm-services/common/go-pubpkg/promexporter"
+	"dbm-services/riak/db-tools/riak-monitor/pkg/config"
+	"dbm-services/riak/db-tools/riak-monitor/pkg/mainloop"
+	"dbm-services/riak/db-tools/riak-monitor/pkg/utils"
+
+	"github.com/robfig/cron/v3"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/exp/slog"
+)
+
+// 常驻运行, 不依赖 mysql-crond, 按监控项的 schedule 运行
+// 指标以 prometheus 格式在 /metrics 暴露, 事件在 /events 查询
+var subCmdServe = &cobra.Command{
+	Use:   "serve",
+	Short: "run monitor items on schedule and expose prometheus metrics",
+	Long:  "run monitor items on schedule and expose prometheus metrics",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := config.InitConfig(viper.GetString("serve-config"))
+		if err != nil {
+			return err
+		}
+		initLogger(config.MonitorConfig.Log)
+		// 加载监控items-config.yaml配置文件
+		err = config.LoadMonitorItemsConfig()
+		if err != nil {
+			slog.Error("serve load items", err)
+			return err
+		}
+		config.InjectHardCodeItem()
+
+		registry := promexporter.NewRegistry(viper.GetInt("serve-max-events"))
+		utils.EnableExporter(registry)
+
+		// 和 mysql-crond 一样支持秒级 schedule, 上一次还没跑完就跳过
+		scheduler := cron.New(
+			cron.WithParser(
+				cron.NewParser(
+					cron.SecondOptional |
+						cron.Minute |
+						cron.Hour |
+						cron.Dom |
+						cron.Month |
+						cron.Dow |
+						cron.Descriptor,
+				),
+			),
+			cron.WithChain(cron.SkipIfStillRunning(cron.DiscardLogger)),
+		)
+
+		itemGroups := make(map[string][]string)
+		var hardCodeItems []string
+		for _, ele := range config.ItemsConfig {
+			if !ele.IsEnable() {
+				continue
+			}
+			// 硬编码监控项
+			if ele.Name == "riak-db-up" || ele.Name == config.HeartBeatName {
+				hardCodeItems = append(hardCodeItems, ele.Name)
+				continue
+			}
+			if !ele.IsMatchMachineType() {
+				continue
+			}
+			schedule := config.MonitorConfig.DefaultSchedule
+			if ele.Schedule != nil {
+				schedule = *ele.Schedule
+			}
+			itemGroups[schedule] = append(itemGroups[schedule], ele.Name)
+		}
+
+		for schedule, itemNames := range itemGroups {
+			itemNames := itemNames
+			_, err := scheduler.AddFunc(schedule, func() {
+				_ = mainloop.RunItems(itemNames, false)
+			})
+			if err != nil {
+				slog.Error("serve add schedule", err, slog.String("schedule", schedule))
+				return err
+			}
+			slog.Info("serve add schedule", slog.String("schedule", schedule), slog.Any("items", itemNames))
+		}
+		if len(hardCodeItems) > 0 {
+			_, err := scheduler.AddFunc(config.HardCodeSchedule, func() {
+				_ = mainloop.RunItems(hardCodeItems, true)
+			})
+			if err != nil {
+				slog.Error("serve add hardcode schedule", err)
+				return err
+			}
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		server := &http.Server{
+			Addr:              viper.GetString("serve-address"),
+			Handler:           registry.Handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- server.ListenAndServe()
+		}()
+		scheduler.Start()
+		slog.Info("serve start", slog.String("address", server.Addr))
+
+		select {
+		case err = <-errChan:
+		case <-ctx.Done():
+		}
+		<-scheduler.Stop().Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("serve", err)
+			return err
+		}
+		slog.Info("serve stopped")
+		return nil
+	},
+}
+
+func init() {
+	// 配置文件
+	subCmdServe.PersistentFlags().StringP("config", "c", "", "config file")
+	_ = subCmdServe.MarkPersistentFlagRequired("config")
+	_ = viper.BindPFlag("serve-config", subCmdServe.PersistentFlags().Lookup("config"))
+	// 监听地址
+	subCmdServe.PersistentFlags().StringP("address", "", "", "listen address, e.g. 127.0.0.1:9100")
+	_ = subCmdServe.MarkPersistentFlagRequired("address")
+	_ = viper.BindPFlag("serve-address", subCmdServe.PersistentFlags().Lookup("address"))
+	// 内存中保留的事件数
+	subCmdServe.PersistentFlags().Int("max-events", promexporter.DefaultMaxEvents, "events kept in memory")
+	_ = viper.BindPFlag("serve-max-events", subCmdServe.PersistentFlags().Lookup("max-events"))
+
+	rootCmd.AddCommand(subCmdServe)
+}
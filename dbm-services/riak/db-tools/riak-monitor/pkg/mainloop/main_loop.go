@@ -34,6 +34,11 @@ func Run(hardcode bool) error {
 	} else {
 		iNames = viper.GetStringSlice("run-items")
 	}
+	return RunItems(iNames, hardcode)
+}
+
+// RunItems 运行指定的监控项, serve 模式按调度直接调用
+func RunItems(iNames []string, hardcode bool) error {
 	slog.Info("main loop", slog.String("items", strings.Join(iNames, ",")))
 	slog.Info("main loop", slog.Bool("hardcode", hardcode))
 
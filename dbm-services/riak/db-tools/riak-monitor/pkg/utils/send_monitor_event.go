@@ -3,6 +3,7 @@ package utils
 import (
 	"strconv"
 
+	"dbm-services/common/go-pubpkg/promexporter"
 	ma "dbm-services/mysql/db-tools/mysql-crond/api"
 	"dbm-services/riak/db-tools/riak-monitor/pkg/config"
 
@@ -26,6 +27,12 @@ func SendMonitorEvent(name string, msg string) {
 		"bk_target_service_instance_id": strconv.FormatInt(config.MonitorConfig.BkInstanceId, 10),
 	}
 
+	if exporter != nil {
+		exporter.AddEvent(name, msg, promexporter.LabelsFromDimension(additionDimension))
+		slog.Info("add event", slog.String("name", name), slog.String("msg", msg))
+		return
+	}
+
 	err := crondManager.SendEvent(
 		name,
 		msg,
@@ -3,6 +3,7 @@ package utils
 import (
 	"strconv"
 
+	"dbm-services/common/go-pubpkg/promexporter"
 	ma "dbm-services/mysql/db-tools/mysql-crond/api"
 	"dbm-services/riak/db-tools/riak-monitor/pkg/config"
 
@@ -27,6 +28,12 @@ func SendMonitorMetrics(name string, value int64, customDimension map[string]int
 		maps.Copy(additionDimension, customDimension)
 	}
 
+	if exporter != nil {
+		exporter.SetGauge(name, float64(value), promexporter.LabelsFromDimension(additionDimension))
+		slog.Info("set metrics", slog.String("name", name), slog.Int64("value", value))
+		return
+	}
+
 	err := crondManager.SendMetrics(
 		name,
 		value,
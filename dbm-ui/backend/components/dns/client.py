@@ -50,6 +50,11 @@ class _DnsApi(BaseApi):
             url="/api/v1/dns/domain/all",
             description=_("获取所有ip、域名关系"),
         )
+        self.get_deleted_domain_list = self.generate_data_api(
+            method="GET",
+            url="/api/v1/dns/domain/deleted",
+            description=_("获取已删除的ip、域名关系"),
+        )
 
 
 DnsApi = _DnsApi()
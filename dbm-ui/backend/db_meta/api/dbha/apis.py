@@ -393,6 +393,55 @@ def swap_cc_svr_instance_role(ins1_obj: StorageInstance, ins2_obj: StorageInstan
     )
 
 
+@transaction.atomic
+def mongodb_cluster_swap(payloads: List, bk_cloud_id: int):
+    """
+    mongodb 副本集成员切换, instance1 为原 primary, instance2 为新 primary
+    1. 互换两个成员的角色
+    2. 原 primary 绑定的访问入口转移到新 primary
+    3. 切换CC 服务实例 角色
+    """
+    DBHASwapRequestSerializer(data={"payloads": payloads}).is_valid(raise_exception=True)
+    for pl in payloads:
+        ins1 = pl["instance1"]
+        ins2 = pl["instance2"]
+
+        ins1_obj = StorageInstance.objects.get(
+            machine__ip=ins1["ip"], port=ins1["port"], machine__bk_cloud_id=bk_cloud_id
+        )
+        ins2_obj = StorageInstance.objects.get(
+            machine__ip=ins2["ip"], port=ins2["port"], machine__bk_cloud_id=bk_cloud_id
+        )
+
+        cluster_obj = ins1_obj.cluster.first()
+        if cluster_obj is None or not ins2_obj.cluster.filter(id=cluster_obj.id).exists():
+            raise Exception(
+                "{}:{} and {}:{} not in the same replica set".format(
+                    ins1_obj.machine.ip, ins1_obj.port, ins2_obj.machine.ip, ins2_obj.port
+                )
+            )
+        if cluster_obj.cluster_type not in [ClusterType.MongoReplicaSet, ClusterType.MongoShardedCluster]:
+            raise Exception("cluster {} is not mongodb cluster".format(cluster_obj.immute_domain))
+
+        for entry_obj in ins1_obj.bind_entry.all():
+            ins1_obj.bind_entry.remove(entry_obj)
+            ins2_obj.bind_entry.add(entry_obj)
+
+        temp_instance_role = ins1_obj.instance_role
+        tmep_instance_inner_role = ins1_obj.instance_inner_role
+
+        ins1_obj.instance_role = ins2_obj.instance_role
+        ins1_obj.instance_inner_role = ins2_obj.instance_inner_role
+
+        ins2_obj.instance_role = temp_instance_role
+        ins2_obj.instance_inner_role = tmep_instance_inner_role
+
+        ins1_obj.save(update_fields=["instance_role", "instance_inner_role"])
+        ins2_obj.save(update_fields=["instance_role", "instance_inner_role"])
+        # 切换CC 服务实例 角色，性能数据展示使用
+        swap_cc_svr_instance_role(ins1_obj, ins2_obj)
+
+
 @transaction.atomic
 def sqlserver_cluster_swap(payloads: List, bk_cloud_id: int):
     """
@@ -201,3 +201,14 @@ def sqlserver_cluster_swap(request: Request):
         return JsonResponse({"code": 0, "data": "", "msg": ""})
     except Exception as e:
         return JsonResponse({"code": 1, "data": "", "msg": "{}".format(e)})
+
+
+@api_view(["POST"])
+# @permission_classes([AllowAny])
+@csrf_exempt
+def mongodb_cluster_swap(request: Request):
+    try:
+        api.dbha.mongodb_cluster_swap(request.data)
+        return JsonResponse({"code": 0, "data": "", "msg": ""})
+    except Exception as e:
+        return JsonResponse({"code": 1, "data": "", "msg": "{}".format(e)})
@@ -16,7 +16,11 @@ from backend.db_proxy.views.serialiers import BaseProxyPassSerializer
 
 
 class GetAllDomainListSerializer(BaseProxyPassSerializer):
-    pass
+    last_change_time = serializers.CharField(help_text=_("只查询该时间之后变更的记录"), required=False)
+
+
+class GetDeletedDomainListSerializer(BaseProxyPassSerializer):
+    last_change_time = serializers.CharField(help_text=_("只查询该时间之后删除的记录"))
 
 
 class GetAllDomainListResponseSerializer(serializers.Serializer):
@@ -42,6 +42,21 @@ class DnsProxyPassViewSet(BaseProxyPassViewSet):
         validated_data = self.params_validate(self.get_serializer_class())
         return Response(DnsApi.get_all_domain_list(params=validated_data))
 
+    @common_swagger_auto_schema(
+        operation_summary=_("[dns]获取已删除的ip、域名关系"),
+        request_body=serializers.GetDeletedDomainListSerializer(),
+        tags=[SWAGGER_TAG],
+    )
+    @action(
+        methods=["POST"],
+        detail=False,
+        serializer_class=serializers.GetDeletedDomainListSerializer,
+        url_path="dns/domain/deleted",
+    )
+    def get_deleted_domain_list(self, request):
+        validated_data = self.params_validate(self.get_serializer_class())
+        return Response(DnsApi.get_deleted_domain_list(params=validated_data))
+
     @common_swagger_auto_schema(
         operation_summary=_("[dns]获取域名映射关系"),
         request_body=serializers.GetDomainSerializer(),